@@ -10,9 +10,11 @@ import (
 
 	"github.com/radiusdt/vector-dsp/internal/config"
 	"github.com/radiusdt/vector-dsp/internal/database"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
 	"github.com/radiusdt/vector-dsp/internal/httpserver"
 	"github.com/radiusdt/vector-dsp/internal/metrics"
 	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/storage"
 	"go.uber.org/zap"
 )
 
@@ -60,6 +62,24 @@ func main() {
 	}
 	defer redis.Close()
 
+	// Per-advertiser API keys and user logins, shared by the auth middleware
+	// and the admin API
+	advertisers := dsp.NewAdvertiserService(storage.NewPostgresAdvertiserRepo(db.Pool))
+	apiKeys := dsp.NewAPIKeyService(
+		storage.NewPostgresAPIKeyRepo(db.Pool),
+		advertisers,
+		cfg.Auth,
+		logger,
+	)
+	go apiKeys.Run(ctx)
+	users := dsp.NewUserService(
+		storage.NewPostgresUserRepo(db.Pool),
+		advertisers,
+		cfg.Auth,
+		logger,
+		m,
+	)
+
 	// Build dependencies
 	deps := &httpserver.Dependencies{
 		DB:      db,
@@ -67,6 +87,9 @@ func main() {
 		Config:  cfg,
 		Logger:  logger,
 		Metrics: m,
+		APIKeys: apiKeys,
+		Users:   users,
+		Context: ctx,
 	}
 
 	// Create HTTP server with all middlewares
@@ -78,6 +101,9 @@ func main() {
 	rateLimitMW := middleware.NewRateLimitMiddleware(cfg.RateLimit, logger)
 	rateLimitMW.SetMetrics(m)
 	authMW := middleware.NewAuthMiddleware(cfg.Auth, logger)
+	authMW.SetMetrics(m)
+	authMW.SetKeyStore(apiKeys)
+	authMW.SetTokenVerifier(users)
 
 	finalHandler := recoveryMW.Handler(
 		loggingMW.Handler(
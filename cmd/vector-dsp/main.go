@@ -6,12 +6,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/radiusdt/vector-dsp/internal/config"
 	"github.com/radiusdt/vector-dsp/internal/database"
 	"github.com/radiusdt/vector-dsp/internal/httpserver"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/storage"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -33,6 +36,13 @@ func main() {
 		zap.String("addr", cfg.Server.Addr),
 	)
 
+	// Initialize Prometheus metrics
+	var m *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		m = metrics.NewMetrics("vector_dsp")
+		logger.Info("Prometheus metrics enabled", zap.String("path", cfg.Metrics.Path))
+	}
+
 	// Initialize database connections
 	var db *database.PostgresDB
 	var redis *database.RedisDB
@@ -57,12 +67,41 @@ func main() {
 		logger.Info("connected to Redis")
 	}
 
+	// Try to connect to ClickHouse for event storage
+	var events *storage.ClickHouseEventStore
+	if cfg.ClickHouse.Enabled {
+		ch, err := database.NewClickHouseDB(cfg.ClickHouse.Addr, cfg.ClickHouse.Database, cfg.ClickHouse.User, cfg.ClickHouse.Password)
+		if err != nil {
+			logger.Warn("ClickHouse not available, storing events in the primary database", zap.Error(err))
+		} else {
+			defer ch.Close()
+			events = storage.NewClickHouseEventStore(ch.Conn, storage.ClickHouseEventStoreConfig{
+				BatchSize:      cfg.ClickHouse.BatchSize,
+				FlushInterval:  cfg.ClickHouse.FlushInterval,
+				BufferSize:     cfg.ClickHouse.BufferSize,
+				EnqueueTimeout: cfg.ClickHouse.EnqueueTimeout,
+			}, logger, m)
+			logger.Info("connected to ClickHouse")
+		}
+	}
+
+	// Background workers stop when the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+
 	// Create HTTP server
 	deps := &httpserver.Dependencies{
-		DB:     db,
-		Redis:  redis,
-		Config: cfg,
-		Logger: logger,
+		DB:      db,
+		Redis:   redis,
+		Config:  cfg,
+		Logger:  logger,
+		Metrics: m,
+		Context: workerCtx,
+		Workers: &workers,
+	}
+	if events != nil {
+		deps.EventStore = events
 	}
 
 	handler := httpserver.NewServer(deps)
@@ -97,6 +136,26 @@ func main() {
 	if err := srv.Shutdown(ctx); err != nil {
 		logger.Error("server forced to shutdown", zap.Error(err))
 	}
+	stopWorkers()
+
+	// Wait for the workers' final flushes, which may still write events
+	stopped := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		logger.Error("background workers did not stop in time")
+	}
+
+	// Flush buffered events once no more requests are coming in
+	if events != nil {
+		if err := events.Close(ctx); err != nil {
+			logger.Error("failed to flush events", zap.Error(err))
+		}
+	}
 
 	logger.Info("server stopped")
 }
@@ -10,16 +10,28 @@ import (
 
 // Config holds all configuration for the Vector-DSP application.
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	Redis     RedisConfig
-	Auth      AuthConfig
-	RateLimit RateLimitConfig
-	Log       LogConfig
-	Metrics   MetricsConfig
-	Geo       GeoConfig
-	Pacing    PacingGlobalConfig
-	Tracking  TrackingConfig
+	Server     ServerConfig
+	Database   DatabaseConfig
+	Redis      RedisConfig
+	ClickHouse ClickHouseConfig
+	Auth       AuthConfig
+	RateLimit  RateLimitConfig
+	Log        LogConfig
+	Metrics    MetricsConfig
+	Geo        GeoConfig
+	Pacing     PacingGlobalConfig
+	Tracking   TrackingConfig
+	Currency   CurrencyConfig
+	Fraud      FraudConfig
+	Sampling   SamplingConfig
+	Reports    ReportsConfig
+	Stats      StatsConfig
+	Live       LiveConfig
+	Billing    BillingConfig
+	Invoicing  InvoicingConfig
+	Lifecycle  LifecycleConfig
+	Automation AutomationConfig
+	Alerts     AlertsConfig
 }
 
 type ServerConfig struct {
@@ -53,10 +65,43 @@ type RedisConfig struct {
 	DB       int
 }
 
+type ClickHouseConfig struct {
+	// Enabled stores events in ClickHouse instead of the primary database
+	Enabled bool
+
+	// Addr is the native protocol address (host:9000)
+	Addr     string
+	Database string
+	User     string
+	Password string
+
+	// BatchSize is the number of rows per INSERT
+	BatchSize int
+
+	// FlushInterval is the longest an event waits in the buffer
+	FlushInterval time.Duration
+
+	// BufferSize is the number of events buffered before writes block
+	BufferSize int
+
+	// EnqueueTimeout is how long a write waits on a full buffer before failing
+	EnqueueTimeout time.Duration
+}
+
 type AuthConfig struct {
 	Enabled   bool
 	MasterKey string
 	SkipPaths []string
+
+	// Per-advertiser keys from the api_keys table
+	KeyCacheTTL           time.Duration // How long a looked-up key is trusted before reloading
+	LastUsedFlushInterval time.Duration // How often last_used_at is written
+
+	// User logins for the management UI; disabled without a JWT secret
+	JWTSecret       string
+	AccessTokenTTL  time.Duration // Lifetime of a JWT access token
+	RefreshTokenTTL time.Duration // Lifetime of a refresh token; rotated on use
+	TOTPIssuer      string        // Shown in authenticator apps
 }
 
 type RateLimitConfig struct {
@@ -108,6 +153,250 @@ type TrackingConfig struct {
 
 	// EnableClickDedup enables click deduplication
 	EnableClickDedup bool
+
+	// ClickDedupWindow is how long repeat clicks (same IFA or IP+UA, campaign
+	// and creative) reuse the original click
+	ClickDedupWindow time.Duration
+
+	// LinkSecret is the HMAC key for signing click links; empty disables signing
+	LinkSecret string
+
+	// LinkTTL is how long a signed click link stays valid
+	LinkTTL time.Duration
+}
+
+// CurrencyConfig holds exchange-rate configuration
+type CurrencyConfig struct {
+	// RatesSource selects the rate provider: static, file or api
+	RatesSource string
+
+	// RatesFile is a CSV of date,currency,rate rows (RatesSource=file)
+	RatesFile string
+
+	// RatesAPIURL is the rates endpoint; may contain {date} (RatesSource=api)
+	RatesAPIURL string
+
+	// RatesAPITimeout is the timeout for rates API calls
+	RatesAPITimeout time.Duration
+
+	// RepriceInterval is how often wins and conversions held for a missing
+	// rate are converted and recorded
+	RepriceInterval time.Duration
+}
+
+// FraudConfig holds fraud detection configuration
+type FraudConfig struct {
+	// Enabled turns on click and install fraud scoring
+	Enabled bool
+
+	// MinCTIT is the minimum click-to-install time; faster installs are click injection
+	MinCTIT time.Duration
+
+	// MaxClicksPerIFA is the click limit per device within ClickWindow
+	MaxClicksPerIFA int
+
+	// MaxClicksPerIP is the click limit per IP within ClickWindow
+	MaxClicksPerIP int
+
+	// ClickWindow is the window for click spamming limits
+	ClickWindow time.Duration
+
+	// DataCenterFile lists data-center CIDR ranges, one per line
+	DataCenterFile string
+
+	// FlagThreshold is the score (0-100) at which an event is flagged
+	FlagThreshold int
+}
+
+// SamplingConfig holds bid request sampling configuration
+type SamplingConfig struct {
+	// BidRate is the percentage (0-100) of bid requests sampled
+	BidRate float64
+
+	// BidSources are RTB source IDs whose requests are always sampled
+	BidSources []string
+
+	// BidLineItems are line item IDs whose evaluations are always sampled
+	BidLineItems []string
+
+	// BufferSize is how many samples are kept in memory without ClickHouse
+	BufferSize int
+}
+
+// ReportsConfig holds scheduled report configuration
+type ReportsConfig struct {
+	// Enabled starts the scheduler that runs and delivers saved reports
+	Enabled bool
+
+	// PollInterval is how often due reports and retries are checked
+	PollInterval time.Duration
+
+	// MaxAttempts is how many times a run is attempted before it fails
+	MaxAttempts int
+
+	// RetryBackoff is the delay before the first retry; it doubles per attempt
+	RetryBackoff time.Duration
+
+	// WebhookTimeout bounds a webhook delivery request
+	WebhookTimeout time.Duration
+
+	// OutputDir is the root for "dir" deliveries
+	OutputDir string
+
+	// SMTP server for email delivery (host:port); auth is skipped without a user
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+	SMTPFrom     string
+
+	// SFTPKeyFile is the private key passed to the sftp client
+	SFTPKeyFile string
+}
+
+// StatsConfig holds daily stats rollup configuration
+type StatsConfig struct {
+	// RollupEnabled starts the worker that rolls events into daily stats
+	RollupEnabled bool
+
+	// RollupInterval is how often the worker rolls up new events
+	RollupInterval time.Duration
+
+	// LateWindow is how far behind the watermark each pass re-rolls, so
+	// late postbacks land in their day's stats
+	LateWindow time.Duration
+
+	// BackfillDays is how many days the first pass rolls up without a watermark
+	BackfillDays int
+}
+
+// LiveConfig holds real-time dashboard feed configuration
+type LiveConfig struct {
+	// Enabled serves per-second campaign aggregates at /api/stream/stats
+	Enabled bool
+
+	// MaxSubscribers bounds concurrent stream clients
+	MaxSubscribers int
+
+	// BufferFrames is how many seconds a client may lag before frames are
+	// dropped; one that misses that many in a row is disconnected
+	BufferFrames int
+}
+
+// BillingConfig holds advertiser billing ledger configuration
+type BillingConfig struct {
+	// Enabled debits win spend and conversion payouts from advertiser
+	// balances and stops bidding for advertisers without available balance
+	Enabled bool
+
+	// FlushInterval is how often accrued charges are written to the ledger
+	FlushInterval time.Duration
+}
+
+// InvoicingConfig holds closing document (счёт, акт, счёт-фактура) configuration
+type InvoicingConfig struct {
+	// Enabled generates the previous month's documents on GenerateDay
+	Enabled bool
+
+	// GenerateDay is the day of the month documents are generated
+	GenerateDay int
+
+	// Timezone bounds the months documents cover
+	Timezone string
+
+	// VATRate is the VAT percent; 0 issues documents "без НДС" and no
+	// счёт-фактура
+	VATRate float64
+
+	// VATIncluded means billed spend includes VAT; otherwise VAT is added
+	VATIncluded bool
+
+	// NumberPrefix is prepended to document numbers
+	NumberPrefix string
+
+	// TemplateDir holds invoice.tmpl, act.tmpl and vat_invoice.tmpl
+	// overriding the built-in templates
+	TemplateDir string
+
+	// FontFile is the TrueType font embedded in PDFs; it must cover Cyrillic
+	FontFile string
+
+	// Supplier requisites printed on every document
+	SupplierName        string
+	SupplierTaxID       string
+	SupplierKPP         string
+	SupplierOGRN        string
+	SupplierAddress     string
+	SupplierBankName    string
+	SupplierBIK         string
+	SupplierAccount     string
+	SupplierCorrAccount string
+	SupplierDirector    string
+	SupplierAccountant  string
+}
+
+// LifecycleConfig holds campaign lifecycle scheduler configuration
+type LifecycleConfig struct {
+	// Enabled starts the scheduler that starts, ends and pauses campaigns
+	Enabled bool
+
+	// Interval is how often the scheduler checks campaigns
+	Interval time.Duration
+
+	// WebhookURL receives a POST for every campaign status change; empty
+	// disables notifications
+	WebhookURL string
+
+	// WebhookSecret signs webhook bodies (X-Vector-Signature, HMAC-SHA256)
+	WebhookSecret string
+
+	// WebhookTimeout bounds a webhook delivery request
+	WebhookTimeout time.Duration
+}
+
+// AutomationConfig holds automation rule engine configuration
+type AutomationConfig struct {
+	// Enabled starts the engine that evaluates enabled rules
+	Enabled bool
+
+	// Interval is how often rules are evaluated
+	Interval time.Duration
+
+	// WebhookURL receives the notify actions of rules; empty disables them
+	WebhookURL string
+
+	// WebhookSecret signs webhook bodies (X-Vector-Signature, HMAC-SHA256)
+	WebhookSecret string
+
+	// WebhookTimeout bounds a webhook delivery request
+	WebhookTimeout time.Duration
+}
+
+// AlertsConfig holds delivery and health alerting configuration
+type AlertsConfig struct {
+	// Enabled starts the evaluator that checks enabled alert definitions
+	Enabled bool
+
+	// Interval is how often alert definitions are checked
+	Interval time.Duration
+
+	// Default channels, used by definitions without channels of their own
+	WebhookURL      string
+	SlackWebhookURL string
+	EmailTo         []string
+
+	// WebhookSecret signs webhook channel bodies (X-Vector-Signature,
+	// HMAC-SHA256)
+	WebhookSecret string
+
+	// Timeout bounds a webhook or Slack delivery request
+	Timeout time.Duration
+
+	// SMTP server for email channels (host:port); auth is skipped without a
+	// user
+	SMTPAddr     string
+	SMTPUser     string
+	SMTPPassword string
+	SMTPFrom     string
 }
 
 // Load reads configuration from environment variables with sensible defaults.
@@ -133,6 +422,17 @@ func Load() (*Config, error) {
 			Password: getEnv("VECTOR_DSP_REDIS_PASSWORD", ""),
 			DB:       getIntEnv("VECTOR_DSP_REDIS_DB", 0),
 		},
+		ClickHouse: ClickHouseConfig{
+			Enabled:        getBoolEnv("VECTOR_DSP_CLICKHOUSE_ENABLED", false),
+			Addr:           getEnv("VECTOR_DSP_CLICKHOUSE_ADDR", "localhost:9000"),
+			Database:       getEnv("VECTOR_DSP_CLICKHOUSE_DB", "vectordsp"),
+			User:           getEnv("VECTOR_DSP_CLICKHOUSE_USER", "default"),
+			Password:       getEnv("VECTOR_DSP_CLICKHOUSE_PASSWORD", ""),
+			BatchSize:      getIntEnv("VECTOR_DSP_CLICKHOUSE_BATCH_SIZE", 1000),
+			FlushInterval:  getDurationEnv("VECTOR_DSP_CLICKHOUSE_FLUSH_INTERVAL", 1*time.Second),
+			BufferSize:     getIntEnv("VECTOR_DSP_CLICKHOUSE_BUFFER_SIZE", 50000),
+			EnqueueTimeout: getDurationEnv("VECTOR_DSP_CLICKHOUSE_ENQUEUE_TIMEOUT", 100*time.Millisecond),
+		},
 		Auth: AuthConfig{
 			Enabled:   getBoolEnv("VECTOR_DSP_AUTH_ENABLED", true),
 			MasterKey: getEnv("VECTOR_DSP_API_KEY_MASTER", ""),
@@ -146,6 +446,12 @@ func Load() (*Config, error) {
 				"/postback",
 				"/s2s/",
 			}),
+			KeyCacheTTL:           getDurationEnv("VECTOR_DSP_AUTH_KEY_CACHE_TTL", 1*time.Minute),
+			LastUsedFlushInterval: getDurationEnv("VECTOR_DSP_AUTH_LAST_USED_FLUSH_INTERVAL", 1*time.Minute),
+			JWTSecret:             getEnv("VECTOR_DSP_AUTH_JWT_SECRET", ""),
+			AccessTokenTTL:        getDurationEnv("VECTOR_DSP_AUTH_ACCESS_TOKEN_TTL", 15*time.Minute),
+			RefreshTokenTTL:       getDurationEnv("VECTOR_DSP_AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			TOTPIssuer:            getEnv("VECTOR_DSP_AUTH_TOTP_ISSUER", "Vector DSP"),
 		},
 		RateLimit: RateLimitConfig{
 			Enabled:   getBoolEnv("VECTOR_DSP_RATE_LIMIT_ENABLED", true),
@@ -180,6 +486,107 @@ func Load() (*Config, error) {
 			MMPCallTimeout:     getDurationEnv("VECTOR_DSP_TRACKING_MMP_TIMEOUT", 5*time.Second),
 			EnableViewTracking: getBoolEnv("VECTOR_DSP_TRACKING_VIEW_ENABLED", true),
 			EnableClickDedup:   getBoolEnv("VECTOR_DSP_TRACKING_CLICK_DEDUP", true),
+			ClickDedupWindow:   getDurationEnv("VECTOR_DSP_TRACKING_CLICK_DEDUP_WINDOW", 1*time.Minute),
+			LinkSecret:         getEnv("VECTOR_DSP_TRACKING_LINK_SECRET", ""),
+			LinkTTL:            getDurationEnv("VECTOR_DSP_TRACKING_LINK_TTL", 7*24*time.Hour),
+		},
+		Currency: CurrencyConfig{
+			RatesSource:     getEnv("VECTOR_DSP_FX_RATES_SOURCE", "static"),
+			RatesFile:       getEnv("VECTOR_DSP_FX_RATES_FILE", "/app/data/exchange_rates.csv"),
+			RatesAPIURL:     getEnv("VECTOR_DSP_FX_RATES_API_URL", "https://api.frankfurter.app/{date}?from=USD"),
+			RatesAPITimeout: getDurationEnv("VECTOR_DSP_FX_RATES_API_TIMEOUT", 5*time.Second),
+			RepriceInterval: getDurationEnv("VECTOR_DSP_FX_REPRICE_INTERVAL", 5*time.Minute),
+		},
+		Fraud: FraudConfig{
+			Enabled:         getBoolEnv("VECTOR_DSP_FRAUD_ENABLED", true),
+			MinCTIT:         getDurationEnv("VECTOR_DSP_FRAUD_MIN_CTIT", 10*time.Second),
+			MaxClicksPerIFA: getIntEnv("VECTOR_DSP_FRAUD_MAX_CLICKS_PER_IFA", 20),
+			MaxClicksPerIP:  getIntEnv("VECTOR_DSP_FRAUD_MAX_CLICKS_PER_IP", 50),
+			ClickWindow:     getDurationEnv("VECTOR_DSP_FRAUD_CLICK_WINDOW", 1*time.Hour),
+			DataCenterFile:  getEnv("VECTOR_DSP_FRAUD_DATACENTER_FILE", "/app/data/datacenter_ranges.txt"),
+			FlagThreshold:   getIntEnv("VECTOR_DSP_FRAUD_FLAG_THRESHOLD", 50),
+		},
+		Sampling: SamplingConfig{
+			BidRate:      getFloatEnv("VECTOR_DSP_SAMPLING_BID_RATE", 0),
+			BidSources:   getSliceEnv("VECTOR_DSP_SAMPLING_BID_SOURCES", nil),
+			BidLineItems: getSliceEnv("VECTOR_DSP_SAMPLING_BID_LINE_ITEMS", nil),
+			BufferSize:   getIntEnv("VECTOR_DSP_SAMPLING_BUFFER_SIZE", 1000),
+		},
+		Reports: ReportsConfig{
+			Enabled:        getBoolEnv("VECTOR_DSP_REPORTS_ENABLED", true),
+			PollInterval:   getDurationEnv("VECTOR_DSP_REPORTS_POLL_INTERVAL", time.Minute),
+			MaxAttempts:    getIntEnv("VECTOR_DSP_REPORTS_MAX_ATTEMPTS", 5),
+			RetryBackoff:   getDurationEnv("VECTOR_DSP_REPORTS_RETRY_BACKOFF", 5*time.Minute),
+			WebhookTimeout: getDurationEnv("VECTOR_DSP_REPORTS_WEBHOOK_TIMEOUT", 30*time.Second),
+			OutputDir:      getEnv("VECTOR_DSP_REPORTS_OUTPUT_DIR", "./reports"),
+			SMTPAddr:       getEnv("VECTOR_DSP_REPORTS_SMTP_ADDR", "localhost:1025"),
+			SMTPUser:       getEnv("VECTOR_DSP_REPORTS_SMTP_USER", ""),
+			SMTPPassword:   getEnv("VECTOR_DSP_REPORTS_SMTP_PASSWORD", ""),
+			SMTPFrom:       getEnv("VECTOR_DSP_REPORTS_SMTP_FROM", "reports@vector-dsp.local"),
+			SFTPKeyFile:    getEnv("VECTOR_DSP_REPORTS_SFTP_KEY_FILE", ""),
+		},
+		Stats: StatsConfig{
+			RollupEnabled:  getBoolEnv("VECTOR_DSP_STATS_ROLLUP_ENABLED", true),
+			RollupInterval: getDurationEnv("VECTOR_DSP_STATS_ROLLUP_INTERVAL", 5*time.Minute),
+			LateWindow:     getDurationEnv("VECTOR_DSP_STATS_LATE_WINDOW", 72*time.Hour),
+			BackfillDays:   getIntEnv("VECTOR_DSP_STATS_BACKFILL_DAYS", 30),
+		},
+		Live: LiveConfig{
+			Enabled:        getBoolEnv("VECTOR_DSP_LIVE_ENABLED", true),
+			MaxSubscribers: getIntEnv("VECTOR_DSP_LIVE_MAX_SUBSCRIBERS", 100),
+			BufferFrames:   getIntEnv("VECTOR_DSP_LIVE_BUFFER_FRAMES", 10),
+		},
+		Billing: BillingConfig{
+			Enabled:       getBoolEnv("VECTOR_DSP_BILLING_ENABLED", false),
+			FlushInterval: getDurationEnv("VECTOR_DSP_BILLING_FLUSH_INTERVAL", 5*time.Second),
+		},
+		Invoicing: InvoicingConfig{
+			Enabled:             getBoolEnv("VECTOR_DSP_INVOICING_ENABLED", false),
+			GenerateDay:         getIntEnv("VECTOR_DSP_INVOICING_GENERATE_DAY", 1),
+			Timezone:            getEnv("VECTOR_DSP_INVOICING_TIMEZONE", "Europe/Moscow"),
+			VATRate:             getFloatEnv("VECTOR_DSP_INVOICING_VAT_RATE", 22),
+			VATIncluded:         getBoolEnv("VECTOR_DSP_INVOICING_VAT_INCLUDED", true),
+			NumberPrefix:        getEnv("VECTOR_DSP_INVOICING_NUMBER_PREFIX", ""),
+			TemplateDir:         getEnv("VECTOR_DSP_INVOICING_TEMPLATE_DIR", ""),
+			FontFile:            getEnv("VECTOR_DSP_INVOICING_FONT_FILE", "/usr/share/fonts/dejavu/DejaVuSans.ttf"),
+			SupplierName:        getEnv("VECTOR_DSP_INVOICING_SUPPLIER_NAME", ""),
+			SupplierTaxID:       getEnv("VECTOR_DSP_INVOICING_SUPPLIER_INN", ""),
+			SupplierKPP:         getEnv("VECTOR_DSP_INVOICING_SUPPLIER_KPP", ""),
+			SupplierOGRN:        getEnv("VECTOR_DSP_INVOICING_SUPPLIER_OGRN", ""),
+			SupplierAddress:     getEnv("VECTOR_DSP_INVOICING_SUPPLIER_ADDRESS", ""),
+			SupplierBankName:    getEnv("VECTOR_DSP_INVOICING_SUPPLIER_BANK", ""),
+			SupplierBIK:         getEnv("VECTOR_DSP_INVOICING_SUPPLIER_BIK", ""),
+			SupplierAccount:     getEnv("VECTOR_DSP_INVOICING_SUPPLIER_ACCOUNT", ""),
+			SupplierCorrAccount: getEnv("VECTOR_DSP_INVOICING_SUPPLIER_CORR_ACCOUNT", ""),
+			SupplierDirector:    getEnv("VECTOR_DSP_INVOICING_SUPPLIER_DIRECTOR", ""),
+			SupplierAccountant:  getEnv("VECTOR_DSP_INVOICING_SUPPLIER_ACCOUNTANT", ""),
+		},
+		Lifecycle: LifecycleConfig{
+			Enabled:        getBoolEnv("VECTOR_DSP_LIFECYCLE_ENABLED", true),
+			Interval:       getDurationEnv("VECTOR_DSP_LIFECYCLE_INTERVAL", time.Minute),
+			WebhookURL:     getEnv("VECTOR_DSP_LIFECYCLE_WEBHOOK_URL", ""),
+			WebhookSecret:  getEnv("VECTOR_DSP_LIFECYCLE_WEBHOOK_SECRET", ""),
+			WebhookTimeout: getDurationEnv("VECTOR_DSP_LIFECYCLE_WEBHOOK_TIMEOUT", 10*time.Second),
+		},
+		Automation: AutomationConfig{
+			Enabled:        getBoolEnv("VECTOR_DSP_AUTOMATION_ENABLED", true),
+			Interval:       getDurationEnv("VECTOR_DSP_AUTOMATION_INTERVAL", 15*time.Minute),
+			WebhookURL:     getEnv("VECTOR_DSP_AUTOMATION_WEBHOOK_URL", ""),
+			WebhookSecret:  getEnv("VECTOR_DSP_AUTOMATION_WEBHOOK_SECRET", ""),
+			WebhookTimeout: getDurationEnv("VECTOR_DSP_AUTOMATION_WEBHOOK_TIMEOUT", 10*time.Second),
+		},
+		Alerts: AlertsConfig{
+			Enabled:         getBoolEnv("VECTOR_DSP_ALERTS_ENABLED", true),
+			Interval:        getDurationEnv("VECTOR_DSP_ALERTS_INTERVAL", 5*time.Minute),
+			WebhookURL:      getEnv("VECTOR_DSP_ALERTS_WEBHOOK_URL", ""),
+			SlackWebhookURL: getEnv("VECTOR_DSP_ALERTS_SLACK_WEBHOOK_URL", ""),
+			EmailTo:         getSliceEnv("VECTOR_DSP_ALERTS_EMAIL_TO", nil),
+			WebhookSecret:   getEnv("VECTOR_DSP_ALERTS_WEBHOOK_SECRET", ""),
+			Timeout:         getDurationEnv("VECTOR_DSP_ALERTS_TIMEOUT", 10*time.Second),
+			SMTPAddr:        getEnv("VECTOR_DSP_ALERTS_SMTP_ADDR", "localhost:1025"),
+			SMTPUser:        getEnv("VECTOR_DSP_ALERTS_SMTP_USER", ""),
+			SMTPPassword:    getEnv("VECTOR_DSP_ALERTS_SMTP_PASSWORD", ""),
+			SMTPFrom:        getEnv("VECTOR_DSP_ALERTS_SMTP_FROM", "alerts@vector-dsp.local"),
 		},
 	}
 
@@ -195,6 +602,65 @@ func (c *Config) Validate() error {
 	if c.Auth.Enabled && c.Auth.MasterKey == "" {
 		return fmt.Errorf("VECTOR_DSP_API_KEY_MASTER is required when auth is enabled")
 	}
+	if c.Auth.JWTSecret != "" && len(c.Auth.JWTSecret) < 32 {
+		return fmt.Errorf("VECTOR_DSP_AUTH_JWT_SECRET must be at least 32 characters")
+	}
+	if c.Auth.JWTSecret != "" && (c.Auth.AccessTokenTTL <= 0 || c.Auth.RefreshTokenTTL < c.Auth.AccessTokenTTL) {
+		return fmt.Errorf("VECTOR_DSP_AUTH_ACCESS_TOKEN_TTL must be positive and not above VECTOR_DSP_AUTH_REFRESH_TOKEN_TTL")
+	}
+	if c.IsProduction() && c.Tracking.LinkSecret == "" {
+		return fmt.Errorf("VECTOR_DSP_TRACKING_LINK_SECRET is required in production")
+	}
+	if c.Sampling.BidRate < 0 || c.Sampling.BidRate > 100 {
+		return fmt.Errorf("VECTOR_DSP_SAMPLING_BID_RATE must be between 0 and 100")
+	}
+	if c.ClickHouse.Enabled && (c.ClickHouse.BatchSize <= 0 || c.ClickHouse.BufferSize < c.ClickHouse.BatchSize) {
+		return fmt.Errorf("VECTOR_DSP_CLICKHOUSE_BUFFER_SIZE must be at least VECTOR_DSP_CLICKHOUSE_BATCH_SIZE (> 0)")
+	}
+	if c.Reports.Enabled && (c.Reports.PollInterval <= 0 || c.Reports.MaxAttempts < 1) {
+		return fmt.Errorf("VECTOR_DSP_REPORTS_POLL_INTERVAL must be positive and VECTOR_DSP_REPORTS_MAX_ATTEMPTS at least 1")
+	}
+	if c.Stats.RollupEnabled && (c.Stats.RollupInterval <= 0 || c.Stats.LateWindow < 0 || c.Stats.BackfillDays < 0) {
+		return fmt.Errorf("VECTOR_DSP_STATS_ROLLUP_INTERVAL must be positive and VECTOR_DSP_STATS_LATE_WINDOW, VECTOR_DSP_STATS_BACKFILL_DAYS not negative")
+	}
+	if c.Live.Enabled && (c.Live.MaxSubscribers < 1 || c.Live.BufferFrames < 1) {
+		return fmt.Errorf("VECTOR_DSP_LIVE_MAX_SUBSCRIBERS and VECTOR_DSP_LIVE_BUFFER_FRAMES must be at least 1")
+	}
+	if c.Billing.Enabled && c.Billing.FlushInterval <= 0 {
+		return fmt.Errorf("VECTOR_DSP_BILLING_FLUSH_INTERVAL must be positive")
+	}
+	if c.Invoicing.VATRate < 0 || c.Invoicing.VATRate >= 100 {
+		return fmt.Errorf("VECTOR_DSP_INVOICING_VAT_RATE must be between 0 and 100")
+	}
+	if c.Invoicing.Enabled && (c.Invoicing.GenerateDay < 1 || c.Invoicing.GenerateDay > 28) {
+		return fmt.Errorf("VECTOR_DSP_INVOICING_GENERATE_DAY must be between 1 and 28")
+	}
+	if _, err := time.LoadLocation(c.Invoicing.Timezone); err != nil {
+		return fmt.Errorf("VECTOR_DSP_INVOICING_TIMEZONE: %w", err)
+	}
+	if c.Lifecycle.Enabled && c.Lifecycle.Interval <= 0 {
+		return fmt.Errorf("VECTOR_DSP_LIFECYCLE_INTERVAL must be positive")
+	}
+	if c.Lifecycle.WebhookURL != "" && !strings.HasPrefix(c.Lifecycle.WebhookURL, "http://") && !strings.HasPrefix(c.Lifecycle.WebhookURL, "https://") {
+		return fmt.Errorf("VECTOR_DSP_LIFECYCLE_WEBHOOK_URL must be an http(s) url")
+	}
+	if c.Automation.Enabled && c.Automation.Interval <= 0 {
+		return fmt.Errorf("VECTOR_DSP_AUTOMATION_INTERVAL must be positive")
+	}
+	if c.Automation.WebhookURL != "" && !strings.HasPrefix(c.Automation.WebhookURL, "http://") && !strings.HasPrefix(c.Automation.WebhookURL, "https://") {
+		return fmt.Errorf("VECTOR_DSP_AUTOMATION_WEBHOOK_URL must be an http(s) url")
+	}
+	if c.Alerts.Enabled && c.Alerts.Interval <= 0 {
+		return fmt.Errorf("VECTOR_DSP_ALERTS_INTERVAL must be positive")
+	}
+	for name, u := range map[string]string{
+		"VECTOR_DSP_ALERTS_WEBHOOK_URL":       c.Alerts.WebhookURL,
+		"VECTOR_DSP_ALERTS_SLACK_WEBHOOK_URL": c.Alerts.SlackWebhookURL,
+	} {
+		if u != "" && !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+			return fmt.Errorf("%s must be an http(s) url", name)
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,222 @@
+package currency
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ReportingCurrency is the currency all *_usd event fields are stored in.
+const ReportingCurrency = "USD"
+
+// dateLayout is the key format of the daily rates table.
+const dateLayout = "2006-01-02"
+
+// failureTTL is how long a failed fetch for a day is remembered before the
+// provider is asked again. Events on that day use the fallback rates (or
+// fail fast) in the meantime instead of each waiting for the provider.
+const failureTTL = time.Minute
+
+// RateProvider supplies daily exchange rates.
+// Rates are expressed as units of currency per one ReportingCurrency unit
+// (e.g. RUB: 92.5 means 1 USD = 92.5 RUB).
+type RateProvider interface {
+	FetchRates(ctx context.Context, date time.Time) (map[string]float64, error)
+}
+
+// Converter converts amounts between currencies using a daily rates table.
+type Converter struct {
+	provider RateProvider
+	logger   *zap.Logger
+
+	mu       sync.RWMutex
+	rates    map[string]map[string]float64 // date -> currency -> rate
+	failures map[string]*fetchFailure      // date -> last failed fetch
+	now      func() time.Time
+}
+
+// fetchFailure is a failed fetch for a day: the earlier day's rates used
+// instead (nil if there were none) and the error, until retryAt.
+type fetchFailure struct {
+	fallback map[string]float64
+	err      error
+	retryAt  time.Time
+}
+
+// NewConverter creates a new currency converter.
+func NewConverter(provider RateProvider, logger *zap.Logger) *Converter {
+	return &Converter{
+		provider: provider,
+		logger:   logger,
+		rates:    make(map[string]map[string]float64),
+		failures: make(map[string]*fetchFailure),
+		now:      time.Now,
+	}
+}
+
+// Normalize returns the upper-cased currency code, defaulting to ReportingCurrency.
+func Normalize(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return ReportingCurrency
+	}
+	return code
+}
+
+// Refresh loads rates for the given day from the provider.
+func (c *Converter) Refresh(ctx context.Context, date time.Time) error {
+	rates, err := c.provider.FetchRates(ctx, date)
+	if err != nil {
+		return fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	c.SetRates(date, rates)
+	return nil
+}
+
+// SetRates stores rates for the given day, replacing any existing entry.
+func (c *Converter) SetRates(date time.Time, rates map[string]float64) {
+	day := make(map[string]float64, len(rates)+1)
+	for code, rate := range rates {
+		if rate > 0 {
+			day[Normalize(code)] = rate
+		}
+	}
+	day[ReportingCurrency] = 1
+
+	key := date.UTC().Format(dateLayout)
+	c.mu.Lock()
+	c.rates[key] = day
+	delete(c.failures, key)
+	c.mu.Unlock()
+}
+
+// Rates returns a copy of the rates effective on the given day.
+func (c *Converter) Rates(ctx context.Context, date time.Time) (map[string]float64, error) {
+	day, err := c.ratesFor(ctx, date)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]float64, len(day))
+	for code, rate := range day {
+		out[code] = rate
+	}
+	return out, nil
+}
+
+// Rate returns how many units of "to" one unit of "from" buys at the given time.
+func (c *Converter) Rate(ctx context.Context, from, to string, at time.Time) (float64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return 1, nil
+	}
+
+	day, err := c.ratesFor(ctx, at)
+	if err != nil {
+		return 0, err
+	}
+
+	fromRate, ok := day[from]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s on %s", from, at.UTC().Format(dateLayout))
+	}
+	toRate, ok := day[to]
+	if !ok {
+		return 0, fmt.Errorf("no exchange rate for %s on %s", to, at.UTC().Format(dateLayout))
+	}
+
+	return toRate / fromRate, nil
+}
+
+// Convert converts amount from one currency to another at the given time.
+func (c *Converter) Convert(ctx context.Context, amount float64, from, to string, at time.Time) (float64, error) {
+	if amount == 0 {
+		return 0, nil
+	}
+	rate, err := c.Rate(ctx, from, to, at)
+	if err != nil {
+		return 0, err
+	}
+	return amount * rate, nil
+}
+
+// ToReporting converts amount into ReportingCurrency at the given time.
+func (c *Converter) ToReporting(ctx context.Context, amount float64, from string, at time.Time) (float64, error) {
+	return c.Convert(ctx, amount, from, ReportingCurrency, at)
+}
+
+// FromReporting converts a ReportingCurrency amount into another currency.
+func (c *Converter) FromReporting(ctx context.Context, amount float64, to string, at time.Time) (float64, error) {
+	return c.Convert(ctx, amount, ReportingCurrency, to, at)
+}
+
+// ratesFor returns the table entry for a day, fetching it on first use.
+// If the provider fails (weekends, outages) the latest earlier day is used;
+// the failure is cached for failureTTL so the provider isn't called again
+// for every event on that day.
+func (c *Converter) ratesFor(ctx context.Context, at time.Time) (map[string]float64, error) {
+	key := at.UTC().Format(dateLayout)
+
+	c.mu.RLock()
+	day, ok := c.rates[key]
+	failure := c.failures[key]
+	c.mu.RUnlock()
+	if ok {
+		return day, nil
+	}
+	if failure != nil && c.now().Before(failure.retryAt) {
+		return failure.result()
+	}
+
+	if err := c.Refresh(ctx, at); err != nil {
+		failure := &fetchFailure{
+			fallback: c.latestBefore(key),
+			err:      err,
+			retryAt:  c.now().Add(failureTTL),
+		}
+		c.mu.Lock()
+		c.failures[key] = failure
+		c.mu.Unlock()
+
+		if c.logger != nil {
+			c.logger.Warn("exchange rates unavailable, using previous day",
+				zap.String("date", key),
+				zap.Bool("fallback", failure.fallback != nil),
+				zap.Error(err),
+			)
+		}
+		return failure.result()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.rates[key], nil
+}
+
+func (f *fetchFailure) result() (map[string]float64, error) {
+	if f.fallback != nil {
+		return f.fallback, nil
+	}
+	return nil, f.err
+}
+
+func (c *Converter) latestBefore(key string) map[string]float64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	dates := make([]string, 0, len(c.rates))
+	for d := range c.rates {
+		if d <= key {
+			dates = append(dates, d)
+		}
+	}
+	if len(dates) == 0 {
+		return nil
+	}
+	sort.Strings(dates)
+	return c.rates[dates[len(dates)-1]]
+}
@@ -0,0 +1,168 @@
+package currency
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// stubProvider serves rates per day and counts fetches.
+type stubProvider struct {
+	days  map[string]map[string]float64
+	calls map[string]int
+}
+
+func newStubProvider(days map[string]map[string]float64) *stubProvider {
+	return &stubProvider{days: days, calls: make(map[string]int)}
+}
+
+func (p *stubProvider) FetchRates(ctx context.Context, date time.Time) (map[string]float64, error) {
+	key := date.UTC().Format(dateLayout)
+	p.calls[key]++
+	rates, ok := p.days[key]
+	if !ok {
+		return nil, errors.New("no rates published")
+	}
+	return rates, nil
+}
+
+func day(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return t.Add(12 * time.Hour)
+}
+
+func TestConvert(t *testing.T) {
+	provider := newStubProvider(map[string]map[string]float64{
+		"2024-05-03": {"EUR": 0.9, "RUB": 90},
+	})
+	c := NewConverter(provider, nil)
+
+	tests := []struct {
+		name     string
+		amount   float64
+		from, to string
+		at       time.Time
+		want     float64
+		wantErr  bool
+	}{
+		{name: "same currency", amount: 10, from: "RUB", to: "rub", at: day("2024-05-03"), want: 10},
+		{name: "zero amount", amount: 0, from: "XXX", to: "USD", at: day("2024-05-03"), want: 0},
+		{name: "to reporting", amount: 900, from: "RUB", to: "USD", at: day("2024-05-03"), want: 10},
+		{name: "from reporting", amount: 10, from: "", to: "EUR", at: day("2024-05-03"), want: 9},
+		{name: "cross rate", amount: 9, from: "EUR", to: "RUB", at: day("2024-05-03"), want: 900},
+		{name: "unknown currency", amount: 1, from: "GBP", to: "USD", at: day("2024-05-03"), wantErr: true},
+		{name: "falls back to previous day", amount: 900, from: "RUB", to: "USD", at: day("2024-05-04"), want: 10},
+		{name: "no earlier day", amount: 900, from: "RUB", to: "USD", at: day("2024-05-01"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := c.Convert(context.Background(), tt.amount, tt.from, tt.to, tt.at)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Convert() = %v, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Convert() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRatesForCachesFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		published map[string]map[string]float64
+		warm      string // Day fetched first, if any
+		at        string
+		wantRUB   float64 // 0 means an error is expected
+	}{
+		{
+			name:      "weekend uses friday",
+			published: map[string]map[string]float64{"2024-05-03": {"RUB": 90}},
+			warm:      "2024-05-03",
+			at:        "2024-05-04",
+			wantRUB:   90,
+		},
+		{
+			name:      "nothing published",
+			published: map[string]map[string]float64{},
+			at:        "2024-05-04",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newStubProvider(tt.published)
+			c := NewConverter(provider, nil)
+			now := day("2024-05-04")
+			c.now = func() time.Time { return now }
+			ctx := context.Background()
+
+			if tt.warm != "" {
+				if _, err := c.Rates(ctx, day(tt.warm)); err != nil {
+					t.Fatalf("Rates(%s) error = %v", tt.warm, err)
+				}
+			}
+
+			for i := 0; i < 3; i++ {
+				rates, err := c.Rates(ctx, day(tt.at))
+				if tt.wantRUB == 0 {
+					if err == nil {
+						t.Fatalf("Rates() = %v, want error", rates)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("Rates() error = %v", err)
+				}
+				if rates["RUB"] != tt.wantRUB {
+					t.Fatalf("RUB = %v, want %v", rates["RUB"], tt.wantRUB)
+				}
+			}
+			if got := provider.calls[tt.at]; got != 1 {
+				t.Errorf("provider called %d times for %s within the TTL, want 1", got, tt.at)
+			}
+
+			// After the TTL the provider is asked again and published
+			// rates replace the fallback.
+			now = now.Add(failureTTL)
+			provider.days[tt.at] = map[string]float64{"RUB": 95}
+			rates, err := c.Rates(ctx, day(tt.at))
+			if err != nil {
+				t.Fatalf("Rates() after TTL error = %v", err)
+			}
+			if rates["RUB"] != 95 {
+				t.Errorf("RUB after TTL = %v, want 95", rates["RUB"])
+			}
+			if got := provider.calls[tt.at]; got != 2 {
+				t.Errorf("provider called %d times for %s, want 2", got, tt.at)
+			}
+		})
+	}
+}
+
+func TestSetRatesClearsFailure(t *testing.T) {
+	c := NewConverter(newStubProvider(nil), nil)
+	ctx := context.Background()
+
+	if _, err := c.Rate(ctx, "RUB", "USD", day("2024-05-04")); err == nil {
+		t.Fatal("Rate() without rates succeeded")
+	}
+	c.SetRates(day("2024-05-04"), map[string]float64{"RUB": 100})
+	rate, err := c.Rate(ctx, "RUB", "USD", day("2024-05-04"))
+	if err != nil {
+		t.Fatalf("Rate() after SetRates error = %v", err)
+	}
+	if rate != 0.01 {
+		t.Errorf("Rate() = %v, want 0.01", rate)
+	}
+}
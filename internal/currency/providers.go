@@ -0,0 +1,194 @@
+package currency
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// =============================================
+// Static provider
+// =============================================
+
+// DefaultStaticRates are fallback rates used in development and tests.
+var DefaultStaticRates = map[string]float64{
+	"USD": 1,
+	"EUR": 0.92,
+	"RUB": 92.5,
+}
+
+// StaticRateProvider returns the same rates for every day.
+type StaticRateProvider struct {
+	rates map[string]float64
+}
+
+// NewStaticRateProvider creates a new static rate provider.
+// A nil map uses DefaultStaticRates.
+func NewStaticRateProvider(rates map[string]float64) *StaticRateProvider {
+	if rates == nil {
+		rates = DefaultStaticRates
+	}
+	return &StaticRateProvider{rates: rates}
+}
+
+// FetchRates returns the configured rates.
+func (p *StaticRateProvider) FetchRates(ctx context.Context, date time.Time) (map[string]float64, error) {
+	out := make(map[string]float64, len(p.rates))
+	for code, rate := range p.rates {
+		out[code] = rate
+	}
+	return out, nil
+}
+
+// =============================================
+// File provider
+// =============================================
+
+// FileRateProvider reads daily rates from a CSV file with rows of
+// "date,currency,rate" (e.g. "2024-05-01,RUB,91.78").
+// For a day missing from the file the latest earlier day is returned.
+type FileRateProvider struct {
+	rates map[string]map[string]float64
+	dates []string
+}
+
+// NewFileRateProvider loads a rates file.
+func NewFileRateProvider(path string) (*FileRateProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open rates file: %w", err)
+	}
+	defer f.Close()
+
+	p := &FileRateProvider{rates: make(map[string]map[string]float64)}
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.FieldsPerRecord = 3
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rates file: %w", err)
+		}
+
+		date := strings.TrimSpace(rec[0])
+		if _, err := time.Parse(dateLayout, date); err != nil {
+			// Header row or garbage
+			continue
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[2]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("invalid rate on %s for %s: %q", date, rec[1], rec[2])
+		}
+
+		day, ok := p.rates[date]
+		if !ok {
+			day = make(map[string]float64)
+			p.rates[date] = day
+			p.dates = append(p.dates, date)
+		}
+		day[Normalize(rec[1])] = rate
+	}
+
+	if len(p.dates) == 0 {
+		return nil, fmt.Errorf("rates file %s contains no rates", path)
+	}
+	sort.Strings(p.dates)
+
+	return p, nil
+}
+
+// FetchRates returns the rates effective on the given day.
+func (p *FileRateProvider) FetchRates(ctx context.Context, date time.Time) (map[string]float64, error) {
+	key := date.UTC().Format(dateLayout)
+	i := sort.SearchStrings(p.dates, key)
+	if i < len(p.dates) && p.dates[i] == key {
+		return p.rates[key], nil
+	}
+	if i == 0 {
+		return nil, fmt.Errorf("no rates on or before %s", key)
+	}
+	return p.rates[p.dates[i-1]], nil
+}
+
+// =============================================
+// HTTP API provider
+// =============================================
+
+// HTTPRateProvider fetches rates from an HTTP API returning
+// {"base": "EUR", "rates": {"USD": 1.08, "RUB": 99.1}}.
+// The URL may contain a {date} macro (YYYY-MM-DD); rates in any base are
+// rebased to ReportingCurrency.
+type HTTPRateProvider struct {
+	urlTemplate string
+	httpClient  *http.Client
+}
+
+// NewHTTPRateProvider creates a new HTTP rate provider.
+func NewHTTPRateProvider(urlTemplate string, timeout time.Duration) *HTTPRateProvider {
+	return &HTTPRateProvider{
+		urlTemplate: urlTemplate,
+		httpClient: &http.Client{
+			Timeout: timeout,
+		},
+	}
+}
+
+// FetchRates fetches and rebases rates for the given day.
+func (p *HTTPRateProvider) FetchRates(ctx context.Context, date time.Time) (map[string]float64, error) {
+	u := strings.ReplaceAll(p.urlTemplate, "{date}", date.UTC().Format(dateLayout))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call rates API: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rates API returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Base  string             `json:"base"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to decode rates response: %w", err)
+	}
+
+	base := Normalize(body.Base)
+	rates := make(map[string]float64, len(body.Rates)+1)
+	for code, rate := range body.Rates {
+		rates[Normalize(code)] = rate
+	}
+	rates[base] = 1
+
+	if base == ReportingCurrency {
+		return rates, nil
+	}
+
+	usd, ok := rates[ReportingCurrency]
+	if !ok || usd <= 0 {
+		return nil, fmt.Errorf("rates API response has no %s rate", ReportingCurrency)
+	}
+	for code, rate := range rates {
+		rates[code] = rate / usd
+	}
+	return rates, nil
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// ClickHouseDB wraps a ClickHouse native connection.
+type ClickHouseDB struct {
+	Conn driver.Conn
+}
+
+// NewClickHouseDB creates a new ClickHouse connection.
+func NewClickHouseDB(addr, database, user, password string) (*ClickHouseDB, error) {
+	conn, err := clickhouse.Open(&clickhouse.Options{
+		Addr: []string{addr},
+		Auth: clickhouse.Auth{
+			Database: database,
+			Username: user,
+			Password: password,
+		},
+		DialTimeout:     5 * time.Second,
+		MaxOpenConns:    10,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: time.Hour,
+		Compression: &clickhouse.Compression{
+			Method: clickhouse.CompressionLZ4,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := conn.Ping(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &ClickHouseDB{Conn: conn}, nil
+}
+
+// Close closes the ClickHouse connection.
+func (db *ClickHouseDB) Close() error {
+	if db.Conn != nil {
+		return db.Conn.Close()
+	}
+	return nil
+}
+
+// Ping checks the ClickHouse connection.
+func (db *ClickHouseDB) Ping(ctx context.Context) error {
+	return db.Conn.Ping(ctx)
+}
@@ -1,46 +1,48 @@
 package dsp
 
 import (
-    "time"
-    "github.com/radiusdt/vector-dsp/internal/models"
-    "github.com/radiusdt/vector-dsp/internal/storage"
+	"context"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
 )
 
 // AdGroupService provides CRUD operations over ad groups.
 // It manages timestamps and validation before delegating to the repository.
 type AdGroupService struct {
-    repo storage.AdGroupRepo
+	repo storage.AdGroupRepo
 }
 
 // NewAdGroupService constructs a new AdGroupService.
 func NewAdGroupService(repo storage.AdGroupRepo) *AdGroupService {
-    return &AdGroupService{repo: repo}
+	return &AdGroupService{repo: repo}
 }
 
 // ListAdGroups returns all ad groups.
-func (s *AdGroupService) ListAdGroups() ([]*models.AdGroup, error) {
-    return s.repo.ListAdGroups()
+func (s *AdGroupService) ListAdGroups(ctx context.Context) ([]*models.AdGroup, error) {
+	return s.repo.ListAll(ctx)
 }
 
 // ListAdGroupsByCampaign lists ad groups for a specific campaign.
-func (s *AdGroupService) ListAdGroupsByCampaign(campaignID string) ([]*models.AdGroup, error) {
-    return s.repo.ListAdGroupsByCampaign(campaignID)
+func (s *AdGroupService) ListAdGroupsByCampaign(ctx context.Context, campaignID string) ([]*models.AdGroup, error) {
+	return s.repo.ListByCampaign(ctx, campaignID)
 }
 
 // GetAdGroup returns an ad group by ID.
-func (s *AdGroupService) GetAdGroup(id string) (*models.AdGroup, error) {
-    return s.repo.GetAdGroup(id)
+func (s *AdGroupService) GetAdGroup(ctx context.Context, id string) (*models.AdGroup, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
 // UpsertAdGroup validates and stores an ad group with timestamp management.
-func (s *AdGroupService) UpsertAdGroup(g *models.AdGroup) error {
-    now := time.Now().UTC()
-    if g.CreatedAt.IsZero() {
-        g.CreatedAt = now
-    }
-    g.UpdatedAt = now
-    if err := g.Validate(); err != nil {
-        return err
-    }
-    return s.repo.UpsertAdGroup(g)
-}
\ No newline at end of file
+func (s *AdGroupService) UpsertAdGroup(ctx context.Context, g *models.AdGroup) error {
+	now := time.Now().UTC()
+	if g.CreatedAt.IsZero() {
+		g.CreatedAt = now
+	}
+	g.UpdatedAt = now
+	if err := g.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Upsert(ctx, g)
+}
@@ -1,42 +1,59 @@
 package dsp
 
 import (
-    "time"
-    "github.com/radiusdt/vector-dsp/internal/models"
-    "github.com/radiusdt/vector-dsp/internal/storage"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
 )
 
 // AdvertiserService provides CRUD operations over advertisers.
 // It wraps a repository and adds timestamp management and validation.
 type AdvertiserService struct {
-    repo storage.AdvertiserRepo
+	repo storage.AdvertiserRepo
 }
 
 // NewAdvertiserService constructs a new AdvertiserService.
 func NewAdvertiserService(repo storage.AdvertiserRepo) *AdvertiserService {
-    return &AdvertiserService{repo: repo}
+	return &AdvertiserService{repo: repo}
 }
 
 // ListAdvertisers returns all advertisers.
-func (s *AdvertiserService) ListAdvertisers() ([]*models.Advertiser, error) {
-    return s.repo.ListAdvertisers()
+func (s *AdvertiserService) ListAdvertisers(ctx context.Context) ([]*models.Advertiser, error) {
+	return s.repo.ListAll(ctx)
 }
 
 // GetAdvertiser returns advertiser by ID.
-func (s *AdvertiserService) GetAdvertiser(id string) (*models.Advertiser, error) {
-    return s.repo.GetAdvertiser(id)
+func (s *AdvertiserService) GetAdvertiser(ctx context.Context, id string) (*models.Advertiser, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
 // UpsertAdvertiser validates and saves an advertiser.  If CreatedAt is zero
-// it sets it to now.  UpdatedAt is always set to now.
-func (s *AdvertiserService) UpsertAdvertiser(a *models.Advertiser) error {
-    now := time.Now().UTC()
-    if a.CreatedAt.IsZero() {
-        a.CreatedAt = now
-    }
-    a.UpdatedAt = now
-    if err := a.Validate(); err != nil {
-        return err
-    }
-    return s.repo.UpsertAdvertiser(a)
-}
\ No newline at end of file
+// it sets it to now.  UpdatedAt is always set to now.  Balance is owned by
+// the billing ledger: the given value is replaced with the stored one (zero
+// for new advertisers), and the currency can't change while it's nonzero.
+func (s *AdvertiserService) UpsertAdvertiser(ctx context.Context, a *models.Advertiser) error {
+	now := time.Now().UTC()
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = now
+	}
+	a.UpdatedAt = now
+	if err := a.Validate(); err != nil {
+		return err
+	}
+	existing, err := s.repo.GetByID(ctx, a.ID)
+	if err != nil {
+		return err
+	}
+	a.Balance = 0
+	if existing != nil {
+		a.Balance = existing.Balance
+		if a.Balance != 0 && currency.Normalize(a.Currency) != currency.Normalize(existing.Currency) {
+			return errors.New("currency can't change while the balance is not zero")
+		}
+	}
+	return s.repo.Upsert(ctx, a)
+}
@@ -0,0 +1,743 @@
+package dsp
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/fraud"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ErrBuiltInAlert is returned when deleting a built-in alert definition.
+var ErrBuiltInAlert = errors.New("built-in alert definitions can't be deleted; disable them instead")
+
+// underdeliveryGraceHours is how far into the UTC day underdelivery is
+// first checked; early in the day the pacing target is too small to judge.
+const underdeliveryGraceHours = 2
+
+// builtInAlerts are created on startup unless they exist; they notify the
+// default channels.
+var builtInAlerts = []models.AlertDefinition{
+	{ID: "builtin-underdelivery", Name: "Underdelivery", Type: models.AlertUnderdelivery, Threshold: 50},
+	{ID: "builtin-zero-conversions", Name: "No conversions", Type: models.AlertZeroConversions, WindowHours: 6, MinVolume: 200},
+	{ID: "builtin-postback-errors", Name: "Postback errors", Type: models.AlertPostbackErrors, Threshold: 20, WindowHours: 1, MinVolume: 50},
+	{ID: "builtin-fraud-spike", Name: "Fraud spike", Type: models.AlertFraudSpike, Threshold: 30, WindowHours: 24, MinVolume: 200},
+	{ID: "builtin-budget-80", Name: "Budget 80% spent", Type: models.AlertBudget, Threshold: 80},
+	{ID: "builtin-budget-100", Name: "Budget spent", Type: models.AlertBudget, Threshold: 100},
+}
+
+// Alert notification events.
+const (
+	alertTriggered = "alert.triggered"
+	alertResolved  = "alert.resolved"
+	alertTest      = "alert.test"
+)
+
+// AlertService checks alert definitions for delivery and health anomalies
+// and notifies their channels. An anomaly is one open alert for as long as
+// checks find it: it is notified when found (again after a snooze or a
+// failed delivery, and every RenotifyHours if set) and when resolved.
+type AlertService struct {
+	repo       storage.AlertRepo
+	campaigns  *CampaignService
+	pacing     PacingEngine
+	reporting  *ReportingService
+	stats      storage.StatsRepo
+	fraud      *fraud.Scorer
+	postbacks  *PostbackMonitor
+	cfg        config.AlertsConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+	metrics    *metrics.Metrics
+
+	// mu serializes passes and snoozes, which both save alerts
+	mu sync.Mutex
+}
+
+// NewAlertService creates a new alert service.
+func NewAlertService(
+	repo storage.AlertRepo,
+	campaigns *CampaignService,
+	pacing PacingEngine,
+	reporting *ReportingService,
+	stats storage.StatsRepo,
+	fraudScorer *fraud.Scorer,
+	postbacks *PostbackMonitor,
+	cfg config.AlertsConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *AlertService {
+	return &AlertService{
+		repo:       repo,
+		campaigns:  campaigns,
+		pacing:     pacing,
+		reporting:  reporting,
+		stats:      stats,
+		fraud:      fraudScorer,
+		postbacks:  postbacks,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.Timeout},
+		logger:     logger,
+		metrics:    m,
+	}
+}
+
+// =============================================
+// Definitions
+// =============================================
+
+// ListDefinitions lists alert definitions, all when advertiserID is empty.
+func (s *AlertService) ListDefinitions(ctx context.Context, advertiserID string) ([]*models.AlertDefinition, error) {
+	return s.repo.ListDefinitions(ctx, advertiserID)
+}
+
+// GetDefinition returns an alert definition, or nil if it doesn't exist.
+func (s *AlertService) GetDefinition(ctx context.Context, id string) (*models.AlertDefinition, error) {
+	return s.repo.GetDefinition(ctx, id)
+}
+
+// SaveDefinition validates and saves an alert definition. Built-in
+// definitions stay built in and keep their type.
+func (s *AlertService) SaveDefinition(ctx context.Context, d *models.AlertDefinition) error {
+	existing, err := s.repo.GetDefinition(ctx, d.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get alert definition: %w", err)
+	}
+	d.BuiltIn = existing != nil && existing.BuiltIn
+	if d.BuiltIn && d.Type != existing.Type {
+		return models.ValidationErrors{{Field: "type", Message: "can't be changed for built-in definitions"}}
+	}
+	if err := d.Validate(); err != nil {
+		return err
+	}
+	return s.repo.UpsertDefinition(ctx, d)
+}
+
+// DeleteDefinition deletes an alert definition; its alerts are kept.
+func (s *AlertService) DeleteDefinition(ctx context.Context, id string) error {
+	d, err := s.repo.GetDefinition(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get alert definition: %w", err)
+	}
+	if d != nil && d.BuiltIn {
+		return ErrBuiltInAlert
+	}
+	return s.repo.DeleteDefinition(ctx, id)
+}
+
+// EnsureBuiltIns creates the built-in definitions that don't exist.
+func (s *AlertService) EnsureBuiltIns(ctx context.Context) error {
+	for _, b := range builtInAlerts {
+		existing, err := s.repo.GetDefinition(ctx, b.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get alert definition: %w", err)
+		}
+		if existing != nil {
+			continue
+		}
+		d := b
+		d.Enabled = true
+		d.BuiltIn = true
+		if err := s.repo.UpsertDefinition(ctx, &d); err != nil {
+			return fmt.Errorf("failed to create built-in alert definition %s: %w", d.ID, err)
+		}
+	}
+	return nil
+}
+
+// =============================================
+// Alerts
+// =============================================
+
+// Alerts lists alerts, newest first.
+func (s *AlertService) Alerts(ctx context.Context, filter storage.AlertFilter) ([]*models.Alert, error) {
+	list, err := s.repo.ListAlerts(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	return list, nil
+}
+
+// GetAlert returns an alert, or nil if it doesn't exist.
+func (s *AlertService) GetAlert(ctx context.Context, id string) (*models.Alert, error) {
+	return s.repo.GetAlert(ctx, id)
+}
+
+// SnoozeAlert mutes the notifications of an alert until until, or unmutes
+// them if until is nil. An open alert first found while snoozed is
+// notified when the snooze ends.
+func (s *AlertService) SnoozeAlert(ctx context.Context, id string, until *time.Time) (*models.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, err := s.repo.GetAlert(ctx, id)
+	if err != nil || a == nil {
+		return nil, err
+	}
+	a.SnoozedUntil = until
+	if err := s.repo.UpsertAlert(ctx, a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// =============================================
+// Checks
+// =============================================
+
+// Run checks enabled definitions every interval until ctx is cancelled,
+// after creating the built-in ones.
+func (s *AlertService) Run(ctx context.Context) {
+	if err := s.EnsureBuiltIns(ctx); err != nil {
+		s.logger.Error("failed to create built-in alert definitions", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Pass(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			s.logger.Error("alert pass failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass checks every enabled definition as of now. A definition whose check
+// fails is logged and keeps its alerts until the next pass.
+func (s *AlertService) Pass(ctx context.Context, now time.Time) error {
+	list, err := s.repo.ListDefinitions(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list alert definitions: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range list {
+		if !d.Enabled {
+			continue
+		}
+		if err := s.checkDefinition(ctx, d, now); err != nil {
+			s.logger.Error("failed to check alert definition", zap.String("definition_id", d.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// alertFinding is an anomaly found by one check.
+type alertFinding struct {
+	key          string
+	advertiserID string
+	campaignID   string
+	lineItemID   string
+	sourceID     string
+	mmp          string
+	value        float64
+	message      string
+}
+
+// checkDefinition runs the check of d and opens, updates and resolves its
+// alerts accordingly.
+func (s *AlertService) checkDefinition(ctx context.Context, d *models.AlertDefinition, now time.Time) error {
+	var findings []alertFinding
+	var err error
+	switch d.Type {
+	case models.AlertUnderdelivery:
+		findings, err = s.checkUnderdelivery(ctx, d, now)
+	case models.AlertZeroConversions:
+		findings, err = s.checkZeroConversions(ctx, d, now)
+	case models.AlertPostbackErrors:
+		findings = s.checkPostbackErrors(d, now)
+	case models.AlertFraudSpike:
+		findings = s.checkFraudSpike(d, now)
+	case models.AlertBudget:
+		findings, err = s.checkBudget(ctx, d, now)
+	}
+	if err != nil {
+		return err
+	}
+
+	open, err := s.repo.ListAlerts(ctx, storage.AlertFilter{DefinitionID: d.ID, Status: models.AlertOpen})
+	if err != nil {
+		return fmt.Errorf("failed to list open alerts: %w", err)
+	}
+	byKey := make(map[string]*models.Alert, len(open))
+	for _, a := range open {
+		byKey[a.Key] = a
+	}
+
+	for _, f := range findings {
+		a := byKey[f.key]
+		if a != nil {
+			delete(byKey, f.key)
+			a.Checks++
+		} else {
+			a = &models.Alert{
+				ID:           uuid.New().String(),
+				DefinitionID: d.ID,
+				Type:         d.Type,
+				Key:          f.key,
+				Status:       models.AlertOpen,
+				AdvertiserID: f.advertiserID,
+				CampaignID:   f.campaignID,
+				LineItemID:   f.lineItemID,
+				SourceID:     f.sourceID,
+				MMP:          f.mmp,
+				Checks:       1,
+				FirstSeenAt:  now,
+			}
+			if s.metrics != nil {
+				s.metrics.RecordAlertEvent(string(d.Type), "triggered")
+			}
+			s.logger.Warn("alert triggered",
+				zap.String("definition_id", d.ID),
+				zap.String("key", f.key),
+				zap.String("message", f.message),
+			)
+		}
+		a.DefinitionName = d.Name
+		a.Message = f.message
+		a.Value = roundAmount(f.value)
+		a.Threshold = d.Threshold
+		a.LastSeenAt = now
+
+		due := a.NotifiedAt == nil ||
+			(d.RenotifyHours > 0 && now.Sub(*a.NotifiedAt) >= time.Duration(d.RenotifyHours)*time.Hour)
+		if due && !alertMuted(d, a, now) && s.deliver(d, alertTriggered, a) {
+			notified := now
+			a.NotifiedAt = &notified
+		}
+		if err := s.repo.UpsertAlert(ctx, a); err != nil {
+			return fmt.Errorf("failed to save alert: %w", err)
+		}
+	}
+
+	// Open alerts the check no longer finds are over. Only alerts someone
+	// was told about are notified.
+	for _, a := range byKey {
+		resolved := now
+		a.Status = models.AlertResolved
+		a.ResolvedAt = &resolved
+		if a.NotifiedAt != nil && !alertMuted(d, a, now) {
+			s.deliver(d, alertResolved, a)
+		}
+		if err := s.repo.UpsertAlert(ctx, a); err != nil {
+			return fmt.Errorf("failed to save alert: %w", err)
+		}
+		if s.metrics != nil {
+			s.metrics.RecordAlertEvent(string(d.Type), "resolved")
+		}
+		s.logger.Info("alert resolved", zap.String("definition_id", d.ID), zap.String("key", a.Key))
+	}
+	return nil
+}
+
+// alertMuted reports whether a's notifications are snoozed at now, on the
+// alert or on its definition.
+func alertMuted(d *models.AlertDefinition, a *models.Alert, now time.Time) bool {
+	return a.Snoozed(now) || (d.SnoozedUntil != nil && now.Before(*d.SnoozedUntil))
+}
+
+// alertCampaigns returns the campaigns d selects with one of statuses.
+func (s *AlertService) alertCampaigns(ctx context.Context, d *models.AlertDefinition, statuses ...models.CampaignStatus) ([]*models.Campaign, error) {
+	list, err := s.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	var result []*models.Campaign
+	for _, c := range list {
+		if (d.AdvertiserID != "" && c.AdvertiserID != d.AdvertiserID) ||
+			(len(d.CampaignIDs) > 0 && !containsString(d.CampaignIDs, c.ID)) {
+			continue
+		}
+		for _, st := range statuses {
+			if c.Status == st {
+				result = append(result, c)
+				break
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// checkUnderdelivery finds active line items whose spend today is below
+// Threshold% of their pacing target. Line items of campaigns or pacing
+// windows that started today aren't checked, as they missed part of the
+// day.
+func (s *AlertService) checkUnderdelivery(ctx context.Context, d *models.AlertDefinition, now time.Time) ([]alertFinding, error) {
+	dayStart := now.Truncate(24 * time.Hour)
+	hours := now.Sub(dayStart).Hours()
+	if hours < underdeliveryGraceHours || s.pacing == nil {
+		return nil, nil
+	}
+	campaigns, err := s.alertCampaigns(ctx, d, models.CampaignStatusActive)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []alertFinding
+	for _, c := range campaigns {
+		if c.StartDate.After(dayStart) {
+			continue
+		}
+		for i := range c.LineItems {
+			li := &c.LineItems[i]
+			if !li.IsActive || li.Pacing.DailyBudget <= 0 || li.Pacing.StartAt.After(dayStart) ||
+				(!li.Pacing.EndAt.IsZero() && li.Pacing.EndAt.Before(now)) {
+				continue
+			}
+			target := pacingTarget(li.Pacing, hours)
+			stats, err := s.pacing.GetStats(li.ID)
+			if err != nil || stats == nil || target <= 0 {
+				continue
+			}
+			pct := stats.DailySpend / target * 100
+			if pct >= d.Threshold {
+				continue
+			}
+			findings = append(findings, alertFinding{
+				key:          "line_item:" + li.ID,
+				advertiserID: c.AdvertiserID,
+				campaignID:   c.ID,
+				lineItemID:   li.ID,
+				value:        pct,
+				message: fmt.Sprintf("line item %s of campaign %s spent $%.2f today, %.0f%% of its $%.2f pacing target",
+					li.ID, c.ID, stats.DailySpend, pct, target),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// checkZeroConversions finds active campaigns with at least MinVolume
+// clicks (at least one) and no conversions over the window.
+func (s *AlertService) checkZeroConversions(ctx context.Context, d *models.AlertDefinition, now time.Time) ([]alertFinding, error) {
+	campaigns, err := s.alertCampaigns(ctx, d, models.CampaignStatusActive)
+	if err != nil || len(campaigns) == 0 {
+		return nil, err
+	}
+	byID := make(map[string]*models.Campaign, len(campaigns))
+	filter := ReportFilter{
+		StartDate: now.Add(-time.Duration(d.WindowHours) * time.Hour),
+		EndDate:   now,
+		GroupBy:   []string{storage.ReportDimCampaign},
+		Metrics:   []string{storage.ReportMetricClicks, storage.ReportMetricConversions},
+		Limit:     storage.MaxReportLimit,
+	}
+	for _, c := range campaigns {
+		byID[c.ID] = c
+		filter.CampaignIDs = append(filter.CampaignIDs, c.ID)
+	}
+
+	minClicks := float64(d.MinVolume)
+	if minClicks < 1 {
+		minClicks = 1
+	}
+	var findings []alertFinding
+	for {
+		result, err := s.reporting.Report(ctx, filter)
+		if err != nil {
+			return nil, fmt.Errorf("failed to run conversion report: %w", err)
+		}
+		for _, row := range result.Rows {
+			c := byID[row.Dimensions[storage.ReportDimCampaign]]
+			clicks := row.Metrics[storage.ReportMetricClicks]
+			if c == nil || clicks < minClicks || row.Metrics[storage.ReportMetricConversions] > 0 {
+				continue
+			}
+			findings = append(findings, alertFinding{
+				key:          "campaign:" + c.ID,
+				advertiserID: c.AdvertiserID,
+				campaignID:   c.ID,
+				value:        clicks,
+				message:      fmt.Sprintf("campaign %s had %.0f clicks and no conversions in the last %dh", c.ID, clicks, d.WindowHours),
+			})
+		}
+		filter.Offset += len(result.Rows)
+		if len(result.Rows) == 0 || int64(filter.Offset) >= result.Total {
+			break
+		}
+	}
+	return findings, nil
+}
+
+// checkPostbackErrors finds MMPs with at least MinVolume postbacks over
+// the window of which more than Threshold% failed.
+func (s *AlertService) checkPostbackErrors(d *models.AlertDefinition, now time.Time) []alertFinding {
+	if s.postbacks == nil {
+		return nil
+	}
+	var findings []alertFinding
+	for _, c := range s.postbacks.Counts(now.Add(-time.Duration(d.WindowHours) * time.Hour)) {
+		if c.Total < d.MinVolume {
+			continue
+		}
+		rate := float64(c.Failed) / float64(c.Total) * 100
+		if rate <= d.Threshold {
+			continue
+		}
+		findings = append(findings, alertFinding{
+			key:     "mmp:" + c.MMP,
+			mmp:     c.MMP,
+			value:   rate,
+			message: fmt.Sprintf("%d of %d %s postbacks failed in the last %dh (%.1f%%)", c.Failed, c.Total, c.MMP, d.WindowHours, rate),
+		})
+	}
+	return findings
+}
+
+// checkFraudSpike finds sources with at least MinVolume clicks over the
+// days of the window of which more than Threshold% of clicks or
+// conversions were flagged.
+func (s *AlertService) checkFraudSpike(d *models.AlertDefinition, now time.Time) []alertFinding {
+	if s.fraud == nil {
+		return nil
+	}
+	totals := make(map[string]*fraud.SourceStats)
+	var keys []string
+	for _, st := range s.fraud.SourceReport(now.Add(-time.Duration(d.WindowHours)*time.Hour), now) {
+		key := st.SourceType + ":" + st.SourceID
+		t := totals[key]
+		if t == nil {
+			t = &fraud.SourceStats{SourceType: st.SourceType, SourceID: st.SourceID}
+			totals[key] = t
+			keys = append(keys, key)
+		}
+		t.Clicks += st.Clicks
+		t.FlaggedClicks += st.FlaggedClicks
+		t.Conversions += st.Conversions
+		t.FlaggedConversions += st.FlaggedConversions
+	}
+	sort.Strings(keys)
+
+	var findings []alertFinding
+	for _, key := range keys {
+		t := totals[key]
+		if t.Clicks == 0 || t.Clicks < d.MinVolume {
+			continue
+		}
+		clickRate := float64(t.FlaggedClicks) / float64(t.Clicks) * 100
+		convRate := 0.0
+		if t.Conversions > 0 {
+			convRate = float64(t.FlaggedConversions) / float64(t.Conversions) * 100
+		}
+		if clickRate <= d.Threshold && convRate <= d.Threshold {
+			continue
+		}
+		findings = append(findings, alertFinding{
+			key:      "source:" + key,
+			sourceID: t.SourceID,
+			value:    maxFloat(clickRate, convRate),
+			message: fmt.Sprintf("%s source %s: %.1f%% of %d clicks and %.1f%% of %d conversions flagged as fraud",
+				t.SourceType, t.SourceID, clickRate, t.Clicks, convRate, t.Conversions),
+		})
+	}
+	return findings
+}
+
+// checkBudget finds campaigns that spent at least Threshold% of their
+// total budget. Ended campaigns are still checked, so their alerts stay
+// open until they are archived.
+func (s *AlertService) checkBudget(ctx context.Context, d *models.AlertDefinition, now time.Time) ([]alertFinding, error) {
+	if s.stats == nil {
+		return nil, nil
+	}
+	campaigns, err := s.alertCampaigns(ctx, d, models.CampaignStatusScheduled, models.CampaignStatusActive,
+		models.CampaignStatusPaused, models.CampaignStatusEnded)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []alertFinding
+	for _, c := range campaigns {
+		if c.TotalBudget <= 0 {
+			continue
+		}
+		agg, err := s.stats.GetCampaignStats(ctx, c.ID, time.Time{}, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign spend: %w", err)
+		}
+		if agg == nil {
+			continue
+		}
+		pct := agg.Spend / c.TotalBudget * 100
+		if pct < d.Threshold {
+			continue
+		}
+		findings = append(findings, alertFinding{
+			key:          "campaign:" + c.ID,
+			advertiserID: c.AdvertiserID,
+			campaignID:   c.ID,
+			value:        pct,
+			message:      fmt.Sprintf("campaign %s spent $%.2f of its $%.2f total budget (%.0f%%)", c.ID, agg.Spend, c.TotalBudget, pct),
+		})
+	}
+	return findings, nil
+}
+
+func maxFloat(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// =============================================
+// Notifications
+// =============================================
+
+// TestDefinition sends a test notification to the channels of d.
+func (s *AlertService) TestDefinition(d *models.AlertDefinition) error {
+	channels := s.channels(d)
+	if len(channels) == 0 {
+		return errors.New("no channels: the definition has none and no default channel is configured")
+	}
+	now := time.Now().UTC()
+	a := &models.Alert{
+		ID:             "test",
+		DefinitionID:   d.ID,
+		DefinitionName: d.Name,
+		Type:           d.Type,
+		Key:            "test",
+		Status:         models.AlertOpen,
+		Message:        "test notification of alert definition " + d.ID,
+		Threshold:      d.Threshold,
+		Checks:         1,
+		FirstSeenAt:    now,
+		LastSeenAt:     now,
+	}
+	var failed []string
+	for _, ch := range channels {
+		if err := s.send(ch, alertTest, a); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", ch.Type, err))
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// channels returns the channels d notifies: its own or the defaults.
+func (s *AlertService) channels(d *models.AlertDefinition) []models.AlertChannel {
+	if len(d.Channels) > 0 {
+		return d.Channels
+	}
+	var defaults []models.AlertChannel
+	if s.cfg.WebhookURL != "" {
+		defaults = append(defaults, models.AlertChannel{Type: models.AlertChannelWebhook, URL: s.cfg.WebhookURL})
+	}
+	if s.cfg.SlackWebhookURL != "" {
+		defaults = append(defaults, models.AlertChannel{Type: models.AlertChannelSlack, URL: s.cfg.SlackWebhookURL})
+	}
+	if len(s.cfg.EmailTo) > 0 {
+		defaults = append(defaults, models.AlertChannel{Type: models.AlertChannelEmail, Recipients: s.cfg.EmailTo})
+	}
+	return defaults
+}
+
+// deliver sends event of a to every channel of d and reports whether any
+// delivery succeeded. Failures are logged.
+func (s *AlertService) deliver(d *models.AlertDefinition, event string, a *models.Alert) bool {
+	sent := false
+	for _, ch := range s.channels(d) {
+		err := s.send(ch, event, a)
+		result := "ok"
+		if err != nil {
+			result = "error"
+			s.logger.Error("failed to deliver alert notification",
+				zap.String("alert_id", a.ID),
+				zap.String("channel", string(ch.Type)),
+				zap.Error(err),
+			)
+		}
+		if s.metrics != nil {
+			s.metrics.RecordAlertNotification(string(ch.Type), result)
+		}
+		sent = sent || err == nil
+	}
+	return sent
+}
+
+// send delivers one notification to ch.
+func (s *AlertService) send(ch models.AlertChannel, event string, a *models.Alert) error {
+	switch ch.Type {
+	case models.AlertChannelWebhook:
+		return postWebhook(s.httpClient, ch.URL, s.cfg.WebhookSecret, models.AlertNotification{Event: event, Alert: *a})
+	case models.AlertChannelSlack:
+		return postWebhook(s.httpClient, ch.URL, "", map[string]string{"text": alertText(event, a)})
+	case models.AlertChannelEmail:
+		return s.sendEmail(ch.Recipients, alertText(event, a), alertEmailBody(event, a))
+	}
+	return fmt.Errorf("unknown channel type %q", ch.Type)
+}
+
+// alertText is the one-line summary of a notification.
+func alertText(event string, a *models.Alert) string {
+	prefix := "[ALERT]"
+	switch event {
+	case alertResolved:
+		prefix = "[RESOLVED]"
+	case alertTest:
+		prefix = "[TEST]"
+	}
+	return fmt.Sprintf("%s %s: %s", prefix, a.DefinitionName, a.Message)
+}
+
+// alertEmailBody is the text of alert emails.
+func alertEmailBody(event string, a *models.Alert) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\r\n\r\n", alertText(event, a))
+	fmt.Fprintf(&b, "Alert:      %s\r\n", a.ID)
+	fmt.Fprintf(&b, "Definition: %s (%s)\r\n", a.DefinitionName, a.DefinitionID)
+	fmt.Fprintf(&b, "Type:       %s\r\n", a.Type)
+	fmt.Fprintf(&b, "Value:      %g (threshold %g)\r\n", a.Value, a.Threshold)
+	fmt.Fprintf(&b, "First seen: %s\r\n", a.FirstSeenAt.UTC().Format(time.RFC3339))
+	if a.ResolvedAt != nil {
+		fmt.Fprintf(&b, "Resolved:   %s\r\n", a.ResolvedAt.UTC().Format(time.RFC3339))
+	}
+	return b.String()
+}
+
+// sendEmail sends a plain text email through the configured SMTP server.
+func (s *AlertService) sendEmail(to []string, subject, text string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", s.cfg.SMTPFrom)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: <%s@vector-dsp>\r\n", uuid.New().String())
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(text)
+
+	var auth smtp.Auth
+	if s.cfg.SMTPUser != "" {
+		host, _, _ := net.SplitHostPort(s.cfg.SMTPAddr)
+		auth = smtp.PlainAuth("", s.cfg.SMTPUser, s.cfg.SMTPPassword, host)
+	}
+	if err := smtp.SendMail(s.cfg.SMTPAddr, auth, s.cfg.SMTPFrom, to, msg.Bytes()); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err)
+	}
+	return nil
+}
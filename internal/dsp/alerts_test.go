@@ -0,0 +1,195 @@
+package dsp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// alertRecorder is a webhook channel that records notification events and
+// answers with status.
+type alertRecorder struct {
+	mu     sync.Mutex
+	status int
+	events []string
+}
+
+func (r *alertRecorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var n models.AlertNotification
+	json.NewDecoder(req.Body).Decode(&n)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, n.Event)
+	w.WriteHeader(r.status)
+}
+
+// take returns the recorded events and sets the status of later requests.
+func (r *alertRecorder) take(status int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := r.events
+	r.events = nil
+	r.status = status
+	return events
+}
+
+// alertStep is one pass of the budget check.
+type alertStep struct {
+	after      time.Duration // Since the first pass
+	spend      float64       // Campaign spend of a $100 budget
+	snoozeFor  time.Duration // Snooze the open alert this long before the pass
+	failing    bool          // The webhook fails during the pass
+	wantEvents []string
+	wantStatus models.AlertStatus // Of the newest alert
+	wantAlerts int
+	wantChecks int // Of the newest alert; 0 skips the check
+}
+
+func TestAlertDedupAndSnooze(t *testing.T) {
+	start := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		renotifyHours int
+		defSnooze     time.Duration // Definition snoozed this long from the first pass
+		steps         []alertStep
+	}{
+		{
+			name: "one alert while the anomaly lasts",
+			steps: []alertStep{
+				{after: 0, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1, wantChecks: 1},
+				{after: time.Hour, spend: 95, wantStatus: models.AlertOpen, wantAlerts: 1, wantChecks: 2},
+				{after: 2 * time.Hour, spend: 50, wantEvents: []string{alertResolved}, wantStatus: models.AlertResolved, wantAlerts: 1},
+				{after: 3 * time.Hour, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 2, wantChecks: 1},
+			},
+		},
+		{
+			name:          "renotified every RenotifyHours",
+			renotifyHours: 2,
+			steps: []alertStep{
+				{after: 0, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: time.Hour, spend: 90, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: 2 * time.Hour, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1, wantChecks: 3},
+			},
+		},
+		{
+			name:      "definition snooze delays the first notification",
+			defSnooze: time.Hour,
+			steps: []alertStep{
+				{after: 0, spend: 90, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: 30 * time.Minute, spend: 90, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: time.Hour, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: 2 * time.Hour, spend: 90, wantStatus: models.AlertOpen, wantAlerts: 1},
+			},
+		},
+		{
+			name: "resolution while snoozed is not notified",
+			steps: []alertStep{
+				{after: 0, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: time.Hour, spend: 50, snoozeFor: 4 * time.Hour, wantStatus: models.AlertResolved, wantAlerts: 1},
+			},
+		},
+		{
+			name: "never notified alert resolves silently",
+			steps: []alertStep{
+				{after: 0, spend: 90, failing: true, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: time.Hour, spend: 50, wantStatus: models.AlertResolved, wantAlerts: 1},
+			},
+		},
+		{
+			name: "failed delivery is retried on the next pass",
+			steps: []alertStep{
+				{after: 0, spend: 90, failing: true, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: time.Hour, spend: 90, wantEvents: []string{alertTriggered}, wantStatus: models.AlertOpen, wantAlerts: 1},
+				{after: 2 * time.Hour, spend: 90, wantStatus: models.AlertOpen, wantAlerts: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			rec := &alertRecorder{status: http.StatusOK}
+			srv := httptest.NewServer(rec)
+			defer srv.Close()
+
+			campaigns := NewCampaignService(storage.NewInMemoryCampaignRepo())
+			if err := campaigns.UpsertCampaign(ctx, &models.Campaign{
+				ID: "cmp-1", Name: "Campaign", AdvertiserID: "adv-1",
+				Status: models.CampaignStatusActive, TotalBudget: 100,
+			}); err != nil {
+				t.Fatalf("UpsertCampaign() error = %v", err)
+			}
+			stats := storage.NewInMemoryStatsRepo()
+			repo := storage.NewInMemoryAlertRepo()
+			d := &models.AlertDefinition{
+				ID: "budget", Name: "Budget", Type: models.AlertBudget, Enabled: true,
+				Threshold: 80, RenotifyHours: tt.renotifyHours,
+				Channels: []models.AlertChannel{{Type: models.AlertChannelWebhook, URL: srv.URL}},
+			}
+			if tt.defSnooze > 0 {
+				until := start.Add(tt.defSnooze)
+				d.SnoozedUntil = &until
+			}
+			svc := NewAlertService(repo, campaigns, nil, nil, stats, nil, nil,
+				config.AlertsConfig{Timeout: time.Second}, zap.NewNop(), nil)
+			if err := svc.SaveDefinition(ctx, d); err != nil {
+				t.Fatalf("SaveDefinition() error = %v", err)
+			}
+
+			for i, step := range tt.steps {
+				now := start.Add(step.after)
+				if err := stats.UpsertDailyStats(ctx, &storage.DailyStats{Date: start, CampaignID: "cmp-1", Spend: step.spend}); err != nil {
+					t.Fatalf("step %d: UpsertDailyStats() error = %v", i, err)
+				}
+				if step.snoozeFor > 0 {
+					open, _ := svc.Alerts(ctx, storage.AlertFilter{Status: models.AlertOpen})
+					until := now.Add(step.snoozeFor)
+					for _, a := range open {
+						if _, err := svc.SnoozeAlert(ctx, a.ID, &until); err != nil {
+							t.Fatalf("step %d: SnoozeAlert() error = %v", i, err)
+						}
+					}
+				}
+				status := http.StatusOK
+				if step.failing {
+					status = http.StatusInternalServerError
+				}
+				rec.take(status)
+
+				if err := svc.Pass(ctx, now); err != nil {
+					t.Fatalf("step %d: Pass() error = %v", i, err)
+				}
+
+				if events := rec.take(http.StatusOK); !reflect.DeepEqual(events, step.wantEvents) {
+					t.Errorf("step %d: notified %v, want %v", i, events, step.wantEvents)
+				}
+				alerts, err := svc.Alerts(ctx, storage.AlertFilter{DefinitionID: d.ID})
+				if err != nil {
+					t.Fatalf("step %d: Alerts() error = %v", i, err)
+				}
+				if len(alerts) != step.wantAlerts {
+					t.Fatalf("step %d: %d alerts, want %d", i, len(alerts), step.wantAlerts)
+				}
+				newest := alerts[0]
+				if newest.Status != step.wantStatus {
+					t.Errorf("step %d: status = %s, want %s", i, newest.Status, step.wantStatus)
+				}
+				if step.wantChecks > 0 && newest.Checks != step.wantChecks {
+					t.Errorf("step %d: checks = %d, want %d", i, newest.Checks, step.wantChecks)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package dsp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidAPIKey wraps API key validation errors.
+	ErrInvalidAPIKey = errors.New("invalid api key request")
+
+	// ErrAPIKeyNotFound is returned for unknown API key IDs.
+	ErrAPIKeyNotFound = errors.New("api key not found")
+)
+
+// APIKeyPrefix starts every generated API key so leaked keys are easy to
+// search for.
+const APIKeyPrefix = "vdsp_"
+
+// maxUnknownKeys bounds the cache of hashes that matched no key, so random
+// keys cannot grow it without limit.
+const maxUnknownKeys = 10000
+
+// HashAPIKey returns the hex SHA-256 hash stored for key.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedAPIKey is a looked-up key; key is nil if the hash matched none.
+type cachedAPIKey struct {
+	key      *models.APIKey
+	loadedAt time.Time
+}
+
+// APIKeyService authenticates per-advertiser API keys. Lookups are cached
+// for KeyCacheTTL, so revoking a key through the API takes effect at once
+// on this instance and within the TTL on others. Uses are collected in
+// memory and written to last_used_at every LastUsedFlushInterval by Run.
+type APIKeyService struct {
+	repo        storage.APIKeyRepo
+	advertisers *AdvertiserService
+	cfg         config.AuthConfig
+	logger      *zap.Logger
+
+	mu      sync.Mutex
+	cache   map[string]*cachedAPIKey // hash -> key
+	unknown int                      // Cached hashes without a key
+	used    map[string]time.Time     // key ID -> last use not yet written
+}
+
+// NewAPIKeyService creates a new API key service.
+func NewAPIKeyService(repo storage.APIKeyRepo, advertisers *AdvertiserService, cfg config.AuthConfig, logger *zap.Logger) *APIKeyService {
+	return &APIKeyService{
+		repo:        repo,
+		advertisers: advertisers,
+		cfg:         cfg,
+		logger:      logger,
+		cache:       make(map[string]*cachedAPIKey),
+		used:        make(map[string]time.Time),
+	}
+}
+
+// Authenticate returns the API key matching key, or nil if there is none.
+// Revoked and expired keys are returned too; callers check Active.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	hash := HashAPIKey(key)
+	now := time.Now()
+
+	s.mu.Lock()
+	c, ok := s.cache[hash]
+	s.mu.Unlock()
+	if !ok || now.Sub(c.loadedAt) >= s.cfg.KeyCacheTTL {
+		k, err := s.repo.GetByHash(ctx, hash)
+		if err != nil {
+			return nil, err
+		}
+		c = &cachedAPIKey{key: k, loadedAt: now}
+		s.store(hash, c)
+	}
+
+	if c.key != nil && c.key.Active(now) {
+		s.mu.Lock()
+		s.used[c.key.ID] = now
+		s.mu.Unlock()
+	}
+	return c.key, nil
+}
+
+// store caches a lookup, dropping the unknown hashes when there are too many.
+func (s *APIKeyService) store(hash string, c *cachedAPIKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if old, ok := s.cache[hash]; ok && old.key == nil {
+		s.unknown--
+	}
+	if c.key == nil {
+		if s.unknown >= maxUnknownKeys {
+			for h, e := range s.cache {
+				if e.key == nil {
+					delete(s.cache, h)
+				}
+			}
+			s.unknown = 0
+		}
+		s.unknown++
+	}
+	s.cache[hash] = c
+}
+
+// invalidate drops the cached lookup of key ID.
+func (s *APIKeyService) invalidate(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for h, e := range s.cache {
+		if e.key != nil && e.key.ID == id {
+			delete(s.cache, h)
+		}
+	}
+}
+
+// =============================================
+// Management
+// =============================================
+
+// Create stores a new key and returns it with the generated key, which is
+// not stored and cannot be retrieved again.
+func (s *APIKeyService) Create(ctx context.Context, k *models.APIKey) (*models.APIKey, string, error) {
+	if err := k.Validate(); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidAPIKey, err)
+	}
+	now := time.Now()
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKey)
+	}
+	if k.AdvertiserID != "" {
+		adv, err := s.advertisers.GetAdvertiser(ctx, k.AdvertiserID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get advertiser: %w", err)
+		}
+		if adv == nil {
+			return nil, "", fmt.Errorf("%w: %s", ErrAdvertiserNotFound, k.AdvertiserID)
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	raw := APIKeyPrefix + hex.EncodeToString(secret)
+
+	key := *k
+	key.ID = uuid.New().String()
+	key.KeyHash = HashAPIKey(raw)
+	key.Status = models.APIKeyActive
+	key.LastUsedAt = nil
+	key.CreatedAt = now
+	if err := s.repo.Create(ctx, &key); err != nil {
+		return nil, "", err
+	}
+
+	// Drop a cached miss of the new hash
+	s.invalidateHash(key.KeyHash)
+
+	s.logger.Info("api key created",
+		zap.String("api_key_id", key.ID),
+		zap.String("advertiser_id", key.AdvertiserID),
+		zap.Strings("permissions", key.Permissions))
+	return &key, raw, nil
+}
+
+func (s *APIKeyService) invalidateHash(hash string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.cache[hash]; ok {
+		if e.key == nil {
+			s.unknown--
+		}
+		delete(s.cache, hash)
+	}
+}
+
+// List returns the keys of an advertiser, or all keys for "".
+func (s *APIKeyService) List(ctx context.Context, advertiserID string) ([]*models.APIKey, error) {
+	return s.repo.List(ctx, advertiserID)
+}
+
+// Get returns a key by ID, or nil if it does not exist.
+func (s *APIKeyService) Get(ctx context.Context, id string) (*models.APIKey, error) {
+	return s.repo.Get(ctx, id)
+}
+
+// Revoke disables a key. Revoking a revoked key is a no-op.
+func (s *APIKeyService) Revoke(ctx context.Context, id string) (*models.APIKey, error) {
+	k, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+	if k.Status != models.APIKeyRevoked {
+		if err := s.repo.UpdateStatus(ctx, id, models.APIKeyRevoked); err != nil {
+			return nil, err
+		}
+		k.Status = models.APIKeyRevoked
+		s.logger.Info("api key revoked", zap.String("api_key_id", id))
+	}
+	s.invalidate(id)
+	return k, nil
+}
+
+// =============================================
+// Last use tracking
+// =============================================
+
+// Run writes key uses every LastUsedFlushInterval until ctx is done.
+func (s *APIKeyService) Run(ctx context.Context) {
+	interval := s.cfg.LastUsedFlushInterval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Final flush with a fresh context
+			flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			s.flushUsed(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			s.flushUsed(ctx)
+		}
+	}
+}
+
+func (s *APIKeyService) flushUsed(ctx context.Context) {
+	s.mu.Lock()
+	used := s.used
+	s.used = make(map[string]time.Time)
+	s.mu.Unlock()
+
+	if len(used) == 0 {
+		return
+	}
+	if err := s.repo.TouchLastUsed(ctx, used); err != nil {
+		s.logger.Warn("failed to update api key last use", zap.Error(err))
+
+		// Keep the uses for the next flush unless newer ones arrived
+		s.mu.Lock()
+		for id, t := range used {
+			if _, ok := s.used[id]; !ok {
+				s.used[id] = t
+			}
+		}
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,290 @@
+package dsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAuditEntryNotFound is returned for unknown audit entry IDs.
+	ErrAuditEntryNotFound = errors.New("audit entry not found")
+
+	// ErrInvalidRollback is returned for entries that can't be rolled back
+	// to.
+	ErrInvalidRollback = errors.New("invalid rollback")
+)
+
+// auditIgnoredFields are left out of diffs, so saves that only touch them
+// aren't recorded: timestamps are stamped on every save and advertiser
+// balances are only changed by the billing ledger, which keeps its own
+// record.
+var auditIgnoredFields = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"balance":    true,
+}
+
+// AuditService writes and reads the audit log of management changes.
+type AuditService struct {
+	repo    storage.AuditRepo
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+}
+
+// NewAuditService creates a new audit service.
+func NewAuditService(repo storage.AuditRepo, logger *zap.Logger, m *metrics.Metrics) *AuditService {
+	return &AuditService{repo: repo, logger: logger, metrics: m}
+}
+
+// Record appends an entry for a change of an entity by actor. before is nil
+// for creations and after for deletions; the action follows from which is
+// nil. Updates that change no fields are not recorded and return nil.
+func (s *AuditService) Record(ctx context.Context, actor *models.Principal, entityType, entityID string, before, after interface{}) (*models.AuditEntry, error) {
+	return s.record(ctx, actor, entityType, entityID, "", before, after, "")
+}
+
+// RecordRollback appends the entry of a rollback to the version of entry.
+func (s *AuditService) RecordRollback(ctx context.Context, actor *models.Principal, entry *models.AuditEntry, before, after interface{}) (*models.AuditEntry, error) {
+	return s.record(ctx, actor, entry.EntityType, entry.EntityID, models.AuditRollback, before, after, entry.ID)
+}
+
+func (s *AuditService) record(ctx context.Context, actor *models.Principal, entityType, entityID, action string, before, after interface{}, rollbackOf string) (*models.AuditEntry, error) {
+	beforeJSON, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	afterJSON, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	diff, err := auditDiff(beforeJSON, afterJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if action == "" {
+		switch {
+		case beforeJSON == nil:
+			action = models.AuditCreate
+		case afterJSON == nil:
+			action = models.AuditDelete
+		default:
+			action = models.AuditUpdate
+		}
+	}
+	if action == models.AuditUpdate && len(diff) == 0 {
+		return nil, nil
+	}
+
+	e := &models.AuditEntry{
+		ID:         uuid.New().String(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		ActorKind:  models.AuditAnonymous,
+		Before:     beforeJSON,
+		After:      afterJSON,
+		Diff:       diff,
+		RollbackOf: rollbackOf,
+		CreatedAt:  time.Now().UTC(),
+	}
+	if actor != nil {
+		e.ActorKind = actor.Kind
+		e.ActorID = actor.ID
+		e.ActorName = actor.Name
+	}
+
+	if err := s.repo.Append(ctx, e); err != nil {
+		if s.metrics != nil {
+			s.metrics.RecordAuditFailure()
+		}
+		return nil, err
+	}
+	if s.metrics != nil {
+		s.metrics.RecordAuditEntry(entityType, action)
+	}
+	return e, nil
+}
+
+// List returns the entries matching filter, newest first.
+func (s *AuditService) List(ctx context.Context, filter storage.AuditFilter) ([]*models.AuditEntry, error) {
+	return s.repo.List(ctx, filter)
+}
+
+// Get returns an entry by ID.
+func (s *AuditService) Get(ctx context.Context, id string) (*models.AuditEntry, error) {
+	e, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAuditEntryNotFound, id)
+	}
+	return e, nil
+}
+
+// RollbackTarget returns the entry to roll back to and the version of the
+// entity it recorded, which is what a rollback restores.
+func (s *AuditService) RollbackTarget(ctx context.Context, id string) (*models.AuditEntry, json.RawMessage, error) {
+	e, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if e.After == nil {
+		return nil, nil, fmt.Errorf("%w: the entity was deleted by entry %s; roll back to an earlier entry", ErrInvalidRollback, id)
+	}
+	return e, e.After, nil
+}
+
+// auditSnapshot returns v as JSON, or nil for nil values.
+func auditSnapshot(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode audit snapshot: %w", err)
+	}
+	if string(b) == "null" {
+		return nil, nil
+	}
+	return b, nil
+}
+
+// auditDiff lists the fields that differ between two snapshots, sorted by
+// field.
+func auditDiff(before, after json.RawMessage) ([]models.AuditChange, error) {
+	b, err := flattenSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := flattenSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(a))
+	for f := range b {
+		fields = append(fields, f)
+	}
+	for f := range a {
+		if _, ok := b[f]; !ok {
+			fields = append(fields, f)
+		}
+	}
+	sort.Strings(fields)
+
+	diff := make([]models.AuditChange, 0)
+	for _, f := range fields {
+		if bytes.Equal(b[f], a[f]) {
+			continue
+		}
+		diff = append(diff, models.AuditChange{Field: f, Before: b[f], After: a[f]})
+	}
+	return diff, nil
+}
+
+// flattenSnapshot returns the leaf values of a JSON object by dotted path.
+func flattenSnapshot(snapshot json.RawMessage) (map[string]json.RawMessage, error) {
+	out := make(map[string]json.RawMessage)
+	if snapshot == nil {
+		return out, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(snapshot))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode audit snapshot: %w", err)
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("audit snapshot is not an object")
+	}
+	for k, val := range obj {
+		if auditIgnoredFields[k] {
+			continue
+		}
+		if err := flattenValue(k, val, out); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// flattenValue adds v to out under path. Objects are descended into, and so
+// are arrays of objects with IDs, keyed by ID (e.g. "line_items[li-1].bid"),
+// so one changed line item isn't reported as the whole array.
+func flattenValue(path string, v interface{}, out map[string]json.RawMessage) error {
+	if obj, ok := v.(map[string]interface{}); ok && len(obj) > 0 {
+		for k, val := range obj {
+			if err := flattenValue(path+"."+k, val, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if ids, ok := elementIDs(v); ok {
+		for i, elem := range v.([]interface{}) {
+			if err := flattenValue(path+"["+ids[i]+"]", elem, out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if n, ok := v.(json.Number); ok {
+		v = normalizeNumber(n)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit field %s: %w", path, err)
+	}
+	out[path] = b
+	return nil
+}
+
+// elementIDs returns the IDs of the elements of a non-empty array of
+// objects that all have a distinct string "id".
+func elementIDs(v interface{}) ([]string, bool) {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) == 0 {
+		return nil, false
+	}
+	ids := make([]string, len(arr))
+	seen := make(map[string]bool, len(arr))
+	for i, elem := range arr {
+		obj, ok := elem.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		id, ok := obj["id"].(string)
+		if !ok || id == "" || seen[id] {
+			return nil, false
+		}
+		ids[i] = id
+		seen[id] = true
+	}
+	return ids, true
+}
+
+// normalizeNumber returns n as an int64 or float64, so equal numbers
+// written differently (1.5 and 1.50) compare equal.
+func normalizeNumber(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	if f, err := n.Float64(); err == nil {
+		return f
+	}
+	return n
+}
@@ -0,0 +1,615 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/fraud"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ruleScopeDimensions maps rule scopes to the report dimension they are
+// checked per, in addition to the line item.
+var ruleScopeDimensions = map[models.RuleScope]string{
+	models.RuleScopeSource:    storage.ReportDimSource,
+	models.RuleScopeCountry:   storage.ReportDimCountry,
+	models.RuleScopeAppBundle: storage.ReportDimAppBundle,
+}
+
+// ruleReportMetrics are the base report metrics rule metrics derive from.
+var ruleReportMetrics = []string{
+	storage.ReportMetricImpressions, storage.ReportMetricClicks, storage.ReportMetricConversions,
+	storage.ReportMetricInstalls, storage.ReportMetricSpend, storage.ReportMetricRevenue,
+}
+
+// AutomationService evaluates automation rules: it sums reporting metrics
+// of the selected line items over each rule's lookback window and, where
+// every condition holds, pauses the line item, adjusts its bids,
+// blacklists the app bundle or notifies. Every action is logged as a
+// firing; a rule doesn't fire again for the same line item and scope value
+// within its cooldown.
+type AutomationService struct {
+	repo       storage.AutomationRuleRepo
+	reporting  *ReportingService
+	campaigns  *CampaignService
+	fraud      *fraud.Scorer // nil if fraud scoring is disabled
+	audit      *AuditService
+	cfg        config.AutomationConfig
+	httpClient *http.Client
+	logger     *zap.Logger
+	metrics    *metrics.Metrics
+
+	// mu serializes evaluations, so the scheduler and API dry runs or
+	// manual runs don't change the same campaigns concurrently.
+	mu sync.Mutex
+}
+
+// NewAutomationService creates a new automation rule service.
+func NewAutomationService(
+	repo storage.AutomationRuleRepo,
+	reporting *ReportingService,
+	campaigns *CampaignService,
+	fraudScorer *fraud.Scorer,
+	audit *AuditService,
+	cfg config.AutomationConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *AutomationService {
+	return &AutomationService{
+		repo:       repo,
+		reporting:  reporting,
+		campaigns:  campaigns,
+		fraud:      fraudScorer,
+		audit:      audit,
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.WebhookTimeout},
+		logger:     logger,
+		metrics:    m,
+	}
+}
+
+// =============================================
+// Rules
+// =============================================
+
+// ListRules returns the rules of an advertiser, or all when advertiserID
+// is empty.
+func (s *AutomationService) ListRules(ctx context.Context, advertiserID string) ([]*models.AutomationRule, error) {
+	return s.repo.List(ctx, advertiserID)
+}
+
+// GetRule returns a rule, or nil if it doesn't exist.
+func (s *AutomationService) GetRule(ctx context.Context, id string) (*models.AutomationRule, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// SaveRule validates and saves a rule. Countries are upper-cased.
+func (s *AutomationService) SaveRule(ctx context.Context, rule *models.AutomationRule) error {
+	normalizeRule(rule)
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return s.repo.Upsert(ctx, rule)
+}
+
+// DeleteRule deletes a rule. Its firings stay in the log.
+func (s *AutomationService) DeleteRule(ctx context.Context, id string) error {
+	return s.repo.Delete(ctx, id)
+}
+
+// Firings lists logged rule actions, newest first.
+func (s *AutomationService) Firings(ctx context.Context, filter storage.RuleFiringFilter) ([]*models.RuleFiring, error) {
+	list, err := s.repo.ListFirings(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule firings: %w", err)
+	}
+	return list, nil
+}
+
+func normalizeRule(rule *models.AutomationRule) {
+	if rule.Scope == models.RuleScopeCountry {
+		for i, v := range rule.ScopeValues {
+			rule.ScopeValues[i] = strings.ToUpper(strings.TrimSpace(v))
+		}
+	}
+	for i := range rule.Actions {
+		if rule.Actions[i].Type == models.RuleActionBlacklist && rule.Actions[i].List == "" {
+			rule.Actions[i].List = "bundle_blacklist"
+		}
+	}
+}
+
+// =============================================
+// Evaluation
+// =============================================
+
+// Run evaluates enabled rules every interval until ctx is cancelled.
+func (s *AutomationService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Pass(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			s.logger.Error("automation pass failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass evaluates every enabled rule as of now. A rule that fails is logged
+// and evaluated again on the next pass.
+func (s *AutomationService) Pass(ctx context.Context, now time.Time) error {
+	rules, err := s.repo.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list automation rules: %w", err)
+	}
+	for _, rule := range rules {
+		if !rule.Enabled {
+			continue
+		}
+		if _, err := s.Evaluate(ctx, rule, now, false); err != nil {
+			s.logger.Error("failed to evaluate automation rule", zap.String("rule_id", rule.ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// ruleTarget is a line item, and for scoped rules one source, country or
+// bundle within it, whose metrics met every condition.
+type ruleTarget struct {
+	campaign   *models.Campaign
+	lineItemID string
+	scopeValue string
+	metrics    map[string]float64
+}
+
+// Evaluate checks rule over the lookback window ending at now and applies
+// its actions to the matching line items. A dry run (which needn't be a
+// saved rule) only reports the actions it would take.
+func (s *AutomationService) Evaluate(ctx context.Context, rule *models.AutomationRule, now time.Time, dryRun bool) (*models.RuleEvaluation, error) {
+	normalizeRule(rule)
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	eval, targets, err := s.match(ctx, rule, now)
+	if err != nil {
+		if s.metrics != nil {
+			s.metrics.RecordRuleEvaluation("error")
+		}
+		return nil, err
+	}
+	eval.DryRun = dryRun
+
+	var due []*ruleTarget
+	for _, t := range targets {
+		cooling, err := s.coolingDown(ctx, rule, t, now)
+		if err != nil {
+			return nil, err
+		}
+		if cooling {
+			eval.Cooldown++
+			continue
+		}
+		due = append(due, t)
+	}
+
+	eval.Firings = s.apply(ctx, rule, due, now, dryRun)
+	if s.metrics != nil {
+		s.metrics.RecordRuleEvaluation("ok")
+	}
+	return eval, nil
+}
+
+// match returns the rule's targets: the line item (and scope value) rows
+// of the report over its window that meet every condition.
+func (s *AutomationService) match(ctx context.Context, rule *models.AutomationRule, now time.Time) (*models.RuleEvaluation, []*ruleTarget, error) {
+	eval := &models.RuleEvaluation{
+		RuleID:  rule.ID,
+		Start:   now.Add(-time.Duration(rule.LookbackHours) * time.Hour),
+		End:     now,
+		Firings: []models.RuleFiring{},
+	}
+
+	campaigns, err := s.ruleCampaigns(ctx, rule)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(campaigns) == 0 {
+		return eval, nil, nil
+	}
+
+	filter := ReportFilter{
+		LineItemIDs: rule.LineItemIDs,
+		StartDate:   eval.Start,
+		EndDate:     eval.End,
+		GroupBy:     []string{storage.ReportDimCampaign, storage.ReportDimLineItem},
+		Metrics:     ruleReportMetrics,
+		Limit:       storage.MaxReportLimit,
+	}
+	for id := range campaigns {
+		filter.CampaignIDs = append(filter.CampaignIDs, id)
+	}
+	sort.Strings(filter.CampaignIDs)
+	dim := ruleScopeDimensions[rule.Scope]
+	if dim != "" {
+		filter.GroupBy = append(filter.GroupBy, dim)
+	}
+	switch rule.Scope {
+	case models.RuleScopeSource:
+		filter.SourceIDs = rule.ScopeValues
+	case models.RuleScopeCountry:
+		filter.Countries = rule.ScopeValues
+	case models.RuleScopeAppBundle:
+		filter.AppBundles = rule.ScopeValues
+	}
+
+	var fraudRates map[string]float64
+	if ruleUsesMetric(rule, models.RuleMetricFraudRate) {
+		fraudRates = s.sourceFraudRates(eval.Start, eval.End)
+	}
+
+	var targets []*ruleTarget
+	for {
+		result, err := s.reporting.Report(ctx, filter)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to run rule report: %w", err)
+		}
+		for _, row := range result.Rows {
+			c := campaigns[row.Dimensions[storage.ReportDimCampaign]]
+			liID := row.Dimensions[storage.ReportDimLineItem]
+			if c == nil || !activeLineItem(c, liID) {
+				continue
+			}
+			value := ""
+			if dim != "" {
+				if value = row.Dimensions[dim]; value == "" {
+					continue // Unknown country or bundle can't be acted on
+				}
+			}
+
+			m := ruleMetrics(row.Metrics)
+			if fraudRates != nil {
+				m[models.RuleMetricFraudRate] = fraudRates[value]
+			}
+			eval.Checked++
+			if !ruleConditionsHold(rule, m) {
+				continue
+			}
+			eval.Matched++
+			targets = append(targets, &ruleTarget{campaign: c, lineItemID: liID, scopeValue: value, metrics: m})
+		}
+		filter.Offset += len(result.Rows)
+		if len(result.Rows) == 0 || int64(filter.Offset) >= result.Total {
+			break
+		}
+	}
+	return eval, targets, nil
+}
+
+// ruleCampaigns returns the active campaigns the rule selects, by ID.
+func (s *AutomationService) ruleCampaigns(ctx context.Context, rule *models.AutomationRule) (map[string]*models.Campaign, error) {
+	list, err := s.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	result := make(map[string]*models.Campaign)
+	for _, c := range list {
+		if c.Status != models.CampaignStatusActive ||
+			(rule.AdvertiserID != "" && c.AdvertiserID != rule.AdvertiserID) ||
+			(len(rule.CampaignIDs) > 0 && !containsString(rule.CampaignIDs, c.ID)) {
+			continue
+		}
+		result[c.ID] = c
+	}
+	return result, nil
+}
+
+// activeLineItem reports whether c has an active line item id.
+func activeLineItem(c *models.Campaign, id string) bool {
+	idx := findLineItem(c, id)
+	return idx >= 0 && c.LineItems[idx].IsActive
+}
+
+// findLineItem returns the index of line item id in c, or -1.
+func findLineItem(c *models.Campaign, id string) int {
+	for i := range c.LineItems {
+		if c.LineItems[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// ruleMetrics derives rule metrics from a report row's base metrics. Costs
+// per install or conversion are the spend when there are none yet, so
+// sources spending without installs still trip "cpi > X".
+func ruleMetrics(row map[string]float64) map[string]float64 {
+	spend := row[storage.ReportMetricSpend]
+	costPer := func(n float64) float64 {
+		if n == 0 {
+			return spend
+		}
+		return spend / n
+	}
+	ratio := func(num, den, scale float64) float64 {
+		if den == 0 {
+			return 0
+		}
+		return num / den * scale
+	}
+	return map[string]float64{
+		models.RuleMetricSpend:       roundAmount(spend),
+		models.RuleMetricImpressions: row[storage.ReportMetricImpressions],
+		models.RuleMetricClicks:      row[storage.ReportMetricClicks],
+		models.RuleMetricInstalls:    row[storage.ReportMetricInstalls],
+		models.RuleMetricCPI:         roundAmount(costPer(row[storage.ReportMetricInstalls])),
+		models.RuleMetricCPA:         roundAmount(costPer(row[storage.ReportMetricConversions])),
+		models.RuleMetricCR:          roundAmount(ratio(row[storage.ReportMetricConversions], row[storage.ReportMetricClicks], 100)),
+		models.RuleMetricCTR:         roundAmount(ratio(row[storage.ReportMetricClicks], row[storage.ReportMetricImpressions], 100)),
+		models.RuleMetricROAS:        roundAmount(ratio(row[storage.ReportMetricRevenue], spend, 1)),
+	}
+}
+
+func ruleUsesMetric(rule *models.AutomationRule, metric string) bool {
+	for _, c := range rule.Conditions {
+		if c.Metric == metric {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleConditionsHold(rule *models.AutomationRule, m map[string]float64) bool {
+	for i := range rule.Conditions {
+		if !rule.Conditions[i].Holds(m[rule.Conditions[i].Metric]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sourceFraudRates returns the percentage of flagged clicks per source ID
+// over the days of the window. Sources without clicks are left out.
+func (s *AutomationService) sourceFraudRates(start, end time.Time) map[string]float64 {
+	rates := make(map[string]float64)
+	if s.fraud == nil {
+		return rates
+	}
+	clicks := make(map[string][2]int64)
+	for _, st := range s.fraud.SourceReport(start, end) {
+		n := clicks[st.SourceID]
+		n[0] += st.Clicks
+		n[1] += st.FlaggedClicks
+		clicks[st.SourceID] = n
+	}
+	for id, n := range clicks {
+		if n[0] > 0 {
+			rates[id] = roundAmount(float64(n[1]) / float64(n[0]) * 100)
+		}
+	}
+	return rates
+}
+
+// coolingDown reports whether the rule fired for t within its cooldown.
+// Failed firings don't count.
+func (s *AutomationService) coolingDown(ctx context.Context, rule *models.AutomationRule, t *ruleTarget, now time.Time) (bool, error) {
+	recent, err := s.repo.ListFirings(ctx, storage.RuleFiringFilter{
+		RuleID:     rule.ID,
+		LineItemID: t.lineItemID,
+		ScopeValue: t.scopeValue,
+		From:       now.Add(-rule.Cooldown()),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check rule cooldown: %w", err)
+	}
+	for _, f := range recent {
+		if f.ScopeValue == t.scopeValue && f.Error == "" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// =============================================
+// Actions
+// =============================================
+
+// apply takes the rule's actions on the targets and returns the firings.
+// Line items are paused and rebid at most once per evaluation, however
+// many of their sources, countries or bundles matched. Unless it is a dry
+// run, changed campaigns are saved and audited and the firings logged.
+func (s *AutomationService) apply(ctx context.Context, rule *models.AutomationRule, targets []*ruleTarget, now time.Time, dryRun bool) []models.RuleFiring {
+	firings := make([]models.RuleFiring, 0)
+	changed := make(map[string]*models.Campaign) // Campaign ID -> changed copy
+	var order []string
+	done := make(map[string]bool) // Line item ID + action of once-only actions
+
+	for _, t := range targets {
+		c, ok := changed[t.campaign.ID]
+		if !ok {
+			c = copyCampaign(t.campaign)
+		}
+		li := &c.LineItems[findLineItem(c, t.lineItemID)]
+		dirty := false
+
+		for _, a := range rule.Actions {
+			f := models.RuleFiring{
+				ID:           uuid.New().String(),
+				RuleID:       rule.ID,
+				RuleName:     rule.Name,
+				AdvertiserID: c.AdvertiserID,
+				CampaignID:   c.ID,
+				LineItemID:   li.ID,
+				Scope:        rule.Scope,
+				ScopeValue:   t.scopeValue,
+				Action:       a.Type,
+				Metrics:      t.metrics,
+				DryRun:       dryRun,
+				CreatedAt:    now,
+			}
+
+			switch a.Type {
+			case models.RuleActionPause, models.RuleActionAdjustBid:
+				key := li.ID + "/" + string(a.Type)
+				if done[key] {
+					continue
+				}
+				done[key] = true
+				if a.Type == models.RuleActionPause {
+					f.Detail = "line item paused"
+					li.IsActive = false
+				} else {
+					f.Detail = adjustBid(li, a.BidAdjustPct)
+				}
+				dirty = true
+
+			case models.RuleActionBlacklist:
+				list := targetingList(&li.Targeting, a.List)
+				if containsString(*list, t.scopeValue) {
+					continue
+				}
+				*list = addValues(*list, []string{t.scopeValue})
+				f.Detail = t.scopeValue + " added to " + a.List
+				dirty = true
+
+			case models.RuleActionNotify:
+				f.Detail = "notified"
+				if s.cfg.WebhookURL == "" {
+					f.Detail = "notify skipped: no automation webhook configured"
+				}
+			}
+			firings = append(firings, f)
+		}
+
+		if dirty && !ok {
+			changed[c.ID] = c
+			order = append(order, c.ID)
+		}
+	}
+
+	if dryRun {
+		return firings
+	}
+
+	actor := &models.Principal{Kind: models.PrincipalSystem, ID: rule.ID, Name: "automation rule " + rule.Name}
+	failed := make(map[string]string) // Campaign ID -> save error
+	for _, id := range order {
+		after := changed[id]
+		before, err := s.campaigns.GetCampaign(ctx, id)
+		if err == nil && before == nil {
+			err = fmt.Errorf("campaign %s not found", id)
+		}
+		if err == nil {
+			StampLineItems(after, before)
+			err = s.campaigns.UpsertCampaign(ctx, after)
+		}
+		if err != nil {
+			failed[id] = err.Error()
+			s.logger.Error("failed to apply automation rule", zap.String("rule_id", rule.ID), zap.String("campaign_id", id), zap.Error(err))
+			continue
+		}
+		if s.audit != nil {
+			if _, err := s.audit.Record(ctx, actor, models.AuditCampaign, id, before, after); err != nil {
+				s.logger.Error("failed to record audit entry", zap.String("campaign_id", id), zap.Error(err))
+			}
+		}
+	}
+
+	var notify []models.RuleFiring
+	for i := range firings {
+		f := &firings[i]
+		if msg, ok := failed[f.CampaignID]; ok && f.Action != models.RuleActionNotify {
+			f.Error = msg
+		}
+		result := "ok"
+		if f.Error != "" {
+			result = "error"
+		}
+		if s.metrics != nil {
+			s.metrics.RecordRuleFiring(string(f.Action), result)
+		}
+		if err := s.repo.AppendFiring(ctx, f); err != nil {
+			s.logger.Error("failed to log rule firing", zap.String("rule_id", rule.ID), zap.Error(err))
+		}
+		if f.Action == models.RuleActionNotify && s.cfg.WebhookURL != "" {
+			notify = append(notify, *f)
+		}
+		s.logger.Info("automation rule fired",
+			zap.String("rule_id", rule.ID),
+			zap.String("line_item_id", f.LineItemID),
+			zap.String("scope_value", f.ScopeValue),
+			zap.String("action", string(f.Action)),
+			zap.String("detail", f.Detail),
+			zap.String("error", f.Error),
+		)
+	}
+	if len(notify) > 0 {
+		go s.notify(models.RuleWebhook{
+			Event:    "automation.rule_fired",
+			RuleID:   rule.ID,
+			RuleName: rule.Name,
+			Firings:  notify,
+		})
+	}
+	return firings
+}
+
+// copyCampaign returns a copy of c whose line items and their targeting
+// lists can be changed without changing c.
+func copyCampaign(c *models.Campaign) *models.Campaign {
+	cp := *c
+	cp.LineItems = append([]models.LineItem(nil), c.LineItems...)
+	return &cp
+}
+
+// adjustBid scales the line item's fixed, min and max CPM by pct percent
+// and describes the change.
+func adjustBid(li *models.LineItem, pct float64) string {
+	before := li.BidStrategy
+	factor := 1 + pct/100
+	applyLineItemChanges(li, &models.LineItemChanges{BidMultiplier: &factor})
+
+	var parts []string
+	for _, p := range []struct {
+		name          string
+		before, after float64
+	}{
+		{"fixed_cpm", before.FixedCPM, li.BidStrategy.FixedCPM},
+		{"min_cpm", before.MinCPM, li.BidStrategy.MinCPM},
+		{"max_cpm", before.MaxCPM, li.BidStrategy.MaxCPM},
+	} {
+		if p.before != 0 {
+			parts = append(parts, fmt.Sprintf("%s %.4g -> %.4g", p.name, p.before, p.after))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("bids %+g%% (no CPM set)", pct)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// notify posts the rule webhook.
+func (s *AutomationService) notify(body models.RuleWebhook) {
+	err := postWebhook(s.httpClient, s.cfg.WebhookURL, s.cfg.WebhookSecret, body)
+	if err != nil {
+		s.logger.Error("failed to deliver automation webhook", zap.String("rule_id", body.RuleID), zap.Error(err))
+	}
+}
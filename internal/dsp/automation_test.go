@@ -0,0 +1,347 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// automationFixture is an automation service over in-memory repos. Over
+// the hour before now:
+//
+//	cmp-1 li-1: 10 USD spend (8 in BR, 2 in US), 2 installs: CPI 5
+//	cmp-1 li-2: 3 USD spend in DE, 3 installs: CPI 1
+//	cmp-1 li-3: inactive, 100 USD spend
+//	cmp-2 li-4: paused campaign, 100 USD spend
+//
+// and li-2 spent another 100 USD seven hours before now.
+type automationFixture struct {
+	automation *AutomationService
+	campaigns  *storage.InMemoryCampaignRepo
+	rules      *storage.InMemoryAutomationRuleRepo
+	now        time.Time
+}
+
+func newAutomationFixture(t *testing.T) *automationFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &automationFixture{
+		campaigns: storage.NewInMemoryCampaignRepo(),
+		rules:     storage.NewInMemoryAutomationRuleRepo(),
+		now:       time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC),
+	}
+	events := storage.NewInMemoryEventStore()
+	f.automation = NewAutomationService(f.rules, NewReportingService(events, nil), NewCampaignService(f.campaigns), nil,
+		NewAuditService(storage.NewInMemoryAuditRepo(), zap.NewNop(), nil), config.AutomationConfig{}, zap.NewNop(), nil)
+
+	lineItem := func(id, campaignID string, active bool) models.LineItem {
+		return models.LineItem{
+			ID: id, CampaignID: campaignID, IsActive: active,
+			BidStrategy: models.BidStrategy{Type: models.BidStrategyFixedCPM, FixedCPM: 2, MaxCPM: 3},
+			Pacing:      models.PacingConfig{DailyBudget: 100},
+			Creatives:   []models.Creative{{ID: "cr-1"}},
+		}
+	}
+	for _, c := range []*models.Campaign{
+		{ID: "cmp-1", Name: "One", AdvertiserID: "adv-1", Status: models.CampaignStatusActive, LineItems: []models.LineItem{
+			lineItem("li-1", "cmp-1", true), lineItem("li-2", "cmp-1", true), lineItem("li-3", "cmp-1", false),
+		}},
+		{ID: "cmp-2", Name: "Two", AdvertiserID: "adv-1", Status: models.CampaignStatusPaused, LineItems: []models.LineItem{
+			lineItem("li-4", "cmp-2", true),
+		}},
+	} {
+		if err := f.campaigns.Upsert(ctx, c); err != nil {
+			t.Fatalf("Upsert(campaign) error = %v", err)
+		}
+	}
+
+	n := 0
+	win := func(campaignID, lineItemID, country string, usd float64, ago time.Duration) {
+		n++
+		if err := events.SaveWin(ctx, &models.Win{
+			ID: fmt.Sprintf("win-%d", n), Timestamp: f.now.Add(-ago), CampaignID: campaignID, LineItemID: lineItemID,
+			SourceID: "src-1", GeoCountry: country, WinPrice: usd, WinPriceUSD: usd,
+		}); err != nil {
+			t.Fatalf("SaveWin() error = %v", err)
+		}
+	}
+	install := func(lineItemID string) {
+		n++
+		if err := events.SaveConversion(ctx, &models.Conversion{
+			ID: fmt.Sprintf("conv-%d", n), Timestamp: f.now.Add(-time.Hour), CampaignID: "cmp-1", LineItemID: lineItemID,
+			SourceType: "s2s", SourceID: "src-1", Event: storage.InstallEvent,
+		}); err != nil {
+			t.Fatalf("SaveConversion() error = %v", err)
+		}
+	}
+	for i := 0; i < 4; i++ {
+		win("cmp-1", "li-1", "BR", 2, time.Hour)
+	}
+	win("cmp-1", "li-1", "US", 2, time.Hour)
+	install("li-1")
+	install("li-1")
+	win("cmp-1", "li-2", "DE", 3, time.Hour)
+	for i := 0; i < 3; i++ {
+		install("li-2")
+	}
+	win("cmp-1", "li-2", "DE", 100, 7*time.Hour)
+	win("cmp-1", "li-3", "BR", 100, time.Hour)
+	win("cmp-2", "li-4", "BR", 100, time.Hour)
+	return f
+}
+
+// lineItem returns line item id of the saved cmp-1.
+func (f *automationFixture) lineItem(t *testing.T, id string) *models.LineItem {
+	t.Helper()
+	c, err := f.campaigns.GetByID(context.Background(), "cmp-1")
+	if err != nil || c == nil {
+		t.Fatalf("GetByID() = %v, %v", c, err)
+	}
+	return &c.LineItems[findLineItem(c, id)]
+}
+
+// firedFor returns the line item (and scope value) of each firing.
+func firedFor(firings []models.RuleFiring) []string {
+	out := make([]string, 0, len(firings))
+	for _, f := range firings {
+		target := f.LineItemID
+		if f.ScopeValue != "" {
+			target += "/" + f.ScopeValue
+		}
+		out = append(out, target)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestAutomationConditions(t *testing.T) {
+	f := newAutomationFixture(t)
+	cond := func(metric, op string, value float64) models.RuleCondition {
+		return models.RuleCondition{Metric: metric, Operator: op, Value: value}
+	}
+
+	tests := []struct {
+		name        string
+		conditions  []models.RuleCondition
+		wantChecked int
+		want        []string
+	}{
+		{"cpi above", []models.RuleCondition{cond(models.RuleMetricCPI, models.RuleOpGreater, 4)}, 2, []string{"li-1"}},
+		{"cpi at most", []models.RuleCondition{cond(models.RuleMetricCPI, models.RuleOpLessEqual, 1)}, 2, []string{"li-2"}},
+		{"strictly below the value", []models.RuleCondition{cond(models.RuleMetricCPI, models.RuleOpLess, 1)}, 2, []string{}},
+		{"all conditions hold", []models.RuleCondition{
+			cond(models.RuleMetricSpend, models.RuleOpGreaterEqual, 3),
+			cond(models.RuleMetricInstalls, models.RuleOpGreaterEqual, 3),
+		}, 2, []string{"li-2"}},
+		// The 100 USD of li-2 seven hours ago is outside the window, and
+		// inactive line items and paused campaigns aren't checked
+		{"window and active line items only", []models.RuleCondition{cond(models.RuleMetricSpend, models.RuleOpGreater, 50)}, 2, []string{}},
+		{"every line item", []models.RuleCondition{cond(models.RuleMetricSpend, models.RuleOpGreater, 1)}, 2, []string{"li-1", "li-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eval, err := f.automation.Evaluate(context.Background(), &models.AutomationRule{
+				ID: "rule-1", Name: "Rule", Scope: models.RuleScopeLineItem, LookbackHours: 6,
+				Conditions: tt.conditions, Actions: []models.RuleAction{{Type: models.RuleActionPause}},
+			}, f.now, true)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if eval.Checked != tt.wantChecked || eval.Matched != len(tt.want) {
+				t.Errorf("Evaluate() checked %d, matched %d; want %d, %d", eval.Checked, eval.Matched, tt.wantChecked, len(tt.want))
+			}
+			if got := firedFor(eval.Firings); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("firings for %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Dry runs change nothing
+	if li := f.lineItem(t, "li-1"); !li.IsActive {
+		t.Error("a dry run paused li-1")
+	}
+}
+
+func TestAutomationActionsAndCooldown(t *testing.T) {
+	ctx := context.Background()
+	f := newAutomationFixture(t)
+	rule := &models.AutomationRule{
+		ID: "rule-1", Name: "High CPI", Enabled: true, Scope: models.RuleScopeLineItem,
+		LookbackHours: 6, CooldownHours: 1,
+		Conditions: []models.RuleCondition{{Metric: models.RuleMetricCPI, Operator: models.RuleOpGreater, Value: 4}},
+		Actions: []models.RuleAction{
+			{Type: models.RuleActionPause},
+			{Type: models.RuleActionAdjustBid, BidAdjustPct: -20},
+		},
+	}
+	if err := f.automation.SaveRule(ctx, rule); err != nil {
+		t.Fatalf("SaveRule() error = %v", err)
+	}
+
+	if err := f.automation.Pass(ctx, f.now); err != nil {
+		t.Fatalf("Pass() error = %v", err)
+	}
+	li := f.lineItem(t, "li-1")
+	if li.IsActive || li.BidStrategy.FixedCPM != 1.6 || li.BidStrategy.MaxCPM != 2.4 {
+		t.Errorf("li-1 = active %v, fixed %v, max %v; want paused with bids lowered 20%% to 1.6, 2.4",
+			li.IsActive, li.BidStrategy.FixedCPM, li.BidStrategy.MaxCPM)
+	}
+	if other := f.lineItem(t, "li-2"); !other.IsActive || other.BidStrategy.FixedCPM != 2 {
+		t.Errorf("li-2 changed: active %v, fixed %v", other.IsActive, other.BidStrategy.FixedCPM)
+	}
+	firings, err := f.automation.Firings(ctx, storage.RuleFiringFilter{RuleID: "rule-1"})
+	if err != nil || len(firings) != 2 {
+		t.Fatalf("Firings() = %d, %v; want pause and adjust_bid", len(firings), err)
+	}
+
+	// Reactivated by hand: within the cooldown the rule leaves it alone
+	c, _ := f.campaigns.GetByID(ctx, "cmp-1")
+	c.LineItems[findLineItem(c, "li-1")].IsActive = true
+	if err := f.campaigns.Upsert(ctx, c); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	eval, err := f.automation.Evaluate(ctx, rule, f.now.Add(30*time.Minute), false)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if eval.Matched != 1 || eval.Cooldown != 1 || len(eval.Firings) != 0 {
+		t.Errorf("Evaluate() in the cooldown = %d matched, %d cooling down, %d firings; want 1, 1, 0",
+			eval.Matched, eval.Cooldown, len(eval.Firings))
+	}
+
+	// After it, the rule fires again
+	eval, err = f.automation.Evaluate(ctx, rule, f.now.Add(2*time.Hour), false)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	if eval.Cooldown != 0 || len(eval.Firings) != 2 || f.lineItem(t, "li-1").IsActive {
+		t.Errorf("Evaluate() after the cooldown = %d cooling down, %d firings; want li-1 paused again", eval.Cooldown, len(eval.Firings))
+	}
+
+	// Disabled rules don't run in passes
+	rule.Enabled = false
+	if err := f.automation.SaveRule(ctx, rule); err != nil {
+		t.Fatalf("SaveRule() error = %v", err)
+	}
+	c, _ = f.campaigns.GetByID(ctx, "cmp-1")
+	c.LineItems[findLineItem(c, "li-1")].IsActive = true
+	if err := f.campaigns.Upsert(ctx, c); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	if err := f.automation.Pass(ctx, f.now.Add(4*time.Hour)); err != nil {
+		t.Fatalf("Pass() error = %v", err)
+	}
+	if !f.lineItem(t, "li-1").IsActive {
+		t.Error("a disabled rule paused li-1")
+	}
+}
+
+func TestAutomationCountryScope(t *testing.T) {
+	tests := []struct {
+		name        string
+		scopeValues []string
+		wantChecked int
+		want        []string
+	}{
+		{"every country", nil, 3, []string{"li-1/BR"}},
+		// Countries are upper-cased
+		{"selected country", []string{"br"}, 1, []string{"li-1/BR"}},
+		{"other country", []string{"US", "DE"}, 2, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAutomationFixture(t)
+			eval, err := f.automation.Evaluate(context.Background(), &models.AutomationRule{
+				ID: "rule-1", Name: "Country spend", Scope: models.RuleScopeCountry, ScopeValues: tt.scopeValues, LookbackHours: 6,
+				Conditions: []models.RuleCondition{{Metric: models.RuleMetricSpend, Operator: models.RuleOpGreater, Value: 5}},
+				Actions:    []models.RuleAction{{Type: models.RuleActionNotify}},
+			}, f.now, false)
+			if err != nil {
+				t.Fatalf("Evaluate() error = %v", err)
+			}
+			if eval.Checked != tt.wantChecked {
+				t.Errorf("Evaluate() checked %d, want %d", eval.Checked, tt.wantChecked)
+			}
+			if got := firedFor(eval.Firings); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("firings for %v, want %v", got, tt.want)
+			}
+			for _, fr := range eval.Firings {
+				if fr.Detail != "notify skipped: no automation webhook configured" || fr.Metrics[models.RuleMetricSpend] != 8 {
+					t.Errorf("firing = %q with %v spend, want a skipped notification for 8 USD", fr.Detail, fr.Metrics[models.RuleMetricSpend])
+				}
+			}
+		})
+	}
+}
+
+func TestRuleMetrics(t *testing.T) {
+	tests := []struct {
+		name   string
+		row    map[string]float64
+		metric string
+		want   float64
+	}{
+		{"cpi", map[string]float64{storage.ReportMetricSpend: 10, storage.ReportMetricInstalls: 4}, models.RuleMetricCPI, 2.5},
+		// Spending without installs trips cpi conditions
+		{"cpi without installs", map[string]float64{storage.ReportMetricSpend: 10}, models.RuleMetricCPI, 10},
+		{"cpa without conversions", map[string]float64{storage.ReportMetricSpend: 7}, models.RuleMetricCPA, 7},
+		{"cr", map[string]float64{storage.ReportMetricConversions: 5, storage.ReportMetricClicks: 200}, models.RuleMetricCR, 2.5},
+		{"cr without clicks", map[string]float64{storage.ReportMetricConversions: 5}, models.RuleMetricCR, 0},
+		{"ctr", map[string]float64{storage.ReportMetricClicks: 3, storage.ReportMetricImpressions: 1000}, models.RuleMetricCTR, 0.3},
+		{"roas", map[string]float64{storage.ReportMetricRevenue: 30, storage.ReportMetricSpend: 20}, models.RuleMetricROAS, 1.5},
+		{"roas without spend", map[string]float64{storage.ReportMetricRevenue: 30}, models.RuleMetricROAS, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ruleMetrics(tt.row)[tt.metric]; got != tt.want {
+				t.Errorf("%s = %v, want %v", tt.metric, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAutomationInvalidRule(t *testing.T) {
+	f := newAutomationFixture(t)
+	base := func() *models.AutomationRule {
+		return &models.AutomationRule{
+			ID: "rule-1", Name: "Rule", Scope: models.RuleScopeLineItem, LookbackHours: 6,
+			Conditions: []models.RuleCondition{{Metric: models.RuleMetricCPI, Operator: models.RuleOpGreater, Value: 4}},
+			Actions:    []models.RuleAction{{Type: models.RuleActionPause}},
+		}
+	}
+
+	tests := []struct {
+		name      string
+		change    func(r *models.AutomationRule)
+		wantField string
+	}{
+		{"fraud rate outside source scope", func(r *models.AutomationRule) { r.Conditions[0].Metric = models.RuleMetricFraudRate }, "conditions[0].metric"},
+		{"unknown operator", func(r *models.AutomationRule) { r.Conditions[0].Operator = "eq" }, "conditions[0].operator"},
+		{"blacklist outside app bundle scope", func(r *models.AutomationRule) { r.Actions[0].Type = models.RuleActionBlacklist }, "actions[0].type"},
+		{"bids down 100%", func(r *models.AutomationRule) {
+			r.Actions[0] = models.RuleAction{Type: models.RuleActionAdjustBid, BidAdjustPct: -100}
+		}, "actions[0].bid_adjust_pct"},
+		{"lookback too long", func(r *models.AutomationRule) { r.LookbackHours = 24*30 + 1 }, "lookback_hours"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := base()
+			tt.change(rule)
+			_, err := f.automation.Evaluate(context.Background(), rule, f.now, true)
+			var verrs models.ValidationErrors
+			if !errors.As(err, &verrs) || len(verrs) == 0 || verrs[0].Field != tt.wantField {
+				t.Errorf("Evaluate() error = %v, want an invalid %s", err, tt.wantField)
+			}
+		})
+	}
+}
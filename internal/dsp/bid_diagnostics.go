@@ -0,0 +1,190 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// StageCheck is the dry-run outcome of one bid stage.
+type StageCheck struct {
+	Stage  string `json:"stage"` // One of the models.BidStage* constants
+	Passed bool   `json:"passed"`
+	Detail string `json:"detail,omitempty"` // e.g. "os_version 12.0 < min 13.0"
+}
+
+// LineItemDiagnosis explains whether a line item would bid on an impression.
+type LineItemDiagnosis struct {
+	ImpID        string       `json:"imp_id"`
+	CampaignID   string       `json:"campaign_id"`
+	LineItemID   string       `json:"line_item_id"`
+	LineItemName string       `json:"line_item_name,omitempty"`
+	Price        float64      `json:"price"`
+	Eligible     bool         `json:"eligible"` // Passed every stage
+	Winner       bool         `json:"winner"`   // Would win the internal auction
+	Stages       []StageCheck `json:"stages"`
+}
+
+// BidDiagnosis is the "why no bid" report for one bid request.
+type BidDiagnosis struct {
+	RequestID string              `json:"request_id"`
+	UserID    string              `json:"user_id"`
+	LineItems []LineItemDiagnosis `json:"line_items"`
+}
+
+// ExplainBid runs a bid request through the balance check, targeting,
+// pricing, floor, pacing and creative selection for every active line item without bidding or
+// touching pacing counters and metrics. Unlike BuildBidResponse, every
+// stage is evaluated so all reasons a line item can't bid are reported.
+func (s *BidService) ExplainBid(ctx context.Context, br *models.BidRequest) (*BidDiagnosis, error) {
+	if br == nil {
+		return nil, errors.New("nil bid request")
+	}
+
+	campaigns, err := s.repo.ListAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+
+	diag := &BidDiagnosis{
+		RequestID: br.ID,
+		UserID:    s.extractUserID(br),
+		LineItems: make([]LineItemDiagnosis, 0),
+	}
+
+	for i := range br.Imp {
+		imp := &br.Imp[i]
+		winner := -1
+		var bestPriority int32
+		var bestPrice float64
+
+		for _, c := range campaigns {
+			if c.Status != models.CampaignStatusActive {
+				continue
+			}
+			for j := range c.LineItems {
+				li := &c.LineItems[j]
+				if !li.IsActive {
+					continue
+				}
+
+				d := s.explainLineItem(br, imp, c, li, diag.UserID)
+				if d.Eligible && (winner < 0 || li.Priority > bestPriority || (li.Priority == bestPriority && d.Price > bestPrice)) {
+					winner = len(diag.LineItems)
+					bestPriority = li.Priority
+					bestPrice = d.Price
+				}
+				diag.LineItems = append(diag.LineItems, d)
+			}
+		}
+
+		if winner >= 0 {
+			diag.LineItems[winner].Winner = true
+		}
+	}
+
+	return diag, nil
+}
+
+// explainLineItem evaluates every bid stage of one line item for imp.
+func (s *BidService) explainLineItem(br *models.BidRequest, imp *models.Imp, c *models.Campaign, li *models.LineItem, userID string) LineItemDiagnosis {
+	d := LineItemDiagnosis{
+		ImpID:        imp.ID,
+		CampaignID:   c.ID,
+		LineItemID:   li.ID,
+		LineItemName: li.Name,
+		Stages:       make([]StageCheck, 0, 6),
+	}
+
+	// Balance
+	if s.billing != nil {
+		balanceCheck := StageCheck{Stage: models.BidStageBalance, Passed: s.billing.CanSpend(c.AdvertiserID)}
+		if !balanceCheck.Passed {
+			balanceCheck.Detail = "advertiser " + c.AdvertiserID + " has no available balance"
+		}
+		d.Stages = append(d.Stages, balanceCheck)
+	}
+
+	// Targeting
+	targetingCheck := StageCheck{Stage: models.BidStageTargeting, Passed: true}
+	if s.targeting != nil {
+		if result := s.targeting.Explain(br, imp, li); !result.Matched {
+			targetingCheck.Passed = false
+			targetingCheck.Detail = result.FailedCriteria + ": " + result.Detail
+		}
+	} else if !s.matchesBasicTargeting(br, imp, li) {
+		targetingCheck.Passed = false
+		targetingCheck.Detail = "basic targeting (device type, os or banner size)"
+	}
+	d.Stages = append(d.Stages, targetingCheck)
+
+	// Price
+	price := s.calculateBidPrice(br, imp, li)
+	d.Price = price
+	priceCheck := StageCheck{Stage: models.BidStagePrice, Passed: price > 0}
+	if price > 0 {
+		priceCheck.Detail = fmt.Sprintf("%s price %.4f", li.BidStrategy.Type, price)
+	} else {
+		priceCheck.Detail = fmt.Sprintf("%s strategy produced no price", li.BidStrategy.Type)
+	}
+	d.Stages = append(d.Stages, priceCheck)
+
+	// Floor
+	floorCheck := StageCheck{Stage: models.BidStageFloor, Passed: true}
+	switch {
+	case imp.BidFloor <= 0:
+		floorCheck.Detail = "no floor"
+	case price < imp.BidFloor:
+		floorCheck.Passed = false
+		floorCheck.Detail = fmt.Sprintf("price %.4f < floor %.4f", price, imp.BidFloor)
+	default:
+		floorCheck.Detail = fmt.Sprintf("price %.4f >= floor %.4f", price, imp.BidFloor)
+	}
+	d.Stages = append(d.Stages, floorCheck)
+
+	// Pacing
+	pacingCheck := StageCheck{Stage: models.BidStagePacing, Passed: true}
+	if decision := s.pacer.Check(li.ID, userID, li.Pacing, price); !decision.Allowed {
+		pacingCheck.Passed = false
+		pacingCheck.Detail = decision.Reason
+		if decision.Detail != "" {
+			pacingCheck.Detail += ": " + decision.Detail
+		}
+	}
+	d.Stages = append(d.Stages, pacingCheck)
+
+	// Creative
+	creativeCheck := StageCheck{Stage: models.BidStageCreative, Passed: true}
+	if cr := selectCreative(imp, li); cr != nil {
+		creativeCheck.Detail = "creative " + cr.ID
+	} else {
+		creativeCheck.Passed = false
+		creativeCheck.Detail = fmt.Sprintf("no creative fits %s impression among %d creatives", impFormat(imp), len(li.Creatives))
+	}
+	d.Stages = append(d.Stages, creativeCheck)
+
+	d.Eligible = true
+	for _, st := range d.Stages {
+		if !st.Passed {
+			d.Eligible = false
+			break
+		}
+	}
+	return d
+}
+
+// impFormat describes the ad format an impression asks for.
+func impFormat(imp *models.Imp) string {
+	switch {
+	case imp.Video != nil:
+		return "video"
+	case imp.Native != nil:
+		return "native"
+	case imp.Banner != nil:
+		return fmt.Sprintf("banner %dx%d", imp.Banner.W, imp.Banner.H)
+	default:
+		return "unknown"
+	}
+}
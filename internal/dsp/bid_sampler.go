@@ -0,0 +1,166 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// BidSamplingConfig selects which bid requests are sampled.
+type BidSamplingConfig struct {
+	Rate        float64  `json:"rate"`          // Percent of all requests (0-100)
+	SourceIDs   []string `json:"source_ids"`    // Requests from these sources are always sampled
+	LineItemIDs []string `json:"line_item_ids"` // Requests evaluating these line items are always sampled
+}
+
+// BidSampler records sampled bid requests, our responses and the decision
+// trail of every line item evaluated.
+type BidSampler struct {
+	store storage.BidSampleStore
+
+	mu        sync.RWMutex
+	cfg       BidSamplingConfig
+	sources   map[string]bool
+	lineItems map[string]bool
+}
+
+// NewBidSampler creates a bid sampler writing to store.
+func NewBidSampler(store storage.BidSampleStore, cfg BidSamplingConfig) (*BidSampler, error) {
+	s := &BidSampler{store: store}
+	if err := s.SetConfig(cfg); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Config returns the current sampling configuration.
+func (s *BidSampler) Config() BidSamplingConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cfg
+}
+
+// SetConfig replaces the sampling configuration.
+func (s *BidSampler) SetConfig(cfg BidSamplingConfig) error {
+	if cfg.Rate < 0 || cfg.Rate > 100 {
+		return fmt.Errorf("rate must be between 0 and 100")
+	}
+	if cfg.SourceIDs == nil {
+		cfg.SourceIDs = []string{}
+	}
+	if cfg.LineItemIDs == nil {
+		cfg.LineItemIDs = []string{}
+	}
+
+	sources := make(map[string]bool, len(cfg.SourceIDs))
+	for _, id := range cfg.SourceIDs {
+		sources[id] = true
+	}
+	lineItems := make(map[string]bool, len(cfg.LineItemIDs))
+	for _, id := range cfg.LineItemIDs {
+		lineItems[id] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cfg = cfg
+	s.sources = sources
+	s.lineItems = lineItems
+	return nil
+}
+
+// Store returns the sample store.
+func (s *BidSampler) Store() storage.BidSampleStore {
+	return s.store
+}
+
+// start returns a trace for the request, or nil when it can't be sampled.
+// Requests not picked by rate or source are still traced while line items
+// are watched, and kept only if one of them is evaluated.
+func (s *BidSampler) start(sourceID string) *bidTrace {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sampled := s.sources[sourceID] || (s.cfg.Rate > 0 && rand.Float64()*100 < s.cfg.Rate)
+	if !sampled && len(s.lineItems) == 0 {
+		return nil
+	}
+	return &bidTrace{
+		sourceID: sourceID,
+		sampled:  sampled,
+		watch:    s.lineItems,
+	}
+}
+
+// finish stores the sample if the trace was sampled.
+func (s *BidSampler) finish(t *bidTrace, br *models.BidRequest, resp *models.BidResponse, noBidReason string) {
+	if t == nil || !t.sampled {
+		return
+	}
+
+	sample := &models.BidSample{
+		ID:          uuid.New().String(),
+		Timestamp:   time.Now(),
+		SourceID:    t.sourceID,
+		Request:     br,
+		Response:    resp,
+		NoBidReason: noBidReason,
+		Trail:       t.trail,
+	}
+	if sample.Trail == nil {
+		sample.Trail = []models.LineItemDecision{}
+	}
+
+	// Sampling is best effort; the store buffers and reports its own errors
+	_ = s.store.SaveBidSample(context.Background(), sample)
+}
+
+// bidTrace collects line item decisions for one bid request.
+type bidTrace struct {
+	sourceID string
+	sampled  bool
+	watch    map[string]bool // Line items that force a sample (read-only)
+	trail    []models.LineItemDecision
+}
+
+// add records a decision. Safe to call on a nil trace.
+func (t *bidTrace) add(imp *models.Imp, c *models.Campaign, li *models.LineItem, stage, reason string, price float64) {
+	if t == nil {
+		return
+	}
+	t.trail = append(t.trail, models.LineItemDecision{
+		ImpID:      imp.ID,
+		CampaignID: c.ID,
+		LineItemID: li.ID,
+		Stage:      stage,
+		Reason:     reason,
+		Price:      price,
+	})
+	if t.watch[li.ID] {
+		t.sampled = true
+	}
+}
+
+// settle marks the winning line item of an impression as bid; the other
+// eligible line items were outbid.
+func (t *bidTrace) settle(impID, winnerLineItemID string) {
+	if t == nil {
+		return
+	}
+	for i := range t.trail {
+		d := &t.trail[i]
+		if d.ImpID == impID && d.Stage == models.BidStageOutbid && d.LineItemID == winnerLineItemID {
+			d.Stage = models.BidStageBid
+		}
+	}
+}
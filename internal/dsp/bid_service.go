@@ -1,6 +1,7 @@
 package dsp
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strings"
@@ -18,6 +19,9 @@ type BidService struct {
 	pacer      PacingEngine
 	targeting  *targeting.TargetingEngine
 	metrics    *metrics.Metrics
+	sampler    *BidSampler
+	live       *LiveFeed
+	billing    *BillingService
 }
 
 // NewBidService constructs a BidService with the given dependencies.
@@ -30,6 +34,21 @@ func NewBidService(repo storage.CampaignRepo, pacer PacingEngine, targetingEngin
 	}
 }
 
+// SetSampler enables bid request sampling.
+func (s *BidService) SetSampler(sampler *BidSampler) {
+	s.sampler = sampler
+}
+
+// SetLiveFeed enables publishing bids to the live feed.
+func (s *BidService) SetLiveFeed(feed *LiveFeed) {
+	s.live = feed
+}
+
+// SetBilling enables stopping campaigns of advertisers out of balance.
+func (s *BidService) SetBilling(billing *BillingService) {
+	s.billing = billing
+}
+
 // NoBidReason represents reasons for not bidding.
 type NoBidReason string
 
@@ -42,16 +61,20 @@ const (
 	NoBidReasonTargeting     NoBidReason = "targeting"
 	NoBidReasonInactive      NoBidReason = "inactive"
 	NoBidReasonNoCampaigns   NoBidReason = "no_campaigns"
+	NoBidReasonBalance       NoBidReason = "balance"
 )
 
 // BuildBidResponse generates a bid response for the given request.
-func (s *BidService) BuildBidResponse(br *models.BidRequest) (*models.BidResponse, error) {
+// sourceID identifies the RTB source for sampling and may be empty.
+func (s *BidService) BuildBidResponse(ctx context.Context, br *models.BidRequest, sourceID string) (*models.BidResponse, error) {
 	start := time.Now()
 	
 	if br == nil {
 		return nil, errors.New("nil bid request")
 	}
 
+	trace := s.sampler.start(sourceID)
+
 	// Record request metrics
 	source := "unknown"
 	if br.App != nil && br.App.Bundle != "" {
@@ -68,14 +91,16 @@ func (s *BidService) BuildBidResponse(br *models.BidRequest) (*models.BidRespons
 	}
 
 	// Fetch campaigns
-	campaigns, err := s.repo.ListCampaigns()
+	campaigns, err := s.repo.ListAll(ctx)
 	if err != nil {
 		s.recordNoBid("error", time.Since(start))
+		s.sampler.finish(trace, br, nil, "error")
 		return nil, err
 	}
 
 	if len(campaigns) == 0 {
 		s.recordNoBid(string(NoBidReasonNoCampaigns), time.Since(start))
+		s.sampler.finish(trace, br, nil, string(NoBidReasonNoCampaigns))
 		return nil, nil
 	}
 
@@ -85,7 +110,7 @@ func (s *BidService) BuildBidResponse(br *models.BidRequest) (*models.BidRespons
 	var seatBids []models.SeatBid
 
 	for _, imp := range br.Imp {
-		bid := s.findBestBid(br, &imp, campaigns, userID)
+		bid := s.findBestBid(br, &imp, campaigns, userID, trace)
 		if bid != nil {
 			seatBids = append(seatBids, models.SeatBid{Bid: []models.Bid{*bid}})
 		}
@@ -93,6 +118,7 @@ func (s *BidService) BuildBidResponse(br *models.BidRequest) (*models.BidRespons
 
 	if len(seatBids) == 0 {
 		s.recordNoBid("no_bids", time.Since(start))
+		s.sampler.finish(trace, br, nil, "no_bids")
 		return nil, nil
 	}
 
@@ -105,21 +131,37 @@ func (s *BidService) BuildBidResponse(br *models.BidRequest) (*models.BidRespons
 	if s.metrics != nil {
 		s.metrics.RecordBidResponse("bid", seatBids[0].Bid[0].CID, time.Since(start))
 	}
+	s.sampler.finish(trace, br, resp, "")
 
 	return resp, nil
 }
 
-// findBestBid finds the best bid for an impression.
-func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaigns []*models.Campaign, userID string) *models.Bid {
+// findBestBid finds the best bid for an impression. Line item decisions
+// are recorded on trace when the request is sampled.
+func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaigns []*models.Campaign, userID string, trace *bidTrace) *models.Bid {
 	var bestBid *models.Bid
 	var bestPriority int32 = -1 << 31
 	var bestPrice float64
+	var bestLineItemID string
 
 	for _, c := range campaigns {
 		if c.Status != models.CampaignStatusActive {
 			continue
 		}
 
+		// No campaign of an advertiser out of balance bids
+		if s.billing != nil && !s.billing.CanSpend(c.AdvertiserID) {
+			if s.metrics != nil {
+				s.metrics.RecordNoBid(string(NoBidReasonBalance))
+			}
+			for i := range c.LineItems {
+				if c.LineItems[i].IsActive {
+					trace.add(imp, c, &c.LineItems[i], models.BidStageBalance, c.AdvertiserID, 0)
+				}
+			}
+			continue
+		}
+
 		for _, li := range c.LineItems {
 			if !li.IsActive {
 				continue
@@ -132,11 +174,13 @@ func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaig
 					if s.metrics != nil {
 						s.metrics.RecordNoBid("targeting_" + result.FailedCriteria)
 					}
+					trace.add(imp, c, &li, models.BidStageTargeting, result.FailedCriteria, 0)
 					continue
 				}
 			} else {
 				// Fallback to basic targeting
 				if !s.matchesBasicTargeting(br, imp, &li) {
+					trace.add(imp, c, &li, models.BidStageTargeting, "basic", 0)
 					continue
 				}
 			}
@@ -144,6 +188,7 @@ func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaig
 			// Calculate price
 			price := s.calculateBidPrice(br, imp, &li)
 			if price <= 0 {
+				trace.add(imp, c, &li, models.BidStagePrice, string(li.BidStrategy.Type), 0)
 				continue
 			}
 
@@ -152,6 +197,7 @@ func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaig
 				if s.metrics != nil {
 					s.metrics.RecordNoBid(string(NoBidReasonBelowFloor))
 				}
+				trace.add(imp, c, &li, models.BidStageFloor, fmt.Sprintf("price %.4f < floor %.4f", price, imp.BidFloor), price)
 				continue
 			}
 
@@ -160,22 +206,27 @@ func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaig
 				if s.metrics != nil {
 					s.metrics.RecordNoBid(string(NoBidReasonPacing))
 				}
+				trace.add(imp, c, &li, models.BidStagePacing, "", price)
 				continue
 			}
 
 			// Select creative
-			cr := s.selectCreative(imp, &li)
+			cr := selectCreative(imp, &li)
 			if cr == nil {
 				if s.metrics != nil {
 					s.metrics.RecordNoBid(string(NoBidReasonNoCreative))
 				}
+				trace.add(imp, c, &li, models.BidStageCreative, "", price)
 				continue
 			}
 
+			trace.add(imp, c, &li, models.BidStageOutbid, "", price)
+
 			// Compare with current best
 			if li.Priority > bestPriority || (li.Priority == bestPriority && price > bestPrice) {
 				bestPriority = li.Priority
 				bestPrice = price
+				bestLineItemID = li.ID
 
 				// Build ad markup
 				adm := s.buildAdMarkup(imp, cr)
@@ -203,10 +254,17 @@ func (s *BidService) findBestBid(br *models.BidRequest, imp *models.Imp, campaig
 				if s.metrics != nil {
 					s.metrics.RecordBid(c.ID, li.ID, price)
 				}
+				if s.live != nil {
+					s.live.RecordBid(c.ID)
+				}
 			}
 		}
 	}
 
+	if bestBid != nil {
+		trace.settle(imp.ID, bestLineItemID)
+	}
+
 	return bestBid
 }
 
@@ -255,7 +313,8 @@ func (s *BidService) calculateBidPrice(br *models.BidRequest, imp *models.Imp, l
 }
 
 // selectCreative selects the best matching creative for an impression.
-func (s *BidService) selectCreative(imp *models.Imp, li *models.LineItem) *models.Creative {
+// Shared by the RTB bidder and the S2S offer service.
+func selectCreative(imp *models.Imp, li *models.LineItem) *models.Creative {
 	if imp.Video != nil {
 		// Video request
 		for i := range li.Creatives {
@@ -0,0 +1,575 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidBilling wraps top-up, adjustment and statement validation errors.
+	ErrInvalidBilling = errors.New("invalid billing request")
+
+	// ErrAdvertiserNotFound is returned for billing requests of unknown advertisers.
+	ErrAdvertiserNotFound = errors.New("advertiser not found")
+)
+
+// MaxStatementDays bounds a statement's period.
+const MaxStatementDays = 366
+
+// billingAccrual is the charge of one campaign and type not yet written to
+// the ledger.
+type billingAccrual struct {
+	advertiserID string
+	campaignID   string
+	txType       models.BillingTransactionType
+	amountUSD    float64
+	quantity     int64
+	start, end   time.Time
+}
+
+type accrualKey struct {
+	campaignID string
+	txType     models.BillingTransactionType
+}
+
+// billingAccount is the last known ledger state of an advertiser.
+type billingAccount struct {
+	currency    string
+	rate        float64 // Units of currency per USD; 0 until a rate is known
+	balance     float64
+	creditLimit float64
+}
+
+// BillingService keeps the advertiser ledger. Win spend and conversion
+// payouts (the CPA charge) accrue in memory per campaign and are debited
+// every flush interval as one transaction each. An advertiser is exhausted
+// when balance + credit limit - accrued charges reaches zero; BidService
+// then skips all of its campaigns until a top-up or adjustment.
+//
+// Charges are accrued in USD and converted to the advertiser's currency
+// when debited. An advertiser whose currency has no known USD rate is kept
+// exhausted, as its charges could neither be netted nor debited.
+type BillingService struct {
+	repo        storage.BillingRepo
+	advertisers *AdvertiserService
+	campaigns   storage.CampaignRepo
+	converter   *currency.Converter
+	cfg         config.BillingConfig
+	logger      *zap.Logger
+	metrics     *metrics.Metrics
+
+	mu         sync.Mutex
+	pending    map[accrualKey]*billingAccrual
+	pendingUSD map[string]float64 // Per advertiser, including a flush in progress
+	accounts   map[string]*billingAccount
+	owners     map[string]string // Campaign -> advertiser
+
+	// exhausted is read on every bid
+	exhaustedMu sync.RWMutex
+	exhausted   map[string]bool
+}
+
+// NewBillingService creates a new billing service.
+func NewBillingService(
+	repo storage.BillingRepo,
+	advertisers *AdvertiserService,
+	campaigns storage.CampaignRepo,
+	converter *currency.Converter,
+	cfg config.BillingConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *BillingService {
+	return &BillingService{
+		repo:        repo,
+		advertisers: advertisers,
+		campaigns:   campaigns,
+		converter:   converter,
+		cfg:         cfg,
+		logger:      logger,
+		metrics:     m,
+		pending:     make(map[accrualKey]*billingAccrual),
+		pendingUSD:  make(map[string]float64),
+		accounts:    make(map[string]*billingAccount),
+		owners:      make(map[string]string),
+		exhausted:   make(map[string]bool),
+	}
+}
+
+// =============================================
+// Accrual and bid gating
+// =============================================
+
+// RecordWin accrues a win's media cost.
+func (s *BillingService) RecordWin(ctx context.Context, campaignID string, spendUSD float64) {
+	s.accrue(ctx, models.BillingTxSpend, campaignID, spendUSD, time.Now())
+}
+
+// RecordConversion accrues a conversion's payout as a CPA charge.
+func (s *BillingService) RecordConversion(ctx context.Context, campaignID string, payoutUSD float64) {
+	s.accrue(ctx, models.BillingTxCPA, campaignID, payoutUSD, time.Now())
+}
+
+// accrue adds a charge to the campaign advertiser's pending amount.
+func (s *BillingService) accrue(ctx context.Context, txType models.BillingTransactionType, campaignID string, amountUSD float64, at time.Time) {
+	if !s.cfg.Enabled || amountUSD <= 0 || campaignID == "" {
+		return
+	}
+	advertiserID := s.owner(ctx, campaignID)
+	if advertiserID == "" {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := accrualKey{campaignID: campaignID, txType: txType}
+	a, ok := s.pending[key]
+	if !ok {
+		a = &billingAccrual{advertiserID: advertiserID, campaignID: campaignID, txType: txType, start: at, end: at}
+		s.pending[key] = a
+	}
+	a.amountUSD += amountUSD
+	a.quantity++
+	if at.After(a.end) {
+		a.end = at
+	}
+	s.pendingUSD[advertiserID] += amountUSD
+	s.updateExhausted(advertiserID)
+}
+
+// owner returns the campaign's advertiser, looking it up once.
+func (s *BillingService) owner(ctx context.Context, campaignID string) string {
+	s.mu.Lock()
+	adv, ok := s.owners[campaignID]
+	s.mu.Unlock()
+	if ok {
+		return adv
+	}
+
+	c, err := s.campaigns.GetByID(ctx, campaignID)
+	if err != nil {
+		s.logger.Warn("failed to get campaign for billing", zap.String("campaign_id", campaignID), zap.Error(err))
+		return ""
+	}
+	if c == nil || c.AdvertiserID == "" {
+		// Not cached: the campaign may be created or assigned later
+		return ""
+	}
+	adv = c.AdvertiserID
+	s.mu.Lock()
+	s.owners[campaignID] = adv
+	s.mu.Unlock()
+	return adv
+}
+
+// CanSpend reports whether the advertiser's campaigns may bid. Campaigns
+// without an advertiser and advertisers not loaded yet always may.
+func (s *BillingService) CanSpend(advertiserID string) bool {
+	if !s.cfg.Enabled || advertiserID == "" {
+		return true
+	}
+	s.exhaustedMu.RLock()
+	defer s.exhaustedMu.RUnlock()
+	return !s.exhausted[advertiserID]
+}
+
+// available returns the advertiser's available balance net of accrued
+// charges. Callers hold mu.
+func (s *BillingService) available(advertiserID string, acct *billingAccount) float64 {
+	return acct.balance + acct.creditLimit - s.pendingUSD[advertiserID]*acct.rate
+}
+
+// updateExhausted recomputes whether the advertiser may bid. Callers hold mu.
+func (s *BillingService) updateExhausted(advertiserID string) {
+	acct, ok := s.accounts[advertiserID]
+	if !ok {
+		return
+	}
+	exhausted := s.cfg.Enabled && (acct.rate <= 0 || s.available(advertiserID, acct) <= 0)
+
+	s.exhaustedMu.Lock()
+	defer s.exhaustedMu.Unlock()
+	if s.exhausted[advertiserID] == exhausted {
+		return
+	}
+	if exhausted {
+		s.exhausted[advertiserID] = true
+		s.logger.Warn("advertiser balance exhausted, bidding stopped",
+			zap.String("advertiser_id", advertiserID),
+			zap.Float64("balance", acct.balance),
+			zap.Float64("credit_limit", acct.creditLimit),
+			zap.String("currency", acct.currency),
+		)
+	} else {
+		delete(s.exhausted, advertiserID)
+		s.logger.Info("advertiser balance available, bidding resumed", zap.String("advertiser_id", advertiserID))
+	}
+	if s.metrics != nil {
+		s.metrics.SetExhaustedAdvertisers(len(s.exhausted))
+	}
+}
+
+// Balance returns the advertiser's balance with accrued charges, or nil if
+// the advertiser doesn't exist.
+func (s *BillingService) Balance(ctx context.Context, advertiserID string) (*models.BillingBalance, error) {
+	if _, err := s.refreshAccount(ctx, advertiserID); err != nil {
+		if errors.Is(err, ErrAdvertiserNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	acct := s.accounts[advertiserID]
+	return &models.BillingBalance{
+		AdvertiserID: advertiserID,
+		Currency:     acct.currency,
+		Balance:      acct.balance,
+		CreditLimit:  acct.creditLimit,
+		Pending:      roundAmount(s.pendingUSD[advertiserID] * acct.rate),
+		Available:    roundAmount(s.available(advertiserID, acct)),
+		Exhausted:    !s.CanSpend(advertiserID),
+	}, nil
+}
+
+// =============================================
+// Ledger refresh and flush
+// =============================================
+
+// Run debits accrued charges every flush interval until ctx is cancelled,
+// then debits what is left.
+func (s *BillingService) Run(ctx context.Context) {
+	if err := s.refresh(ctx); err != nil {
+		s.logger.Error("failed to load advertiser balances", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			s.Flush(flushCtx)
+			cancel()
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes accrued charges to the ledger and reloads balances. Charges
+// that fail to be written are kept for the next flush.
+func (s *BillingService) Flush(ctx context.Context) {
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[accrualKey]*billingAccrual, len(batch))
+	s.mu.Unlock()
+
+	for key, a := range batch {
+		err := s.charge(ctx, a)
+		s.mu.Lock()
+		if err != nil {
+			s.restore(key, a)
+		} else {
+			s.pendingUSD[a.advertiserID] -= a.amountUSD
+			if s.pendingUSD[a.advertiserID] <= 1e-9 {
+				delete(s.pendingUSD, a.advertiserID)
+			}
+		}
+		s.mu.Unlock()
+
+		if err != nil {
+			s.logger.Error("failed to debit charges",
+				zap.String("advertiser_id", a.advertiserID),
+				zap.String("campaign_id", a.campaignID),
+				zap.String("type", string(a.txType)),
+				zap.Error(err),
+			)
+			if s.metrics != nil {
+				s.metrics.RecordBillingFlushFailure()
+			}
+		}
+	}
+
+	if err := s.refresh(ctx); err != nil {
+		s.logger.Error("failed to reload advertiser balances", zap.Error(err))
+	}
+}
+
+// restore puts a failed charge back in front of newer accruals. Callers
+// hold mu.
+func (s *BillingService) restore(key accrualKey, a *billingAccrual) {
+	cur, ok := s.pending[key]
+	if !ok {
+		s.pending[key] = a
+		return
+	}
+	cur.amountUSD += a.amountUSD
+	cur.quantity += a.quantity
+	if a.start.Before(cur.start) {
+		cur.start = a.start
+	}
+	if a.end.After(cur.end) {
+		cur.end = a.end
+	}
+}
+
+// charge debits one accrual.
+func (s *BillingService) charge(ctx context.Context, a *billingAccrual) error {
+	adv, err := s.advertisers.GetAdvertiser(ctx, a.advertiserID)
+	if err != nil {
+		return fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	if adv == nil {
+		// Nothing to debit; drop the charge rather than retry forever
+		s.logger.Warn("dropping charges of unknown advertiser",
+			zap.String("advertiser_id", a.advertiserID),
+			zap.Float64("amount_usd", a.amountUSD),
+		)
+		return nil
+	}
+
+	cur := currency.Normalize(adv.Currency)
+	amount, err := s.converter.FromReporting(ctx, a.amountUSD, cur, a.end)
+	if err != nil {
+		return fmt.Errorf("failed to convert charges: %w", err)
+	}
+
+	start, end := a.start, a.end
+	tx := &models.BillingTransaction{
+		ID:           uuid.New().String(),
+		AdvertiserID: a.advertiserID,
+		Type:         a.txType,
+		Amount:       -roundAmount(amount),
+		Currency:     cur,
+		CampaignID:   a.campaignID,
+		Quantity:     a.quantity,
+		PeriodStart:  &start,
+		PeriodEnd:    &end,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if a.txType == models.BillingTxSpend {
+		tx.Description = fmt.Sprintf("Media spend, %d wins", a.quantity)
+	} else {
+		tx.Description = fmt.Sprintf("CPA charges, %d conversions", a.quantity)
+	}
+	return s.apply(ctx, tx)
+}
+
+func (s *BillingService) apply(ctx context.Context, tx *models.BillingTransaction) error {
+	if err := s.repo.Apply(ctx, tx); err != nil {
+		return err
+	}
+	if s.metrics != nil {
+		s.metrics.RecordBillingTransaction(string(tx.Type))
+	}
+	return nil
+}
+
+// refresh reloads every advertiser's balance.
+func (s *BillingService) refresh(ctx context.Context) error {
+	advertisers, err := s.advertisers.ListAdvertisers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list advertisers: %w", err)
+	}
+	for _, adv := range advertisers {
+		s.setAccount(ctx, adv)
+	}
+	return nil
+}
+
+// refreshAccount reloads one advertiser's balance.
+func (s *BillingService) refreshAccount(ctx context.Context, advertiserID string) (*models.Advertiser, error) {
+	adv, err := s.advertisers.GetAdvertiser(ctx, advertiserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	if adv == nil {
+		return nil, ErrAdvertiserNotFound
+	}
+	s.setAccount(ctx, adv)
+	return adv, nil
+}
+
+func (s *BillingService) setAccount(ctx context.Context, adv *models.Advertiser) {
+	cur := currency.Normalize(adv.Currency)
+	rate, err := s.converter.Rate(ctx, currency.ReportingCurrency, cur, time.Now())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	acct, ok := s.accounts[adv.ID]
+	if !ok {
+		acct = &billingAccount{}
+		s.accounts[adv.ID] = acct
+	}
+	if err != nil {
+		// Keep the last rate of the same currency; accrued charges are
+		// small next to a balance. Without one the advertiser stays
+		// exhausted until a rate is known.
+		s.logger.Warn("failed to get exchange rate for billing", zap.String("currency", cur), zap.Error(err))
+		rate = 0
+		if acct.currency == cur {
+			rate = acct.rate
+		}
+	}
+	acct.currency = cur
+	acct.rate = rate
+	acct.balance = adv.Balance
+	acct.creditLimit = adv.CreditLimit
+	s.updateExhausted(adv.ID)
+}
+
+// =============================================
+// Top-ups, adjustments and statements
+// =============================================
+
+// TopUp credits a payment. reference (e.g. the bank payment ID) makes the
+// call idempotent: a reused reference returns storage.ErrDuplicateBillingReference.
+func (s *BillingService) TopUp(ctx context.Context, advertiserID string, amount float64, reference, description, actor string) (*models.BillingTransaction, error) {
+	if amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidBilling)
+	}
+	return s.credit(ctx, models.BillingTxTopUp, advertiserID, amount, reference, description, actor)
+}
+
+// Adjust credits (positive amount) or debits (negative) the balance, e.g.
+// for refunds or corrections. A description is required.
+func (s *BillingService) Adjust(ctx context.Context, advertiserID string, amount float64, reference, description, actor string) (*models.BillingTransaction, error) {
+	if amount == 0 {
+		return nil, fmt.Errorf("%w: amount must not be zero", ErrInvalidBilling)
+	}
+	if description == "" {
+		return nil, fmt.Errorf("%w: description is required for adjustments", ErrInvalidBilling)
+	}
+	return s.credit(ctx, models.BillingTxAdjustment, advertiserID, amount, reference, description, actor)
+}
+
+func (s *BillingService) credit(ctx context.Context, txType models.BillingTransactionType, advertiserID string, amount float64, reference, description, actor string) (*models.BillingTransaction, error) {
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, fmt.Errorf("%w: invalid amount", ErrInvalidBilling)
+	}
+	adv, err := s.advertisers.GetAdvertiser(ctx, advertiserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	if adv == nil {
+		return nil, ErrAdvertiserNotFound
+	}
+
+	tx := &models.BillingTransaction{
+		ID:           uuid.New().String(),
+		AdvertiserID: advertiserID,
+		Type:         txType,
+		Amount:       roundAmount(amount),
+		Currency:     currency.Normalize(adv.Currency),
+		Reference:    reference,
+		Description:  description,
+		CreatedBy:    actor,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.apply(ctx, tx); err != nil {
+		if errors.Is(err, storage.ErrDuplicateBillingReference) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to apply %s: %w", txType, err)
+	}
+
+	s.logger.Info("billing transaction applied",
+		zap.String("advertiser_id", advertiserID),
+		zap.String("type", string(txType)),
+		zap.Float64("amount", tx.Amount),
+		zap.Float64("balance_after", tx.BalanceAfter),
+		zap.String("created_by", actor),
+	)
+
+	if _, err := s.refreshAccount(ctx, advertiserID); err != nil {
+		s.logger.Warn("failed to reload advertiser balance", zap.String("advertiser_id", advertiserID), zap.Error(err))
+	}
+	return tx, nil
+}
+
+// Transactions lists ledger entries, oldest first.
+func (s *BillingService) Transactions(ctx context.Context, filter storage.BillingFilter) ([]*models.BillingTransaction, error) {
+	switch filter.Type {
+	case "", models.BillingTxSpend, models.BillingTxCPA, models.BillingTxTopUp, models.BillingTxAdjustment:
+	default:
+		return nil, fmt.Errorf("%w: unknown transaction type %q", ErrInvalidBilling, filter.Type)
+	}
+	return s.repo.ListTransactions(ctx, filter)
+}
+
+// Statement summarizes the ledger over [from, to). Charges accrued but not
+// yet debited are not included.
+func (s *BillingService) Statement(ctx context.Context, advertiserID string, from, to time.Time) (*models.BillingStatement, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("%w: to must be after from", ErrInvalidBilling)
+	}
+	if to.Sub(from) > MaxStatementDays*24*time.Hour {
+		return nil, fmt.Errorf("%w: period exceeds %d days", ErrInvalidBilling, MaxStatementDays)
+	}
+	adv, err := s.advertisers.GetAdvertiser(ctx, advertiserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	if adv == nil {
+		return nil, ErrAdvertiserNotFound
+	}
+
+	opening, err := s.repo.BalanceAt(ctx, advertiserID, from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get opening balance: %w", err)
+	}
+	txs, err := s.repo.ListTransactions(ctx, storage.BillingFilter{AdvertiserID: advertiserID, From: from, To: to})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
+	}
+
+	st := &models.BillingStatement{
+		AdvertiserID:   advertiserID,
+		Currency:       currency.Normalize(adv.Currency),
+		From:           from,
+		To:             to,
+		OpeningBalance: roundAmount(opening),
+		ClosingBalance: roundAmount(opening),
+		Transactions:   txs,
+	}
+	for _, tx := range txs {
+		switch tx.Type {
+		case models.BillingTxSpend:
+			st.Spend -= tx.Amount
+		case models.BillingTxCPA:
+			st.CPA -= tx.Amount
+		case models.BillingTxTopUp:
+			st.TopUps += tx.Amount
+		case models.BillingTxAdjustment:
+			st.Adjustments += tx.Amount
+		}
+		st.ClosingBalance = tx.BalanceAfter
+	}
+	st.Spend = roundAmount(st.Spend)
+	st.CPA = roundAmount(st.CPA)
+	st.TopUps = roundAmount(st.TopUps)
+	st.Adjustments = roundAmount(st.Adjustments)
+	return st, nil
+}
+
+// roundAmount rounds to the ledger's 4 decimal places.
+func roundAmount(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
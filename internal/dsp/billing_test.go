@@ -0,0 +1,294 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// flakyBillingRepo is a BillingRepo whose writes fail while fail is set.
+type flakyBillingRepo struct {
+	storage.BillingRepo
+
+	mu   sync.Mutex
+	fail bool
+}
+
+func (r *flakyBillingRepo) setFail(fail bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fail = fail
+}
+
+func (r *flakyBillingRepo) Apply(ctx context.Context, tx *models.BillingTransaction) error {
+	r.mu.Lock()
+	fail := r.fail
+	r.mu.Unlock()
+	if fail {
+		return errors.New("ledger unavailable")
+	}
+	return r.BillingRepo.Apply(ctx, tx)
+}
+
+// billingFixture is a billing service over in-memory repos with one RUB
+// advertiser (adv-1) owning cmp-1, at 100 RUB per USD.
+type billingFixture struct {
+	billing   *BillingService
+	repo      *flakyBillingRepo
+	campaigns *storage.InMemoryCampaignRepo
+	converter *currency.Converter
+}
+
+func newBillingFixture(t *testing.T, balance, creditLimit float64) *billingFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	advRepo := storage.NewInMemoryAdvertiserRepo()
+	if err := advRepo.Upsert(ctx, &models.Advertiser{
+		ID: "adv-1", Name: "Advertiser", Currency: "RUB", CreditLimit: creditLimit,
+	}); err != nil {
+		t.Fatalf("Upsert(advertiser) error = %v", err)
+	}
+	if _, err := advRepo.UpdateBalance(ctx, "adv-1", balance); err != nil {
+		t.Fatalf("UpdateBalance() error = %v", err)
+	}
+	campaigns := storage.NewInMemoryCampaignRepo()
+	if err := campaigns.Upsert(ctx, &models.Campaign{ID: "cmp-1", AdvertiserID: "adv-1"}); err != nil {
+		t.Fatalf("Upsert(campaign) error = %v", err)
+	}
+
+	f := &billingFixture{
+		repo:      &flakyBillingRepo{BillingRepo: storage.NewInMemoryBillingRepo(advRepo)},
+		campaigns: campaigns,
+		converter: currency.NewConverter(currency.NewStaticRateProvider(map[string]float64{"RUB": 100}), nil),
+	}
+	f.billing = NewBillingService(f.repo, NewAdvertiserService(advRepo), campaigns, f.converter,
+		config.BillingConfig{Enabled: true, FlushInterval: time.Minute}, zap.NewNop(), nil)
+	if err := f.billing.refresh(ctx); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	return f
+}
+
+func (f *billingFixture) balance(t *testing.T) *models.BillingBalance {
+	t.Helper()
+	b, err := f.billing.Balance(context.Background(), "adv-1")
+	if err != nil || b == nil {
+		t.Fatalf("Balance() = %v, %v", b, err)
+	}
+	return b
+}
+
+func (f *billingFixture) transactions(t *testing.T, txType models.BillingTransactionType) []*models.BillingTransaction {
+	t.Helper()
+	txs, err := f.billing.Transactions(context.Background(), storage.BillingFilter{AdvertiserID: "adv-1", Type: txType})
+	if err != nil {
+		t.Fatalf("Transactions() error = %v", err)
+	}
+	return txs
+}
+
+func TestBillingAccrualAndExhaustion(t *testing.T) {
+	tests := []struct {
+		name          string
+		balance       float64
+		creditLimit   float64
+		spendUSD      []float64
+		payoutUSD     []float64
+		wantPending   float64
+		wantExhausted bool
+	}{
+		{"within balance", 100, 0, []float64{0.3, 0.3}, nil, 60, false},
+		{"balance used up", 100, 0, []float64{0.6}, []float64{0.4}, 100, true},
+		{"credit limit extends the balance", 100, 50, []float64{1.2}, nil, 120, false},
+		{"credit limit used up", 100, 50, []float64{1}, []float64{0.6}, 160, true},
+		{"negative balance within the credit limit", -20, 50, []float64{0.2}, nil, 20, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			f := newBillingFixture(t, tt.balance, tt.creditLimit)
+			for _, usd := range tt.spendUSD {
+				f.billing.RecordWin(ctx, "cmp-1", usd)
+			}
+			for _, usd := range tt.payoutUSD {
+				f.billing.RecordConversion(ctx, "cmp-1", usd)
+			}
+
+			b := f.balance(t)
+			if math.Abs(b.Pending-tt.wantPending) > 1e-9 || b.Balance != tt.balance {
+				t.Errorf("Balance() = %v balance, %v pending; want %v, %v", b.Balance, b.Pending, tt.balance, tt.wantPending)
+			}
+			if got := !f.billing.CanSpend("adv-1"); got != tt.wantExhausted || b.Exhausted != tt.wantExhausted {
+				t.Errorf("exhausted = %v (balance %v); want %v", got, b.Exhausted, tt.wantExhausted)
+			}
+		})
+	}
+}
+
+func TestBillingFlush(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture(t, 100, 0)
+
+	f.billing.RecordWin(ctx, "cmp-1", 0.25)
+	f.billing.RecordWin(ctx, "cmp-1", 0.5)
+	f.billing.RecordConversion(ctx, "cmp-1", 0.2)
+	// Charges of campaigns without an advertiser are not accrued
+	f.billing.RecordWin(ctx, "cmp-unknown", 1)
+
+	f.billing.Flush(ctx)
+
+	spend := f.transactions(t, models.BillingTxSpend)
+	if len(spend) != 1 || spend[0].Amount != -75 || spend[0].Quantity != 2 || spend[0].Currency != "RUB" || spend[0].CampaignID != "cmp-1" {
+		t.Errorf("spend transactions = %+v, want one of -75 RUB for 2 wins of cmp-1", spend)
+	}
+	cpa := f.transactions(t, models.BillingTxCPA)
+	if len(cpa) != 1 || cpa[0].Amount != -20 || cpa[0].Quantity != 1 {
+		t.Errorf("CPA transactions = %+v, want one of -20 RUB", cpa)
+	}
+	if b := f.balance(t); b.Balance != 5 || b.Pending != 0 || b.Available != 5 {
+		t.Errorf("Balance() = %v balance, %v pending, %v available; want 5, 0, 5", b.Balance, b.Pending, b.Available)
+	}
+
+	// Nothing accrued: nothing written
+	f.billing.Flush(ctx)
+	if n := len(f.transactions(t, "")); n != 2 {
+		t.Errorf("%d transactions after an empty flush, want 2", n)
+	}
+}
+
+func TestBillingFlushRestoresFailedCharges(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture(t, 100, 0)
+
+	f.billing.RecordWin(ctx, "cmp-1", 0.5)
+	f.repo.setFail(true)
+	f.billing.Flush(ctx)
+
+	if n := len(f.transactions(t, "")); n != 0 {
+		t.Fatalf("%d transactions written by a failed flush, want 0", n)
+	}
+	if b := f.balance(t); b.Pending != 50 || b.Balance != 100 {
+		t.Errorf("after a failed flush: %v balance, %v pending; want 100, 50", b.Balance, b.Pending)
+	}
+
+	// The failed charge is debited with later ones, once
+	f.billing.RecordWin(ctx, "cmp-1", 0.3)
+	f.repo.setFail(false)
+	f.billing.Flush(ctx)
+
+	spend := f.transactions(t, models.BillingTxSpend)
+	if len(spend) != 1 || spend[0].Amount != -80 || spend[0].Quantity != 2 {
+		t.Errorf("spend transactions = %+v, want one of -80 RUB for 2 wins", spend)
+	}
+	if b := f.balance(t); b.Pending != 0 || b.Balance != 20 {
+		t.Errorf("after flushing: %v balance, %v pending; want 20, 0", b.Balance, b.Pending)
+	}
+}
+
+func TestBillingTopUp(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture(t, 10, 0)
+
+	f.billing.RecordWin(ctx, "cmp-1", 0.2)
+	f.billing.Flush(ctx)
+	if f.billing.CanSpend("adv-1") {
+		t.Fatal("CanSpend() = true with a -10 RUB balance")
+	}
+
+	tx, err := f.billing.TopUp(ctx, "adv-1", 50, "pay-1", "Payment", "admin")
+	if err != nil {
+		t.Fatalf("TopUp() error = %v", err)
+	}
+	if tx.BalanceAfter != 40 || tx.Currency != "RUB" {
+		t.Errorf("TopUp() = %v RUB balance after, in %s; want 40 RUB", tx.BalanceAfter, tx.Currency)
+	}
+	if !f.billing.CanSpend("adv-1") {
+		t.Error("CanSpend() = false after the top-up")
+	}
+
+	// The same payment again is rejected and credited once
+	if _, err := f.billing.TopUp(ctx, "adv-1", 50, "pay-1", "Payment", "admin"); !errors.Is(err, storage.ErrDuplicateBillingReference) {
+		t.Errorf("repeated TopUp() error = %v, want %v", err, storage.ErrDuplicateBillingReference)
+	}
+	if b := f.balance(t); b.Balance != 40 {
+		t.Errorf("balance after the repeated top-up = %v, want 40", b.Balance)
+	}
+	if n := len(f.transactions(t, models.BillingTxTopUp)); n != 1 {
+		t.Errorf("%d top-up transactions, want 1", n)
+	}
+
+	invalid := []struct {
+		name   string
+		amount float64
+	}{
+		{"zero", 0},
+		{"negative", -5},
+		{"not a number", math.NaN()},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.billing.TopUp(ctx, "adv-1", tt.amount, "", "", "admin"); !errors.Is(err, ErrInvalidBilling) {
+				t.Errorf("TopUp(%v) error = %v, want %v", tt.amount, err, ErrInvalidBilling)
+			}
+		})
+	}
+}
+
+func TestBillingUnknownRate(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture(t, 0, 100)
+	advertisers := f.billing.advertisers
+
+	// Switched to a currency without a rate: charges can't be netted
+	// against the credit limit
+	adv, _ := advertisers.GetAdvertiser(ctx, "adv-1")
+	adv.Currency = "EUR"
+	if err := advertisers.UpsertAdvertiser(ctx, adv); err != nil {
+		t.Fatalf("UpsertAdvertiser() error = %v", err)
+	}
+	f.billing.RecordWin(ctx, "cmp-1", 5)
+	if err := f.billing.refresh(ctx); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if f.billing.CanSpend("adv-1") {
+		t.Error("CanSpend() = true without an EUR rate")
+	}
+
+	f.converter.SetRates(time.Now(), map[string]float64{"RUB": 100, "EUR": 0.9})
+	if err := f.billing.refresh(ctx); err != nil {
+		t.Fatalf("refresh() error = %v", err)
+	}
+	if !f.billing.CanSpend("adv-1") {
+		t.Error("CanSpend() = false once the EUR rate is known")
+	}
+	if b := f.balance(t); b.Currency != "EUR" || math.Abs(b.Pending-4.5) > 1e-9 {
+		t.Errorf("Balance() = %v %s pending, want 4.5 EUR", b.Pending, b.Currency)
+	}
+}
+
+func TestBillingOwnerLookup(t *testing.T) {
+	ctx := context.Background()
+	f := newBillingFixture(t, 100, 0)
+
+	// A campaign charged before it is saved is looked up again later
+	f.billing.RecordWin(ctx, "cmp-2", 0.1)
+	if err := f.campaigns.Upsert(ctx, &models.Campaign{ID: "cmp-2", AdvertiserID: "adv-1"}); err != nil {
+		t.Fatalf("Upsert(campaign) error = %v", err)
+	}
+	f.billing.RecordWin(ctx, "cmp-2", 0.2)
+
+	if b := f.balance(t); math.Abs(b.Pending-20) > 1e-9 {
+		t.Errorf("pending = %v, want 20 (the win after the campaign was saved)", b.Pending)
+	}
+}
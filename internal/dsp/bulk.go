@@ -0,0 +1,651 @@
+package dsp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidBulk is returned for bulk requests that can't be applied to
+// any object, such as ones without changes.
+var ErrInvalidBulk = errors.New("invalid bulk operation")
+
+// BulkService applies changes to many campaigns, line items or creatives
+// at once. Each operation is all or nothing: every changed object is
+// validated first, nothing is saved if any fails, and objects already
+// saved are restored if a later save fails. Dry runs report the same
+// per-row diffs without saving.
+type BulkService struct {
+	campaigns *CampaignService
+	creatives *CreativeService
+	audit     *AuditService
+	lifecycle *LifecycleService
+	logger    *zap.Logger
+}
+
+// NewBulkService creates a new bulk service. Saved changes are recorded
+// in audit, and campaign status changes in lifecycle.
+func NewBulkService(campaigns *CampaignService, creatives *CreativeService, audit *AuditService, lifecycle *LifecycleService, logger *zap.Logger) *BulkService {
+	return &BulkService{campaigns: campaigns, creatives: creatives, audit: audit, lifecycle: lifecycle, logger: logger}
+}
+
+// campaignChange is a campaign changed by a bulk operation and the rows
+// of the result that belong to it.
+type campaignChange struct {
+	before *models.Campaign // Nil for new campaigns
+	after  *models.Campaign
+	rows   []int
+}
+
+// =============================================
+// Line items
+// =============================================
+
+// UpdateLineItems applies req.Changes to every line item matching
+// req.Filter, with one result row per matched line item.
+func (s *BulkService) UpdateLineItems(ctx context.Context, actor *models.Principal, req *models.BulkLineItemRequest) (*models.BulkResult, error) {
+	if err := validateLineItemChanges(&req.Changes); err != nil {
+		return nil, err
+	}
+	list, err := s.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	f := &req.Filter
+	result := &models.BulkResult{DryRun: req.DryRun, Rows: make([]models.BulkRowResult, 0)}
+	var changes []*campaignChange
+	for _, c := range list {
+		if !matchCampaign(c, f.CampaignIDs, f.AdvertiserID, f.CampaignStatuses, "") {
+			continue
+		}
+		after := *c
+		after.LineItems = append([]models.LineItem(nil), c.LineItems...)
+		change := &campaignChange{before: c, after: &after}
+
+		for i := range after.LineItems {
+			li := &after.LineItems[i]
+			if !matchLineItem(li, f) {
+				continue
+			}
+			prev := *li
+			applyLineItemChanges(li, &req.Changes)
+			row := models.BulkRowResult{CampaignID: c.ID, LineItemID: li.ID, Status: models.BulkUnchanged}
+			if row.Diff, err = objectDiff(&prev, li); err != nil {
+				return nil, err
+			}
+			if len(row.Diff) > 0 {
+				row.Status = models.BulkChanged
+				if err := li.Validate(); err != nil {
+					failRow(&row, err)
+				}
+			}
+			change.rows = append(change.rows, len(result.Rows))
+			result.Rows = append(result.Rows, row)
+		}
+		if len(change.rows) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return s.finishCampaigns(ctx, actor, result, changes)
+}
+
+// validateLineItemChanges checks that changes change something and that
+// their values are in range.
+func validateLineItemChanges(ch *models.LineItemChanges) error {
+	if ch.FixedCPM == nil && ch.BidMultiplier == nil && ch.DailyBudget == nil && ch.TotalBudget == nil &&
+		ch.IsActive == nil && ch.Priority == nil && len(ch.TargetingAdd) == 0 && len(ch.TargetingRemove) == 0 {
+		return fmt.Errorf("%w: no changes", ErrInvalidBulk)
+	}
+	if ch.FixedCPM != nil && *ch.FixedCPM <= 0 {
+		return fmt.Errorf("%w: fixed_cpm must be > 0", ErrInvalidBulk)
+	}
+	if ch.BidMultiplier != nil && *ch.BidMultiplier <= 0 {
+		return fmt.Errorf("%w: bid_multiplier must be > 0", ErrInvalidBulk)
+	}
+	if ch.DailyBudget != nil && *ch.DailyBudget <= 0 {
+		return fmt.Errorf("%w: daily_budget must be > 0", ErrInvalidBulk)
+	}
+	if ch.TotalBudget != nil && *ch.TotalBudget < 0 {
+		return fmt.Errorf("%w: total_budget must not be negative", ErrInvalidBulk)
+	}
+	for _, m := range []map[string][]string{ch.TargetingAdd, ch.TargetingRemove} {
+		for field := range m {
+			if targetingList(&models.Targeting{}, field) == nil {
+				return fmt.Errorf("%w: unknown targeting list %q", ErrInvalidBulk, field)
+			}
+		}
+	}
+	return nil
+}
+
+// matchLineItem reports whether li passes the line item part of f.
+func matchLineItem(li *models.LineItem, f *models.LineItemSelector) bool {
+	if len(f.LineItemIDs) > 0 && !containsString(f.LineItemIDs, li.ID) {
+		return false
+	}
+	if f.NameContains != "" && !strings.Contains(strings.ToLower(li.Name), strings.ToLower(f.NameContains)) {
+		return false
+	}
+	if f.IsActive != nil && li.IsActive != *f.IsActive {
+		return false
+	}
+	if len(f.Countries) > 0 {
+		found := false
+		for _, c := range f.Countries {
+			if containsFold(li.Targeting.Countries, c) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// applyLineItemChanges changes li in place. Targeting lists are replaced,
+// never modified, so li may share them with the stored line item.
+func applyLineItemChanges(li *models.LineItem, ch *models.LineItemChanges) {
+	if ch.FixedCPM != nil {
+		li.BidStrategy.FixedCPM = *ch.FixedCPM
+	}
+	if ch.BidMultiplier != nil {
+		li.BidStrategy.FixedCPM = roundAmount(li.BidStrategy.FixedCPM * *ch.BidMultiplier)
+		li.BidStrategy.MinCPM = roundAmount(li.BidStrategy.MinCPM * *ch.BidMultiplier)
+		li.BidStrategy.MaxCPM = roundAmount(li.BidStrategy.MaxCPM * *ch.BidMultiplier)
+	}
+	if ch.DailyBudget != nil {
+		li.Pacing.DailyBudget = *ch.DailyBudget
+	}
+	if ch.TotalBudget != nil {
+		li.Pacing.TotalBudget = *ch.TotalBudget
+	}
+	if ch.IsActive != nil {
+		li.IsActive = *ch.IsActive
+	}
+	if ch.Priority != nil {
+		li.Priority = *ch.Priority
+	}
+	for field, values := range ch.TargetingAdd {
+		list := targetingList(&li.Targeting, field)
+		*list = addValues(*list, values)
+	}
+	for field, values := range ch.TargetingRemove {
+		list := targetingList(&li.Targeting, field)
+		*list = removeValues(*list, values)
+	}
+}
+
+// targetingList returns the targeting list named by its JSON field, or nil
+// for unknown names.
+func targetingList(t *models.Targeting, field string) *[]string {
+	switch field {
+	case "countries":
+		return &t.Countries
+	case "regions":
+		return &t.Regions
+	case "cities":
+		return &t.Cities
+	case "postal_codes":
+		return &t.PostalCodes
+	case "site_domains":
+		return &t.SiteDomains
+	case "domain_blacklist":
+		return &t.DomainBlacklist
+	case "app_bundles":
+		return &t.AppBundles
+	case "bundle_blacklist":
+		return &t.BundleBlacklist
+	case "os":
+		return &t.OS
+	case "device_makes":
+		return &t.DeviceMakes
+	case "device_models":
+		return &t.DeviceModels
+	case "carriers":
+		return &t.Carriers
+	case "cat_whitelist":
+		return &t.CatWhitelist
+	case "cat_blacklist":
+		return &t.CatBlacklist
+	case "languages":
+		return &t.Languages
+	case "audience_ids":
+		return &t.AudienceIDs
+	case "audience_exclude":
+		return &t.AudienceExclude
+	case "publisher_ids":
+		return &t.PublisherIDs
+	case "publisher_exclude":
+		return &t.PublisherExclude
+	}
+	return nil
+}
+
+// =============================================
+// Campaigns
+// =============================================
+
+// UpdateCampaigns applies req.Changes to every campaign matching
+// req.Filter, with one result row per matched campaign.
+func (s *BulkService) UpdateCampaigns(ctx context.Context, actor *models.Principal, req *models.BulkCampaignRequest) (*models.BulkResult, error) {
+	ch := &req.Changes
+	if ch.Status == nil && ch.DailyBudget == nil && ch.TotalBudget == nil && ch.EndDate == nil {
+		return nil, fmt.Errorf("%w: no changes", ErrInvalidBulk)
+	}
+	list, err := s.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	f := &req.Filter
+	result := &models.BulkResult{DryRun: req.DryRun, Rows: make([]models.BulkRowResult, 0)}
+	var changes []*campaignChange
+	for _, c := range list {
+		if !matchCampaign(c, f.CampaignIDs, f.AdvertiserID, f.Statuses, f.NameContains) {
+			continue
+		}
+		after := *c
+		if ch.Status != nil {
+			after.Status = *ch.Status
+		}
+		if ch.DailyBudget != nil {
+			after.DailyBudget = *ch.DailyBudget
+		}
+		if ch.TotalBudget != nil {
+			after.TotalBudget = *ch.TotalBudget
+		}
+		if ch.EndDate != nil {
+			after.EndDate = *ch.EndDate
+		}
+
+		row := models.BulkRowResult{CampaignID: c.ID, Status: models.BulkUnchanged}
+		if row.Diff, err = objectDiff(c, &after); err != nil {
+			return nil, err
+		}
+		if len(row.Diff) > 0 {
+			row.Status = models.BulkChanged
+		}
+		changes = append(changes, &campaignChange{before: c, after: &after, rows: []int{len(result.Rows)}})
+		result.Rows = append(result.Rows, row)
+	}
+
+	return s.finishCampaigns(ctx, actor, result, changes)
+}
+
+// matchCampaign reports whether c passes a campaign filter. Without
+// statuses, archived campaigns don't match.
+func matchCampaign(c *models.Campaign, ids []string, advertiserID string, statuses []models.CampaignStatus, name string) bool {
+	if len(ids) > 0 && !containsString(ids, c.ID) {
+		return false
+	}
+	if advertiserID != "" && c.AdvertiserID != advertiserID {
+		return false
+	}
+	if len(statuses) > 0 {
+		found := false
+		for _, st := range statuses {
+			if c.Status == st {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	} else if c.Status == models.CampaignStatusArchived {
+		return false
+	}
+	if name != "" && !strings.Contains(strings.ToLower(c.Name), strings.ToLower(name)) {
+		return false
+	}
+	return true
+}
+
+// finishCampaigns validates the changed campaigns, counts the result and,
+// unless it is a dry run or a row failed, saves them.
+func (s *BulkService) finishCampaigns(ctx context.Context, actor *models.Principal, result *models.BulkResult, changes []*campaignChange) (*models.BulkResult, error) {
+	var changed []*campaignChange
+	for _, ch := range changes {
+		dirty := ch.before == nil
+		for _, i := range ch.rows {
+			if result.Rows[i].Status != models.BulkUnchanged {
+				dirty = true
+			}
+		}
+		if !dirty {
+			continue
+		}
+		StampLineItems(ch.after, ch.before)
+		if err := ch.after.Validate(); err != nil {
+			failCampaignRows(result, ch, err)
+		}
+		changed = append(changed, ch)
+	}
+
+	countRows(result)
+	if result.DryRun || result.Failed > 0 {
+		return result, nil
+	}
+
+	var saved []*campaignChange
+	for _, ch := range changed {
+		if err := s.campaigns.UpsertCampaign(ctx, ch.after); err != nil {
+			s.restoreCampaigns(ctx, saved)
+			return nil, fmt.Errorf("failed to save campaign %s: %w", ch.after.ID, err)
+		}
+		saved = append(saved, ch)
+	}
+	for _, ch := range saved {
+		var before interface{}
+		if ch.before != nil {
+			before = ch.before
+		}
+		if _, err := s.audit.Record(ctx, actor, models.AuditCampaign, ch.after.ID, before, ch.after); err != nil {
+			s.logger.Error("failed to record audit entry", zap.String("campaign_id", ch.after.ID), zap.Error(err))
+		}
+		if s.lifecycle != nil {
+			s.lifecycle.RecordChange(ctx, actor, ch.before, ch.after)
+		}
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// failCampaignRows reports a campaign's validation errors on its rows. A
+// line item's errors go to the rows of that line item, with fields
+// relative to it; other errors (e.g. of a line item no row selected) fail
+// every row of the campaign.
+func failCampaignRows(result *models.BulkResult, ch *campaignChange, err error) {
+	var verrs models.ValidationErrors
+	if !errors.As(err, &verrs) {
+		for _, i := range ch.rows {
+			failRow(&result.Rows[i], err)
+		}
+		return
+	}
+
+	perRow := make(map[int]models.ValidationErrors)
+	for _, fe := range verrs {
+		targets := ch.rows
+		if idx, field, ok := lineItemField(fe.Field); ok && idx < len(ch.after.LineItems) {
+			var own []int
+			for _, i := range ch.rows {
+				if result.Rows[i].LineItemID == ch.after.LineItems[idx].ID {
+					own = append(own, i)
+				}
+			}
+			if len(own) > 0 {
+				targets = own
+				fe.Field = field
+			}
+		}
+		for _, i := range targets {
+			perRow[i] = append(perRow[i], fe)
+		}
+	}
+	for _, i := range ch.rows {
+		if errs := perRow[i]; len(errs) > 0 {
+			failRow(&result.Rows[i], errs)
+		}
+	}
+}
+
+// lineItemField splits a campaign validation field such as
+// "line_items[2].pacing.daily_budget" into the line item's index and its
+// own field.
+func lineItemField(field string) (int, string, bool) {
+	if !strings.HasPrefix(field, "line_items[") {
+		return 0, "", false
+	}
+	end := strings.Index(field, "].")
+	if end < 0 {
+		return 0, "", false
+	}
+	idx, err := strconv.Atoi(field[len("line_items["):end])
+	if err != nil {
+		return 0, "", false
+	}
+	return idx, field[end+2:], true
+}
+
+// restoreCampaigns undoes the saves of a failed bulk operation. New
+// campaigns are archived, as campaigns can't be deleted.
+func (s *BulkService) restoreCampaigns(ctx context.Context, saved []*campaignChange) {
+	for _, ch := range saved {
+		restore := ch.before
+		if restore == nil {
+			archived := *ch.after
+			archived.Status = models.CampaignStatusArchived
+			restore = &archived
+		}
+		prev := *restore
+		if err := s.campaigns.UpsertCampaign(ctx, &prev); err != nil {
+			s.logger.Error("failed to restore campaign after a failed bulk operation",
+				zap.String("campaign_id", restore.ID), zap.Error(err))
+		}
+	}
+}
+
+// =============================================
+// Creatives
+// =============================================
+
+// UpdateCreatives applies req.Changes to every creative matching
+// req.Filter. Copies of creatives inside line items are not changed.
+func (s *BulkService) UpdateCreatives(ctx context.Context, actor *models.Principal, req *models.BulkCreativeRequest) (*models.BulkResult, error) {
+	ch := &req.Changes
+	if ch.ClickURL == nil && ch.AuditStatus == nil && ch.ADomain == nil &&
+		len(ch.ImpressionTrackersAdd) == 0 && len(ch.ImpressionTrackersRemove) == 0 &&
+		len(ch.ClickTrackersAdd) == 0 && len(ch.ClickTrackersRemove) == 0 {
+		return nil, fmt.Errorf("%w: no changes", ErrInvalidBulk)
+	}
+	if ch.ClickURL != nil && *ch.ClickURL == "" {
+		return nil, fmt.Errorf("%w: click_url must not be empty", ErrInvalidBulk)
+	}
+
+	f := &req.Filter
+	list, err := s.creatives.ListCreatives(ctx, f.AdvertiserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list creatives: %w", err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+
+	result := &models.BulkResult{DryRun: req.DryRun, Rows: make([]models.BulkRowResult, 0)}
+	type creativeChange struct {
+		before, after *models.Creative
+	}
+	var changed []creativeChange
+	for _, cr := range list {
+		if (len(f.CreativeIDs) > 0 && !containsString(f.CreativeIDs, cr.ID)) ||
+			(f.AdvertiserID != "" && cr.AdvertiserID != f.AdvertiserID) ||
+			(f.Format != "" && cr.Format != f.Format) ||
+			(f.NameContains != "" && !strings.Contains(strings.ToLower(cr.Name), strings.ToLower(f.NameContains))) {
+			continue
+		}
+		after := *cr
+		if ch.ClickURL != nil {
+			after.ClickURL = *ch.ClickURL
+		}
+		if ch.AuditStatus != nil {
+			after.AuditStatus = *ch.AuditStatus
+		}
+		if ch.ADomain != nil {
+			after.ADomain = append([]string(nil), ch.ADomain...)
+		}
+		after.ImpressionTrackers = removeValues(addValues(after.ImpressionTrackers, ch.ImpressionTrackersAdd), ch.ImpressionTrackersRemove)
+		after.ClickTrackers = removeValues(addValues(after.ClickTrackers, ch.ClickTrackersAdd), ch.ClickTrackersRemove)
+
+		row := models.BulkRowResult{CreativeID: cr.ID, Status: models.BulkUnchanged}
+		if row.Diff, err = objectDiff(cr, &after); err != nil {
+			return nil, err
+		}
+		if len(row.Diff) > 0 {
+			row.Status = models.BulkChanged
+			changed = append(changed, creativeChange{before: cr, after: &after})
+		}
+		result.Rows = append(result.Rows, row)
+	}
+
+	countRows(result)
+	if result.DryRun || result.Failed > 0 {
+		return result, nil
+	}
+
+	var saved []creativeChange
+	for _, c := range changed {
+		if err := s.creatives.UpsertCreative(ctx, c.after); err != nil {
+			for _, prev := range saved {
+				restore := *prev.before
+				if err := s.creatives.UpsertCreative(ctx, &restore); err != nil {
+					s.logger.Error("failed to restore creative after a failed bulk operation",
+						zap.String("creative_id", restore.ID), zap.Error(err))
+				}
+			}
+			return nil, fmt.Errorf("failed to save creative %s: %w", c.after.ID, err)
+		}
+		saved = append(saved, c)
+	}
+	for _, c := range saved {
+		if _, err := s.audit.Record(ctx, actor, models.AuditCreative, c.after.ID, c.before, c.after); err != nil {
+			s.logger.Error("failed to record audit entry", zap.String("creative_id", c.after.ID), zap.Error(err))
+		}
+	}
+	result.Applied = true
+	return result, nil
+}
+
+// =============================================
+// Helpers
+// =============================================
+
+// StampLineItems sets the timestamps of c's line items: new ones are
+// created now, and ones that differ from their version in prev (nil for
+// new campaigns) are updated now. It also sets their campaign ID.
+func StampLineItems(c, prev *models.Campaign) {
+	now := time.Now().UTC()
+	old := make(map[string]*models.LineItem)
+	if prev != nil {
+		for i := range prev.LineItems {
+			old[prev.LineItems[i].ID] = &prev.LineItems[i]
+		}
+	}
+	for i := range c.LineItems {
+		li := &c.LineItems[i]
+		li.CampaignID = c.ID
+		o, ok := old[li.ID]
+		if !ok {
+			li.CreatedAt = now
+			li.UpdatedAt = now
+			continue
+		}
+		li.CreatedAt = o.CreatedAt
+		li.UpdatedAt = o.UpdatedAt
+		a, _ := json.Marshal(li)
+		b, _ := json.Marshal(o)
+		if !bytes.Equal(a, b) {
+			li.UpdatedAt = now
+		}
+	}
+}
+
+// objectDiff returns the audit diff between two versions of an object.
+func objectDiff(before, after interface{}) ([]models.AuditChange, error) {
+	b, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	a, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+	return auditDiff(b, a)
+}
+
+// failRow marks row failed with err, listing its fields for validation
+// errors. Errors add up when a row fails more than once.
+func failRow(row *models.BulkRowResult, err error) {
+	row.Status = models.BulkFailed
+	if row.Error != "" {
+		row.Error += "; "
+	}
+	row.Error += err.Error()
+	var verrs models.ValidationErrors
+	if errors.As(err, &verrs) {
+		row.Fields = append(row.Fields, verrs...)
+	}
+}
+
+// countRows fills the counters of result from its rows.
+func countRows(result *models.BulkResult) {
+	result.Matched = len(result.Rows)
+	result.Changed, result.Failed = 0, 0
+	for _, row := range result.Rows {
+		switch row.Status {
+		case models.BulkChanged, models.BulkCreated:
+			result.Changed++
+		case models.BulkFailed:
+			result.Failed++
+		}
+	}
+}
+
+// addValues returns list with the values it lacks appended.
+func addValues(list, values []string) []string {
+	if len(values) == 0 {
+		return list
+	}
+	out := append([]string(nil), list...)
+	for _, v := range values {
+		if !containsString(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// removeValues returns list without values.
+func removeValues(list, values []string) []string {
+	if len(values) == 0 {
+		return list
+	}
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if !containsString(values, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,335 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// failingCampaignRepo is a CampaignRepo whose saves of campaign failID fail.
+type failingCampaignRepo struct {
+	*storage.InMemoryCampaignRepo
+	failID string
+}
+
+func (r *failingCampaignRepo) Upsert(ctx context.Context, c *models.Campaign) error {
+	if c.ID == r.failID {
+		return errors.New("database unavailable")
+	}
+	return r.InMemoryCampaignRepo.Upsert(ctx, c)
+}
+
+// failingCreativeRepo is a CreativeRepo whose saves of creative failID fail.
+type failingCreativeRepo struct {
+	*storage.InMemoryCreativeRepo
+	failID string
+}
+
+func (r *failingCreativeRepo) Upsert(ctx context.Context, cr *models.Creative) error {
+	if cr.ID == r.failID {
+		return errors.New("database unavailable")
+	}
+	return r.InMemoryCreativeRepo.Upsert(ctx, cr)
+}
+
+// bulkFixture is a bulk service over in-memory repos holding:
+//
+//	cmp-1: li-1, li-2
+//	cmp-2: li-3 and li-4, which has no creatives and so is invalid
+//	cmp-3: li-5
+//	creatives cr-1, cr-2, cr-3
+type bulkFixture struct {
+	bulk      *BulkService
+	campaigns *failingCampaignRepo
+	creatives *failingCreativeRepo
+	audit     *AuditService
+}
+
+func newBulkFixture(t *testing.T) *bulkFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &bulkFixture{
+		campaigns: &failingCampaignRepo{InMemoryCampaignRepo: storage.NewInMemoryCampaignRepo()},
+		creatives: &failingCreativeRepo{InMemoryCreativeRepo: storage.NewInMemoryCreativeRepo()},
+		audit:     NewAuditService(storage.NewInMemoryAuditRepo(), zap.NewNop(), nil),
+	}
+	f.bulk = NewBulkService(NewCampaignService(f.campaigns), NewCreativeService(f.creatives), f.audit, nil, zap.NewNop())
+
+	lineItem := func(id, campaignID string) models.LineItem {
+		return models.LineItem{
+			ID: id, Name: id, CampaignID: campaignID, IsActive: true,
+			BidStrategy: models.BidStrategy{Type: models.BidStrategyFixedCPM, FixedCPM: 1},
+			Pacing:      models.PacingConfig{DailyBudget: 10},
+			Creatives:   []models.Creative{{ID: "cr-1"}},
+		}
+	}
+	invalid := lineItem("li-4", "cmp-2")
+	invalid.Creatives = nil
+	// Saved straight to the repo, which doesn't validate
+	for _, c := range []*models.Campaign{
+		{ID: "cmp-1", Name: "One", AdvertiserID: "adv-1", Status: models.CampaignStatusActive,
+			LineItems: []models.LineItem{lineItem("li-1", "cmp-1"), lineItem("li-2", "cmp-1")}},
+		{ID: "cmp-2", Name: "Two", AdvertiserID: "adv-1", Status: models.CampaignStatusActive,
+			LineItems: []models.LineItem{lineItem("li-3", "cmp-2"), invalid}},
+		{ID: "cmp-3", Name: "Three", AdvertiserID: "adv-1", Status: models.CampaignStatusPaused,
+			LineItems: []models.LineItem{lineItem("li-5", "cmp-3")}},
+	} {
+		if err := f.campaigns.InMemoryCampaignRepo.Upsert(ctx, c); err != nil {
+			t.Fatalf("Upsert(campaign) error = %v", err)
+		}
+	}
+	for _, id := range []string{"cr-1", "cr-2", "cr-3"} {
+		if err := f.creatives.InMemoryCreativeRepo.Upsert(ctx, &models.Creative{ID: id, AdvertiserID: "adv-1", ClickURL: "https://old.example.com"}); err != nil {
+			t.Fatalf("Upsert(creative) error = %v", err)
+		}
+	}
+	return f
+}
+
+// priorities returns the saved priority of every line item by ID.
+func (f *bulkFixture) priorities(t *testing.T) map[string]int32 {
+	t.Helper()
+	list, err := f.campaigns.ListAll(context.Background())
+	if err != nil {
+		t.Fatalf("ListAll() error = %v", err)
+	}
+	out := make(map[string]int32)
+	for _, c := range list {
+		for _, li := range c.LineItems {
+			out[li.ID] = li.Priority
+		}
+	}
+	return out
+}
+
+// auditEntries returns the number of audit entries recorded.
+func (f *bulkFixture) auditEntries(t *testing.T) int {
+	t.Helper()
+	entries, err := f.audit.List(context.Background(), storage.AuditFilter{})
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	return len(entries)
+}
+
+// rowStatuses returns the status of each row by line item, creative or
+// campaign ID.
+func rowStatuses(result *models.BulkResult) map[string]string {
+	out := make(map[string]string)
+	for _, row := range result.Rows {
+		id := row.CampaignID
+		if row.LineItemID != "" {
+			id = row.LineItemID
+		} else if row.CreativeID != "" {
+			id = row.CreativeID
+		}
+		out[id] = row.Status
+	}
+	return out
+}
+
+func TestBulkLineItemsPartialFailure(t *testing.T) {
+	priority := int32(5)
+
+	tests := []struct {
+		name         string
+		filter       models.LineItemSelector
+		wantStatuses map[string]string
+		wantFailed   string // Line item of the failed row
+		wantField    string // Its first invalid field
+	}{
+		{
+			name:   "selected line item invalid",
+			filter: models.LineItemSelector{CampaignIDs: []string{"cmp-1", "cmp-2"}},
+			wantStatuses: map[string]string{
+				"li-1": models.BulkChanged, "li-2": models.BulkChanged,
+				"li-3": models.BulkChanged, "li-4": models.BulkFailed,
+			},
+			wantFailed: "li-4",
+			wantField:  "creatives",
+		},
+		{
+			// The invalid line item isn't selected: its campaign can't be
+			// saved, so the rows of that campaign fail
+			name:   "unselected line item invalid",
+			filter: models.LineItemSelector{LineItemIDs: []string{"li-1", "li-3"}},
+			wantStatuses: map[string]string{
+				"li-1": models.BulkChanged, "li-3": models.BulkFailed,
+			},
+			wantFailed: "li-3",
+			wantField:  "line_items[1].creatives",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newBulkFixture(t)
+			result, err := f.bulk.UpdateLineItems(context.Background(), nil, &models.BulkLineItemRequest{
+				Filter: tt.filter, Changes: models.LineItemChanges{Priority: &priority},
+			})
+			if err != nil {
+				t.Fatalf("UpdateLineItems() error = %v", err)
+			}
+
+			if result.Applied || result.Failed != 1 || result.Matched != len(tt.wantStatuses) {
+				t.Errorf("result = applied %v, %d matched, %d failed; want not applied, %d, 1",
+					result.Applied, result.Matched, result.Failed, len(tt.wantStatuses))
+			}
+			got := rowStatuses(result)
+			for id, want := range tt.wantStatuses {
+				if got[id] != want {
+					t.Errorf("row of %s = %q, want %q", id, got[id], want)
+				}
+			}
+			for _, row := range result.Rows {
+				if row.LineItemID == tt.wantFailed && (len(row.Fields) == 0 || row.Fields[0].Field != tt.wantField) {
+					t.Errorf("fields of the failed row = %+v, want %s", row.Fields, tt.wantField)
+				}
+			}
+
+			// All or nothing: the valid rows weren't saved either
+			for id, p := range f.priorities(t) {
+				if p != 0 {
+					t.Errorf("%s saved with priority %d", id, p)
+				}
+			}
+			if n := f.auditEntries(t); n != 0 {
+				t.Errorf("%d audit entries, want 0", n)
+			}
+		})
+	}
+}
+
+func TestBulkLineItemsRestoresOnSaveFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newBulkFixture(t)
+	f.campaigns.failID = "cmp-3"
+	priority := int32(5)
+	req := &models.BulkLineItemRequest{
+		Filter:  models.LineItemSelector{LineItemIDs: []string{"li-1", "li-5"}},
+		Changes: models.LineItemChanges{Priority: &priority},
+	}
+
+	// cmp-1 is saved first, then cmp-3 fails
+	if _, err := f.bulk.UpdateLineItems(ctx, nil, req); err == nil {
+		t.Fatal("UpdateLineItems() error = nil, want the save failure")
+	}
+	if p := f.priorities(t); p["li-1"] != 0 || p["li-5"] != 0 {
+		t.Errorf("priorities after a failed save = li-1 %d, li-5 %d; want both restored to 0", p["li-1"], p["li-5"])
+	}
+	if n := f.auditEntries(t); n != 0 {
+		t.Errorf("%d audit entries after a failed save, want 0", n)
+	}
+
+	// A dry run saves nothing, and once saves work the change applies
+	f.campaigns.failID = ""
+	req.DryRun = true
+	result, err := f.bulk.UpdateLineItems(ctx, nil, req)
+	if err != nil || result.Applied || result.Changed != 2 {
+		t.Fatalf("dry run = %+v, %v; want 2 changed rows, not applied", result, err)
+	}
+	if p := f.priorities(t); p["li-1"] != 0 {
+		t.Errorf("dry run saved li-1 with priority %d", p["li-1"])
+	}
+
+	req.DryRun = false
+	result, err = f.bulk.UpdateLineItems(ctx, nil, req)
+	if err != nil || !result.Applied {
+		t.Fatalf("UpdateLineItems() = %+v, %v; want applied", result, err)
+	}
+	if p := f.priorities(t); p["li-1"] != 5 || p["li-5"] != 5 || p["li-2"] != 0 {
+		t.Errorf("priorities = %v, want 5 for li-1 and li-5 only", p)
+	}
+	if n := f.auditEntries(t); n != 2 {
+		t.Errorf("%d audit entries, want one per saved campaign", n)
+	}
+}
+
+func TestBulkCampaignsPartialFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newBulkFixture(t)
+	budget := 500.0
+	paused := models.CampaignStatusPaused
+
+	// cmp-2 can't be saved while its line item is invalid
+	result, err := f.bulk.UpdateCampaigns(ctx, nil, &models.BulkCampaignRequest{
+		Changes: models.CampaignChanges{Status: &paused, DailyBudget: &budget},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCampaigns() error = %v", err)
+	}
+	got := rowStatuses(result)
+	if got["cmp-1"] != models.BulkChanged || got["cmp-2"] != models.BulkFailed || got["cmp-3"] != models.BulkChanged {
+		t.Errorf("rows = %v, want cmp-2 failed and the others changed", got)
+	}
+	if result.Applied {
+		t.Error("result applied with a failed row")
+	}
+	c, _ := f.campaigns.GetByID(ctx, "cmp-1")
+	if c.DailyBudget != 0 || c.Status != models.CampaignStatusActive {
+		t.Errorf("cmp-1 saved as %s with %v daily budget, want unchanged", c.Status, c.DailyBudget)
+	}
+
+	// A save failure restores the campaigns saved before it
+	f.campaigns.failID = "cmp-3"
+	if _, err := f.bulk.UpdateCampaigns(ctx, nil, &models.BulkCampaignRequest{
+		Filter:  models.CampaignSelector{CampaignIDs: []string{"cmp-1", "cmp-3"}},
+		Changes: models.CampaignChanges{DailyBudget: &budget},
+	}); err == nil {
+		t.Fatal("UpdateCampaigns() error = nil, want the save failure")
+	}
+	c, _ = f.campaigns.GetByID(ctx, "cmp-1")
+	if c.DailyBudget != 0 {
+		t.Errorf("cmp-1 daily budget after a failed save = %v, want restored to 0", c.DailyBudget)
+	}
+}
+
+func TestBulkCreativesRestoresOnSaveFailure(t *testing.T) {
+	ctx := context.Background()
+	f := newBulkFixture(t)
+	f.creatives.failID = "cr-3"
+	url := "https://new.example.com"
+
+	if _, err := f.bulk.UpdateCreatives(ctx, nil, &models.BulkCreativeRequest{
+		Changes: models.CreativeChanges{ClickURL: &url},
+	}); err == nil {
+		t.Fatal("UpdateCreatives() error = nil, want the save failure")
+	}
+	for _, id := range []string{"cr-1", "cr-2", "cr-3"} {
+		cr, _ := f.creatives.GetByID(ctx, id)
+		if cr.ClickURL != "https://old.example.com" {
+			t.Errorf("%s click URL after a failed save = %s, want restored", id, cr.ClickURL)
+		}
+	}
+	if n := f.auditEntries(t); n != 0 {
+		t.Errorf("%d audit entries after a failed save, want 0", n)
+	}
+}
+
+func TestBulkInvalidRequest(t *testing.T) {
+	f := newBulkFixture(t)
+	zero, multiplier := 0.0, -1.0
+
+	tests := []struct {
+		name    string
+		changes models.LineItemChanges
+	}{
+		{"no changes", models.LineItemChanges{}},
+		{"zero fixed CPM", models.LineItemChanges{FixedCPM: &zero}},
+		{"negative multiplier", models.LineItemChanges{BidMultiplier: &multiplier}},
+		{"zero daily budget", models.LineItemChanges{DailyBudget: &zero}},
+		{"unknown targeting list", models.LineItemChanges{TargetingAdd: map[string][]string{"planets": {"mars"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := f.bulk.UpdateLineItems(context.Background(), nil, &models.BulkLineItemRequest{Changes: tt.changes})
+			if !errors.Is(err, ErrInvalidBulk) {
+				t.Errorf("UpdateLineItems() error = %v, want %v", err, ErrInvalidBulk)
+			}
+		})
+	}
+}
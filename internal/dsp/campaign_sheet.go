@@ -0,0 +1,436 @@
+package dsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// CampaignSheetColumns are the columns of campaign import and export
+// sheets: one row per line item, with the campaign's columns repeated on
+// each. A campaign without line items has one row with empty line item
+// columns. Lists are separated by ";".
+var CampaignSheetColumns = []string{
+	"campaign_id", "campaign_name", "advertiser_id", "status", "objective", "app_bundle",
+	"campaign_daily_budget", "campaign_total_budget", "start_date", "end_date",
+	"line_item_id", "line_item_name", "is_active", "priority", "optimization_goal",
+	"bid_strategy", "fixed_cpm", "min_cpm", "max_cpm", "target_cpi", "target_cpa",
+	"daily_budget", "total_budget", "countries", "os", "app_bundles", "creative_ids",
+}
+
+// campaignColumns are the sheet columns that belong to the campaign rather
+// than to a line item.
+var campaignColumns = map[string]bool{
+	"campaign_name": true, "advertiser_id": true, "status": true, "objective": true, "app_bundle": true,
+	"campaign_daily_budget": true, "campaign_total_budget": true, "start_date": true, "end_date": true,
+}
+
+// sheetListSep separates the values of list cells.
+const sheetListSep = ";"
+
+// ExportCampaigns returns the rows of a campaign sheet, without the
+// header, for the campaigns matching filter.
+func (s *BulkService) ExportCampaigns(ctx context.Context, filter models.CampaignSelector) ([][]string, error) {
+	list, err := s.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	sortCampaigns(list)
+
+	rows := make([][]string, 0)
+	for _, c := range list {
+		if !matchCampaign(c, filter.CampaignIDs, filter.AdvertiserID, filter.Statuses, filter.NameContains) {
+			continue
+		}
+		if len(c.LineItems) == 0 {
+			rows = append(rows, campaignSheetRow(c, nil))
+		}
+		for i := range c.LineItems {
+			rows = append(rows, campaignSheetRow(c, &c.LineItems[i]))
+		}
+	}
+	return rows, nil
+}
+
+// campaignSheetRow returns the cells of one sheet row.
+func campaignSheetRow(c *models.Campaign, li *models.LineItem) []string {
+	cells := map[string]string{
+		"campaign_id":           c.ID,
+		"campaign_name":         c.Name,
+		"advertiser_id":         c.AdvertiserID,
+		"status":                string(c.Status),
+		"objective":             string(c.Objective),
+		"app_bundle":            c.AppBundle,
+		"campaign_daily_budget": formatSheetNumber(c.DailyBudget),
+		"campaign_total_budget": formatSheetNumber(c.TotalBudget),
+		"start_date":            formatSheetTime(c.StartDate),
+		"end_date":              formatSheetTime(c.EndDate),
+	}
+	if li != nil {
+		creativeIDs := make([]string, len(li.Creatives))
+		for i, cr := range li.Creatives {
+			creativeIDs[i] = cr.ID
+		}
+		cells["line_item_id"] = li.ID
+		cells["line_item_name"] = li.Name
+		cells["is_active"] = strconv.FormatBool(li.IsActive)
+		cells["priority"] = strconv.Itoa(int(li.Priority))
+		cells["optimization_goal"] = string(li.OptimizationGoal)
+		cells["bid_strategy"] = string(li.BidStrategy.Type)
+		cells["fixed_cpm"] = formatSheetNumber(li.BidStrategy.FixedCPM)
+		cells["min_cpm"] = formatSheetNumber(li.BidStrategy.MinCPM)
+		cells["max_cpm"] = formatSheetNumber(li.BidStrategy.MaxCPM)
+		cells["target_cpi"] = formatSheetNumber(li.BidStrategy.TargetCPI)
+		cells["target_cpa"] = formatSheetNumber(li.BidStrategy.TargetCPA)
+		cells["daily_budget"] = formatSheetNumber(li.Pacing.DailyBudget)
+		cells["total_budget"] = formatSheetNumber(li.Pacing.TotalBudget)
+		cells["countries"] = strings.Join(li.Targeting.Countries, sheetListSep)
+		cells["os"] = strings.Join(li.Targeting.OS, sheetListSep)
+		cells["app_bundles"] = strings.Join(li.Targeting.AppBundles, sheetListSep)
+		cells["creative_ids"] = strings.Join(creativeIDs, sheetListSep)
+	}
+
+	row := make([]string, len(CampaignSheetColumns))
+	for i, col := range CampaignSheetColumns {
+		row[i] = cells[col]
+	}
+	return row
+}
+
+// ImportCampaigns creates and updates campaigns and line items from the
+// rows of a campaign sheet, header first. Columns may be in any order and
+// any but campaign_id may be left out; empty cells keep the current value.
+// Line items missing from the sheet are kept. With advertiserID set, only
+// that advertiser's campaigns may be imported. The result has one row per
+// sheet row.
+func (s *BulkService) ImportCampaigns(ctx context.Context, actor *models.Principal, rows [][]string, advertiserID string, dryRun bool) (*models.BulkResult, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: empty file", ErrInvalidBulk)
+	}
+	header := make(map[string]int, len(rows[0]))
+	known := make(map[string]bool, len(CampaignSheetColumns))
+	for _, col := range CampaignSheetColumns {
+		known[col] = true
+	}
+	for i, col := range rows[0] {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if col == "" {
+			continue
+		}
+		if !known[col] {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidBulk, col)
+		}
+		if _, dup := header[col]; dup {
+			return nil, fmt.Errorf("%w: duplicate column %q", ErrInvalidBulk, col)
+		}
+		header[col] = i
+	}
+	if _, ok := header["campaign_id"]; !ok {
+		return nil, fmt.Errorf("%w: campaign_id column required", ErrInvalidBulk)
+	}
+
+	result := &models.BulkResult{DryRun: dryRun, Rows: make([]models.BulkRowResult, 0, len(rows)-1)}
+	byID := make(map[string]*campaignChange)
+	var changes []*campaignChange
+	for n, cells := range rows[1:] {
+		if isBlankRow(cells) {
+			continue
+		}
+		get := func(col string) string {
+			if i, ok := header[col]; ok && i < len(cells) {
+				return strings.TrimSpace(cells[i])
+			}
+			return ""
+		}
+		row := models.BulkRowResult{Row: n + 2, CampaignID: get("campaign_id"), LineItemID: get("line_item_id")}
+		if row.CampaignID == "" {
+			row.Status = models.BulkFailed
+			row.Error = "campaign_id is required"
+			result.Rows = append(result.Rows, row)
+			continue
+		}
+
+		ch, ok := byID[row.CampaignID]
+		if !ok {
+			current, err := s.campaigns.GetCampaign(ctx, row.CampaignID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get campaign %s: %w", row.CampaignID, err)
+			}
+			ch = &campaignChange{before: current}
+			if current != nil {
+				after := *current
+				after.LineItems = append([]models.LineItem(nil), current.LineItems...)
+				ch.after = &after
+			} else {
+				ch.after = &models.Campaign{
+					ID:           row.CampaignID,
+					AdvertiserID: advertiserID,
+					Status:       models.CampaignStatusDraft,
+					LineItems:    []models.LineItem{},
+				}
+			}
+			byID[row.CampaignID] = ch
+			changes = append(changes, ch)
+		}
+
+		var errs models.ValidationErrors
+		applySheetCampaign(ch.after, get, &errs)
+		if row.LineItemID != "" {
+			idx := findSheetLineItem(ch.after, row.LineItemID)
+			if idx < 0 {
+				ch.after.LineItems = append(ch.after.LineItems, models.LineItem{ID: row.LineItemID, CampaignID: row.CampaignID})
+				idx = len(ch.after.LineItems) - 1
+			}
+			s.applySheetLineItem(ctx, &ch.after.LineItems[idx], get, &errs)
+		}
+		if advertiserID != "" && (ch.after.AdvertiserID != advertiserID || (ch.before != nil && ch.before.AdvertiserID != advertiserID)) {
+			errs = append(errs, models.FieldError{Field: "advertiser_id", Message: "advertiser not allowed for this API key"})
+		}
+		if len(errs) > 0 {
+			failRow(&row, errs)
+		}
+		ch.rows = append(ch.rows, len(result.Rows))
+		result.Rows = append(result.Rows, row)
+	}
+
+	// Diffs are taken once every row is applied, so each row shows the
+	// final state of its line item, and the campaign's first row also
+	// shows the campaign's own changes.
+	for _, ch := range changes {
+		diff, err := objectDiff(ch.before, ch.after)
+		if err != nil {
+			return nil, err
+		}
+		for k, i := range ch.rows {
+			row := &result.Rows[i]
+			if ch.before == nil || (row.LineItemID != "" && findSheetLineItem(ch.before, row.LineItemID) < 0) {
+				if row.Status != models.BulkFailed {
+					row.Status = models.BulkCreated
+				}
+			}
+			prefix := "line_items[" + row.LineItemID + "]"
+			for _, d := range diff {
+				if isEmptyValue(d.Before) && isEmptyValue(d.After) {
+					continue
+				}
+				inLineItems := d.Field == "line_items" || strings.HasPrefix(d.Field, "line_items[")
+				if (k == 0 && !inLineItems) || (row.LineItemID != "" && strings.HasPrefix(d.Field, prefix)) {
+					row.Diff = append(row.Diff, d)
+				}
+			}
+			if row.Status == "" {
+				row.Status = models.BulkUnchanged
+				if len(row.Diff) > 0 {
+					row.Status = models.BulkChanged
+				}
+			}
+		}
+	}
+
+	return s.finishCampaigns(ctx, actor, result, changes)
+}
+
+// applySheetCampaign sets the campaign columns of a row on c.
+func applySheetCampaign(c *models.Campaign, get func(string) string, errs *models.ValidationErrors) {
+	for col := range campaignColumns {
+		v := get(col)
+		if v == "" {
+			continue
+		}
+		switch col {
+		case "campaign_name":
+			c.Name = v
+		case "advertiser_id":
+			c.AdvertiserID = v
+		case "status":
+			c.Status = models.CampaignStatus(v)
+		case "objective":
+			c.Objective = models.CampaignObjective(v)
+		case "app_bundle":
+			c.AppBundle = v
+		case "campaign_daily_budget":
+			setSheetNumber(&c.DailyBudget, col, v, errs)
+		case "campaign_total_budget":
+			setSheetNumber(&c.TotalBudget, col, v, errs)
+		case "start_date":
+			setSheetTime(&c.StartDate, col, v, errs)
+		case "end_date":
+			setSheetTime(&c.EndDate, col, v, errs)
+		}
+	}
+}
+
+// applySheetLineItem sets the line item columns of a row on li. Creatives
+// are looked up by ID in the creative library.
+func (s *BulkService) applySheetLineItem(ctx context.Context, li *models.LineItem, get func(string) string, errs *models.ValidationErrors) {
+	if v := get("line_item_name"); v != "" {
+		li.Name = v
+	}
+	if v := get("is_active"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			li.IsActive = b
+		} else {
+			*errs = append(*errs, models.FieldError{Field: "is_active", Message: "must be true or false"})
+		}
+	}
+	if v := get("priority"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+			li.Priority = int32(n)
+		} else {
+			*errs = append(*errs, models.FieldError{Field: "priority", Message: "must be an integer"})
+		}
+	}
+	if v := get("optimization_goal"); v != "" {
+		li.OptimizationGoal = models.OptimizationGoal(v)
+	}
+	if v := get("bid_strategy"); v != "" {
+		li.BidStrategy.Type = models.BidStrategyType(v)
+	}
+	numbers := []struct {
+		col string
+		dst *float64
+	}{
+		{"fixed_cpm", &li.BidStrategy.FixedCPM},
+		{"min_cpm", &li.BidStrategy.MinCPM},
+		{"max_cpm", &li.BidStrategy.MaxCPM},
+		{"target_cpi", &li.BidStrategy.TargetCPI},
+		{"target_cpa", &li.BidStrategy.TargetCPA},
+		{"daily_budget", &li.Pacing.DailyBudget},
+		{"total_budget", &li.Pacing.TotalBudget},
+	}
+	for _, n := range numbers {
+		if v := get(n.col); v != "" {
+			setSheetNumber(n.dst, n.col, v, errs)
+		}
+	}
+	if v := get("countries"); v != "" {
+		li.Targeting.Countries = splitSheetList(v)
+	}
+	if v := get("os"); v != "" {
+		li.Targeting.OS = splitSheetList(v)
+	}
+	if v := get("app_bundles"); v != "" {
+		li.Targeting.AppBundles = splitSheetList(v)
+	}
+	if v := get("creative_ids"); v != "" {
+		ids := splitSheetList(v)
+		creatives := make([]models.Creative, 0, len(ids))
+		for _, id := range ids {
+			cr, err := s.creatives.GetCreative(ctx, id)
+			if err != nil || cr == nil {
+				*errs = append(*errs, models.FieldError{Field: "creative_ids", Message: "unknown creative " + id})
+				continue
+			}
+			creatives = append(creatives, *cr)
+		}
+		li.Creatives = creatives
+	}
+}
+
+func findSheetLineItem(c *models.Campaign, id string) int {
+	for i := range c.LineItems {
+		if c.LineItems[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func isBlankRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func splitSheetList(v string) []string {
+	parts := strings.Split(v, sheetListSep)
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// setSheetNumber sets *dst to the number in cell v of column col. Decimal
+// commas are accepted, as spreadsheets in some locales write them.
+func setSheetNumber(dst *float64, col, v string, errs *models.ValidationErrors) {
+	f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+	if err != nil {
+		*errs = append(*errs, models.FieldError{Field: col, Message: "must be a number"})
+		return
+	}
+	*dst = f
+}
+
+// setSheetTime sets *dst to the date (YYYY-MM-DD, UTC) or RFC 3339 time in
+// cell v of column col.
+func setSheetTime(dst *time.Time, col, v string, errs *models.ValidationErrors) {
+	if t, err := time.Parse("2006-01-02", v); err == nil {
+		*dst = t
+		return
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		*errs = append(*errs, models.FieldError{Field: col, Message: "must be YYYY-MM-DD or RFC 3339"})
+		return
+	}
+	*dst = t
+}
+
+// isEmptyValue reports whether a diff value is empty: null, zero, false,
+// "", an empty list or object, or the zero time. Diffs of created objects
+// leave empty fields out.
+func isEmptyValue(raw json.RawMessage) bool {
+	var v interface{}
+	if len(raw) == 0 || json.Unmarshal(raw, &v) != nil {
+		return len(raw) == 0
+	}
+	switch x := v.(type) {
+	case nil:
+		return true
+	case bool:
+		return !x
+	case string:
+		return x == "" || x == "0001-01-01T00:00:00Z"
+	case float64:
+		return x == 0
+	case []interface{}:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+	return false
+}
+
+func formatSheetNumber(v float64) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatSheetTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// sortCampaigns orders campaigns by ID, so sheets are stable.
+func sortCampaigns(list []*models.Campaign) {
+	for i := 1; i < len(list); i++ {
+		for j := i; j > 0 && list[j].ID < list[j-1].ID; j-- {
+			list[j], list[j-1] = list[j-1], list[j]
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package dsp
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Link verification errors.
+var (
+	ErrLinkUnsigned = errors.New("tracking link is not signed")
+	ErrLinkTampered = errors.New("tracking link signature mismatch")
+	ErrLinkExpired  = errors.New("tracking link expired")
+)
+
+// signedLinkParams are the click link parameters covered by the signature.
+var signedLinkParams = []string{"cid", "cr", "li", "src", "st", "ts"}
+
+// =============================================
+// Link Signing
+// =============================================
+
+// LinkSigner signs tracking links with HMAC-SHA256 so partners cannot forge
+// or alter them.
+type LinkSigner struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+// NewLinkSigner creates a link signer. Links older than ttl are rejected
+// (ttl <= 0 disables expiry).
+func NewLinkSigner(secret string, ttl time.Duration) *LinkSigner {
+	return &LinkSigner{secret: []byte(secret), ttl: ttl}
+}
+
+// Sign sets the ts and sig parameters on params.
+func (s *LinkSigner) Sign(params url.Values, at time.Time) {
+	params.Set("ts", strconv.FormatInt(at.Unix(), 10))
+	params.Set("sig", s.signature(params))
+}
+
+// Verify checks the signature and age of a link.
+func (s *LinkSigner) Verify(params url.Values, now time.Time) error {
+	sig := params.Get("sig")
+	if sig == "" {
+		return ErrLinkUnsigned
+	}
+	if !hmac.Equal([]byte(sig), []byte(s.signature(params))) {
+		return ErrLinkTampered
+	}
+
+	ts, err := strconv.ParseInt(params.Get("ts"), 10, 64)
+	if err != nil {
+		return ErrLinkTampered
+	}
+	if s.ttl > 0 && now.Sub(time.Unix(ts, 0)) > s.ttl {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+// signature signs the query-encoded signed parameters, so a value holding
+// "&" or "=" can't be split into other parameters.
+func (s *LinkSigner) signature(params url.Values) string {
+	signed := make(url.Values, len(signedLinkParams))
+	for _, name := range signedLinkParams {
+		signed.Set(name, params.Get(name))
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signed.Encode()))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// =============================================
+// Click Deduplication
+// =============================================
+
+// ClickDeduper remembers recent clicks so repeats within a window reuse the
+// original click instead of creating a new one.
+type ClickDeduper interface {
+	// Claim stores clickID under key for window unless the key is already
+	// taken; it returns the existing click ID for duplicates and "" otherwise.
+	Claim(ctx context.Context, key, clickID string, window time.Duration) (string, error)
+}
+
+// ClickDedupKey builds the dedup key for a click: the device IFA, or IP and
+// User-Agent when there is no IFA, scoped to campaign and creative.
+func ClickDedupKey(campaignID, creativeID, deviceIFA, ip, userAgent string) string {
+	device := deviceIFA
+	if device == "" {
+		h := sha256.Sum256([]byte(ip + "|" + userAgent))
+		device = hex.EncodeToString(h[:16])
+	}
+	return campaignID + ":" + creativeID + ":" + device
+}
+
+// RedisClickDeduper implements ClickDeduper using Redis SETNX.
+type RedisClickDeduper struct {
+	client *redis.Client
+}
+
+// NewRedisClickDeduper creates a new Redis-backed click deduper.
+func NewRedisClickDeduper(client *redis.Client) *RedisClickDeduper {
+	return &RedisClickDeduper{client: client}
+}
+
+// Claim implements ClickDeduper.
+func (d *RedisClickDeduper) Claim(ctx context.Context, key, clickID string, window time.Duration) (string, error) {
+	redisKey := "click:dedup:" + key
+	ok, err := d.client.SetNX(ctx, redisKey, clickID, window).Result()
+	if err != nil {
+		return "", err
+	}
+	if ok {
+		return "", nil
+	}
+	existing, err := d.client.Get(ctx, redisKey).Result()
+	if err == redis.Nil {
+		return "", nil // Expired in between
+	}
+	if err != nil {
+		return "", err
+	}
+	return existing, nil
+}
+
+// InMemoryClickDeduper implements ClickDeduper in process memory.
+type InMemoryClickDeduper struct {
+	mu     sync.Mutex
+	clicks map[string]dedupEntry
+	lastGC time.Time
+}
+
+type dedupEntry struct {
+	clickID   string
+	expiresAt time.Time
+}
+
+// NewInMemoryClickDeduper creates a new in-memory click deduper.
+func NewInMemoryClickDeduper() *InMemoryClickDeduper {
+	return &InMemoryClickDeduper{
+		clicks: make(map[string]dedupEntry),
+		lastGC: time.Now(),
+	}
+}
+
+// Claim implements ClickDeduper.
+func (d *InMemoryClickDeduper) Claim(ctx context.Context, key, clickID string, window time.Duration) (string, error) {
+	now := time.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if e, ok := d.clicks[key]; ok && now.Before(e.expiresAt) {
+		return e.clickID, nil
+	}
+	d.clicks[key] = dedupEntry{clickID: clickID, expiresAt: now.Add(window)}
+
+	// Drop expired entries periodically
+	if now.Sub(d.lastGC) > window {
+		for k, e := range d.clicks {
+			if now.After(e.expiresAt) {
+				delete(d.clicks, k)
+			}
+		}
+		d.lastGC = now
+	}
+
+	return "", nil
+}
@@ -0,0 +1,208 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/fraud"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// redisAddrEnv names the Redis server the Redis deduper test runs against.
+// The keys it writes expire within a second.
+const redisAddrEnv = "VECTOR_DSP_TEST_REDIS_ADDR"
+
+func TestLinkSigner(t *testing.T) {
+	signedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	link := func() url.Values {
+		params := url.Values{
+			"cid": {"cmp-1"}, "cr": {"cr-1"}, "li": {"li-1"}, "src": {"src-1"}, "st": {"s2s"},
+			"gaid": {"ifa-1"},
+		}
+		NewLinkSigner("secret", time.Hour).Sign(params, signedAt)
+		return params
+	}
+
+	tests := []struct {
+		name   string
+		signer *LinkSigner
+		modify func(params url.Values)
+		now    time.Time
+		want   error
+	}{
+		{
+			name:   "valid link",
+			signer: NewLinkSigner("secret", time.Hour),
+			now:    signedAt.Add(time.Minute),
+		},
+		{
+			name:   "unsigned parameters may change",
+			signer: NewLinkSigner("secret", time.Hour),
+			modify: func(params url.Values) { params.Set("gaid", "ifa-2"); params.Set("sub1", "x") },
+			now:    signedAt.Add(time.Minute),
+		},
+		{
+			name:   "changed campaign",
+			signer: NewLinkSigner("secret", time.Hour),
+			modify: func(params url.Values) { params.Set("cid", "cmp-2") },
+			now:    signedAt.Add(time.Minute),
+			want:   ErrLinkTampered,
+		},
+		{
+			name:   "changed timestamp",
+			signer: NewLinkSigner("secret", time.Hour),
+			modify: func(params url.Values) { params.Set("ts", "9999999999") },
+			now:    signedAt.Add(time.Minute),
+			want:   ErrLinkTampered,
+		},
+		{
+			name:   "other secret",
+			signer: NewLinkSigner("other", time.Hour),
+			now:    signedAt.Add(time.Minute),
+			want:   ErrLinkTampered,
+		},
+		{
+			name:   "missing signature",
+			signer: NewLinkSigner("secret", time.Hour),
+			modify: func(params url.Values) { params.Del("sig") },
+			now:    signedAt.Add(time.Minute),
+			want:   ErrLinkUnsigned,
+		},
+		{
+			name:   "expired",
+			signer: NewLinkSigner("secret", time.Hour),
+			now:    signedAt.Add(time.Hour + time.Second),
+			want:   ErrLinkExpired,
+		},
+		{
+			name:   "no expiry without a ttl",
+			signer: NewLinkSigner("secret", 0),
+			now:    signedAt.Add(365 * 24 * time.Hour),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := link()
+			if tt.modify != nil {
+				tt.modify(params)
+			}
+			if err := tt.signer.Verify(params, tt.now); !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Joined without escaping, both links would read cr=cr-1&li=x&li=li-1
+	t.Run("parameter boundary moved", func(t *testing.T) {
+		signer := NewLinkSigner("secret", time.Hour)
+		params := url.Values{"cid": {"cmp-1"}, "cr": {"cr-1&li=x"}, "li": {"li-1"}, "src": {"src-1"}, "st": {"s2s"}}
+		signer.Sign(params, signedAt)
+		params.Set("cr", "cr-1")
+		params.Set("li", "x&li=li-1")
+		if err := signer.Verify(params, signedAt); !errors.Is(err, ErrLinkTampered) {
+			t.Errorf("Verify() error = %v, want %v", err, ErrLinkTampered)
+		}
+	})
+}
+
+func TestClickDedupers(t *testing.T) {
+	dedupers := map[string]func(t *testing.T) ClickDeduper{
+		"in-memory": func(t *testing.T) ClickDeduper { return NewInMemoryClickDeduper() },
+		"redis": func(t *testing.T) ClickDeduper {
+			addr := os.Getenv(redisAddrEnv)
+			if addr == "" {
+				t.Skipf("%s not set", redisAddrEnv)
+			}
+			client := redis.NewClient(&redis.Options{Addr: addr})
+			t.Cleanup(func() { client.Close() })
+			return NewRedisClickDeduper(client)
+		},
+	}
+
+	for name, newDeduper := range dedupers {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			d := newDeduper(t)
+			// Unique per run, as Redis keeps keys between runs
+			ifa := "ifa-" + time.Now().Format("150405.000000000")
+			key := ClickDedupKey("cmp-1", "cr-1", ifa, "", "")
+			window := 200 * time.Millisecond
+
+			if got, err := d.Claim(ctx, key, "click-1", window); err != nil || got != "" {
+				t.Fatalf("first Claim() = %q, %v; want a new click", got, err)
+			}
+			if got, err := d.Claim(ctx, key, "click-2", window); err != nil || got != "click-1" {
+				t.Errorf("repeat Claim() = %q, %v; want click-1", got, err)
+			}
+			other := ClickDedupKey("cmp-1", "cr-2", ifa, "", "")
+			if got, err := d.Claim(ctx, other, "click-3", window); err != nil || got != "" {
+				t.Errorf("Claim() of another key = %q, %v; want a new click", got, err)
+			}
+
+			time.Sleep(window + 50*time.Millisecond)
+			if got, err := d.Claim(ctx, key, "click-4", window); err != nil || got != "" {
+				t.Errorf("Claim() after the window = %q, %v; want a new click", got, err)
+			}
+			if got, err := d.Claim(ctx, key, "click-5", window); err != nil || got != "click-4" {
+				t.Errorf("repeat Claim() after the window = %q, %v; want click-4", got, err)
+			}
+		})
+	}
+}
+
+func TestClickDedupKey(t *testing.T) {
+	byIFA := ClickDedupKey("cmp-1", "cr-1", "ifa-1", "10.0.0.1", "ua")
+	if byIFA != "cmp-1:cr-1:ifa-1" {
+		t.Errorf("key with an IFA = %q", byIFA)
+	}
+	byIP := ClickDedupKey("cmp-1", "cr-1", "", "10.0.0.1", "ua")
+	if byIP == ClickDedupKey("cmp-1", "cr-1", "", "10.0.0.2", "ua") {
+		t.Error("keys without an IFA don't depend on the IP")
+	}
+	if byIP == ClickDedupKey("cmp-1", "cr-1", "", "10.0.0.1", "other ua") {
+		t.Error("keys without an IFA don't depend on the User-Agent")
+	}
+}
+
+func TestRegisterClickDedup(t *testing.T) {
+	ctx := context.Background()
+
+	events := storage.NewInMemoryEventStore()
+	counter := fraud.NewInMemoryClickCounter()
+	scorer := fraud.NewScorer(fraud.Config{MaxClicksPerIFA: 100, ClickWindow: time.Hour}, counter, nil)
+	s := NewTrackingService(events, storage.NewInMemoryCampaignRepo(), nil, nil, scorer, "", zap.NewNop(), nil)
+	s.SetClickDeduper(NewInMemoryClickDeduper(), time.Minute)
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.RegisterClick(ctx, "cmp-1", "cr-1", "li-1", "s2s", "src-1", "",
+			"ifa-1", "", "10.0.0.1", "ua", "", "", "", "", ""); err != nil {
+			t.Fatalf("RegisterClick() error = %v", err)
+		}
+	}
+
+	clicks, err := events.GetClicksByDevice(ctx, "ifa-1", time.Time{})
+	if err != nil || len(clicks) != 1 {
+		t.Fatalf("GetClicksByDevice() = %d clicks, %v; want 1", len(clicks), err)
+	}
+
+	// Duplicates were counted for click spamming before being dropped
+	n, err := counter.Incr(ctx, "ifa:ifa-1", time.Hour)
+	if err != nil || n != 4 {
+		t.Errorf("IFA click count = %d, %v; want 4 (three clicks and this one)", n, err)
+	}
+	var reported int64
+	for _, st := range scorer.SourceReport(time.Now(), time.Now()) {
+		reported += st.Clicks
+	}
+	if reported != 3 {
+		t.Errorf("fraud report clicks = %d, want 3", reported)
+	}
+}
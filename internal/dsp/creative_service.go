@@ -1,10 +1,11 @@
 package dsp
 
 import (
-    "time"
+	"context"
+	"time"
 
-    "github.com/radiusdt/vector-dsp/internal/models"
-    "github.com/radiusdt/vector-dsp/internal/storage"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
 )
 
 // CreativeService provides CRUD operations over creatives.  It sets
@@ -13,22 +14,25 @@ import (
 // through their ID.  This service does not enforce any specific
 // relations between advertisers and campaigns.
 type CreativeService struct {
-    repo storage.CreativeRepo
+	repo storage.CreativeRepo
 }
 
 // NewCreativeService constructs a CreativeService backed by the given repo.
 func NewCreativeService(repo storage.CreativeRepo) *CreativeService {
-    return &CreativeService{repo: repo}
+	return &CreativeService{repo: repo}
 }
 
 // ListCreatives returns all creatives, optionally filtered by advertiser ID.
-func (s *CreativeService) ListCreatives(advertiserID string) ([]*models.Creative, error) {
-    return s.repo.ListCreativesByAdvertiser(advertiserID)
+func (s *CreativeService) ListCreatives(ctx context.Context, advertiserID string) ([]*models.Creative, error) {
+	if advertiserID == "" {
+		return s.repo.ListAll(ctx)
+	}
+	return s.repo.ListByAdvertiser(ctx, advertiserID)
 }
 
 // GetCreative returns a creative by ID.
-func (s *CreativeService) GetCreative(id string) (*models.Creative, error) {
-    return s.repo.GetCreative(id)
+func (s *CreativeService) GetCreative(ctx context.Context, id string) (*models.Creative, error) {
+	return s.repo.GetByID(ctx, id)
 }
 
 // UpsertCreative validates the creative and saves it.  It populates
@@ -36,14 +40,14 @@ func (s *CreativeService) GetCreative(id string) (*models.Creative, error) {
 // fields.  The Creative struct in models defines no validation so
 // additional checks (e.g. max size, valid URL) should be implemented
 // at the API layer.
-func (s *CreativeService) UpsertCreative(c *models.Creative) error {
-    if c == nil {
-        return nil
-    }
-    now := time.Now().UTC()
-    if c.CreatedAt.IsZero() {
-        c.CreatedAt = now
-    }
-    c.UpdatedAt = now
-    return s.repo.UpsertCreative(c)
-}
\ No newline at end of file
+func (s *CreativeService) UpsertCreative(ctx context.Context, c *models.Creative) error {
+	if c == nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	if c.CreatedAt.IsZero() {
+		c.CreatedAt = now
+	}
+	c.UpdatedAt = now
+	return s.repo.Upsert(ctx, c)
+}
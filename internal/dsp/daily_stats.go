@@ -0,0 +1,498 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// MaxRollupDays bounds a manual rollup or reconciliation range.
+const MaxRollupDays = 366
+
+// rollupDimensions is the grain of rolled-up daily stats.
+var rollupDimensions = []string{
+	storage.ReportDimCampaign, storage.ReportDimSourceType, storage.ReportDimSource, storage.ReportDimCountry,
+}
+
+// rollupMetrics are the raw event counts and amounts rolled up.
+var rollupMetrics = []string{
+	storage.ReportMetricImpressions, storage.ReportMetricClicks, storage.ReportMetricConversions,
+	storage.ReportMetricInstalls, storage.ReportMetricSpend, storage.ReportMetricRevenue, storage.ReportMetricPayout,
+}
+
+// DailyStatsService rolls events into daily stats per UTC date, campaign,
+// source and country, and serves /api/stats from them. Rolling up a day
+// replaces its rows, so days can be rolled up again after late postbacks.
+type DailyStatsService struct {
+	reports   storage.ReportStore // nil if the event store can't run reports
+	repo      storage.StatsRepo
+	reporting *ReportingService
+	cfg       config.StatsConfig
+	logger    *zap.Logger
+	metrics   *metrics.Metrics
+
+	// mu serializes rollups so a manual rerun and a pass don't interleave
+	mu sync.Mutex
+}
+
+// NewDailyStatsService creates a new daily stats service. Money is
+// converted for display with the reporting service's rates.
+func NewDailyStatsService(
+	eventStore storage.EventStore,
+	repo storage.StatsRepo,
+	reporting *ReportingService,
+	cfg config.StatsConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *DailyStatsService {
+	reports, _ := eventStore.(storage.ReportStore)
+	return &DailyStatsService{
+		reports:   reports,
+		repo:      repo,
+		reporting: reporting,
+		cfg:       cfg,
+		logger:    logger,
+		metrics:   m,
+	}
+}
+
+// Run rolls up new events every rollup interval until ctx is cancelled.
+func (s *DailyStatsService) Run(ctx context.Context) {
+	if s.reports == nil {
+		s.logger.Warn("event store does not support reports, daily stats rollup disabled")
+		return
+	}
+
+	ticker := time.NewTicker(s.cfg.RollupInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.pass(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			s.logger.Error("daily stats rollup failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// pass rolls up from the late window behind the watermark through now and
+// advances the watermark. Without a watermark it backfills BackfillDays.
+func (s *DailyStatsService) pass(ctx context.Context, now time.Time) error {
+	watermark, err := s.repo.GetRollupWatermark(ctx)
+	if err != nil {
+		return err
+	}
+	from := watermark.Add(-s.cfg.LateWindow)
+	if watermark.IsZero() {
+		from = now.AddDate(0, 0, -s.cfg.BackfillDays)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.rollupRange(ctx, from, now); err != nil {
+		return err
+	}
+	if err := s.repo.SetRollupWatermark(ctx, now); err != nil {
+		return err
+	}
+	if s.metrics != nil {
+		s.metrics.SetStatsWatermark(now)
+	}
+	return nil
+}
+
+// Rollup rolls up every UTC date from from through to, e.g. to pick up late
+// postbacks outside the late window. It returns the number of days rolled.
+func (s *DailyStatsService) Rollup(ctx context.Context, from, to time.Time) (int, error) {
+	if s.reports == nil {
+		return 0, ErrReportsUnsupported
+	}
+	if err := checkRollupRange(from, to); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rollupRange(ctx, from, to)
+}
+
+func checkRollupRange(from, to time.Time) error {
+	from, to = storage.StatsDate(from), storage.StatsDate(to)
+	if to.Before(from) {
+		return fmt.Errorf("%w: start date must not be after end date", ErrInvalidReport)
+	}
+	if to.Sub(from) >= MaxRollupDays*24*time.Hour {
+		return fmt.Errorf("%w: range must be at most %d days", ErrInvalidReport, MaxRollupDays)
+	}
+	return nil
+}
+
+func (s *DailyStatsService) rollupRange(ctx context.Context, from, to time.Time) (int, error) {
+	days := 0
+	for day := storage.StatsDate(from); !day.After(storage.StatsDate(to)); day = day.AddDate(0, 0, 1) {
+		if err := ctx.Err(); err != nil {
+			return days, err
+		}
+		err := s.rollupDay(ctx, day)
+		if s.metrics != nil {
+			status := "ok"
+			if err != nil {
+				status = "error"
+			}
+			s.metrics.RecordStatsRollup(status)
+		}
+		if err != nil {
+			return days, fmt.Errorf("failed to roll up %s: %w", day.Format("2006-01-02"), err)
+		}
+		days++
+	}
+	return days, nil
+}
+
+// rollupDay replaces the day's daily stats with totals from the events.
+func (s *DailyStatsService) rollupDay(ctx context.Context, day time.Time) error {
+	rows, err := s.queryRaw(ctx, day, day.AddDate(0, 0, 1), rollupDimensions)
+	if err != nil {
+		return err
+	}
+
+	stats := make([]*storage.DailyStats, 0, len(rows))
+	for _, row := range rows {
+		stats = append(stats, &storage.DailyStats{
+			Date:        day,
+			CampaignID:  row.Dimensions[storage.ReportDimCampaign],
+			SourceType:  row.Dimensions[storage.ReportDimSourceType],
+			SourceID:    row.Dimensions[storage.ReportDimSource],
+			GeoCountry:  row.Dimensions[storage.ReportDimCountry],
+			Impressions: int64(row.Metrics[storage.ReportMetricImpressions]),
+			Clicks:      int64(row.Metrics[storage.ReportMetricClicks]),
+			Installs:    int64(row.Metrics[storage.ReportMetricInstalls]),
+			Events:      int64(row.Metrics[storage.ReportMetricConversions] - row.Metrics[storage.ReportMetricInstalls]),
+			Spend:       row.Metrics[storage.ReportMetricSpend],
+			Revenue:     row.Metrics[storage.ReportMetricRevenue],
+			Payout:      row.Metrics[storage.ReportMetricPayout],
+		})
+	}
+	return s.repo.ReplaceDailyStats(ctx, day, stats)
+}
+
+// queryRaw pages through the event store's totals over [start, end) in UTC.
+func (s *DailyStatsService) queryRaw(ctx context.Context, start, end time.Time, dims []string) ([]storage.ReportRow, error) {
+	q := storage.ReportQuery{
+		Start:      start,
+		End:        end,
+		Location:   time.UTC,
+		Dimensions: dims,
+		Metrics:    rollupMetrics,
+		Limit:      storage.MaxReportLimit,
+	}
+
+	var rows []storage.ReportRow
+	for {
+		page, err := s.reports.QueryReport(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, page.Rows...)
+		if len(page.Rows) < q.Limit || int64(len(rows)) >= page.Total {
+			return rows, nil
+		}
+		q.Offset += q.Limit
+	}
+}
+
+// StatsStatus describes the rollup's progress.
+type StatsStatus struct {
+	Enabled    bool       `json:"enabled"`
+	Watermark  *time.Time `json:"watermark,omitempty"` // Events before it are rolled up
+	LateWindow string     `json:"late_window"`
+}
+
+// Status returns the rollup watermark.
+func (s *DailyStatsService) Status(ctx context.Context) (*StatsStatus, error) {
+	watermark, err := s.repo.GetRollupWatermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &StatsStatus{
+		Enabled:    s.cfg.RollupEnabled && s.reports != nil,
+		LateWindow: s.cfg.LateWindow.String(),
+	}
+	if !watermark.IsZero() {
+		status.Watermark = &watermark
+	}
+	return status, nil
+}
+
+// StatsDiscrepancy is a metric whose daily stats total differs from the
+// raw events on a date.
+type StatsDiscrepancy struct {
+	Date   string  `json:"date"`
+	Metric string  `json:"metric"`
+	Raw    float64 `json:"raw"`
+	Rolled float64 `json:"rolled"`
+}
+
+// StatsReconciliation is the outcome of comparing daily stats to events.
+type StatsReconciliation struct {
+	StartDate     string             `json:"start_date"`
+	EndDate       string             `json:"end_date"`
+	Days          int                `json:"days"`
+	OK            bool               `json:"ok"`
+	Discrepancies []StatsDiscrepancy `json:"discrepancies"`
+}
+
+// Reconcile compares per-date totals of the daily stats with the raw
+// events from from through to. Amounts may differ by rounding to the
+// table's four decimals.
+func (s *DailyStatsService) Reconcile(ctx context.Context, from, to time.Time) (*StatsReconciliation, error) {
+	if s.reports == nil {
+		return nil, ErrReportsUnsupported
+	}
+	if err := checkRollupRange(from, to); err != nil {
+		return nil, err
+	}
+	from, to = storage.StatsDate(from), storage.StatsDate(to)
+
+	raw, err := s.queryRaw(ctx, from, to.AddDate(0, 0, 1), []string{storage.ReportDimDate})
+	if err != nil {
+		return nil, err
+	}
+	rawByDate := make(map[string]map[string]float64, len(raw))
+	for _, row := range raw {
+		rawByDate[row.Dimensions[storage.ReportDimDate]] = row.Metrics
+	}
+
+	rolled, err := s.repo.GetDailyStats(ctx, storage.StatsFilter{
+		StartDate: from,
+		EndDate:   to,
+		GroupBy:   []string{storage.StatsGroupDate},
+	})
+	if err != nil {
+		return nil, err
+	}
+	rolledByDate := make(map[string]*storage.DailyStats, len(rolled))
+	for _, st := range rolled {
+		rolledByDate[st.Date.Format("2006-01-02")] = st
+	}
+
+	rec := &StatsReconciliation{
+		StartDate:     from.Format("2006-01-02"),
+		EndDate:       to.Format("2006-01-02"),
+		Discrepancies: []StatsDiscrepancy{},
+	}
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		rec.Days++
+		date := day.Format("2006-01-02")
+		m := rawByDate[date]
+		st := rolledByDate[date]
+		if st == nil {
+			st = &storage.DailyStats{}
+		}
+
+		checks := []struct {
+			metric      string
+			raw, rolled float64
+			tolerance   float64
+		}{
+			{storage.ReportMetricImpressions, m[storage.ReportMetricImpressions], float64(st.Impressions), 0},
+			{storage.ReportMetricClicks, m[storage.ReportMetricClicks], float64(st.Clicks), 0},
+			{storage.ReportMetricInstalls, m[storage.ReportMetricInstalls], float64(st.Installs), 0},
+			{storage.ReportMetricConversions, m[storage.ReportMetricConversions], float64(st.Installs + st.Events), 0},
+			{storage.ReportMetricSpend, m[storage.ReportMetricSpend], st.Spend, 0.01},
+			{storage.ReportMetricRevenue, m[storage.ReportMetricRevenue], st.Revenue, 0.01},
+			{storage.ReportMetricPayout, m[storage.ReportMetricPayout], st.Payout, 0.01},
+		}
+		for _, c := range checks {
+			if math.Abs(c.raw-c.rolled) > c.tolerance {
+				rec.Discrepancies = append(rec.Discrepancies, StatsDiscrepancy{
+					Date: date, Metric: c.metric, Raw: c.raw, Rolled: c.rolled,
+				})
+			}
+		}
+	}
+	rec.OK = len(rec.Discrepancies) == 0
+
+	if !rec.OK {
+		s.logger.Warn("daily stats differ from events",
+			zap.String("start_date", rec.StartDate),
+			zap.String("end_date", rec.EndDate),
+			zap.Int("discrepancies", len(rec.Discrepancies)))
+	}
+	return rec, nil
+}
+
+// StatsQuery selects daily stats for /api/stats.
+type StatsQuery struct {
+	CampaignID  string
+	CampaignIDs []string // Any of these campaigns; empty matches all
+	SourceType  string
+	SourceID    string
+	Country     string
+	StartDate   time.Time // Inclusive; defaults to 30 days before the end date
+	EndDate     time.Time // Inclusive; defaults to today
+	GroupBy     []string  // See storage.StatsGroups; defaults to campaign_id
+	Currency    string
+}
+
+// StatsRow is a group of daily stats with derived rates.
+type StatsRow struct {
+	Date       string `json:"date,omitempty"`
+	CampaignID string `json:"campaign_id,omitempty"`
+	SourceType string `json:"source_type,omitempty"`
+	SourceID   string `json:"source_id,omitempty"`
+	Country    string `json:"country,omitempty"`
+	Currency   string `json:"currency"`
+
+	Impressions int64 `json:"impressions"`
+	Clicks      int64 `json:"clicks"`
+	Installs    int64 `json:"installs"`
+	Events      int64 `json:"events"` // Non-install conversions
+
+	Spend   float64 `json:"spend"`
+	Revenue float64 `json:"revenue"`
+	Payout  float64 `json:"payout"`
+	Profit  float64 `json:"profit"`
+
+	CTR  float64 `json:"ctr"`  // Click-through rate (%)
+	CVR  float64 `json:"cvr"`  // Installs per click (%)
+	CPI  float64 `json:"cpi"`  // Spend per install
+	ROAS float64 `json:"roas"` // Revenue / spend
+}
+
+// Query returns daily stats grouped as requested. Money is converted into
+// the query currency at each day's rate.
+func (s *DailyStatsService) Query(ctx context.Context, q StatsQuery) ([]StatsRow, error) {
+	if len(q.GroupBy) == 0 {
+		q.GroupBy = []string{storage.StatsGroupCampaign}
+	}
+	if err := storage.ValidateStatsGroupBy(q.GroupBy); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	if q.EndDate.IsZero() {
+		q.EndDate = time.Now()
+	}
+	if q.StartDate.IsZero() {
+		q.StartDate = q.EndDate.AddDate(0, 0, -30)
+	}
+	cur := currency.Normalize(q.Currency)
+
+	// Fetch per day so each day converts at its own rate, then regroup
+	groupBy := q.GroupBy
+	if !containsGroup(groupBy, storage.StatsGroupDate) {
+		groupBy = append([]string{storage.StatsGroupDate}, groupBy...)
+	}
+	daily, err := s.repo.GetDailyStats(ctx, storage.StatsFilter{
+		CampaignID:  q.CampaignID,
+		CampaignIDs: q.CampaignIDs,
+		SourceType:  q.SourceType,
+		SourceID:    q.SourceID,
+		Country:     q.Country,
+		StartDate:   q.StartDate,
+		EndDate:     q.EndDate,
+		GroupBy:     groupBy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]*StatsRow)
+	for _, st := range daily {
+		row := StatsRow{Currency: cur}
+		if containsGroup(q.GroupBy, storage.StatsGroupDate) {
+			row.Date = st.Date.Format("2006-01-02")
+		}
+		if containsGroup(q.GroupBy, storage.StatsGroupCampaign) {
+			row.CampaignID = st.CampaignID
+		}
+		if containsGroup(q.GroupBy, storage.StatsGroupSourceType) {
+			row.SourceType = st.SourceType
+		}
+		if containsGroup(q.GroupBy, storage.StatsGroupSource) {
+			row.SourceID = st.SourceID
+		}
+		if containsGroup(q.GroupBy, storage.StatsGroupCountry) {
+			row.Country = st.GeoCountry
+		}
+		key := strings.Join([]string{row.Date, row.CampaignID, row.SourceType, row.SourceID, row.Country}, "\x00")
+		agg, ok := byKey[key]
+		if !ok {
+			agg = &row
+			byKey[key] = agg
+		}
+
+		agg.Impressions += st.Impressions
+		agg.Clicks += st.Clicks
+		agg.Installs += st.Installs
+		agg.Events += st.Events
+		spend, err := s.reporting.convert(ctx, st.Spend, cur, st.Date)
+		if err != nil {
+			return nil, err
+		}
+		revenue, err := s.reporting.convert(ctx, st.Revenue, cur, st.Date)
+		if err != nil {
+			return nil, err
+		}
+		payout, err := s.reporting.convert(ctx, st.Payout, cur, st.Date)
+		if err != nil {
+			return nil, err
+		}
+		agg.Spend += spend
+		agg.Revenue += revenue
+		agg.Payout += payout
+	}
+
+	rows := make([]StatsRow, 0, len(byKey))
+	for _, row := range byKey {
+		row.Profit = row.Revenue - row.Spend
+		if row.Impressions > 0 {
+			row.CTR = float64(row.Clicks) / float64(row.Impressions) * 100
+		}
+		if row.Clicks > 0 {
+			row.CVR = float64(row.Installs) / float64(row.Clicks) * 100
+		}
+		if row.Installs > 0 {
+			row.CPI = row.Spend / float64(row.Installs)
+		}
+		if row.Spend > 0 {
+			row.ROAS = row.Revenue / row.Spend
+		}
+		rows = append(rows, *row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		for _, pair := range [][2]string{
+			{a.Date, b.Date}, {a.CampaignID, b.CampaignID}, {a.SourceType, b.SourceType},
+			{a.SourceID, b.SourceID}, {a.Country, b.Country},
+		} {
+			if pair[0] != pair[1] {
+				return pair[0] < pair[1]
+			}
+		}
+		return false
+	})
+	return rows, nil
+}
+
+func containsGroup(groups []string, g string) bool {
+	for _, v := range groups {
+		if v == g {
+			return true
+		}
+	}
+	return false
+}
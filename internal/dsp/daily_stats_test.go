@@ -0,0 +1,156 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestDailyStatsRollup(t *testing.T) {
+	ctx := context.Background()
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	now := day.Add(36 * time.Hour)
+
+	events := storage.NewInMemoryEventStore()
+	stats := NewDailyStatsService(events, storage.NewInMemoryStatsRepo(), NewReportingService(events, nil),
+		config.StatsConfig{RollupInterval: time.Minute, LateWindow: 48 * time.Hour, BackfillDays: 2}, zap.NewNop(), nil)
+
+	n := 0
+	click := func(at time.Time) {
+		t.Helper()
+		n++
+		if err := events.SaveClick(ctx, &models.Click{
+			ID: fmt.Sprintf("click-%d", n), Timestamp: at, CampaignID: "cmp-1", SourceType: "s2s", SourceID: "src-1",
+		}); err != nil {
+			t.Fatalf("SaveClick() error = %v", err)
+		}
+	}
+	install := func(at time.Time) {
+		t.Helper()
+		n++
+		if err := events.SaveConversion(ctx, &models.Conversion{
+			ID: fmt.Sprintf("conv-%d", n), Timestamp: at, CampaignID: "cmp-1", SourceType: "s2s", SourceID: "src-1",
+			Event: storage.InstallEvent, Payout: 1, PayoutCurrency: "USD", PayoutUSD: 1,
+		}); err != nil {
+			t.Fatalf("SaveConversion() error = %v", err)
+		}
+	}
+	// rolled returns the daily stats totals of date
+	rolled := func(date time.Time) StatsRow {
+		t.Helper()
+		rows, err := stats.Query(ctx, StatsQuery{
+			StartDate: date, EndDate: date, GroupBy: []string{storage.StatsGroupDate}, Currency: "USD",
+		})
+		if err != nil {
+			t.Fatalf("Query() error = %v", err)
+		}
+		if len(rows) == 0 {
+			return StatsRow{}
+		}
+		return rows[0]
+	}
+	check := func(stage string, date time.Time, clicks, installs int64, spend float64) {
+		t.Helper()
+		row := rolled(date)
+		if row.Clicks != clicks || row.Installs != installs || math.Abs(row.Spend-spend) > 1e-9 {
+			t.Errorf("%s: %s = %d clicks, %d installs, %v spend; want %d, %d, %v", stage, date.Format("2006-01-02"),
+				row.Clicks, row.Installs, row.Spend, clicks, installs, spend)
+		}
+	}
+
+	click(day.Add(10 * time.Hour))
+	click(day.Add(11 * time.Hour))
+	install(day.Add(12 * time.Hour))
+	if err := events.SaveWin(ctx, &models.Win{
+		ID: "win-1", Timestamp: day.Add(9 * time.Hour), CampaignID: "cmp-1", WinPrice: 0.5, WinPriceUSD: 0.5,
+	}); err != nil {
+		t.Fatalf("SaveWin() error = %v", err)
+	}
+
+	if err := stats.pass(ctx, now); err != nil {
+		t.Fatalf("pass() error = %v", err)
+	}
+	check("first pass", day, 2, 1, 0.5)
+
+	// Passes and manual reruns replace the day instead of adding to it
+	if err := stats.pass(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatalf("pass() error = %v", err)
+	}
+	if days, err := stats.Rollup(ctx, day, day); err != nil || days != 1 {
+		t.Fatalf("Rollup() = %d, %v; want 1 day", days, err)
+	}
+	check("rerun", day, 2, 1, 0.5)
+
+	// A late postback within the late window lands on its own day
+	install(day.Add(23 * time.Hour))
+	if err := stats.pass(ctx, now.Add(time.Hour)); err != nil {
+		t.Fatalf("pass() error = %v", err)
+	}
+	check("late postback", day, 2, 2, 0.5)
+	check("late postback", day.AddDate(0, 0, 1), 0, 0, 0)
+
+	status, err := stats.Status(ctx)
+	if err != nil || status.Watermark == nil || !status.Watermark.Equal(now.Add(time.Hour)) {
+		t.Errorf("Status() = %+v, %v; want watermark %v", status, err, now.Add(time.Hour))
+	}
+
+	// Behind the late window, only a manual rollup picks an event up
+	old := day.AddDate(0, 0, -3)
+	click(old.Add(time.Hour))
+	if err := stats.pass(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("pass() error = %v", err)
+	}
+	check("event behind the late window", old, 0, 0, 0)
+
+	rec, err := stats.Reconcile(ctx, old, day)
+	if err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if rec.OK || rec.Days != 4 || len(rec.Discrepancies) != 1 {
+		t.Fatalf("Reconcile() = %+v, want one discrepancy over 4 days", rec)
+	}
+	if d := rec.Discrepancies[0]; d.Date != "2026-02-27" || d.Metric != storage.ReportMetricClicks || d.Raw != 1 || d.Rolled != 0 {
+		t.Errorf("discrepancy = %+v, want 1 raw and 0 rolled clicks on 2026-02-27", d)
+	}
+
+	if days, err := stats.Rollup(ctx, old, old); err != nil || days != 1 {
+		t.Fatalf("Rollup() = %d, %v; want 1 day", days, err)
+	}
+	check("manual rollup", old, 1, 0, 0)
+	if rec, err := stats.Reconcile(ctx, old, day); err != nil || !rec.OK {
+		t.Errorf("Reconcile() after the rollup = %+v, %v; want ok", rec, err)
+	}
+}
+
+func TestDailyStatsRollupRange(t *testing.T) {
+	events := storage.NewInMemoryEventStore()
+	stats := NewDailyStatsService(events, storage.NewInMemoryStatsRepo(), NewReportingService(events, nil),
+		config.StatsConfig{}, zap.NewNop(), nil)
+	day := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		from, to time.Time
+		wantErr  bool
+	}{
+		{"one day", day, day.Add(23 * time.Hour), false},
+		{"end before start", day, day.AddDate(0, 0, -1), true},
+		{"longest range", day, day.AddDate(0, 0, MaxRollupDays-1), false},
+		{"too long", day, day.AddDate(0, 0, MaxRollupDays), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := stats.Rollup(context.Background(), tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Rollup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package dsp
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJWT(t *testing.T) {
+	secret := []byte("secret")
+	issued := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	claims := func() *AccessClaims {
+		return &AccessClaims{
+			Subject:      "user-1",
+			Role:         "trader",
+			AdvertiserID: "adv-1",
+			Issuer:       jwtIssuer,
+			IssuedAt:     issued.Unix(),
+			ExpiresAt:    issued.Add(15 * time.Minute).Unix(),
+		}
+	}
+	sign := func(t *testing.T, secret []byte, c *AccessClaims) string {
+		t.Helper()
+		token, err := signJWT(secret, c)
+		if err != nil {
+			t.Fatalf("signJWT() error = %v", err)
+		}
+		return token
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		got, err := parseJWT(secret, sign(t, secret, claims()), issued.Add(time.Minute))
+		if err != nil {
+			t.Fatalf("parseJWT() error = %v", err)
+		}
+		if *got != *claims() {
+			t.Errorf("parseJWT() = %+v, want %+v", got, claims())
+		}
+	})
+
+	// replacePayload swaps the token's payload and keeps its signature
+	replacePayload := func(token, payload string) string {
+		parts := strings.Split(token, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(payload))
+		return strings.Join(parts, ".")
+	}
+	valid := sign(t, secret, claims())
+	noIssuer := claims()
+	noIssuer.Issuer = "other"
+	noSubject := claims()
+	noSubject.Subject = ""
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		wantErr error
+	}{
+		{"just before expiry", valid, issued.Add(15*time.Minute - time.Second), nil},
+		{"at expiry", valid, issued.Add(15 * time.Minute), ErrInvalidToken},
+		{"other secret", sign(t, []byte("other"), claims()), issued, ErrInvalidToken},
+		{"tampered payload", replacePayload(valid, `{"sub":"admin-1","role":"admin","iss":"vector-dsp","exp":9999999999}`), issued, ErrInvalidToken},
+		{"unsigned", strings.Join(strings.Split(valid, ".")[:2], ".") + ".", issued, ErrInvalidToken},
+		{"alg none", base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." +
+			strings.Split(valid, ".")[1] + ".", issued, ErrInvalidToken},
+		{"other issuer", sign(t, secret, noIssuer), issued, ErrInvalidToken},
+		{"no subject", sign(t, secret, noSubject), issued, ErrInvalidToken},
+		{"malformed", "not-a-token", issued, ErrInvalidToken},
+		{"empty", "", issued, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseJWT(secret, tt.token, tt.now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("parseJWT() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
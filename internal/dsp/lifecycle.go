@@ -0,0 +1,307 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// lifecycleActor is the principal of the scheduler's changes in the
+// audit log.
+var lifecycleActor = &models.Principal{Kind: models.PrincipalSystem, Name: "lifecycle scheduler"}
+
+// maxLifecycleSteps bounds the transitions of one campaign in one pass
+// (e.g. scheduled -> active -> paused).
+const maxLifecycleSteps = 3
+
+// LifecycleService moves campaigns through their statuses: it activates
+// scheduled campaigns at their start date, ends campaigns at their end date
+// or once spend reaches the total budget, and pauses active campaigns
+// while their advertiser's balance is exhausted, resuming them when it is
+// topped up. Every status change, by the scheduler or through the API, is
+// recorded as a transition with its reason and posted to the status
+// webhook.
+//
+// Spend is read from the daily stats rollup, so a campaign may overspend
+// its total budget by up to a rollup interval of delivery.
+type LifecycleService struct {
+	campaigns   *CampaignService
+	advertisers *AdvertiserService
+	billing     *BillingService
+	stats       storage.StatsRepo
+	repo        storage.CampaignTransitionRepo
+	audit       *AuditService
+	cfg         config.LifecycleConfig
+	httpClient  *http.Client
+	logger      *zap.Logger
+	metrics     *metrics.Metrics
+}
+
+// NewLifecycleService creates a new lifecycle service.
+func NewLifecycleService(
+	campaigns *CampaignService,
+	advertisers *AdvertiserService,
+	billing *BillingService,
+	stats storage.StatsRepo,
+	repo storage.CampaignTransitionRepo,
+	audit *AuditService,
+	cfg config.LifecycleConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *LifecycleService {
+	return &LifecycleService{
+		campaigns:   campaigns,
+		advertisers: advertisers,
+		billing:     billing,
+		stats:       stats,
+		repo:        repo,
+		audit:       audit,
+		cfg:         cfg,
+		httpClient:  &http.Client{Timeout: cfg.WebhookTimeout},
+		logger:      logger,
+		metrics:     m,
+	}
+}
+
+// =============================================
+// Scheduler
+// =============================================
+
+// Run checks campaigns every interval until ctx is cancelled.
+func (s *LifecycleService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Pass(ctx, time.Now().UTC()); err != nil && ctx.Err() == nil {
+			s.logger.Error("campaign lifecycle pass failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass applies every due transition as of now. A campaign that fails to
+// transition is logged and retried on the next pass.
+func (s *LifecycleService) Pass(ctx context.Context, now time.Time) error {
+	list, err := s.campaigns.ListCampaigns(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	for _, c := range list {
+		for step := 0; step < maxLifecycleSteps; step++ {
+			to, reason, detail, err := s.next(ctx, c, now)
+			if err != nil {
+				s.logger.Error("failed to check campaign lifecycle", zap.String("campaign_id", c.ID), zap.Error(err))
+				break
+			}
+			if to == "" {
+				break
+			}
+			saved, err := s.transition(ctx, c, to, reason, detail)
+			if err != nil {
+				s.logger.Error("failed to change campaign status",
+					zap.String("campaign_id", c.ID),
+					zap.String("to", string(to)),
+					zap.String("reason", reason),
+					zap.Error(err),
+				)
+				break
+			}
+			c = saved
+		}
+	}
+	return nil
+}
+
+// next returns the status campaign c should move to as of now and why, or
+// an empty status if it should stay.
+func (s *LifecycleService) next(ctx context.Context, c *models.Campaign, now time.Time) (models.CampaignStatus, string, string, error) {
+	switch c.Status {
+	case models.CampaignStatusScheduled, models.CampaignStatusActive, models.CampaignStatusPaused:
+	default:
+		return "", "", "", nil
+	}
+
+	if !c.EndDate.IsZero() && !now.Before(c.EndDate) {
+		return models.CampaignStatusEnded, models.TransitionEndDate,
+			"end date " + c.EndDate.UTC().Format(time.RFC3339), nil
+	}
+	if c.TotalBudget > 0 && c.Status != models.CampaignStatusScheduled && s.stats != nil {
+		agg, err := s.stats.GetCampaignStats(ctx, c.ID, time.Time{}, now)
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to get campaign spend: %w", err)
+		}
+		if agg != nil && agg.Spend >= c.TotalBudget {
+			return models.CampaignStatusEnded, models.TransitionBudgetSpent,
+				fmt.Sprintf("spent $%.2f of $%.2f", agg.Spend, c.TotalBudget), nil
+		}
+	}
+
+	canSpend := s.billing == nil || s.billing.CanSpend(c.AdvertiserID)
+	switch c.Status {
+	case models.CampaignStatusScheduled:
+		if c.StartDate.IsZero() {
+			return models.CampaignStatusActive, models.TransitionStartDate, "no start date", nil
+		}
+		if !now.Before(c.StartDate) {
+			return models.CampaignStatusActive, models.TransitionStartDate,
+				"start date " + c.StartDate.UTC().Format(time.RFC3339), nil
+		}
+	case models.CampaignStatusActive:
+		if !canSpend {
+			return models.CampaignStatusPaused, models.TransitionBalanceExhausted,
+				"advertiser " + c.AdvertiserID + " has no available balance", nil
+		}
+	case models.CampaignStatusPaused:
+		if !canSpend {
+			break
+		}
+		// Only campaigns the scheduler paused for the balance resume; a
+		// manual pause since then wins.
+		last, err := s.repo.List(ctx, storage.TransitionFilter{CampaignID: c.ID, Limit: 1})
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to get last transition: %w", err)
+		}
+		if len(last) == 1 && last[0].Reason == models.TransitionBalanceExhausted {
+			return models.CampaignStatusActive, models.TransitionBalanceRestored,
+				"advertiser " + c.AdvertiserID + " balance available", nil
+		}
+	}
+	return "", "", "", nil
+}
+
+// transition saves campaign c with status to and records the change.
+func (s *LifecycleService) transition(ctx context.Context, c *models.Campaign, to models.CampaignStatus, reason, detail string) (*models.Campaign, error) {
+	updated := *c
+	updated.Status = to
+	if err := s.campaigns.UpsertCampaign(ctx, &updated); err != nil {
+		return nil, fmt.Errorf("failed to save campaign: %w", err)
+	}
+	if s.audit != nil {
+		if _, err := s.audit.Record(ctx, lifecycleActor, models.AuditCampaign, c.ID, c, &updated); err != nil {
+			s.logger.Error("failed to record audit entry", zap.String("campaign_id", c.ID), zap.Error(err))
+		}
+	}
+	s.record(ctx, lifecycleActor, c, &updated, reason, detail)
+	return &updated, nil
+}
+
+// =============================================
+// Transitions
+// =============================================
+
+// RecordChange records a status change made through the API. before is nil
+// for new campaigns; new drafts and unchanged statuses aren't recorded.
+func (s *LifecycleService) RecordChange(ctx context.Context, actor *models.Principal, before, after *models.Campaign) {
+	if after == nil {
+		return
+	}
+	var from models.CampaignStatus
+	if before != nil {
+		from = before.Status
+	}
+	if from == after.Status || (before == nil && (after.Status == "" || after.Status == models.CampaignStatusDraft)) {
+		return
+	}
+	s.record(ctx, actor, before, after, models.TransitionManual, "")
+}
+
+// record appends the transition from before to after and posts the status
+// webhook. Failures are logged; the status change itself stands.
+func (s *LifecycleService) record(ctx context.Context, actor *models.Principal, before, after *models.Campaign, reason, detail string) {
+	t := &models.CampaignTransition{
+		ID:           uuid.New().String(),
+		CampaignID:   after.ID,
+		AdvertiserID: after.AdvertiserID,
+		To:           after.Status,
+		Reason:       reason,
+		Detail:       detail,
+		ActorKind:    models.AuditAnonymous,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if before != nil {
+		t.From = before.Status
+	}
+	if actor != nil {
+		t.ActorKind = actor.Kind
+		t.ActorID = actor.ID
+		t.ActorName = actor.Name
+	}
+
+	if err := s.repo.Append(ctx, t); err != nil {
+		s.logger.Error("failed to record campaign transition", zap.String("campaign_id", t.CampaignID), zap.Error(err))
+	}
+	if s.metrics != nil {
+		s.metrics.RecordCampaignTransition(string(t.To), t.Reason)
+	}
+	s.logger.Info("campaign status changed",
+		zap.String("campaign_id", t.CampaignID),
+		zap.String("from", string(t.From)),
+		zap.String("to", string(t.To)),
+		zap.String("reason", t.Reason),
+		zap.String("detail", t.Detail),
+	)
+
+	if s.cfg.WebhookURL != "" {
+		go s.notify(t, after.Name)
+	}
+}
+
+// Transitions lists recorded transitions, newest first.
+func (s *LifecycleService) Transitions(ctx context.Context, filter storage.TransitionFilter) ([]*models.CampaignTransition, error) {
+	list, err := s.repo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign transitions: %w", err)
+	}
+	return list, nil
+}
+
+// =============================================
+// Webhooks
+// =============================================
+
+// notify posts the status webhook of t, with the advertiser's account
+// manager so the receiver can route it.
+func (s *LifecycleService) notify(t *models.CampaignTransition, campaignName string) {
+	body := models.CampaignStatusWebhook{
+		Event:        "campaign.status_changed",
+		Transition:   *t,
+		CampaignName: campaignName,
+	}
+	if s.advertisers != nil && t.AdvertiserID != "" {
+		adv, err := s.advertisers.GetAdvertiser(context.Background(), t.AdvertiserID)
+		if err != nil {
+			s.logger.Warn("failed to get advertiser for status webhook", zap.String("advertiser_id", t.AdvertiserID), zap.Error(err))
+		}
+		if adv != nil {
+			body.AdvertiserName = adv.Name
+			body.AccountManager = adv.AccountManager
+		}
+	}
+
+	err := postWebhook(s.httpClient, s.cfg.WebhookURL, s.cfg.WebhookSecret, body)
+	result := "ok"
+	if err != nil {
+		result = "error"
+		s.logger.Error("failed to deliver campaign status webhook",
+			zap.String("campaign_id", t.CampaignID),
+			zap.String("transition_id", t.ID),
+			zap.Error(err),
+		)
+	}
+	if s.metrics != nil {
+		s.metrics.RecordLifecycleWebhook(result)
+	}
+}
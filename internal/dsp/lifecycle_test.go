@@ -0,0 +1,231 @@
+package dsp
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestLifecyclePass(t *testing.T) {
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	tests := []struct {
+		name        string
+		status      models.CampaignStatus
+		start, end  time.Time
+		totalBudget float64
+		spend       float64
+		exhausted   bool   // Advertiser balance is exhausted
+		lastReason  string // Reason of the campaign's previous transition, if any
+		wantStatus  models.CampaignStatus
+		wantReasons []string // Transitions recorded by the pass, in order
+	}{
+		{
+			name:       "scheduled before start stays",
+			status:     models.CampaignStatusScheduled,
+			start:      future,
+			wantStatus: models.CampaignStatusScheduled,
+		},
+		{
+			name:        "scheduled activates at start date",
+			status:      models.CampaignStatusScheduled,
+			start:       past,
+			wantStatus:  models.CampaignStatusActive,
+			wantReasons: []string{models.TransitionStartDate},
+		},
+		{
+			name:        "scheduled without start date activates",
+			status:      models.CampaignStatusScheduled,
+			wantStatus:  models.CampaignStatusActive,
+			wantReasons: []string{models.TransitionStartDate},
+		},
+		{
+			name:        "activated campaign pauses on exhausted balance in the same pass",
+			status:      models.CampaignStatusScheduled,
+			start:       past,
+			exhausted:   true,
+			wantStatus:  models.CampaignStatusPaused,
+			wantReasons: []string{models.TransitionStartDate, models.TransitionBalanceExhausted},
+		},
+		{
+			name:        "end date ends",
+			status:      models.CampaignStatusActive,
+			start:       past.Add(-time.Hour),
+			end:         past,
+			wantStatus:  models.CampaignStatusEnded,
+			wantReasons: []string{models.TransitionEndDate},
+		},
+		{
+			name:        "end date wins over a paused campaign",
+			status:      models.CampaignStatusPaused,
+			end:         now,
+			wantStatus:  models.CampaignStatusEnded,
+			wantReasons: []string{models.TransitionEndDate},
+		},
+		{
+			name:        "spent budget ends",
+			status:      models.CampaignStatusActive,
+			totalBudget: 100,
+			spend:       100,
+			wantStatus:  models.CampaignStatusEnded,
+			wantReasons: []string{models.TransitionBudgetSpent},
+		},
+		{
+			name:        "budget not yet spent stays",
+			status:      models.CampaignStatusActive,
+			totalBudget: 100,
+			spend:       99.99,
+			wantStatus:  models.CampaignStatusActive,
+		},
+		{
+			name:        "exhausted balance pauses",
+			status:      models.CampaignStatusActive,
+			exhausted:   true,
+			wantStatus:  models.CampaignStatusPaused,
+			wantReasons: []string{models.TransitionBalanceExhausted},
+		},
+		{
+			name:        "restored balance resumes a balance pause",
+			status:      models.CampaignStatusPaused,
+			lastReason:  models.TransitionBalanceExhausted,
+			wantStatus:  models.CampaignStatusActive,
+			wantReasons: []string{models.TransitionBalanceRestored},
+		},
+		{
+			name:       "restored balance keeps a manual pause",
+			status:     models.CampaignStatusPaused,
+			lastReason: models.TransitionManual,
+			wantStatus: models.CampaignStatusPaused,
+		},
+		{
+			name:       "balance pause stays while exhausted",
+			status:     models.CampaignStatusPaused,
+			lastReason: models.TransitionBalanceExhausted,
+			exhausted:  true,
+			wantStatus: models.CampaignStatusPaused,
+		},
+		{
+			name:       "ended stays ended",
+			status:     models.CampaignStatusEnded,
+			end:        past,
+			wantStatus: models.CampaignStatusEnded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			campaigns := NewCampaignService(storage.NewInMemoryCampaignRepo())
+			c := &models.Campaign{
+				ID:           "cmp-1",
+				Name:         "Campaign",
+				AdvertiserID: "adv-1",
+				Status:       tt.status,
+				StartDate:    tt.start,
+				EndDate:      tt.end,
+				TotalBudget:  tt.totalBudget,
+			}
+			if err := campaigns.UpsertCampaign(ctx, c); err != nil {
+				t.Fatalf("UpsertCampaign() error = %v", err)
+			}
+
+			stats := storage.NewInMemoryStatsRepo()
+			if tt.spend > 0 {
+				if err := stats.UpsertDailyStats(ctx, &storage.DailyStats{Date: now.Truncate(24 * time.Hour), CampaignID: c.ID, Spend: tt.spend}); err != nil {
+					t.Fatalf("UpsertDailyStats() error = %v", err)
+				}
+			}
+
+			transitions := storage.NewInMemoryTransitionRepo()
+			if tt.lastReason != "" {
+				if err := transitions.Append(ctx, &models.CampaignTransition{
+					ID: "prev", CampaignID: c.ID, From: models.CampaignStatusActive, To: models.CampaignStatusPaused,
+					Reason: tt.lastReason, CreatedAt: past,
+				}); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+			}
+
+			billing := &BillingService{cfg: config.BillingConfig{Enabled: true}, exhausted: map[string]bool{"adv-1": tt.exhausted}}
+			svc := NewLifecycleService(campaigns, nil, billing, stats, transitions, nil, config.LifecycleConfig{}, zap.NewNop(), nil)
+
+			if err := svc.Pass(ctx, now); err != nil {
+				t.Fatalf("Pass() error = %v", err)
+			}
+
+			got, err := campaigns.GetCampaign(ctx, c.ID)
+			if err != nil {
+				t.Fatalf("GetCampaign() error = %v", err)
+			}
+			if got.Status != tt.wantStatus {
+				t.Errorf("Status = %s, want %s", got.Status, tt.wantStatus)
+			}
+
+			list, err := transitions.List(ctx, storage.TransitionFilter{CampaignID: c.ID})
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var reasons []string
+			for i := len(list) - 1; i >= 0; i-- {
+				if list[i].ID == "prev" {
+					continue
+				}
+				if list[i].ActorKind != models.PrincipalSystem {
+					t.Errorf("transition %s actor = %q, want %q", list[i].Reason, list[i].ActorKind, models.PrincipalSystem)
+				}
+				reasons = append(reasons, list[i].Reason)
+			}
+			if !reflect.DeepEqual(reasons, tt.wantReasons) {
+				t.Errorf("transitions = %v, want %v", reasons, tt.wantReasons)
+			}
+		})
+	}
+}
+
+func TestLifecycleRecordChange(t *testing.T) {
+	draft := &models.Campaign{ID: "cmp-1", AdvertiserID: "adv-1", Status: models.CampaignStatusDraft}
+	active := &models.Campaign{ID: "cmp-1", AdvertiserID: "adv-1", Status: models.CampaignStatusActive}
+	paused := &models.Campaign{ID: "cmp-1", AdvertiserID: "adv-1", Status: models.CampaignStatusPaused}
+
+	tests := []struct {
+		name          string
+		before, after *models.Campaign
+		wantFrom      models.CampaignStatus
+		wantRecorded  bool
+	}{
+		{name: "new draft", after: draft},
+		{name: "new active campaign", after: active, wantRecorded: true},
+		{name: "status unchanged", before: active, after: active},
+		{name: "manual pause", before: active, after: paused, wantFrom: models.CampaignStatusActive, wantRecorded: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			transitions := storage.NewInMemoryTransitionRepo()
+			svc := NewLifecycleService(nil, nil, nil, nil, transitions, nil, config.LifecycleConfig{}, zap.NewNop(), nil)
+			actor := &models.Principal{Kind: models.PrincipalUser, ID: "u-1", Name: "user"}
+
+			svc.RecordChange(ctx, actor, tt.before, tt.after)
+
+			list, _ := transitions.List(ctx, storage.TransitionFilter{})
+			if got := len(list) == 1; got != tt.wantRecorded {
+				t.Fatalf("recorded %d transitions, want recorded = %v", len(list), tt.wantRecorded)
+			}
+			if !tt.wantRecorded {
+				return
+			}
+			tr := list[0]
+			if tr.From != tt.wantFrom || tr.To != tt.after.Status || tr.Reason != models.TransitionManual || tr.ActorID != "u-1" {
+				t.Errorf("transition = %+v", tr)
+			}
+		})
+	}
+}
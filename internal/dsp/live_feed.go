@@ -0,0 +1,291 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ErrTooManySubscribers is returned when the live feed is at capacity.
+var ErrTooManySubscribers = errors.New("too many live feed subscribers")
+
+// LiveStats are a campaign's counts and USD amounts over one second.
+type LiveStats struct {
+	Bids        int64   `json:"bids"`
+	Wins        int64   `json:"wins"`
+	Clicks      int64   `json:"clicks"`
+	Installs    int64   `json:"installs"`
+	Conversions int64   `json:"conversions"` // Including installs
+	Spend       float64 `json:"spend"`
+	Revenue     float64 `json:"revenue"`
+	Payout      float64 `json:"payout"`
+}
+
+func (st *LiveStats) add(o *LiveStats) {
+	st.Bids += o.Bids
+	st.Wins += o.Wins
+	st.Clicks += o.Clicks
+	st.Installs += o.Installs
+	st.Conversions += o.Conversions
+	st.Spend += o.Spend
+	st.Revenue += o.Revenue
+	st.Payout += o.Payout
+}
+
+// LiveCampaignStats is one campaign's share of a frame.
+type LiveCampaignStats struct {
+	CampaignID   string `json:"campaign_id"`
+	AdvertiserID string `json:"advertiser_id,omitempty"`
+	LiveStats
+}
+
+// LiveFrame is one second of activity. Campaigns without activity are left
+// out; a frame is published every second even when it's empty.
+type LiveFrame struct {
+	Time      time.Time           `json:"time"` // Start of the second
+	Campaigns []LiveCampaignStats `json:"campaigns"`
+	Totals    LiveStats           `json:"totals"`
+}
+
+// LiveFilter selects the campaigns a subscriber sees; empty matches all.
+type LiveFilter struct {
+	AdvertiserID string
+	CampaignIDs  []string
+}
+
+// Apply returns the frame restricted to the filter.
+func (f LiveFilter) Apply(frame *LiveFrame) *LiveFrame {
+	if f.AdvertiserID == "" && len(f.CampaignIDs) == 0 {
+		return frame
+	}
+	out := &LiveFrame{Time: frame.Time, Campaigns: []LiveCampaignStats{}}
+	for _, c := range frame.Campaigns {
+		if f.AdvertiserID != "" && c.AdvertiserID != f.AdvertiserID {
+			continue
+		}
+		if len(f.CampaignIDs) > 0 && !containsGroup(f.CampaignIDs, c.CampaignID) {
+			continue
+		}
+		out.Campaigns = append(out.Campaigns, c)
+		out.Totals.add(&c.LiveStats)
+	}
+	return out
+}
+
+// LiveSubscription receives frames until it is closed. Frames is closed
+// when the subscriber falls too far behind.
+type LiveSubscription struct {
+	Frames <-chan *LiveFrame
+
+	frames  chan *LiveFrame
+	dropped int // Consecutive frames dropped
+}
+
+// LiveFeed aggregates bids, wins, clicks and conversions per campaign and
+// second and fans the frames out to subscribers. Recording only touches an
+// in-memory bucket; publishing never blocks on a subscriber, whose frames
+// are dropped when its buffer is full.
+type LiveFeed struct {
+	campaigns storage.CampaignRepo
+	cfg       config.LiveConfig
+	logger    *zap.Logger
+	metrics   *metrics.Metrics
+
+	mu     sync.Mutex
+	bucket map[string]*LiveStats
+
+	subMu       sync.Mutex
+	subscribers map[*LiveSubscription]struct{}
+
+	// advertisers caches campaign advertisers; only the publisher uses it
+	advertisers map[string]string
+}
+
+// NewLiveFeed creates a new live feed. Campaign advertisers are looked up
+// in campaigns for advertiser filters.
+func NewLiveFeed(campaigns storage.CampaignRepo, cfg config.LiveConfig, logger *zap.Logger, m *metrics.Metrics) *LiveFeed {
+	return &LiveFeed{
+		campaigns:   campaigns,
+		cfg:         cfg,
+		logger:      logger,
+		metrics:     m,
+		bucket:      make(map[string]*LiveStats),
+		subscribers: make(map[*LiveSubscription]struct{}),
+		advertisers: make(map[string]string),
+	}
+}
+
+// record applies fn to the campaign's stats of the current second.
+func (f *LiveFeed) record(campaignID string, fn func(st *LiveStats)) {
+	if campaignID == "" {
+		return
+	}
+	f.mu.Lock()
+	st, ok := f.bucket[campaignID]
+	if !ok {
+		st = &LiveStats{}
+		f.bucket[campaignID] = st
+	}
+	fn(st)
+	f.mu.Unlock()
+}
+
+// RecordBid counts a bid.
+func (f *LiveFeed) RecordBid(campaignID string) {
+	f.record(campaignID, func(st *LiveStats) { st.Bids++ })
+}
+
+// RecordWin counts a win and its spend in USD.
+func (f *LiveFeed) RecordWin(campaignID string, spendUSD float64) {
+	f.record(campaignID, func(st *LiveStats) {
+		st.Wins++
+		st.Spend += spendUSD
+	})
+}
+
+// RecordClick counts a click.
+func (f *LiveFeed) RecordClick(campaignID string) {
+	f.record(campaignID, func(st *LiveStats) { st.Clicks++ })
+}
+
+// RecordConversion counts a conversion with its revenue and payout in USD.
+func (f *LiveFeed) RecordConversion(campaignID, event string, revenueUSD, payoutUSD float64) {
+	f.record(campaignID, func(st *LiveStats) {
+		st.Conversions++
+		if event == storage.InstallEvent {
+			st.Installs++
+		}
+		st.Revenue += revenueUSD
+		st.Payout += payoutUSD
+	})
+}
+
+// Subscribe registers a subscriber. Call Unsubscribe when done.
+func (f *LiveFeed) Subscribe() (*LiveSubscription, error) {
+	f.subMu.Lock()
+	defer f.subMu.Unlock()
+
+	if len(f.subscribers) >= f.cfg.MaxSubscribers {
+		return nil, ErrTooManySubscribers
+	}
+	frames := make(chan *LiveFrame, f.cfg.BufferFrames)
+	sub := &LiveSubscription{Frames: frames, frames: frames}
+	f.subscribers[sub] = struct{}{}
+	if f.metrics != nil {
+		f.metrics.SetLiveSubscribers(len(f.subscribers))
+	}
+	return sub, nil
+}
+
+// Unsubscribe removes a subscriber and closes its frames.
+func (f *LiveFeed) Unsubscribe(sub *LiveSubscription) {
+	f.subMu.Lock()
+	defer f.subMu.Unlock()
+	f.remove(sub)
+}
+
+func (f *LiveFeed) remove(sub *LiveSubscription) {
+	if _, ok := f.subscribers[sub]; !ok {
+		return
+	}
+	delete(f.subscribers, sub)
+	close(sub.frames)
+	if f.metrics != nil {
+		f.metrics.SetLiveSubscribers(len(f.subscribers))
+	}
+}
+
+// Run publishes a frame every second until ctx is cancelled.
+func (f *LiveFeed) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			f.subMu.Lock()
+			for sub := range f.subscribers {
+				f.remove(sub)
+			}
+			f.subMu.Unlock()
+			return
+		case now := <-ticker.C:
+			f.publish(f.flush(now.Truncate(time.Second).Add(-time.Second)))
+		}
+	}
+}
+
+// flush swaps out the current bucket and builds its frame.
+func (f *LiveFeed) flush(second time.Time) *LiveFrame {
+	f.mu.Lock()
+	bucket := f.bucket
+	f.bucket = make(map[string]*LiveStats, len(bucket))
+	f.mu.Unlock()
+
+	frame := &LiveFrame{Time: second, Campaigns: make([]LiveCampaignStats, 0, len(bucket))}
+	for campaignID, st := range bucket {
+		frame.Campaigns = append(frame.Campaigns, LiveCampaignStats{
+			CampaignID:   campaignID,
+			AdvertiserID: f.advertiser(campaignID),
+			LiveStats:    *st,
+		})
+		frame.Totals.add(st)
+	}
+	sort.Slice(frame.Campaigns, func(i, j int) bool {
+		return frame.Campaigns[i].CampaignID < frame.Campaigns[j].CampaignID
+	})
+	return frame
+}
+
+// advertiser returns the campaign's advertiser, looking it up once.
+func (f *LiveFeed) advertiser(campaignID string) string {
+	if adv, ok := f.advertisers[campaignID]; ok {
+		return adv
+	}
+	adv := ""
+	if f.campaigns != nil {
+		c, err := f.campaigns.GetByID(context.Background(), campaignID)
+		if err != nil {
+			f.logger.Debug("failed to get campaign for live feed", zap.String("campaign_id", campaignID), zap.Error(err))
+			return "" // Retry next second
+		}
+		if c != nil {
+			adv = c.AdvertiserID
+		}
+	}
+	f.advertisers[campaignID] = adv
+	return adv
+}
+
+// publish hands the frame to every subscriber without blocking. A
+// subscriber that misses a full buffer's worth of frames in a row is
+// dropped; its client can reconnect.
+func (f *LiveFeed) publish(frame *LiveFrame) {
+	f.subMu.Lock()
+	defer f.subMu.Unlock()
+
+	for sub := range f.subscribers {
+		select {
+		case sub.frames <- frame:
+			sub.dropped = 0
+			continue
+		default:
+		}
+
+		sub.dropped++
+		if f.metrics != nil {
+			f.metrics.RecordLiveFrameDropped()
+		}
+		if sub.dropped > f.cfg.BufferFrames {
+			f.logger.Info("dropping slow live feed subscriber")
+			f.remove(sub)
+		}
+	}
+}
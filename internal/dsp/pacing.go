@@ -3,6 +3,7 @@ package dsp
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -22,6 +23,21 @@ type PacingEngine interface {
 	
 	// RecordWin records an auction win for pacing calculations.
 	RecordWin(lineItemID string, price float64) error
+
+	// Check explains whether a bid would be allowed without changing
+	// any counters (dry run).
+	Check(lineItemID, userID string, cfg models.PacingConfig, price float64) *PacingDecision
+}
+
+// PacingDecision explains a pacing check.
+type PacingDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"` // not_started, ended, daily_budget, pacing_ahead, hourly_pacing, hourly_cap, freq_cap_*
+	Detail  string `json:"detail,omitempty"` // e.g. "spent $410.00 of ideal $350.00"
+}
+
+func denyPacing(reason, format string, args ...interface{}) *PacingDecision {
+	return &PacingDecision{Reason: reason, Detail: fmt.Sprintf(format, args...)}
 }
 
 // PacingStats holds current pacing statistics.
@@ -66,31 +82,47 @@ func NewRedisPacingEngine(client *redis.Client, globalCfg config.PacingGlobalCon
 func (p *RedisPacingEngine) Allow(lineItemID, userID string, cfg models.PacingConfig, price float64) bool {
 	ctx := context.Background()
 	now := time.Now().UTC()
+
+	decision := p.evaluate(ctx, lineItemID, userID, cfg, price, now)
+	if !decision.Allowed {
+		if p.metrics != nil {
+			if strings.HasPrefix(decision.Reason, "freq_cap") {
+				p.metrics.RecordFreqCapRejection(lineItemID)
+			} else {
+				p.metrics.RecordPacingRejection(lineItemID, decision.Reason)
+			}
+		}
+		return false
+	}
+
+	// Increment counters (atomic operations)
+	p.incrementCounters(ctx, lineItemID, userID, now.Format("2006-01-02"), now.Hour(), price, cfg)
+
+	return true
+}
+
+// Check evaluates pacing without changing counters or metrics.
+func (p *RedisPacingEngine) Check(lineItemID, userID string, cfg models.PacingConfig, price float64) *PacingDecision {
+	return p.evaluate(context.Background(), lineItemID, userID, cfg, price, time.Now().UTC())
+}
+
+// evaluate runs the schedule, budget and frequency checks read-only.
+func (p *RedisPacingEngine) evaluate(ctx context.Context, lineItemID, userID string, cfg models.PacingConfig, price float64, now time.Time) *PacingDecision {
 	today := now.Format("2006-01-02")
 	hour := now.Hour()
 
 	// Check schedule (start/end dates)
 	if !cfg.StartAt.IsZero() && now.Before(cfg.StartAt) {
-		if p.metrics != nil {
-			p.metrics.RecordPacingRejection(lineItemID, "not_started")
-		}
-		return false
+		return denyPacing("not_started", "starts at %s", cfg.StartAt.UTC().Format(time.RFC3339))
 	}
 	if !cfg.EndAt.IsZero() && now.After(cfg.EndAt) {
-		if p.metrics != nil {
-			p.metrics.RecordPacingRejection(lineItemID, "ended")
-		}
-		return false
+		return denyPacing("ended", "ended at %s", cfg.EndAt.UTC().Format(time.RFC3339))
 	}
 
 	// Check daily budget with smoothing
 	if cfg.DailyBudget > 0 {
-		allowed, reason := p.checkBudget(ctx, lineItemID, today, hour, cfg, price)
-		if !allowed {
-			if p.metrics != nil {
-				p.metrics.RecordPacingRejection(lineItemID, reason)
-			}
-			return false
+		if d := p.checkBudget(ctx, lineItemID, today, hour, cfg, price); d != nil {
+			return d
 		}
 	}
 
@@ -98,10 +130,7 @@ func (p *RedisPacingEngine) Allow(lineItemID, userID string, cfg models.PacingCo
 	if cfg.HourlyBudgetCap > 0 {
 		hourlySpend := p.getHourlySpend(ctx, lineItemID, today, hour)
 		if hourlySpend+price > cfg.HourlyBudgetCap {
-			if p.metrics != nil {
-				p.metrics.RecordPacingRejection(lineItemID, "hourly_cap")
-			}
-			return false
+			return denyPacing("hourly_cap", "spent $%.2f of hourly cap $%.2f", hourlySpend, cfg.HourlyBudgetCap)
 		}
 	}
 
@@ -109,55 +138,44 @@ func (p *RedisPacingEngine) Allow(lineItemID, userID string, cfg models.PacingCo
 	if userID != "" && userID != "anonymous" {
 		// Daily frequency cap
 		if cfg.FreqCapPerUserPerDay > 0 {
-			if !p.checkFreqCap(ctx, lineItemID, userID, today, "day", cfg.FreqCapPerUserPerDay) {
-				if p.metrics != nil {
-					p.metrics.RecordFreqCapRejection(lineItemID)
-				}
-				return false
+			if count := p.freqCount(ctx, lineItemID, userID, today); count >= int64(cfg.FreqCapPerUserPerDay) {
+				return denyPacing("freq_cap_day", "user seen %d times today, cap %d", count, cfg.FreqCapPerUserPerDay)
 			}
 		}
 
 		// Hourly frequency cap
 		if cfg.FreqCapPerUserPerHour > 0 {
 			hourKey := fmt.Sprintf("%s:%02d", today, hour)
-			if !p.checkFreqCap(ctx, lineItemID, userID, hourKey, "hour", cfg.FreqCapPerUserPerHour) {
-				if p.metrics != nil {
-					p.metrics.RecordFreqCapRejection(lineItemID)
-				}
-				return false
+			if count := p.freqCount(ctx, lineItemID, userID, hourKey); count >= int64(cfg.FreqCapPerUserPerHour) {
+				return denyPacing("freq_cap_hour", "user seen %d times this hour, cap %d", count, cfg.FreqCapPerUserPerHour)
 			}
 		}
 
 		// Lifetime frequency cap
 		if cfg.FreqCapPerUserLifetime > 0 {
-			if !p.checkFreqCap(ctx, lineItemID, userID, "lifetime", "lifetime", cfg.FreqCapPerUserLifetime) {
-				if p.metrics != nil {
-					p.metrics.RecordFreqCapRejection(lineItemID)
-				}
-				return false
+			if count := p.freqCount(ctx, lineItemID, userID, "lifetime"); count >= int64(cfg.FreqCapPerUserLifetime) {
+				return denyPacing("freq_cap_lifetime", "user seen %d times, lifetime cap %d", count, cfg.FreqCapPerUserLifetime)
 			}
 		}
 	}
 
-	// Increment counters (atomic operations)
-	p.incrementCounters(ctx, lineItemID, userID, today, hour, price, cfg)
-
-	return true
+	return &PacingDecision{Allowed: true}
 }
 
-// checkBudget checks daily budget with optional smoothing.
-func (p *RedisPacingEngine) checkBudget(ctx context.Context, lineItemID, today string, hour int, cfg models.PacingConfig, price float64) (bool, string) {
+// checkBudget checks daily budget with optional smoothing. It returns nil
+// when the bid fits.
+func (p *RedisPacingEngine) checkBudget(ctx context.Context, lineItemID, today string, hour int, cfg models.PacingConfig, price float64) *PacingDecision {
 	budgetKey := fmt.Sprintf("pacing:budget:%s:%s", lineItemID, today)
 	
 	// Get current spend
 	currentSpend, err := p.client.Get(ctx, budgetKey).Float64()
 	if err != nil && err != redis.Nil {
-		return true, "" // Fail open
+		return nil // Fail open
 	}
 
 	// Check total daily budget
 	if currentSpend+price > cfg.DailyBudget {
-		return false, "daily_budget"
+		return denyPacing("daily_budget", "spent $%.2f of daily budget $%.2f", currentSpend, cfg.DailyBudget)
 	}
 
 	// Apply smoothing if enabled
@@ -171,30 +189,35 @@ func (p *RedisPacingEngine) checkBudget(ctx context.Context, lineItemID, today s
 		// Calculate ideal spend by this hour
 		hoursElapsed := float64(hour) + float64(time.Now().Minute())/60.0
 		
-		var idealSpend float64
-		switch cfg.PacingType {
-		case models.PacingTypeFrontLoaded:
-			// More aggressive early in the day
-			idealSpend = cfg.DailyBudget * (1 - (1-hoursElapsed/24)*(1-hoursElapsed/24))
-		default: // Even pacing
-			idealSpend = cfg.DailyBudget * (hoursElapsed / 24.0)
-		}
+		idealSpend := pacingTarget(cfg, hoursElapsed)
 
 		// Allow some buffer (20% ahead of pace)
 		maxSpend := idealSpend * 1.2
 		if currentSpend+price > maxSpend {
-			return false, "pacing_ahead"
+			return denyPacing("pacing_ahead", "spent $%.2f of ideal $%.2f", currentSpend, idealSpend)
 		}
 
 		// Check hourly spend limit
 		hourlySpend := p.getHourlySpend(ctx, lineItemID, today, hour)
 		maxHourlySpend := cfg.DailyBudget * maxHourlyPct
 		if hourlySpend+price > maxHourlySpend {
-			return false, "hourly_pacing"
+			return denyPacing("hourly_pacing", "spent $%.2f this hour of $%.2f", hourlySpend, maxHourlySpend)
 		}
 	}
 
-	return true, ""
+	return nil
+}
+
+// pacingTarget returns the spend a line item should have reached
+// hoursElapsed hours into the (UTC) day.
+func pacingTarget(cfg models.PacingConfig, hoursElapsed float64) float64 {
+	switch cfg.PacingType {
+	case models.PacingTypeFrontLoaded:
+		// More aggressive early in the day
+		return cfg.DailyBudget * (1 - (1-hoursElapsed/24)*(1-hoursElapsed/24))
+	default: // Even pacing
+		return cfg.DailyBudget * (hoursElapsed / 24.0)
+	}
 }
 
 // getHourlySpend returns spend for a specific hour.
@@ -207,16 +230,15 @@ func (p *RedisPacingEngine) getHourlySpend(ctx context.Context, lineItemID, toda
 	return spend
 }
 
-// checkFreqCap checks and increments frequency cap.
-func (p *RedisPacingEngine) checkFreqCap(ctx context.Context, lineItemID, userID, period, capType string, limit int32) bool {
+// freqCount returns how often the user was served the line item in period.
+// Errors count as zero (fail open).
+func (p *RedisPacingEngine) freqCount(ctx context.Context, lineItemID, userID, period string) int64 {
 	key := fmt.Sprintf("pacing:freq:%s:%s:%s", lineItemID, userID, period)
-	
 	count, err := p.client.Get(ctx, key).Int64()
-	if err != nil && err != redis.Nil {
-		return true // Fail open
+	if err != nil {
+		return 0
 	}
-
-	return count < int64(limit)
+	return count
 }
 
 // incrementCounters increments all relevant pacing counters.
@@ -387,6 +409,25 @@ func (p *InMemoryPacingEngine) Allow(lineItemID, userID string, cfg models.Pacin
 	return true
 }
 
+// Check evaluates the budget and frequency cap without changing counters.
+func (p *InMemoryPacingEngine) Check(lineItemID, userID string, cfg models.PacingConfig, price float64) *PacingDecision {
+	d := dateKey()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	currentSpend := p.dailySpend[lineItemID][d]
+	if cfg.DailyBudget > 0 && currentSpend+price > cfg.DailyBudget {
+		return denyPacing("daily_budget", "spent $%.2f of daily budget $%.2f", currentSpend, cfg.DailyBudget)
+	}
+
+	currentCount := p.dailyFreq[lineItemID][d][userID]
+	if cfg.FreqCapPerUserPerDay > 0 && currentCount >= cfg.FreqCapPerUserPerDay {
+		return denyPacing("freq_cap_day", "user seen %d times today, cap %d", currentCount, cfg.FreqCapPerUserPerDay)
+	}
+
+	return &PacingDecision{Allowed: true}
+}
+
 // GetStats returns current pacing stats.
 func (p *InMemoryPacingEngine) GetStats(lineItemID string) (*PacingStats, error) {
 	d := dateKey()
@@ -0,0 +1,405 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// Rule ID prefixes for payouts priced from legacy campaign/source fields.
+const (
+	legacyCampaignSourceRulePrefix = "campaign_source:"
+	legacyCampaignRulePrefix       = "campaign:"
+	legacySourceDefaultRulePrefix  = "source_default:"
+)
+
+// PayoutEngine prices conversions paid out to traffic sources.
+//
+// Explicit payout rules are evaluated first: the most specific matching rule
+// (campaign, source, country, OS, event) effective at the conversion time
+// wins, ties broken by priority. If none matches, the legacy settings apply
+// in order: CampaignSource.CustomPayout, Campaign.PayoutAmount and finally
+// S2SSource.DefaultPayout, all restricted to Campaign.PayoutEvent.
+type PayoutEngine struct {
+	ruleRepo       storage.PayoutRuleRepo
+	sourceRepo     storage.SourceRepo
+	campaignRepo   storage.CampaignRepo
+	advertiserRepo storage.AdvertiserRepo
+	converter      *currency.Converter
+}
+
+// NewPayoutEngine creates a new payout engine.
+func NewPayoutEngine(
+	ruleRepo storage.PayoutRuleRepo,
+	sourceRepo storage.SourceRepo,
+	campaignRepo storage.CampaignRepo,
+	converter *currency.Converter,
+) *PayoutEngine {
+	return &PayoutEngine{
+		ruleRepo:     ruleRepo,
+		sourceRepo:   sourceRepo,
+		campaignRepo: campaignRepo,
+		converter:    converter,
+	}
+}
+
+// SetAdvertiserRepo enables quoting offers in the advertiser's currency.
+func (e *PayoutEngine) SetAdvertiserRepo(repo storage.AdvertiserRepo) {
+	e.advertiserRepo = repo
+}
+
+// ListRules returns all payout rules.
+func (e *PayoutEngine) ListRules(ctx context.Context) ([]*models.PayoutRule, error) {
+	return e.ruleRepo.ListAll(ctx)
+}
+
+// GetRule returns a payout rule by ID.
+func (e *PayoutEngine) GetRule(ctx context.Context, id string) (*models.PayoutRule, error) {
+	return e.ruleRepo.GetByID(ctx, id)
+}
+
+// UpsertRule creates or updates a payout rule.
+func (e *PayoutEngine) UpsertRule(ctx context.Context, rule *models.PayoutRule) error {
+	if rule.ID == "" {
+		rule.ID = uuid.New().String()
+	}
+	if rule.Currency != "" {
+		rule.Currency = currency.Normalize(rule.Currency)
+	}
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+	return e.ruleRepo.Upsert(ctx, rule)
+}
+
+// DeleteRule deletes a payout rule.
+func (e *PayoutEngine) DeleteRule(ctx context.Context, id string) error {
+	return e.ruleRepo.Delete(ctx, id)
+}
+
+// PayoutInput describes a conversion to be priced.
+type PayoutInput struct {
+	CampaignID      string    `json:"campaign_id"`
+	SourceType      string    `json:"source_type"`
+	SourceID        string    `json:"source_id"`
+	Country         string    `json:"country"`
+	OS              string    `json:"os"`
+	Event           string    `json:"event"`
+	Revenue         float64   `json:"revenue"`
+	RevenueCurrency string    `json:"revenue_currency"`
+	Currency        string    `json:"currency"` // Default payout currency (advertiser's)
+	At              time.Time `json:"at"`
+}
+
+// PayoutQuote is the priced payout and the rule that produced it.
+type PayoutQuote struct {
+	Amount     float64           `json:"amount"`
+	Currency   string            `json:"currency"`
+	AmountUSD  float64           `json:"amount_usd,omitempty"` // Offer quotes only; 0 if no rate
+	RuleID     string            `json:"rule_id,omitempty"`
+	RuleName   string            `json:"rule_name,omitempty"`
+	RuleType   models.PayoutType `json:"rule_type,omitempty"`
+	Candidates []PayoutCandidate `json:"candidates,omitempty"`
+}
+
+// PayoutCandidate explains why a rule was or wasn't applied (preview only).
+type PayoutCandidate struct {
+	RuleID   string  `json:"rule_id"`
+	RuleName string  `json:"rule_name,omitempty"`
+	Matched  bool    `json:"matched"`
+	Reason   string  `json:"reason,omitempty"`
+	Amount   float64 `json:"amount,omitempty"`
+}
+
+// Resolve prices a conversion. A zero quote without RuleID means no rule applied.
+func (e *PayoutEngine) Resolve(ctx context.Context, in PayoutInput) (*PayoutQuote, error) {
+	return e.resolve(ctx, in, false)
+}
+
+// Preview prices a conversion and lists every candidate rule with the
+// reason it did or didn't apply.
+func (e *PayoutEngine) Preview(ctx context.Context, in PayoutInput) (*PayoutQuote, error) {
+	return e.resolve(ctx, in, true)
+}
+
+// QuotePayout returns the payout a source earns for the campaign's payout
+// event (install by default). Used when advertising offers to partners.
+func (e *PayoutEngine) QuotePayout(ctx context.Context, campaignID, sourceType, sourceID, country, os string) (float64, error) {
+	campaign, err := e.campaignRepo.GetByID(ctx, campaignID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if campaign == nil {
+		campaign = &models.Campaign{ID: campaignID}
+	}
+
+	quote, err := e.QuoteOffer(ctx, campaign, sourceType, sourceID, country, os)
+	if err != nil {
+		return 0, err
+	}
+	return quote.Amount, nil
+}
+
+// QuoteOffer prices the campaign's payout event (install by default) for a
+// source in the advertiser's currency, with the amount in the reporting
+// currency alongside for ranking offers.
+func (e *PayoutEngine) QuoteOffer(ctx context.Context, campaign *models.Campaign, sourceType, sourceID, country, os string) (*PayoutQuote, error) {
+	event := "install"
+	if campaign.PayoutEvent != "" {
+		event = campaign.PayoutEvent
+	}
+
+	now := time.Now()
+	quote, err := e.Resolve(ctx, PayoutInput{
+		CampaignID: campaign.ID,
+		SourceType: sourceType,
+		SourceID:   sourceID,
+		Country:    country,
+		OS:         os,
+		Event:      event,
+		Currency:   e.advertiserCurrency(ctx, campaign.AdvertiserID),
+		At:         now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	quote.AmountUSD = quote.Amount
+	if quote.Amount != 0 && quote.Currency != currency.ReportingCurrency {
+		quote.AmountUSD = 0
+		if e.converter != nil {
+			if usd, err := e.converter.ToReporting(ctx, quote.Amount, quote.Currency, now); err == nil {
+				quote.AmountUSD = usd
+			}
+		}
+	}
+	return quote, nil
+}
+
+// advertiserCurrency returns the advertiser's currency, defaulting to the
+// reporting currency.
+func (e *PayoutEngine) advertiserCurrency(ctx context.Context, advertiserID string) string {
+	if e.advertiserRepo == nil || advertiserID == "" {
+		return currency.ReportingCurrency
+	}
+	adv, err := e.advertiserRepo.GetByID(ctx, advertiserID)
+	if err != nil || adv == nil {
+		return currency.ReportingCurrency
+	}
+	return currency.Normalize(adv.Currency)
+}
+
+func (e *PayoutEngine) resolve(ctx context.Context, in PayoutInput, explain bool) (*PayoutQuote, error) {
+	if in.At.IsZero() {
+		in.At = time.Now()
+	}
+	in.Currency = currency.Normalize(in.Currency)
+	in.RevenueCurrency = currency.Normalize(in.RevenueCurrency)
+
+	var campaign *models.Campaign
+	if in.CampaignID != "" {
+		c, err := e.campaignRepo.GetByID(ctx, in.CampaignID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign: %w", err)
+		}
+		campaign = c
+	}
+
+	rules, err := e.explicitRules(ctx, in.CampaignID)
+	if err != nil {
+		return nil, err
+	}
+	legacy, err := e.legacyRules(ctx, campaign, in)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &PayoutQuote{Currency: in.Currency}
+	var winner *models.PayoutRule
+
+	for _, group := range [][]*models.PayoutRule{rules, legacy} {
+		for _, rule := range group {
+			reason := e.mismatchReason(rule, in)
+			if winner == nil && reason == "" {
+				winner = rule
+				e.price(ctx, quote, rule, in)
+			}
+			if explain {
+				c := PayoutCandidate{RuleID: rule.ID, RuleName: rule.Name, Matched: reason == "", Reason: reason}
+				if rule == winner {
+					c.Amount = quote.Amount
+				} else if reason == "" {
+					c.Reason = "shadowed by " + winner.ID
+				}
+				quote.Candidates = append(quote.Candidates, c)
+			}
+		}
+		if winner != nil && !explain {
+			break
+		}
+	}
+
+	return quote, nil
+}
+
+// price fills in the quote from a rule, converting revenue into the rule's currency.
+func (e *PayoutEngine) price(ctx context.Context, quote *PayoutQuote, rule *models.PayoutRule, in PayoutInput) {
+	ruleCurrency := in.Currency
+	if rule.Currency != "" {
+		ruleCurrency = currency.Normalize(rule.Currency)
+	}
+
+	revenue := in.Revenue
+	if e.converter != nil && revenue != 0 && in.RevenueCurrency != ruleCurrency {
+		if converted, err := e.converter.Convert(ctx, revenue, in.RevenueCurrency, ruleCurrency, in.At); err == nil {
+			revenue = converted
+		}
+	}
+
+	quote.Amount = rule.Calculate(revenue)
+	quote.Currency = ruleCurrency
+	quote.RuleID = rule.ID
+	quote.RuleName = rule.Name
+	quote.RuleType = rule.Type
+}
+
+// explicitRules returns payout rules ordered by specificity, then priority.
+func (e *PayoutEngine) explicitRules(ctx context.Context, campaignID string) ([]*models.PayoutRule, error) {
+	if e.ruleRepo == nil {
+		return nil, nil
+	}
+
+	var rules []*models.PayoutRule
+	var err error
+	if campaignID != "" {
+		rules, err = e.ruleRepo.ListByCampaign(ctx, campaignID)
+	} else {
+		rules, err = e.ruleRepo.ListAll(ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payout rules: %w", err)
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool {
+		si, sj := rules[i].Specificity(), rules[j].Specificity()
+		if si != sj {
+			return si > sj
+		}
+		return rules[i].Priority > rules[j].Priority
+	})
+	return rules, nil
+}
+
+// legacyRules expresses the pre-rule payout settings as rules so they are
+// priced and recorded the same way.
+func (e *PayoutEngine) legacyRules(ctx context.Context, campaign *models.Campaign, in PayoutInput) ([]*models.PayoutRule, error) {
+	if campaign == nil {
+		return nil, nil
+	}
+
+	var rules []*models.PayoutRule
+
+	// Campaign-source custom payout
+	if e.sourceRepo != nil && in.SourceID != "" {
+		links, err := e.sourceRepo.GetCampaignSources(ctx, campaign.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get campaign sources: %w", err)
+		}
+		for _, link := range links {
+			if link.CustomPayout == nil || link.SourceID != in.SourceID || link.SourceType != in.SourceType {
+				continue
+			}
+			rules = append(rules, &models.PayoutRule{
+				ID:         legacyCampaignSourceRulePrefix + link.ID,
+				Name:       "campaign source custom payout",
+				CampaignID: campaign.ID,
+				SourceType: link.SourceType,
+				SourceID:   link.SourceID,
+				Event:      campaign.PayoutEvent,
+				Type:       legacyPayoutType(link.CustomPayoutType),
+				Amount:     *link.CustomPayout,
+				Percent:    *link.CustomPayout,
+				IsActive:   link.Status != "paused",
+			})
+		}
+	}
+
+	// Campaign payout
+	if campaign.PayoutAmount > 0 {
+		rules = append(rules, &models.PayoutRule{
+			ID:         legacyCampaignRulePrefix + campaign.ID,
+			Name:       "campaign payout",
+			CampaignID: campaign.ID,
+			Event:      campaign.PayoutEvent,
+			Type:       legacyPayoutType(campaign.PayoutType),
+			Amount:     campaign.PayoutAmount,
+			Percent:    campaign.PayoutAmount,
+			IsActive:   true,
+		})
+	}
+
+	// S2S source default payout
+	if e.sourceRepo != nil && in.SourceType == "s2s" && in.SourceID != "" {
+		src, err := e.sourceRepo.GetS2SSource(ctx, in.SourceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get source: %w", err)
+		}
+		if src != nil && src.DefaultPayout > 0 {
+			rules = append(rules, &models.PayoutRule{
+				ID:         legacySourceDefaultRulePrefix + src.ID,
+				Name:       "source default payout",
+				SourceType: "s2s",
+				SourceID:   src.ID,
+				Event:      campaign.PayoutEvent,
+				Type:       legacyPayoutType(src.DefaultPayoutType),
+				Amount:     src.DefaultPayout,
+				Percent:    src.DefaultPayout,
+				IsActive:   true,
+			})
+		}
+	}
+
+	return rules, nil
+}
+
+// mismatchReason returns why a rule doesn't apply, or "" if it does.
+func (e *PayoutEngine) mismatchReason(rule *models.PayoutRule, in PayoutInput) string {
+	if !rule.IsActive {
+		return "inactive"
+	}
+	if !rule.EffectiveAt(in.At) {
+		return "not effective at " + in.At.Format(time.RFC3339)
+	}
+
+	keys := []struct {
+		name, rule, val string
+	}{
+		{"campaign_id", rule.CampaignID, in.CampaignID},
+		{"source_type", rule.SourceType, in.SourceType},
+		{"source_id", rule.SourceID, in.SourceID},
+		{"country", rule.Country, in.Country},
+		{"os", rule.OS, in.OS},
+		{"event", rule.Event, in.Event},
+	}
+	for _, k := range keys {
+		if k.rule != "" && !strings.EqualFold(k.rule, k.val) {
+			return fmt.Sprintf("%s mismatch: rule=%s, got=%s", k.name, k.rule, k.val)
+		}
+	}
+	return ""
+}
+
+// legacyPayoutType maps the free-form legacy payout type to a PayoutType.
+func legacyPayoutType(t string) models.PayoutType {
+	if strings.EqualFold(t, "percent") {
+		return models.PayoutTypePercent
+	}
+	return models.PayoutTypeFixed
+}
@@ -0,0 +1,223 @@
+package dsp
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+func TestPayoutEngineResolve(t *testing.T) {
+	at := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	later := at.Add(24 * time.Hour)
+
+	install := PayoutInput{
+		CampaignID: "cmp-1",
+		SourceType: "s2s",
+		SourceID:   "src-1",
+		Country:    "RU",
+		OS:         "android",
+		Event:      "install",
+		Currency:   "USD",
+		At:         at,
+	}
+	purchase := install
+	purchase.Event = "purchase"
+	purchase.Revenue = 50
+
+	tests := []struct {
+		name       string
+		rules      []*models.PayoutRule
+		campaign   *models.Campaign
+		in         PayoutInput
+		wantRuleID string
+		wantAmount float64
+	}{
+		{
+			name:       "no rules",
+			in:         install,
+			wantRuleID: "",
+			wantAmount: 0,
+		},
+		{
+			name: "more specific rule beats higher priority",
+			rules: []*models.PayoutRule{
+				{ID: "campaign", CampaignID: "cmp-1", Type: models.PayoutTypeFixed, Amount: 1, Priority: 100, IsActive: true},
+				{ID: "campaign-country", CampaignID: "cmp-1", Country: "RU", Type: models.PayoutTypeFixed, Amount: 2, IsActive: true},
+			},
+			in:         install,
+			wantRuleID: "campaign-country",
+			wantAmount: 2,
+		},
+		{
+			name: "priority breaks specificity ties",
+			rules: []*models.PayoutRule{
+				{ID: "low", CampaignID: "cmp-1", Country: "RU", Type: models.PayoutTypeFixed, Amount: 1, Priority: 1, IsActive: true},
+				{ID: "high", CampaignID: "cmp-1", OS: "android", Type: models.PayoutTypeFixed, Amount: 3, Priority: 5, IsActive: true},
+			},
+			in:         install,
+			wantRuleID: "high",
+			wantAmount: 3,
+		},
+		{
+			name: "mismatched key is skipped",
+			rules: []*models.PayoutRule{
+				{ID: "kz", CampaignID: "cmp-1", Country: "KZ", Type: models.PayoutTypeFixed, Amount: 5, IsActive: true},
+				{ID: "any", Type: models.PayoutTypeFixed, Amount: 0.5, IsActive: true},
+			},
+			in:         install,
+			wantRuleID: "any",
+			wantAmount: 0.5,
+		},
+		{
+			name: "inactive and not yet effective rules are skipped",
+			rules: []*models.PayoutRule{
+				{ID: "inactive", CampaignID: "cmp-1", Country: "RU", Type: models.PayoutTypeFixed, Amount: 5},
+				{ID: "future", CampaignID: "cmp-1", OS: "android", Type: models.PayoutTypeFixed, Amount: 4, EffectiveFrom: &later, IsActive: true},
+				{ID: "campaign", CampaignID: "cmp-1", Type: models.PayoutTypeFixed, Amount: 1, IsActive: true},
+			},
+			in:         install,
+			wantRuleID: "campaign",
+			wantAmount: 1,
+		},
+		{
+			name: "percent of revenue with cap",
+			rules: []*models.PayoutRule{
+				{ID: "pct", Event: "purchase", Type: models.PayoutTypePercent, Percent: 30, MaxPayout: 10, IsActive: true},
+			},
+			in:         purchase,
+			wantRuleID: "pct",
+			wantAmount: 10,
+		},
+		{
+			name: "tiered picks the highest reached tier",
+			rules: []*models.PayoutRule{
+				{ID: "tiers", Event: "purchase", Type: models.PayoutTypeTiered, IsActive: true, Tiers: []models.PayoutTier{
+					{MinRevenue: 0, Amount: 1},
+					{MinRevenue: 20, Amount: 2, Percent: 10},
+					{MinRevenue: 100, Amount: 20},
+				}},
+			},
+			in:         purchase,
+			wantRuleID: "tiers",
+			wantAmount: 7,
+		},
+		{
+			name:       "legacy campaign payout when no rule matches",
+			campaign:   &models.Campaign{ID: "cmp-1", PayoutAmount: 1.5, PayoutEvent: "install"},
+			in:         install,
+			wantRuleID: legacyCampaignRulePrefix + "cmp-1",
+			wantAmount: 1.5,
+		},
+		{
+			name: "explicit rule beats legacy campaign payout",
+			rules: []*models.PayoutRule{
+				{ID: "any", Type: models.PayoutTypeFixed, Amount: 0.7, IsActive: true},
+			},
+			campaign:   &models.Campaign{ID: "cmp-1", PayoutAmount: 1.5},
+			in:         install,
+			wantRuleID: "any",
+			wantAmount: 0.7,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ruleRepo := storage.NewInMemoryPayoutRuleRepo()
+			for _, rule := range tt.rules {
+				if err := ruleRepo.Upsert(ctx, rule); err != nil {
+					t.Fatalf("Upsert(%s) error = %v", rule.ID, err)
+				}
+			}
+			campaignRepo := storage.NewInMemoryCampaignRepo()
+			if tt.campaign != nil {
+				if err := campaignRepo.Upsert(ctx, tt.campaign); err != nil {
+					t.Fatalf("Upsert(campaign) error = %v", err)
+				}
+			}
+
+			engine := NewPayoutEngine(ruleRepo, nil, campaignRepo, nil)
+			quote, err := engine.Resolve(ctx, tt.in)
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			if quote.RuleID != tt.wantRuleID {
+				t.Errorf("RuleID = %q, want %q", quote.RuleID, tt.wantRuleID)
+			}
+			if math.Abs(quote.Amount-tt.wantAmount) > 1e-9 {
+				t.Errorf("Amount = %v, want %v", quote.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestPayoutEngineConvertsRevenueToRuleCurrency(t *testing.T) {
+	ctx := context.Background()
+	ruleRepo := storage.NewInMemoryPayoutRuleRepo()
+	rule := &models.PayoutRule{ID: "rub", Type: models.PayoutTypePercent, Percent: 10, Currency: "RUB", IsActive: true}
+	if err := ruleRepo.Upsert(ctx, rule); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+	converter := currency.NewConverter(currency.NewStaticRateProvider(map[string]float64{"RUB": 90}), nil)
+	engine := NewPayoutEngine(ruleRepo, nil, storage.NewInMemoryCampaignRepo(), converter)
+
+	quote, err := engine.Resolve(ctx, PayoutInput{Event: "purchase", Revenue: 10, RevenueCurrency: "USD", Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if quote.Currency != "RUB" {
+		t.Errorf("Currency = %q, want RUB", quote.Currency)
+	}
+	if math.Abs(quote.Amount-90) > 1e-9 {
+		t.Errorf("Amount = %v, want 90 (10%% of 900 RUB)", quote.Amount)
+	}
+}
+
+func TestPayoutEngineQuoteOffer(t *testing.T) {
+	tests := []struct {
+		name          string
+		advCurrency   string
+		rates         map[string]float64
+		wantCurrency  string
+		wantAmountUSD float64
+	}{
+		{name: "usd advertiser", advCurrency: "USD", wantCurrency: "USD", wantAmountUSD: 90},
+		{name: "quoted in advertiser currency", advCurrency: "RUB", rates: map[string]float64{"RUB": 90}, wantCurrency: "RUB", wantAmountUSD: 1},
+		{name: "no rate ranks last", advCurrency: "RUB", rates: map[string]float64{}, wantCurrency: "RUB", wantAmountUSD: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			ruleRepo := storage.NewInMemoryPayoutRuleRepo()
+			if err := ruleRepo.Upsert(ctx, &models.PayoutRule{ID: "any", Type: models.PayoutTypeFixed, Amount: 90, IsActive: true}); err != nil {
+				t.Fatalf("Upsert(rule) error = %v", err)
+			}
+			advRepo := storage.NewInMemoryAdvertiserRepo()
+			if err := advRepo.Upsert(ctx, &models.Advertiser{ID: "adv-1", Name: "adv", Currency: tt.advCurrency}); err != nil {
+				t.Fatalf("Upsert(advertiser) error = %v", err)
+			}
+			var converter *currency.Converter
+			if tt.rates != nil {
+				converter = currency.NewConverter(currency.NewStaticRateProvider(tt.rates), nil)
+			}
+			engine := NewPayoutEngine(ruleRepo, nil, storage.NewInMemoryCampaignRepo(), converter)
+			engine.SetAdvertiserRepo(advRepo)
+
+			quote, err := engine.QuoteOffer(ctx, &models.Campaign{ID: "cmp-1", AdvertiserID: "adv-1"}, "s2s", "src-1", "RU", "android")
+			if err != nil {
+				t.Fatalf("QuoteOffer() error = %v", err)
+			}
+			if quote.Currency != tt.wantCurrency || quote.Amount != 90 {
+				t.Errorf("quote = %v %s, want 90 %s", quote.Amount, quote.Currency, tt.wantCurrency)
+			}
+			if math.Abs(quote.AmountUSD-tt.wantAmountUSD) > 1e-9 {
+				t.Errorf("AmountUSD = %v, want %v", quote.AmountUSD, tt.wantAmountUSD)
+			}
+		})
+	}
+}
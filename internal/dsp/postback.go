@@ -11,6 +11,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/fraud"
 	"github.com/radiusdt/vector-dsp/internal/metrics"
 	"github.com/radiusdt/vector-dsp/internal/models"
 	"github.com/radiusdt/vector-dsp/internal/storage"
@@ -19,12 +21,20 @@ import (
 
 // PostbackHandler handles postbacks from MMPs (AppsFlyer, Adjust, Singular, etc.)
 type PostbackHandler struct {
-	eventStore   storage.EventStore
-	sourceRepo   storage.SourceRepo
-	campaignRepo storage.CampaignRepo
-	logger       *zap.Logger
-	metrics      *metrics.Metrics
-	httpClient   *http.Client
+	eventStore     storage.EventStore
+	sourceRepo     storage.SourceRepo
+	campaignRepo   storage.CampaignRepo
+	advertiserRepo storage.AdvertiserRepo
+	payoutEngine   *PayoutEngine
+	converter      *currency.Converter
+	fraudScorer    *fraud.Scorer
+	logger         *zap.Logger
+	metrics        *metrics.Metrics
+	httpClient     *http.Client
+	sourceCaps     SourceCapTracker
+	live           *LiveFeed
+	billing        *BillingService
+	unpriced       storage.UnpricedEventRepo
 }
 
 // PostbackResult represents the result of processing a postback.
@@ -40,21 +50,51 @@ func NewPostbackHandler(
 	eventStore storage.EventStore,
 	sourceRepo storage.SourceRepo,
 	campaignRepo storage.CampaignRepo,
+	advertiserRepo storage.AdvertiserRepo,
+	payoutEngine *PayoutEngine,
+	converter *currency.Converter,
+	fraudScorer *fraud.Scorer,
 	logger *zap.Logger,
 	m *metrics.Metrics,
 ) *PostbackHandler {
 	return &PostbackHandler{
-		eventStore:   eventStore,
-		sourceRepo:   sourceRepo,
-		campaignRepo: campaignRepo,
-		logger:       logger,
-		metrics:      m,
+		eventStore:     eventStore,
+		sourceRepo:     sourceRepo,
+		campaignRepo:   campaignRepo,
+		advertiserRepo: advertiserRepo,
+		payoutEngine:   payoutEngine,
+		converter:      converter,
+		fraudScorer:    fraudScorer,
+		logger:         logger,
+		metrics:        m,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 }
 
+// SetSourceCaps enables counting of S2S payouts against source daily budget caps.
+func (h *PostbackHandler) SetSourceCaps(caps SourceCapTracker) {
+	h.sourceCaps = caps
+}
+
+// SetLiveFeed enables publishing conversions to the live feed.
+func (h *PostbackHandler) SetLiveFeed(feed *LiveFeed) {
+	h.live = feed
+}
+
+// SetBilling enables charging conversion payouts to advertiser balances.
+func (h *PostbackHandler) SetBilling(billing *BillingService) {
+	h.billing = billing
+}
+
+// SetUnpricedEvents holds conversions without an exchange rate in repo
+// until a Repricer records them, instead of saving them with no USD
+// amounts, charging nothing and withholding their postbacks.
+func (h *PostbackHandler) SetUnpricedEvents(repo storage.UnpricedEventRepo) {
+	h.unpriced = repo
+}
+
 // HandleAppsFlyer processes AppsFlyer postbacks.
 // Expected URL: /postback/appsflyer?click_id={clickid}&event={event_name}&revenue={event_revenue}&currency={currency}&idfa={idfa}&gaid={advertising_id}
 func (h *PostbackHandler) HandleAppsFlyer(ctx context.Context, r *http.Request) (*PostbackResult, error) {
@@ -105,7 +145,7 @@ func (h *PostbackHandler) HandleAppsFlyer(ctx context.Context, r *http.Request)
 	// Map AppsFlyer event to internal event
 	internalEvent := mapAppsFlyerEvent(eventName)
 
-	return h.processPostback(ctx, clickID, internalEvent, eventName, revenue, currency, gaid, idfa, externalID)
+	return h.processPostback(ctx, clickID, internalEvent, eventName, revenue, currency, gaid, idfa, externalID, installCountry(q))
 }
 
 // HandleAdjust processes Adjust postbacks.
@@ -146,7 +186,7 @@ func (h *PostbackHandler) HandleAdjust(ctx context.Context, r *http.Request) (*P
 
 	internalEvent := mapAdjustEvent(eventToken)
 
-	return h.processPostback(ctx, clickID, internalEvent, eventToken, revenue, currency, gaid, idfa, externalID)
+	return h.processPostback(ctx, clickID, internalEvent, eventToken, revenue, currency, gaid, idfa, externalID, installCountry(q))
 }
 
 // HandleSingular processes Singular postbacks.
@@ -184,7 +224,7 @@ func (h *PostbackHandler) HandleSingular(ctx context.Context, r *http.Request) (
 
 	internalEvent := mapSingularEvent(eventName)
 
-	return h.processPostback(ctx, clickID, internalEvent, eventName, revenue, currency, gaid, idfa, externalID)
+	return h.processPostback(ctx, clickID, internalEvent, eventName, revenue, currency, gaid, idfa, externalID, installCountry(q))
 }
 
 // HandleGeneric processes generic/custom postbacks.
@@ -219,15 +259,16 @@ func (h *PostbackHandler) HandleGeneric(ctx context.Context, r *http.Request) (*
 	idfa := q.Get("idfa")
 	externalID := q.Get("external_id")
 
-	return h.processPostback(ctx, clickID, eventName, eventName, revenue, currency, gaid, idfa, externalID)
+	return h.processPostback(ctx, clickID, eventName, eventName, revenue, currency, gaid, idfa, externalID, installCountry(q))
 }
 
 // processPostback is the common logic for all MMP postbacks.
 func (h *PostbackHandler) processPostback(
 	ctx context.Context,
 	clickID, internalEvent, originalEvent string,
-	revenue float64, currency string,
+	revenue float64, revenueCurrency string,
 	gaid, idfa, externalID string,
+	installCountry string,
 ) (*PostbackResult, error) {
 	// Look up the original click
 	click, err := h.eventStore.GetClick(ctx, clickID)
@@ -244,9 +285,9 @@ func (h *PostbackHandler) processPostback(
 	conversionID := uuid.New().String()
 
 	// Calculate time to install
-	timeToInstall := int32(0)
+	timeToInstall := int64(0)
 	if click != nil {
-		timeToInstall = int32(time.Since(click.Timestamp).Seconds())
+		timeToInstall = int64(time.Since(click.Timestamp).Seconds())
 	}
 
 	// Determine device IFA
@@ -264,41 +305,69 @@ func (h *PostbackHandler) processPostback(
 		geoCountry = click.GeoCountry
 	}
 
-	// Calculate payout (simplified - real implementation would check campaign settings)
+	// Price the payout. Payouts default to the advertiser's currency.
+	now := time.Now()
+	currencyCode := currency.Normalize(revenueCurrency)
 	payout := 0.0
+	payoutCurrency := currency.ReportingCurrency
+	payoutRuleID := ""
+	advertiserID := ""
 	if click != nil {
 		campaign, err := h.campaignRepo.GetByID(ctx, click.CampaignID)
 		if err == nil && campaign != nil {
-			if campaign.PayoutEvent == internalEvent || campaign.PayoutEvent == "" {
-				payout = campaign.PayoutAmount
+			advertiserID = campaign.AdvertiserID
+			payoutCurrency = h.advertiserCurrency(ctx, campaign.AdvertiserID)
+		}
+
+		if h.payoutEngine != nil {
+			quote, err := h.payoutEngine.Resolve(ctx, PayoutInput{
+				CampaignID:      click.CampaignID,
+				SourceType:      click.SourceType,
+				SourceID:        click.SourceID,
+				Country:         geoCountry,
+				OS:              click.DeviceOS,
+				Event:           internalEvent,
+				Revenue:         revenue,
+				RevenueCurrency: currencyCode,
+				Currency:        payoutCurrency,
+				At:              now,
+			})
+			if err != nil {
+				h.logger.Error("failed to resolve payout", zap.String("click_id", clickID), zap.Error(err))
+			} else {
+				payout = quote.Amount
+				payoutCurrency = quote.Currency
+				payoutRuleID = quote.RuleID
 			}
 		}
 	}
 
-	// Convert revenue to USD (simplified - real implementation would use exchange rates)
-	revenueUSD := revenue
-	if currency != "USD" && revenue > 0 {
-		// Apply exchange rate conversion here
-		revenueUSD = revenue // Placeholder
-	}
+	// Convert revenue and payout to the reporting currency at event time.
+	// Conversions with an amount without a rate are held for re-pricing,
+	// or saved unconverted and flagged if there is nowhere to hold them.
+	revenueUSD, revenueOK := h.toReporting(ctx, revenue, currencyCode, now)
+	payoutUSD, payoutOK := h.toReporting(ctx, payout, payoutCurrency, now)
 
 	// Create conversion record
 	conversion := &models.Conversion{
-		ID:            conversionID,
-		Timestamp:     time.Now(),
-		ClickID:       clickID,
-		Event:         internalEvent,
-		EventOriginal: originalEvent,
-		Revenue:       revenue,
-		RevenueCurrency: currency,
-		RevenueUSD:    revenueUSD,
-		Payout:        payout,
-		PayoutCurrency: "USD",
-		PayoutUSD:     payout,
-		DeviceIFA:     deviceIFA,
-		GeoCountry:    geoCountry,
-		TimeToInstall: timeToInstall,
-		ExternalID:    externalID,
+		ID:              conversionID,
+		Timestamp:       now,
+		ClickID:         clickID,
+		AdvertiserID:    advertiserID,
+		Event:           internalEvent,
+		EventOriginal:   originalEvent,
+		Revenue:         revenue,
+		RevenueCurrency: currencyCode,
+		RevenueUSD:      revenueUSD,
+		Payout:          payout,
+		PayoutCurrency:  payoutCurrency,
+		PayoutUSD:       payoutUSD,
+		PayoutRuleID:    payoutRuleID,
+		NeedsConversion: !revenueOK || !payoutOK,
+		DeviceIFA:       deviceIFA,
+		GeoCountry:      geoCountry,
+		TimeToInstall:   timeToInstall,
+		ExternalID:      externalID,
 	}
 
 	if click != nil {
@@ -307,38 +376,153 @@ func (h *PostbackHandler) processPostback(
 		conversion.CreativeID = click.CreativeID
 		conversion.SourceType = click.SourceType
 		conversion.SourceID = click.SourceID
-		conversion.ClickTimestamp = click.Timestamp
+		clickTime := click.Timestamp
+		conversion.ClickTime = &clickTime
+	}
+
+	// Score for fraud: click injection, geo mismatch, flagged clicks
+	if h.fraudScorer != nil && click != nil {
+		res := h.fraudScorer.ScoreConversion(ctx, fraud.ConversionSignals{
+			SourceType:     click.SourceType,
+			SourceID:       click.SourceID,
+			Event:          internalEvent,
+			ClickTime:      click.Timestamp,
+			At:             now,
+			ClickCountry:   geoCountry,
+			InstallCountry: installCountry,
+			ClickFlagged:   click.FraudFlagged,
+		})
+		conversion.FraudScore = res.Score
+		conversion.FraudReasons = res.Reasons
+		conversion.FraudFlagged = res.Flagged
+		if h.metrics != nil {
+			for _, reason := range res.Reasons {
+				h.metrics.RecordFraudFlag("conversion", reason)
+			}
+		}
+	}
+
+	if conversion.NeedsConversion && h.unpriced != nil {
+		err := h.unpriced.Hold(ctx, &models.UnpricedEvent{
+			ID:         conversionID,
+			Kind:       models.UnpricedConversion,
+			Conversion: conversion,
+			CreatedAt:  now,
+		})
+		if err == nil {
+			h.logger.Info("conversion held until its amounts can be converted",
+				zap.String("conversion_id", conversionID),
+				zap.String("click_id", clickID),
+				zap.String("revenue_currency", currencyCode),
+				zap.String("payout_currency", payoutCurrency),
+			)
+			return &PostbackResult{
+				Success:      true,
+				Message:      "conversion held until its amounts can be converted",
+				ConversionID: conversionID,
+			}, nil
+		}
+		h.logger.Error("failed to hold conversion, saving it unconverted", zap.Error(err))
+	}
+
+	if err := h.recordConversion(ctx, conversion, click); err != nil {
+		return &PostbackResult{Success: false, Error: "failed to save conversion"}, err
 	}
 
-	// Save conversion
+	return &PostbackResult{
+		Success:      true,
+		Message:      "conversion recorded",
+		ConversionID: conversionID,
+	}, nil
+}
+
+// recordConversion saves a conversion, counts it against the source cap,
+// in metrics and the live feed, charges its payout and posts it back to
+// its S2S source. Nothing but the save is done if the save fails.
+func (h *PostbackHandler) recordConversion(ctx context.Context, conversion *models.Conversion, click *models.Click) error {
 	if err := h.eventStore.SaveConversion(ctx, conversion); err != nil {
 		h.logger.Error("failed to save conversion", zap.Error(err))
-		return &PostbackResult{Success: false, Error: "failed to save conversion"}, err
+		return err
 	}
 
 	h.logger.Info("conversion recorded",
-		zap.String("conversion_id", conversionID),
-		zap.String("click_id", clickID),
-		zap.String("event", internalEvent),
-		zap.Float64("revenue", revenue),
-		zap.Float64("payout", payout),
+		zap.String("conversion_id", conversion.ID),
+		zap.String("click_id", conversion.ClickID),
+		zap.String("event", conversion.Event),
+		zap.Float64("revenue", conversion.Revenue),
+		zap.Float64("payout", conversion.Payout),
+		zap.String("payout_rule_id", conversion.PayoutRuleID),
+		zap.Int("fraud_score", conversion.FraudScore),
 	)
 
+	// Count payout against the source's daily budget cap
+	if h.sourceCaps != nil && click != nil && click.SourceType == "s2s" && !conversion.FraudFlagged {
+		if err := h.sourceCaps.RecordSpend(ctx, click.SourceID, conversion.PayoutUSD); err != nil {
+			h.logger.Warn("failed to count source spend", zap.String("source_id", click.SourceID), zap.Error(err))
+		}
+	}
+
 	// Record metrics
 	if h.metrics != nil && click != nil {
-		h.metrics.RecordConversion(click.CampaignID, click.LineItemID, internalEvent, payout)
+		h.metrics.RecordConversion(click.CampaignID, conversion.Event, conversion.RevenueUSD)
+	}
+	if h.live != nil {
+		h.live.RecordConversion(conversion.CampaignID, conversion.Event, conversion.RevenueUSD, conversion.PayoutUSD)
+	}
+
+	// Flagged conversions are not charged to the advertiser
+	if h.billing != nil && !conversion.FraudFlagged {
+		h.billing.RecordConversion(ctx, conversion.CampaignID, conversion.PayoutUSD)
 	}
 
-	// Send postback to S2S source if configured
+	// Send postback to S2S source if configured; flagged conversions are withheld
 	if click != nil && click.SourceType == "s2s" {
-		go h.sendPostbackToSource(ctx, conversion, click)
+		if conversion.FraudFlagged {
+			h.logger.Info("postback withheld for flagged conversion",
+				zap.String("conversion_id", conversion.ID),
+				zap.String("source_id", click.SourceID),
+				zap.Strings("fraud_reasons", conversion.FraudReasons),
+			)
+		} else {
+			go h.sendPostbackToSource(ctx, conversion, click)
+		}
 	}
+	return nil
+}
 
-	return &PostbackResult{
-		Success:      true,
-		Message:      "conversion recorded",
-		ConversionID: conversionID,
-	}, nil
+// advertiserCurrency returns the billing currency of an advertiser.
+func (h *PostbackHandler) advertiserCurrency(ctx context.Context, advertiserID string) string {
+	if h.advertiserRepo == nil || advertiserID == "" {
+		return currency.ReportingCurrency
+	}
+	adv, err := h.advertiserRepo.GetByID(ctx, advertiserID)
+	if err != nil || adv == nil {
+		return currency.ReportingCurrency
+	}
+	return currency.Normalize(adv.Currency)
+}
+
+// toReporting converts an amount to the reporting currency. If no rate is
+// available it logs and returns 0 and false; the caller keeps the original
+// amount and currency so the event can be re-priced.
+func (h *PostbackHandler) toReporting(ctx context.Context, amount float64, code string, at time.Time) (float64, bool) {
+	if amount == 0 || code == currency.ReportingCurrency {
+		return amount, true
+	}
+	if h.converter == nil {
+		h.logger.Warn("no currency converter, event needs re-pricing", zap.String("currency", code))
+		return 0, false
+	}
+	converted, err := h.converter.ToReporting(ctx, amount, code, at)
+	if err != nil {
+		h.logger.Warn("currency conversion failed, event needs re-pricing",
+			zap.String("currency", code),
+			zap.Float64("amount", amount),
+			zap.Error(err),
+		)
+		return 0, false
+	}
+	return converted, true
 }
 
 // sendPostbackToSource sends conversion postback to S2S source.
@@ -357,6 +541,17 @@ func (h *PostbackHandler) sendPostbackToSource(ctx context.Context, conv *models
 		return
 	}
 
+	// {payout} is in the reporting currency; a payout with no rate at event
+	// time would be sent as 0, so the postback waits for re-pricing.
+	if conv.NeedsConversion && conv.Payout != 0 && conv.PayoutUSD == 0 {
+		h.logger.Warn("postback withheld until the payout is converted",
+			zap.String("conversion_id", conv.ID),
+			zap.String("source_id", source.ID),
+			zap.String("payout_currency", conv.PayoutCurrency),
+		)
+		return
+	}
+
 	// Build postback URL with macro replacements
 	postbackURL := source.PostbackURL
 
@@ -367,13 +562,16 @@ func (h *PostbackHandler) sendPostbackToSource(ctx context.Context, conv *models
 		"{event}":         conv.Event,
 		"{revenue}":       fmt.Sprintf("%.4f", conv.Revenue),
 		"{currency}":      conv.RevenueCurrency,
-		"{payout}":        fmt.Sprintf("%.4f", conv.Payout),
+		"{payout}":        fmt.Sprintf("%.4f", conv.PayoutUSD),
 		"{timestamp}":     fmt.Sprintf("%d", conv.Timestamp.Unix()),
 		"{sub1}":          click.Sub1,
 		"{sub2}":          click.Sub2,
 		"{sub3}":          click.Sub3,
 		"{sub4}":          click.Sub4,
 		"{sub5}":          click.Sub5,
+
+		"{payout_original}":          fmt.Sprintf("%.4f", conv.Payout),
+		"{payout_original_currency}": conv.PayoutCurrency,
 	}
 
 	for macro, value := range replacements {
@@ -419,6 +617,15 @@ func (h *PostbackHandler) sendPostbackToSource(ctx context.Context, conv *models
 	}
 }
 
+// installCountry returns the install country reported by the MMP (ISO code).
+func installCountry(q url.Values) string {
+	country := q.Get("country_code")
+	if country == "" {
+		country = q.Get("country")
+	}
+	return strings.ToUpper(strings.TrimSpace(country))
+}
+
 // shouldSendEvent checks if event should be sent based on source configuration.
 func shouldSendEvent(configuredEvents []string, event string) bool {
 	if len(configuredEvents) == 0 {
@@ -438,21 +645,21 @@ func shouldSendEvent(configuredEvents []string, event string) bool {
 
 func mapAppsFlyerEvent(event string) string {
 	mapping := map[string]string{
-		"install":                    "install",
-		"af_app_install":             "install",
-		"af_complete_registration":   "registration",
-		"af_purchase":                "purchase",
-		"af_first_purchase":          "first_purchase",
-		"af_subscribe":               "subscribe",
-		"af_add_to_cart":             "add_to_cart",
-		"af_initiated_checkout":      "checkout",
-		"af_level_achieved":          "level_achieved",
-		"af_tutorial_completion":     "tutorial_complete",
-		"af_achievement_unlocked":    "achievement",
-		"af_content_view":            "content_view",
-		"af_search":                  "search",
-		"af_rate":                    "rate",
-		"af_start_trial":             "start_trial",
+		"install":                  "install",
+		"af_app_install":           "install",
+		"af_complete_registration": "registration",
+		"af_purchase":              "purchase",
+		"af_first_purchase":        "first_purchase",
+		"af_subscribe":             "subscribe",
+		"af_add_to_cart":           "add_to_cart",
+		"af_initiated_checkout":    "checkout",
+		"af_level_achieved":        "level_achieved",
+		"af_tutorial_completion":   "tutorial_complete",
+		"af_achievement_unlocked":  "achievement",
+		"af_content_view":          "content_view",
+		"af_search":                "search",
+		"af_rate":                  "rate",
+		"af_start_trial":           "start_trial",
 	}
 
 	if mapped, ok := mapping[event]; ok {
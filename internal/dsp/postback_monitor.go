@@ -0,0 +1,99 @@
+package dsp
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+)
+
+// postbackMonitorRetention is how far back postback outcomes are kept; it
+// covers the longest alert window.
+const postbackMonitorRetention = 7 * 24 * time.Hour
+
+// PostbackCounts are the postbacks of an MMP over a window.
+type PostbackCounts struct {
+	MMP    string `json:"mmp"`
+	Total  int64  `json:"total"`
+	Failed int64  `json:"failed"`
+}
+
+// PostbackMonitor counts postback outcomes per MMP in minute buckets for
+// postback error alerts. Counts are kept in memory, so each instance sees
+// the postbacks it received.
+type PostbackMonitor struct {
+	mu      sync.Mutex
+	buckets map[string]map[int64]*PostbackCounts // MMP -> unix minute -> counts
+	metrics *metrics.Metrics
+}
+
+// NewPostbackMonitor creates a new postback monitor.
+func NewPostbackMonitor(m *metrics.Metrics) *PostbackMonitor {
+	return &PostbackMonitor{
+		buckets: make(map[string]map[int64]*PostbackCounts),
+		metrics: m,
+	}
+}
+
+// Record counts a postback from mmp received at. A postback fails if it
+// errored or wasn't accepted (e.g. an unknown click).
+func (m *PostbackMonitor) Record(mmp string, ok bool, at time.Time) {
+	if m.metrics != nil {
+		result := "ok"
+		if !ok {
+			result = "error"
+		}
+		m.metrics.RecordPostback(mmp, result)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	minute := at.Unix() / 60
+	byMinute := m.buckets[mmp]
+	if byMinute == nil {
+		byMinute = make(map[int64]*PostbackCounts)
+		m.buckets[mmp] = byMinute
+	}
+	c := byMinute[minute]
+	if c == nil {
+		c = &PostbackCounts{MMP: mmp}
+		byMinute[minute] = c
+		// Buckets are created at most once a minute per MMP; drop the
+		// expired ones then.
+		oldest := at.Add(-postbackMonitorRetention).Unix() / 60
+		for bucket := range byMinute {
+			if bucket < oldest {
+				delete(byMinute, bucket)
+			}
+		}
+	}
+	c.Total++
+	if !ok {
+		c.Failed++
+	}
+}
+
+// Counts returns the postbacks per MMP received since, by MMP name.
+func (m *PostbackMonitor) Counts(since time.Time) []PostbackCounts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from := since.Unix() / 60
+	result := make([]PostbackCounts, 0, len(m.buckets))
+	for mmp, byMinute := range m.buckets {
+		total := PostbackCounts{MMP: mmp}
+		for bucket, c := range byMinute {
+			if bucket >= from {
+				total.Total += c.Total
+				total.Failed += c.Failed
+			}
+		}
+		if total.Total > 0 {
+			result = append(result, total)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].MMP < result[j].MMP })
+	return result
+}
@@ -0,0 +1,171 @@
+package dsp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/fraud"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// spendRecorder is a SourceCapTracker that remembers counted spend.
+type spendRecorder struct {
+	mu    sync.Mutex
+	spend map[string]float64
+}
+
+func (r *spendRecorder) RecordClick(ctx context.Context, sourceID string) error { return nil }
+
+func (r *spendRecorder) RecordSpend(ctx context.Context, sourceID string, amount float64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spend[sourceID] += amount
+	return nil
+}
+
+func (r *spendRecorder) Usage(ctx context.Context, sourceID string) (*SourceUsage, error) {
+	return nil, nil
+}
+
+func TestProcessPostbackFraud(t *testing.T) {
+	tests := []struct {
+		name           string
+		clickAge       time.Duration
+		clickFlagged   bool
+		installCountry string
+		wantReasons    []string
+		wantFlagged    bool
+	}{
+		{
+			name:           "clean conversion",
+			clickAge:       time.Hour,
+			installCountry: "RU",
+		},
+		{
+			name:           "click injection is flagged",
+			clickAge:       2 * time.Second,
+			installCountry: "RU",
+			wantReasons:    []string{fraud.ReasonCTITTooShort},
+			wantFlagged:    true,
+		},
+		{
+			name:           "geo mismatch alone stays below the threshold",
+			clickAge:       time.Hour,
+			installCountry: "KZ",
+			wantReasons:    []string{fraud.ReasonGeoMismatch},
+		},
+		{
+			name:           "conversion on a flagged click is flagged",
+			clickAge:       time.Hour,
+			clickFlagged:   true,
+			installCountry: "RU",
+			wantReasons:    []string{fraud.ReasonFlaggedClick},
+			wantFlagged:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			postbacks := make(chan string, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				postbacks <- r.URL.RawQuery
+			}))
+			defer srv.Close()
+
+			sources := storage.NewInMemorySourceRepo()
+			if err := sources.UpsertS2SSource(ctx, &models.S2SSource{
+				ID:           "src-1",
+				InternalName: "src-1",
+				PostbackURL:  srv.URL + "/pb?click={click_id}&payout={payout}",
+			}); err != nil {
+				t.Fatalf("UpsertS2SSource() error = %v", err)
+			}
+
+			events := storage.NewInMemoryEventStore()
+			click := &models.Click{
+				ID:           "click-1",
+				Timestamp:    time.Now().Add(-tt.clickAge),
+				CampaignID:   "cmp-1",
+				SourceType:   "s2s",
+				SourceID:     "src-1",
+				GeoCountry:   "RU",
+				FraudFlagged: tt.clickFlagged,
+			}
+			if err := events.SaveClick(ctx, click); err != nil {
+				t.Fatalf("SaveClick() error = %v", err)
+			}
+
+			rules := storage.NewInMemoryPayoutRuleRepo()
+			if err := rules.Upsert(ctx, &models.PayoutRule{ID: "any", Type: models.PayoutTypeFixed, Amount: 2, IsActive: true}); err != nil {
+				t.Fatalf("Upsert(rule) error = %v", err)
+			}
+			campaigns := storage.NewInMemoryCampaignRepo()
+			scorer := fraud.NewScorer(fraud.Config{MinCTIT: 10 * time.Second, FlagThreshold: 50}, fraud.NewInMemoryClickCounter(), nil)
+
+			h := NewPostbackHandler(events, sources, campaigns, storage.NewInMemoryAdvertiserRepo(),
+				NewPayoutEngine(rules, nil, campaigns, nil), nil, scorer, zap.NewNop(), nil)
+			caps := &spendRecorder{spend: make(map[string]float64)}
+			h.SetSourceCaps(caps)
+
+			res, err := h.processPostback(ctx, click.ID, "install", "install", 0, "USD", "", "", "", tt.installCountry)
+			if err != nil || !res.Success {
+				t.Fatalf("processPostback() = %+v, %v", res, err)
+			}
+
+			convs, err := events.GetConversionsByClick(ctx, click.ID)
+			if err != nil || len(convs) != 1 {
+				t.Fatalf("GetConversionsByClick() = %d conversions, %v", len(convs), err)
+			}
+			conv := convs[0]
+			if conv.FraudFlagged != tt.wantFlagged {
+				t.Errorf("FraudFlagged = %v, want %v", conv.FraudFlagged, tt.wantFlagged)
+			}
+			if len(conv.FraudReasons) != 0 || len(tt.wantReasons) != 0 {
+				if !reflect.DeepEqual(conv.FraudReasons, tt.wantReasons) {
+					t.Errorf("FraudReasons = %v, want %v", conv.FraudReasons, tt.wantReasons)
+				}
+			}
+			if conv.GeoCountry != "RU" {
+				t.Errorf("GeoCountry = %q, want RU", conv.GeoCountry)
+			}
+			if conv.ClickTime == nil || !conv.ClickTime.Equal(click.Timestamp) {
+				t.Errorf("ClickTime = %v, want %v", conv.ClickTime, click.Timestamp)
+			}
+
+			// Flagged conversions are neither counted against the source
+			// cap nor posted back to it.
+			wantSpend := 2.0
+			if tt.wantFlagged {
+				wantSpend = 0
+			}
+			caps.mu.Lock()
+			gotSpend := caps.spend["src-1"]
+			caps.mu.Unlock()
+			if gotSpend != wantSpend {
+				t.Errorf("source spend = %v, want %v", gotSpend, wantSpend)
+			}
+
+			select {
+			case query := <-postbacks:
+				if tt.wantFlagged {
+					t.Errorf("postback sent for flagged conversion: %s", query)
+				} else if query != "click=click-1&payout=2.0000" {
+					t.Errorf("postback query = %q", query)
+				}
+			case <-time.After(500 * time.Millisecond):
+				if !tt.wantFlagged {
+					t.Error("postback not sent")
+				}
+			}
+		})
+	}
+}
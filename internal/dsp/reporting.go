@@ -2,401 +2,586 @@ package dsp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/radiusdt/vector-dsp/internal/currency"
 	"github.com/radiusdt/vector-dsp/internal/storage"
-	"github.com/redis/go-redis/v9"
 )
 
-// ReportingService provides comprehensive campaign reporting.
+// ReportingService provides comprehensive campaign reporting. Reports are
+// grouped, sorted and paginated by the event store (see storage.ReportStore).
 type ReportingService struct {
 	eventStore storage.EventStore
-	redis      *redis.Client
+	reports    storage.ReportStore // nil if the event store can't run reports
+	cohorts    storage.CohortStore // nil if the event store can't run cohorts
+	converter  *currency.Converter
 }
 
 // NewReportingService creates a new reporting service.
-func NewReportingService(eventStore storage.EventStore, redis *redis.Client) *ReportingService {
+func NewReportingService(eventStore storage.EventStore, converter *currency.Converter) *ReportingService {
+	reports, _ := eventStore.(storage.ReportStore)
+	cohorts, _ := eventStore.(storage.CohortStore)
 	return &ReportingService{
 		eventStore: eventStore,
-		redis:      redis,
+		reports:    reports,
+		cohorts:    cohorts,
+		converter:  converter,
 	}
 }
 
 // CampaignStats aggregates metrics for a campaign.
 type CampaignStats struct {
-	CampaignID     string    `json:"campaign_id"`
-	CampaignName   string    `json:"campaign_name,omitempty"`
-	Date           string    `json:"date,omitempty"`
-	
+	CampaignID   string `json:"campaign_id"`
+	CampaignName string `json:"campaign_name,omitempty"`
+	Date         string `json:"date,omitempty"`
+	Currency     string `json:"currency"`
+
 	// Volume metrics
-	Impressions    int64     `json:"impressions"`
-	Clicks         int64     `json:"clicks"`
-	Conversions    int64     `json:"conversions"`
-	
+	Impressions int64 `json:"impressions"`
+	Clicks      int64 `json:"clicks"`
+	Conversions int64 `json:"conversions"`
+
 	// Financial metrics
-	Spend          float64   `json:"spend"`
-	Revenue        float64   `json:"revenue"`
-	Profit         float64   `json:"profit"`
-	
+	Spend   float64 `json:"spend"`
+	Revenue float64 `json:"revenue"`
+	Profit  float64 `json:"profit"`
+
 	// Rate metrics
-	CTR            float64   `json:"ctr"`       // Click-through rate (%)
-	CVR            float64   `json:"cvr"`       // Conversion rate (%)
-	WinRate        float64   `json:"win_rate"`  // Auction win rate (%)
-	
+	CTR     float64 `json:"ctr"`      // Click-through rate (%)
+	CVR     float64 `json:"cvr"`      // Conversion rate (%)
+	WinRate float64 `json:"win_rate"` // Auction win rate (%)
+
 	// Cost metrics
-	eCPM           float64   `json:"ecpm"`      // Effective CPM
-	eCPC           float64   `json:"ecpc"`      // Effective CPC
-	eCPA           float64   `json:"ecpa"`      // Effective CPA
-	ROAS           float64   `json:"roas"`      // Return on ad spend
-	
+	ECPM float64 `json:"ecpm"` // Effective CPM
+	ECPC float64 `json:"ecpc"` // Effective CPC
+	ECPA float64 `json:"ecpa"` // Effective CPA
+	ROAS float64 `json:"roas"` // Return on ad spend
+
 	// Pacing
-	BudgetSpent    float64   `json:"budget_spent_pct"`
-	
-	LastUpdated    time.Time `json:"last_updated"`
+	BudgetSpent float64 `json:"budget_spent_pct"`
+
+	LastUpdated time.Time `json:"last_updated"`
 }
 
 // LineItemStats aggregates metrics for a line item.
 type LineItemStats struct {
-	LineItemID     string    `json:"line_item_id"`
-	LineItemName   string    `json:"line_item_name,omitempty"`
-	CampaignID     string    `json:"campaign_id"`
-	Date           string    `json:"date,omitempty"`
-	
-	Impressions    int64     `json:"impressions"`
-	Clicks         int64     `json:"clicks"`
-	Conversions    int64     `json:"conversions"`
-	Spend          float64   `json:"spend"`
-	Revenue        float64   `json:"revenue"`
-	
-	CTR            float64   `json:"ctr"`
-	CVR            float64   `json:"cvr"`
-	eCPM           float64   `json:"ecpm"`
-	eCPC           float64   `json:"ecpc"`
-	eCPA           float64   `json:"ecpa"`
-	
+	LineItemID   string `json:"line_item_id"`
+	LineItemName string `json:"line_item_name,omitempty"`
+	CampaignID   string `json:"campaign_id"`
+	Date         string `json:"date,omitempty"`
+	Currency     string `json:"currency"`
+
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	Conversions int64   `json:"conversions"`
+	Spend       float64 `json:"spend"`
+	Revenue     float64 `json:"revenue"`
+
+	CTR  float64 `json:"ctr"`
+	CVR  float64 `json:"cvr"`
+	ECPM float64 `json:"ecpm"`
+	ECPC float64 `json:"ecpc"`
+	ECPA float64 `json:"ecpa"`
+
 	// Pacing info
-	DailyBudget    float64   `json:"daily_budget"`
-	DailySpend     float64   `json:"daily_spend"`
-	BudgetPacing   float64   `json:"budget_pacing_pct"`
-	
-	LastUpdated    time.Time `json:"last_updated"`
+	DailyBudget  float64 `json:"daily_budget"`
+	DailySpend   float64 `json:"daily_spend"`
+	BudgetPacing float64 `json:"budget_pacing_pct"`
+
+	LastUpdated time.Time `json:"last_updated"`
 }
 
 // CreativeStats aggregates metrics for a creative.
 type CreativeStats struct {
-	CreativeID     string    `json:"creative_id"`
-	CreativeName   string    `json:"creative_name,omitempty"`
-	LineItemID     string    `json:"line_item_id"`
-	CampaignID     string    `json:"campaign_id"`
-	
-	Impressions    int64     `json:"impressions"`
-	Clicks         int64     `json:"clicks"`
-	CTR            float64   `json:"ctr"`
-	Spend          float64   `json:"spend"`
-	
-	LastUpdated    time.Time `json:"last_updated"`
+	CreativeID   string `json:"creative_id"`
+	CreativeName string `json:"creative_name,omitempty"`
+	LineItemID   string `json:"line_item_id"`
+	CampaignID   string `json:"campaign_id"`
+
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	CTR         float64 `json:"ctr"`
+	Spend       float64 `json:"spend"`
+
+	LastUpdated time.Time `json:"last_updated"`
 }
 
 // GeoStats aggregates metrics by geography.
 type GeoStats struct {
-	Country        string    `json:"country"`
-	Region         string    `json:"region,omitempty"`
-	City           string    `json:"city,omitempty"`
-	
-	Impressions    int64     `json:"impressions"`
-	Clicks         int64     `json:"clicks"`
-	Conversions    int64     `json:"conversions"`
-	Spend          float64   `json:"spend"`
-	CTR            float64   `json:"ctr"`
+	Country string `json:"country"`
+	Region  string `json:"region,omitempty"`
+	City    string `json:"city,omitempty"`
+
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	Conversions int64   `json:"conversions"`
+	Spend       float64 `json:"spend"`
+	CTR         float64 `json:"ctr"`
 }
 
 // TimeSeriesPoint represents a single data point.
 type TimeSeriesPoint struct {
-	Timestamp      time.Time `json:"timestamp"`
-	Impressions    int64     `json:"impressions"`
-	Clicks         int64     `json:"clicks"`
-	Spend          float64   `json:"spend"`
-	Conversions    int64     `json:"conversions"`
+	Timestamp   time.Time `json:"timestamp"`
+	Impressions int64     `json:"impressions"`
+	Clicks      int64     `json:"clicks"`
+	Spend       float64   `json:"spend"`
+	Conversions int64     `json:"conversions"`
+}
+
+// SourceStats aggregates metrics for a traffic source.
+type SourceStats struct {
+	SourceID    string  `json:"source_id"`
+	Currency    string  `json:"currency"`
+	Impressions int64   `json:"impressions"`
+	Clicks      int64   `json:"clicks"`
+	Conversions int64   `json:"conversions"`
+	Spend       float64 `json:"spend"`
+	Revenue     float64 `json:"revenue"`
+	Payout      float64 `json:"payout"`
+	CTR         float64 `json:"ctr"`
+	CVR         float64 `json:"cvr"`
 }
 
 // ReportFilter defines filters for reports.
 type ReportFilter struct {
-	CampaignIDs    []string  `json:"campaign_ids,omitempty"`
-	LineItemIDs    []string  `json:"line_item_ids,omitempty"`
-	CreativeIDs    []string  `json:"creative_ids,omitempty"`
-	AdvertiserID   string    `json:"advertiser_id,omitempty"`
-	StartDate      time.Time `json:"start_date"`
-	EndDate        time.Time `json:"end_date"`
-	Granularity    string    `json:"granularity,omitempty"` // hourly, daily, weekly
-	GroupBy        []string  `json:"group_by,omitempty"`    // campaign, line_item, creative, geo, device
+	CampaignIDs  []string  `json:"campaign_ids,omitempty"`
+	LineItemIDs  []string  `json:"line_item_ids,omitempty"`
+	CreativeIDs  []string  `json:"creative_ids,omitempty"`
+	SourceIDs    []string  `json:"source_ids,omitempty"`
+	Countries    []string  `json:"countries,omitempty"`
+	OS           []string  `json:"os,omitempty"`
+	AppBundles   []string  `json:"app_bundles,omitempty"`
+	PublisherIDs []string  `json:"publisher_ids,omitempty"`
+	AdvertiserID string    `json:"advertiser_id,omitempty"`
+	StartDate    time.Time `json:"start_date"`            // Inclusive
+	EndDate      time.Time `json:"end_date"`              // Exclusive
+	Timezone     string    `json:"timezone,omitempty"`    // IANA name for time buckets; defaults to UTC
+	Granularity  string    `json:"granularity,omitempty"` // hourly, daily, weekly, monthly; adds a time dimension
+	GroupBy      []string  `json:"group_by,omitempty"`    // See storage.ReportDimensions
+	Metrics      []string  `json:"metrics,omitempty"`     // See storage.ReportMetrics; defaults to all
+	Sort         string    `json:"sort,omitempty"`        // Dimension or metric, "-" prefix for descending
+	Limit        int       `json:"limit,omitempty"`
+	Offset       int       `json:"offset,omitempty"`
+	Currency     string    `json:"currency,omitempty"` // Output currency; defaults to the reporting currency
 }
 
-// GetCampaignStats returns aggregated stats for campaigns.
-func (r *ReportingService) GetCampaignStats(ctx context.Context, filter ReportFilter) ([]CampaignStats, error) {
-	// Get clicks from event store
-	clicks, err := r.eventStore.ListClicks()
+// ErrReportsUnsupported is returned when the event store cannot run reports.
+var ErrReportsUnsupported = errors.New("event store does not support reports")
+
+// ErrInvalidReport wraps report filter validation errors.
+var ErrInvalidReport = errors.New("invalid report")
+
+// reportGranularities maps ReportFilter.Granularity to a time dimension.
+var reportGranularities = map[string]string{
+	"hourly":  storage.ReportDimHour,
+	"daily":   storage.ReportDimDate,
+	"weekly":  storage.ReportDimWeek,
+	"monthly": storage.ReportDimMonth,
+}
+
+// reportDimensionAliases keeps the group-by names accepted before the query layer.
+var reportDimensionAliases = map[string]string{
+	"geo":    storage.ReportDimCountry,
+	"device": storage.ReportDimOS,
+}
+
+// reportMoneyMetrics are converted into the report currency.
+var reportMoneyMetrics = []string{
+	storage.ReportMetricSpend, storage.ReportMetricRevenue, storage.ReportMetricPayout,
+	storage.ReportMetricProfit, storage.ReportMetricECPM, storage.ReportMetricECPC, storage.ReportMetricECPA,
+}
+
+// Report runs a grouped report in the event store and converts money
+// metrics into the filter currency at each row's date.
+func (r *ReportingService) Report(ctx context.Context, filter ReportFilter) (*storage.ReportResult, error) {
+	if r.reports == nil {
+		return nil, ErrReportsUnsupported
+	}
+
+	q, err := r.buildQuery(filter)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
 	}
 
-	// Get conversions
-	conversions, err := r.eventStore.ListConversions()
+	result, err := r.reports.QueryReport(ctx, q)
 	if err != nil {
 		return nil, err
 	}
 
-	// Build campaign stats map
-	statsMap := make(map[string]*CampaignStats)
-
-	// Aggregate clicks
-	for _, c := range clicks {
-		if !r.matchesFilter(c.CampaignID, filter) {
-			continue
-		}
-		
-		st, ok := statsMap[c.CampaignID]
-		if !ok {
-			st = &CampaignStats{
-				CampaignID:  c.CampaignID,
-				LastUpdated: time.Now(),
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		at := reportRowTime(row, q)
+		for _, m := range reportMoneyMetrics {
+			if v, ok := row.Metrics[m]; ok {
+				if row.Metrics[m], err = r.convert(ctx, v, filter.Currency, at); err != nil {
+					return nil, err
+				}
 			}
-			statsMap[c.CampaignID] = st
 		}
-		st.Clicks++
 	}
+	return result, nil
+}
 
-	// Aggregate conversions
-	for _, conv := range conversions {
-		if conv.ClickID == "" {
-			continue
+// ValidateFilter checks a filter without running it. Dates are not checked
+// so saved filters whose range is filled in later pass.
+func (r *ReportingService) ValidateFilter(filter ReportFilter) error {
+	filter.StartDate, filter.EndDate = time.Time{}, time.Time{}
+	if _, err := r.buildQuery(filter); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	return nil
+}
+
+// buildQuery translates a filter into a validated storage query.
+func (r *ReportingService) buildQuery(filter ReportFilter) (storage.ReportQuery, error) {
+	q := storage.ReportQuery{
+		Start:        filter.StartDate,
+		End:          filter.EndDate,
+		Metrics:      filter.Metrics,
+		CampaignIDs:  filter.CampaignIDs,
+		LineItemIDs:  filter.LineItemIDs,
+		CreativeIDs:  filter.CreativeIDs,
+		SourceIDs:    filter.SourceIDs,
+		Countries:    filter.Countries,
+		OS:           filter.OS,
+		AppBundles:   filter.AppBundles,
+		PublisherIDs: filter.PublisherIDs,
+		Limit:        filter.Limit,
+		Offset:       filter.Offset,
+	}
+
+	q.Location = time.UTC
+	if filter.Timezone != "" {
+		loc, err := time.LoadLocation(filter.Timezone)
+		if err != nil {
+			return q, fmt.Errorf("unknown timezone %q", filter.Timezone)
+		}
+		q.Location = loc
+	}
+
+	if filter.Granularity != "" {
+		dim, ok := reportGranularities[filter.Granularity]
+		if !ok {
+			return q, fmt.Errorf("unknown granularity %q", filter.Granularity)
 		}
-		click, err := r.eventStore.GetClick(conv.ClickID)
-		if err != nil || click == nil {
-			continue
+		q.Dimensions = append(q.Dimensions, dim)
+	}
+	for _, d := range filter.GroupBy {
+		if alias, ok := reportDimensionAliases[d]; ok {
+			d = alias
 		}
-		
-		if !r.matchesFilter(click.CampaignID, filter) {
-			continue
+		if len(q.Dimensions) > 0 && q.Dimensions[0] == d && filter.Granularity != "" {
+			continue // Granularity already added it
 		}
+		q.Dimensions = append(q.Dimensions, d)
+	}
 
-		st, ok := statsMap[click.CampaignID]
-		if !ok {
-			st = &CampaignStats{
-				CampaignID:  click.CampaignID,
-				LastUpdated: time.Now(),
+	q.SortBy = strings.TrimPrefix(filter.Sort, "-")
+	q.SortDesc = strings.HasPrefix(filter.Sort, "-")
+
+	if err := q.Validate(); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// reportRowTime returns the start of the row's time bucket, or the end of
+// the report when it is not grouped by time.
+func reportRowTime(row *storage.ReportRow, q storage.ReportQuery) time.Time {
+	layouts := map[string]string{
+		storage.ReportDimHour:  "2006-01-02 15:00",
+		storage.ReportDimDate:  "2006-01-02",
+		storage.ReportDimWeek:  "2006-01-02",
+		storage.ReportDimMonth: "2006-01",
+	}
+	for dim, layout := range layouts {
+		if v, ok := row.Dimensions[dim]; ok {
+			if t, err := time.ParseInLocation(layout, v, q.Location); err == nil {
+				return t
 			}
-			statsMap[click.CampaignID] = st
 		}
-		st.Conversions++
-		st.Revenue += conv.Revenue
 	}
+	return q.End.Add(-time.Nanosecond)
+}
 
-	// Get impressions and spend from Redis
-	for campaignID, st := range statsMap {
-		imps, spend := r.getImpressionData(ctx, campaignID, filter.StartDate, filter.EndDate)
-		st.Impressions = imps
-		st.Spend = spend
-		
-		// Calculate derived metrics
-		r.calculateDerivedMetrics(st)
+// statsFilter is filter grouped by dims with every metric and row, over the
+// last 30 days unless a start date is set.
+func statsFilter(filter ReportFilter, dims ...string) ReportFilter {
+	filter.GroupBy = dims
+	filter.Granularity = ""
+	filter.Metrics = nil
+	filter.Sort = ""
+	filter.Limit = storage.MaxReportLimit
+	filter.Offset = 0
+	if filter.StartDate.IsZero() {
+		end := filter.EndDate
+		if end.IsZero() {
+			end = time.Now()
+		}
+		filter.StartDate = end.AddDate(0, 0, -30)
 	}
+	return filter
+}
 
-	// Convert to slice
-	result := make([]CampaignStats, 0, len(statsMap))
-	for _, st := range statsMap {
-		result = append(result, *st)
+// GetCampaignStats returns aggregated stats for campaigns.
+func (r *ReportingService) GetCampaignStats(ctx context.Context, filter ReportFilter) ([]CampaignStats, error) {
+	result, err := r.Report(ctx, statsFilter(filter, storage.ReportDimCampaign))
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
+	stats := make([]CampaignStats, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		st := CampaignStats{
+			CampaignID:  row.Dimensions[storage.ReportDimCampaign],
+			Currency:    currency.Normalize(filter.Currency),
+			Impressions: int64(row.Metrics[storage.ReportMetricImpressions]),
+			Clicks:      int64(row.Metrics[storage.ReportMetricClicks]),
+			Conversions: int64(row.Metrics[storage.ReportMetricConversions]),
+			Spend:       row.Metrics[storage.ReportMetricSpend],
+			Revenue:     row.Metrics[storage.ReportMetricRevenue],
+			LastUpdated: time.Now(),
+		}
+		r.calculateDerivedMetrics(&st)
+		stats = append(stats, st)
+	}
+	return stats, nil
 }
 
 // GetLineItemStats returns aggregated stats for line items.
 func (r *ReportingService) GetLineItemStats(ctx context.Context, filter ReportFilter) ([]LineItemStats, error) {
-	// Similar implementation to GetCampaignStats but grouped by line item
-	statsMap := make(map[string]*LineItemStats)
-
-	clicks, err := r.eventStore.ListClicks()
+	result, err := r.Report(ctx, statsFilter(filter, storage.ReportDimLineItem, storage.ReportDimCampaign))
 	if err != nil {
 		return nil, err
 	}
 
-	for _, c := range clicks {
-		if !r.matchesLineItemFilter(c.LineItemID, filter) {
-			continue
-		}
-
-		st, ok := statsMap[c.LineItemID]
-		if !ok {
-			st = &LineItemStats{
-				LineItemID:  c.LineItemID,
-				CampaignID:  c.CampaignID,
-				LastUpdated: time.Now(),
-			}
-			statsMap[c.LineItemID] = st
+	stats := make([]LineItemStats, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		st := LineItemStats{
+			LineItemID:  row.Dimensions[storage.ReportDimLineItem],
+			CampaignID:  row.Dimensions[storage.ReportDimCampaign],
+			Currency:    currency.Normalize(filter.Currency),
+			Impressions: int64(row.Metrics[storage.ReportMetricImpressions]),
+			Clicks:      int64(row.Metrics[storage.ReportMetricClicks]),
+			Conversions: int64(row.Metrics[storage.ReportMetricConversions]),
+			Spend:       row.Metrics[storage.ReportMetricSpend],
+			Revenue:     row.Metrics[storage.ReportMetricRevenue],
+			LastUpdated: time.Now(),
 		}
-		st.Clicks++
+		r.calculateLineItemDerivedMetrics(&st)
+		stats = append(stats, st)
 	}
+	return stats, nil
+}
 
-	// Get conversions
-	conversions, _ := r.eventStore.ListConversions()
-	for _, conv := range conversions {
-		if conv.ClickID == "" {
-			continue
-		}
-		click, _ := r.eventStore.GetClick(conv.ClickID)
-		if click == nil {
-			continue
-		}
+// GetSourceStats returns stats per traffic source for the last 30 days.
+func (r *ReportingService) GetSourceStats(ctx context.Context) ([]SourceStats, error) {
+	result, err := r.Report(ctx, statsFilter(ReportFilter{}, storage.ReportDimSource))
+	if err != nil {
+		return nil, err
+	}
 
-		st, ok := statsMap[click.LineItemID]
-		if !ok {
-			continue
-		}
-		st.Conversions++
-		st.Revenue += conv.Revenue
+	stats := make([]SourceStats, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		stats = append(stats, SourceStats{
+			SourceID:    row.Dimensions[storage.ReportDimSource],
+			Currency:    currency.ReportingCurrency,
+			Impressions: int64(row.Metrics[storage.ReportMetricImpressions]),
+			Clicks:      int64(row.Metrics[storage.ReportMetricClicks]),
+			Conversions: int64(row.Metrics[storage.ReportMetricConversions]),
+			Spend:       row.Metrics[storage.ReportMetricSpend],
+			Revenue:     row.Metrics[storage.ReportMetricRevenue],
+			Payout:      row.Metrics[storage.ReportMetricPayout],
+			CTR:         row.Metrics[storage.ReportMetricCTR],
+			CVR:         row.Metrics[storage.ReportMetricCVR],
+		})
 	}
+	return stats, nil
+}
 
-	// Get impressions from Redis
-	for lineItemID, st := range statsMap {
-		imps, spend := r.getLineItemImpressionData(ctx, lineItemID, filter.StartDate, filter.EndDate)
-		st.Impressions = imps
-		st.Spend = spend
-		r.calculateLineItemDerivedMetrics(st)
+// GetTimeSeries returns hourly or daily (default) points for a campaign,
+// with empty buckets filled with zeros.
+func (r *ReportingService) GetTimeSeries(ctx context.Context, campaignID string, filter ReportFilter) ([]TimeSeriesPoint, error) {
+	if filter.Granularity != "hourly" {
+		filter.Granularity = "daily"
+	}
+	if filter.EndDate.IsZero() {
+		filter.EndDate = time.Now()
+	}
+	if filter.StartDate.IsZero() {
+		filter.StartDate = filter.EndDate.AddDate(0, 0, -7)
+	}
+	if campaignID != "" {
+		filter.CampaignIDs = []string{campaignID}
+	}
+	filter.GroupBy = nil
+	filter.Metrics = []string{
+		storage.ReportMetricImpressions, storage.ReportMetricClicks,
+		storage.ReportMetricConversions, storage.ReportMetricSpend,
 	}
+	filter.Sort = ""
+	filter.Limit = storage.MaxReportLimit
+	filter.Offset = 0
 
-	result := make([]LineItemStats, 0, len(statsMap))
-	for _, st := range statsMap {
-		result = append(result, *st)
+	q, err := r.buildQuery(filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+	result, err := r.Report(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
-}
+	dim := q.Dimensions[0]
+	byBucket := make(map[string]storage.ReportRow, len(result.Rows))
+	for _, row := range result.Rows {
+		byBucket[row.Dimensions[dim]] = row
+	}
 
-// GetTimeSeries returns time series data for a campaign.
-func (r *ReportingService) GetTimeSeries(ctx context.Context, campaignID string, filter ReportFilter) ([]TimeSeriesPoint, error) {
-	// This would typically query a time-series database
-	// For now, return aggregated daily data from Redis
-	
-	var points []TimeSeriesPoint
-	
-	start := filter.StartDate
-	if start.IsZero() {
-		start = time.Now().AddDate(0, 0, -7)
-	}
-	end := filter.EndDate
-	if end.IsZero() {
-		end = time.Now()
-	}
-
-	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
-		dateStr := d.Format("2006-01-02")
-		
-		// Get daily stats from Redis
-		impsKey := fmt.Sprintf("stats:imps:%s:%s", campaignID, dateStr)
-		spendKey := fmt.Sprintf("stats:spend:%s:%s", campaignID, dateStr)
-		clicksKey := fmt.Sprintf("stats:clicks:%s:%s", campaignID, dateStr)
-		
-		imps, _ := r.redis.Get(ctx, impsKey).Int64()
-		spend, _ := r.redis.Get(ctx, spendKey).Float64()
-		clicks, _ := r.redis.Get(ctx, clicksKey).Int64()
+	step := func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }
+	layout := "2006-01-02"
+	start := filter.StartDate.In(q.Location)
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, q.Location)
+	if dim == storage.ReportDimHour {
+		step = func(t time.Time) time.Time { return t.Add(time.Hour) }
+		layout = "2006-01-02 15:00"
+		start = filter.StartDate.In(q.Location)
+		start = time.Date(start.Year(), start.Month(), start.Day(), start.Hour(), 0, 0, 0, q.Location)
+	}
 
+	points := make([]TimeSeriesPoint, 0)
+	for t := start; t.Before(filter.EndDate); t = step(t) {
+		row := byBucket[t.Format(layout)]
 		points = append(points, TimeSeriesPoint{
-			Timestamp:   d,
-			Impressions: imps,
-			Clicks:      clicks,
-			Spend:       spend,
+			Timestamp:   t,
+			Impressions: int64(row.Metrics[storage.ReportMetricImpressions]),
+			Clicks:      int64(row.Metrics[storage.ReportMetricClicks]),
+			Conversions: int64(row.Metrics[storage.ReportMetricConversions]),
+			Spend:       row.Metrics[storage.ReportMetricSpend],
 		})
 	}
-
 	return points, nil
 }
 
-// GetGeoBreakdown returns geographic breakdown of stats.
+// GetGeoBreakdown returns stats per country for a campaign over the last
+// 30 days, most impressions first.
 func (r *ReportingService) GetGeoBreakdown(ctx context.Context, campaignID string) ([]GeoStats, error) {
-	// This would typically query aggregated geo data
-	// For now, return empty slice as this requires additional tracking
-	return []GeoStats{}, nil
-}
-
-// Helper methods
-
-func (r *ReportingService) matchesFilter(campaignID string, filter ReportFilter) bool {
-	if len(filter.CampaignIDs) == 0 {
-		return true
+	filter := statsFilter(ReportFilter{}, storage.ReportDimCountry)
+	if campaignID != "" {
+		filter.CampaignIDs = []string{campaignID}
 	}
-	for _, id := range filter.CampaignIDs {
-		if id == campaignID {
-			return true
-		}
-	}
-	return false
-}
+	filter.Sort = "-" + storage.ReportMetricImpressions
 
-func (r *ReportingService) matchesLineItemFilter(lineItemID string, filter ReportFilter) bool {
-	if len(filter.LineItemIDs) == 0 {
-		return true
+	result, err := r.Report(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
-	for _, id := range filter.LineItemIDs {
-		if id == lineItemID {
-			return true
-		}
+
+	stats := make([]GeoStats, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		stats = append(stats, GeoStats{
+			Country:     row.Dimensions[storage.ReportDimCountry],
+			Impressions: int64(row.Metrics[storage.ReportMetricImpressions]),
+			Clicks:      int64(row.Metrics[storage.ReportMetricClicks]),
+			Conversions: int64(row.Metrics[storage.ReportMetricConversions]),
+			Spend:       row.Metrics[storage.ReportMetricSpend],
+			CTR:         row.Metrics[storage.ReportMetricCTR],
+		})
 	}
-	return false
+	return stats, nil
 }
 
-func (r *ReportingService) getImpressionData(ctx context.Context, campaignID string, start, end time.Time) (int64, float64) {
-	var totalImps int64
-	var totalSpend float64
+// CohortFilter defines an install cohort report.
+type CohortFilter struct {
+	CampaignIDs     []string  `json:"campaign_ids,omitempty"`
+	SourceIDs       []string  `json:"source_ids,omitempty"`
+	AdvertiserID    string    `json:"advertiser_id,omitempty"`
+	StartDate       time.Time `json:"start_date"`                 // First install date, inclusive
+	EndDate         time.Time `json:"end_date"`                   // Exclusive
+	Timezone        string    `json:"timezone,omitempty"`         // IANA name for install dates; defaults to UTC
+	GroupBy         []string  `json:"group_by,omitempty"`         // See storage.CohortDimensions
+	Days            []int     `json:"days,omitempty"`             // Days since install, e.g. 0, 1, 7, 30
+	RetentionEvents []string  `json:"retention_events,omitempty"` // Events counted as retention, e.g. session
+	Currency        string    `json:"currency,omitempty"`
+}
 
-	if start.IsZero() {
-		start = time.Now().AddDate(0, 0, -30)
+// GetCohorts reports revenue, ROAS, payers and retention of installs by
+// days since install. Money is converted at each cohort's install date.
+func (r *ReportingService) GetCohorts(ctx context.Context, filter CohortFilter) (*storage.CohortResult, error) {
+	if r.cohorts == nil {
+		return nil, ErrReportsUnsupported
+	}
+
+	q := storage.CohortQuery{
+		Start:           filter.StartDate,
+		End:             filter.EndDate,
+		Dimensions:      filter.GroupBy,
+		Days:            filter.Days,
+		RetentionEvents: filter.RetentionEvents,
+		CampaignIDs:     filter.CampaignIDs,
+		SourceIDs:       filter.SourceIDs,
+	}
+	if filter.Timezone != "" {
+		loc, err := time.LoadLocation(filter.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown timezone %q", ErrInvalidReport, filter.Timezone)
+		}
+		q.Location = loc
 	}
-	if end.IsZero() {
-		end = time.Now()
+	if err := q.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
 	}
 
-	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
-		dateStr := d.Format("2006-01-02")
-		
-		impsKey := fmt.Sprintf("stats:imps:%s:%s", campaignID, dateStr)
-		spendKey := fmt.Sprintf("stats:spend:%s:%s", campaignID, dateStr)
-		
-		imps, _ := r.redis.Get(ctx, impsKey).Int64()
-		spend, _ := r.redis.Get(ctx, spendKey).Float64()
-		
-		totalImps += imps
-		totalSpend += spend
+	result, err := r.cohorts.QueryCohorts(ctx, q)
+	if err != nil {
+		return nil, err
 	}
 
-	return totalImps, totalSpend
+	for i := range result.Rows {
+		row := &result.Rows[i]
+		at := q.End.Add(-time.Nanosecond)
+		if date, ok := row.Dimensions[storage.CohortDimInstallDate]; ok {
+			if t, err := time.ParseInLocation("2006-01-02", date, q.Location); err == nil {
+				at = t
+			}
+		}
+		if row.Cost, err = r.convert(ctx, row.Cost, filter.Currency, at); err != nil {
+			return nil, err
+		}
+		for j := range row.Days {
+			day := &row.Days[j]
+			if day.Revenue, err = r.convert(ctx, day.Revenue, filter.Currency, at); err != nil {
+				return nil, err
+			}
+			if day.ARPU, err = r.convert(ctx, day.ARPU, filter.Currency, at); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
 }
 
-func (r *ReportingService) getLineItemImpressionData(ctx context.Context, lineItemID string, start, end time.Time) (int64, float64) {
-	var totalImps int64
-	var totalSpend float64
+// Helper methods
 
-	if start.IsZero() {
-		start = time.Now().AddDate(0, 0, -30)
+// convert converts a reporting-currency amount into the requested report
+// currency using the rate of the day the amount was booked. It fails rather
+// than return a USD amount labeled as another currency.
+func (r *ReportingService) convert(ctx context.Context, amount float64, cur string, at time.Time) (float64, error) {
+	cur = currency.Normalize(cur)
+	if amount == 0 || cur == currency.ReportingCurrency {
+		return amount, nil
 	}
-	if end.IsZero() {
-		end = time.Now()
+	if r.converter == nil {
+		return 0, fmt.Errorf("no exchange rates to convert the report to %s", cur)
 	}
-
-	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
-		dateStr := d.Format("2006-01-02")
-		
-		impsKey := fmt.Sprintf("pacing:imps:%s:%s", lineItemID, dateStr)
-		spendKey := fmt.Sprintf("pacing:budget:%s:%s", lineItemID, dateStr)
-		
-		imps, _ := r.redis.Get(ctx, impsKey).Int64()
-		spend, _ := r.redis.Get(ctx, spendKey).Float64()
-		
-		totalImps += imps
-		totalSpend += spend
+	converted, err := r.converter.FromReporting(ctx, amount, cur, at)
+	if err != nil {
+		return 0, fmt.Errorf("failed to convert the report to %s: %w", cur, err)
 	}
-
-	return totalImps, totalSpend
+	return converted, nil
 }
 
 func (r *ReportingService) calculateDerivedMetrics(st *CampaignStats) {
@@ -404,32 +589,32 @@ func (r *ReportingService) calculateDerivedMetrics(st *CampaignStats) {
 	if st.Impressions > 0 {
 		st.CTR = float64(st.Clicks) / float64(st.Impressions) * 100
 	}
-	
+
 	// CVR
 	if st.Clicks > 0 {
 		st.CVR = float64(st.Conversions) / float64(st.Clicks) * 100
 	}
-	
-	// eCPM
+
+	// ECPM
 	if st.Impressions > 0 {
-		st.eCPM = st.Spend / float64(st.Impressions) * 1000
+		st.ECPM = st.Spend / float64(st.Impressions) * 1000
 	}
-	
-	// eCPC
+
+	// ECPC
 	if st.Clicks > 0 {
-		st.eCPC = st.Spend / float64(st.Clicks)
+		st.ECPC = st.Spend / float64(st.Clicks)
 	}
-	
-	// eCPA
+
+	// ECPA
 	if st.Conversions > 0 {
-		st.eCPA = st.Spend / float64(st.Conversions)
+		st.ECPA = st.Spend / float64(st.Conversions)
 	}
-	
+
 	// ROAS
 	if st.Spend > 0 {
 		st.ROAS = st.Revenue / st.Spend
 	}
-	
+
 	// Profit
 	st.Profit = st.Revenue - st.Spend
 }
@@ -437,14 +622,14 @@ func (r *ReportingService) calculateDerivedMetrics(st *CampaignStats) {
 func (r *ReportingService) calculateLineItemDerivedMetrics(st *LineItemStats) {
 	if st.Impressions > 0 {
 		st.CTR = float64(st.Clicks) / float64(st.Impressions) * 100
-		st.eCPM = st.Spend / float64(st.Impressions) * 1000
+		st.ECPM = st.Spend / float64(st.Impressions) * 1000
 	}
 	if st.Clicks > 0 {
 		st.CVR = float64(st.Conversions) / float64(st.Clicks) * 100
-		st.eCPC = st.Spend / float64(st.Clicks)
+		st.ECPC = st.Spend / float64(st.Clicks)
 	}
 	if st.Conversions > 0 {
-		st.eCPA = st.Spend / float64(st.Conversions)
+		st.ECPA = st.Spend / float64(st.Conversions)
 	}
 	if st.DailyBudget > 0 {
 		st.BudgetPacing = st.DailySpend / st.DailyBudget * 100
@@ -457,9 +642,9 @@ type StatsService struct {
 }
 
 // NewStatsService creates a new stats service.
-func NewStatsService(eventStore storage.EventStore, redis *redis.Client) *StatsService {
+func NewStatsService(eventStore storage.EventStore) *StatsService {
 	return &StatsService{
-		reporting: NewReportingService(eventStore, redis),
+		reporting: NewReportingService(eventStore, nil),
 	}
 }
 
@@ -0,0 +1,80 @@
+package dsp
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+func TestReportSpendFromWins(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	events := storage.NewInMemoryEventStore()
+	converter := currency.NewConverter(currency.NewStaticRateProvider(map[string]float64{"RUB": 100}), nil)
+	tracking := NewTrackingService(events, storage.NewInMemoryCampaignRepo(), nil, converter, nil, "", zap.NewNop(), nil)
+
+	// Impressions carry no price; spend is the USD price of the wins
+	if err := events.SaveImpression(ctx, &models.Impression{ID: "imp-1", Timestamp: now, CampaignID: "cmp-1"}); err != nil {
+		t.Fatalf("SaveImpression() error = %v", err)
+	}
+	tracking.RegisterWin(ctx, "1", "cmp-1", "li-1", "cr-1", 150, "RUB")
+	tracking.RegisterWin(ctx, "1", "cmp-1", "li-1", "cr-1", 0.5, "USD")
+
+	// An install of the same campaign, for the cohort cost
+	if err := events.SaveConversion(ctx, &models.Conversion{
+		ID: "conv-1", Timestamp: now, ClickID: "click-1", CampaignID: "cmp-1", Event: storage.InstallEvent,
+		Payout: 1, PayoutCurrency: "USD", PayoutUSD: 1,
+	}); err != nil {
+		t.Fatalf("SaveConversion() error = %v", err)
+	}
+
+	reporting := NewReportingService(events, converter)
+	start := now.Add(-time.Hour)
+	end := now.Add(time.Hour)
+
+	report, err := reporting.Report(ctx, ReportFilter{
+		StartDate: start,
+		EndDate:   end,
+		GroupBy:   []string{storage.ReportDimCampaign},
+		Metrics:   []string{storage.ReportMetricImpressions, storage.ReportMetricSpend, storage.ReportMetricECPM},
+	})
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(report.Rows) != 1 {
+		t.Fatalf("Report() = %d rows, want 1", len(report.Rows))
+	}
+	metrics := report.Rows[0].Metrics
+	if got := metrics[storage.ReportMetricSpend]; math.Abs(got-2) > 1e-9 {
+		t.Errorf("spend = %v, want 2 (1.5 from RUB and 0.5 USD)", got)
+	}
+	if got := metrics[storage.ReportMetricImpressions]; got != 1 {
+		t.Errorf("impressions = %v, want 1", got)
+	}
+	if got := metrics[storage.ReportMetricECPM]; math.Abs(got-2000) > 1e-6 {
+		t.Errorf("ecpm = %v, want 2000", got)
+	}
+
+	cohorts, err := reporting.GetCohorts(ctx, CohortFilter{
+		StartDate: start,
+		EndDate:   end,
+		GroupBy:   []string{storage.CohortDimCampaign},
+		Days:      []int{0},
+	})
+	if err != nil {
+		t.Fatalf("GetCohorts() error = %v", err)
+	}
+	if len(cohorts.Rows) != 1 {
+		t.Fatalf("GetCohorts() = %d rows, want 1", len(cohorts.Rows))
+	}
+	if got := cohorts.Rows[0].Cost; math.Abs(got-3) > 1e-9 {
+		t.Errorf("cohort cost = %v, want 3 (2 win spend and 1 payout)", got)
+	}
+}
@@ -0,0 +1,160 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// Repricer records the wins and conversions held by the tracking service
+// and postback handler once exchange rates for their event times are
+// known: it converts their amounts at the event time, saves them, charges
+// billing and sends the postbacks that were held with them.
+type Repricer struct {
+	repo      storage.UnpricedEventRepo
+	converter *currency.Converter
+	tracking  *TrackingService
+	postbacks *PostbackHandler
+	interval  time.Duration
+	logger    *zap.Logger
+}
+
+// NewRepricer creates a re-pricing job. tracking and postbacks must hold
+// their events in repo.
+func NewRepricer(
+	repo storage.UnpricedEventRepo,
+	converter *currency.Converter,
+	tracking *TrackingService,
+	postbacks *PostbackHandler,
+	interval time.Duration,
+	logger *zap.Logger,
+) *Repricer {
+	return &Repricer{
+		repo:      repo,
+		converter: converter,
+		tracking:  tracking,
+		postbacks: postbacks,
+		interval:  interval,
+		logger:    logger,
+	}
+}
+
+// Run re-prices held events every interval until ctx is cancelled.
+func (r *Repricer) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.Pass(ctx); err != nil && ctx.Err() == nil {
+			r.logger.Error("re-pricing pass failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Pass records every held event whose amounts can now be converted. Events
+// that still can't be are kept, with the failed attempt counted.
+func (r *Repricer) Pass(ctx context.Context) error {
+	held, err := r.repo.List(ctx, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list held events: %w", err)
+	}
+	for _, e := range held {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := r.reprice(ctx, e); err != nil {
+			e.Attempts++
+			e.LastError = err.Error()
+			if err := r.repo.Hold(ctx, e); err != nil {
+				r.logger.Error("failed to update held event", zap.String("id", e.ID), zap.Error(err))
+			}
+		}
+	}
+	return nil
+}
+
+// reprice converts and records one held event. The event is released
+// before it is recorded, so a pass running elsewhere can't record it too.
+func (r *Repricer) reprice(ctx context.Context, e *models.UnpricedEvent) error {
+	switch {
+	case e.Kind == models.UnpricedWin && e.Win != nil:
+		win := e.Win
+		priceUSD, err := r.toReporting(ctx, win.WinPrice, win.WinCurrency, win.Timestamp)
+		if err != nil {
+			return err
+		}
+		if released, err := r.repo.Release(ctx, e.ID); err != nil || !released {
+			return err
+		}
+		win.WinPriceUSD = priceUSD
+		win.NeedsConversion = false
+		r.tracking.recordWin(ctx, win)
+
+		r.logger.Info("held win recorded",
+			zap.String("win_id", win.ID),
+			zap.String("campaign_id", win.CampaignID),
+			zap.Float64("win_price_usd", priceUSD),
+			zap.Int("attempts", e.Attempts),
+		)
+		return nil
+
+	case e.Kind == models.UnpricedConversion && e.Conversion != nil:
+		conv := e.Conversion
+		revenueUSD, err := r.toReporting(ctx, conv.Revenue, conv.RevenueCurrency, conv.Timestamp)
+		if err != nil {
+			return err
+		}
+		payoutUSD, err := r.toReporting(ctx, conv.Payout, conv.PayoutCurrency, conv.Timestamp)
+		if err != nil {
+			return err
+		}
+		var click *models.Click
+		if conv.ClickID != "" {
+			if click, err = r.postbacks.eventStore.GetClick(ctx, conv.ClickID); err != nil {
+				return fmt.Errorf("failed to get click: %w", err)
+			}
+		}
+		if released, err := r.repo.Release(ctx, e.ID); err != nil || !released {
+			return err
+		}
+		conv.RevenueUSD = revenueUSD
+		conv.PayoutUSD = payoutUSD
+		conv.NeedsConversion = false
+		if err := r.postbacks.recordConversion(ctx, conv, click); err != nil {
+			// Held again by Pass; nothing was charged or sent
+			return fmt.Errorf("failed to save conversion: %w", err)
+		}
+
+		r.logger.Info("held conversion recorded",
+			zap.String("conversion_id", conv.ID),
+			zap.String("campaign_id", conv.CampaignID),
+			zap.Float64("revenue_usd", revenueUSD),
+			zap.Float64("payout_usd", payoutUSD),
+			zap.Int("attempts", e.Attempts),
+		)
+		return nil
+	}
+	return fmt.Errorf("held event has no %s", e.Kind)
+}
+
+// toReporting converts an amount to the reporting currency at the event
+// time.
+func (r *Repricer) toReporting(ctx context.Context, amount float64, code string, at time.Time) (float64, error) {
+	if amount == 0 || code == currency.ReportingCurrency {
+		return amount, nil
+	}
+	if r.converter == nil {
+		return 0, fmt.Errorf("no currency converter")
+	}
+	return r.converter.ToReporting(ctx, amount, code, at)
+}
@@ -0,0 +1,151 @@
+package dsp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// winRecorder is an EventStore that remembers saved wins.
+type winRecorder struct {
+	storage.EventStore
+
+	mu   sync.Mutex
+	wins []*models.Win
+}
+
+func (s *winRecorder) SaveWin(ctx context.Context, win *models.Win) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saved := *win
+	s.wins = append(s.wins, &saved)
+	return nil
+}
+
+func TestRepricerRecordsHeldEvents(t *testing.T) {
+	ctx := context.Background()
+
+	postbacks := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		postbacks <- r.URL.RawQuery
+	}))
+	defer srv.Close()
+
+	sources := storage.NewInMemorySourceRepo()
+	if err := sources.UpsertS2SSource(ctx, &models.S2SSource{
+		ID:           "src-1",
+		InternalName: "src-1",
+		PostbackURL:  srv.URL + "/pb?click={click_id}&payout={payout}",
+	}); err != nil {
+		t.Fatalf("UpsertS2SSource() error = %v", err)
+	}
+
+	events := &winRecorder{EventStore: storage.NewInMemoryEventStore()}
+	click := &models.Click{
+		ID:         "click-1",
+		Timestamp:  time.Now().Add(-time.Hour),
+		CampaignID: "cmp-1",
+		SourceType: "s2s",
+		SourceID:   "src-1",
+	}
+	if err := events.SaveClick(ctx, click); err != nil {
+		t.Fatalf("SaveClick() error = %v", err)
+	}
+
+	rules := storage.NewInMemoryPayoutRuleRepo()
+	if err := rules.Upsert(ctx, &models.PayoutRule{ID: "any", Type: models.PayoutTypeFixed, Amount: 200, Currency: "RUB", IsActive: true}); err != nil {
+		t.Fatalf("Upsert(rule) error = %v", err)
+	}
+	campaigns := storage.NewInMemoryCampaignRepo()
+
+	// No RUB rate until the test publishes one
+	converter := currency.NewConverter(currency.NewStaticRateProvider(map[string]float64{}), nil)
+	unpriced := storage.NewInMemoryUnpricedEventRepo()
+
+	h := NewPostbackHandler(events, sources, campaigns, storage.NewInMemoryAdvertiserRepo(),
+		NewPayoutEngine(rules, nil, campaigns, nil), converter, nil, zap.NewNop(), nil)
+	caps := &spendRecorder{spend: make(map[string]float64)}
+	h.SetSourceCaps(caps)
+	h.SetUnpricedEvents(unpriced)
+
+	tracking := NewTrackingService(events, campaigns, nil, converter, nil, "", zap.NewNop(), nil)
+	tracking.SetUnpricedEvents(unpriced)
+
+	repricer := NewRepricer(unpriced, converter, tracking, h, time.Minute, zap.NewNop())
+
+	res, err := h.processPostback(ctx, click.ID, "install", "install", 0, "USD", "", "", "", "")
+	if err != nil || !res.Success {
+		t.Fatalf("processPostback() = %+v, %v", res, err)
+	}
+	tracking.RegisterWin(ctx, "1", "cmp-1", "li-1", "cr-1", 150, "RUB")
+
+	// Held: nothing saved, counted or posted back
+	if convs, _ := events.GetConversionsByClick(ctx, click.ID); len(convs) != 0 {
+		t.Errorf("%d conversions saved before re-pricing, want 0", len(convs))
+	}
+	if len(events.wins) != 0 {
+		t.Errorf("%d wins saved before re-pricing, want 0", len(events.wins))
+	}
+
+	// Still no rate: the attempt is counted
+	if err := repricer.Pass(ctx); err != nil {
+		t.Fatalf("Pass() error = %v", err)
+	}
+	held, err := unpriced.List(ctx, 0)
+	if err != nil || len(held) != 2 {
+		t.Fatalf("List() = %d events, %v; want 2", len(held), err)
+	}
+	for _, e := range held {
+		if e.Attempts != 1 || e.LastError == "" {
+			t.Errorf("held %s: attempts = %d, last error = %q; want 1 and an error", e.Kind, e.Attempts, e.LastError)
+		}
+	}
+	caps.mu.Lock()
+	gotSpend := caps.spend["src-1"]
+	caps.mu.Unlock()
+	if gotSpend != 0 {
+		t.Errorf("source spend before re-pricing = %v, want 0", gotSpend)
+	}
+
+	// The rate is published: both are recorded at it
+	converter.SetRates(held[0].CreatedAt, map[string]float64{"RUB": 100})
+	if err := repricer.Pass(ctx); err != nil {
+		t.Fatalf("Pass() error = %v", err)
+	}
+	if held, _ := unpriced.List(ctx, 0); len(held) != 0 {
+		t.Errorf("%d events still held after re-pricing, want 0", len(held))
+	}
+
+	convs, err := events.GetConversionsByClick(ctx, click.ID)
+	if err != nil || len(convs) != 1 {
+		t.Fatalf("GetConversionsByClick() = %d conversions, %v", len(convs), err)
+	}
+	if conv := convs[0]; conv.PayoutUSD != 2 || conv.NeedsConversion {
+		t.Errorf("conversion payout = %v USD, needs conversion %v; want 2 USD, false", conv.PayoutUSD, conv.NeedsConversion)
+	}
+	if len(events.wins) != 1 || events.wins[0].WinPriceUSD != 1.5 || events.wins[0].NeedsConversion {
+		t.Errorf("wins = %+v, want one at 1.5 USD", events.wins)
+	}
+	caps.mu.Lock()
+	gotSpend = caps.spend["src-1"]
+	caps.mu.Unlock()
+	if gotSpend != 2 {
+		t.Errorf("source spend = %v, want 2", gotSpend)
+	}
+	select {
+	case query := <-postbacks:
+		if query != "click=click-1&payout=2.0000" {
+			t.Errorf("postback query = %q", query)
+		}
+	case <-time.After(500 * time.Millisecond):
+		t.Error("held postback not sent")
+	}
+}
@@ -0,0 +1,381 @@
+package dsp
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"github.com/radiusdt/vector-dsp/internal/targeting"
+)
+
+// ErrSourceCapped is returned when an S2S source has reached its daily
+// click or budget cap.
+var ErrSourceCapped = errors.New("source daily cap reached")
+
+// Offers per S2S ad request.
+const (
+	DefaultS2SOffers = 1
+	MaxS2SOffers     = 50
+)
+
+// S2SAdRequest is an ad request from an S2S partner.
+type S2SAdRequest struct {
+	// Device
+	IP             string
+	UserAgent      string
+	OS             string
+	OSV            string
+	DeviceType     int32 // OpenRTB device type
+	Make           string
+	Model          string
+	GAID           string
+	IDFA           string
+	Language       string
+	Carrier        string
+	ConnectionType int32
+
+	// Geo declared by the partner, used when the IP can't be located
+	Country string // ISO 3166-1 alpha-2
+	Region  string
+	City    string
+
+	// Placement
+	AppBundle string
+	Format    string // banner (default), video, native
+	W         int32
+	H         int32
+
+	// CampaignID optionally restricts offers to one campaign
+	CampaignID string
+
+	// Limit is the number of offers wanted (DefaultS2SOffers if zero)
+	Limit int
+
+	// Sub IDs passed through to click links
+	Sub1 string
+	Sub2 string
+	Sub3 string
+	Sub4 string
+	Sub5 string
+}
+
+// S2SCreative is the creative of an S2S offer.
+type S2SCreative struct {
+	ID   string `json:"id" xml:"id"`
+	Type string `json:"type" xml:"type"`
+	URL  string `json:"url" xml:"url"`
+	W    int32  `json:"w" xml:"w"`
+	H    int32  `json:"h" xml:"h"`
+}
+
+// S2SOffer is a ranked offer returned to an S2S partner.
+type S2SOffer struct {
+	CampaignID     string      `json:"campaign_id" xml:"campaign_id"`
+	LineItemID     string      `json:"line_item_id" xml:"line_item_id"`
+	AppBundle      string      `json:"app_bundle,omitempty" xml:"app_bundle,omitempty"`
+	AppName        string      `json:"app_name,omitempty" xml:"app_name,omitempty"`
+	Priority       int32       `json:"priority" xml:"priority"`
+	Creative       S2SCreative `json:"creative" xml:"creative"`
+	ClickURL       string      `json:"click_url" xml:"click_url"`
+	ViewURL        string      `json:"view_url" xml:"view_url"`
+	Payout         float64     `json:"payout" xml:"payout"`
+	PayoutCurrency string      `json:"payout_currency" xml:"payout_currency"`
+	PayoutEvent    string      `json:"payout_event" xml:"payout_event"`
+}
+
+// S2SFeed is the XML feed of offers.
+type S2SFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Offers  []*S2SOffer `xml:"offer"`
+}
+
+// S2SAdService selects offers for S2S partners using the same targeting,
+// pacing and priority rules as the RTB bidder, ranked by payout within a
+// priority.
+type S2SAdService struct {
+	sourceRepo      storage.SourceRepo
+	pacer           PacingEngine
+	targetingEngine *targeting.TargetingEngine
+	payoutEngine    *PayoutEngine
+	trackingService *TrackingService
+	caps            SourceCapTracker
+	metrics         *metrics.Metrics
+}
+
+// NewS2SAdService creates a new S2S ad service. caps may be nil.
+func NewS2SAdService(
+	sourceRepo storage.SourceRepo,
+	pacer PacingEngine,
+	targetingEngine *targeting.TargetingEngine,
+	payoutEngine *PayoutEngine,
+	trackingService *TrackingService,
+	caps SourceCapTracker,
+	m *metrics.Metrics,
+) *S2SAdService {
+	return &S2SAdService{
+		sourceRepo:      sourceRepo,
+		pacer:           pacer,
+		targetingEngine: targetingEngine,
+		payoutEngine:    payoutEngine,
+		trackingService: trackingService,
+		caps:            caps,
+		metrics:         m,
+	}
+}
+
+// s2sCandidate is a line item that passed targeting.
+type s2sCandidate struct {
+	campaign *models.Campaign
+	lineItem *models.LineItem
+	creative *models.Creative
+	quote    *PayoutQuote
+}
+
+// GetOffers returns up to req.Limit offers for the source, best first: higher
+// line item priority, then higher payout. At most one offer per campaign.
+func (s *S2SAdService) GetOffers(ctx context.Context, source *models.S2SSource, req *S2SAdRequest) ([]*S2SOffer, error) {
+	if source.Status != "" && source.Status != "active" {
+		return nil, nil
+	}
+	if err := s.checkCaps(ctx, source); err != nil {
+		return nil, err
+	}
+
+	campaigns, err := s.sourceRepo.GetCampaignsForSource(ctx, "s2s", source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaigns for source: %w", err)
+	}
+
+	br, imp := s.bidRequest(req)
+	country := ""
+	if br.Device.Geo != nil {
+		country = br.Device.Geo.Country
+	}
+
+	var candidates []s2sCandidate
+	for _, c := range campaigns {
+		if c.Status != models.CampaignStatusActive {
+			continue
+		}
+		if req.CampaignID != "" && c.ID != req.CampaignID {
+			continue
+		}
+
+		for i := range c.LineItems {
+			li := &c.LineItems[i]
+			if !li.IsActive {
+				continue
+			}
+
+			// Check targeting
+			if s.targetingEngine != nil {
+				result := s.targetingEngine.Match(br, imp, li)
+				if !result.Matched {
+					if s.metrics != nil {
+						s.metrics.RecordNoBid("s2s_targeting_" + result.FailedCriteria)
+					}
+					continue
+				}
+			}
+
+			// Select creative
+			cr := selectCreative(imp, li)
+			if cr == nil {
+				if s.metrics != nil {
+					s.metrics.RecordNoBid("s2s_" + string(NoBidReasonNoCreative))
+				}
+				continue
+			}
+
+			quote := &PayoutQuote{Currency: currency.ReportingCurrency}
+			if s.payoutEngine != nil {
+				q, err := s.payoutEngine.QuoteOffer(ctx, c, "s2s", source.ID, country, req.OS)
+				if err != nil {
+					return nil, fmt.Errorf("failed to quote payout: %w", err)
+				}
+				quote = q
+			}
+
+			candidates = append(candidates, s2sCandidate{campaign: c, lineItem: li, creative: cr, quote: quote})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.lineItem.Priority != b.lineItem.Priority {
+			return a.lineItem.Priority > b.lineItem.Priority
+		}
+		// Quotes are in each advertiser's currency; compare them in USD.
+		return a.quote.AmountUSD > b.quote.AmountUSD
+	})
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = DefaultS2SOffers
+	}
+	if limit > MaxS2SOffers {
+		limit = MaxS2SOffers
+	}
+
+	// Pacing is checked in rank order so frequency counters only move for
+	// offers actually served. S2S traffic is paid on conversion, so nothing
+	// is spent here; schedule, exhausted budgets and frequency caps apply.
+	userID := req.GAID
+	if userID == "" {
+		userID = req.IDFA
+	}
+	if userID == "" {
+		userID = "anonymous"
+	}
+
+	offers := make([]*S2SOffer, 0, limit)
+	seen := make(map[string]bool)
+	for _, cand := range candidates {
+		if len(offers) >= limit {
+			break
+		}
+		if seen[cand.campaign.ID] {
+			continue
+		}
+		if s.pacer != nil && !s.pacer.Allow(cand.lineItem.ID, userID, cand.lineItem.Pacing, 0) {
+			if s.metrics != nil {
+				s.metrics.RecordNoBid("s2s_" + string(NoBidReasonPacing))
+			}
+			continue
+		}
+		seen[cand.campaign.ID] = true
+		offers = append(offers, s.buildOffer(source, req, cand))
+	}
+
+	return offers, nil
+}
+
+// checkCaps returns ErrSourceCapped if the source is over its daily caps.
+// Counter errors fail open, like pacing.
+func (s *S2SAdService) checkCaps(ctx context.Context, source *models.S2SSource) error {
+	if s.caps == nil || (source.DailyClickCap <= 0 && source.DailyBudgetCap <= 0) {
+		return nil
+	}
+	usage, err := s.caps.Usage(ctx, source.ID)
+	if err != nil {
+		return nil
+	}
+	if source.DailyClickCap > 0 && usage.Clicks >= source.DailyClickCap {
+		return ErrSourceCapped
+	}
+	if source.DailyBudgetCap > 0 && usage.Spend >= source.DailyBudgetCap {
+		return ErrSourceCapped
+	}
+	return nil
+}
+
+// bidRequest expresses the S2S request as an OpenRTB request so the RTB
+// targeting engine can evaluate it.
+func (s *S2SAdService) bidRequest(req *S2SAdRequest) (*models.BidRequest, *models.Imp) {
+	ifa := req.GAID
+	if ifa == "" {
+		ifa = req.IDFA
+	}
+
+	// Resolve geo from the IP, falling back to what the partner declared
+	geo := &models.Geo{
+		Country: strings.ToUpper(req.Country),
+		Region:  req.Region,
+		City:    req.City,
+	}
+	if s.targetingEngine != nil && req.IP != "" {
+		if info := s.targetingEngine.GetGeoInfo(req.IP); info != nil && info.CountryCode != "" {
+			geo = &models.Geo{
+				Country: strings.ToUpper(info.CountryCode),
+				Region:  info.Region,
+				City:    info.City,
+			}
+		}
+	}
+
+	imp := &models.Imp{ID: "1"}
+	switch strings.ToLower(req.Format) {
+	case "video":
+		imp.Video = &models.Video{}
+	case "native":
+		imp.Native = &models.Native{}
+	default:
+		if req.W > 0 && req.H > 0 {
+			imp.Banner = &models.Banner{W: req.W, H: req.H}
+		}
+	}
+
+	br := &models.BidRequest{
+		ID:  "s2s",
+		Imp: []models.Imp{*imp},
+		Device: &models.Device{
+			Ua:             req.UserAgent,
+			IP:             req.IP,
+			OS:             req.OS,
+			OSV:            req.OSV,
+			DeviceType:     req.DeviceType,
+			Make:           req.Make,
+			Model:          req.Model,
+			Ifa:            ifa,
+			Geo:            geo,
+			ConnectionType: req.ConnectionType,
+			Carrier:        req.Carrier,
+			Language:       req.Language,
+		},
+	}
+	if req.AppBundle != "" {
+		br.App = &models.App{Bundle: req.AppBundle}
+	}
+
+	return br, imp
+}
+
+func (s *S2SAdService) buildOffer(source *models.S2SSource, req *S2SAdRequest, cand s2sCandidate) *S2SOffer {
+	c, li, cr := cand.campaign, cand.lineItem, cand.creative
+
+	payoutEvent := c.PayoutEvent
+	if payoutEvent == "" {
+		payoutEvent = "install"
+	}
+
+	offer := &S2SOffer{
+		CampaignID: c.ID,
+		LineItemID: li.ID,
+		AppBundle:  c.AppBundle,
+		AppName:    c.AppName,
+		Priority:   li.Priority,
+		Creative: S2SCreative{
+			ID:   cr.ID,
+			Type: cr.Format,
+			URL:  cr.AdmTemplate,
+			W:    cr.W,
+			H:    cr.H,
+		},
+		Payout:         cand.quote.Amount,
+		PayoutCurrency: cand.quote.Currency,
+		PayoutEvent:    payoutEvent,
+	}
+
+	if s.trackingService != nil {
+		offer.ClickURL = s.trackingService.BuildOurClickURL(
+			c.ID, cr.ID, li.ID,
+			"s2s", source.ID,
+			"", req.GAID, req.IDFA,
+			req.Sub1, req.Sub2, req.Sub3, req.Sub4, req.Sub5,
+		)
+		offer.ViewURL = s.trackingService.BuildOurViewURL(
+			c.ID, cr.ID, li.ID,
+			"s2s", source.ID,
+			"", req.GAID, req.IDFA,
+		)
+	}
+
+	return offer
+}
@@ -0,0 +1,133 @@
+package dsp
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// SourceUsage is the daily delivery of a traffic source, checked against
+// S2SSource.DailyClickCap and DailyBudgetCap.
+type SourceUsage struct {
+	Date   string  `json:"date"`
+	Clicks int64   `json:"clicks"`
+	Spend  float64 `json:"spend"` // Payouts in the reporting currency
+}
+
+// SourceCapTracker counts clicks and payouts per source per day (UTC).
+type SourceCapTracker interface {
+	RecordClick(ctx context.Context, sourceID string) error
+	RecordSpend(ctx context.Context, sourceID string, amount float64) error
+	Usage(ctx context.Context, sourceID string) (*SourceUsage, error)
+}
+
+// =============================================
+// Redis Implementation
+// =============================================
+
+// RedisSourceCapTracker implements SourceCapTracker using Redis counters.
+type RedisSourceCapTracker struct {
+	client *redis.Client
+}
+
+// NewRedisSourceCapTracker creates a new Redis-backed source cap tracker.
+func NewRedisSourceCapTracker(client *redis.Client) *RedisSourceCapTracker {
+	return &RedisSourceCapTracker{client: client}
+}
+
+// RecordClick implements SourceCapTracker.
+func (t *RedisSourceCapTracker) RecordClick(ctx context.Context, sourceID string) error {
+	key := fmt.Sprintf("source:clicks:%s:%s", sourceID, dateKey())
+	pipe := t.client.TxPipeline()
+	pipe.Incr(ctx, key)
+	pipe.Expire(ctx, key, 48*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RecordSpend implements SourceCapTracker.
+func (t *RedisSourceCapTracker) RecordSpend(ctx context.Context, sourceID string, amount float64) error {
+	if amount == 0 {
+		return nil
+	}
+	key := fmt.Sprintf("source:spend:%s:%s", sourceID, dateKey())
+	pipe := t.client.TxPipeline()
+	pipe.IncrByFloat(ctx, key, amount)
+	pipe.Expire(ctx, key, 48*time.Hour)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// Usage implements SourceCapTracker.
+func (t *RedisSourceCapTracker) Usage(ctx context.Context, sourceID string) (*SourceUsage, error) {
+	d := dateKey()
+	usage := &SourceUsage{Date: d}
+
+	clicks, err := t.client.Get(ctx, fmt.Sprintf("source:clicks:%s:%s", sourceID, d)).Int64()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get source clicks: %w", err)
+	}
+	usage.Clicks = clicks
+
+	spend, err := t.client.Get(ctx, fmt.Sprintf("source:spend:%s:%s", sourceID, d)).Float64()
+	if err != nil && err != redis.Nil {
+		return nil, fmt.Errorf("failed to get source spend: %w", err)
+	}
+	usage.Spend = spend
+
+	return usage, nil
+}
+
+// =============================================
+// In-Memory Implementation
+// =============================================
+
+// InMemorySourceCapTracker implements SourceCapTracker in process memory.
+type InMemorySourceCapTracker struct {
+	mu    sync.Mutex
+	usage map[string]*SourceUsage // sourceID -> today's usage
+}
+
+// NewInMemorySourceCapTracker creates a new in-memory source cap tracker.
+func NewInMemorySourceCapTracker() *InMemorySourceCapTracker {
+	return &InMemorySourceCapTracker{
+		usage: make(map[string]*SourceUsage),
+	}
+}
+
+// RecordClick implements SourceCapTracker.
+func (t *InMemorySourceCapTracker) RecordClick(ctx context.Context, sourceID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.today(sourceID).Clicks++
+	return nil
+}
+
+// RecordSpend implements SourceCapTracker.
+func (t *InMemorySourceCapTracker) RecordSpend(ctx context.Context, sourceID string, amount float64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.today(sourceID).Spend += amount
+	return nil
+}
+
+// Usage implements SourceCapTracker.
+func (t *InMemorySourceCapTracker) Usage(ctx context.Context, sourceID string) (*SourceUsage, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	u := *t.today(sourceID)
+	return &u, nil
+}
+
+func (t *InMemorySourceCapTracker) today(sourceID string) *SourceUsage {
+	d := dateKey()
+	u, ok := t.usage[sourceID]
+	if !ok || u.Date != d {
+		u = &SourceUsage{Date: d}
+		t.usage[sourceID] = u
+	}
+	return u
+}
@@ -3,7 +3,6 @@ package dsp
 import (
 	"context"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -183,97 +182,3 @@ func (s *SourceService) LinkCampaignSource(ctx context.Context, link *models.Cam
 func (s *SourceService) GetCampaignSources(ctx context.Context, campaignID string) ([]*models.CampaignSource, error) {
 	return s.repo.GetCampaignSources(ctx, campaignID)
 }
-
-// FindCampaignForSource finds a matching campaign for an S2S source request.
-func (s *SourceService) FindCampaignForSource(
-	ctx context.Context,
-	sourceID string,
-	country string,
-	os string,
-) (*models.Campaign, *models.Creative, error) {
-	// Get campaigns linked to this source
-	campaigns, err := s.repo.GetCampaignsForSource(ctx, "s2s", sourceID)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	// Find first matching campaign
-	for _, campaign := range campaigns {
-		if !matchesTargeting(campaign, country, os) {
-			continue
-		}
-
-		// Get creative for campaign
-		creative := getFirstCreative(campaign)
-		if creative == nil {
-			continue
-		}
-
-		return campaign, creative, nil
-	}
-
-	return nil, nil, fmt.Errorf("no matching campaign found")
-}
-
-// =============================================
-// Helper Functions
-// =============================================
-
-func matchesTargeting(campaign *models.Campaign, country, os string) bool {
-	// Check if campaign has any line items
-	if len(campaign.LineItems) == 0 {
-		return false
-	}
-
-	// Check status
-	if campaign.Status != "active" {
-		return false
-	}
-
-	// Get first line item's targeting (simplified)
-	li := campaign.LineItems[0]
-	if li.Status != "active" {
-		return false
-	}
-
-	// Check geo targeting
-	if len(li.Targeting.Countries) > 0 {
-		found := false
-		for _, c := range li.Targeting.Countries {
-			if c == country {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-
-	// Check OS targeting
-	if len(li.Targeting.OS) > 0 {
-		found := false
-		for _, o := range li.Targeting.OS {
-			if o == os {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-
-	return true
-}
-
-func getFirstCreative(campaign *models.Campaign) *models.Creative {
-	if len(campaign.LineItems) == 0 {
-		return nil
-	}
-	li := campaign.LineItems[0]
-	if len(li.Creatives) == 0 {
-		return nil
-	}
-	return &li.Creatives[0]
-}
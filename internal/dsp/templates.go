@@ -0,0 +1,360 @@
+package dsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+var (
+	// ErrDuplicateID is returned when a copy would get the ID of an
+	// existing object.
+	ErrDuplicateID = errors.New("an object with this id already exists")
+
+	// ErrDraftConflict is returned when publishing a draft of a campaign
+	// that changed since the draft was started.
+	ErrDraftConflict = errors.New("the campaign changed since the draft was started")
+)
+
+// TemplateService clones campaigns, line items and creatives, keeps
+// campaign templates and stages campaign drafts.
+//
+// Copies get new IDs. Creatives inside copied line items keep theirs: they
+// are copies of library creatives, and exchanges audit creatives by ID.
+// Copies that could bid are staged: cloned campaigns and campaigns created
+// from templates are drafts, and cloned line items are inactive.
+type TemplateService struct {
+	templates storage.CampaignTemplateRepo
+	drafts    storage.CampaignDraftRepo
+	campaigns *CampaignService
+	creatives *CreativeService
+}
+
+// NewTemplateService creates a new template service.
+func NewTemplateService(templates storage.CampaignTemplateRepo, drafts storage.CampaignDraftRepo, campaigns *CampaignService, creatives *CreativeService) *TemplateService {
+	return &TemplateService{templates: templates, drafts: drafts, campaigns: campaigns, creatives: creatives}
+}
+
+// =============================================
+// Clones
+// =============================================
+
+// CloneCampaign saves a draft copy of c with new campaign and line item
+// IDs and req's overrides.
+func (s *TemplateService) CloneCampaign(ctx context.Context, c *models.Campaign, req *models.CloneRequest) (*models.Campaign, error) {
+	clone, err := deepCopyCampaign(c)
+	if err != nil {
+		return nil, err
+	}
+	clone.Name = copyName(c.Name, req.Name)
+	return s.createCampaign(ctx, clone, req)
+}
+
+// CloneLineItem adds an inactive copy of li with a new ID and req's
+// overrides to campaign to, and returns to as saved. The copy is its last
+// line item.
+func (s *TemplateService) CloneLineItem(ctx context.Context, li *models.LineItem, to *models.Campaign, req *models.CloneRequest) (*models.Campaign, error) {
+	var clone models.LineItem
+	if err := deepCopy(li, &clone); err != nil {
+		return nil, err
+	}
+	clone.ID = req.ID
+	if clone.ID == "" {
+		clone.ID = uuid.New().String()
+	}
+	for i := range to.LineItems {
+		if to.LineItems[i].ID == clone.ID {
+			return nil, fmt.Errorf("%w: line item %s", ErrDuplicateID, clone.ID)
+		}
+	}
+	clone.Name = copyName(li.Name, req.Name)
+	clone.IsActive = false
+	applyLineItemOverrides(&clone, req)
+	if req.DailyBudget != nil {
+		clone.Pacing.DailyBudget = *req.DailyBudget
+	}
+	if req.TotalBudget != nil {
+		clone.Pacing.TotalBudget = *req.TotalBudget
+	}
+
+	updated := *to
+	updated.LineItems = append(append([]models.LineItem{}, to.LineItems...), clone)
+	StampLineItems(&updated, to)
+	if err := updated.LineItems[len(updated.LineItems)-1].Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.campaigns.UpsertCampaign(ctx, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+// CloneCreative saves a copy of creative cr with a new ID and req's name
+// and click URL. The copy's audit status is cleared, as exchanges audit
+// it anew.
+func (s *TemplateService) CloneCreative(ctx context.Context, cr *models.Creative, req *models.CloneRequest) (*models.Creative, error) {
+	var clone models.Creative
+	if err := deepCopy(cr, &clone); err != nil {
+		return nil, err
+	}
+	clone.ID = req.ID
+	if clone.ID == "" {
+		clone.ID = uuid.New().String()
+	}
+	existing, err := s.creatives.GetCreative(ctx, clone.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get creative: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: creative %s", ErrDuplicateID, clone.ID)
+	}
+	clone.Name = copyName(cr.Name, req.Name)
+	if req.ClickURL != "" {
+		clone.ClickURL = req.ClickURL
+	}
+	clone.AuditStatus = ""
+	clone.CreatedAt = time.Time{}
+	if err := s.creatives.UpsertCreative(ctx, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// createCampaign gives c, a copy of a campaign or template, new IDs and
+// the overrides of req, and saves it as a draft.
+func (s *TemplateService) createCampaign(ctx context.Context, c *models.Campaign, req *models.CloneRequest) (*models.Campaign, error) {
+	c.ID = req.ID
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+	existing, err := s.campaigns.GetCampaign(ctx, c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("%w: campaign %s", ErrDuplicateID, c.ID)
+	}
+
+	c.Status = models.CampaignStatusDraft
+	c.CreatedAt = time.Time{}
+	if req.DailyBudget != nil {
+		c.DailyBudget = *req.DailyBudget
+	}
+	if req.TotalBudget != nil {
+		c.TotalBudget = *req.TotalBudget
+	}
+	if req.StartDate != nil {
+		c.StartDate = *req.StartDate
+	}
+	if req.EndDate != nil {
+		c.EndDate = *req.EndDate
+	}
+	for i := range c.LineItems {
+		c.LineItems[i].ID = uuid.New().String()
+		applyLineItemOverrides(&c.LineItems[i], req)
+	}
+	StampLineItems(c, nil)
+	if err := s.campaigns.UpsertCampaign(ctx, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// applyLineItemOverrides applies the targeting overrides of req to a
+// copied line item.
+func applyLineItemOverrides(li *models.LineItem, req *models.CloneRequest) {
+	if len(req.Countries) > 0 {
+		li.Targeting.Countries = make([]string, len(req.Countries))
+		for i, cc := range req.Countries {
+			li.Targeting.Countries[i] = strings.ToUpper(strings.TrimSpace(cc))
+		}
+	}
+}
+
+// copyName returns the name of a copy: name if set, otherwise the
+// original's marked as a copy.
+func copyName(original, name string) string {
+	if name != "" {
+		return name
+	}
+	return original + " (copy)"
+}
+
+// deepCopyCampaign returns a deep copy of c, so changing the copy's line
+// items and targeting leaves c alone.
+func deepCopyCampaign(c *models.Campaign) (*models.Campaign, error) {
+	var clone models.Campaign
+	if err := deepCopy(c, &clone); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// deepCopy copies src into dst through JSON, which covers every exported
+// field of the models.
+func deepCopy(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	if err := json.Unmarshal(b, dst); err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
+	return nil
+}
+
+// =============================================
+// Templates
+// =============================================
+
+// ListTemplates lists campaign templates, all when advertiserID is empty.
+func (s *TemplateService) ListTemplates(ctx context.Context, advertiserID string) ([]*models.CampaignTemplate, error) {
+	return s.templates.List(ctx, advertiserID)
+}
+
+// GetTemplate returns a template, or nil if it doesn't exist.
+func (s *TemplateService) GetTemplate(ctx context.Context, id string) (*models.CampaignTemplate, error) {
+	return s.templates.GetByID(ctx, id)
+}
+
+// SaveTemplate validates and saves a template. The blueprint loses the
+// fields campaigns created from it get anew.
+func (s *TemplateService) SaveTemplate(ctx context.Context, t *models.CampaignTemplate) error {
+	c := &t.Campaign
+	c.ID = ""
+	c.AdvertiserID = t.AdvertiserID
+	c.Status = ""
+	c.CreatedAt = time.Time{}
+	c.UpdatedAt = time.Time{}
+	for i := range c.LineItems {
+		li := &c.LineItems[i]
+		li.CampaignID = ""
+		li.CreatedAt = time.Time{}
+		li.UpdatedAt = time.Time{}
+	}
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	return s.templates.Upsert(ctx, t)
+}
+
+// DeleteTemplate deletes a template. Campaigns created from it are kept.
+func (s *TemplateService) DeleteTemplate(ctx context.Context, id string) error {
+	return s.templates.Delete(ctx, id)
+}
+
+// SetTemplateCampaign makes a copy of campaign c the blueprint of t.
+func (s *TemplateService) SetTemplateCampaign(t *models.CampaignTemplate, c *models.Campaign) error {
+	clone, err := deepCopyCampaign(c)
+	if err != nil {
+		return err
+	}
+	t.Campaign = *clone
+	t.SourceCampaignID = c.ID
+	if t.AdvertiserID == "" {
+		t.AdvertiserID = c.AdvertiserID
+	}
+	return nil
+}
+
+// CreateFromTemplate saves a draft campaign copying t with new IDs and
+// req's overrides. It is named after the template unless req names it.
+func (s *TemplateService) CreateFromTemplate(ctx context.Context, t *models.CampaignTemplate, req *models.CloneRequest) (*models.Campaign, error) {
+	c, err := deepCopyCampaign(&t.Campaign)
+	if err != nil {
+		return nil, err
+	}
+	c.AdvertiserID = t.AdvertiserID
+	switch {
+	case req.Name != "":
+		c.Name = req.Name
+	case c.Name == "":
+		c.Name = t.Name
+	}
+	return s.createCampaign(ctx, c, req)
+}
+
+// =============================================
+// Drafts
+// =============================================
+
+// ListDrafts lists campaign drafts, all when advertiserID is empty.
+func (s *TemplateService) ListDrafts(ctx context.Context, advertiserID string) ([]*models.CampaignDraft, error) {
+	return s.drafts.List(ctx, advertiserID)
+}
+
+// GetDraft returns the draft of a campaign, or nil if it has none.
+func (s *TemplateService) GetDraft(ctx context.Context, campaignID string) (*models.CampaignDraft, error) {
+	return s.drafts.Get(ctx, campaignID)
+}
+
+// SaveDraft validates staged, a version of the live campaign, and saves it
+// as the campaign's draft. A new draft starts from the live version; an
+// existing one keeps the version it started from.
+func (s *TemplateService) SaveDraft(ctx context.Context, actor *models.Principal, live, staged *models.Campaign) (*models.CampaignDraft, error) {
+	existing, err := s.drafts.Get(ctx, live.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign draft: %w", err)
+	}
+
+	staged.ID = live.ID
+	staged.CreatedAt = live.CreatedAt
+	StampLineItems(staged, live)
+	if err := staged.Validate(); err != nil {
+		return nil, err
+	}
+
+	d := &models.CampaignDraft{
+		CampaignID:    live.ID,
+		AdvertiserID:  staged.AdvertiserID,
+		Campaign:      *staged,
+		BaseUpdatedAt: live.UpdatedAt,
+	}
+	if existing != nil {
+		d.BaseUpdatedAt = existing.BaseUpdatedAt
+		d.CreatedAt = existing.CreatedAt
+	}
+	if actor != nil {
+		d.ActorID = actor.ID
+		d.ActorName = actor.Name
+	}
+	if err := s.drafts.Upsert(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// DiscardDraft deletes the draft of a campaign.
+func (s *TemplateService) DiscardDraft(ctx context.Context, campaignID string) error {
+	return s.drafts.Delete(ctx, campaignID)
+}
+
+// PublishDraft saves draft d as the live version of campaign live, and
+// deletes it. It returns ErrDraftConflict if the campaign changed since
+// the draft was started, unless force is set.
+func (s *TemplateService) PublishDraft(ctx context.Context, live *models.Campaign, d *models.CampaignDraft, force bool) (*models.Campaign, error) {
+	if !force && live.UpdatedAt.UnixMicro() != d.BaseUpdatedAt.UnixMicro() {
+		return nil, ErrDraftConflict
+	}
+	published, err := deepCopyCampaign(&d.Campaign)
+	if err != nil {
+		return nil, err
+	}
+	published.ID = live.ID
+	published.CreatedAt = live.CreatedAt
+	StampLineItems(published, live)
+	if err := s.campaigns.UpsertCampaign(ctx, published); err != nil {
+		return nil, err
+	}
+	if err := s.drafts.Delete(ctx, live.ID); err != nil {
+		return nil, fmt.Errorf("published, but failed to delete the draft: %w", err)
+	}
+	return published, nil
+}
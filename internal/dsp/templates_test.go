@@ -0,0 +1,377 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// templateFixture is a template service over in-memory repos holding the
+// active campaign cmp-1 of adv-1, with line items li-1 and li-2 targeting
+// DE, and the approved creative cr-1.
+type templateFixture struct {
+	templates *TemplateService
+	campaigns *CampaignService
+	creatives *CreativeService
+}
+
+func newTemplateFixture(t *testing.T) *templateFixture {
+	t.Helper()
+	ctx := context.Background()
+	f := &templateFixture{
+		campaigns: NewCampaignService(storage.NewInMemoryCampaignRepo()),
+		creatives: NewCreativeService(storage.NewInMemoryCreativeRepo()),
+	}
+	f.templates = NewTemplateService(storage.NewInMemoryCampaignTemplateRepo(), storage.NewInMemoryCampaignDraftRepo(), f.campaigns, f.creatives)
+
+	lineItem := func(id string) models.LineItem {
+		return models.LineItem{
+			ID: id, Name: "Line " + id, IsActive: true,
+			BidStrategy: models.BidStrategy{Type: models.BidStrategyFixedCPM, FixedCPM: 2},
+			Pacing:      models.PacingConfig{DailyBudget: 10},
+			Targeting:   models.Targeting{Countries: []string{"DE"}},
+			Creatives:   []models.Creative{{ID: "cr-1"}},
+		}
+	}
+	c := &models.Campaign{
+		ID: "cmp-1", Name: "One", AdvertiserID: "adv-1", Status: models.CampaignStatusActive, DailyBudget: 100,
+		LineItems: []models.LineItem{lineItem("li-1"), lineItem("li-2")},
+	}
+	StampLineItems(c, nil)
+	if err := f.campaigns.UpsertCampaign(ctx, c); err != nil {
+		t.Fatalf("UpsertCampaign() error = %v", err)
+	}
+	if err := f.creatives.UpsertCreative(ctx, &models.Creative{
+		ID: "cr-1", Name: "Banner", AdvertiserID: "adv-1", ClickURL: "https://example.com", AuditStatus: "approved",
+	}); err != nil {
+		t.Fatalf("UpsertCreative() error = %v", err)
+	}
+	return f
+}
+
+// campaign returns the saved campaign id.
+func (f *templateFixture) campaign(t *testing.T, id string) *models.Campaign {
+	t.Helper()
+	c, err := f.campaigns.GetCampaign(context.Background(), id)
+	if err != nil || c == nil {
+		t.Fatalf("GetCampaign(%s) = %v, %v", id, c, err)
+	}
+	return c
+}
+
+func float64Ptr(v float64) *float64 { return &v }
+
+func TestCloneCampaign(t *testing.T) {
+	tests := []struct {
+		name          string
+		req           models.CloneRequest
+		wantErr       error
+		wantName      string
+		wantCountries string
+		wantBudget    float64
+	}{
+		{"defaults", models.CloneRequest{}, nil, "One (copy)", "DE", 100},
+		{"overrides", models.CloneRequest{
+			ID: "cmp-2", Name: "One BR", Countries: []string{"br", " us "}, DailyBudget: float64Ptr(50),
+		}, nil, "One BR", "BR,US", 50},
+		{"existing id", models.CloneRequest{ID: "cmp-1"}, ErrDuplicateID, "", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTemplateFixture(t)
+			original := f.campaign(t, "cmp-1")
+			clone, err := f.templates.CloneCampaign(context.Background(), original, &tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CloneCampaign() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			saved := f.campaign(t, clone.ID)
+			if saved.ID == "cmp-1" || (tt.req.ID != "" && saved.ID != tt.req.ID) {
+				t.Errorf("clone ID = %q, want a new ID", saved.ID)
+			}
+			if saved.Name != tt.wantName || saved.DailyBudget != tt.wantBudget {
+				t.Errorf("clone = %q with budget %v, want %q with %v", saved.Name, saved.DailyBudget, tt.wantName, tt.wantBudget)
+			}
+			// Nothing bids before review
+			if saved.Status != models.CampaignStatusDraft {
+				t.Errorf("clone status = %q, want %q", saved.Status, models.CampaignStatusDraft)
+			}
+			if len(saved.LineItems) != 2 {
+				t.Fatalf("clone has %d line items, want 2", len(saved.LineItems))
+			}
+			for _, li := range saved.LineItems {
+				if li.ID == "li-1" || li.ID == "li-2" || li.CampaignID != saved.ID {
+					t.Errorf("cloned line item %s of campaign %s, want a new ID in %s", li.ID, li.CampaignID, saved.ID)
+				}
+				if got := strings.Join(li.Targeting.Countries, ","); got != tt.wantCountries {
+					t.Errorf("cloned line item countries = %s, want %s", got, tt.wantCountries)
+				}
+				// Exchanges audit creatives by ID
+				if li.Creatives[0].ID != "cr-1" {
+					t.Errorf("cloned line item creative = %s, want cr-1", li.Creatives[0].ID)
+				}
+			}
+
+			after := f.campaign(t, "cmp-1")
+			if after.Status != models.CampaignStatusActive || after.LineItems[0].ID != "li-1" || strings.Join(after.LineItems[0].Targeting.Countries, ",") != "DE" {
+				t.Errorf("original changed: %+v", after)
+			}
+		})
+	}
+}
+
+func TestCloneLineItem(t *testing.T) {
+	tests := []struct {
+		name       string
+		req        models.CloneRequest
+		wantErr    error
+		wantName   string
+		wantBudget float64
+	}{
+		{"defaults", models.CloneRequest{}, nil, "Line li-1 (copy)", 10},
+		{"overrides", models.CloneRequest{ID: "li-3", Name: "Line BR", Countries: []string{"br"}, DailyBudget: float64Ptr(25)}, nil, "Line BR", 25},
+		{"existing id", models.CloneRequest{ID: "li-2"}, ErrDuplicateID, "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTemplateFixture(t)
+			c := f.campaign(t, "cmp-1")
+			_, err := f.templates.CloneLineItem(context.Background(), &c.LineItems[0], c, &tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CloneLineItem() error = %v, want %v", err, tt.wantErr)
+			}
+
+			saved := f.campaign(t, "cmp-1")
+			if err != nil {
+				if len(saved.LineItems) != 2 {
+					t.Errorf("campaign has %d line items after a failed clone, want 2", len(saved.LineItems))
+				}
+				return
+			}
+			if len(saved.LineItems) != 3 {
+				t.Fatalf("campaign has %d line items, want 3", len(saved.LineItems))
+			}
+			clone := saved.LineItems[2]
+			if clone.ID == "li-1" || (tt.req.ID != "" && clone.ID != tt.req.ID) {
+				t.Errorf("clone ID = %q, want a new ID", clone.ID)
+			}
+			if clone.IsActive {
+				t.Error("clone is active, want inactive")
+			}
+			if clone.Name != tt.wantName || clone.Pacing.DailyBudget != tt.wantBudget {
+				t.Errorf("clone = %q with budget %v, want %q with %v", clone.Name, clone.Pacing.DailyBudget, tt.wantName, tt.wantBudget)
+			}
+			if len(tt.req.Countries) > 0 && strings.Join(clone.Targeting.Countries, ",") != "BR" {
+				t.Errorf("clone countries = %v, want [BR]", clone.Targeting.Countries)
+			}
+			if !saved.LineItems[0].IsActive || strings.Join(saved.LineItems[0].Targeting.Countries, ",") != "DE" {
+				t.Errorf("original line item changed: %+v", saved.LineItems[0])
+			}
+		})
+	}
+}
+
+func TestCloneCreative(t *testing.T) {
+	ctx := context.Background()
+	f := newTemplateFixture(t)
+	original, err := f.creatives.GetCreative(ctx, "cr-1")
+	if err != nil || original == nil {
+		t.Fatalf("GetCreative() = %v, %v", original, err)
+	}
+
+	clone, err := f.templates.CloneCreative(ctx, original, &models.CloneRequest{ID: "cr-2", ClickURL: "https://example.com/br"})
+	if err != nil {
+		t.Fatalf("CloneCreative() error = %v", err)
+	}
+	saved, err := f.creatives.GetCreative(ctx, clone.ID)
+	if err != nil || saved == nil {
+		t.Fatalf("GetCreative() = %v, %v", saved, err)
+	}
+	// Exchanges audit the copy anew
+	if saved.Name != "Banner (copy)" || saved.ClickURL != "https://example.com/br" || saved.AuditStatus != "" {
+		t.Errorf("clone = %q, %q, audit %q; want a renamed, unaudited copy with the new click URL", saved.Name, saved.ClickURL, saved.AuditStatus)
+	}
+
+	if _, err := f.templates.CloneCreative(ctx, original, &models.CloneRequest{ID: "cr-2"}); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("CloneCreative(existing id) error = %v, want %v", err, ErrDuplicateID)
+	}
+}
+
+func TestTemplates(t *testing.T) {
+	ctx := context.Background()
+	f := newTemplateFixture(t)
+
+	tmpl := &models.CampaignTemplate{ID: "tmpl-1", Name: "Geo launch"}
+	if err := f.templates.SetTemplateCampaign(tmpl, f.campaign(t, "cmp-1")); err != nil {
+		t.Fatalf("SetTemplateCampaign() error = %v", err)
+	}
+	if err := f.templates.SaveTemplate(ctx, tmpl); err != nil {
+		t.Fatalf("SaveTemplate() error = %v", err)
+	}
+	saved, err := f.templates.GetTemplate(ctx, "tmpl-1")
+	if err != nil || saved == nil {
+		t.Fatalf("GetTemplate() = %v, %v", saved, err)
+	}
+	if saved.AdvertiserID != "adv-1" || saved.SourceCampaignID != "cmp-1" {
+		t.Errorf("template advertiser %q, source %q; want adv-1, cmp-1", saved.AdvertiserID, saved.SourceCampaignID)
+	}
+	// The blueprint loses what its campaigns get anew
+	if saved.Campaign.ID != "" || saved.Campaign.Status != "" || saved.Campaign.LineItems[0].CampaignID != "" {
+		t.Errorf("blueprint kept campaign %q, status %q", saved.Campaign.ID, saved.Campaign.Status)
+	}
+
+	for _, tt := range []struct {
+		advertiserID string
+		want         int
+	}{{"", 1}, {"adv-1", 1}, {"adv-2", 0}} {
+		list, err := f.templates.ListTemplates(ctx, tt.advertiserID)
+		if err != nil || len(list) != tt.want {
+			t.Errorf("ListTemplates(%q) = %d, %v; want %d", tt.advertiserID, len(list), err, tt.want)
+		}
+	}
+
+	c, err := f.templates.CreateFromTemplate(ctx, saved, &models.CloneRequest{Countries: []string{"br"}})
+	if err != nil {
+		t.Fatalf("CreateFromTemplate() error = %v", err)
+	}
+	created := f.campaign(t, c.ID)
+	if created.ID == "" || created.ID == "cmp-1" || created.Name != "One" || created.AdvertiserID != "adv-1" ||
+		created.Status != models.CampaignStatusDraft {
+		t.Errorf("created %q %q of %q, status %q; want a new draft named One of adv-1", created.ID, created.Name, created.AdvertiserID, created.Status)
+	}
+	if len(created.LineItems) != 2 || created.LineItems[0].ID == "li-1" || strings.Join(created.LineItems[0].Targeting.Countries, ",") != "BR" {
+		t.Errorf("created line items = %+v, want 2 new ones targeting BR", created.LineItems)
+	}
+
+	invalid := &models.CampaignTemplate{ID: "tmpl-2", AdvertiserID: "adv-1"}
+	var verrs models.ValidationErrors
+	if err := f.templates.SaveTemplate(ctx, invalid); !errors.As(err, &verrs) {
+		t.Errorf("SaveTemplate(no name) error = %v, want validation errors", err)
+	}
+
+	if err := f.templates.DeleteTemplate(ctx, "tmpl-1"); err != nil {
+		t.Fatalf("DeleteTemplate() error = %v", err)
+	}
+	if got, _ := f.templates.GetTemplate(ctx, "tmpl-1"); got != nil {
+		t.Error("GetTemplate() found a deleted template")
+	}
+	f.campaign(t, c.ID) // Campaigns created from it are kept
+}
+
+func TestDrafts(t *testing.T) {
+	ctx := context.Background()
+	actor := &models.Principal{ID: "user-1", Name: "Trader"}
+
+	t.Run("staged until published", func(t *testing.T) {
+		f := newTemplateFixture(t)
+		live := f.campaign(t, "cmp-1")
+		staged := f.campaign(t, "cmp-1")
+		staged.DailyBudget = 200
+		d, err := f.templates.SaveDraft(ctx, actor, live, staged)
+		if err != nil {
+			t.Fatalf("SaveDraft() error = %v", err)
+		}
+		if d.ActorID != "user-1" || !d.BaseUpdatedAt.Equal(live.UpdatedAt) {
+			t.Errorf("draft actor %q, base %v; want user-1, %v", d.ActorID, d.BaseUpdatedAt, live.UpdatedAt)
+		}
+		if got := f.campaign(t, "cmp-1").DailyBudget; got != 100 {
+			t.Errorf("live budget = %v before publishing, want 100", got)
+		}
+
+		// Editing again keeps the version the draft started from
+		staged.DailyBudget = 300
+		d, err = f.templates.SaveDraft(ctx, actor, live, staged)
+		if err != nil {
+			t.Fatalf("SaveDraft() error = %v", err)
+		}
+		if !d.BaseUpdatedAt.Equal(live.UpdatedAt) {
+			t.Errorf("draft base = %v, want %v", d.BaseUpdatedAt, live.UpdatedAt)
+		}
+
+		published, err := f.templates.PublishDraft(ctx, live, d, false)
+		if err != nil {
+			t.Fatalf("PublishDraft() error = %v", err)
+		}
+		if got := f.campaign(t, "cmp-1"); got.DailyBudget != 300 || !got.CreatedAt.Equal(live.CreatedAt) || published.ID != "cmp-1" {
+			t.Errorf("live campaign = budget %v, created %v; want 300, %v", got.DailyBudget, got.CreatedAt, live.CreatedAt)
+		}
+		if got, _ := f.templates.GetDraft(ctx, "cmp-1"); got != nil {
+			t.Error("the draft is kept after publishing")
+		}
+	})
+
+	t.Run("validated", func(t *testing.T) {
+		f := newTemplateFixture(t)
+		live := f.campaign(t, "cmp-1")
+		staged := f.campaign(t, "cmp-1")
+		staged.LineItems[0].Creatives = nil
+		var verrs models.ValidationErrors
+		if _, err := f.templates.SaveDraft(ctx, actor, live, staged); !errors.As(err, &verrs) {
+			t.Errorf("SaveDraft(invalid) error = %v, want validation errors", err)
+		}
+		if got, _ := f.templates.GetDraft(ctx, "cmp-1"); got != nil {
+			t.Error("an invalid draft was saved")
+		}
+	})
+
+	t.Run("stale", func(t *testing.T) {
+		f := newTemplateFixture(t)
+		live := f.campaign(t, "cmp-1")
+		staged := f.campaign(t, "cmp-1")
+		staged.DailyBudget = 200
+		d, err := f.templates.SaveDraft(ctx, actor, live, staged)
+		if err != nil {
+			t.Fatalf("SaveDraft() error = %v", err)
+		}
+
+		// The live campaign changes after the draft started
+		changed := f.campaign(t, "cmp-1")
+		changed.Name = "One renamed"
+		changed.UpdatedAt = time.Time{}
+		time.Sleep(time.Millisecond)
+		if err := f.campaigns.UpsertCampaign(ctx, changed); err != nil {
+			t.Fatalf("UpsertCampaign() error = %v", err)
+		}
+		live = f.campaign(t, "cmp-1")
+
+		if _, err := f.templates.PublishDraft(ctx, live, d, false); !errors.Is(err, ErrDraftConflict) {
+			t.Fatalf("PublishDraft() error = %v, want %v", err, ErrDraftConflict)
+		}
+		if got := f.campaign(t, "cmp-1"); got.DailyBudget != 100 {
+			t.Errorf("live budget = %v after a conflict, want 100", got.DailyBudget)
+		}
+		if _, err := f.templates.PublishDraft(ctx, live, d, true); err != nil {
+			t.Fatalf("PublishDraft(force) error = %v", err)
+		}
+		if got := f.campaign(t, "cmp-1"); got.DailyBudget != 200 {
+			t.Errorf("live budget = %v after a forced publish, want 200", got.DailyBudget)
+		}
+	})
+
+	t.Run("discarded", func(t *testing.T) {
+		f := newTemplateFixture(t)
+		live := f.campaign(t, "cmp-1")
+		if _, err := f.templates.SaveDraft(ctx, actor, live, f.campaign(t, "cmp-1")); err != nil {
+			t.Fatalf("SaveDraft() error = %v", err)
+		}
+		if list, err := f.templates.ListDrafts(ctx, "adv-1"); err != nil || len(list) != 1 {
+			t.Errorf("ListDrafts() = %d, %v; want 1", len(list), err)
+		}
+		if err := f.templates.DiscardDraft(ctx, "cmp-1"); err != nil {
+			t.Fatalf("DiscardDraft() error = %v", err)
+		}
+		if got, _ := f.templates.GetDraft(ctx, "cmp-1"); got != nil {
+			t.Error("GetDraft() found a discarded draft")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package dsp
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// rfcSecret is the RFC 6238 SHA-1 test key "12345678901234567890" in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		got, err := totpCode(rfcSecret, tt.unix/totpPeriod)
+		if err != nil {
+			t.Fatalf("totpCode() error = %v", err)
+		}
+		if got != tt.want {
+			t.Errorf("totpCode(T=%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// Secrets are accepted in lower case
+	if got, _ := totpCode("gezdgnbvgy3tqojqgezdgnbvgy3tqojq", 1); got != "287082" {
+		t.Errorf("totpCode() of a lower case secret = %s, want 287082", got)
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	step := now.Unix() / totpPeriod
+	code := func(step int64) string {
+		c, err := totpCode(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("totpCode() error = %v", err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		lastStep int64
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current step", code(step), 0, step, true},
+		{"previous step", code(step - 1), 0, step - 1, true},
+		{"next step", code(step + 1), 0, step + 1, true},
+		{"two steps behind", code(step - 2), 0, 0, false},
+		{"two steps ahead", code(step + 2), 0, 0, false},
+		{"surrounding spaces", " " + code(step) + "\n", 0, step, true},
+		{"too short", code(step)[:5], 0, 0, false},
+		{"too long", code(step) + "0", 0, 0, false},
+		{"empty", "", 0, 0, false},
+		// A used step can't be used again, nor an earlier one
+		{"replayed step", code(step), step, 0, false},
+		{"step before the last used", code(step - 1), step, 0, false},
+		{"step after the last used", code(step + 1), step, step + 1, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := verifyTOTP(rfcSecret, tt.code, tt.lastStep, now)
+			if ok != tt.wantOK || got != tt.wantStep {
+				t.Errorf("verifyTOTP() = %d, %v; want %d, %v", got, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+
+	if _, ok := verifyTOTP("not base32!", "123456", 0, now); ok {
+		t.Error("verifyTOTP() accepted a code for a malformed secret")
+	}
+}
+
+func TestUserServiceTOTPLogin(t *testing.T) {
+	ctx := context.Background()
+	users := NewUserService(storage.NewInMemoryUserRepo(), nil, config.AuthConfig{
+		JWTSecret: "secret", AccessTokenTTL: time.Minute, RefreshTokenTTL: time.Hour, TOTPIssuer: "Vector DSP",
+	}, zap.NewNop(), nil)
+
+	u, err := users.Create(ctx, &models.UserAccount{Email: "trader@example.com", Role: models.RoleTrader}, "long enough password")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	secret, _, err := users.SetupTOTP(ctx, u.ID)
+	if err != nil {
+		t.Fatalf("SetupTOTP() error = %v", err)
+	}
+	step := time.Now().Unix() / totpPeriod
+	code := func(step int64) string {
+		c, err := totpCode(secret, step)
+		if err != nil {
+			t.Fatalf("totpCode() error = %v", err)
+		}
+		return c
+	}
+	login := func(totp string) (*TokenPair, error) {
+		return users.Login(ctx, "trader@example.com", "long enough password", totp, "test", "127.0.0.1")
+	}
+
+	if err := users.EnableTOTP(ctx, u.ID, code(step)); err != nil {
+		t.Fatalf("EnableTOTP() error = %v", err)
+	}
+
+	if _, err := login(""); !errors.Is(err, ErrTOTPRequired) {
+		t.Errorf("Login() without a code error = %v, want %v", err, ErrTOTPRequired)
+	}
+	// The code that enabled 2FA has been used
+	if _, err := login(code(step)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with the enabling code error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	tokens, err := login(code(step + 1))
+	if err != nil {
+		t.Fatalf("Login() with the next code error = %v", err)
+	}
+	if _, err := login(code(step + 1)); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with a replayed code error = %v, want %v", err, ErrInvalidCredentials)
+	}
+	if _, err := users.Login(ctx, "trader@example.com", "wrong password", code(step+1), "test", "127.0.0.1"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Login() with a wrong password error = %v, want %v", err, ErrInvalidCredentials)
+	}
+
+	p, err := users.VerifyAccessToken(ctx, tokens.AccessToken)
+	if err != nil || p == nil || p.ID != u.ID {
+		t.Errorf("VerifyAccessToken() = %+v, %v; want user %s", p, err, u.ID)
+	}
+	other := NewUserService(storage.NewInMemoryUserRepo(), nil, config.AuthConfig{JWTSecret: "other"}, zap.NewNop(), nil)
+	if p, err := other.VerifyAccessToken(ctx, tokens.AccessToken); err != nil || p != nil {
+		t.Errorf("VerifyAccessToken() with another secret = %+v, %v; want nil", p, err)
+	}
+}
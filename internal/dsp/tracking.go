@@ -9,6 +9,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/fraud"
 	"github.com/radiusdt/vector-dsp/internal/metrics"
 	"github.com/radiusdt/vector-dsp/internal/models"
 	"github.com/radiusdt/vector-dsp/internal/storage"
@@ -18,13 +20,24 @@ import (
 
 // TrackingService handles click and view tracking.
 type TrackingService struct {
-	eventStore       storage.EventStore
-	campaignRepo     storage.CampaignRepo
-	targetingEngine  *targeting.TargetingEngine
-	baseURL          string
-	logger           *zap.Logger
-	metrics          *metrics.Metrics
-	httpClient       *http.Client
+	eventStore      storage.EventStore
+	campaignRepo    storage.CampaignRepo
+	targetingEngine *targeting.TargetingEngine
+	converter       *currency.Converter
+	fraudScorer     *fraud.Scorer
+	baseURL         string
+	logger          *zap.Logger
+	metrics         *metrics.Metrics
+	httpClient      *http.Client
+
+	// Optional click protection
+	linkSigner  *LinkSigner
+	deduper     ClickDeduper
+	dedupWindow time.Duration
+	sourceCaps  SourceCapTracker
+	live        *LiveFeed
+	billing     *BillingService
+	unpriced    storage.UnpricedEventRepo
 }
 
 // NewTrackingService creates a new tracking service.
@@ -32,6 +45,8 @@ func NewTrackingService(
 	eventStore storage.EventStore,
 	campaignRepo storage.CampaignRepo,
 	targetingEngine *targeting.TargetingEngine,
+	converter *currency.Converter,
+	fraudScorer *fraud.Scorer,
 	baseURL string,
 	logger *zap.Logger,
 	m *metrics.Metrics,
@@ -40,6 +55,8 @@ func NewTrackingService(
 		eventStore:      eventStore,
 		campaignRepo:    campaignRepo,
 		targetingEngine: targetingEngine,
+		converter:       converter,
+		fraudScorer:     fraudScorer,
 		baseURL:         baseURL,
 		logger:          logger,
 		metrics:         m,
@@ -52,6 +69,48 @@ func NewTrackingService(
 	}
 }
 
+// SetLinkSigner enables signing of generated click links and verification
+// of incoming ones.
+func (s *TrackingService) SetLinkSigner(signer *LinkSigner) {
+	s.linkSigner = signer
+}
+
+// SetClickDeduper enables click deduplication within window.
+func (s *TrackingService) SetClickDeduper(deduper ClickDeduper, window time.Duration) {
+	s.deduper = deduper
+	s.dedupWindow = window
+}
+
+// SetSourceCaps enables counting of S2S clicks against source daily caps.
+func (s *TrackingService) SetSourceCaps(caps SourceCapTracker) {
+	s.sourceCaps = caps
+}
+
+// SetLiveFeed enables publishing wins and clicks to the live feed.
+func (s *TrackingService) SetLiveFeed(feed *LiveFeed) {
+	s.live = feed
+}
+
+// SetBilling enables debiting win spend from advertiser balances.
+func (s *TrackingService) SetBilling(billing *BillingService) {
+	s.billing = billing
+}
+
+// SetUnpricedEvents holds wins without an exchange rate in repo until a
+// Repricer records them, instead of saving them with no USD price.
+func (s *TrackingService) SetUnpricedEvents(repo storage.UnpricedEventRepo) {
+	s.unpriced = repo
+}
+
+// VerifyClickLink rejects tampered or expired click links. It is a no-op
+// when link signing is not configured.
+func (s *TrackingService) VerifyClickLink(params url.Values) error {
+	if s.linkSigner == nil {
+		return nil
+	}
+	return s.linkSigner.Verify(params, time.Now())
+}
+
 // RegisterClick handles click tracking and returns MMP redirect URL.
 func (s *TrackingService) RegisterClick(
 	ctx context.Context,
@@ -76,7 +135,7 @@ func (s *TrackingService) RegisterClick(
 	if s.targetingEngine != nil {
 		geoInfo := s.targetingEngine.GetGeoInfo(ip)
 		if geoInfo != nil {
-			geoCountry = geoInfo.Country
+			geoCountry = geoInfo.CountryCode
 			geoRegion = geoInfo.Region
 			geoCity = geoInfo.City
 		}
@@ -91,10 +150,56 @@ func (s *TrackingService) RegisterClick(
 		deviceIFA = idfa
 	}
 
+	// Score for fraud before dedup, so repeat clicks still feed the click
+	// spamming counters; a scoring failure must not lose the click
+	now := time.Now()
+	var fraudRes *fraud.Result
+	if s.fraudScorer != nil {
+		res, err := s.fraudScorer.ScoreClick(ctx, fraud.ClickSignals{
+			SourceType: sourceType,
+			SourceID:   sourceID,
+			DeviceIFA:  deviceIFA,
+			GAID:       gaid,
+			IDFA:       idfa,
+			IP:         ip,
+			UserAgent:  userAgent,
+			At:         now,
+		})
+		if err != nil {
+			s.logger.Warn("failed to score click", zap.Error(err))
+		} else {
+			fraudRes = res
+			if s.metrics != nil {
+				for _, reason := range res.Reasons {
+					s.metrics.RecordFraudFlag("click", reason)
+				}
+			}
+		}
+	}
+
+	// Repeat clicks within the dedup window reuse the original click and
+	// are not counted again
+	if s.deduper != nil && s.dedupWindow > 0 {
+		key := ClickDedupKey(campaignID, creativeID, deviceIFA, ip, userAgent)
+		existingID, err := s.deduper.Claim(ctx, key, clickID, s.dedupWindow)
+		if err != nil {
+			s.logger.Warn("click dedup failed", zap.Error(err))
+		} else if existingID != "" {
+			original, err := s.eventStore.GetClick(ctx, existingID)
+			if err == nil && original != nil {
+				s.logger.Debug("duplicate click",
+					zap.String("click_id", existingID),
+					zap.String("campaign_id", campaignID),
+				)
+				return s.clickRedirectURL(campaign, original, gaid, idfa), nil
+			}
+		}
+	}
+
 	// Create click record
 	click := &models.Click{
 		ID:          clickID,
-		Timestamp:   time.Now(),
+		Timestamp:   now,
 		CampaignID:  campaignID,
 		LineItemID:  lineItemID,
 		CreativeID:  creativeID,
@@ -116,6 +221,11 @@ func (s *TrackingService) RegisterClick(
 		Sub4:        sub4,
 		Sub5:        sub5,
 	}
+	if fraudRes != nil {
+		click.FraudScore = fraudRes.Score
+		click.FraudReasons = fraudRes.Reasons
+		click.FraudFlagged = fraudRes.Flagged
+	}
 
 	// Save click
 	if err := s.eventStore.SaveClick(ctx, click); err != nil {
@@ -123,27 +233,46 @@ func (s *TrackingService) RegisterClick(
 		return "", fmt.Errorf("failed to save click: %w", err)
 	}
 
+	if s.sourceCaps != nil && sourceType == "s2s" && sourceID != "" {
+		if err := s.sourceCaps.RecordClick(ctx, sourceID); err != nil {
+			s.logger.Warn("failed to count source click", zap.String("source_id", sourceID), zap.Error(err))
+		}
+	}
+	if s.metrics != nil {
+		s.metrics.RecordClick(campaignID, lineItemID)
+	}
+	if s.live != nil {
+		s.live.RecordClick(campaignID)
+	}
+
 	s.logger.Info("click registered",
 		zap.String("click_id", clickID),
 		zap.String("campaign_id", campaignID),
 		zap.String("source_id", sourceID),
 		zap.String("device_ifa", deviceIFA),
 		zap.String("geo_country", geoCountry),
+		zap.Int("fraud_score", click.FraudScore),
 	)
 
+	return s.clickRedirectURL(campaign, click, gaid, idfa), nil
+}
+
+// clickRedirectURL returns the MMP click URL with macros replaced, or the
+// app store URL if the campaign has no MMP.
+func (s *TrackingService) clickRedirectURL(campaign *models.Campaign, click *models.Click, gaid, idfa string) string {
 	// Build MMP Click URL with macro replacements
 	if campaign != nil && campaign.MMP.ClickURL != "" {
 		redirectURL := s.buildMMPClickURL(campaign, click, gaid, idfa)
 		click.TargetURL = redirectURL
-		return redirectURL, nil
+		return redirectURL
 	}
 
 	// No MMP URL - return app store URL
 	if campaign != nil && campaign.AppStoreURL != "" {
-		return campaign.AppStoreURL, nil
+		return campaign.AppStoreURL
 	}
 
-	return "", nil
+	return ""
 }
 
 // RegisterView handles view/impression tracking.
@@ -163,7 +292,7 @@ func (s *TrackingService) RegisterView(
 	if s.targetingEngine != nil {
 		geoInfo := s.targetingEngine.GetGeoInfo(ip)
 		if geoInfo != nil {
-			geoCountry = geoInfo.Country
+			geoCountry = geoInfo.CountryCode
 		}
 	}
 
@@ -215,16 +344,86 @@ func (s *TrackingService) RegisterView(
 func (s *TrackingService) RegisterWin(
 	ctx context.Context,
 	impID, campaignID, lineItemID, creativeID string,
-	winPrice float64,
+	winPrice float64, priceCurrency string,
 ) {
-	// This could be stored in a separate wins table for analysis
+	now := time.Now()
+	priceCurrency = currency.Normalize(priceCurrency)
+
+	// Spend is stored in the reporting currency at event time. Without a
+	// rate the win is held for re-pricing, or saved unconverted and flagged
+	// if there is nowhere to hold it.
+	winPriceUSD := winPrice
+	needsConversion := false
+	if winPrice != 0 && priceCurrency != currency.ReportingCurrency {
+		winPriceUSD, needsConversion = 0, true
+		if s.converter != nil {
+			converted, err := s.converter.ToReporting(ctx, winPrice, priceCurrency, now)
+			if err == nil {
+				winPriceUSD, needsConversion = converted, false
+			} else {
+				s.logger.Warn("failed to convert win price, win needs re-pricing",
+					zap.String("currency", priceCurrency),
+					zap.Float64("win_price", winPrice),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	win := &models.Win{
+		ID:          uuid.New().String(),
+		Timestamp:   now,
+		ImpID:       impID,
+		CampaignID:  campaignID,
+		LineItemID:  lineItemID,
+		CreativeID:  creativeID,
+		WinPrice:    winPrice,
+		WinCurrency: priceCurrency,
+		WinPriceUSD: winPriceUSD,
+
+		NeedsConversion: needsConversion,
+	}
+	if needsConversion && s.unpriced != nil {
+		err := s.unpriced.Hold(ctx, &models.UnpricedEvent{
+			ID:        win.ID,
+			Kind:      models.UnpricedWin,
+			Win:       win,
+			CreatedAt: now,
+		})
+		if err == nil {
+			s.logger.Info("win held until its price can be converted",
+				zap.String("win_id", win.ID),
+				zap.String("campaign_id", campaignID),
+				zap.String("currency", priceCurrency),
+			)
+			return
+		}
+		s.logger.Error("failed to hold win, saving it unconverted", zap.Error(err))
+	}
+	s.recordWin(ctx, win)
+
 	s.logger.Info("win registered",
 		zap.String("imp_id", impID),
 		zap.String("campaign_id", campaignID),
 		zap.Float64("win_price", winPrice),
+		zap.Float64("win_price_usd", winPriceUSD),
 	)
 }
 
+// recordWin saves a win and counts its spend in the live feed and
+// advertiser billing.
+func (s *TrackingService) recordWin(ctx context.Context, win *models.Win) {
+	if err := s.eventStore.SaveWin(ctx, win); err != nil {
+		s.logger.Error("failed to save win", zap.Error(err))
+	}
+	if s.live != nil {
+		s.live.RecordWin(win.CampaignID, win.WinPriceUSD)
+	}
+	if s.billing != nil {
+		s.billing.RecordWin(ctx, win.CampaignID, win.WinPriceUSD)
+	}
+}
+
 // buildMMPClickURL replaces macros in MMP Click URL.
 func (s *TrackingService) buildMMPClickURL(campaign *models.Campaign, click *models.Click, gaid, idfa string) string {
 	if campaign.MMP.ClickURL == "" {
@@ -377,6 +576,9 @@ func (s *TrackingService) BuildOurClickURL(
 	if sub5 != "" {
 		params.Set("sub5", sub5)
 	}
+	if s.linkSigner != nil {
+		s.linkSigner.Sign(params, time.Now())
+	}
 
 	return fmt.Sprintf("%s/track/click?%s", s.baseURL, params.Encode())
 }
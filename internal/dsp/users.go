@@ -0,0 +1,581 @@
+package dsp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	// ErrInvalidUser wraps user validation errors.
+	ErrInvalidUser = errors.New("invalid user request")
+
+	// ErrUserNotFound is returned for unknown user IDs.
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrUserExists is returned when the email of a new or changed user is
+	// taken.
+	ErrUserExists = errors.New("user with this email already exists")
+
+	// ErrInvalidCredentials is returned for wrong emails, passwords and TOTP
+	// codes, and for disabled users. The cases aren't told apart.
+	ErrInvalidCredentials = errors.New("invalid email, password or code")
+
+	// ErrTOTPRequired is returned by Login when the user has 2FA enabled and
+	// no code was given.
+	ErrTOTPRequired = errors.New("totp code required")
+
+	// ErrLoginDisabled is returned when no JWT secret is configured.
+	ErrLoginDisabled = errors.New("user login is not configured")
+)
+
+// minPasswordLength is the shortest accepted password.
+const minPasswordLength = 10
+
+// TokenPair is returned by Login and Refresh. The refresh token is single
+// use: every refresh returns a new one.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"` // Seconds until the access token expires
+}
+
+// UserUpdate changes a user; nil fields are left unchanged.
+type UserUpdate struct {
+	Email        *string `json:"email,omitempty"`
+	Name         *string `json:"name,omitempty"`
+	Role         *string `json:"role,omitempty"`
+	AdvertiserID *string `json:"advertiser_id,omitempty"`
+	Status       *string `json:"status,omitempty"`
+	Password     *string `json:"password,omitempty"`
+	ResetTOTP    bool    `json:"reset_totp,omitempty"` // Turns off 2FA for a user who lost their device
+}
+
+// cachedUser is a user loaded to check an access token.
+type cachedUser struct {
+	user     *models.UserAccount
+	loadedAt time.Time
+}
+
+// UserService manages management UI users and their sessions. Access
+// tokens are HS256 JWTs valid for AccessTokenTTL; refresh tokens are random
+// strings stored hashed in user_sessions and rotated on every use. Reusing
+// a rotated refresh token revokes all sessions of the user, since it means
+// the token was copied.
+//
+// Users checked for access tokens are cached for KeyCacheTTL, so disabling
+// a user or changing their role takes effect at once on this instance and
+// within the TTL on others.
+type UserService struct {
+	repo        storage.UserRepo
+	advertisers *AdvertiserService
+	cfg         config.AuthConfig
+	logger      *zap.Logger
+	metrics     *metrics.Metrics
+
+	secret    []byte
+	dummyHash []byte // Compared for unknown emails so timing doesn't reveal them
+
+	mu    sync.Mutex
+	users map[string]*cachedUser
+}
+
+// NewUserService creates a new user service. Login is disabled if
+// cfg.JWTSecret is empty.
+func NewUserService(repo storage.UserRepo, advertisers *AdvertiserService, cfg config.AuthConfig, logger *zap.Logger, m *metrics.Metrics) *UserService {
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+	return &UserService{
+		repo:        repo,
+		advertisers: advertisers,
+		cfg:         cfg,
+		logger:      logger,
+		metrics:     m,
+		secret:      []byte(cfg.JWTSecret),
+		dummyHash:   dummyHash,
+		users:       make(map[string]*cachedUser),
+	}
+}
+
+// Enabled reports whether users can log in.
+func (s *UserService) Enabled() bool {
+	return len(s.secret) > 0
+}
+
+func (s *UserService) record(result string) {
+	if s.metrics != nil {
+		s.metrics.RecordUserLogin(result)
+	}
+}
+
+// =============================================
+// Sessions
+// =============================================
+
+// Login checks the credentials of a user and starts a session. Users with
+// 2FA enabled need totpCode too; without it ErrTOTPRequired is returned so
+// the UI can ask for it.
+func (s *UserService) Login(ctx context.Context, email, password, totpCode, userAgent, ip string) (*TokenPair, error) {
+	if !s.Enabled() {
+		return nil, ErrLoginDisabled
+	}
+
+	u, err := s.repo.GetUserByEmail(ctx, strings.TrimSpace(email))
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.Status != models.UserActive {
+		bcrypt.CompareHashAndPassword(s.dummyHash, []byte(password))
+		s.record("invalid")
+		return nil, ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) != nil {
+		s.record("invalid")
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if u.TOTPEnabled {
+		if totpCode == "" {
+			s.record("totp_required")
+			return nil, ErrTOTPRequired
+		}
+		step, ok := verifyTOTP(u.TOTPSecret, totpCode, u.TOTPLastStep, now)
+		if !ok {
+			s.record("invalid")
+			return nil, ErrInvalidCredentials
+		}
+		u.TOTPLastStep = step
+	}
+
+	u.LastLoginAt = &now
+	if err := s.repo.UpdateUser(ctx, u); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.issue(ctx, u, userAgent, ip, now)
+	if err != nil {
+		return nil, err
+	}
+	s.record("ok")
+	s.logger.Info("user logged in", zap.String("user_id", u.ID), zap.String("ip", ip))
+	return tokens, nil
+}
+
+// Refresh exchanges a refresh token for new tokens. The old refresh token
+// stops working.
+func (s *UserService) Refresh(ctx context.Context, refreshToken, userAgent, ip string) (*TokenPair, error) {
+	if !s.Enabled() {
+		return nil, ErrLoginDisabled
+	}
+
+	sess, err := s.repo.GetSessionByHash(ctx, HashAPIKey(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if sess == nil {
+		return nil, ErrInvalidToken
+	}
+
+	now := time.Now()
+	if sess.RevokedAt != nil {
+		s.revokeReused(ctx, sess, now)
+		return nil, ErrInvalidToken
+	}
+	if !sess.Active(now) {
+		return nil, ErrInvalidToken
+	}
+
+	u, err := s.repo.GetUser(ctx, sess.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.Status != models.UserActive {
+		return nil, ErrInvalidToken
+	}
+
+	revoked, err := s.repo.RevokeSession(ctx, sess.ID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// A concurrent refresh won the race with the same token
+		s.revokeReused(ctx, sess, now)
+		return nil, ErrInvalidToken
+	}
+
+	tokens, err := s.issue(ctx, u, userAgent, ip, now)
+	if err != nil {
+		return nil, err
+	}
+	s.record("refreshed")
+	return tokens, nil
+}
+
+func (s *UserService) revokeReused(ctx context.Context, sess *models.UserSession, now time.Time) {
+	s.record("refresh_reused")
+	s.logger.Warn("revoked refresh token reused, revoking all sessions",
+		zap.String("user_id", sess.UserID),
+		zap.String("session_id", sess.ID))
+	if err := s.repo.RevokeUserSessions(ctx, sess.UserID, now); err != nil {
+		s.logger.Error("failed to revoke user sessions", zap.String("user_id", sess.UserID), zap.Error(err))
+	}
+}
+
+// Logout ends the session of a refresh token. Unknown tokens are ignored.
+func (s *UserService) Logout(ctx context.Context, refreshToken string) error {
+	sess, err := s.repo.GetSessionByHash(ctx, HashAPIKey(refreshToken))
+	if err != nil || sess == nil {
+		return err
+	}
+	_, err = s.repo.RevokeSession(ctx, sess.ID, time.Now())
+	return err
+}
+
+// issue signs an access token for u and stores a new refresh token.
+func (s *UserService) issue(ctx context.Context, u *models.UserAccount, userAgent, ip string, now time.Time) (*TokenPair, error) {
+	access, err := signJWT(s.secret, &AccessClaims{
+		Subject:      u.ID,
+		Role:         u.Role,
+		AdvertiserID: u.AdvertiserID,
+		Issuer:       jwtIssuer,
+		IssuedAt:     now.Unix(),
+		ExpiresAt:    now.Add(s.cfg.AccessTokenTTL).Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign access token: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refresh := hex.EncodeToString(b)
+
+	sess := &models.UserSession{
+		ID:        uuid.New().String(),
+		UserID:    u.ID,
+		TokenHash: HashAPIKey(refresh),
+		UserAgent: userAgent,
+		IP:        ip,
+		ExpiresAt: now.Add(s.cfg.RefreshTokenTTL),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateSession(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.cfg.AccessTokenTTL / time.Second),
+	}, nil
+}
+
+// VerifyAccessToken returns the principal of an access token, or nil if
+// the token is invalid or expired. The user is reloaded (through the cache)
+// so disabled users are locked out and role changes apply before the token
+// expires.
+func (s *UserService) VerifyAccessToken(ctx context.Context, token string) (*models.Principal, error) {
+	if !s.Enabled() {
+		return nil, nil
+	}
+	claims, err := parseJWT(s.secret, token, time.Now())
+	if err != nil {
+		return nil, nil
+	}
+	u, err := s.cachedUser(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil || u.Status != models.UserActive {
+		return nil, nil
+	}
+	return models.NewUserPrincipal(u), nil
+}
+
+func (s *UserService) cachedUser(ctx context.Context, id string) (*models.UserAccount, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	c, ok := s.users[id]
+	s.mu.Unlock()
+	if ok && now.Sub(c.loadedAt) < s.cfg.KeyCacheTTL {
+		return c.user, nil
+	}
+
+	u, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if u == nil {
+		// Tokens only name stored users; don't let forged IDs fill the cache
+		delete(s.users, id)
+	} else {
+		s.users[id] = &cachedUser{user: u, loadedAt: now}
+	}
+	s.mu.Unlock()
+	return u, nil
+}
+
+func (s *UserService) invalidate(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.users, id)
+}
+
+// =============================================
+// Management
+// =============================================
+
+// List returns all users.
+func (s *UserService) List(ctx context.Context) ([]*models.UserAccount, error) {
+	return s.repo.ListUsers(ctx)
+}
+
+// Get returns a user by ID, or nil if it does not exist.
+func (s *UserService) Get(ctx context.Context, id string) (*models.UserAccount, error) {
+	return s.repo.GetUser(ctx, id)
+}
+
+// Create stores a new user with password.
+func (s *UserService) Create(ctx context.Context, u *models.UserAccount, password string) (*models.UserAccount, error) {
+	now := time.Now()
+	user := *u
+	user.ID = uuid.New().String()
+	user.Email = strings.TrimSpace(user.Email)
+	if user.Status == "" {
+		user.Status = models.UserActive
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabled = false
+	user.TOTPLastStep = 0
+	user.LastLoginAt = nil
+	user.CreatedAt = now
+	user.UpdatedAt = now
+
+	if err := s.validate(ctx, &user); err != nil {
+		return nil, err
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = hash
+
+	if err := s.repo.CreateUser(ctx, &user); err != nil {
+		if errors.Is(err, storage.ErrDuplicateUserEmail) {
+			return nil, fmt.Errorf("%w: %s", ErrUserExists, user.Email)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("user created",
+		zap.String("user_id", user.ID),
+		zap.String("role", user.Role),
+		zap.String("advertiser_id", user.AdvertiserID))
+	return &user, nil
+}
+
+// Update changes a user. Setting a password or disabling the user ends all
+// of their sessions.
+func (s *UserService) Update(ctx context.Context, id string, upd *UserUpdate) (*models.UserAccount, error) {
+	u, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+
+	endSessions := false
+	if upd.Email != nil {
+		u.Email = strings.TrimSpace(*upd.Email)
+	}
+	if upd.Name != nil {
+		u.Name = *upd.Name
+	}
+	if upd.Role != nil {
+		u.Role = *upd.Role
+	}
+	if upd.AdvertiserID != nil {
+		u.AdvertiserID = *upd.AdvertiserID
+	}
+	if upd.Status != nil {
+		endSessions = *upd.Status == models.UserDisabled && u.Status != models.UserDisabled
+		u.Status = *upd.Status
+	}
+	if err := s.validate(ctx, u); err != nil {
+		return nil, err
+	}
+	if upd.Password != nil {
+		hash, err := hashPassword(*upd.Password)
+		if err != nil {
+			return nil, err
+		}
+		u.PasswordHash = hash
+		endSessions = true
+	}
+	if upd.ResetTOTP {
+		u.TOTPSecret = ""
+		u.TOTPEnabled = false
+	}
+
+	if err := s.save(ctx, u, endSessions); err != nil {
+		return nil, err
+	}
+	s.logger.Info("user updated", zap.String("user_id", u.ID), zap.Bool("sessions_ended", endSessions))
+	return u, nil
+}
+
+// ChangePassword sets a new password for a user who knows the current one
+// and ends all of their sessions.
+func (s *UserService) ChangePassword(ctx context.Context, id, current, password string) error {
+	u, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(current)) != nil {
+		return ErrInvalidCredentials
+	}
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = hash
+	return s.save(ctx, u, true)
+}
+
+// SetupTOTP generates a new 2FA secret for a user and returns it with its
+// otpauth:// URL. 2FA is only required after EnableTOTP confirms a code.
+func (s *UserService) SetupTOTP(ctx context.Context, id string) (string, string, error) {
+	u, err := s.getUser(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	if u.TOTPEnabled {
+		return "", "", fmt.Errorf("%w: 2FA is already enabled", ErrInvalidUser)
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	u.TOTPSecret = secret
+	u.TOTPLastStep = 0
+	if err := s.save(ctx, u, false); err != nil {
+		return "", "", err
+	}
+	return secret, totpURL(s.cfg.TOTPIssuer, u.Email, secret), nil
+}
+
+// EnableTOTP turns on 2FA once the user proves their app has the secret.
+func (s *UserService) EnableTOTP(ctx context.Context, id, code string) error {
+	u, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if u.TOTPSecret == "" {
+		return fmt.Errorf("%w: call totp setup first", ErrInvalidUser)
+	}
+	step, ok := verifyTOTP(u.TOTPSecret, code, u.TOTPLastStep, time.Now())
+	if !ok {
+		return ErrInvalidCredentials
+	}
+	u.TOTPEnabled = true
+	u.TOTPLastStep = step
+	return s.save(ctx, u, false)
+}
+
+// DisableTOTP turns off 2FA; it needs a current code.
+func (s *UserService) DisableTOTP(ctx context.Context, id, code string) error {
+	u, err := s.getUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !u.TOTPEnabled {
+		return nil
+	}
+	if _, ok := verifyTOTP(u.TOTPSecret, code, u.TOTPLastStep, time.Now()); !ok {
+		return ErrInvalidCredentials
+	}
+	u.TOTPSecret = ""
+	u.TOTPEnabled = false
+	return s.save(ctx, u, false)
+}
+
+func (s *UserService) getUser(ctx context.Context, id string) (*models.UserAccount, error) {
+	u, err := s.repo.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+	}
+	return u, nil
+}
+
+// save stores u, optionally ending all of its sessions.
+func (s *UserService) save(ctx context.Context, u *models.UserAccount, endSessions bool) error {
+	now := time.Now()
+	u.UpdatedAt = now
+	if err := s.repo.UpdateUser(ctx, u); err != nil {
+		if errors.Is(err, storage.ErrDuplicateUserEmail) {
+			return fmt.Errorf("%w: %s", ErrUserExists, u.Email)
+		}
+		return err
+	}
+	s.invalidate(u.ID)
+	if endSessions {
+		if err := s.repo.RevokeUserSessions(ctx, u.ID, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks u and that its advertiser exists.
+func (s *UserService) validate(ctx context.Context, u *models.UserAccount) error {
+	if err := u.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidUser, err)
+	}
+	if u.AdvertiserID != "" {
+		adv, err := s.advertisers.GetAdvertiser(ctx, u.AdvertiserID)
+		if err != nil {
+			return fmt.Errorf("failed to get advertiser: %w", err)
+		}
+		if adv == nil {
+			return fmt.Errorf("%w: %s", ErrAdvertiserNotFound, u.AdvertiserID)
+		}
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	if len(password) < minPasswordLength {
+		return "", fmt.Errorf("%w: password must be at least %d characters", ErrInvalidUser, minPasswordLength)
+	}
+	// bcrypt ignores everything after 72 bytes
+	if len(password) > 72 {
+		return "", fmt.Errorf("%w: password must be at most 72 bytes", ErrInvalidUser)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
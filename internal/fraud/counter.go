@@ -0,0 +1,90 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ClickCounter counts clicks per key in fixed time windows.
+type ClickCounter interface {
+	// Incr adds a click for key and returns the count in the current window.
+	Incr(ctx context.Context, key string, window time.Duration) (int64, error)
+}
+
+// RedisClickCounter counts clicks in Redis so limits hold across instances.
+type RedisClickCounter struct {
+	client *redis.Client
+}
+
+// NewRedisClickCounter creates a new Redis-backed click counter.
+func NewRedisClickCounter(client *redis.Client) *RedisClickCounter {
+	return &RedisClickCounter{client: client}
+}
+
+// Incr increments the counter for the current window.
+func (c *RedisClickCounter) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	bucket := time.Now().UnixNano() / int64(window)
+	redisKey := fmt.Sprintf("fraud:clicks:%s:%d", key, bucket)
+
+	pipe := c.client.TxPipeline()
+	incr := pipe.Incr(ctx, redisKey)
+	pipe.Expire(ctx, redisKey, window*2)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return incr.Val(), nil
+}
+
+// InMemoryClickCounter counts clicks in process memory.
+type InMemoryClickCounter struct {
+	mu     sync.Mutex
+	counts map[string]*windowCount
+	lastGC time.Time
+}
+
+type windowCount struct {
+	bucket  int64
+	count   int64
+	expires time.Time
+}
+
+// NewInMemoryClickCounter creates a new in-memory click counter.
+func NewInMemoryClickCounter() *InMemoryClickCounter {
+	return &InMemoryClickCounter{
+		counts: make(map[string]*windowCount),
+		lastGC: time.Now(),
+	}
+}
+
+// Incr increments the counter for the current window.
+func (c *InMemoryClickCounter) Incr(ctx context.Context, key string, window time.Duration) (int64, error) {
+	now := time.Now()
+	bucket := now.UnixNano() / int64(window)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	wc, ok := c.counts[key]
+	if !ok || wc.bucket != bucket {
+		wc = &windowCount{bucket: bucket}
+		c.counts[key] = wc
+	}
+	wc.count++
+	wc.expires = now.Add(window)
+
+	// Drop stale keys periodically
+	if now.Sub(c.lastGC) > window {
+		for k, v := range c.counts {
+			if now.After(v.expires) {
+				delete(c.counts, k)
+			}
+		}
+		c.lastGC = now
+	}
+
+	return wc.count, nil
+}
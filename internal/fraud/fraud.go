@@ -0,0 +1,211 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Fraud reasons.
+const (
+	ReasonCTITTooShort = "ctit_too_short" // Click injection
+	ReasonClickSpamIFA = "click_spam_ifa" // Too many clicks per device
+	ReasonClickSpamIP  = "click_spam_ip"  // Too many clicks per IP
+	ReasonDataCenterIP = "datacenter_ip"  // Click from a hosting/data-center range
+	ReasonUAOSMismatch = "ua_os_mismatch" // User-Agent disagrees with request params
+	ReasonGeoMismatch  = "geo_mismatch"   // Install country differs from click country
+	ReasonFlaggedClick = "flagged_click"  // Conversion attributed to a flagged click
+)
+
+// reasonWeights are the score contributions of each reason (score is capped at 100).
+var reasonWeights = map[string]int{
+	ReasonCTITTooShort: 60,
+	ReasonClickSpamIFA: 40,
+	ReasonClickSpamIP:  30,
+	ReasonDataCenterIP: 50,
+	ReasonUAOSMismatch: 40,
+	ReasonGeoMismatch:  30,
+}
+
+// Config holds fraud rule thresholds.
+type Config struct {
+	MinCTIT         time.Duration // Installs faster than this after the click are click injection
+	MaxClicksPerIFA int64         // Per ClickWindow
+	MaxClicksPerIP  int64         // Per ClickWindow
+	ClickWindow     time.Duration
+	FlagThreshold   int // Events scoring at least this are flagged
+}
+
+// Result is the fraud verdict for a single event.
+type Result struct {
+	Score   int      `json:"score"`
+	Reasons []string `json:"reasons,omitempty"`
+	Flagged bool     `json:"flagged"`
+}
+
+func (r *Result) add(reason string) {
+	r.Reasons = append(r.Reasons, reason)
+	r.Score += reasonWeights[reason]
+	if r.Score > 100 {
+		r.Score = 100
+	}
+}
+
+// ClickSignals are the attributes of a click used for scoring.
+type ClickSignals struct {
+	SourceType string
+	SourceID   string
+	DeviceIFA  string
+	GAID       string
+	IDFA       string
+	IP         string
+	UserAgent  string
+	OS         string // OS claimed by the source (request param), optional
+	At         time.Time
+}
+
+// ConversionSignals are the attributes of a conversion used for scoring.
+type ConversionSignals struct {
+	SourceType     string
+	SourceID       string
+	Event          string
+	ClickTime      time.Time
+	At             time.Time
+	ClickCountry   string
+	InstallCountry string
+	ClickFlagged   bool
+}
+
+// Scorer scores clicks and conversions against the fraud rules.
+type Scorer struct {
+	cfg        Config
+	counter    ClickCounter
+	dataCenter *IPRanges
+	report     *Report
+}
+
+// NewScorer creates a new fraud scorer. dataCenter may be nil.
+func NewScorer(cfg Config, counter ClickCounter, dataCenter *IPRanges) *Scorer {
+	return &Scorer{
+		cfg:        cfg,
+		counter:    counter,
+		dataCenter: dataCenter,
+		report:     NewReport(),
+	}
+}
+
+// ScoreClick scores a click and records it in the source report.
+func (s *Scorer) ScoreClick(ctx context.Context, sig ClickSignals) (*Result, error) {
+	if sig.At.IsZero() {
+		sig.At = time.Now()
+	}
+	res := &Result{}
+
+	// Click spamming
+	if s.counter != nil && s.cfg.ClickWindow > 0 {
+		if sig.DeviceIFA != "" && s.cfg.MaxClicksPerIFA > 0 {
+			n, err := s.counter.Incr(ctx, "ifa:"+sig.DeviceIFA, s.cfg.ClickWindow)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count clicks: %w", err)
+			}
+			if n > s.cfg.MaxClicksPerIFA {
+				res.add(ReasonClickSpamIFA)
+			}
+		}
+		if sig.IP != "" && s.cfg.MaxClicksPerIP > 0 {
+			n, err := s.counter.Incr(ctx, "ip:"+sig.IP, s.cfg.ClickWindow)
+			if err != nil {
+				return nil, fmt.Errorf("failed to count clicks: %w", err)
+			}
+			if n > s.cfg.MaxClicksPerIP {
+				res.add(ReasonClickSpamIP)
+			}
+		}
+	}
+
+	// Data-center traffic
+	if s.dataCenter != nil && s.dataCenter.Contains(sig.IP) {
+		res.add(ReasonDataCenterIP)
+	}
+
+	// UA/OS mismatch
+	if uaOSMismatch(sig) {
+		res.add(ReasonUAOSMismatch)
+	}
+
+	res.Flagged = s.flagged(res)
+	s.report.RecordClick(sig.SourceType, sig.SourceID, sig.At, res)
+	return res, nil
+}
+
+// ScoreConversion scores a conversion and records it in the source report.
+func (s *Scorer) ScoreConversion(ctx context.Context, sig ConversionSignals) *Result {
+	if sig.At.IsZero() {
+		sig.At = time.Now()
+	}
+	res := &Result{}
+
+	// Click injection: install too soon after the click
+	if sig.Event == "install" && s.cfg.MinCTIT > 0 && !sig.ClickTime.IsZero() {
+		if sig.At.Sub(sig.ClickTime) < s.cfg.MinCTIT {
+			res.add(ReasonCTITTooShort)
+		}
+	}
+
+	// Geo mismatch between click and install
+	if sig.ClickCountry != "" && sig.InstallCountry != "" &&
+		!strings.EqualFold(sig.ClickCountry, sig.InstallCountry) {
+		res.add(ReasonGeoMismatch)
+	}
+
+	// Conversions on flagged clicks inherit the flag
+	if sig.ClickFlagged {
+		res.Reasons = append(res.Reasons, ReasonFlaggedClick)
+		res.Flagged = true
+	}
+
+	res.Flagged = res.Flagged || s.flagged(res)
+	s.report.RecordConversion(sig.SourceType, sig.SourceID, sig.At, res)
+	return res
+}
+
+// SourceReport returns per-source fraud stats for the date range (inclusive).
+func (s *Scorer) SourceReport(from, to time.Time) []SourceStats {
+	return s.report.Sources(from, to)
+}
+
+func (s *Scorer) flagged(res *Result) bool {
+	return s.cfg.FlagThreshold > 0 && res.Score >= s.cfg.FlagThreshold
+}
+
+// uaOSMismatch detects a User-Agent whose OS contradicts the device ID type
+// or the OS the source claims.
+func uaOSMismatch(sig ClickSignals) bool {
+	uaOS := OSFromUserAgent(sig.UserAgent)
+	if uaOS == "" {
+		return false
+	}
+	if sig.GAID != "" && uaOS == "ios" {
+		return true
+	}
+	if sig.IDFA != "" && uaOS == "android" {
+		return true
+	}
+	if sig.OS != "" && !strings.EqualFold(sig.OS, uaOS) {
+		return true
+	}
+	return false
+}
+
+// OSFromUserAgent returns "android", "ios" or "" if the UA is not a mobile OS.
+func OSFromUserAgent(ua string) string {
+	ua = strings.ToLower(ua)
+	switch {
+	case strings.Contains(ua, "android"):
+		return "android"
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return "ios"
+	}
+	return ""
+}
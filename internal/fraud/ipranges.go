@@ -0,0 +1,86 @@
+package fraud
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// IPRanges is a list of CIDR ranges (e.g. known data-center/hosting networks).
+type IPRanges struct {
+	nets []*net.IPNet
+}
+
+// LoadIPRanges reads one CIDR or IP per line; blank lines and # comments are ignored.
+func LoadIPRanges(path string) (*IPRanges, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open IP ranges file: %w", err)
+	}
+	defer f.Close()
+
+	r := &IPRanges{}
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if err := r.Add(line); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNo, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read IP ranges file: %w", err)
+	}
+
+	return r, nil
+}
+
+// Add adds a CIDR range or a single IP.
+func (r *IPRanges) Add(cidr string) error {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return fmt.Errorf("invalid IP: %s", cidr)
+		}
+		if ip.To4() != nil {
+			cidr += "/32"
+		} else {
+			cidr += "/128"
+		}
+	}
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return fmt.Errorf("invalid CIDR: %s", cidr)
+	}
+	r.nets = append(r.nets, n)
+	return nil
+}
+
+// Contains returns true if ip falls into any range.
+func (r *IPRanges) Contains(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range r.nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of ranges.
+func (r *IPRanges) Len() int {
+	return len(r.nets)
+}
@@ -0,0 +1,135 @@
+package fraud
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SourceStats are fraud counters for one traffic source.
+type SourceStats struct {
+	SourceType          string           `json:"source_type"`
+	SourceID            string           `json:"source_id"`
+	Clicks              int64            `json:"clicks"`
+	FlaggedClicks       int64            `json:"flagged_clicks"`
+	Conversions         int64            `json:"conversions"`
+	FlaggedConversions  int64            `json:"flagged_conversions"`
+	ClickFraudRate      float64          `json:"click_fraud_rate"`      // %
+	ConversionFraudRate float64          `json:"conversion_fraud_rate"` // %
+	Reasons             map[string]int64 `json:"reasons"`
+}
+
+// Report aggregates fraud verdicts per source and day.
+type Report struct {
+	mu   sync.RWMutex
+	days map[string]map[string]*SourceStats // date -> sourceType:sourceID -> stats
+}
+
+// NewReport creates an empty fraud report.
+func NewReport() *Report {
+	return &Report{
+		days: make(map[string]map[string]*SourceStats),
+	}
+}
+
+// RecordClick adds a scored click.
+func (r *Report) RecordClick(sourceType, sourceID string, at time.Time, res *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.entry(sourceType, sourceID, at)
+	st.Clicks++
+	if res.Flagged {
+		st.FlaggedClicks++
+	}
+	for _, reason := range res.Reasons {
+		st.Reasons[reason]++
+	}
+}
+
+// RecordConversion adds a scored conversion.
+func (r *Report) RecordConversion(sourceType, sourceID string, at time.Time, res *Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st := r.entry(sourceType, sourceID, at)
+	st.Conversions++
+	if res.Flagged {
+		st.FlaggedConversions++
+	}
+	for _, reason := range res.Reasons {
+		st.Reasons[reason]++
+	}
+}
+
+// Sources returns stats per source between from and to (inclusive, by day),
+// worst click fraud rate first.
+func (r *Report) Sources(from, to time.Time) []SourceStats {
+	fromKey := from.UTC().Format("2006-01-02")
+	toKey := to.UTC().Format("2006-01-02")
+
+	r.mu.RLock()
+	totals := make(map[string]*SourceStats)
+	for day, sources := range r.days {
+		if (!from.IsZero() && day < fromKey) || (!to.IsZero() && day > toKey) {
+			continue
+		}
+		for key, st := range sources {
+			t, ok := totals[key]
+			if !ok {
+				t = &SourceStats{
+					SourceType: st.SourceType,
+					SourceID:   st.SourceID,
+					Reasons:    make(map[string]int64),
+				}
+				totals[key] = t
+			}
+			t.Clicks += st.Clicks
+			t.FlaggedClicks += st.FlaggedClicks
+			t.Conversions += st.Conversions
+			t.FlaggedConversions += st.FlaggedConversions
+			for reason, n := range st.Reasons {
+				t.Reasons[reason] += n
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	result := make([]SourceStats, 0, len(totals))
+	for _, t := range totals {
+		if t.Clicks > 0 {
+			t.ClickFraudRate = float64(t.FlaggedClicks) / float64(t.Clicks) * 100
+		}
+		if t.Conversions > 0 {
+			t.ConversionFraudRate = float64(t.FlaggedConversions) / float64(t.Conversions) * 100
+		}
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ClickFraudRate != result[j].ClickFraudRate {
+			return result[i].ClickFraudRate > result[j].ClickFraudRate
+		}
+		return result[i].SourceID < result[j].SourceID
+	})
+	return result
+}
+
+func (r *Report) entry(sourceType, sourceID string, at time.Time) *SourceStats {
+	day := at.UTC().Format("2006-01-02")
+	sources, ok := r.days[day]
+	if !ok {
+		sources = make(map[string]*SourceStats)
+		r.days[day] = sources
+	}
+	key := sourceType + ":" + sourceID
+	st, ok := sources[key]
+	if !ok {
+		st = &SourceStats{
+			SourceType: sourceType,
+			SourceID:   sourceID,
+			Reasons:    make(map[string]int64),
+		}
+		sources[key] = st
+	}
+	return st
+}
@@ -0,0 +1,354 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// =============================================
+// API v1 - Alerts
+// =============================================
+//
+// Alert definitions check for delivery and health anomalies every alert
+// interval; each anomaly is an alert, open while checks find it and
+// notified to the definition's channels when it opens and resolves.
+// Alerts and definitions can be snoozed. Keys restricted to an advertiser
+// only see that advertiser's campaign alerts.
+
+// alertDefinitionSortFields are the fields v1 alert definition lists sort
+// by.
+var alertDefinitionSortFields = map[string]bool{
+	"id": true, "name": true, "type": true, "created_at": true, "updated_at": true,
+}
+
+// alertDefinitionSortValue returns the value of an alert definition sort
+// field.
+func alertDefinitionSortValue(d *models.AlertDefinition, field string) interface{} {
+	switch field {
+	case "name":
+		return strings.ToLower(d.Name)
+	case "type":
+		return string(d.Type)
+	case "created_at":
+		return v1SortTime(d.CreatedAt)
+	case "updated_at":
+		return v1SortTime(d.UpdatedAt)
+	}
+	return d.ID
+}
+
+// alertSnoozeRequest snoozes an alert until a time or for a number of
+// hours.
+type alertSnoozeRequest struct {
+	Until *time.Time `json:"until,omitempty"`
+	Hours int        `json:"hours,omitempty"`
+}
+
+// handleV1Alerts lists alerts, newest first, filtered by status, type,
+// definition_id, campaign_id and the RFC 3339 from and to (first seen);
+// limit defaults to v1DefaultLimit.
+func (s *Server) handleV1Alerts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	q := r.URL.Query()
+	filter := storage.AlertFilter{
+		DefinitionID: q.Get("definition_id"),
+		Type:         models.AlertType(q.Get("type")),
+		Status:       models.AlertStatus(q.Get("status")),
+		AdvertiserID: middleware.GetAdvertiserScope(r.Context()),
+		CampaignID:   q.Get("campaign_id"),
+		Limit:        v1DefaultLimit,
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > v1MaxLimit {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, fmt.Sprintf("limit must be between 1 and %d", v1MaxLimit), nil)
+			return
+		}
+		filter.Limit = n
+	}
+	for name, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "invalid "+name+" (RFC 3339)", nil)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	list, err := s.alerts.Alerts(r.Context(), filter)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, err.Error(), nil)
+		return
+	}
+	page := v1Page{Data: make([]interface{}, len(list))}
+	for i, a := range list {
+		page.Data[i] = a
+	}
+	s.jsonResponse(w, page)
+}
+
+// handleV1AlertByID serves /api/v1/alerts/{id} and its snooze under
+// /api/v1/alerts/{id}/snooze (POST to snooze, DELETE to unsnooze), as well
+// as alert definitions under /api/v1/alerts/definitions.
+func (s *Server) handleV1AlertByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/alerts/"), "/")
+	id := parts[0]
+	if id == "definitions" {
+		s.handleV1AlertDefinitions(w, r, parts[1:])
+		return
+	}
+	if id == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "snooze") {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+		return
+	}
+
+	a, err := s.alerts.GetAlert(r.Context(), id)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); a == nil || (scope != "" && a.AdvertiserID != scope) {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "alert "+id+" not found", nil)
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		s.jsonResponse(w, a)
+		return
+	}
+
+	var until *time.Time
+	switch r.Method {
+	case http.MethodPost:
+		var req alertSnoozeRequest
+		if !s.decodeV1(w, r, &req) {
+			return
+		}
+		switch {
+		case req.Until != nil && req.Hours == 0:
+			until = req.Until
+		case req.Until == nil && req.Hours > 0:
+			t := time.Now().UTC().Add(time.Duration(req.Hours) * time.Hour)
+			until = &t
+		default:
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "until", Message: "either until or a positive hours is required"}})
+			return
+		}
+	case http.MethodDelete:
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	a, err = s.alerts.SnoozeAlert(r.Context(), id, until)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to snooze: "+err.Error(), nil)
+		return
+	}
+	if a == nil {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "alert "+id+" not found", nil)
+		return
+	}
+	s.jsonResponse(w, a)
+}
+
+// handleV1AlertDefinitions serves /api/v1/alerts/definitions (list and
+// create), /api/v1/alerts/definitions/{id} and its test notification under
+// /api/v1/alerts/definitions/{id}/test. parts is the path after
+// "definitions".
+func (s *Server) handleV1AlertDefinitions(w http.ResponseWriter, r *http.Request, parts []string) {
+	switch {
+	case len(parts) == 0 || (len(parts) == 1 && parts[0] == ""):
+		s.handleV1AlertDefinitionList(w, r)
+		return
+	case parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "test"):
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+		return
+	}
+
+	id := parts[0]
+	d, ok := s.v1AlertDefinition(w, r, id)
+	if !ok {
+		return
+	}
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		if err := s.alerts.TestDefinition(d); err != nil {
+			s.v1Error(w, http.StatusBadGateway, v1InternalError, "test notification failed: "+err.Error(), nil)
+			return
+		}
+		s.jsonResponse(w, map[string]string{"status": "sent"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if notModified(w, r, d.UpdatedAt) {
+			return
+		}
+		s.v1Response(w, http.StatusOK, d.UpdatedAt, d)
+
+	case http.MethodPut, http.MethodPatch:
+		if !s.checkIfMatch(w, r, d.UpdatedAt) {
+			return
+		}
+		var updated models.AlertDefinition
+		if r.Method == http.MethodPut {
+			if !s.decodeV1(w, r, &updated) || !s.checkBodyVersion(w, updated.UpdatedAt, d.UpdatedAt) {
+				return
+			}
+		} else if !s.mergePatch(w, r, d, d.UpdatedAt, &updated) {
+			return
+		}
+		if updated.ID != "" && updated.ID != id {
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "id", Message: "can't be changed"}})
+			return
+		}
+		updated.ID = id
+		updated.CreatedAt = d.CreatedAt
+		if !s.allowAlertDefinitionWrite(w, r, &updated) {
+			return
+		}
+		if err := s.alerts.SaveDefinition(r.Context(), &updated); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditAlert, id, d, &updated)
+		s.v1Response(w, http.StatusOK, updated.UpdatedAt, updated)
+
+	case http.MethodDelete:
+		// The definition's alerts are kept.
+		if !s.checkIfMatch(w, r, d.UpdatedAt) {
+			return
+		}
+		if err := s.alerts.DeleteDefinition(r.Context(), id); err != nil {
+			if errors.Is(err, dsp.ErrBuiltInAlert) {
+				s.v1Error(w, http.StatusConflict, v1Conflict, err.Error(), nil)
+				return
+			}
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to delete: "+err.Error(), nil)
+			return
+		}
+		s.audit(r, models.AuditAlert, id, d, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// handleV1AlertDefinitionList lists (GET) and creates (POST) alert
+// definitions. Lists filter by type and enabled and sort by sort
+// (alertDefinitionSortFields; default name).
+func (s *Server) handleV1AlertDefinitionList(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		params, err := parseV1List(r, alertDefinitionSortFields, "name")
+		if err != nil {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+			return
+		}
+		list, err := s.alerts.ListDefinitions(r.Context(), middleware.GetAdvertiserScope(r.Context()))
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to list", nil)
+			return
+		}
+
+		types := queryValues(r, "type")
+		enabled := r.URL.Query().Get("enabled")
+		rows := make([]v1Row, 0, len(list))
+		for _, d := range list {
+			if (types != nil && !types[string(d.Type)]) ||
+				(enabled != "" && strconv.FormatBool(d.Enabled) != enabled) {
+				continue
+			}
+			rows = append(rows, v1Row{id: d.ID, key: alertDefinitionSortValue(d, strings.TrimPrefix(params.sort, "-")), item: d})
+		}
+		s.jsonResponse(w, paginateV1(rows, params))
+
+	case http.MethodPost:
+		var d models.AlertDefinition
+		if !s.decodeV1(w, r, &d) {
+			return
+		}
+		if d.ID == "" {
+			d.ID = uuid.New().String()
+		}
+		if !s.allowAlertDefinitionWrite(w, r, &d) {
+			return
+		}
+		existing, err := s.alerts.GetDefinition(r.Context(), d.ID)
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+			return
+		}
+		if existing != nil {
+			s.v1Error(w, http.StatusConflict, v1Conflict, "alert definition "+d.ID+" already exists", nil)
+			return
+		}
+		d.CreatedAt = time.Time{}
+		if err := s.alerts.SaveDefinition(r.Context(), &d); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditAlert, d.ID, nil, &d)
+		w.Header().Set("Location", "/api/v1/alerts/definitions/"+d.ID)
+		s.v1Response(w, http.StatusCreated, d.UpdatedAt, d)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// v1AlertDefinition returns an alert definition the request may access,
+// responding 404 (also for other advertisers' definitions) or 500
+// otherwise.
+func (s *Server) v1AlertDefinition(w http.ResponseWriter, r *http.Request, id string) (*models.AlertDefinition, bool) {
+	d, err := s.alerts.GetDefinition(r.Context(), id)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return nil, false
+	}
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if d == nil || (scope != "" && d.AdvertiserID != scope) {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "alert definition "+id+" not found", nil)
+		return nil, false
+	}
+	return d, true
+}
+
+// allowAlertDefinitionWrite restricts definitions saved with a key
+// restricted to an advertiser to that advertiser's campaigns.
+func (s *Server) allowAlertDefinitionWrite(w http.ResponseWriter, r *http.Request, d *models.AlertDefinition) bool {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return true
+	}
+	if d.AdvertiserID == "" {
+		d.AdvertiserID = scope
+	}
+	return s.allowAdvertiser(w, r, d.AdvertiserID)
+}
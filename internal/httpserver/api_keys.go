@@ -0,0 +1,296 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// =============================================
+// Admin API - API Keys
+// =============================================
+
+// apiKeyRequest is the body of API key creation requests.
+type apiKeyRequest struct {
+	Name         string     `json:"name"`
+	AdvertiserID string     `json:"advertiser_id"`
+	Permissions  []string   `json:"permissions"`
+	RateLimit    int        `json:"rate_limit"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+// handleAPIKeys lists and creates API keys. Keys restricted to an
+// advertiser manage only that advertiser's keys.
+func (s *Server) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	scope := middleware.GetAdvertiserScope(r.Context())
+
+	switch r.Method {
+	case http.MethodGet:
+		advertiserID, ok := s.scopeAdvertiserID(w, r, r.URL.Query().Get("advertiser_id"))
+		if !ok {
+			return
+		}
+		list, err := s.apiKeys.List(r.Context(), advertiserID)
+		if err != nil {
+			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
+			return
+		}
+		s.jsonResponse(w, list)
+
+	case http.MethodPost:
+		var req apiKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if scope != "" {
+			if req.AdvertiserID != "" && req.AdvertiserID != scope {
+				s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+				return
+			}
+			req.AdvertiserID = scope
+		}
+		key, raw, err := s.apiKeys.Create(r.Context(), &models.APIKey{
+			Name:         req.Name,
+			AdvertiserID: req.AdvertiserID,
+			Permissions:  req.Permissions,
+			RateLimit:    req.RateLimit,
+			ExpiresAt:    req.ExpiresAt,
+		})
+		if err != nil {
+			s.apiKeyError(w, "failed to create api key", err)
+			return
+		}
+		// The key is only shown once
+		s.jsonResponse(w, map[string]interface{}{
+			"key":     raw,
+			"api_key": key,
+		})
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIKeyByID serves /api/api-keys/{id} and /api/api-keys/{id}/revoke.
+func (s *Server) handleAPIKeyByID(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/api-keys/"), "/")
+	if id == "" || (action != "" && action != "revoke") {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	key, err := s.apiKeys.Get(r.Context(), id)
+	if err != nil {
+		s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if key == nil {
+		http.NotFound(w, r)
+		return
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" && key.AdvertiserID != scope {
+		s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+		return
+	}
+
+	if action == "revoke" {
+		if r.Method != http.MethodPost {
+			s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		key, err = s.apiKeys.Revoke(r.Context(), id)
+		if err != nil {
+			s.apiKeyError(w, "failed to revoke api key", err)
+			return
+		}
+		s.jsonResponse(w, key)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.jsonResponse(w, key)
+}
+
+// requireAdmin responds 403 unless the request's API key or user has the
+// admin scope. The master key and unauthenticated requests (auth disabled)
+// pass.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	p := middleware.GetPrincipal(r.Context())
+	if p == nil || p.Allows(models.ScopeAdmin) {
+		return true
+	}
+	if p.Kind == models.PrincipalUser {
+		s.errorResponse(w, "role "+p.Role+" lacks admin permission", http.StatusForbidden)
+	} else {
+		s.errorResponse(w, "API key lacks admin permission", http.StatusForbidden)
+	}
+	return false
+}
+
+// apiKeyError maps API key service errors to status codes.
+func (s *Server) apiKeyError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, dsp.ErrInvalidAPIKey):
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, dsp.ErrAPIKeyNotFound), errors.Is(err, dsp.ErrAdvertiserNotFound):
+		s.errorResponse(w, err.Error(), http.StatusNotFound)
+	default:
+		s.errorResponse(w, msg+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// =============================================
+// Advertiser Scope
+// =============================================
+
+// noCampaignID is a campaign ID no campaign has (PostgreSQL text cannot
+// hold NUL). Filters of advertisers without campaigns use it to match
+// nothing.
+const noCampaignID = "\x00"
+
+// requireUnscoped responds 403 to keys restricted to an advertiser. It
+// guards platform-wide endpoints such as sources, payout rules, pacing and
+// bid diagnostics.
+func (s *Server) requireUnscoped(w http.ResponseWriter, r *http.Request) bool {
+	if middleware.GetAdvertiserScope(r.Context()) != "" {
+		s.errorResponse(w, "this endpoint needs an unrestricted API key", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// allowAdvertiser responds 403 if the request's key is restricted to
+// another advertiser.
+func (s *Server) allowAdvertiser(w http.ResponseWriter, r *http.Request, advertiserID string) bool {
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" && scope != advertiserID {
+		s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// allowCampaign is allowAdvertiser for the campaign's advertiser. Keys
+// restricted to an advertiser get 403 for unknown campaigns too.
+func (s *Server) allowCampaign(w http.ResponseWriter, r *http.Request, campaignID string) bool {
+	if middleware.GetAdvertiserScope(r.Context()) == "" {
+		return true
+	}
+	c, err := s.campaignService.GetCampaign(r.Context(), campaignID)
+	if err != nil {
+		s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if c == nil {
+		s.errorResponse(w, "campaign not allowed for this API key", http.StatusForbidden)
+		return false
+	}
+	return s.allowAdvertiser(w, r, c.AdvertiserID)
+}
+
+// scopeAdvertiserID returns the advertiser filter of a request: the
+// requested one, or the key's advertiser for restricted keys, which may
+// not ask for another.
+func (s *Server) scopeAdvertiserID(w http.ResponseWriter, r *http.Request, requested string) (string, bool) {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return requested, true
+	}
+	if requested != "" && requested != scope {
+		s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+		return "", false
+	}
+	return scope, true
+}
+
+// scopeCampaignIDs returns the campaign filter of a request. For keys
+// restricted to an advertiser, requested campaigns must be the
+// advertiser's and no campaigns means all of them.
+func (s *Server) scopeCampaignIDs(w http.ResponseWriter, r *http.Request, requested []string) ([]string, bool) {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return requested, true
+	}
+	owned, err := s.advertiserCampaignIDs(r.Context(), scope)
+	if err != nil {
+		s.errorResponse(w, "failed to list campaigns: "+err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	if len(requested) == 0 {
+		if len(owned) == 0 {
+			return []string{noCampaignID}, true
+		}
+		return owned, true
+	}
+	allowed := make(map[string]bool, len(owned))
+	for _, id := range owned {
+		allowed[id] = true
+	}
+	for _, id := range requested {
+		if !allowed[id] {
+			s.errorResponse(w, "campaign not allowed for this API key", http.StatusForbidden)
+			return nil, false
+		}
+	}
+	return requested, true
+}
+
+// advertiserCampaignIDs returns the IDs of an advertiser's campaigns.
+func (s *Server) advertiserCampaignIDs(ctx context.Context, advertiserID string) ([]string, error) {
+	list, err := s.campaignService.ListCampaigns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0)
+	for _, c := range list {
+		if c.AdvertiserID == advertiserID {
+			ids = append(ids, c.ID)
+		}
+	}
+	return ids, nil
+}
+
+// scopeReportFilter restricts a report filter to the campaigns of the
+// request key's advertiser; see scopeCampaignIDs.
+func (s *Server) scopeReportFilter(w http.ResponseWriter, r *http.Request, filter *dsp.ReportFilter) bool {
+	advertiserID, ok := s.scopeAdvertiserID(w, r, filter.AdvertiserID)
+	if !ok {
+		return false
+	}
+	campaignIDs, ok := s.scopeCampaignIDs(w, r, filter.CampaignIDs)
+	if !ok {
+		return false
+	}
+	filter.AdvertiserID, filter.CampaignIDs = advertiserID, campaignIDs
+	return true
+}
+
+// requireCampaignForScope checks the campaign of a single-campaign
+// report. Keys restricted to an advertiser must name one of its campaigns,
+// as no campaign means all of them.
+func (s *Server) requireCampaignForScope(w http.ResponseWriter, r *http.Request, campaignID string) bool {
+	if middleware.GetAdvertiserScope(r.Context()) == "" {
+		return true
+	}
+	if campaignID == "" {
+		s.errorResponse(w, "campaign_id required for this API key", http.StatusBadRequest)
+		return false
+	}
+	return s.allowCampaign(w, r, campaignID)
+}
@@ -0,0 +1,806 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// =============================================
+// API v1
+// =============================================
+//
+// /api/v1 serves campaigns and their line items with partial updates (JSON
+// Merge Patch, RFC 7396), archiving instead of deletion, cursor pagination
+// and optimistic concurrency: responses carry an ETag derived from the
+// campaign's updated_at, and writes with a stale If-Match (or a stale
+// updated_at in a patch) get 412. Errors have a code and, for validation
+// failures, the invalid fields.
+
+// v1 error codes.
+const (
+	v1InvalidRequest     = "invalid_request"
+	v1NotFound           = "not_found"
+	v1Conflict           = "conflict"
+	v1ValidationFailed   = "validation_failed"
+	v1PreconditionFailed = "precondition_failed"
+	v1InternalError      = "internal_error"
+)
+
+// Page sizes of v1 lists.
+const (
+	v1DefaultLimit = 50
+	v1MaxLimit     = 500
+)
+
+// v1ErrorBody is the body of v1 error responses. Error is the message, as
+// on the unversioned API.
+type v1ErrorBody struct {
+	Error  string              `json:"error"`
+	Code   string              `json:"code"`
+	Fields []models.FieldError `json:"fields,omitempty"`
+}
+
+// v1Page is one page of a v1 list. NextCursor is empty on the last page.
+type v1Page struct {
+	Data       []interface{} `json:"data"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// v1Error writes a v1 error response.
+func (s *Server) v1Error(w http.ResponseWriter, code int, errCode, message string, fields []models.FieldError) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v1ErrorBody{Error: message, Code: errCode, Fields: fields})
+}
+
+// v1SaveError maps a failed save to 422 with the invalid fields, or 500.
+func (s *Server) v1SaveError(w http.ResponseWriter, err error) {
+	var verrs models.ValidationErrors
+	if errors.As(err, &verrs) {
+		s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed", verrs)
+		return
+	}
+	s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to save: "+err.Error(), nil)
+}
+
+// v1Response writes data with status code and the ETag of version.
+func (s *Server) v1Response(w http.ResponseWriter, code int, version time.Time, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("ETag", v1ETag(version))
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(data)
+}
+
+// v1ETag returns the entity tag of a version. It has microsecond
+// precision, which is what PostgreSQL keeps of updated_at.
+func v1ETag(version time.Time) string {
+	return `"` + strconv.FormatInt(version.UnixMicro(), 36) + `"`
+}
+
+// checkIfMatch responds 412 unless the request's If-Match (if any) matches
+// the current version.
+func (s *Server) checkIfMatch(w http.ResponseWriter, r *http.Request, current time.Time) bool {
+	h := r.Header.Get("If-Match")
+	if h == "" || etagListContains(h, v1ETag(current)) {
+		return true
+	}
+	s.v1Error(w, http.StatusPreconditionFailed, v1PreconditionFailed,
+		"the object was changed since it was read (current ETag "+v1ETag(current)+")", nil)
+	return false
+}
+
+// notModified responds 304 if the request's If-None-Match matches the
+// current version.
+func notModified(w http.ResponseWriter, r *http.Request, current time.Time) bool {
+	h := r.Header.Get("If-None-Match")
+	if h == "" || !etagListContains(h, v1ETag(current)) {
+		return false
+	}
+	w.Header().Set("ETag", v1ETag(current))
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListContains reports whether an If-Match or If-None-Match header
+// lists etag or is "*". Weak tags compare by their opaque part.
+func etagListContains(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeV1 decodes a JSON request body into v, rejecting unknown fields.
+func (s *Server) decodeV1(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "invalid json: "+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// mergePatch applies a JSON Merge Patch (RFC 7396) read from r to current
+// and decodes the result into dst. A patch that sets updated_at must carry
+// version, current's updated_at, so it works like If-Match.
+func (s *Server) mergePatch(w http.ResponseWriter, r *http.Request, current interface{}, version time.Time, dst interface{}) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct != "" && !strings.HasPrefix(ct, "application/merge-patch+json") && !strings.HasPrefix(ct, "application/json") {
+		s.v1Error(w, http.StatusUnsupportedMediaType, v1InvalidRequest, "use application/merge-patch+json", nil)
+		return false
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "failed to read body", nil)
+		return false
+	}
+	var patch interface{}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "invalid json: "+err.Error(), nil)
+		return false
+	}
+	if obj, ok := patch.(map[string]interface{}); ok {
+		if v, ok := obj["updated_at"]; ok {
+			t, err := time.Parse(time.RFC3339Nano, fmt.Sprint(v))
+			if err != nil || !sameVersion(t, version) {
+				s.v1Error(w, http.StatusPreconditionFailed, v1PreconditionFailed, "updated_at doesn't match the current version", nil)
+				return false
+			}
+			delete(obj, "updated_at")
+		}
+	}
+
+	b, err := json.Marshal(current)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to encode object", nil)
+		return false
+	}
+	var doc interface{}
+	if err := json.Unmarshal(b, &doc); err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to decode object", nil)
+		return false
+	}
+	merged, err := json.Marshal(applyMergePatch(doc, patch))
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to encode patched object", nil)
+		return false
+	}
+	dec := json.NewDecoder(bytes.NewReader(merged))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "invalid patch: "+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// sameVersion reports whether two updated_at values are the same version,
+// at the precision of v1ETag.
+func sameVersion(a, b time.Time) bool {
+	return a.UnixMicro() == b.UnixMicro()
+}
+
+// checkBodyVersion responds 412 if a PUT body has an updated_at other than
+// the current one. A zero updated_at isn't checked.
+func (s *Server) checkBodyVersion(w http.ResponseWriter, body, current time.Time) bool {
+	if body.IsZero() || sameVersion(body, current) {
+		return true
+	}
+	s.v1Error(w, http.StatusPreconditionFailed, v1PreconditionFailed, "updated_at doesn't match the current version", nil)
+	return false
+}
+
+// applyMergePatch returns target with patch applied as in RFC 7396: object
+// members are merged recursively, null removes a member and any other
+// value, arrays included, replaces the target.
+func applyMergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = applyMergePatch(t[k], v)
+	}
+	return t
+}
+
+// =============================================
+// API v1 - Pagination
+// =============================================
+
+// v1Row is a list item with the value it's sorted by. Sort values are
+// strings or float64s; times are formatted by v1SortTime.
+type v1Row struct {
+	id   string
+	key  interface{}
+	item interface{}
+}
+
+// v1Cursor marks the last row of a page. It holds the sort, so a cursor
+// can't be used with another one.
+type v1Cursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// v1ListParams are the sort and page of a list request.
+type v1ListParams struct {
+	sort   string // Field, "-" prefixed for descending
+	limit  int
+	cursor *v1Cursor
+}
+
+// parseV1List reads sort, limit and cursor from a list request. sortFields
+// are the fields the list may be sorted by.
+func parseV1List(r *http.Request, sortFields map[string]bool, defaultSort string) (*v1ListParams, error) {
+	q := r.URL.Query()
+	p := &v1ListParams{sort: defaultSort, limit: v1DefaultLimit}
+	if v := q.Get("sort"); v != "" {
+		if !sortFields[strings.TrimPrefix(v, "-")] {
+			return nil, fmt.Errorf("can't sort by %s", strings.TrimPrefix(v, "-"))
+		}
+		p.sort = v
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > v1MaxLimit {
+			return nil, fmt.Errorf("invalid limit (1-%d)", v1MaxLimit)
+		}
+		p.limit = n
+	}
+	if v := q.Get("cursor"); v != "" {
+		b, err := base64.RawURLEncoding.DecodeString(v)
+		var c v1Cursor
+		if err == nil {
+			err = json.Unmarshal(b, &c)
+		}
+		if err != nil || c.Sort != p.sort {
+			return nil, errors.New("invalid cursor")
+		}
+		p.cursor = &c
+	}
+	return p, nil
+}
+
+// paginateV1 sorts rows and returns the page after the cursor.
+func paginateV1(rows []v1Row, p *v1ListParams) v1Page {
+	desc := strings.HasPrefix(p.sort, "-")
+	less := func(ak interface{}, aid string, bk interface{}, bid string) bool {
+		if c := compareSortValues(ak, bk); c != 0 {
+			return (c < 0) != desc
+		}
+		return aid < bid
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return less(rows[i].key, rows[i].id, rows[j].key, rows[j].id)
+	})
+
+	start := 0
+	if p.cursor != nil {
+		start = sort.Search(len(rows), func(i int) bool {
+			return less(p.cursor.Value, p.cursor.ID, rows[i].key, rows[i].id)
+		})
+	}
+	end := start + p.limit
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	page := v1Page{Data: make([]interface{}, 0, end-start)}
+	for _, row := range rows[start:end] {
+		page.Data = append(page.Data, row.item)
+	}
+	if end < len(rows) {
+		last := rows[end-1]
+		b, _ := json.Marshal(v1Cursor{Sort: p.sort, Value: last.key, ID: last.id})
+		page.NextCursor = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return page
+}
+
+// compareSortValues orders two sort values; values of different types
+// (including nil) order by type.
+func compareSortValues(a, b interface{}) int {
+	switch av := a.(type) {
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case float64:
+		if bv, ok := b.(float64); ok {
+			switch {
+			case av < bv:
+				return -1
+			case av > bv:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%T", a), fmt.Sprintf("%T", b))
+}
+
+// v1SortTime formats t so that times sort as strings.
+func v1SortTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000000Z")
+}
+
+// queryValues returns the comma-separated values of a filter parameter.
+func queryValues(r *http.Request, name string) map[string]bool {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil
+	}
+	values := make(map[string]bool)
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			values[s] = true
+		}
+	}
+	return values
+}
+
+// =============================================
+// API v1 - Campaigns
+// =============================================
+
+// campaignSortFields are the fields v1 campaign lists sort by.
+var campaignSortFields = map[string]bool{
+	"id": true, "name": true, "status": true, "advertiser_id": true,
+	"created_at": true, "updated_at": true, "start_date": true, "end_date": true,
+	"total_budget": true, "daily_budget": true,
+}
+
+// campaignSortValue returns the value of a campaign sort field.
+func campaignSortValue(c *models.Campaign, field string) interface{} {
+	switch field {
+	case "name":
+		return strings.ToLower(c.Name)
+	case "status":
+		return string(c.Status)
+	case "advertiser_id":
+		return c.AdvertiserID
+	case "created_at":
+		return v1SortTime(c.CreatedAt)
+	case "updated_at":
+		return v1SortTime(c.UpdatedAt)
+	case "start_date":
+		return v1SortTime(c.StartDate)
+	case "end_date":
+		return v1SortTime(c.EndDate)
+	case "total_budget":
+		return c.TotalBudget
+	case "daily_budget":
+		return c.DailyBudget
+	}
+	return c.ID
+}
+
+// handleV1Campaigns lists (GET) and creates (POST) campaigns.
+//
+// Lists filter by status, advertiser_id, objective and app_bundle (each
+// comma-separated) and q (a substring of the name), and sort by sort
+// (campaignSortFields, "-" for descending; default -created_at). Archived
+// campaigns are left out unless include_archived=true or status asks for
+// them.
+func (s *Server) handleV1Campaigns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		params, err := parseV1List(r, campaignSortFields, "-created_at")
+		if err != nil {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+			return
+		}
+		list, err := s.campaignService.ListCampaigns(r.Context())
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to list", nil)
+			return
+		}
+
+		statuses := queryValues(r, "status")
+		advertisers := queryValues(r, "advertiser_id")
+		if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+			advertisers = map[string]bool{scope: true}
+		}
+		objectives := queryValues(r, "objective")
+		bundles := queryValues(r, "app_bundle")
+		name := strings.ToLower(r.URL.Query().Get("q"))
+		includeArchived := r.URL.Query().Get("include_archived") == "true"
+
+		rows := make([]v1Row, 0, len(list))
+		for _, c := range list {
+			switch {
+			case statuses != nil && !statuses[string(c.Status)],
+				statuses == nil && !includeArchived && c.Status == models.CampaignStatusArchived,
+				advertisers != nil && !advertisers[c.AdvertiserID],
+				objectives != nil && !objectives[string(c.Objective)],
+				bundles != nil && !bundles[c.AppBundle],
+				name != "" && !strings.Contains(strings.ToLower(c.Name), name):
+				continue
+			}
+			rows = append(rows, v1Row{id: c.ID, key: campaignSortValue(c, strings.TrimPrefix(params.sort, "-")), item: c})
+		}
+		s.jsonResponse(w, paginateV1(rows, params))
+
+	case http.MethodPost:
+		var c models.Campaign
+		if !s.decodeV1(w, r, &c) {
+			return
+		}
+		if c.ID == "" {
+			c.ID = uuid.New().String()
+		}
+		if !s.allowCampaignWrite(w, r, &c) {
+			return
+		}
+		existing, err := s.campaignService.GetCampaign(r.Context(), c.ID)
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+			return
+		}
+		if existing != nil {
+			s.v1Error(w, http.StatusConflict, v1Conflict, "campaign "+c.ID+" already exists", nil)
+			return
+		}
+		c.CreatedAt = time.Time{}
+		dsp.StampLineItems(&c, nil)
+		if err := s.campaignService.UpsertCampaign(r.Context(), &c); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditCampaign, c.ID, nil, &c)
+		w.Header().Set("Location", "/api/v1/campaigns/"+c.ID)
+		s.v1Response(w, http.StatusCreated, c.UpdatedAt, c)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// handleV1CampaignByID serves /api/v1/campaigns/{id}, its line items
+// under /api/v1/campaigns/{id}/line_items, its status history under
+// /api/v1/campaigns/{id}/transitions, and its clones and draft (see
+// templates.go).
+func (s *Server) handleV1CampaignByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/campaigns/"), "/")
+	id := parts[0]
+	sub := strings.Join(parts[1:], "/")
+	known := false
+	switch {
+	case len(parts) == 1, sub == "line_items", sub == "transitions", sub == "clone", sub == "draft", sub == "draft/publish":
+		known = true
+	case parts[1] == "line_items" && parts[2] != "":
+		known = len(parts) == 3 || (len(parts) == 4 && parts[3] == "clone")
+	}
+	if id == "" || !known {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+		return
+	}
+
+	c, ok := s.v1Campaign(w, r, id)
+	if !ok {
+		return
+	}
+	switch {
+	case sub == "transitions":
+		s.handleV1Transitions(w, r, c)
+		return
+	case sub == "clone":
+		s.handleV1CampaignClone(w, r, c)
+		return
+	case sub == "draft", sub == "draft/publish":
+		s.handleV1CampaignDraft(w, r, c, sub == "draft/publish")
+		return
+	case sub == "line_items":
+		s.handleV1LineItems(w, r, c)
+		return
+	case len(parts) == 4:
+		s.handleV1LineItemClone(w, r, c, parts[2])
+		return
+	case len(parts) == 3:
+		s.handleV1LineItem(w, r, c, parts[2])
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if notModified(w, r, c.UpdatedAt) {
+			return
+		}
+		s.v1Response(w, http.StatusOK, c.UpdatedAt, c)
+
+	case http.MethodPut, http.MethodPatch:
+		if !s.checkIfMatch(w, r, c.UpdatedAt) {
+			return
+		}
+		var updated models.Campaign
+		if r.Method == http.MethodPut {
+			if !s.decodeV1(w, r, &updated) || !s.checkBodyVersion(w, updated.UpdatedAt, c.UpdatedAt) {
+				return
+			}
+		} else if !s.mergePatch(w, r, c, c.UpdatedAt, &updated) {
+			return
+		}
+		if updated.ID != "" && updated.ID != id {
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "id", Message: "can't be changed"}})
+			return
+		}
+		updated.ID = id
+		updated.CreatedAt = c.CreatedAt
+		if !s.allowCampaignWrite(w, r, &updated) {
+			return
+		}
+		dsp.StampLineItems(&updated, c)
+		if err := s.campaignService.UpsertCampaign(r.Context(), &updated); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditCampaign, id, c, &updated)
+		s.v1Response(w, http.StatusOK, updated.UpdatedAt, updated)
+
+	case http.MethodDelete:
+		// Campaigns are archived, not deleted: stats, billing and the audit
+		// log keep referring to them. PATCH the status to restore one.
+		if !s.checkIfMatch(w, r, c.UpdatedAt) {
+			return
+		}
+		if c.Status != models.CampaignStatusArchived {
+			archived := *c
+			archived.Status = models.CampaignStatusArchived
+			if err := s.campaignService.UpsertCampaign(r.Context(), &archived); err != nil {
+				s.v1SaveError(w, err)
+				return
+			}
+			s.audit(r, models.AuditCampaign, id, c, &archived)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// v1Campaign returns a campaign the request may access, responding 404
+// (also for other advertisers' campaigns) or 500 otherwise.
+func (s *Server) v1Campaign(w http.ResponseWriter, r *http.Request, id string) (*models.Campaign, bool) {
+	c, err := s.campaignService.GetCampaign(r.Context(), id)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return nil, false
+	}
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if c == nil || (scope != "" && c.AdvertiserID != scope) {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "campaign "+id+" not found", nil)
+		return nil, false
+	}
+	return c, true
+}
+
+// =============================================
+// API v1 - Line Items
+// =============================================
+
+// lineItemSortFields are the fields v1 line item lists sort by.
+var lineItemSortFields = map[string]bool{
+	"id": true, "name": true, "priority": true, "daily_budget": true,
+	"created_at": true, "updated_at": true,
+}
+
+// lineItemSortValue returns the value of a line item sort field.
+func lineItemSortValue(li *models.LineItem, field string) interface{} {
+	switch field {
+	case "name":
+		return strings.ToLower(li.Name)
+	case "priority":
+		return float64(li.Priority)
+	case "daily_budget":
+		return li.Pacing.DailyBudget
+	case "created_at":
+		return v1SortTime(li.CreatedAt)
+	case "updated_at":
+		return v1SortTime(li.UpdatedAt)
+	}
+	return li.ID
+}
+
+// handleV1LineItems lists (GET) and adds (POST) the line items of c. Lists
+// filter by is_active and optimization_goal and sort by sort
+// (lineItemSortFields; default id). Line item responses carry the ETag of
+// the campaign, which changes with any of its line items.
+func (s *Server) handleV1LineItems(w http.ResponseWriter, r *http.Request, c *models.Campaign) {
+	switch r.Method {
+	case http.MethodGet:
+		params, err := parseV1List(r, lineItemSortFields, "id")
+		if err != nil {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+			return
+		}
+		active := r.URL.Query().Get("is_active")
+		goals := queryValues(r, "optimization_goal")
+
+		rows := make([]v1Row, 0, len(c.LineItems))
+		for i := range c.LineItems {
+			li := &c.LineItems[i]
+			if (active != "" && strconv.FormatBool(li.IsActive) != active) ||
+				(goals != nil && !goals[string(li.OptimizationGoal)]) {
+				continue
+			}
+			rows = append(rows, v1Row{id: li.ID, key: lineItemSortValue(li, strings.TrimPrefix(params.sort, "-")), item: li})
+		}
+		w.Header().Set("ETag", v1ETag(c.UpdatedAt))
+		s.jsonResponse(w, paginateV1(rows, params))
+
+	case http.MethodPost:
+		if !s.checkIfMatch(w, r, c.UpdatedAt) {
+			return
+		}
+		var li models.LineItem
+		if !s.decodeV1(w, r, &li) {
+			return
+		}
+		if li.ID == "" {
+			li.ID = uuid.New().String()
+		}
+		if findLineItem(c, li.ID) >= 0 {
+			s.v1Error(w, http.StatusConflict, v1Conflict, "line item "+li.ID+" already exists", nil)
+			return
+		}
+		updated := *c
+		updated.LineItems = append(append([]models.LineItem{}, c.LineItems...), li)
+		s.saveV1LineItem(w, r, c, &updated, li.ID, http.StatusCreated)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// handleV1LineItem serves one line item of c. DELETE removes it from the
+// campaign; the audit log keeps the removed version.
+func (s *Server) handleV1LineItem(w http.ResponseWriter, r *http.Request, c *models.Campaign, liID string) {
+	idx := findLineItem(c, liID)
+	if idx < 0 {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "line item "+liID+" not found", nil)
+		return
+	}
+	li := &c.LineItems[idx]
+
+	switch r.Method {
+	case http.MethodGet:
+		if notModified(w, r, c.UpdatedAt) {
+			return
+		}
+		s.v1Response(w, http.StatusOK, c.UpdatedAt, li)
+
+	case http.MethodPut, http.MethodPatch:
+		if !s.checkIfMatch(w, r, c.UpdatedAt) {
+			return
+		}
+		var updatedLI models.LineItem
+		if r.Method == http.MethodPut {
+			if !s.decodeV1(w, r, &updatedLI) || !s.checkBodyVersion(w, updatedLI.UpdatedAt, li.UpdatedAt) {
+				return
+			}
+		} else if !s.mergePatch(w, r, li, li.UpdatedAt, &updatedLI) {
+			return
+		}
+		if updatedLI.ID != "" && updatedLI.ID != liID {
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "id", Message: "can't be changed"}})
+			return
+		}
+		updatedLI.ID = liID
+		updated := *c
+		updated.LineItems = append([]models.LineItem{}, c.LineItems...)
+		updated.LineItems[idx] = updatedLI
+		s.saveV1LineItem(w, r, c, &updated, liID, http.StatusOK)
+
+	case http.MethodDelete:
+		if !s.checkIfMatch(w, r, c.UpdatedAt) {
+			return
+		}
+		updated := *c
+		updated.LineItems = append(append([]models.LineItem{}, c.LineItems[:idx]...), c.LineItems[idx+1:]...)
+		if err := s.campaignService.UpsertCampaign(r.Context(), &updated); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditCampaign, c.ID, c, &updated)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// saveV1LineItem saves updated, the campaign c with line item liID added
+// or changed, and responds with the line item. The line item is validated
+// on its own first, so its errors name its own fields.
+func (s *Server) saveV1LineItem(w http.ResponseWriter, r *http.Request, c, updated *models.Campaign, liID string, code int) {
+	dsp.StampLineItems(updated, c)
+	idx := findLineItem(updated, liID)
+	if err := updated.LineItems[idx].Validate(); err != nil {
+		s.v1SaveError(w, err)
+		return
+	}
+	if err := s.campaignService.UpsertCampaign(r.Context(), updated); err != nil {
+		s.v1SaveError(w, err)
+		return
+	}
+	s.audit(r, models.AuditCampaign, c.ID, c, updated)
+	if code == http.StatusCreated {
+		w.Header().Set("Location", "/api/v1/campaigns/"+c.ID+"/line_items/"+liID)
+	}
+	s.v1Response(w, code, updated.UpdatedAt, updated.LineItems[idx])
+}
+
+// handleV1Transitions lists the status changes of campaign c, newest
+// first, optionally only those with reason. limit defaults to
+// v1DefaultLimit.
+func (s *Server) handleV1Transitions(w http.ResponseWriter, r *http.Request, c *models.Campaign) {
+	if r.Method != http.MethodGet {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	limit := v1DefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > v1MaxLimit {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, fmt.Sprintf("limit must be between 1 and %d", v1MaxLimit), nil)
+			return
+		}
+		limit = n
+	}
+	list, err := s.lifecycle.Transitions(r.Context(), storage.TransitionFilter{
+		CampaignID: c.ID,
+		Reason:     r.URL.Query().Get("reason"),
+		Limit:      limit,
+	})
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, err.Error(), nil)
+		return
+	}
+	page := v1Page{Data: make([]interface{}, len(list))}
+	for i, t := range list {
+		page.Data[i] = t
+	}
+	s.jsonResponse(w, page)
+}
+
+// findLineItem returns the index of line item id in c, or -1.
+func findLineItem(c *models.Campaign, id string) int {
+	for i := range c.LineItems {
+		if c.LineItems[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
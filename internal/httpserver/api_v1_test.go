@@ -0,0 +1,414 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// v1CampaignPage is a page of a v1 campaign list.
+type v1CampaignPage struct {
+	Data       []models.Campaign `json:"data"`
+	NextCursor string            `json:"next_cursor"`
+}
+
+// createV1Campaign creates a campaign through the v1 API and returns its
+// ETag.
+func createV1Campaign(t *testing.T, h http.Handler, key, body string) string {
+	t.Helper()
+	w := serve(t, h, testRequest{method: http.MethodPost, target: "/api/v1/campaigns", key: key, body: body})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST /api/v1/campaigns = %d %s, want 201", w.Code, w.Body.String())
+	}
+	return w.Header().Get("ETag")
+}
+
+func TestV1Campaign(t *testing.T) {
+	h := newTestServer(t)
+	etag := createV1Campaign(t, h, "", `{"id":"cmp-1","name":"Spring","advertiser_id":"adv-1","status":"active","daily_budget":100}`)
+	if etag == "" {
+		t.Fatal("created campaign has no ETag")
+	}
+
+	tests := []struct {
+		name      string
+		req       testRequest
+		wantCode  int
+		wantError string // v1 error code
+		wantField string // First invalid field
+		check     func(*testing.T, *models.Campaign)
+	}{
+		{
+			name:      "create existing",
+			req:       testRequest{method: http.MethodPost, target: "/api/v1/campaigns", body: `{"id":"cmp-1","name":"Again","advertiser_id":"adv-1"}`},
+			wantCode:  http.StatusConflict,
+			wantError: v1Conflict,
+		},
+		{
+			name:      "create invalid",
+			req:       testRequest{method: http.MethodPost, target: "/api/v1/campaigns", body: `{"advertiser_id":"adv-1"}`},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantError: v1ValidationFailed,
+			wantField: "name",
+		},
+		{
+			name:      "create with unknown field",
+			req:       testRequest{method: http.MethodPost, target: "/api/v1/campaigns", body: `{"name":"X","advertiser_id":"adv-1","budget":5}`},
+			wantCode:  http.StatusBadRequest,
+			wantError: v1InvalidRequest,
+		},
+		{
+			name:     "get",
+			req:      testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-1"},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, c *models.Campaign) {
+				if c.Name != "Spring" || c.DailyBudget != 100 {
+					t.Errorf("campaign = %+v, want Spring with a daily budget of 100", c)
+				}
+			},
+		},
+		{
+			name:     "get unchanged",
+			req:      testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-1", header: map[string]string{"If-None-Match": etag}},
+			wantCode: http.StatusNotModified,
+		},
+		{
+			name:      "get unknown",
+			req:       testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-unknown"},
+			wantCode:  http.StatusNotFound,
+			wantError: v1NotFound,
+		},
+		{
+			name:      "unknown subresource",
+			req:       testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-1/stats"},
+			wantCode:  http.StatusNotFound,
+			wantError: v1NotFound,
+		},
+		{
+			name:      "patch with a stale ETag",
+			req:       testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `{"name":"Summer"}`, header: map[string]string{"If-Match": `"stale"`}},
+			wantCode:  http.StatusPreconditionFailed,
+			wantError: v1PreconditionFailed,
+		},
+		{
+			name:      "patch with a stale updated_at",
+			req:       testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `{"name":"Summer","updated_at":"2020-01-01T00:00:00Z"}`},
+			wantCode:  http.StatusPreconditionFailed,
+			wantError: v1PreconditionFailed,
+		},
+		{
+			name:      "patch the id",
+			req:       testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `{"id":"cmp-2"}`},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantError: v1ValidationFailed,
+			wantField: "id",
+		},
+		{
+			name:      "patch to an invalid campaign",
+			req:       testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `{"name":null}`},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantError: v1ValidationFailed,
+			wantField: "name",
+		},
+		{
+			name:      "patch of another content type",
+			req:       testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `name=Summer`, header: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}},
+			wantCode:  http.StatusUnsupportedMediaType,
+			wantError: v1InvalidRequest,
+		},
+		{
+			// Members left out of a merge patch keep their values
+			name:     "patch",
+			req:      testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `{"name":"Summer"}`, header: map[string]string{"If-Match": etag, "Content-Type": "application/merge-patch+json"}},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, c *models.Campaign) {
+				if c.Name != "Summer" || c.AdvertiserID != "adv-1" || c.DailyBudget != 100 || c.Status != models.CampaignStatusActive {
+					t.Errorf("patched campaign = %+v, want Summer with the rest unchanged", c)
+				}
+			},
+		},
+		{
+			name:      "method not allowed",
+			req:       testRequest{method: http.MethodPost, target: "/api/v1/campaigns/cmp-1"},
+			wantCode:  http.StatusMethodNotAllowed,
+			wantError: v1InvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, h, tt.req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s %s = %d %s, want %d", tt.req.method, tt.req.target, w.Code, w.Body.String(), tt.wantCode)
+			}
+			if tt.wantError != "" {
+				var body v1ErrorBody
+				decodeBody(t, w, &body)
+				if body.Code != tt.wantError {
+					t.Errorf("error code = %q, want %q", body.Code, tt.wantError)
+				}
+				if tt.wantField != "" && (len(body.Fields) == 0 || body.Fields[0].Field != tt.wantField) {
+					t.Errorf("invalid fields = %+v, want %s", body.Fields, tt.wantField)
+				}
+			}
+			if tt.check != nil {
+				var c models.Campaign
+				decodeBody(t, w, &c)
+				tt.check(t, &c)
+			}
+		})
+	}
+}
+
+func TestV1CampaignArchive(t *testing.T) {
+	h := newTestServer(t)
+	createV1Campaign(t, h, "", `{"id":"cmp-1","name":"Kept","advertiser_id":"adv-1","status":"active"}`)
+	createV1Campaign(t, h, "", `{"id":"cmp-2","name":"Archived","advertiser_id":"adv-1","status":"paused"}`)
+
+	if w := serve(t, h, testRequest{method: http.MethodDelete, target: "/api/v1/campaigns/cmp-2"}); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d %s, want 204", w.Code, w.Body.String())
+	}
+	// Deleting again is a no-op
+	if w := serve(t, h, testRequest{method: http.MethodDelete, target: "/api/v1/campaigns/cmp-2"}); w.Code != http.StatusNoContent {
+		t.Fatalf("second DELETE = %d, want 204", w.Code)
+	}
+
+	var c models.Campaign
+	w := serve(t, h, testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-2"})
+	decodeBody(t, w, &c)
+	if w.Code != http.StatusOK || c.Status != models.CampaignStatusArchived {
+		t.Errorf("archived campaign = %d, status %q; want 200, archived", w.Code, c.Status)
+	}
+
+	lists := []struct {
+		target string
+		want   []string
+	}{
+		{"/api/v1/campaigns", []string{"cmp-1"}},
+		{"/api/v1/campaigns?include_archived=true&sort=id", []string{"cmp-1", "cmp-2"}},
+		{"/api/v1/campaigns?status=archived", []string{"cmp-2"}},
+	}
+	for _, tt := range lists {
+		t.Run(tt.target, func(t *testing.T) {
+			var page v1CampaignPage
+			decodeBody(t, serve(t, h, testRequest{method: http.MethodGet, target: tt.target}), &page)
+			if got := campaignIDs(page.Data); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("campaigns = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// PATCHing the status restores a campaign
+	w = serve(t, h, testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-2", body: `{"status":"paused"}`})
+	if w.Code != http.StatusOK {
+		t.Errorf("PATCH status = %d %s, want 200", w.Code, w.Body.String())
+	}
+}
+
+func TestV1CampaignPagination(t *testing.T) {
+	h := newTestServer(t)
+	for i, name := range []string{"delta", "Alpha", "echo", "charlie", "Bravo"} {
+		createV1Campaign(t, h, "", fmt.Sprintf(`{"id":"cmp-%d","name":%q,"advertiser_id":"adv-1","daily_budget":%d}`, i, name, 10*(i%2)))
+	}
+
+	tests := []struct {
+		sort string
+		want []string
+	}{
+		// Names sort case-insensitively
+		{"name", []string{"Alpha", "Bravo", "charlie", "delta", "echo"}},
+		{"-name", []string{"echo", "delta", "charlie", "Bravo", "Alpha"}},
+		// Ties are broken by id
+		{"daily_budget", []string{"delta", "echo", "Bravo", "Alpha", "charlie"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sort, func(t *testing.T) {
+			var names []string
+			cursor := ""
+			for pages := 1; ; pages++ {
+				var page v1CampaignPage
+				w := serve(t, h, testRequest{method: http.MethodGet, target: "/api/v1/campaigns?limit=2&sort=" + tt.sort + "&cursor=" + cursor})
+				if w.Code != http.StatusOK {
+					t.Fatalf("page %d = %d %s, want 200", pages, w.Code, w.Body.String())
+				}
+				decodeBody(t, w, &page)
+				for _, c := range page.Data {
+					names = append(names, c.Name)
+				}
+				if page.NextCursor == "" {
+					if pages != 3 {
+						t.Errorf("%d pages, want 3", pages)
+					}
+					break
+				}
+				if pages == 3 {
+					t.Fatal("the last page has a next cursor")
+				}
+				cursor = page.NextCursor
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.want) {
+				t.Errorf("campaigns = %v, want %v", names, tt.want)
+			}
+		})
+	}
+
+	var first v1CampaignPage
+	decodeBody(t, serve(t, h, testRequest{method: http.MethodGet, target: "/api/v1/campaigns?limit=2&sort=name"}), &first)
+	invalid := []string{
+		"/api/v1/campaigns?sort=app_name",
+		"/api/v1/campaigns?limit=0",
+		fmt.Sprintf("/api/v1/campaigns?limit=%d", v1MaxLimit+1),
+		"/api/v1/campaigns?cursor=not-a-cursor",
+		// A cursor only works with the sort it was made for
+		"/api/v1/campaigns?sort=id&cursor=" + first.NextCursor,
+	}
+	for _, target := range invalid {
+		t.Run(target, func(t *testing.T) {
+			if w := serve(t, h, testRequest{method: http.MethodGet, target: target}); w.Code != http.StatusBadRequest {
+				t.Errorf("GET = %d, want 400", w.Code)
+			}
+		})
+	}
+}
+
+func TestV1AdvertiserScope(t *testing.T) {
+	h := newTestServer(t)
+	createV1Campaign(t, h, "", `{"id":"cmp-1","name":"Own","advertiser_id":"adv-1"}`)
+	createV1Campaign(t, h, "", `{"id":"cmp-2","name":"Other","advertiser_id":"adv-2"}`)
+
+	var page v1CampaignPage
+	decodeBody(t, serve(t, h, testRequest{method: http.MethodGet, target: "/api/v1/campaigns?advertiser_id=adv-2", key: testAdv1Key}), &page)
+	if got := campaignIDs(page.Data); fmt.Sprint(got) != "[cmp-1]" {
+		t.Errorf("campaigns listed for an adv-1 key = %v, want [cmp-1]", got)
+	}
+
+	tests := []struct {
+		name     string
+		req      testRequest
+		wantCode int
+	}{
+		{"get own", testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-1"}, http.StatusOK},
+		{"get other", testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-2"}, http.StatusNotFound},
+		{"patch other", testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-2", body: `{"name":"Mine"}`}, http.StatusNotFound},
+		{"move own to other", testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1", body: `{"advertiser_id":"adv-2"}`}, http.StatusForbidden},
+		{"delete other", testRequest{method: http.MethodDelete, target: "/api/v1/campaigns/cmp-2"}, http.StatusNotFound},
+		{"line items of other", testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-2/line_items"}, http.StatusNotFound},
+		{"create for other", testRequest{method: http.MethodPost, target: "/api/v1/campaigns", body: `{"name":"New","advertiser_id":"adv-2"}`}, http.StatusForbidden},
+		{"create over other", testRequest{method: http.MethodPost, target: "/api/v1/campaigns", body: `{"id":"cmp-2","name":"New"}`}, http.StatusForbidden},
+		{"create without advertiser", testRequest{method: http.MethodPost, target: "/api/v1/campaigns", body: `{"id":"cmp-3","name":"New"}`}, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.key = testAdv1Key
+			if w := serve(t, h, tt.req); w.Code != tt.wantCode {
+				t.Errorf("%s %s = %d %s, want %d", tt.req.method, tt.req.target, w.Code, w.Body.String(), tt.wantCode)
+			}
+		})
+	}
+
+	// Campaigns created without an advertiser get the key's
+	var c models.Campaign
+	decodeBody(t, serve(t, h, testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-3"}), &c)
+	if c.AdvertiserID != "adv-1" {
+		t.Errorf("advertiser of a campaign created by an adv-1 key = %q, want adv-1", c.AdvertiserID)
+	}
+}
+
+func TestV1LineItems(t *testing.T) {
+	h := newTestServer(t)
+	createV1Campaign(t, h, "", `{"id":"cmp-1","name":"Spring","advertiser_id":"adv-1"}`)
+	const lineItem = `{"id":"li-1","name":"Banner","pacing":{"daily_budget":50},"creatives":[{"id":"cr-1"}],"is_active":true}`
+
+	tests := []struct {
+		name      string
+		req       testRequest
+		wantCode  int
+		wantField string
+		check     func(*testing.T, *models.LineItem)
+	}{
+		{
+			name:     "add",
+			req:      testRequest{method: http.MethodPost, target: "/api/v1/campaigns/cmp-1/line_items", body: lineItem},
+			wantCode: http.StatusCreated,
+			check: func(t *testing.T, li *models.LineItem) {
+				if li.CampaignID != "cmp-1" || li.CreatedAt.IsZero() {
+					t.Errorf("added line item = %+v, want campaign cmp-1 and a creation time", li)
+				}
+			},
+		},
+		{
+			name:     "add existing",
+			req:      testRequest{method: http.MethodPost, target: "/api/v1/campaigns/cmp-1/line_items", body: lineItem},
+			wantCode: http.StatusConflict,
+		},
+		{
+			// Errors name the line item's fields, not the campaign's
+			name:      "add invalid",
+			req:       testRequest{method: http.MethodPost, target: "/api/v1/campaigns/cmp-1/line_items", body: `{"id":"li-2","creatives":[{"id":"cr-1"}]}`},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "pacing.daily_budget",
+		},
+		{
+			name:     "patch",
+			req:      testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1/line_items/li-1", body: `{"pacing":{"daily_budget":75}}`},
+			wantCode: http.StatusOK,
+			check: func(t *testing.T, li *models.LineItem) {
+				if li.Pacing.DailyBudget != 75 || li.Name != "Banner" || len(li.Creatives) != 1 {
+					t.Errorf("patched line item = %+v, want a daily budget of 75 and the rest unchanged", li)
+				}
+			},
+		},
+		{
+			name:      "patch the id",
+			req:       testRequest{method: http.MethodPatch, target: "/api/v1/campaigns/cmp-1/line_items/li-1", body: `{"id":"li-9"}`},
+			wantCode:  http.StatusUnprocessableEntity,
+			wantField: "id",
+		},
+		{
+			name:     "get unknown",
+			req:      testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-1/line_items/li-9"},
+			wantCode: http.StatusNotFound,
+		},
+		{
+			name:     "delete",
+			req:      testRequest{method: http.MethodDelete, target: "/api/v1/campaigns/cmp-1/line_items/li-1"},
+			wantCode: http.StatusNoContent,
+		},
+		{
+			name:     "get deleted",
+			req:      testRequest{method: http.MethodGet, target: "/api/v1/campaigns/cmp-1/line_items/li-1"},
+			wantCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(t, h, tt.req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("%s %s = %d %s, want %d", tt.req.method, tt.req.target, w.Code, w.Body.String(), tt.wantCode)
+			}
+			if tt.wantField != "" {
+				var body v1ErrorBody
+				decodeBody(t, w, &body)
+				if len(body.Fields) == 0 || body.Fields[0].Field != tt.wantField {
+					t.Errorf("invalid fields = %+v, want %s", body.Fields, tt.wantField)
+				}
+			}
+			if tt.check != nil {
+				var li models.LineItem
+				decodeBody(t, w, &li)
+				tt.check(t, &li)
+			}
+		})
+	}
+}
+
+func campaignIDs(list []models.Campaign) []string {
+	ids := make([]string, len(list))
+	for i := range list {
+		ids[i] = list[i].ID
+	}
+	return ids
+}
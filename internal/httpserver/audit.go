@@ -0,0 +1,320 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// =============================================
+// Admin API - Audit Log
+// =============================================
+
+// audit records a management change made by the request. A failed record
+// is logged and doesn't fail the request: the change is already saved.
+func (s *Server) audit(r *http.Request, entityType, entityID string, before, after interface{}) {
+	actor := middleware.GetPrincipal(r.Context())
+	if _, err := s.auditLog.Record(r.Context(), actor, entityType, entityID, before, after); err != nil {
+		s.logger.Error("failed to record audit entry",
+			zap.String("entity_type", entityType),
+			zap.String("entity_id", entityID),
+			zap.Error(err))
+	}
+	if entityType == models.AuditCampaign {
+		s.recordStatusChange(r, before, after)
+	}
+}
+
+// recordStatusChange records a campaign status change made through the
+// API as a lifecycle transition.
+func (s *Server) recordStatusChange(r *http.Request, before, after interface{}) {
+	b, _ := before.(*models.Campaign)
+	a, _ := after.(*models.Campaign)
+	s.lifecycle.RecordChange(r.Context(), middleware.GetPrincipal(r.Context()), b, a)
+}
+
+// handleAudit lists audit entries, newest first, filtered by entity_type,
+// entity_id, actor_id, action and a from/to range.
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+
+	q := r.URL.Query()
+	from, to, err := parseBillingRange(q)
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := storage.AuditFilter{
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		ActorID:    q.Get("actor_id"),
+		Action:     q.Get("action"),
+		From:       from,
+		To:         to,
+		Limit:      100,
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 1000 {
+			s.errorResponse(w, "invalid limit (1-1000)", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = n
+	}
+
+	list, err := s.auditLog.List(r.Context(), filter)
+	if err != nil {
+		s.errorResponse(w, "failed to list", http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, list)
+}
+
+// handleAuditByID serves GET /api/audit/{id} and
+// POST /api/audit/{id}/rollback, which restores the entity to the version
+// recorded by the entry.
+func (s *Server) handleAuditByID(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/audit/")
+	parts := strings.Split(path, "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "rollback") {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+
+	if len(parts) == 1 {
+		if r.Method != http.MethodGet {
+			s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		e, err := s.auditLog.Get(r.Context(), id)
+		if err != nil {
+			s.auditError(w, "failed to get audit entry", err)
+			return
+		}
+		s.jsonResponse(w, e)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	target, snapshot, err := s.auditLog.RollbackTarget(r.Context(), id)
+	if err != nil {
+		s.auditError(w, "failed to roll back", err)
+		return
+	}
+	before, after, err := s.restoreEntity(r.Context(), target.EntityType, target.EntityID, snapshot)
+	if err != nil {
+		s.auditError(w, "failed to roll back", err)
+		return
+	}
+	if target.EntityType == models.AuditCampaign {
+		s.recordStatusChange(r, before, after)
+	}
+	e, err := s.auditLog.RecordRollback(r.Context(), middleware.GetPrincipal(r.Context()), target, before, after)
+	if err != nil {
+		s.errorResponse(w, "rolled back but failed to record: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, e)
+}
+
+// restoreEntity saves snapshot as the current version of an entity and
+// returns the versions before and after.
+func (s *Server) restoreEntity(ctx context.Context, entityType, entityID string, snapshot json.RawMessage) (before, after interface{}, err error) {
+	decode := func(v interface{}) error {
+		if err := json.Unmarshal(snapshot, v); err != nil {
+			return fmt.Errorf("%w: failed to decode %s snapshot: %v", dsp.ErrInvalidRollback, entityType, err)
+		}
+		return nil
+	}
+
+	switch entityType {
+	case models.AuditCampaign:
+		var c models.Campaign
+		if err := decode(&c); err != nil {
+			return nil, nil, err
+		}
+		c.ID = entityID
+		cur, err := s.campaignService.GetCampaign(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.campaignService.UpsertCampaign(ctx, &c); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &c, nil
+
+	case models.AuditAdGroup:
+		var g models.AdGroup
+		if err := decode(&g); err != nil {
+			return nil, nil, err
+		}
+		g.ID = entityID
+		cur, err := s.adGroupService.GetAdGroup(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.adGroupService.UpsertAdGroup(ctx, &g); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &g, nil
+
+	case models.AuditCreative:
+		var cr models.Creative
+		if err := decode(&cr); err != nil {
+			return nil, nil, err
+		}
+		cr.ID = entityID
+		cur, err := s.creativeService.GetCreative(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.creativeService.UpsertCreative(ctx, &cr); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &cr, nil
+
+	case models.AuditS2SSource:
+		var src models.S2SSource
+		if err := decode(&src); err != nil {
+			return nil, nil, err
+		}
+		src.ID = entityID
+		cur, err := s.sourceService.GetS2SSource(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.sourceService.UpsertS2SSource(ctx, &src); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &src, nil
+
+	case models.AuditRTBSource:
+		var src models.RTBSource
+		if err := decode(&src); err != nil {
+			return nil, nil, err
+		}
+		src.ID = entityID
+		cur, err := s.sourceService.GetRTBSource(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.sourceService.UpsertRTBSource(ctx, &src); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &src, nil
+
+	case models.AuditAdvertiser:
+		var a models.Advertiser
+		if err := decode(&a); err != nil {
+			return nil, nil, err
+		}
+		a.ID = entityID
+		cur, err := s.advertiserService.GetAdvertiser(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.advertiserService.UpsertAdvertiser(ctx, &a); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &a, nil
+
+	case models.AuditPayoutRule:
+		var rule models.PayoutRule
+		if err := decode(&rule); err != nil {
+			return nil, nil, err
+		}
+		rule.ID = entityID
+		cur, err := s.payoutEngine.GetRule(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.payoutEngine.UpsertRule(ctx, &rule); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &rule, nil
+
+	case models.AuditRule:
+		var rule models.AutomationRule
+		if err := decode(&rule); err != nil {
+			return nil, nil, err
+		}
+		rule.ID = entityID
+		cur, err := s.automation.GetRule(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.automation.SaveRule(ctx, &rule); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &rule, nil
+
+	case models.AuditAlert:
+		var def models.AlertDefinition
+		if err := decode(&def); err != nil {
+			return nil, nil, err
+		}
+		def.ID = entityID
+		cur, err := s.alerts.GetDefinition(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.alerts.SaveDefinition(ctx, &def); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &def, nil
+
+	case models.AuditTemplate:
+		var t models.CampaignTemplate
+		if err := decode(&t); err != nil {
+			return nil, nil, err
+		}
+		t.ID = entityID
+		cur, err := s.templates.GetTemplate(ctx, entityID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := s.templates.SaveTemplate(ctx, &t); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", dsp.ErrInvalidRollback, err)
+		}
+		return cur, &t, nil
+	}
+	return nil, nil, fmt.Errorf("%w: unknown entity type %q", dsp.ErrInvalidRollback, entityType)
+}
+
+// auditError maps audit service errors to status codes.
+func (s *Server) auditError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, dsp.ErrAuditEntryNotFound):
+		s.errorResponse(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, dsp.ErrInvalidRollback):
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+	default:
+		s.errorResponse(w, msg+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
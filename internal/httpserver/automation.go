@@ -0,0 +1,334 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// =============================================
+// API v1 - Automation rules
+// =============================================
+//
+// Automation rules check reporting metrics of line items (or of their
+// sources, countries or app bundles) over a lookback window and pause,
+// rebid, blacklist or notify when every condition holds. Enabled rules run
+// on the automation interval; any rule, saved or not, can be dry run to see
+// what it would do. Rules of advertiser keys only see that advertiser's
+// campaigns.
+
+// ruleSortFields are the fields v1 rule lists sort by.
+var ruleSortFields = map[string]bool{
+	"id": true, "name": true, "scope": true, "created_at": true, "updated_at": true,
+}
+
+// ruleSortValue returns the value of a rule sort field.
+func ruleSortValue(rule *models.AutomationRule, field string) interface{} {
+	switch field {
+	case "name":
+		return strings.ToLower(rule.Name)
+	case "scope":
+		return string(rule.Scope)
+	case "created_at":
+		return v1SortTime(rule.CreatedAt)
+	case "updated_at":
+		return v1SortTime(rule.UpdatedAt)
+	}
+	return rule.ID
+}
+
+// handleV1Rules lists (GET) and creates (POST) automation rules. Lists
+// filter by enabled and scope and sort by sort (ruleSortFields; default
+// name).
+func (s *Server) handleV1Rules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		params, err := parseV1List(r, ruleSortFields, "name")
+		if err != nil {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+			return
+		}
+		advertiserID := r.URL.Query().Get("advertiser_id")
+		if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+			advertiserID = scope
+		}
+		list, err := s.automation.ListRules(r.Context(), advertiserID)
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to list", nil)
+			return
+		}
+
+		enabled := r.URL.Query().Get("enabled")
+		scopes := queryValues(r, "scope")
+		rows := make([]v1Row, 0, len(list))
+		for _, rule := range list {
+			if (enabled != "" && strconv.FormatBool(rule.Enabled) != enabled) ||
+				(scopes != nil && !scopes[string(rule.Scope)]) {
+				continue
+			}
+			rows = append(rows, v1Row{id: rule.ID, key: ruleSortValue(rule, strings.TrimPrefix(params.sort, "-")), item: rule})
+		}
+		s.jsonResponse(w, paginateV1(rows, params))
+
+	case http.MethodPost:
+		var rule models.AutomationRule
+		if !s.decodeV1(w, r, &rule) {
+			return
+		}
+		if rule.ID == "" {
+			rule.ID = uuid.New().String()
+		}
+		if !s.allowRuleWrite(w, r, &rule) {
+			return
+		}
+		existing, err := s.automation.GetRule(r.Context(), rule.ID)
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+			return
+		}
+		if existing != nil {
+			s.v1Error(w, http.StatusConflict, v1Conflict, "rule "+rule.ID+" already exists", nil)
+			return
+		}
+		rule.CreatedAt = time.Time{}
+		if err := s.automation.SaveRule(r.Context(), &rule); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditRule, rule.ID, nil, &rule)
+		w.Header().Set("Location", "/api/v1/rules/"+rule.ID)
+		s.v1Response(w, http.StatusCreated, rule.UpdatedAt, rule)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// handleV1RuleByID serves /api/v1/rules/{id}, its evaluation under
+// /api/v1/rules/{id}/evaluate and its firings under
+// /api/v1/rules/{id}/firings, as well as dry runs of unsaved rules at
+// /api/v1/rules/evaluate and the firings of every rule at
+// /api/v1/rules/firings.
+func (s *Server) handleV1RuleByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/rules/"), "/")
+	id := parts[0]
+	switch {
+	case len(parts) == 1 && id == "evaluate":
+		s.handleV1RuleDryRun(w, r)
+		return
+	case len(parts) == 1 && id == "firings":
+		s.handleV1RuleFirings(w, r, "")
+		return
+	case id == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "evaluate" && parts[1] != "firings"):
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+		return
+	}
+
+	rule, ok := s.v1Rule(w, r, id)
+	if !ok {
+		return
+	}
+	if len(parts) == 2 {
+		if parts[1] == "firings" {
+			s.handleV1RuleFirings(w, r, rule.ID)
+		} else {
+			s.handleV1RuleEvaluate(w, r, rule)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if notModified(w, r, rule.UpdatedAt) {
+			return
+		}
+		s.v1Response(w, http.StatusOK, rule.UpdatedAt, rule)
+
+	case http.MethodPut, http.MethodPatch:
+		if !s.checkIfMatch(w, r, rule.UpdatedAt) {
+			return
+		}
+		var updated models.AutomationRule
+		if r.Method == http.MethodPut {
+			if !s.decodeV1(w, r, &updated) || !s.checkBodyVersion(w, updated.UpdatedAt, rule.UpdatedAt) {
+				return
+			}
+		} else if !s.mergePatch(w, r, rule, rule.UpdatedAt, &updated) {
+			return
+		}
+		if updated.ID != "" && updated.ID != id {
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "id", Message: "can't be changed"}})
+			return
+		}
+		updated.ID = id
+		updated.CreatedAt = rule.CreatedAt
+		if !s.allowRuleWrite(w, r, &updated) {
+			return
+		}
+		if err := s.automation.SaveRule(r.Context(), &updated); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditRule, id, rule, &updated)
+		s.v1Response(w, http.StatusOK, updated.UpdatedAt, updated)
+
+	case http.MethodDelete:
+		// The firing log keeps the rule's firings.
+		if !s.checkIfMatch(w, r, rule.UpdatedAt) {
+			return
+		}
+		if err := s.automation.DeleteRule(r.Context(), id); err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to delete: "+err.Error(), nil)
+			return
+		}
+		s.audit(r, models.AuditRule, id, rule, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// v1Rule returns a rule the request may access, responding 404 (also for
+// other advertisers' rules) or 500 otherwise.
+func (s *Server) v1Rule(w http.ResponseWriter, r *http.Request, id string) (*models.AutomationRule, bool) {
+	rule, err := s.automation.GetRule(r.Context(), id)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return nil, false
+	}
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if rule == nil || (scope != "" && rule.AdvertiserID != scope) {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "rule "+id+" not found", nil)
+		return nil, false
+	}
+	return rule, true
+}
+
+// allowRuleWrite restricts rules saved with a key restricted to an
+// advertiser to that advertiser's campaigns.
+func (s *Server) allowRuleWrite(w http.ResponseWriter, r *http.Request, rule *models.AutomationRule) bool {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return true
+	}
+	if rule.AdvertiserID == "" {
+		rule.AdvertiserID = scope
+	}
+	return s.allowAdvertiser(w, r, rule.AdvertiserID)
+}
+
+// handleV1RuleEvaluate evaluates a saved rule now. It is a dry run unless
+// dry_run=false, which applies the actions like a scheduled run (also for
+// disabled rules).
+func (s *Server) handleV1RuleEvaluate(w http.ResponseWriter, r *http.Request, rule *models.AutomationRule) {
+	if r.Method != http.MethodPost {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") != "false"
+	eval, err := s.automation.Evaluate(r.Context(), rule, time.Now().UTC(), dryRun)
+	if err != nil {
+		s.ruleEvaluateError(w, err)
+		return
+	}
+	s.jsonResponse(w, eval)
+}
+
+// handleV1RuleDryRun dry runs the rule in the request body, which isn't
+// saved.
+func (s *Server) handleV1RuleDryRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	var rule models.AutomationRule
+	if !s.decodeV1(w, r, &rule) {
+		return
+	}
+	if rule.ID == "" {
+		rule.ID = uuid.New().String()
+	}
+	if !s.allowRuleWrite(w, r, &rule) {
+		return
+	}
+	eval, err := s.automation.Evaluate(r.Context(), &rule, time.Now().UTC(), true)
+	if err != nil {
+		s.ruleEvaluateError(w, err)
+		return
+	}
+	s.jsonResponse(w, eval)
+}
+
+// ruleEvaluateError maps a failed evaluation to 422 with the invalid
+// fields, 501 without a reporting event store, or 500.
+func (s *Server) ruleEvaluateError(w http.ResponseWriter, err error) {
+	var verrs models.ValidationErrors
+	switch {
+	case errors.As(err, &verrs):
+		s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed", verrs)
+	case errors.Is(err, dsp.ErrReportsUnsupported):
+		s.v1Error(w, http.StatusNotImplemented, v1InternalError, err.Error(), nil)
+	default:
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to evaluate: "+err.Error(), nil)
+	}
+}
+
+// handleV1RuleFirings lists the firing log, newest first: the firings of
+// rule ruleID, or of every rule the request may access. It filters by
+// campaign_id, line_item_id, scope_value and the RFC 3339 from and to;
+// limit defaults to v1DefaultLimit.
+func (s *Server) handleV1RuleFirings(w http.ResponseWriter, r *http.Request, ruleID string) {
+	if r.Method != http.MethodGet {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	q := r.URL.Query()
+	filter := storage.RuleFiringFilter{
+		RuleID:       ruleID,
+		AdvertiserID: middleware.GetAdvertiserScope(r.Context()),
+		CampaignID:   q.Get("campaign_id"),
+		LineItemID:   q.Get("line_item_id"),
+		ScopeValue:   q.Get("scope_value"),
+		Limit:        v1DefaultLimit,
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > v1MaxLimit {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, fmt.Sprintf("limit must be between 1 and %d", v1MaxLimit), nil)
+			return
+		}
+		filter.Limit = n
+	}
+	for name, t := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if v := q.Get(name); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "invalid "+name+" (RFC 3339)", nil)
+				return
+			}
+			*t = parsed
+		}
+	}
+
+	list, err := s.automation.Firings(r.Context(), filter)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, err.Error(), nil)
+		return
+	}
+	page := v1Page{Data: make([]interface{}, len(list))}
+	for i, f := range list {
+		page.Data[i] = f
+	}
+	s.jsonResponse(w, page)
+}
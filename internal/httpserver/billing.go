@@ -0,0 +1,185 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// =============================================
+// Admin API - Advertiser Billing
+// =============================================
+
+// billingRequest is the body of top-up and adjustment requests.
+type billingRequest struct {
+	Amount      float64 `json:"amount"`
+	Reference   string  `json:"reference"`
+	Description string  `json:"description"`
+	CreatedBy   string  `json:"created_by"`
+}
+
+// handleAdvertiserBilling serves /api/advertisers/{id}/balance, /topup,
+// /adjustments, /transactions and /statement. Keys restricted to an
+// advertiser may read their own ledger but not change it.
+func (s *Server) handleAdvertiserBilling(w http.ResponseWriter, r *http.Request, id, action string) {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope != "" && scope != id {
+		s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+		return
+	}
+
+	switch action {
+	case "balance":
+		if r.Method != http.MethodGet {
+			s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		balance, err := s.billing.Balance(r.Context(), id)
+		if err != nil {
+			s.billingError(w, "failed to get balance", err)
+			return
+		}
+		if balance == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.jsonResponse(w, balance)
+
+	case "topup", "adjustments":
+		if r.Method != http.MethodPost {
+			s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if scope != "" {
+			s.errorResponse(w, "balance changes need an unrestricted API key", http.StatusForbidden)
+			return
+		}
+		var req billingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		var tx *models.BillingTransaction
+		var err error
+		if action == "topup" {
+			tx, err = s.billing.TopUp(r.Context(), id, req.Amount, req.Reference, req.Description, req.CreatedBy)
+		} else {
+			tx, err = s.billing.Adjust(r.Context(), id, req.Amount, req.Reference, req.Description, req.CreatedBy)
+		}
+		if err != nil {
+			s.billingError(w, "failed to apply transaction", err)
+			return
+		}
+		s.jsonResponse(w, tx)
+
+	case "transactions":
+		if r.Method != http.MethodGet {
+			s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		q := r.URL.Query()
+		from, to, err := parseBillingRange(q)
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter := storage.BillingFilter{
+			AdvertiserID: id,
+			Type:         models.BillingTransactionType(q.Get("type")),
+			From:         from,
+			To:           to,
+		}
+		if v := q.Get("limit"); v != "" {
+			if filter.Limit, err = strconv.Atoi(v); err != nil || filter.Limit < 0 {
+				s.errorResponse(w, "invalid limit", http.StatusBadRequest)
+				return
+			}
+		}
+		txs, err := s.billing.Transactions(r.Context(), filter)
+		if err != nil {
+			s.billingError(w, "failed to list transactions", err)
+			return
+		}
+		s.jsonResponse(w, txs)
+
+	case "statement":
+		if r.Method != http.MethodGet {
+			s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		from, to, err := parseBillingRange(r.URL.Query())
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// Defaults to the current month
+		now := time.Now().UTC()
+		if from.IsZero() {
+			from = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		if to.IsZero() {
+			to = now
+		}
+		statement, err := s.billing.Statement(r.Context(), id, from, to)
+		if err != nil {
+			s.billingError(w, "failed to build statement", err)
+			return
+		}
+		s.jsonResponse(w, statement)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) billingError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, dsp.ErrInvalidBilling):
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, dsp.ErrAdvertiserNotFound):
+		s.errorResponse(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, storage.ErrDuplicateBillingReference):
+		s.errorResponse(w, err.Error(), http.StatusConflict)
+	default:
+		s.errorResponse(w, msg+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseBillingRange parses from and to as RFC 3339 times or UTC dates. A
+// date "to" includes the whole day.
+func parseBillingRange(q url.Values) (from, to time.Time, err error) {
+	parse := func(name string) (time.Time, bool, error) {
+		v := q.Get(name)
+		if v == "" {
+			return time.Time{}, false, nil
+		}
+		if t, err := time.Parse("2006-01-02", v); err == nil {
+			return t, true, nil
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s (YYYY-MM-DD or RFC 3339)", name)
+		}
+		return t, false, nil
+	}
+	if from, _, err = parse("from"); err != nil {
+		return from, to, err
+	}
+	var isDate bool
+	if to, isDate, err = parse("to"); err != nil {
+		return from, to, err
+	}
+	if isDate {
+		to = to.AddDate(0, 0, 1)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,205 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/reports"
+)
+
+// =============================================
+// API v1 - Bulk operations
+// =============================================
+//
+// Bulk operations change many objects at once: field changes to a filtered
+// selection of line items, campaigns or creatives, and campaign sheet
+// imports (CSV or XLSX). They are all or nothing and report every row;
+// with dry_run the diff is returned and nothing is saved.
+
+// maxImportSize caps the size of campaign sheet uploads.
+const maxImportSize = 10 << 20
+
+// handleV1Bulk serves /api/v1/bulk/{line_items,campaigns,creatives} and
+// /api/v1/bulk/campaigns/{export,import}.
+func (s *Server) handleV1Bulk(w http.ResponseWriter, r *http.Request) {
+	switch op := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/bulk/"), "/"); op {
+	case "campaigns/export":
+		if r.Method != http.MethodGet {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		s.handleV1CampaignExport(w, r)
+		return
+	case "campaigns/import":
+		if r.Method != http.MethodPost {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		s.handleV1CampaignImport(w, r)
+		return
+	case "line_items", "campaigns", "creatives":
+		if r.Method != http.MethodPost {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		s.handleV1BulkChange(w, r, op)
+	default:
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+	}
+}
+
+// handleV1BulkChange applies a bulk change request to line items,
+// campaigns or creatives.
+func (s *Server) handleV1BulkChange(w http.ResponseWriter, r *http.Request, op string) {
+	actor := middleware.GetPrincipal(r.Context())
+	var (
+		result *models.BulkResult
+		err    error
+	)
+	switch op {
+	case "line_items":
+		var req models.BulkLineItemRequest
+		if !s.decodeV1(w, r, &req) || !s.scopeBulkFilter(w, r, &req.Filter.AdvertiserID) {
+			return
+		}
+		result, err = s.bulk.UpdateLineItems(r.Context(), actor, &req)
+	case "campaigns":
+		var req models.BulkCampaignRequest
+		if !s.decodeV1(w, r, &req) || !s.scopeBulkFilter(w, r, &req.Filter.AdvertiserID) {
+			return
+		}
+		result, err = s.bulk.UpdateCampaigns(r.Context(), actor, &req)
+	case "creatives":
+		var req models.BulkCreativeRequest
+		if !s.decodeV1(w, r, &req) || !s.scopeBulkFilter(w, r, &req.Filter.AdvertiserID) {
+			return
+		}
+		result, err = s.bulk.UpdateCreatives(r.Context(), actor, &req)
+	}
+	s.bulkResponse(w, result, err)
+}
+
+// handleV1CampaignExport exports the campaigns matching the query as a
+// campaign sheet (format=csv, the default, or xlsx). Filters are
+// campaign_id, status (each comma-separated), advertiser_id and q.
+func (s *Server) handleV1CampaignExport(w http.ResponseWriter, r *http.Request) {
+	format := models.ReportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = models.ReportFormatCSV
+	}
+	if format != models.ReportFormatCSV && format != models.ReportFormatXLSX {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "format must be csv or xlsx", nil)
+		return
+	}
+
+	filter := models.CampaignSelector{
+		CampaignIDs:  splitQuery(r.URL.Query().Get("campaign_id")),
+		AdvertiserID: r.URL.Query().Get("advertiser_id"),
+		NameContains: r.URL.Query().Get("q"),
+	}
+	for _, st := range splitQuery(r.URL.Query().Get("status")) {
+		filter.Statuses = append(filter.Statuses, models.CampaignStatus(st))
+	}
+	if !s.scopeBulkFilter(w, r, &filter.AdvertiserID) {
+		return
+	}
+
+	rows, err := s.bulk.ExportCampaigns(r.Context(), filter)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to export: "+err.Error(), nil)
+		return
+	}
+	f, err := reports.ExportTable(dsp.CampaignSheetColumns, rows, format, "campaigns-"+time.Now().UTC().Format("20060102"))
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to export: "+err.Error(), nil)
+		return
+	}
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name))
+	w.Write(f.Data)
+}
+
+// handleV1CampaignImport imports a campaign sheet sent as the raw request
+// body. format is csv or xlsx, taken from the Content-Type when left out;
+// dry_run=true only reports the changes.
+func (s *Server) handleV1CampaignImport(w http.ResponseWriter, r *http.Request) {
+	format := models.ReportFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = models.ReportFormatCSV
+		if strings.Contains(r.Header.Get("Content-Type"), "spreadsheetml") {
+			format = models.ReportFormatXLSX
+		}
+	}
+	if format != models.ReportFormatCSV && format != models.ReportFormatXLSX {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, "format must be csv or xlsx", nil)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		s.v1Error(w, http.StatusRequestEntityTooLarge, v1InvalidRequest, "file too large or unreadable", nil)
+		return
+	}
+	rows, err := reports.ReadTable(data, format)
+	if err != nil {
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	scope := middleware.GetAdvertiserScope(r.Context())
+	result, err := s.bulk.ImportCampaigns(r.Context(), middleware.GetPrincipal(r.Context()), rows, scope, dryRun)
+	s.bulkResponse(w, result, err)
+}
+
+// scopeBulkFilter restricts a bulk filter to the advertiser of a scoped
+// API key, and responds 403 if it names another advertiser.
+func (s *Server) scopeBulkFilter(w http.ResponseWriter, r *http.Request, advertiserID *string) bool {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return true
+	}
+	if *advertiserID != "" && *advertiserID != scope {
+		s.v1Error(w, http.StatusForbidden, v1InvalidRequest, "advertiser not allowed for this API key", nil)
+		return false
+	}
+	*advertiserID = scope
+	return true
+}
+
+// bulkResponse writes a bulk result. Results with failed rows are 422, so
+// clients notice nothing was applied; the body still has every row.
+func (s *Server) bulkResponse(w http.ResponseWriter, result *models.BulkResult, err error) {
+	switch {
+	case errors.Is(err, dsp.ErrInvalidBulk):
+		s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+		return
+	case err != nil:
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "bulk operation failed: "+err.Error(), nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if result.Failed > 0 {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+// splitQuery splits a comma-separated query value, dropping empty parts.
+func splitQuery(v string) []string {
+	var out []string
+	for _, p := range strings.Split(v, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
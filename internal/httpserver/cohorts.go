@@ -0,0 +1,112 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+)
+
+// =============================================
+// Cohort Reports
+// =============================================
+//
+// Cohorts group installs by install date and report, for each day since
+// install, the revenue, ROAS, payers and retention of the installs.
+
+// handleCohortReport reports install cohorts by days since install.
+// GET takes query parameters, POST a JSON dsp.CohortFilter.
+func (s *Server) handleCohortReport(w http.ResponseWriter, r *http.Request) {
+	if s.reportingService == nil {
+		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var filter dsp.CohortFilter
+	switch r.Method {
+	case http.MethodGet:
+		f, err := parseCohortFilter(r.URL.Query())
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter = f
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	advertiserID, ok := s.scopeAdvertiserID(w, r, filter.AdvertiserID)
+	if !ok {
+		return
+	}
+	campaignIDs, ok := s.scopeCampaignIDs(w, r, filter.CampaignIDs)
+	if !ok {
+		return
+	}
+	filter.AdvertiserID, filter.CampaignIDs = advertiserID, campaignIDs
+	filter.Currency = s.reportCurrency(r.Context(), filter.Currency, filter.AdvertiserID)
+
+	result, err := s.reportingService.GetCohorts(r.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, dsp.ErrInvalidReport):
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, dsp.ErrReportsUnsupported):
+			s.errorResponse(w, err.Error(), http.StatusNotImplemented)
+		default:
+			s.errorResponse(w, "failed to run cohort report: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"currency":   currency.Normalize(filter.Currency),
+		"dimensions": result.Dimensions,
+		"days":       result.Days,
+		"rows":       result.Rows,
+	})
+}
+
+// parseCohortFilter reads a cohort filter from query parameters. Install
+// dates are days in timezone, end_date inclusive.
+func parseCohortFilter(q url.Values) (dsp.CohortFilter, error) {
+	// Dates, timezone and list parameters are shared with reports
+	base, err := parseReportFilter(q)
+	if err != nil {
+		return dsp.CohortFilter{}, err
+	}
+	filter := dsp.CohortFilter{
+		CampaignIDs:  base.CampaignIDs,
+		SourceIDs:    base.SourceIDs,
+		AdvertiserID: base.AdvertiserID,
+		StartDate:    base.StartDate,
+		EndDate:      base.EndDate,
+		Timezone:     base.Timezone,
+		GroupBy:      base.GroupBy,
+		Currency:     base.Currency,
+	}
+	if v := q.Get("retention_events"); v != "" {
+		filter.RetentionEvents = strings.Split(v, ",")
+	}
+	if v := q.Get("days"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filter, fmt.Errorf("invalid days")
+			}
+			filter.Days = append(filter.Days, n)
+		}
+	}
+	return filter, nil
+}
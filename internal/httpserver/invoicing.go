@@ -0,0 +1,184 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/radiusdt/vector-dsp/internal/invoicing"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// =============================================
+// Admin API - Invoicing
+// =============================================
+
+// handleInvoicingDocuments lists closing documents, filtered by
+// advertiser_id, period (YYYY-MM) and type. Keys restricted to an
+// advertiser only see that advertiser's documents.
+func (s *Server) handleInvoicingDocuments(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := storage.DocumentFilter{
+		AdvertiserID: q.Get("advertiser_id"),
+		Period:       q.Get("period"),
+		Type:         models.DocumentType(q.Get("type")),
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+		if filter.AdvertiserID != "" && filter.AdvertiserID != scope {
+			s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+			return
+		}
+		filter.AdvertiserID = scope
+	}
+
+	docs, err := s.invoicing.ListDocuments(r.Context(), filter)
+	if err != nil {
+		s.invoicingError(w, "failed to list documents", err)
+		return
+	}
+	s.jsonResponse(w, docs)
+}
+
+// handleInvoicingDocumentByID serves /api/invoicing/documents/{id} and
+// /api/invoicing/documents/{id}/download?format=pdf|xlsx.
+func (s *Server) handleInvoicingDocumentByID(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/invoicing/documents/"), "/")
+	if id == "" || (action != "" && action != "download") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	doc, err := s.invoicing.GetDocument(r.Context(), id)
+	if err != nil {
+		s.invoicingError(w, "failed to get document", err)
+		return
+	}
+	// Other advertisers' documents don't exist for scoped keys
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" && doc.AdvertiserID != scope {
+		http.NotFound(w, r)
+		return
+	}
+	if action == "" {
+		s.jsonResponse(w, doc)
+		return
+	}
+
+	format := models.DocumentFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = models.DocumentFormatPDF
+	}
+	f, err := s.invoicing.Render(doc, format)
+	if err != nil {
+		s.invoicingError(w, "failed to render document", err)
+		return
+	}
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name))
+	w.Write(f.Data)
+}
+
+// handleInvoicingGenerate generates or regenerates a period's documents
+// for one advertiser or, without advertiser_id, for all of them.
+func (s *Server) handleInvoicingGenerate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if middleware.GetAdvertiserScope(r.Context()) != "" {
+		s.errorResponse(w, "generating documents needs an unrestricted API key", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		Period       string `json:"period"`
+		AdvertiserID string `json:"advertiser_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	docs, err := s.invoicing.Generate(r.Context(), req.Period, req.AdvertiserID)
+	if err != nil {
+		s.invoicingError(w, "failed to generate documents", err)
+		return
+	}
+	s.jsonResponse(w, docs)
+}
+
+// handleInvoicingPeriods lists the closed periods.
+func (s *Server) handleInvoicingPeriods(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	periods, err := s.invoicing.ClosedPeriods(r.Context())
+	if err != nil {
+		s.invoicingError(w, "failed to list periods", err)
+		return
+	}
+	s.jsonResponse(w, periods)
+}
+
+// handleInvoicingPeriodByID serves POST /api/invoicing/periods/{period}/close,
+// which locks the period's documents.
+func (s *Server) handleInvoicingPeriodByID(w http.ResponseWriter, r *http.Request) {
+	period, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/invoicing/periods/"), "/")
+	if period == "" || action != "close" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if middleware.GetAdvertiserScope(r.Context()) != "" {
+		s.errorResponse(w, "closing periods needs an unrestricted API key", http.StatusForbidden)
+		return
+	}
+
+	var req struct {
+		ClosedBy string `json:"closed_by"`
+	}
+	// The body is optional
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		s.errorResponse(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	closed, err := s.invoicing.ClosePeriod(r.Context(), period, req.ClosedBy)
+	if err != nil {
+		s.invoicingError(w, "failed to close period", err)
+		return
+	}
+	s.jsonResponse(w, closed)
+}
+
+func (s *Server) invoicingError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, invoicing.ErrInvalidInvoicing):
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, invoicing.ErrDocumentNotFound), errors.Is(err, invoicing.ErrAdvertiserNotFound):
+		s.errorResponse(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, invoicing.ErrPeriodClosed):
+		s.errorResponse(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, invoicing.ErrPDFUnavailable):
+		s.errorResponse(w, err.Error(), http.StatusNotImplemented)
+	default:
+		s.errorResponse(w, msg+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
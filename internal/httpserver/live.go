@@ -0,0 +1,90 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"go.uber.org/zap"
+)
+
+// =============================================
+// Live Stats
+// =============================================
+
+// handleLiveStats streams per-second campaign aggregates as Server-Sent
+// Events, one "stats" event per second. Keys restricted to an advertiser
+// only see that advertiser's campaigns.
+func (s *Server) handleLiveStats(w http.ResponseWriter, r *http.Request) {
+	if s.liveFeed == nil {
+		s.errorResponse(w, "live feed disabled", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := dsp.LiveFilter{AdvertiserID: q.Get("advertiser_id")}
+	if v := q.Get("campaign_id"); v != "" {
+		filter.CampaignIDs = strings.Split(v, ",")
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+		if filter.AdvertiserID != "" && filter.AdvertiserID != scope {
+			s.errorResponse(w, "advertiser not allowed for this API key", http.StatusForbidden)
+			return
+		}
+		filter.AdvertiserID = scope
+	}
+
+	sub, err := s.liveFeed.Subscribe()
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	defer s.liveFeed.Unsubscribe(sub)
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		s.errorResponse(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case frame, ok := <-sub.Frames:
+			if !ok {
+				return // Too slow, or shutting down
+			}
+			data, err := json.Marshal(filter.Apply(frame))
+			if err != nil {
+				s.logger.Error("failed to encode live frame", zap.Error(err))
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: stats\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// =============================================
+// API v1 - OpenAPI
+// =============================================
+
+// v1Operation describes one /api/v1 operation. The OpenAPI document is
+// built from v1Operations and the model types, so it can't drift from the
+// handlers' request and response bodies.
+type v1Operation struct {
+	Method   string
+	Path     string // OpenAPI path template
+	Summary  string
+	Request  interface{} // Body type, nil for none
+	Response interface{} // Body type, nil for 204; a []T is a page of T
+	Created  bool        // Responds 201
+	Query    []string    // List filters
+	Sorts    map[string]bool
+	ETag     bool // Takes If-Match (writes) or If-None-Match (reads)
+}
+
+var v1Operations = []v1Operation{
+	{Method: http.MethodGet, Path: "/api/v1/campaigns", Summary: "List campaigns",
+		Response: []models.Campaign{}, Query: []string{"status", "advertiser_id", "objective", "app_bundle", "q", "include_archived"},
+		Sorts: campaignSortFields},
+	{Method: http.MethodPost, Path: "/api/v1/campaigns", Summary: "Create a campaign",
+		Request: models.Campaign{}, Response: models.Campaign{}, Created: true},
+	{Method: http.MethodGet, Path: "/api/v1/campaigns/{id}", Summary: "Get a campaign",
+		Response: models.Campaign{}, ETag: true},
+	{Method: http.MethodPut, Path: "/api/v1/campaigns/{id}", Summary: "Replace a campaign",
+		Request: models.Campaign{}, Response: models.Campaign{}, ETag: true},
+	{Method: http.MethodPatch, Path: "/api/v1/campaigns/{id}", Summary: "Update a campaign (JSON Merge Patch)",
+		Request: models.Campaign{}, Response: models.Campaign{}, ETag: true},
+	{Method: http.MethodDelete, Path: "/api/v1/campaigns/{id}", Summary: "Archive a campaign",
+		ETag: true},
+	{Method: http.MethodGet, Path: "/api/v1/campaigns/{id}/line_items", Summary: "List the line items of a campaign",
+		Response: []models.LineItem{}, Query: []string{"is_active", "optimization_goal"}, Sorts: lineItemSortFields},
+	{Method: http.MethodPost, Path: "/api/v1/campaigns/{id}/line_items", Summary: "Add a line item",
+		Request: models.LineItem{}, Response: models.LineItem{}, Created: true, ETag: true},
+	{Method: http.MethodGet, Path: "/api/v1/campaigns/{id}/line_items/{line_item_id}", Summary: "Get a line item",
+		Response: models.LineItem{}, ETag: true},
+	{Method: http.MethodPut, Path: "/api/v1/campaigns/{id}/line_items/{line_item_id}", Summary: "Replace a line item",
+		Request: models.LineItem{}, Response: models.LineItem{}, ETag: true},
+	{Method: http.MethodPatch, Path: "/api/v1/campaigns/{id}/line_items/{line_item_id}", Summary: "Update a line item (JSON Merge Patch)",
+		Request: models.LineItem{}, Response: models.LineItem{}, ETag: true},
+	{Method: http.MethodDelete, Path: "/api/v1/campaigns/{id}/line_items/{line_item_id}", Summary: "Remove a line item",
+		ETag: true},
+	{Method: http.MethodGet, Path: "/api/v1/campaigns/{id}/transitions", Summary: "List the status changes of a campaign",
+		Response: []models.CampaignTransition{}, Query: []string{"reason", "limit"}},
+	{Method: http.MethodPost, Path: "/api/v1/bulk/line_items", Summary: "Change the selected line items",
+		Request: models.BulkLineItemRequest{}, Response: models.BulkResult{}},
+	{Method: http.MethodPost, Path: "/api/v1/bulk/campaigns", Summary: "Change the selected campaigns",
+		Request: models.BulkCampaignRequest{}, Response: models.BulkResult{}},
+	{Method: http.MethodPost, Path: "/api/v1/bulk/creatives", Summary: "Change the selected creatives",
+		Request: models.BulkCreativeRequest{}, Response: models.BulkResult{}},
+	{Method: http.MethodGet, Path: "/api/v1/rules", Summary: "List automation rules",
+		Response: []models.AutomationRule{}, Query: []string{"advertiser_id", "enabled", "scope"}, Sorts: ruleSortFields},
+	{Method: http.MethodPost, Path: "/api/v1/rules", Summary: "Create an automation rule",
+		Request: models.AutomationRule{}, Response: models.AutomationRule{}, Created: true, ETag: true},
+	{Method: http.MethodPost, Path: "/api/v1/rules/evaluate", Summary: "Dry run an unsaved automation rule",
+		Request: models.AutomationRule{}, Response: models.RuleEvaluation{}},
+	{Method: http.MethodGet, Path: "/api/v1/rules/firings", Summary: "List the actions taken by automation rules",
+		Response: []models.RuleFiring{}, Query: []string{"campaign_id", "line_item_id", "scope_value", "from", "to", "limit"}},
+	{Method: http.MethodGet, Path: "/api/v1/rules/{id}", Summary: "Get an automation rule",
+		Response: models.AutomationRule{}, ETag: true},
+	{Method: http.MethodPut, Path: "/api/v1/rules/{id}", Summary: "Replace an automation rule",
+		Request: models.AutomationRule{}, Response: models.AutomationRule{}, ETag: true},
+	{Method: http.MethodPatch, Path: "/api/v1/rules/{id}", Summary: "Update an automation rule (JSON Merge Patch)",
+		Request: models.AutomationRule{}, Response: models.AutomationRule{}, ETag: true},
+	{Method: http.MethodDelete, Path: "/api/v1/rules/{id}", Summary: "Delete an automation rule",
+		ETag: true},
+	{Method: http.MethodPost, Path: "/api/v1/rules/{id}/evaluate", Summary: "Evaluate an automation rule now (a dry run unless dry_run=false)",
+		Response: models.RuleEvaluation{}, Query: []string{"dry_run"}},
+	{Method: http.MethodGet, Path: "/api/v1/rules/{id}/firings", Summary: "List the actions taken by an automation rule",
+		Response: []models.RuleFiring{}, Query: []string{"campaign_id", "line_item_id", "scope_value", "from", "to", "limit"}},
+	{Method: http.MethodGet, Path: "/api/v1/alerts", Summary: "List alerts, newest first",
+		Response: []models.Alert{}, Query: []string{"status", "type", "definition_id", "campaign_id", "from", "to", "limit"}},
+	{Method: http.MethodGet, Path: "/api/v1/alerts/{id}", Summary: "Get an alert",
+		Response: models.Alert{}},
+	{Method: http.MethodPost, Path: "/api/v1/alerts/{id}/snooze", Summary: "Snooze the notifications of an alert",
+		Request: alertSnoozeRequest{}, Response: models.Alert{}},
+	{Method: http.MethodDelete, Path: "/api/v1/alerts/{id}/snooze", Summary: "Unsnooze the notifications of an alert",
+		Response: models.Alert{}},
+	{Method: http.MethodGet, Path: "/api/v1/alerts/definitions", Summary: "List alert definitions",
+		Response: []models.AlertDefinition{}, Query: []string{"type", "enabled"}, Sorts: alertDefinitionSortFields},
+	{Method: http.MethodPost, Path: "/api/v1/alerts/definitions", Summary: "Create an alert definition",
+		Request: models.AlertDefinition{}, Response: models.AlertDefinition{}, Created: true, ETag: true},
+	{Method: http.MethodGet, Path: "/api/v1/alerts/definitions/{id}", Summary: "Get an alert definition",
+		Response: models.AlertDefinition{}, ETag: true},
+	{Method: http.MethodPut, Path: "/api/v1/alerts/definitions/{id}", Summary: "Replace an alert definition",
+		Request: models.AlertDefinition{}, Response: models.AlertDefinition{}, ETag: true},
+	{Method: http.MethodPatch, Path: "/api/v1/alerts/definitions/{id}", Summary: "Update an alert definition (JSON Merge Patch)",
+		Request: models.AlertDefinition{}, Response: models.AlertDefinition{}, ETag: true},
+	{Method: http.MethodDelete, Path: "/api/v1/alerts/definitions/{id}", Summary: "Delete an alert definition (built-in ones can only be disabled)",
+		ETag: true},
+	{Method: http.MethodPost, Path: "/api/v1/alerts/definitions/{id}/test", Summary: "Send a test notification to the channels of an alert definition",
+		Response: map[string]string{}},
+	{Method: http.MethodPost, Path: "/api/v1/campaigns/{id}/clone", Summary: "Clone a campaign as a draft with new IDs",
+		Request: models.CloneRequest{}, Response: models.Campaign{}, Created: true},
+	{Method: http.MethodPost, Path: "/api/v1/campaigns/{id}/line_items/{line_item_id}/clone", Summary: "Clone a line item (inactive) into its campaign or campaign_id",
+		Request: models.CloneRequest{}, Response: models.LineItem{}, Created: true},
+	{Method: http.MethodPost, Path: "/api/v1/creatives/{id}/clone", Summary: "Clone a creative",
+		Request: models.CloneRequest{}, Response: models.Creative{}, Created: true},
+	{Method: http.MethodGet, Path: "/api/v1/campaigns/{id}/draft", Summary: "Get the draft of a campaign",
+		Response: models.CampaignDraft{}, ETag: true},
+	{Method: http.MethodPut, Path: "/api/v1/campaigns/{id}/draft", Summary: "Stage a version of a campaign",
+		Request: models.Campaign{}, Response: models.CampaignDraft{}, ETag: true},
+	{Method: http.MethodPatch, Path: "/api/v1/campaigns/{id}/draft", Summary: "Stage changes of a campaign (JSON Merge Patch of the draft or the live campaign)",
+		Request: models.Campaign{}, Response: models.CampaignDraft{}, ETag: true},
+	{Method: http.MethodDelete, Path: "/api/v1/campaigns/{id}/draft", Summary: "Discard the draft of a campaign",
+		ETag: true},
+	{Method: http.MethodPost, Path: "/api/v1/campaigns/{id}/draft/publish", Summary: "Make the draft of a campaign live",
+		Response: models.Campaign{}, Query: []string{"force"}, ETag: true},
+	{Method: http.MethodGet, Path: "/api/v1/drafts", Summary: "List campaign drafts",
+		Response: []models.CampaignDraft{}, Query: []string{"advertiser_id"}},
+	{Method: http.MethodGet, Path: "/api/v1/templates", Summary: "List campaign templates",
+		Response: []models.CampaignTemplate{}, Query: []string{"advertiser_id"}, Sorts: templateSortFields},
+	{Method: http.MethodPost, Path: "/api/v1/templates", Summary: "Create a campaign template (source_campaign_id copies a campaign)",
+		Request: models.CampaignTemplate{}, Response: models.CampaignTemplate{}, Created: true, ETag: true},
+	{Method: http.MethodGet, Path: "/api/v1/templates/{id}", Summary: "Get a campaign template",
+		Response: models.CampaignTemplate{}, ETag: true},
+	{Method: http.MethodPut, Path: "/api/v1/templates/{id}", Summary: "Replace a campaign template",
+		Request: models.CampaignTemplate{}, Response: models.CampaignTemplate{}, ETag: true},
+	{Method: http.MethodPatch, Path: "/api/v1/templates/{id}", Summary: "Update a campaign template (JSON Merge Patch)",
+		Request: models.CampaignTemplate{}, Response: models.CampaignTemplate{}, ETag: true},
+	{Method: http.MethodDelete, Path: "/api/v1/templates/{id}", Summary: "Delete a campaign template",
+		ETag: true},
+	{Method: http.MethodPost, Path: "/api/v1/templates/{id}/campaigns", Summary: "Create a draft campaign from a template",
+		Request: models.CloneRequest{}, Response: models.Campaign{}, Created: true},
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+)
+
+// handleOpenAPI serves the OpenAPI 3 document of /api/v1.
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	openAPIOnce.Do(func() {
+		openAPIDoc, _ = json.MarshalIndent(buildOpenAPI(v1Operations), "", "  ")
+	})
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openAPIDoc)
+}
+
+// buildOpenAPI returns the OpenAPI document of ops.
+func buildOpenAPI(ops []v1Operation) map[string]interface{} {
+	g := &schemaGen{schemas: make(map[string]interface{})}
+	g.schemas["Error"] = g.schema(reflect.TypeOf(v1ErrorBody{}))
+
+	paths := make(map[string]map[string]interface{})
+	for _, op := range ops {
+		item := paths[op.Path]
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+
+		params := make([]interface{}, 0)
+		for _, name := range pathParams(op.Path) {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "path", "required": true, "schema": map[string]string{"type": "string"},
+			})
+		}
+		for _, name := range op.Query {
+			params = append(params, map[string]interface{}{
+				"name": name, "in": "query", "schema": map[string]string{"type": "string"},
+			})
+		}
+		if op.Sorts != nil {
+			sorts := make([]string, 0, 2*len(op.Sorts))
+			for f := range op.Sorts {
+				sorts = append(sorts, f, "-"+f)
+			}
+			sort.Strings(sorts)
+			params = append(params,
+				map[string]interface{}{"name": "sort", "in": "query", "schema": map[string]interface{}{"type": "string", "enum": sorts}},
+				map[string]interface{}{"name": "limit", "in": "query", "schema": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": v1MaxLimit, "default": v1DefaultLimit}},
+				map[string]interface{}{"name": "cursor", "in": "query", "schema": map[string]string{"type": "string"}},
+			)
+		}
+		if op.ETag {
+			header := "If-Match"
+			if op.Method == http.MethodGet {
+				header = "If-None-Match"
+			}
+			params = append(params, map[string]interface{}{
+				"name": header, "in": "header", "schema": map[string]string{"type": "string"},
+			})
+		}
+
+		responses := map[string]interface{}{
+			"default": jsonContent("Error", map[string]string{"$ref": "#/components/schemas/Error"}),
+		}
+		switch {
+		case op.Response == nil:
+			responses["204"] = map[string]string{"description": "No Content"}
+		case reflect.TypeOf(op.Response).Kind() == reflect.Slice:
+			elem := g.schema(reflect.TypeOf(op.Response).Elem())
+			responses["200"] = jsonContent("Page", map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"data":        map[string]interface{}{"type": "array", "items": elem},
+					"next_cursor": map[string]string{"type": "string"},
+				},
+			})
+		case op.Created:
+			responses["201"] = jsonContent("Created", g.schema(reflect.TypeOf(op.Response)))
+		default:
+			responses["200"] = jsonContent("OK", g.schema(reflect.TypeOf(op.Response)))
+		}
+
+		operation := map[string]interface{}{
+			"summary":    op.Summary,
+			"parameters": params,
+			"responses":  responses,
+		}
+		if op.Request != nil {
+			contentType := "application/json"
+			if op.Method == http.MethodPatch {
+				contentType = "application/merge-patch+json"
+			}
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					contentType: map[string]interface{}{"schema": g.schema(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info":    map[string]string{"title": "Vector DSP API", "version": "v1"},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": g.schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiKey":     map[string]string{"type": "apiKey", "in": "header", "name": "X-API-Key"},
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+		"security": []interface{}{
+			map[string][]string{"apiKey": {}},
+			map[string][]string{"bearerAuth": {}},
+		},
+	}
+}
+
+// jsonContent returns a response with a JSON body of schema.
+func jsonContent(description string, schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+// pathParams returns the {names} of a path template.
+func pathParams(path string) []string {
+	var names []string
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			names = append(names, seg[1:len(seg)-1])
+		}
+	}
+	return names
+}
+
+// schemaGen derives JSON schemas from Go types by their JSON encoding.
+// Named structs become component schemas referenced by name.
+type schemaGen struct {
+	schemas map[string]interface{}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *schemaGen) schema(t reflect.Type) interface{} {
+	switch t {
+	case timeType:
+		return map[string]string{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if m, ok := s.(map[string]string); ok && m["$ref"] == "" {
+			n := map[string]interface{}{"nullable": true}
+			for k, v := range m {
+				n[k] = v
+			}
+			return n
+		}
+		return s
+	case reflect.Bool:
+		return map[string]string{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]string{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64:
+		return map[string]string{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]string{"type": "number"}
+	case reflect.String:
+		return map[string]string{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]string{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+		name := t.Name()
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = nil // Placeholder for recursive types
+			g.schemas[name] = g.object(t)
+		}
+		return map[string]string{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// object returns the schema of a struct's JSON object.
+func (g *schemaGen) object(t reflect.Type) map[string]interface{} {
+	props := make(map[string]interface{})
+	g.addFields(t, props)
+	return map[string]interface{}{"type": "object", "properties": props}
+}
+
+// addFields adds the JSON fields of struct t, including those of embedded
+// structs, to props.
+func (g *schemaGen) addFields(t reflect.Type, props map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" || (!f.IsExported() && !f.Anonymous) {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			g.addFields(f.Type, props)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		props[name] = g.schema(f.Type)
+	}
+}
@@ -0,0 +1,276 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/reports"
+)
+
+// =============================================
+// Scheduled Reports
+// =============================================
+//
+// A scheduled report runs a saved report filter daily or weekly and
+// delivers the export (CSV, XLSX or JSON) to its delivery target. Keys
+// restricted to an advertiser only manage that advertiser's reports.
+
+func (s *Server) handleScheduledReports(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		advertiserID, ok := s.scopeAdvertiserID(w, r, r.URL.Query().Get("advertiser_id"))
+		if !ok {
+			return
+		}
+		list, err := s.scheduledReports.List(r.Context(), advertiserID)
+		if err != nil {
+			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
+			return
+		}
+		s.jsonResponse(w, list)
+
+	case http.MethodPost:
+		var report models.ScheduledReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if report.ID == "" {
+			report.ID = uuid.New().String()
+		} else if !s.allowScheduledReport(w, r, report.ID) {
+			return
+		}
+		s.saveScheduledReport(w, r, &report)
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleScheduledReportByID serves /api/scheduled-reports/{id} and its
+// /run, /runs and /export actions.
+func (s *Server) handleScheduledReportByID(w http.ResponseWriter, r *http.Request) {
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/scheduled-reports/"), "/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.allowScheduledReport(w, r, id) {
+		return
+	}
+
+	switch action {
+	case "":
+	case "run":
+		s.handleScheduledReportRun(w, r, id)
+		return
+	case "runs":
+		s.handleScheduledReportRuns(w, r, id)
+		return
+	case "export":
+		s.handleScheduledReportExport(w, r, id)
+		return
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		report, err := s.scheduledReports.GetByID(r.Context(), id)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if report == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.jsonResponse(w, report)
+
+	case http.MethodPut:
+		var report models.ScheduledReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		report.ID = id
+		existing, err := s.scheduledReports.GetByID(r.Context(), id)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			report.LastRunAt = existing.LastRunAt
+		}
+		s.saveScheduledReport(w, r, &report)
+
+	case http.MethodDelete:
+		if err := s.scheduledReports.Delete(r.Context(), id); err != nil {
+			s.errorResponse(w, "failed to delete: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// allowScheduledReport responds 403 if the report belongs to another
+// advertiser than the request key's. Unknown reports pass.
+func (s *Server) allowScheduledReport(w http.ResponseWriter, r *http.Request, id string) bool {
+	if middleware.GetAdvertiserScope(r.Context()) == "" {
+		return true
+	}
+	report, err := s.scheduledReports.GetByID(r.Context(), id)
+	if err != nil {
+		s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return report == nil || s.allowAdvertiser(w, r, report.AdvertiserID)
+}
+
+// saveScheduledReport validates the report and its filter, schedules the
+// next run and stores it.
+//
+// Reports saved with a key restricted to an advertiser belong to it, are
+// delivered by webhook or email only, and their filter is limited to the
+// advertiser's campaigns at the time of saving.
+func (s *Server) saveScheduledReport(w http.ResponseWriter, r *http.Request, report *models.ScheduledReport) {
+	if err := report.Validate(); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+		if report.AdvertiserID == "" {
+			report.AdvertiserID = scope
+		}
+		if !s.allowAdvertiser(w, r, report.AdvertiserID) {
+			return
+		}
+		switch report.Delivery.Type {
+		case models.ReportDeliveryWebhook, models.ReportDeliverySMTP:
+		default:
+			s.errorResponse(w, "delivery type not allowed for this API key", http.StatusForbidden)
+			return
+		}
+
+		var filter dsp.ReportFilter
+		if len(report.Filter) > 0 {
+			if err := json.Unmarshal(report.Filter, &filter); err != nil {
+				s.errorResponse(w, "invalid filter: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if !s.scopeReportFilter(w, r, &filter) {
+			return
+		}
+		data, err := json.Marshal(filter)
+		if err != nil {
+			s.errorResponse(w, "failed to encode filter", http.StatusInternalServerError)
+			return
+		}
+		report.Filter = data
+	}
+	if err := s.reportScheduler.ValidateFilter(report); err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.reportScheduler.Schedule(r.Context(), report, time.Now())
+
+	if err := s.scheduledReports.Upsert(r.Context(), report); err != nil {
+		s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.jsonResponse(w, report)
+}
+
+// handleScheduledReportRun runs a report now and returns the run.
+func (s *Server) handleScheduledReportRun(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	run, err := s.reportScheduler.RunNow(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, reports.ErrReportNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		s.errorResponse(w, "failed to run: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, run)
+}
+
+// handleScheduledReportRuns returns the run history, newest first.
+func (s *Server) handleScheduledReportRuns(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.errorResponse(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := s.scheduledReports.ListRuns(r.Context(), id, limit)
+	if err != nil {
+		s.errorResponse(w, "failed to list runs", http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, runs)
+}
+
+// handleScheduledReportExport downloads the report's current period,
+// optionally in another ?format=.
+func (s *Server) handleScheduledReportExport(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report, err := s.scheduledReports.GetByID(r.Context(), id)
+	if err != nil {
+		s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if report == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	f, err := s.reportScheduler.Export(r.Context(), report, models.ReportFormat(r.URL.Query().Get("format")))
+	if err != nil {
+		switch {
+		case errors.Is(err, dsp.ErrInvalidReport):
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, dsp.ErrReportsUnsupported):
+			s.errorResponse(w, err.Error(), http.StatusNotImplemented)
+		default:
+			s.errorResponse(w, "failed to export: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", f.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name))
+	w.Write(f.Data)
+}
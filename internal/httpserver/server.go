@@ -3,20 +3,29 @@ package httpserver
 import (
 	"context"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/currency"
 	"github.com/radiusdt/vector-dsp/internal/database"
 	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/fraud"
+	"github.com/radiusdt/vector-dsp/internal/invoicing"
 	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
 	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/reports"
 	"github.com/radiusdt/vector-dsp/internal/storage"
 	"github.com/radiusdt/vector-dsp/internal/targeting"
 	"go.uber.org/zap"
@@ -29,13 +38,45 @@ type Dependencies struct {
 	Config  *config.Config
 	Logger  *zap.Logger
 	Metrics *metrics.Metrics
+
+	// EventStore overrides the event store (ClickHouse); the caller closes it
+	EventStore storage.BufferedEventStore
+
+	// APIKeys overrides the API key service; the caller runs it and passes
+	// it to the auth middleware
+	APIKeys *dsp.APIKeyService
+
+	// Users overrides the user service; the caller passes it to the auth
+	// middleware
+	Users *dsp.UserService
+
+	// Context stops background workers such as the report scheduler;
+	// they don't run without it
+	Context context.Context
+
+	// Workers, if set, counts running background workers. Once Context is
+	// cancelled, waiting on it waits for their final flushes (billing
+	// debits, API key last use).
+	Workers *sync.WaitGroup
+}
+
+// startWorker runs a background worker until Context is cancelled.
+func (deps *Dependencies) startWorker(run func(ctx context.Context)) {
+	if deps.Workers != nil {
+		deps.Workers.Add(1)
+	}
+	go func() {
+		if deps.Workers != nil {
+			defer deps.Workers.Done()
+		}
+		run(deps.Context)
+	}()
 }
 
 // Server wraps HTTP handlers and DSP services.
 type Server struct {
 	campaignService   *dsp.CampaignService
 	bidService        *dsp.BidService
-	eventService      *dsp.EventService
 	advertiserService *dsp.AdvertiserService
 	adGroupService    *dsp.AdGroupService
 	creativeService   *dsp.CreativeService
@@ -44,6 +85,26 @@ type Server struct {
 	pacingEngine      dsp.PacingEngine
 	trackingService   *dsp.TrackingService
 	postbackHandler   *dsp.PostbackHandler
+	payoutEngine      *dsp.PayoutEngine
+	s2sAdService      *dsp.S2SAdService
+	converter         *currency.Converter
+	fraudScorer       *fraud.Scorer
+	bidSampler        *dsp.BidSampler
+	scheduledReports  storage.ScheduledReportRepo
+	reportScheduler   *reports.Scheduler
+	dailyStats        *dsp.DailyStatsService
+	liveFeed          *dsp.LiveFeed
+	billing           *dsp.BillingService
+	invoicing         *invoicing.Service
+	apiKeys           *dsp.APIKeyService
+	users             *dsp.UserService
+	auditLog          *dsp.AuditService
+	bulk              *dsp.BulkService
+	lifecycle         *dsp.LifecycleService
+	automation        *dsp.AutomationService
+	alerts            *dsp.AlertService
+	postbackMonitor   *dsp.PostbackMonitor
+	templates         *dsp.TemplateService
 	logger            *zap.Logger
 	config            *config.Config
 	metrics           *metrics.Metrics
@@ -54,23 +115,32 @@ func NewServer(deps *Dependencies) http.Handler {
 	// Initialize repositories
 	var cRepo storage.CampaignRepo
 	var advRepo storage.AdvertiserRepo
+	var agRepo storage.AdGroupRepo
+	var crRepo storage.CreativeRepo
 	var eventStore storage.EventStore
 	var sourceRepo storage.SourceRepo
+	var payoutRuleRepo storage.PayoutRuleRepo
 
 	if deps.DB != nil {
 		cRepo = storage.NewPostgresCampaignRepo(deps.DB.Pool)
 		advRepo = storage.NewPostgresAdvertiserRepo(deps.DB.Pool)
+		agRepo = storage.NewPostgresAdGroupRepo(deps.DB.Pool)
+		crRepo = storage.NewPostgresCreativeRepo(deps.DB.Pool)
 		eventStore = storage.NewPostgresEventStore(deps.DB.Pool)
 		sourceRepo = storage.NewPostgresSourceRepo(deps.DB.Pool)
+		payoutRuleRepo = storage.NewPostgresPayoutRuleRepo(deps.DB.Pool)
 	} else {
 		cRepo = storage.NewInMemoryCampaignRepo()
 		advRepo = storage.NewInMemoryAdvertiserRepo()
+		agRepo = storage.NewInMemoryAdGroupRepo()
+		crRepo = storage.NewInMemoryCreativeRepo()
 		eventStore = storage.NewInMemoryEventStore()
 		sourceRepo = storage.NewInMemorySourceRepo()
+		payoutRuleRepo = storage.NewInMemoryPayoutRuleRepo()
+	}
+	if deps.EventStore != nil {
+		eventStore = deps.EventStore
 	}
-
-	agRepo := storage.NewInMemoryAdGroupRepo()
-	crRepo := storage.NewInMemoryCreativeRepo()
 
 	// Initialize pacing engine
 	var pacer dsp.PacingEngine
@@ -101,43 +171,266 @@ func NewServer(deps *Dependencies) http.Handler {
 		targetingEngine = targeting.NewTargetingEngine(nil, 1000, time.Hour, deps.Metrics)
 	}
 
+	// Initialize exchange rates
+	converter := currency.NewConverter(newRateProvider(deps.Config.Currency, deps.Logger), deps.Logger)
+
 	// Initialize services
 	cSvc := dsp.NewCampaignService(cRepo)
 	bSvc := dsp.NewBidService(cRepo, pacer, targetingEngine, deps.Metrics, deps.Config.Tracking.BaseURL)
-	eSvc := dsp.NewEventService(eventStore)
 	advSvc := dsp.NewAdvertiserService(advRepo)
 	agSvc := dsp.NewAdGroupService(agRepo)
 	crSvc := dsp.NewCreativeService(crRepo)
 	srcSvc := dsp.NewSourceService(sourceRepo)
 
+	// Bid request sampling; samples go to the event store when it keeps them
+	var sampleStore storage.BidSampleStore = storage.NewInMemoryBidSampleStore(deps.Config.Sampling.BufferSize)
+	if bs, ok := eventStore.(storage.BidSampleStore); ok {
+		sampleStore = bs
+	}
+	bidSampler, err := dsp.NewBidSampler(sampleStore, dsp.BidSamplingConfig{
+		Rate:        deps.Config.Sampling.BidRate,
+		SourceIDs:   deps.Config.Sampling.BidSources,
+		LineItemIDs: deps.Config.Sampling.BidLineItems,
+	})
+	if err != nil {
+		deps.Logger.Warn("bid sampling disabled", zap.Error(err))
+	} else {
+		bSvc.SetSampler(bidSampler)
+	}
+
+	payoutEngine := dsp.NewPayoutEngine(payoutRuleRepo, sourceRepo, cRepo, converter)
+	payoutEngine.SetAdvertiserRepo(advRepo)
+
+	// Initialize fraud scoring
+	var fraudScorer *fraud.Scorer
+	if deps.Config.Fraud.Enabled {
+		fraudScorer = newFraudScorer(deps)
+	}
+
 	// Initialize tracking service
 	trackingSvc := dsp.NewTrackingService(
 		eventStore,
 		cRepo,
 		targetingEngine,
+		converter,
+		fraudScorer,
 		deps.Config.Tracking.BaseURL,
 		deps.Logger,
 		deps.Metrics,
 	)
+	if deps.Config.Tracking.LinkSecret != "" {
+		trackingSvc.SetLinkSigner(dsp.NewLinkSigner(deps.Config.Tracking.LinkSecret, deps.Config.Tracking.LinkTTL))
+	} else {
+		deps.Logger.Warn("VECTOR_DSP_TRACKING_LINK_SECRET not set, click links are not signed")
+	}
+	if deps.Config.Tracking.EnableClickDedup {
+		var deduper dsp.ClickDeduper
+		if deps.Redis != nil {
+			deduper = dsp.NewRedisClickDeduper(deps.Redis.Client)
+		} else {
+			deduper = dsp.NewInMemoryClickDeduper()
+		}
+		trackingSvc.SetClickDeduper(deduper, deps.Config.Tracking.ClickDedupWindow)
+	}
 
 	// Initialize postback handler
 	postbackHandler := dsp.NewPostbackHandler(
 		eventStore,
 		sourceRepo,
 		cRepo,
+		advRepo,
+		payoutEngine,
+		converter,
+		fraudScorer,
 		deps.Logger,
 		deps.Metrics,
 	)
 
-	var reportingSvc *dsp.ReportingService
+	// Source daily caps and S2S offer selection
+	var sourceCaps dsp.SourceCapTracker
 	if deps.Redis != nil {
-		reportingSvc = dsp.NewReportingService(eventStore, deps.Redis.Client)
+		sourceCaps = dsp.NewRedisSourceCapTracker(deps.Redis.Client)
+	} else {
+		sourceCaps = dsp.NewInMemorySourceCapTracker()
+	}
+	trackingSvc.SetSourceCaps(sourceCaps)
+	postbackHandler.SetSourceCaps(sourceCaps)
+	// Live dashboard feed
+	var liveFeed *dsp.LiveFeed
+	if deps.Config.Live.Enabled && deps.Context != nil {
+		liveFeed = dsp.NewLiveFeed(cRepo, deps.Config.Live, deps.Logger, deps.Metrics)
+		bSvc.SetLiveFeed(liveFeed)
+		trackingSvc.SetLiveFeed(liveFeed)
+		postbackHandler.SetLiveFeed(liveFeed)
+		deps.startWorker(liveFeed.Run)
+	}
+
+	// Billing ledger
+	var billingRepo storage.BillingRepo
+	if deps.DB != nil {
+		billingRepo = storage.NewPostgresBillingRepo(deps.DB.Pool)
+	} else {
+		billingRepo = storage.NewInMemoryBillingRepo(advRepo)
+	}
+	billing := dsp.NewBillingService(billingRepo, advSvc, cRepo, converter, deps.Config.Billing, deps.Logger, deps.Metrics)
+	if deps.Config.Billing.Enabled && deps.Context != nil {
+		bSvc.SetBilling(billing)
+		trackingSvc.SetBilling(billing)
+		postbackHandler.SetBilling(billing)
+		deps.startWorker(billing.Run)
+	}
+
+	// Wins and conversions without an exchange rate wait for one
+	if deps.Context != nil {
+		var unpricedRepo storage.UnpricedEventRepo
+		if deps.DB != nil {
+			unpricedRepo = storage.NewPostgresUnpricedEventRepo(deps.DB.Pool)
+		} else {
+			unpricedRepo = storage.NewInMemoryUnpricedEventRepo()
+		}
+		trackingSvc.SetUnpricedEvents(unpricedRepo)
+		postbackHandler.SetUnpricedEvents(unpricedRepo)
+		repricer := dsp.NewRepricer(unpricedRepo, converter, trackingSvc, postbackHandler, deps.Config.Currency.RepriceInterval, deps.Logger)
+		deps.startWorker(repricer.Run)
+	}
+
+	// Closing documents
+	var documentRepo storage.BillingDocumentRepo
+	if deps.DB != nil {
+		documentRepo = storage.NewPostgresBillingDocumentRepo(deps.DB.Pool)
+	} else {
+		documentRepo = storage.NewInMemoryBillingDocumentRepo()
+	}
+	invoicingSvc := invoicing.NewService(documentRepo, billingRepo, advSvc, deps.Config.Invoicing, deps.Logger, deps.Metrics)
+	if deps.Config.Invoicing.Enabled && deps.Context != nil {
+		deps.startWorker(invoicingSvc.Run)
+	}
+
+	// API keys
+	apiKeys := deps.APIKeys
+	if apiKeys == nil {
+		var apiKeyRepo storage.APIKeyRepo
+		if deps.DB != nil {
+			apiKeyRepo = storage.NewPostgresAPIKeyRepo(deps.DB.Pool)
+		} else {
+			apiKeyRepo = storage.NewInMemoryAPIKeyRepo()
+		}
+		apiKeys = dsp.NewAPIKeyService(apiKeyRepo, advSvc, deps.Config.Auth, deps.Logger)
+		if deps.Context != nil {
+			deps.startWorker(apiKeys.Run)
+		}
+	}
+
+	// Users
+	users := deps.Users
+	if users == nil {
+		var userRepo storage.UserRepo
+		if deps.DB != nil {
+			userRepo = storage.NewPostgresUserRepo(deps.DB.Pool)
+		} else {
+			userRepo = storage.NewInMemoryUserRepo()
+		}
+		users = dsp.NewUserService(userRepo, advSvc, deps.Config.Auth, deps.Logger, deps.Metrics)
+	}
+
+	// Audit log
+	var auditRepo storage.AuditRepo
+	if deps.DB != nil {
+		auditRepo = storage.NewPostgresAuditRepo(deps.DB.Pool)
+	} else {
+		auditRepo = storage.NewInMemoryAuditRepo()
+	}
+	auditLog := dsp.NewAuditService(auditRepo, deps.Logger, deps.Metrics)
+
+	s2sAdSvc := dsp.NewS2SAdService(sourceRepo, pacer, targetingEngine, payoutEngine, trackingSvc, sourceCaps, deps.Metrics)
+
+	reportingSvc := dsp.NewReportingService(eventStore, converter)
+
+	// Scheduled reports
+	var scheduledReportRepo storage.ScheduledReportRepo
+	if deps.DB != nil {
+		scheduledReportRepo = storage.NewPostgresScheduledReportRepo(deps.DB.Pool)
+	} else {
+		scheduledReportRepo = storage.NewInMemoryScheduledReportRepo()
+	}
+	reportScheduler := reports.NewScheduler(
+		scheduledReportRepo,
+		reportingSvc,
+		advSvc,
+		reports.NewDeliverer(deps.Config.Reports),
+		deps.Config.Reports,
+		deps.Logger,
+		deps.Metrics,
+	)
+	if deps.Config.Reports.Enabled && deps.Context != nil {
+		deps.startWorker(reportScheduler.Run)
+	}
+
+	// Daily stats rollup
+	var statsRepo storage.StatsRepo
+	if deps.DB != nil {
+		statsRepo = storage.NewPostgresStatsRepo(deps.DB.Pool)
+	} else {
+		statsRepo = storage.NewInMemoryStatsRepo()
+	}
+	dailyStats := dsp.NewDailyStatsService(eventStore, statsRepo, reportingSvc, deps.Config.Stats, deps.Logger, deps.Metrics)
+	if deps.Config.Stats.RollupEnabled && deps.Context != nil {
+		deps.startWorker(dailyStats.Run)
+	}
+
+	// Campaign lifecycle
+	var transitionRepo storage.CampaignTransitionRepo
+	if deps.DB != nil {
+		transitionRepo = storage.NewPostgresTransitionRepo(deps.DB.Pool)
+	} else {
+		transitionRepo = storage.NewInMemoryTransitionRepo()
+	}
+	lifecycle := dsp.NewLifecycleService(cSvc, advSvc, billing, statsRepo, transitionRepo, auditLog, deps.Config.Lifecycle, deps.Logger, deps.Metrics)
+	if deps.Config.Lifecycle.Enabled && deps.Context != nil {
+		deps.startWorker(lifecycle.Run)
+	}
+	bulk := dsp.NewBulkService(cSvc, crSvc, auditLog, lifecycle, deps.Logger)
+
+	// Automation rules
+	var ruleRepo storage.AutomationRuleRepo
+	if deps.DB != nil {
+		ruleRepo = storage.NewPostgresAutomationRuleRepo(deps.DB.Pool)
+	} else {
+		ruleRepo = storage.NewInMemoryAutomationRuleRepo()
+	}
+	automation := dsp.NewAutomationService(ruleRepo, reportingSvc, cSvc, fraudScorer, auditLog, deps.Config.Automation, deps.Logger, deps.Metrics)
+	if deps.Config.Automation.Enabled && deps.Context != nil {
+		deps.startWorker(automation.Run)
+	}
+
+	// Delivery and health alerts
+	var alertRepo storage.AlertRepo
+	if deps.DB != nil {
+		alertRepo = storage.NewPostgresAlertRepo(deps.DB.Pool)
+	} else {
+		alertRepo = storage.NewInMemoryAlertRepo()
 	}
+	postbackMonitor := dsp.NewPostbackMonitor(deps.Metrics)
+	alerts := dsp.NewAlertService(alertRepo, cSvc, pacer, reportingSvc, statsRepo, fraudScorer, postbackMonitor, deps.Config.Alerts, deps.Logger, deps.Metrics)
+	if deps.Config.Alerts.Enabled && deps.Context != nil {
+		deps.startWorker(alerts.Run)
+	}
+
+	// Campaign templates and drafts
+	var templateRepo storage.CampaignTemplateRepo
+	var draftRepo storage.CampaignDraftRepo
+	if deps.DB != nil {
+		templateRepo = storage.NewPostgresCampaignTemplateRepo(deps.DB.Pool)
+		draftRepo = storage.NewPostgresCampaignDraftRepo(deps.DB.Pool)
+	} else {
+		templateRepo = storage.NewInMemoryCampaignTemplateRepo()
+		draftRepo = storage.NewInMemoryCampaignDraftRepo()
+	}
+	templates := dsp.NewTemplateService(templateRepo, draftRepo, cSvc, crSvc)
 
 	s := &Server{
 		campaignService:   cSvc,
 		bidService:        bSvc,
-		eventService:      eSvc,
 		advertiserService: advSvc,
 		adGroupService:    agSvc,
 		creativeService:   crSvc,
@@ -146,6 +439,26 @@ func NewServer(deps *Dependencies) http.Handler {
 		pacingEngine:      pacer,
 		trackingService:   trackingSvc,
 		postbackHandler:   postbackHandler,
+		payoutEngine:      payoutEngine,
+		s2sAdService:      s2sAdSvc,
+		converter:         converter,
+		fraudScorer:       fraudScorer,
+		bidSampler:        bidSampler,
+		scheduledReports:  scheduledReportRepo,
+		reportScheduler:   reportScheduler,
+		dailyStats:        dailyStats,
+		liveFeed:          liveFeed,
+		billing:           billing,
+		invoicing:         invoicingSvc,
+		apiKeys:           apiKeys,
+		users:             users,
+		auditLog:          auditLog,
+		bulk:              bulk,
+		lifecycle:         lifecycle,
+		automation:        automation,
+		alerts:            alerts,
+		postbackMonitor:   postbackMonitor,
+		templates:         templates,
 		logger:            deps.Logger,
 		config:            deps.Config,
 		metrics:           deps.Metrics,
@@ -194,12 +507,65 @@ func NewServer(deps *Dependencies) http.Handler {
 	mux.HandleFunc("/api/campaigns", s.handleCampaigns)
 	mux.HandleFunc("/api/campaigns/", s.handleCampaignByID)
 
+	// =============================================
+	// Admin API v1 (see api_v1.go)
+	// =============================================
+	mux.HandleFunc("/api/v1/campaigns", s.handleV1Campaigns)
+	mux.HandleFunc("/api/v1/campaigns/", s.handleV1CampaignByID)
+	mux.HandleFunc("/api/v1/bulk/", s.handleV1Bulk)
+	mux.HandleFunc("/api/v1/rules", s.handleV1Rules)
+	mux.HandleFunc("/api/v1/rules/", s.handleV1RuleByID)
+	mux.HandleFunc("/api/v1/alerts", s.handleV1Alerts)
+	mux.HandleFunc("/api/v1/alerts/", s.handleV1AlertByID)
+	mux.HandleFunc("/api/v1/templates", s.handleV1Templates)
+	mux.HandleFunc("/api/v1/templates/", s.handleV1TemplateByID)
+	mux.HandleFunc("/api/v1/drafts", s.handleV1Drafts)
+	mux.HandleFunc("/api/v1/creatives/", s.handleV1CreativeByID)
+	mux.HandleFunc("/api/v1/openapi.json", s.handleOpenAPI)
+
 	// =============================================
 	// Admin API - Advertisers
 	// =============================================
 	mux.HandleFunc("/api/advertisers", s.handleAdvertisers)
 	mux.HandleFunc("/api/advertisers/", s.handleAdvertiserByID)
 
+	// =============================================
+	// Admin API - Invoicing
+	// =============================================
+	mux.HandleFunc("/api/invoicing/documents", s.handleInvoicingDocuments)
+	mux.HandleFunc("/api/invoicing/documents/", s.handleInvoicingDocumentByID)
+	mux.HandleFunc("/api/invoicing/generate", s.handleInvoicingGenerate)
+	mux.HandleFunc("/api/invoicing/periods", s.handleInvoicingPeriods)
+	mux.HandleFunc("/api/invoicing/periods/", s.handleInvoicingPeriodByID)
+
+	// =============================================
+	// Admin API - API Keys
+	// =============================================
+	mux.HandleFunc("/api/api-keys", s.handleAPIKeys)
+	mux.HandleFunc("/api/api-keys/", s.handleAPIKeyByID)
+
+	// =============================================
+	// Auth API - User Sessions
+	// =============================================
+	mux.HandleFunc("/api/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/auth/refresh", s.handleRefresh)
+	mux.HandleFunc("/api/auth/logout", s.handleLogout)
+	mux.HandleFunc("/api/auth/me", s.handleMe)
+	mux.HandleFunc("/api/auth/password", s.handleChangePassword)
+	mux.HandleFunc("/api/auth/totp/", s.handleTOTP)
+
+	// =============================================
+	// Admin API - Users
+	// =============================================
+	mux.HandleFunc("/api/users", s.handleUsers)
+	mux.HandleFunc("/api/users/", s.handleUserByID)
+
+	// =============================================
+	// Admin API - Audit Log
+	// =============================================
+	mux.HandleFunc("/api/audit", s.handleAudit)
+	mux.HandleFunc("/api/audit/", s.handleAuditByID)
+
 	// =============================================
 	// Admin API - Sources
 	// =============================================
@@ -208,6 +574,13 @@ func NewServer(deps *Dependencies) http.Handler {
 	mux.HandleFunc("/api/sources/rtb", s.handleRTBSources)
 	mux.HandleFunc("/api/sources/rtb/", s.handleRTBSourceByID)
 
+	// =============================================
+	// Admin API - Payout Rules
+	// =============================================
+	mux.HandleFunc("/api/payout-rules", s.handlePayoutRules)
+	mux.HandleFunc("/api/payout-rules/preview", s.handlePayoutPreview)
+	mux.HandleFunc("/api/payout-rules/", s.handlePayoutRuleByID)
+
 	// =============================================
 	// Admin API - Ad Groups
 	// =============================================
@@ -224,13 +597,34 @@ func NewServer(deps *Dependencies) http.Handler {
 	// =============================================
 	// Reporting
 	// =============================================
+	mux.HandleFunc("/api/reports/query", s.handleReportQuery)
+	mux.HandleFunc("/api/reports/cohorts", s.handleCohortReport)
 	mux.HandleFunc("/api/reports/campaigns", s.handleCampaignReports)
 	mux.HandleFunc("/api/reports/sources", s.handleSourceReports)
 	mux.HandleFunc("/api/reports/geo", s.handleGeoReports)
 	mux.HandleFunc("/api/reports/time-series", s.handleTimeSeriesReport)
+	mux.HandleFunc("/api/reports/fraud", s.handleFraudReport)
+	mux.HandleFunc("/api/scheduled-reports", s.handleScheduledReports)
+	mux.HandleFunc("/api/scheduled-reports/", s.handleScheduledReportByID)
+
+	mux.HandleFunc("/api/exchange-rates", s.handleExchangeRates)
+
+	// =============================================
+	// Debugging - Bid Samples
+	// =============================================
+	mux.HandleFunc("/api/bid-samples", s.handleBidSamples)
+	mux.HandleFunc("/api/bid-samples/config", s.handleBidSamplingConfig)
+	mux.HandleFunc("/api/bid-samples/", s.handleBidSampleByID)
+	mux.HandleFunc("/api/diagnostics/bid", s.handleBidDiagnostics)
 
 	// Stats (backward compatibility)
 	mux.HandleFunc("/api/stats", s.handleStats)
+	mux.HandleFunc("/api/stats/status", s.handleStatsStatus)
+	mux.HandleFunc("/api/stats/rollup", s.handleStatsRollup)
+	mux.HandleFunc("/api/stats/reconcile", s.handleStatsReconcile)
+
+	// Live dashboard feed (Server-Sent Events)
+	mux.HandleFunc("/api/stream/stats", s.handleLiveStats)
 
 	// Pacing
 	mux.HandleFunc("/api/pacing/", s.handlePacingStats)
@@ -268,7 +662,8 @@ func (s *Server) handleBid(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := s.bidService.BuildBidResponse(&br)
+	// SSP endpoints carry ?source=<rtb source id> for sampling
+	resp, err := s.bidService.BuildBidResponse(r.Context(), &br, r.URL.Query().Get("source"))
 	if err != nil {
 		s.logger.Error("bid error", zap.Error(err))
 		s.errorResponse(w, "internal error", http.StatusInternalServerError)
@@ -291,6 +686,7 @@ func (s *Server) handleWinNotice(w http.ResponseWriter, r *http.Request) {
 	creativeID := q.Get("creative_id")
 	impID := q.Get("imp_id")
 	priceStr := q.Get("price")
+	priceCurrency := q.Get("cur")
 
 	price := 0.0
 	if priceStr != "" {
@@ -308,7 +704,7 @@ func (s *Server) handleWinNotice(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Log impression
-	s.trackingService.RegisterWin(r.Context(), impID, campaignID, lineItemID, creativeID, price)
+	s.trackingService.RegisterWin(r.Context(), impID, campaignID, lineItemID, creativeID, price, priceCurrency)
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
@@ -352,6 +748,18 @@ func (s *Server) handleTrackClick(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject forged, tampered or expired links
+	if err := s.trackingService.VerifyClickLink(q); err != nil {
+		s.logger.Warn("click link rejected",
+			zap.String("campaign_id", campaignID),
+			zap.String("source_id", sourceID),
+			zap.String("ip", getClientIP(r)),
+			zap.Error(err),
+		)
+		s.errorResponse(w, err.Error(), http.StatusForbidden)
+		return
+	}
+
 	// Get sub parameters
 	sub1 := q.Get("sub1")
 	sub2 := q.Get("sub2")
@@ -374,10 +782,6 @@ func (s *Server) handleTrackClick(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if s.metrics != nil {
-		s.metrics.RecordClick(campaignID, lineItemID)
-	}
-
 	// Redirect to MMP Click URL
 	if redirectURL != "" {
 		http.Redirect(w, r, redirectURL, http.StatusFound)
@@ -438,6 +842,7 @@ func (s *Server) handlePostback(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		s.logger.Error("postback error", zap.Error(err))
 	}
+	s.recordPostback("generic", result, err)
 	s.jsonResponse(w, result)
 }
 
@@ -446,6 +851,7 @@ func (s *Server) handlePostbackAppsFlyer(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		s.logger.Error("appsflyer postback error", zap.Error(err))
 	}
+	s.recordPostback("appsflyer", result, err)
 	s.jsonResponse(w, result)
 }
 
@@ -454,6 +860,7 @@ func (s *Server) handlePostbackAdjust(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		s.logger.Error("adjust postback error", zap.Error(err))
 	}
+	s.recordPostback("adjust", result, err)
 	s.jsonResponse(w, result)
 }
 
@@ -462,9 +869,15 @@ func (s *Server) handlePostbackSingular(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		s.logger.Error("singular postback error", zap.Error(err))
 	}
+	s.recordPostback("singular", result, err)
 	s.jsonResponse(w, result)
 }
 
+// recordPostback counts a postback from mmp for postback error alerts.
+func (s *Server) recordPostback(mmp string, result *dsp.PostbackResult, err error) {
+	s.postbackMonitor.Record(mmp, err == nil && result != nil && result.Success, time.Now())
+}
+
 // =============================================
 // S2S Endpoints
 // =============================================
@@ -499,42 +912,74 @@ func (s *Server) handleS2SAd(w http.ResponseWriter, r *http.Request, sourceName
 
 	// Parse request
 	q := r.URL.Query()
-	country := strings.ToUpper(q.Get("country"))
-	os := strings.ToLower(q.Get("os"))
-	gaid := q.Get("gaid")
-	if gaid == "" {
-		gaid = q.Get("idfa")
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	width, _ := strconv.Atoi(q.Get("w"))
+	height, _ := strconv.Atoi(q.Get("h"))
+	connectionType, _ := strconv.Atoi(q.Get("connection_type"))
+	req := &dsp.S2SAdRequest{
+		IP:             q.Get("ip"),
+		UserAgent:      q.Get("ua"),
+		OS:             strings.ToLower(q.Get("os")),
+		OSV:            q.Get("osv"),
+		DeviceType:     parseS2SDeviceType(q.Get("device_type")),
+		Make:           q.Get("make"),
+		Model:          q.Get("model"),
+		GAID:           q.Get("gaid"),
+		IDFA:           q.Get("idfa"),
+		Language:       q.Get("lang"),
+		Carrier:        q.Get("carrier"),
+		ConnectionType: int32(connectionType),
+		Country:        strings.ToUpper(q.Get("country")),
+		Region:         q.Get("region"),
+		City:           q.Get("city"),
+		AppBundle:      q.Get("bundle"),
+		Format:         q.Get("ad_format"),
+		W:              int32(width),
+		H:              int32(height),
+		CampaignID:     q.Get("campaign_id"),
+		Limit:          limit,
+		Sub1:           q.Get("sub1"),
+		Sub2:           q.Get("sub2"),
+		Sub3:           q.Get("sub3"),
+		Sub4:           q.Get("sub4"),
+		Sub5:           q.Get("sub5"),
+	}
+
+	asXML := strings.EqualFold(q.Get("format"), "xml") ||
+		strings.Contains(r.Header.Get("Accept"), "application/xml")
+
+	offers, err := s.s2sAdService.GetOffers(r.Context(), source, req)
+	if err != nil && err != dsp.ErrSourceCapped {
+		s.logger.Error("s2s offer selection failed", zap.String("source", sourceName), zap.Error(err))
+	}
+
+	if asXML {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Write([]byte(xml.Header))
+		xml.NewEncoder(w).Encode(&dsp.S2SFeed{Offers: offers})
+		return
 	}
 
-	// Find matching campaign
-	campaign, creative, err := s.sourceService.FindCampaignForSource(r.Context(), source.ID, country, os)
-	if err != nil || campaign == nil {
-		s.jsonResponse(w, map[string]interface{}{"success": false, "error": "no ad available"})
+	if len(offers) == 0 {
+		msg := "no ad available"
+		if err == dsp.ErrSourceCapped {
+			msg = err.Error()
+		}
+		s.jsonResponse(w, map[string]interface{}{"success": false, "error": msg})
 		return
 	}
 
-	// Build tracking URLs
-	clickURL := fmt.Sprintf("%s/track/click?cid=%s&cr=%s&src=%s&st=s2s&gaid=%s&sub1=%s&sub2=%s&sub3=%s",
-		s.config.Tracking.BaseURL, campaign.ID, creative.ID, source.ID,
-		gaid, q.Get("sub1"), q.Get("sub2"), q.Get("sub3"))
-
-	viewURL := fmt.Sprintf("%s/track/view?cid=%s&cr=%s&src=%s&st=s2s&gaid=%s",
-		s.config.Tracking.BaseURL, campaign.ID, creative.ID, source.ID, gaid)
-
+	// Top-level fields describe the best offer; all offers are listed in "offers"
+	best := offers[0]
 	s.jsonResponse(w, map[string]interface{}{
 		"success":     true,
-		"campaign_id": campaign.ID,
-		"app_bundle":  campaign.AppBundle,
-		"creative": map[string]interface{}{
-			"id":   creative.ID,
-			"type": creative.Format,
-			"url":  creative.AdmTemplate,
-			"w":    creative.W,
-			"h":    creative.H,
-		},
-		"click_url": clickURL,
-		"view_url":  viewURL,
-		"payout":    source.DefaultPayout,
+		"campaign_id": best.CampaignID,
+		"app_bundle":  best.AppBundle,
+		"creative":    best.Creative,
+		"click_url":   best.ClickURL,
+		"view_url":    best.ViewURL,
+		"payout":      best.Payout,
+		"offers":      offers,
 	})
 }
 
@@ -550,11 +995,20 @@ func (s *Server) handleS2SClick(w http.ResponseWriter, r *http.Request, sourceNa
 func (s *Server) handleCampaigns(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		list, err := s.campaignService.ListCampaigns()
+		list, err := s.campaignService.ListCampaigns(r.Context())
 		if err != nil {
 			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
 			return
 		}
+		if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+			owned := make([]*models.Campaign, 0)
+			for _, c := range list {
+				if c.AdvertiserID == scope {
+					owned = append(owned, c)
+				}
+			}
+			list = owned
+		}
 		s.jsonResponse(w, list)
 
 	case http.MethodPost:
@@ -563,10 +1017,19 @@ func (s *Server) handleCampaigns(w http.ResponseWriter, r *http.Request) {
 			s.errorResponse(w, "invalid json", http.StatusBadRequest)
 			return
 		}
-		if err := s.campaignService.UpsertCampaign(&c); err != nil {
+		if !s.allowCampaignWrite(w, r, &c) {
+			return
+		}
+		before, err := s.campaignService.GetCampaign(r.Context(), c.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.campaignService.UpsertCampaign(r.Context(), &c); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditCampaign, c.ID, before, &c)
 		s.jsonResponse(w, c)
 
 	default:
@@ -583,7 +1046,7 @@ func (s *Server) handleCampaignByID(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		c, err := s.campaignService.GetCampaign(id)
+		c, err := s.campaignService.GetCampaign(r.Context(), id)
 		if err != nil {
 			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -592,6 +1055,9 @@ func (s *Server) handleCampaignByID(w http.ResponseWriter, r *http.Request) {
 			http.NotFound(w, r)
 			return
 		}
+		if !s.allowAdvertiser(w, r, c.AdvertiserID) {
+			return
+		}
 		s.jsonResponse(w, c)
 
 	case http.MethodPut:
@@ -601,10 +1067,19 @@ func (s *Server) handleCampaignByID(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		c.ID = id
-		if err := s.campaignService.UpsertCampaign(&c); err != nil {
+		if !s.allowCampaignWrite(w, r, &c) {
+			return
+		}
+		before, err := s.campaignService.GetCampaign(r.Context(), c.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.campaignService.UpsertCampaign(r.Context(), &c); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditCampaign, c.ID, before, &c)
 		s.jsonResponse(w, c)
 
 	case http.MethodDelete:
@@ -615,6 +1090,31 @@ func (s *Server) handleCampaignByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// allowCampaignWrite checks that a key restricted to an advertiser saves
+// the campaign for that advertiser and doesn't overwrite another
+// advertiser's campaign with the same ID.
+func (s *Server) allowCampaignWrite(w http.ResponseWriter, r *http.Request, c *models.Campaign) bool {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return true
+	}
+	if c.AdvertiserID == "" {
+		c.AdvertiserID = scope
+	}
+	if !s.allowAdvertiser(w, r, c.AdvertiserID) {
+		return false
+	}
+	if c.ID == "" {
+		return true
+	}
+	existing, err := s.campaignService.GetCampaign(r.Context(), c.ID)
+	if err != nil {
+		s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return existing == nil || s.allowAdvertiser(w, r, existing.AdvertiserID)
+}
+
 // =============================================
 // Admin API - Advertisers
 // =============================================
@@ -622,23 +1122,42 @@ func (s *Server) handleCampaignByID(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleAdvertisers(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		list, err := s.advertiserService.ListAdvertisers()
+		list, err := s.advertiserService.ListAdvertisers(r.Context())
 		if err != nil {
 			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
 			return
 		}
+		if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+			owned := make([]*models.Advertiser, 0, 1)
+			for _, a := range list {
+				if a.ID == scope {
+					owned = append(owned, a)
+				}
+			}
+			list = owned
+		}
 		s.jsonResponse(w, list)
 
 	case http.MethodPost:
+		// Advertisers carry credit limits and bank details
+		if !s.requireUnscoped(w, r) {
+			return
+		}
 		var a models.Advertiser
 		if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
 			s.errorResponse(w, "invalid json", http.StatusBadRequest)
 			return
 		}
-		if err := s.advertiserService.UpsertAdvertiser(&a); err != nil {
+		before, err := s.advertiserService.GetAdvertiser(r.Context(), a.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.advertiserService.UpsertAdvertiser(r.Context(), &a); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditAdvertiser, a.ID, before, &a)
 		s.jsonResponse(w, a)
 
 	default:
@@ -647,15 +1166,22 @@ func (s *Server) handleAdvertisers(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleAdvertiserByID(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/advertisers/")
+	id, action, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/api/advertisers/"), "/")
 	if id == "" {
 		http.NotFound(w, r)
 		return
 	}
+	if action != "" {
+		s.handleAdvertiserBilling(w, r, id, action)
+		return
+	}
+	if !s.allowAdvertiser(w, r, id) {
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
-		adv, err := s.advertiserService.GetAdvertiser(id)
+		adv, err := s.advertiserService.GetAdvertiser(r.Context(), id)
 		if err != nil {
 			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -676,6 +1202,9 @@ func (s *Server) handleAdvertiserByID(w http.ResponseWriter, r *http.Request) {
 // =============================================
 
 func (s *Server) handleS2SSources(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
 		list, err := s.sourceService.ListS2SSources(r.Context())
@@ -691,10 +1220,16 @@ func (s *Server) handleS2SSources(w http.ResponseWriter, r *http.Request) {
 			s.errorResponse(w, "invalid json", http.StatusBadRequest)
 			return
 		}
+		before, err := s.sourceService.GetS2SSource(r.Context(), src.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 		if err := s.sourceService.UpsertS2SSource(r.Context(), &src); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditS2SSource, src.ID, before, &src)
 		s.jsonResponse(w, src)
 
 	default:
@@ -703,6 +1238,9 @@ func (s *Server) handleS2SSources(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleS2SSourceByID(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
 	id := strings.TrimPrefix(r.URL.Path, "/api/sources/s2s/")
 	if id == "" {
 		http.NotFound(w, r)
@@ -728,6 +1266,9 @@ func (s *Server) handleS2SSourceByID(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleRTBSources(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
 	switch r.Method {
 	case http.MethodGet:
 		list, err := s.sourceService.ListRTBSources(r.Context())
@@ -743,10 +1284,16 @@ func (s *Server) handleRTBSources(w http.ResponseWriter, r *http.Request) {
 			s.errorResponse(w, "invalid json", http.StatusBadRequest)
 			return
 		}
+		before, err := s.sourceService.GetRTBSource(r.Context(), src.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 		if err := s.sourceService.UpsertRTBSource(r.Context(), &src); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditRTBSource, src.ID, before, &src)
 		s.jsonResponse(w, src)
 
 	default:
@@ -755,6 +1302,9 @@ func (s *Server) handleRTBSources(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleRTBSourceByID(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
 	id := strings.TrimPrefix(r.URL.Path, "/api/sources/rtb/")
 	if id == "" {
 		http.NotFound(w, r)
@@ -790,14 +1340,34 @@ func (s *Server) handleAdGroups(w http.ResponseWriter, r *http.Request) {
 		var list []*models.AdGroup
 		var err error
 		if campaignID != "" {
-			list, err = s.adGroupService.ListAdGroupsByCampaign(campaignID)
+			if !s.allowCampaign(w, r, campaignID) {
+				return
+			}
+			list, err = s.adGroupService.ListAdGroupsByCampaign(r.Context(), campaignID)
 		} else {
-			list, err = s.adGroupService.ListAdGroups()
+			list, err = s.adGroupService.ListAdGroups(r.Context())
 		}
 		if err != nil {
 			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
 			return
 		}
+		if campaignID == "" && middleware.GetAdvertiserScope(r.Context()) != "" {
+			ids, ok := s.scopeCampaignIDs(w, r, nil)
+			if !ok {
+				return
+			}
+			owned := make(map[string]bool, len(ids))
+			for _, id := range ids {
+				owned[id] = true
+			}
+			scoped := make([]*models.AdGroup, 0)
+			for _, g := range list {
+				if owned[g.CampaignID] {
+					scoped = append(scoped, g)
+				}
+			}
+			list = scoped
+		}
 		s.jsonResponse(w, list)
 
 	case http.MethodPost:
@@ -806,10 +1376,29 @@ func (s *Server) handleAdGroups(w http.ResponseWriter, r *http.Request) {
 			s.errorResponse(w, "invalid json", http.StatusBadRequest)
 			return
 		}
-		if err := s.adGroupService.UpsertAdGroup(&g); err != nil {
+		if middleware.GetAdvertiserScope(r.Context()) != "" {
+			if !s.allowCampaign(w, r, g.CampaignID) {
+				return
+			}
+			existing, err := s.adGroupService.GetAdGroup(r.Context(), g.ID)
+			if err != nil {
+				s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if existing != nil && !s.allowCampaign(w, r, existing.CampaignID) {
+				return
+			}
+		}
+		before, err := s.adGroupService.GetAdGroup(r.Context(), g.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.adGroupService.UpsertAdGroup(r.Context(), &g); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditAdGroup, g.ID, before, &g)
 		s.jsonResponse(w, g)
 
 	default:
@@ -826,7 +1415,7 @@ func (s *Server) handleAdGroupByID(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		g, err := s.adGroupService.GetAdGroup(id)
+		g, err := s.adGroupService.GetAdGroup(r.Context(), id)
 		if err != nil {
 			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -835,6 +1424,9 @@ func (s *Server) handleAdGroupByID(w http.ResponseWriter, r *http.Request) {
 			http.NotFound(w, r)
 			return
 		}
+		if !s.allowCampaign(w, r, g.CampaignID) {
+			return
+		}
 		s.jsonResponse(w, g)
 
 	default:
@@ -849,8 +1441,11 @@ func (s *Server) handleAdGroupByID(w http.ResponseWriter, r *http.Request) {
 func (s *Server) handleCreatives(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		advertiserID := r.URL.Query().Get("advertiser_id")
-		list, err := s.creativeService.ListCreatives(advertiserID)
+		advertiserID, ok := s.scopeAdvertiserID(w, r, r.URL.Query().Get("advertiser_id"))
+		if !ok {
+			return
+		}
+		list, err := s.creativeService.ListCreatives(r.Context(), advertiserID)
 		if err != nil {
 			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
 			return
@@ -867,10 +1462,32 @@ func (s *Server) handleCreatives(w http.ResponseWriter, r *http.Request) {
 			s.errorResponse(w, "id is required", http.StatusBadRequest)
 			return
 		}
-		if err := s.creativeService.UpsertCreative(&cr); err != nil {
+		if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+			if cr.AdvertiserID == "" {
+				cr.AdvertiserID = scope
+			}
+			if !s.allowAdvertiser(w, r, cr.AdvertiserID) {
+				return
+			}
+			existing, err := s.creativeService.GetCreative(r.Context(), cr.ID)
+			if err != nil {
+				s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if existing != nil && !s.allowAdvertiser(w, r, existing.AdvertiserID) {
+				return
+			}
+		}
+		before, err := s.creativeService.GetCreative(r.Context(), cr.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.creativeService.UpsertCreative(r.Context(), &cr); err != nil {
 			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
 			return
 		}
+		s.audit(r, models.AuditCreative, cr.ID, before, &cr)
 		s.jsonResponse(w, cr)
 
 	default:
@@ -887,7 +1504,7 @@ func (s *Server) handleCreativeByID(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		cr, err := s.creativeService.GetCreative(id)
+		cr, err := s.creativeService.GetCreative(r.Context(), id)
 		if err != nil {
 			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
 			return
@@ -896,6 +1513,9 @@ func (s *Server) handleCreativeByID(w http.ResponseWriter, r *http.Request) {
 			http.NotFound(w, r)
 			return
 		}
+		if !s.allowAdvertiser(w, r, cr.AdvertiserID) {
+			return
+		}
 		s.jsonResponse(w, cr)
 
 	default:
@@ -954,54 +1574,334 @@ func (s *Server) handleCreativeUpload(w http.ResponseWriter, r *http.Request) {
 }
 
 // =============================================
-// Reporting
+// Payout Rules
 // =============================================
 
-func (s *Server) handleCampaignReports(w http.ResponseWriter, r *http.Request) {
-	if s.reportingService == nil {
-		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
+func (s *Server) handlePayoutRules(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
 		return
 	}
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.payoutEngine.ListRules(r.Context())
+		if err != nil {
+			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
+			return
+		}
+		s.jsonResponse(w, list)
 
-	var filter dsp.ReportFilter
-	if r.Method == http.MethodPost {
-		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+	case http.MethodPost:
+		var rule models.PayoutRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
 			s.errorResponse(w, "invalid json", http.StatusBadRequest)
 			return
 		}
-	}
+		before, err := s.payoutEngine.GetRule(r.Context(), rule.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.payoutEngine.UpsertRule(r.Context(), &rule); err != nil {
+			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.audit(r, models.AuditPayoutRule, rule.ID, before, &rule)
+		s.jsonResponse(w, rule)
 
-	stats, err := s.reportingService.GetCampaignStats(r.Context(), filter)
-	if err != nil {
-		s.errorResponse(w, "failed to get stats", http.StatusInternalServerError)
-		return
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
-
-	s.jsonResponse(w, stats)
 }
 
-func (s *Server) handleSourceReports(w http.ResponseWriter, r *http.Request) {
-	if s.reportingService == nil {
-		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
+func (s *Server) handlePayoutRuleByID(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
 		return
 	}
-
-	stats, err := s.reportingService.GetSourceStats(r.Context())
-	if err != nil {
-		s.errorResponse(w, "failed to get stats", http.StatusInternalServerError)
+	id := strings.TrimPrefix(r.URL.Path, "/api/payout-rules/")
+	if id == "" {
+		http.NotFound(w, r)
 		return
 	}
 
-	s.jsonResponse(w, stats)
-}
+	switch r.Method {
+	case http.MethodGet:
+		rule, err := s.payoutEngine.GetRule(r.Context(), id)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if rule == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.jsonResponse(w, rule)
 
-func (s *Server) handleGeoReports(w http.ResponseWriter, r *http.Request) {
-	if s.reportingService == nil {
+	case http.MethodPut:
+		var rule models.PayoutRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		rule.ID = id
+		before, err := s.payoutEngine.GetRule(r.Context(), rule.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.payoutEngine.UpsertRule(r.Context(), &rule); err != nil {
+			s.errorResponse(w, "failed to save: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.audit(r, models.AuditPayoutRule, rule.ID, before, &rule)
+		s.jsonResponse(w, rule)
+
+	case http.MethodDelete:
+		before, err := s.payoutEngine.GetRule(r.Context(), id)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if err := s.payoutEngine.DeleteRule(r.Context(), id); err != nil {
+			s.errorResponse(w, "failed to delete: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if before != nil {
+			s.audit(r, models.AuditPayoutRule, id, before, nil)
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handlePayoutPreview shows which payout rule would price a conversion.
+// Accepts a JSON PayoutInput (POST) or query parameters (GET).
+func (s *Server) handlePayoutPreview(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	var in dsp.PayoutInput
+	switch r.Method {
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	case http.MethodGet:
+		q := r.URL.Query()
+		in = dsp.PayoutInput{
+			CampaignID:      q.Get("campaign_id"),
+			SourceType:      q.Get("source_type"),
+			SourceID:        q.Get("source_id"),
+			Country:         q.Get("country"),
+			OS:              q.Get("os"),
+			Event:           q.Get("event"),
+			RevenueCurrency: q.Get("revenue_currency"),
+			Currency:        q.Get("currency"),
+		}
+		if rev := q.Get("revenue"); rev != "" {
+			in.Revenue, _ = strconv.ParseFloat(rev, 64)
+		}
+		if at := q.Get("at"); at != "" {
+			t, err := time.Parse(time.RFC3339, at)
+			if err != nil {
+				s.errorResponse(w, "invalid at: expected RFC3339", http.StatusBadRequest)
+				return
+			}
+			in.At = t
+		}
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	quote, err := s.payoutEngine.Preview(r.Context(), in)
+	if err != nil {
+		s.errorResponse(w, "failed to preview payout: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, quote)
+}
+
+// =============================================
+// Reporting
+// =============================================
+
+// handleReportQuery runs a grouped report. GET takes query parameters
+// (comma-separated lists, dates in the report timezone, end_date inclusive);
+// POST takes a dsp.ReportFilter as JSON.
+func (s *Server) handleReportQuery(w http.ResponseWriter, r *http.Request) {
+	if s.reportingService == nil {
+		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var filter dsp.ReportFilter
+	switch r.Method {
+	case http.MethodGet:
+		f, err := parseReportFilter(r.URL.Query())
+		if err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter = f
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.scopeReportFilter(w, r, &filter) {
+		return
+	}
+	filter.Currency = s.reportCurrency(r.Context(), filter.Currency, filter.AdvertiserID)
+
+	result, err := s.reportingService.Report(r.Context(), filter)
+	if err != nil {
+		switch {
+		case errors.Is(err, dsp.ErrInvalidReport):
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, dsp.ErrReportsUnsupported):
+			s.errorResponse(w, err.Error(), http.StatusNotImplemented)
+		default:
+			s.errorResponse(w, "failed to run report: "+err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"currency":   currency.Normalize(filter.Currency),
+		"dimensions": result.Dimensions,
+		"metrics":    result.Metrics,
+		"rows":       result.Rows,
+		"total":      result.Total,
+	})
+}
+
+// parseReportFilter reads a report filter from query parameters.
+func parseReportFilter(q url.Values) (dsp.ReportFilter, error) {
+	list := func(key string) []string {
+		if v := q.Get(key); v != "" {
+			return strings.Split(v, ",")
+		}
+		return nil
+	}
+	filter := dsp.ReportFilter{
+		CampaignIDs:  list("campaign_id"),
+		LineItemIDs:  list("line_item_id"),
+		CreativeIDs:  list("creative_id"),
+		SourceIDs:    list("source_id"),
+		Countries:    list("country"),
+		OS:           list("os"),
+		AppBundles:   list("app_bundle"),
+		PublisherIDs: list("publisher_id"),
+		AdvertiserID: q.Get("advertiser_id"),
+		Timezone:     q.Get("timezone"),
+		Granularity:  q.Get("granularity"),
+		GroupBy:      list("group_by"),
+		Metrics:      list("metrics"),
+		Sort:         q.Get("sort"),
+		Currency:     q.Get("currency"),
+	}
+
+	loc := time.UTC
+	if filter.Timezone != "" {
+		l, err := time.LoadLocation(filter.Timezone)
+		if err != nil {
+			return filter, fmt.Errorf("unknown timezone %q", filter.Timezone)
+		}
+		loc = l
+	}
+	if v := q.Get("start_date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return filter, fmt.Errorf("invalid start_date (YYYY-MM-DD)")
+		}
+		filter.StartDate = t
+	}
+	if v := q.Get("end_date"); v != "" {
+		t, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			return filter, fmt.Errorf("invalid end_date (YYYY-MM-DD)")
+		}
+		filter.EndDate = t.AddDate(0, 0, 1)
+	}
+
+	for key, dst := range map[string]*int{"limit": &filter.Limit, "offset": &filter.Offset} {
+		if v := q.Get(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", key)
+			}
+			*dst = n
+		}
+	}
+	return filter, nil
+}
+
+func (s *Server) handleCampaignReports(w http.ResponseWriter, r *http.Request) {
+	if s.reportingService == nil {
+		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var filter dsp.ReportFilter
+	if r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&filter); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+	if cur := r.URL.Query().Get("currency"); cur != "" {
+		filter.Currency = cur
+	}
+	if !s.scopeReportFilter(w, r, &filter) {
+		return
+	}
+	filter.Currency = s.reportCurrency(r.Context(), filter.Currency, filter.AdvertiserID)
+
+	stats, err := s.reportingService.GetCampaignStats(r.Context(), filter)
+	if err != nil {
+		s.errorResponse(w, "failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, stats)
+}
+
+func (s *Server) handleSourceReports(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	if s.reportingService == nil {
+		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	stats, err := s.reportingService.GetSourceStats(r.Context())
+	if err != nil {
+		s.errorResponse(w, "failed to get stats", http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, stats)
+}
+
+func (s *Server) handleGeoReports(w http.ResponseWriter, r *http.Request) {
+	if s.reportingService == nil {
 		s.errorResponse(w, "reporting not available", http.StatusServiceUnavailable)
 		return
 	}
 
 	campaignID := r.URL.Query().Get("campaign_id")
+	if !s.requireCampaignForScope(w, r, campaignID) {
+		return
+	}
 	stats, err := s.reportingService.GetGeoBreakdown(r.Context(), campaignID)
 	if err != nil {
 		s.errorResponse(w, "failed to get stats", http.StatusInternalServerError)
@@ -1018,7 +1918,16 @@ func (s *Server) handleTimeSeriesReport(w http.ResponseWriter, r *http.Request)
 	}
 
 	campaignID := r.URL.Query().Get("campaign_id")
-	filter := dsp.ReportFilter{}
+	if !s.requireCampaignForScope(w, r, campaignID) {
+		return
+	}
+	advertiserID, ok := s.scopeAdvertiserID(w, r, r.URL.Query().Get("advertiser_id"))
+	if !ok {
+		return
+	}
+	filter := dsp.ReportFilter{
+		Currency: s.reportCurrency(r.Context(), r.URL.Query().Get("currency"), advertiserID),
+	}
 
 	if startStr := r.URL.Query().Get("start_date"); startStr != "" {
 		if t, err := time.Parse("2006-01-02", startStr); err == nil {
@@ -1040,33 +1949,225 @@ func (s *Server) handleTimeSeriesReport(w http.ResponseWriter, r *http.Request)
 	s.jsonResponse(w, points)
 }
 
+func (s *Server) handleFraudReport(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	if s.fraudScorer == nil {
+		s.errorResponse(w, "fraud detection disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	var from, to time.Time
+	if startStr := r.URL.Query().Get("start_date"); startStr != "" {
+		if t, err := time.Parse("2006-01-02", startStr); err == nil {
+			from = t
+		}
+	}
+	if endStr := r.URL.Query().Get("end_date"); endStr != "" {
+		if t, err := time.Parse("2006-01-02", endStr); err == nil {
+			to = t
+		}
+	}
+
+	s.jsonResponse(w, s.fraudScorer.SourceReport(from, to))
+}
+
+// handleStats serves rolled-up daily stats; see DailyStatsService.
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
-	if s.reportingService == nil {
-		s.jsonResponse(w, []dsp.CampaignStats{})
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	stats, err := s.reportingService.GetCampaignStats(context.Background(), dsp.ReportFilter{})
+	q := r.URL.Query()
+	advertiserID, ok := s.scopeAdvertiserID(w, r, q.Get("advertiser_id"))
+	if !ok {
+		return
+	}
+	query := dsp.StatsQuery{
+		CampaignID: q.Get("campaign_id"),
+		SourceType: q.Get("source_type"),
+		SourceID:   q.Get("source_id"),
+		Country:    q.Get("country"),
+		Currency:   s.reportCurrency(r.Context(), q.Get("currency"), advertiserID),
+	}
+	if middleware.GetAdvertiserScope(r.Context()) != "" {
+		var requested []string
+		if query.CampaignID != "" {
+			requested = []string{query.CampaignID}
+		}
+		if query.CampaignIDs, ok = s.scopeCampaignIDs(w, r, requested); !ok {
+			return
+		}
+	}
+	if v := q.Get("group_by"); v != "" {
+		query.GroupBy = strings.Split(v, ",")
+	}
+	start, end, err := parseStatsDates(q)
 	if err != nil {
-		s.errorResponse(w, "failed to compute stats", http.StatusInternalServerError)
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	query.StartDate, query.EndDate = start, end
 
-	campaignID := r.URL.Query().Get("campaign_id")
-	if campaignID != "" {
-		filtered := make([]dsp.CampaignStats, 0)
-		for _, st := range stats {
-			if st.CampaignID == campaignID {
-				filtered = append(filtered, st)
-			}
+	rows, err := s.dailyStats.Query(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, dsp.ErrInvalidReport) {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
 		}
-		stats = filtered
+		s.errorResponse(w, "failed to get stats: "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	s.jsonResponse(w, stats)
+	s.jsonResponse(w, rows)
+}
+
+func (s *Server) handleStatsStatus(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	status, err := s.dailyStats.Status(r.Context())
+	if err != nil {
+		s.errorResponse(w, "failed to get stats status: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	s.jsonResponse(w, status)
+}
+
+// handleStatsRollup rolls up a UTC date range again, e.g. after postbacks
+// arrived later than the late window.
+func (s *Server) handleStatsRollup(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	start, end, err := parseStatsDates(r.URL.Query())
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if start.IsZero() || end.IsZero() {
+		s.errorResponse(w, "start_date and end_date required", http.StatusBadRequest)
+		return
+	}
+
+	days, err := s.dailyStats.Rollup(r.Context(), start, end)
+	if err != nil {
+		s.statsError(w, "failed to roll up stats", err)
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"start_date": start.Format("2006-01-02"),
+		"end_date":   end.Format("2006-01-02"),
+		"days":       days,
+	})
+}
+
+// handleStatsReconcile compares daily stats with raw event totals.
+func (s *Server) handleStatsReconcile(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	start, end, err := parseStatsDates(r.URL.Query())
+	if err != nil {
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	if start.IsZero() {
+		start = end.AddDate(0, 0, -7)
+	}
+
+	rec, err := s.dailyStats.Reconcile(r.Context(), start, end)
+	if err != nil {
+		s.statsError(w, "failed to reconcile stats", err)
+		return
+	}
+	s.jsonResponse(w, rec)
+}
+
+func (s *Server) statsError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, dsp.ErrInvalidReport):
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, dsp.ErrReportsUnsupported):
+		s.errorResponse(w, err.Error(), http.StatusNotImplemented)
+	default:
+		s.errorResponse(w, msg+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// parseStatsDates parses the inclusive UTC start_date and end_date.
+func parseStatsDates(q url.Values) (start, end time.Time, err error) {
+	if v := q.Get("start_date"); v != "" {
+		if start, err = time.Parse("2006-01-02", v); err != nil {
+			return start, end, fmt.Errorf("invalid start_date (YYYY-MM-DD)")
+		}
+	}
+	if v := q.Get("end_date"); v != "" {
+		if end, err = time.Parse("2006-01-02", v); err != nil {
+			return start, end, fmt.Errorf("invalid end_date (YYYY-MM-DD)")
+		}
+	}
+	return start, end, nil
+}
+
+func (s *Server) handleExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	date := time.Now()
+	if dateStr := r.URL.Query().Get("date"); dateStr != "" {
+		t, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			s.errorResponse(w, "invalid date", http.StatusBadRequest)
+			return
+		}
+		date = t
+	}
+
+	rates, err := s.converter.Rates(r.Context(), date)
+	if err != nil {
+		s.errorResponse(w, "exchange rates unavailable: "+err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	s.jsonResponse(w, map[string]interface{}{
+		"date":  date.Format("2006-01-02"),
+		"base":  currency.ReportingCurrency,
+		"rates": rates,
+	})
+}
+
+// reportCurrency resolves the output currency of a report. An explicit
+// currency wins; otherwise figures are shown in the advertiser's currency.
+func (s *Server) reportCurrency(ctx context.Context, requested, advertiserID string) string {
+	if requested != "" {
+		return currency.Normalize(requested)
+	}
+	if advertiserID != "" {
+		if adv, err := s.advertiserService.GetAdvertiser(ctx, advertiserID); err == nil && adv != nil && adv.Currency != "" {
+			return currency.Normalize(adv.Currency)
+		}
+	}
+	return currency.ReportingCurrency
 }
 
 func (s *Server) handlePacingStats(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
 	lineItemID := strings.TrimPrefix(r.URL.Path, "/api/pacing/")
 	if lineItemID == "" {
 		s.errorResponse(w, "line_item_id required", http.StatusBadRequest)
@@ -1082,6 +2183,200 @@ func (s *Server) handlePacingStats(w http.ResponseWriter, r *http.Request) {
 	s.jsonResponse(w, stats)
 }
 
+// =============================================
+// Bid Samples
+// =============================================
+
+// handleBidSamples lists sampled bid requests, newest first. Filter by
+// campaign_id or line_item_id to see why a campaign did not bid.
+func (s *Server) handleBidSamples(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.bidSampler == nil {
+		s.errorResponse(w, "bid sampling disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := storage.BidSampleFilter{
+		SourceID:   q.Get("source_id"),
+		CampaignID: q.Get("campaign_id"),
+		LineItemID: q.Get("line_item_id"),
+		NoBidOnly:  q.Get("no_bid") == "true",
+	}
+	if sinceStr := q.Get("since"); sinceStr != "" {
+		t, err := time.Parse(time.RFC3339, sinceStr)
+		if err != nil {
+			s.errorResponse(w, "invalid since (RFC 3339)", http.StatusBadRequest)
+			return
+		}
+		filter.Since = t
+	}
+	if limitStr := q.Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			s.errorResponse(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	samples, err := s.bidSampler.Store().ListBidSamples(r.Context(), filter)
+	if err != nil {
+		s.errorResponse(w, "failed to list bid samples: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Narrow trails to the requested campaign or line item
+	if filter.CampaignID != "" || filter.LineItemID != "" {
+		for i, sample := range samples {
+			narrowed := *sample
+			narrowed.Trail = make([]models.LineItemDecision, 0)
+			for _, d := range sample.Trail {
+				if (filter.CampaignID == "" || d.CampaignID == filter.CampaignID) &&
+					(filter.LineItemID == "" || d.LineItemID == filter.LineItemID) {
+					narrowed.Trail = append(narrowed.Trail, d)
+				}
+			}
+			samples[i] = &narrowed
+		}
+	}
+
+	s.jsonResponse(w, samples)
+}
+
+func (s *Server) handleBidSampleByID(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/bid-samples/")
+	if id == "" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.bidSampler == nil {
+		s.errorResponse(w, "bid sampling disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	sample, err := s.bidSampler.Store().GetBidSample(r.Context(), id)
+	if err != nil {
+		s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if sample == nil {
+		http.NotFound(w, r)
+		return
+	}
+	s.jsonResponse(w, sample)
+}
+
+// handleBidSamplingConfig reads or changes sampling at runtime, e.g. to
+// always log a line item an advertiser asks about.
+func (s *Server) handleBidSamplingConfig(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	if s.bidSampler == nil {
+		s.errorResponse(w, "bid sampling disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.jsonResponse(w, s.bidSampler.Config())
+
+	case http.MethodPut:
+		var cfg dsp.BidSamplingConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if err := s.bidSampler.SetConfig(cfg); err != nil {
+			s.errorResponse(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.jsonResponse(w, s.bidSampler.Config())
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleBidDiagnostics explains why line items would or would not bid on a
+// request. POST a bid request, or GET with sample_id to replay a sampled
+// one. Pacing is checked read-only; nothing is bid or counted.
+func (s *Server) handleBidDiagnostics(w http.ResponseWriter, r *http.Request) {
+	if !s.requireUnscoped(w, r) {
+		return
+	}
+	var br *models.BidRequest
+
+	switch r.Method {
+	case http.MethodGet:
+		sampleID := r.URL.Query().Get("sample_id")
+		if sampleID == "" {
+			s.errorResponse(w, "sample_id is required", http.StatusBadRequest)
+			return
+		}
+		if s.bidSampler == nil {
+			s.errorResponse(w, "bid sampling disabled", http.StatusServiceUnavailable)
+			return
+		}
+		sample, err := s.bidSampler.Store().GetBidSample(r.Context(), sampleID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if sample == nil || sample.Request == nil {
+			http.NotFound(w, r)
+			return
+		}
+		br = sample.Request
+
+	case http.MethodPost:
+		br = &models.BidRequest{}
+		if err := json.NewDecoder(r.Body).Decode(br); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	diag, err := s.bidService.ExplainBid(r.Context(), br)
+	if err != nil {
+		s.errorResponse(w, "failed to explain bid: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Narrow to one campaign or line item when asked
+	q := r.URL.Query()
+	if campaignID, lineItemID := q.Get("campaign_id"), q.Get("line_item_id"); campaignID != "" || lineItemID != "" {
+		narrowed := make([]dsp.LineItemDiagnosis, 0)
+		for _, d := range diag.LineItems {
+			if (campaignID == "" || d.CampaignID == campaignID) &&
+				(lineItemID == "" || d.LineItemID == lineItemID) {
+				narrowed = append(narrowed, d)
+			}
+		}
+		diag.LineItems = narrowed
+	}
+
+	s.jsonResponse(w, diag)
+}
+
 // =============================================
 // Helper Methods
 // =============================================
@@ -1097,6 +2392,71 @@ func (s *Server) errorResponse(w http.ResponseWriter, message string, code int)
 	json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// newRateProvider builds the configured exchange-rate provider, falling back
+// to static rates if the configured source cannot be loaded.
+func newRateProvider(cfg config.CurrencyConfig, logger *zap.Logger) currency.RateProvider {
+	switch cfg.RatesSource {
+	case "file":
+		p, err := currency.NewFileRateProvider(cfg.RatesFile)
+		if err == nil {
+			return p
+		}
+		logger.Warn("failed to load exchange rates file, using static rates", zap.Error(err))
+	case "api":
+		return currency.NewHTTPRateProvider(cfg.RatesAPIURL, cfg.RatesAPITimeout)
+	}
+	return currency.NewStaticRateProvider(nil)
+}
+
+func newFraudScorer(deps *Dependencies) *fraud.Scorer {
+	cfg := deps.Config.Fraud
+
+	var counter fraud.ClickCounter
+	if deps.Redis != nil {
+		counter = fraud.NewRedisClickCounter(deps.Redis.Client)
+	} else {
+		counter = fraud.NewInMemoryClickCounter()
+	}
+
+	var dataCenter *fraud.IPRanges
+	if cfg.DataCenterFile != "" {
+		ranges, err := fraud.LoadIPRanges(cfg.DataCenterFile)
+		if err != nil {
+			deps.Logger.Warn("failed to load data-center IP ranges", zap.Error(err))
+		} else {
+			dataCenter = ranges
+			deps.Logger.Info("data-center IP ranges loaded", zap.Int("ranges", ranges.Len()))
+		}
+	}
+
+	return fraud.NewScorer(fraud.Config{
+		MinCTIT:         cfg.MinCTIT,
+		MaxClicksPerIFA: int64(cfg.MaxClicksPerIFA),
+		MaxClicksPerIP:  int64(cfg.MaxClicksPerIP),
+		ClickWindow:     cfg.ClickWindow,
+		FlagThreshold:   cfg.FlagThreshold,
+	}, counter, dataCenter)
+}
+
+// parseS2SDeviceType maps an S2S device_type param (name or OpenRTB code)
+// to the OpenRTB device type.
+func parseS2SDeviceType(v string) int32 {
+	switch strings.ToLower(v) {
+	case "":
+		return 0
+	case "phone", "mobile":
+		return 4
+	case "tablet":
+		return 5
+	case "desktop", "pc":
+		return 2
+	case "ctv", "tv":
+		return 3
+	}
+	n, _ := strconv.Atoi(v)
+	return int32(n)
+}
+
 func getClientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		parts := strings.Split(xff, ",")
@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"go.uber.org/zap"
+)
+
+// Keys of the test server: the master key and a key restricted to adv-1.
+const (
+	testMasterKey = "master"
+	testAdv1Key   = "adv-1-key"
+)
+
+// testKeys is a KeyStore of the test server's API keys.
+type testKeys map[string]*models.APIKey
+
+func (k testKeys) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	return k[key], nil
+}
+
+// newTestServer returns the server over in-memory storage behind the auth
+// middleware, without background workers.
+func newTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	cfg := &config.Config{Auth: config.AuthConfig{Enabled: true, MasterKey: testMasterKey}}
+	auth := middleware.NewAuthMiddleware(cfg.Auth, zap.NewNop())
+	auth.SetKeyStore(testKeys{
+		testAdv1Key: {
+			ID: "k-adv-1", AdvertiserID: "adv-1", Permissions: []string{models.ScopeWrite}, Status: models.APIKeyActive,
+		},
+	})
+	return auth.Handler(NewServer(&Dependencies{Config: cfg, Logger: zap.NewNop()}))
+}
+
+// testRequest is a request to the test server.
+type testRequest struct {
+	method string
+	target string
+	key    string // Defaults to the master key
+	body   string
+	header map[string]string
+}
+
+// serve sends req to h and returns the response.
+func serve(t *testing.T, h http.Handler, req testRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(req.method, req.target, strings.NewReader(req.body))
+	if req.key == "" {
+		req.key = testMasterKey
+	}
+	r.Header.Set(middleware.AuthHeaderName, req.key)
+	for k, v := range req.header {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// decodeBody decodes a JSON response into v.
+func decodeBody(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid response %q: %v", w.Body.String(), err)
+	}
+}
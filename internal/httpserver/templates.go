@@ -0,0 +1,466 @@
+package httpserver
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// =============================================
+// API v1 - Clones, templates and drafts
+// =============================================
+//
+// Campaigns, line items and creatives can be cloned with new IDs and
+// overrides (name, countries, budgets, dates). Templates are reusable
+// campaigns of an advertiser that campaigns are created from. Both give
+// draft campaigns, and cloned line items are inactive, so nothing bids
+// before it is reviewed. A campaign's draft stages changes to it: it is
+// validated when saved and goes live when published.
+
+// templateSortFields are the fields v1 template lists sort by.
+var templateSortFields = map[string]bool{
+	"id": true, "name": true, "advertiser_id": true, "created_at": true, "updated_at": true,
+}
+
+// templateSortValue returns the value of a template sort field.
+func templateSortValue(t *models.CampaignTemplate, field string) interface{} {
+	switch field {
+	case "name":
+		return strings.ToLower(t.Name)
+	case "advertiser_id":
+		return t.AdvertiserID
+	case "created_at":
+		return v1SortTime(t.CreatedAt)
+	case "updated_at":
+		return v1SortTime(t.UpdatedAt)
+	}
+	return t.ID
+}
+
+// v1CloneError maps a failed clone to 409 for taken IDs, 422 with the
+// invalid fields, or 500.
+func (s *Server) v1CloneError(w http.ResponseWriter, err error) {
+	if errors.Is(err, dsp.ErrDuplicateID) {
+		s.v1Error(w, http.StatusConflict, v1Conflict, err.Error(), nil)
+		return
+	}
+	s.v1SaveError(w, err)
+}
+
+// =============================================
+// Clones
+// =============================================
+
+// handleV1CampaignClone saves a draft copy of campaign c (POST
+// /api/v1/campaigns/{id}/clone with a models.CloneRequest).
+func (s *Server) handleV1CampaignClone(w http.ResponseWriter, r *http.Request, c *models.Campaign) {
+	if r.Method != http.MethodPost {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	var req models.CloneRequest
+	if !s.decodeV1(w, r, &req) {
+		return
+	}
+	clone, err := s.templates.CloneCampaign(r.Context(), c, &req)
+	if err != nil {
+		s.v1CloneError(w, err)
+		return
+	}
+	s.audit(r, models.AuditCampaign, clone.ID, nil, clone)
+	w.Header().Set("Location", "/api/v1/campaigns/"+clone.ID)
+	s.v1Response(w, http.StatusCreated, clone.UpdatedAt, clone)
+}
+
+// handleV1LineItemClone adds an inactive copy of line item liID of c to c,
+// or to the campaign named by the request's campaign_id (POST
+// /api/v1/campaigns/{id}/line_items/{line_item_id}/clone).
+func (s *Server) handleV1LineItemClone(w http.ResponseWriter, r *http.Request, c *models.Campaign, liID string) {
+	if r.Method != http.MethodPost {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	idx := findLineItem(c, liID)
+	if idx < 0 {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "line item "+liID+" not found", nil)
+		return
+	}
+	var req models.CloneRequest
+	if !s.decodeV1(w, r, &req) {
+		return
+	}
+	to := c
+	if req.CampaignID != "" && req.CampaignID != c.ID {
+		var ok bool
+		if to, ok = s.v1Campaign(w, r, req.CampaignID); !ok {
+			return
+		}
+	}
+
+	updated, err := s.templates.CloneLineItem(r.Context(), &c.LineItems[idx], to, &req)
+	if err != nil {
+		s.v1CloneError(w, err)
+		return
+	}
+	s.audit(r, models.AuditCampaign, to.ID, to, updated)
+	clone := updated.LineItems[len(updated.LineItems)-1]
+	w.Header().Set("Location", "/api/v1/campaigns/"+to.ID+"/line_items/"+clone.ID)
+	s.v1Response(w, http.StatusCreated, updated.UpdatedAt, clone)
+}
+
+// handleV1CreativeByID serves /api/v1/creatives/{id}/clone, which saves a
+// copy of a library creative.
+func (s *Server) handleV1CreativeByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/creatives/"), "/")
+	if parts[0] == "" || len(parts) != 2 || parts[1] != "clone" {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+
+	id := parts[0]
+	cr, err := s.creativeService.GetCreative(r.Context(), id)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); cr == nil || (scope != "" && cr.AdvertiserID != scope) {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "creative "+id+" not found", nil)
+		return
+	}
+	var req models.CloneRequest
+	if !s.decodeV1(w, r, &req) {
+		return
+	}
+	clone, err := s.templates.CloneCreative(r.Context(), cr, &req)
+	if err != nil {
+		s.v1CloneError(w, err)
+		return
+	}
+	s.audit(r, models.AuditCreative, clone.ID, nil, clone)
+	w.Header().Set("Location", "/api/creatives/"+clone.ID)
+	s.v1Response(w, http.StatusCreated, clone.UpdatedAt, clone)
+}
+
+// =============================================
+// Templates
+// =============================================
+
+// handleV1Templates lists (GET) and creates (POST) campaign templates.
+// Lists filter by advertiser_id and sort by sort (templateSortFields;
+// default name). A new template with a source_campaign_id copies that
+// campaign as its blueprint.
+func (s *Server) handleV1Templates(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		params, err := parseV1List(r, templateSortFields, "name")
+		if err != nil {
+			s.v1Error(w, http.StatusBadRequest, v1InvalidRequest, err.Error(), nil)
+			return
+		}
+		advertiserID := r.URL.Query().Get("advertiser_id")
+		if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+			advertiserID = scope
+		}
+		list, err := s.templates.ListTemplates(r.Context(), advertiserID)
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to list", nil)
+			return
+		}
+		rows := make([]v1Row, 0, len(list))
+		for _, t := range list {
+			rows = append(rows, v1Row{id: t.ID, key: templateSortValue(t, strings.TrimPrefix(params.sort, "-")), item: t})
+		}
+		s.jsonResponse(w, paginateV1(rows, params))
+
+	case http.MethodPost:
+		var t models.CampaignTemplate
+		if !s.decodeV1(w, r, &t) {
+			return
+		}
+		if t.ID == "" {
+			t.ID = uuid.New().String()
+		}
+		if t.SourceCampaignID != "" {
+			c, ok := s.v1Campaign(w, r, t.SourceCampaignID)
+			if !ok {
+				return
+			}
+			if err := s.templates.SetTemplateCampaign(&t, c); err != nil {
+				s.v1Error(w, http.StatusInternalServerError, v1InternalError, err.Error(), nil)
+				return
+			}
+		}
+		if !s.allowTemplateWrite(w, r, &t) {
+			return
+		}
+		existing, err := s.templates.GetTemplate(r.Context(), t.ID)
+		if err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+			return
+		}
+		if existing != nil {
+			s.v1Error(w, http.StatusConflict, v1Conflict, "template "+t.ID+" already exists", nil)
+			return
+		}
+		t.CreatedAt = time.Time{}
+		if err := s.templates.SaveTemplate(r.Context(), &t); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditTemplate, t.ID, nil, &t)
+		w.Header().Set("Location", "/api/v1/templates/"+t.ID)
+		s.v1Response(w, http.StatusCreated, t.UpdatedAt, t)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// handleV1TemplateByID serves /api/v1/templates/{id} and creates draft
+// campaigns from it under /api/v1/templates/{id}/campaigns (POST with a
+// models.CloneRequest).
+func (s *Server) handleV1TemplateByID(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/v1/templates/"), "/")
+	id := parts[0]
+	if id == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "campaigns") {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "not found", nil)
+		return
+	}
+
+	t, err := s.templates.GetTemplate(r.Context(), id)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return
+	}
+	if scope := middleware.GetAdvertiserScope(r.Context()); t == nil || (scope != "" && t.AdvertiserID != scope) {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "template "+id+" not found", nil)
+		return
+	}
+
+	if len(parts) == 2 {
+		if r.Method != http.MethodPost {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		var req models.CloneRequest
+		if !s.decodeV1(w, r, &req) {
+			return
+		}
+		c, err := s.templates.CreateFromTemplate(r.Context(), t, &req)
+		if err != nil {
+			s.v1CloneError(w, err)
+			return
+		}
+		s.audit(r, models.AuditCampaign, c.ID, nil, c)
+		w.Header().Set("Location", "/api/v1/campaigns/"+c.ID)
+		s.v1Response(w, http.StatusCreated, c.UpdatedAt, c)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if notModified(w, r, t.UpdatedAt) {
+			return
+		}
+		s.v1Response(w, http.StatusOK, t.UpdatedAt, t)
+
+	case http.MethodPut, http.MethodPatch:
+		if !s.checkIfMatch(w, r, t.UpdatedAt) {
+			return
+		}
+		var updated models.CampaignTemplate
+		if r.Method == http.MethodPut {
+			if !s.decodeV1(w, r, &updated) || !s.checkBodyVersion(w, updated.UpdatedAt, t.UpdatedAt) {
+				return
+			}
+		} else if !s.mergePatch(w, r, t, t.UpdatedAt, &updated) {
+			return
+		}
+		if updated.ID != "" && updated.ID != id {
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "id", Message: "can't be changed"}})
+			return
+		}
+		updated.ID = id
+		updated.CreatedAt = t.CreatedAt
+		updated.SourceCampaignID = t.SourceCampaignID
+		if !s.allowTemplateWrite(w, r, &updated) {
+			return
+		}
+		if err := s.templates.SaveTemplate(r.Context(), &updated); err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditTemplate, id, t, &updated)
+		s.v1Response(w, http.StatusOK, updated.UpdatedAt, updated)
+
+	case http.MethodDelete:
+		// Campaigns created from the template are kept.
+		if !s.checkIfMatch(w, r, t.UpdatedAt) {
+			return
+		}
+		if err := s.templates.DeleteTemplate(r.Context(), id); err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to delete: "+err.Error(), nil)
+			return
+		}
+		s.audit(r, models.AuditTemplate, id, t, nil)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
+
+// allowTemplateWrite restricts templates saved with a key restricted to
+// an advertiser to that advertiser.
+func (s *Server) allowTemplateWrite(w http.ResponseWriter, r *http.Request, t *models.CampaignTemplate) bool {
+	scope := middleware.GetAdvertiserScope(r.Context())
+	if scope == "" {
+		return true
+	}
+	if t.AdvertiserID == "" {
+		t.AdvertiserID = scope
+	}
+	return s.allowAdvertiser(w, r, t.AdvertiserID)
+}
+
+// =============================================
+// Drafts
+// =============================================
+
+// handleV1Drafts lists the campaign drafts, by campaign ID.
+func (s *Server) handleV1Drafts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+		return
+	}
+	advertiserID := r.URL.Query().Get("advertiser_id")
+	if scope := middleware.GetAdvertiserScope(r.Context()); scope != "" {
+		advertiserID = scope
+	}
+	list, err := s.templates.ListDrafts(r.Context(), advertiserID)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to list", nil)
+		return
+	}
+	page := v1Page{Data: make([]interface{}, len(list))}
+	for i, d := range list {
+		page.Data[i] = d
+	}
+	s.jsonResponse(w, page)
+}
+
+// handleV1CampaignDraft serves the draft of campaign c under
+// /api/v1/campaigns/{id}/draft: GET it, PUT or PATCH the staged campaign
+// (a patch applies to the draft, or to the live campaign if there is none
+// yet), DELETE to discard it. POST /api/v1/campaigns/{id}/draft/publish
+// makes it live; it responds 409 if the campaign changed since the draft
+// was started, unless force=true. Draft ETags are of the draft's
+// updated_at.
+func (s *Server) handleV1CampaignDraft(w http.ResponseWriter, r *http.Request, c *models.Campaign, publish bool) {
+	d, err := s.templates.GetDraft(r.Context(), c.ID)
+	if err != nil {
+		s.v1Error(w, http.StatusInternalServerError, v1InternalError, "error: "+err.Error(), nil)
+		return
+	}
+	noDraft := func() {
+		s.v1Error(w, http.StatusNotFound, v1NotFound, "campaign "+c.ID+" has no draft", nil)
+	}
+
+	if publish {
+		if r.Method != http.MethodPost {
+			s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+			return
+		}
+		if d == nil {
+			noDraft()
+			return
+		}
+		if !s.checkIfMatch(w, r, d.UpdatedAt) {
+			return
+		}
+		published, err := s.templates.PublishDraft(r.Context(), c, d, r.URL.Query().Get("force") == "true")
+		if err != nil {
+			if errors.Is(err, dsp.ErrDraftConflict) {
+				s.v1Error(w, http.StatusConflict, v1Conflict, err.Error()+"; publish with force=true to overwrite it", nil)
+				return
+			}
+			s.v1SaveError(w, err)
+			return
+		}
+		s.audit(r, models.AuditCampaign, c.ID, c, published)
+		s.v1Response(w, http.StatusOK, published.UpdatedAt, published)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if d == nil {
+			noDraft()
+			return
+		}
+		if notModified(w, r, d.UpdatedAt) {
+			return
+		}
+		s.v1Response(w, http.StatusOK, d.UpdatedAt, d)
+
+	case http.MethodPut, http.MethodPatch:
+		current := c
+		if d != nil {
+			if !s.checkIfMatch(w, r, d.UpdatedAt) {
+				return
+			}
+			current = &d.Campaign
+		}
+		var staged models.Campaign
+		if r.Method == http.MethodPut {
+			if !s.decodeV1(w, r, &staged) || !s.checkBodyVersion(w, staged.UpdatedAt, current.UpdatedAt) {
+				return
+			}
+		} else if !s.mergePatch(w, r, current, current.UpdatedAt, &staged) {
+			return
+		}
+		if staged.ID != "" && staged.ID != c.ID {
+			s.v1Error(w, http.StatusUnprocessableEntity, v1ValidationFailed, "validation failed",
+				[]models.FieldError{{Field: "id", Message: "can't be changed"}})
+			return
+		}
+		staged.ID = c.ID
+		if !s.allowCampaignWrite(w, r, &staged) {
+			return
+		}
+		saved, err := s.templates.SaveDraft(r.Context(), middleware.GetPrincipal(r.Context()), c, &staged)
+		if err != nil {
+			s.v1SaveError(w, err)
+			return
+		}
+		s.v1Response(w, http.StatusOK, saved.UpdatedAt, saved)
+
+	case http.MethodDelete:
+		if d == nil {
+			noDraft()
+			return
+		}
+		if !s.checkIfMatch(w, r, d.UpdatedAt) {
+			return
+		}
+		if err := s.templates.DiscardDraft(r.Context(), c.ID); err != nil {
+			s.v1Error(w, http.StatusInternalServerError, v1InternalError, "failed to delete: "+err.Error(), nil)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.v1Error(w, http.StatusMethodNotAllowed, v1InvalidRequest, "method not allowed", nil)
+	}
+}
@@ -0,0 +1,304 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/middleware"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// =============================================
+// Auth API - User Sessions
+// =============================================
+
+// loginRequest is the body of login requests. TOTPCode is needed for users
+// with 2FA enabled.
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	TOTPCode string `json:"totp_code"`
+}
+
+// refreshRequest is the body of refresh and logout requests.
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// handleLogin exchanges credentials for an access and refresh token.
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	tokens, err := s.users.Login(r.Context(), req.Email, req.Password, req.TOTPCode, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		s.userError(w, "failed to log in", err)
+		return
+	}
+	s.jsonResponse(w, tokens)
+}
+
+// handleRefresh exchanges a refresh token for new tokens.
+func (s *Server) handleRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		s.errorResponse(w, "refresh_token required", http.StatusBadRequest)
+		return
+	}
+	tokens, err := s.users.Refresh(r.Context(), req.RefreshToken, r.UserAgent(), getClientIP(r))
+	if err != nil {
+		s.userError(w, "failed to refresh", err)
+		return
+	}
+	s.jsonResponse(w, tokens)
+}
+
+// handleLogout ends the session of a refresh token. The access token stays
+// valid until it expires.
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req refreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		s.errorResponse(w, "refresh_token required", http.StatusBadRequest)
+		return
+	}
+	if err := s.users.Logout(r.Context(), req.RefreshToken); err != nil {
+		s.userError(w, "failed to log out", err)
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// handleMe returns the caller and, for users, their account.
+func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	p := middleware.GetPrincipal(r.Context())
+	resp := map[string]interface{}{"principal": p}
+	if p != nil && p.Kind == models.PrincipalUser {
+		u, err := s.users.Get(r.Context(), p.ID)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		resp["user"] = u
+	}
+	s.jsonResponse(w, resp)
+}
+
+// passwordRequest is the body of password change requests.
+type passwordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// handleChangePassword changes the caller's password and ends all of their
+// sessions.
+func (s *Server) handleChangePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+	var req passwordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.errorResponse(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+	if err := s.users.ChangePassword(r.Context(), userID, req.CurrentPassword, req.NewPassword); err != nil {
+		s.userError(w, "failed to change password", err)
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// handleTOTP serves /api/auth/totp/{setup,enable,disable} for the caller's
+// 2FA.
+func (s *Server) handleTOTP(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.URL.Path, "/api/auth/totp/")
+	if action != "setup" && action != "enable" && action != "disable" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, ok := s.requireUser(w, r)
+	if !ok {
+		return
+	}
+
+	if action == "setup" {
+		secret, otpURL, err := s.users.SetupTOTP(r.Context(), userID)
+		if err != nil {
+			s.userError(w, "failed to set up 2FA", err)
+			return
+		}
+		s.jsonResponse(w, map[string]string{"secret": secret, "otpauth_url": otpURL})
+		return
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		s.errorResponse(w, "code required", http.StatusBadRequest)
+		return
+	}
+	var err error
+	if action == "enable" {
+		err = s.users.EnableTOTP(r.Context(), userID, req.Code)
+	} else {
+		err = s.users.DisableTOTP(r.Context(), userID, req.Code)
+	}
+	if err != nil {
+		s.userError(w, "failed to "+action+" 2FA", err)
+		return
+	}
+	s.jsonResponse(w, map[string]string{"status": "ok"})
+}
+
+// requireUser returns the ID of the user making the request, responding
+// 403 to API keys.
+func (s *Server) requireUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	p := middleware.GetPrincipal(r.Context())
+	if p == nil || p.Kind != models.PrincipalUser {
+		s.errorResponse(w, "this endpoint needs a user login", http.StatusForbidden)
+		return "", false
+	}
+	return p.ID, true
+}
+
+// =============================================
+// Admin API - Users
+// =============================================
+
+// userRequest is the body of user creation requests.
+type userRequest struct {
+	Email        string `json:"email"`
+	Name         string `json:"name"`
+	Password     string `json:"password"`
+	Role         string `json:"role"`
+	AdvertiserID string `json:"advertiser_id"`
+}
+
+// handleUsers lists and creates users.
+func (s *Server) handleUsers(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := s.users.List(r.Context())
+		if err != nil {
+			s.errorResponse(w, "failed to list", http.StatusInternalServerError)
+			return
+		}
+		s.jsonResponse(w, list)
+
+	case http.MethodPost:
+		var req userRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		u, err := s.users.Create(r.Context(), &models.UserAccount{
+			Email:        req.Email,
+			Name:         req.Name,
+			Role:         req.Role,
+			AdvertiserID: req.AdvertiserID,
+		}, req.Password)
+		if err != nil {
+			s.userError(w, "failed to create user", err)
+			return
+		}
+		s.jsonResponse(w, u)
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleUserByID serves GET and PATCH /api/users/{id}. Setting a password
+// or disabling a user ends their sessions.
+func (s *Server) handleUserByID(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/users/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		u, err := s.users.Get(r.Context(), id)
+		if err != nil {
+			s.errorResponse(w, "error: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if u == nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.jsonResponse(w, u)
+
+	case http.MethodPatch:
+		var upd dsp.UserUpdate
+		if err := json.NewDecoder(r.Body).Decode(&upd); err != nil {
+			s.errorResponse(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		u, err := s.users.Update(r.Context(), id, &upd)
+		if err != nil {
+			s.userError(w, "failed to update user", err)
+			return
+		}
+		s.jsonResponse(w, u)
+
+	default:
+		s.errorResponse(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// userError maps user service errors to status codes.
+func (s *Server) userError(w http.ResponseWriter, msg string, err error) {
+	switch {
+	case errors.Is(err, dsp.ErrInvalidUser):
+		s.errorResponse(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, dsp.ErrInvalidCredentials), errors.Is(err, dsp.ErrTOTPRequired), errors.Is(err, dsp.ErrInvalidToken):
+		s.errorResponse(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, dsp.ErrUserNotFound), errors.Is(err, dsp.ErrAdvertiserNotFound):
+		s.errorResponse(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, dsp.ErrUserExists):
+		s.errorResponse(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, dsp.ErrLoginDisabled):
+		s.errorResponse(w, err.Error(), http.StatusServiceUnavailable)
+	default:
+		s.errorResponse(w, msg+": "+err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,557 @@
+// Package invoicing generates the monthly closing documents of Russian
+// legal entities (счёт, акт, счёт-фактура) from the billing ledger and
+// renders them to PDF and XLSX.
+package invoicing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidInvoicing wraps validation errors of invoicing requests.
+	ErrInvalidInvoicing = errors.New("invalid invoicing request")
+
+	// ErrPeriodClosed is returned when generating documents for a closed period.
+	ErrPeriodClosed = errors.New("period is closed")
+
+	// ErrDocumentNotFound is returned for unknown document IDs.
+	ErrDocumentNotFound = errors.New("document not found")
+
+	// ErrAdvertiserNotFound is returned when generating for an unknown advertiser.
+	ErrAdvertiserNotFound = errors.New("advertiser not found")
+)
+
+// Service line names and unit
+const (
+	lineSpend = "Услуги по размещению рекламы (RTB)"
+	lineCPA   = "Оплата целевых действий (CPA)"
+	lineUnit  = "усл. ед."
+)
+
+// documentTitles names documents in file metadata.
+var documentTitles = map[models.DocumentType]string{
+	models.DocumentInvoice:    "Счёт на оплату",
+	models.DocumentAct:        "Акт",
+	models.DocumentVATInvoice: "Счёт-фактура",
+}
+
+// contentTypes maps each format to its MIME type.
+var contentTypes = map[models.DocumentFormat]string{
+	models.DocumentFormatPDF:  "application/pdf",
+	models.DocumentFormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// AdvertiserLookup resolves the advertisers documents are issued to.
+type AdvertiserLookup interface {
+	GetAdvertiser(ctx context.Context, id string) (*models.Advertiser, error)
+	ListAdvertisers(ctx context.Context) ([]*models.Advertiser, error)
+}
+
+// File is a rendered document.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Service generates closing documents from the spend and CPA charges in the
+// billing ledger. Documents of a month can be regenerated, keeping their
+// numbers, until the month is closed.
+type Service struct {
+	repo        storage.BillingDocumentRepo
+	ledger      storage.BillingRepo
+	advertisers AdvertiserLookup
+	cfg         config.InvoicingConfig
+	loc         *time.Location
+	logger      *zap.Logger
+	metrics     *metrics.Metrics
+
+	templates map[models.DocumentType]*template.Template
+	font      *trueTypeFont // nil when PDF output is unavailable
+	fontName  string
+
+	// mu serializes generation so each document is numbered once
+	mu         sync.Mutex
+	lastPeriod string // Last period generated by Run
+}
+
+// NewService creates a new invoicing service. Broken template overrides
+// fall back to the built-in templates; a missing font disables PDF output.
+func NewService(
+	repo storage.BillingDocumentRepo,
+	ledger storage.BillingRepo,
+	advertisers AdvertiserLookup,
+	cfg config.InvoicingConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *Service {
+	s := &Service{
+		repo:        repo,
+		ledger:      ledger,
+		advertisers: advertisers,
+		cfg:         cfg,
+		loc:         time.UTC,
+		logger:      logger,
+		metrics:     m,
+	}
+
+	if loc, err := time.LoadLocation(cfg.Timezone); err == nil {
+		s.loc = loc
+	}
+
+	templates, err := loadTemplates(cfg.TemplateDir)
+	if err != nil {
+		logger.Warn("failed to load document templates, using built-in ones", zap.Error(err))
+		templates, _ = loadTemplates("")
+	}
+	s.templates = templates
+
+	if font, err := loadFont(cfg.FontFile); err != nil {
+		logger.Warn("failed to load document font, PDF documents are unavailable",
+			zap.String("font_file", cfg.FontFile), zap.Error(err))
+	} else {
+		s.font = font
+		s.fontName = fontName(cfg.FontFile)
+	}
+	return s
+}
+
+func loadFont(path string) (*trueTypeFont, error) {
+	if path == "" {
+		return nil, errors.New("no font file configured")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrueType(data)
+}
+
+// fontName derives a PDF font name from the font file name.
+func fontName(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	name := strings.Map(func(r rune) rune {
+		if r < 128 && (r == '-' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z') {
+			return r
+		}
+		return -1
+	}, base)
+	if name == "" {
+		return "DocumentFont"
+	}
+	return name
+}
+
+// =============================================
+// Generation
+// =============================================
+
+// Run generates the previous month's documents once a month, on or after
+// the configured day, until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Service) tick(ctx context.Context, now time.Time) {
+	now = now.In(s.loc)
+	if now.Day() < s.cfg.GenerateDay {
+		return
+	}
+	period := time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, s.loc).Format("2006-01")
+	if period == s.lastPeriod {
+		return
+	}
+
+	docs, err := s.Generate(ctx, period, "")
+	if errors.Is(err, ErrPeriodClosed) {
+		s.lastPeriod = period
+		return
+	}
+	if err != nil {
+		// Retried next tick; documents already generated keep their numbers
+		s.logger.Error("failed to generate closing documents", zap.String("period", period), zap.Error(err))
+		return
+	}
+	s.lastPeriod = period
+	s.logger.Info("generated closing documents", zap.String("period", period), zap.Int("documents", len(docs)))
+}
+
+// Generate creates or regenerates the documents of period (YYYY-MM) for
+// one advertiser, or for all advertisers when advertiserID is empty.
+// Advertisers without charges in the period get no documents.
+func (s *Service) Generate(ctx context.Context, period, advertiserID string) ([]*models.BillingDocument, error) {
+	start, err := models.ParsePeriod(period, s.loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoicing, err)
+	}
+	end := start.AddDate(0, 1, 0)
+	if time.Now().Before(end) {
+		return nil, fmt.Errorf("%w: period %s has not ended", ErrInvalidInvoicing, period)
+	}
+
+	var advertisers []*models.Advertiser
+	if advertiserID != "" {
+		adv, err := s.advertisers.GetAdvertiser(ctx, advertiserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get advertiser: %w", err)
+		}
+		if adv == nil {
+			return nil, ErrAdvertiserNotFound
+		}
+		advertisers = []*models.Advertiser{adv}
+	} else {
+		advertisers, err = s.advertisers.ListAdvertisers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list advertisers: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	closed, err := s.repo.GetClosedPeriod(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get closing period: %w", err)
+	}
+	if closed != nil {
+		return nil, fmt.Errorf("%w: %s", ErrPeriodClosed, period)
+	}
+
+	existing, err := s.repo.ListDocuments(ctx, storage.DocumentFilter{AdvertiserID: advertiserID, Period: period})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	byKey := make(map[string]*models.BillingDocument, len(existing))
+	for _, doc := range existing {
+		byKey[doc.AdvertiserID+"/"+string(doc.Type)] = doc
+	}
+
+	result := make([]*models.BillingDocument, 0)
+	failed := 0
+	for _, adv := range advertisers {
+		docs, err := s.generateFor(ctx, adv, period, start, end, byKey)
+		if err != nil {
+			if advertiserID != "" {
+				return nil, err
+			}
+			failed++
+			s.logger.Error("failed to generate closing documents",
+				zap.String("advertiser_id", adv.ID), zap.String("period", period), zap.Error(err))
+			continue
+		}
+		result = append(result, docs...)
+	}
+	if failed > 0 {
+		return result, fmt.Errorf("failed to generate documents for %d advertisers", failed)
+	}
+	return result, nil
+}
+
+// generateFor writes the documents of one advertiser.
+func (s *Service) generateFor(
+	ctx context.Context,
+	adv *models.Advertiser,
+	period string,
+	start, end time.Time,
+	existing map[string]*models.BillingDocument,
+) ([]*models.BillingDocument, error) {
+	txs, err := s.ledger.ListTransactions(ctx, storage.BillingFilter{AdvertiserID: adv.ID, From: start, To: end})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing transactions: %w", err)
+	}
+
+	var spend, cpa float64
+	currency := adv.Currency
+	for _, tx := range txs {
+		switch tx.Type {
+		case models.BillingTxSpend:
+			spend -= tx.Amount
+		case models.BillingTxCPA:
+			cpa -= tx.Amount
+		default:
+			continue
+		}
+		currency = tx.Currency
+	}
+	if currency == "" {
+		currency = "RUB"
+	}
+
+	var lines []models.DocumentLine
+	if spend = roundKopecks(spend); spend > 0 {
+		lines = append(lines, s.line(lineSpend, spend))
+	}
+	if cpa = roundKopecks(cpa); cpa > 0 {
+		lines = append(lines, s.line(lineCPA, cpa))
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	var net, vat, total float64
+	for _, l := range lines {
+		net += l.Net
+		vat += l.VATAmount
+		total += l.Total
+	}
+
+	now := time.Now().UTC()
+	date := end.AddDate(0, 0, -1)
+	supplier := sanitizeParty(s.supplier())
+	buyer := sanitizeParty(buyerParty(adv))
+
+	docs := make([]*models.BillingDocument, 0, len(models.DocumentTypes))
+	for _, docType := range models.DocumentTypes {
+		if docType == models.DocumentVATInvoice && s.cfg.VATRate == 0 {
+			continue
+		}
+
+		doc := &models.BillingDocument{}
+		if prev := existing[adv.ID+"/"+string(docType)]; prev != nil {
+			doc.ID, doc.Number, doc.Seq, doc.CreatedAt = prev.ID, prev.Number, prev.Seq, prev.CreatedAt
+		} else {
+			seq, err := s.repo.NextNumber(ctx, docType, date.Year())
+			if err != nil {
+				return nil, fmt.Errorf("failed to number %s: %w", docType, err)
+			}
+			doc.ID = uuid.New().String()
+			doc.Seq = seq
+			doc.Number = s.cfg.NumberPrefix + strconv.FormatInt(seq, 10)
+			doc.CreatedAt = now
+		}
+
+		doc.AdvertiserID = adv.ID
+		doc.Type = docType
+		doc.Date = date
+		doc.Period = period
+		doc.PeriodStart = start
+		doc.PeriodEnd = end
+		doc.Supplier = supplier
+		doc.Buyer = buyer
+		doc.Currency = currency
+		doc.VATRate = s.cfg.VATRate
+		doc.Lines = lines
+		doc.Net = roundKopecks(net)
+		doc.VATAmount = roundKopecks(vat)
+		doc.Total = roundKopecks(total)
+		doc.UpdatedAt = now
+
+		if err := s.repo.UpsertDocument(ctx, doc); err != nil {
+			return nil, err
+		}
+		if s.metrics != nil {
+			s.metrics.RecordDocumentGenerated(string(docType))
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// line splits a billed amount into net and VAT. Billed spend either
+// includes VAT or has it added on top.
+func (s *Service) line(name string, amount float64) models.DocumentLine {
+	rate := s.cfg.VATRate
+	l := models.DocumentLine{Name: name, Unit: lineUnit, Quantity: 1}
+	if s.cfg.VATIncluded {
+		l.Total = amount
+		l.VATAmount = roundKopecks(amount * rate / (100 + rate))
+		l.Net = roundKopecks(l.Total - l.VATAmount)
+	} else {
+		l.Net = amount
+		l.VATAmount = roundKopecks(amount * rate / 100)
+		l.Total = roundKopecks(l.Net + l.VATAmount)
+	}
+	return l
+}
+
+func (s *Service) supplier() models.LegalParty {
+	return models.LegalParty{
+		Name:          s.cfg.SupplierName,
+		TaxID:         s.cfg.SupplierTaxID,
+		KPP:           s.cfg.SupplierKPP,
+		OGRN:          s.cfg.SupplierOGRN,
+		Address:       s.cfg.SupplierAddress,
+		BankName:      s.cfg.SupplierBankName,
+		BIK:           s.cfg.SupplierBIK,
+		AccountNumber: s.cfg.SupplierAccount,
+		CorrAccount:   s.cfg.SupplierCorrAccount,
+		Director:      s.cfg.SupplierDirector,
+		Accountant:    s.cfg.SupplierAccountant,
+	}
+}
+
+func buyerParty(adv *models.Advertiser) models.LegalParty {
+	name := adv.LegalName
+	if name == "" {
+		name = adv.Name
+	}
+	return models.LegalParty{
+		Name:           name,
+		TaxID:          adv.TaxID,
+		KPP:            adv.KPP,
+		OGRN:           adv.OGRN,
+		Address:        adv.Address,
+		BankName:       adv.BankName,
+		BIK:            adv.BIK,
+		AccountNumber:  adv.AccountNumber,
+		ContractNumber: adv.ContractNumber,
+		ContractDate:   adv.ContractDate,
+	}
+}
+
+// sanitizeParty strips characters that break the template layout.
+func sanitizeParty(p models.LegalParty) models.LegalParty {
+	clean := strings.NewReplacer("|", "/", "\r\n", " ", "\n", " ", "\r", " ").Replace
+	for _, f := range []*string{
+		&p.Name, &p.TaxID, &p.KPP, &p.OGRN, &p.Address, &p.BankName, &p.BIK,
+		&p.AccountNumber, &p.CorrAccount, &p.ContractNumber, &p.Director, &p.Accountant,
+	} {
+		*f = strings.TrimSpace(clean(*f))
+	}
+	return p
+}
+
+// roundKopecks rounds to two decimal places.
+func roundKopecks(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// =============================================
+// Closing periods
+// =============================================
+
+// ClosePeriod locks period against regeneration. Only ended periods can be
+// closed; closing a closed period returns the original record.
+func (s *Service) ClosePeriod(ctx context.Context, period, actor string) (*models.ClosingPeriod, error) {
+	start, err := models.ParsePeriod(period, s.loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInvoicing, err)
+	}
+	if time.Now().Before(start.AddDate(0, 1, 0)) {
+		return nil, fmt.Errorf("%w: period %s has not ended", ErrInvalidInvoicing, period)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p := &models.ClosingPeriod{Period: period, ClosedAt: time.Now().UTC(), ClosedBy: actor}
+	if err := s.repo.ClosePeriod(ctx, p); err != nil {
+		return nil, fmt.Errorf("failed to close period: %w", err)
+	}
+	closed, err := s.repo.GetClosedPeriod(ctx, period)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get closing period: %w", err)
+	}
+	if closed == nil {
+		return p, nil
+	}
+	return closed, nil
+}
+
+// ClosedPeriods lists the closed periods.
+func (s *Service) ClosedPeriods(ctx context.Context) ([]*models.ClosingPeriod, error) {
+	return s.repo.ListClosedPeriods(ctx)
+}
+
+// =============================================
+// Documents
+// =============================================
+
+// ListDocuments lists documents matching filter.
+func (s *Service) ListDocuments(ctx context.Context, filter storage.DocumentFilter) ([]*models.BillingDocument, error) {
+	if filter.Type != "" && documentTitles[filter.Type] == "" {
+		return nil, fmt.Errorf("%w: unknown document type %q", ErrInvalidInvoicing, filter.Type)
+	}
+	if filter.Period != "" {
+		if _, err := models.ParsePeriod(filter.Period, s.loc); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInvoicing, err)
+		}
+	}
+	return s.repo.ListDocuments(ctx, filter)
+}
+
+// GetDocument returns a document by ID.
+func (s *Service) GetDocument(ctx context.Context, id string) (*models.BillingDocument, error) {
+	doc, err := s.repo.GetDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, ErrDocumentNotFound
+	}
+	return doc, nil
+}
+
+// Render renders doc with its type's template in format.
+func (s *Service) Render(doc *models.BillingDocument, format models.DocumentFormat) (*File, error) {
+	contentType, ok := contentTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidInvoicing, format)
+	}
+	tmpl := s.templates[doc.Type]
+	if tmpl == nil {
+		return nil, fmt.Errorf("no template for document type %q", doc.Type)
+	}
+
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, doc); err != nil {
+		return nil, fmt.Errorf("failed to execute %s template: %w", doc.Type, err)
+	}
+	l := parseLayout(out.String())
+	title := documentTitles[doc.Type] + " № " + doc.Number
+
+	var data []byte
+	var err error
+	switch format {
+	case models.DocumentFormatPDF:
+		data, err = renderPDF(s.font, s.fontName, l, title)
+	case models.DocumentFormatXLSX:
+		data, err = renderXLSX(l, documentTitles[doc.Type])
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &File{
+		Name:        fmt.Sprintf("%s_%s_%s.%s", doc.Type, fileSafe(doc.Number), doc.Period, format),
+		ContentType: contentType,
+		Data:        data,
+	}, nil
+}
+
+// fileSafe replaces characters that don't belong in file names.
+func fileSafe(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' {
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,266 @@
+package invoicing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// advertiserRepo adapts the in-memory advertiser repo to AdvertiserLookup.
+type advertiserRepo struct {
+	*storage.InMemoryAdvertiserRepo
+}
+
+func (r advertiserRepo) GetAdvertiser(ctx context.Context, id string) (*models.Advertiser, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r advertiserRepo) ListAdvertisers(ctx context.Context) ([]*models.Advertiser, error) {
+	return r.ListAll(ctx)
+}
+
+// invoicingFixture is an invoicing service over in-memory repos, with
+// months in Moscow time.
+type invoicingFixture struct {
+	svc         *Service
+	docs        *storage.InMemoryBillingDocumentRepo
+	ledger      *storage.InMemoryBillingRepo
+	advertisers advertiserRepo
+	cfg         config.InvoicingConfig
+	loc         *time.Location
+}
+
+func newInvoicingFixture(t *testing.T, cfg config.InvoicingConfig) *invoicingFixture {
+	t.Helper()
+	cfg.Timezone = "Europe/Moscow"
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+
+	f := &invoicingFixture{
+		docs:        storage.NewInMemoryBillingDocumentRepo(),
+		advertisers: advertiserRepo{storage.NewInMemoryAdvertiserRepo()},
+		cfg:         cfg,
+		loc:         loc,
+	}
+	f.ledger = storage.NewInMemoryBillingRepo(f.advertisers)
+	f.svc = f.restart()
+	return f
+}
+
+// restart returns a new service over the same repos, as after a restart.
+func (f *invoicingFixture) restart() *Service {
+	return NewService(f.docs, f.ledger, f.advertisers, f.cfg, zap.NewNop(), nil)
+}
+
+func (f *invoicingFixture) advertiser(t *testing.T, id, cur string) {
+	t.Helper()
+	if err := f.advertisers.Upsert(context.Background(), &models.Advertiser{
+		ID: id, Name: id, LegalName: "ООО " + id, TaxID: "7700000000", Currency: cur,
+	}); err != nil {
+		t.Fatalf("Upsert(advertiser) error = %v", err)
+	}
+}
+
+func (f *invoicingFixture) charge(t *testing.T, advertiserID string, txType models.BillingTransactionType, amount float64, cur string, at time.Time) {
+	t.Helper()
+	if err := f.ledger.Apply(context.Background(), &models.BillingTransaction{
+		ID: at.String() + string(txType), AdvertiserID: advertiserID, Type: txType,
+		Amount: amount, Currency: cur, CreatedAt: at,
+	}); err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+}
+
+func (f *invoicingFixture) documents(t *testing.T, period string) []*models.BillingDocument {
+	t.Helper()
+	docs, err := f.svc.ListDocuments(context.Background(), storage.DocumentFilter{Period: period})
+	if err != nil {
+		t.Fatalf("ListDocuments() error = %v", err)
+	}
+	return docs
+}
+
+func TestGeneratePeriodBoundaries(t *testing.T) {
+	f := newInvoicingFixture(t, config.InvoicingConfig{NumberPrefix: "VD-"})
+	f.advertiser(t, "adv-1", "RUB")
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, f.loc)
+	end := start.AddDate(0, 1, 0)
+	f.charge(t, "adv-1", models.BillingTxSpend, -1, "RUB", start.Add(-time.Nanosecond))
+	f.charge(t, "adv-1", models.BillingTxSpend, -10, "RUB", start)
+	f.charge(t, "adv-1", models.BillingTxCPA, -5.5, "RUB", end.Add(-time.Nanosecond))
+	f.charge(t, "adv-1", models.BillingTxSpend, -100, "RUB", end)
+	// Credits are not invoiced
+	f.charge(t, "adv-1", models.BillingTxTopUp, 1000, "RUB", start.Add(time.Hour))
+
+	docs, err := f.svc.Generate(context.Background(), "2026-01", "")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	// No VAT rate: счёт and акт, no счёт-фактура
+	if len(docs) != 2 {
+		t.Fatalf("Generate() = %d documents, want 2", len(docs))
+	}
+	for _, doc := range docs {
+		if doc.Total != 15.5 || len(doc.Lines) != 2 {
+			t.Errorf("%s total = %v in %d lines, want 15.5 in 2", doc.Type, doc.Total, len(doc.Lines))
+		}
+		if !doc.PeriodStart.Equal(start) || !doc.PeriodEnd.Equal(end) {
+			t.Errorf("%s period = %v - %v, want %v - %v", doc.Type, doc.PeriodStart, doc.PeriodEnd, start, end)
+		}
+		if want := time.Date(2026, 1, 31, 0, 0, 0, 0, f.loc); !doc.Date.Equal(want) {
+			t.Errorf("%s date = %v, want %v", doc.Type, doc.Date, want)
+		}
+	}
+	if docs[0].Number != "VD-1" || docs[1].Number != "VD-1" {
+		t.Errorf("numbers = %s, %s; want VD-1 for each type", docs[0].Number, docs[1].Number)
+	}
+
+	// The charge a nanosecond before Moscow midnight belongs to December
+	t.Run("previous month", func(t *testing.T) {
+		docs, err := f.svc.Generate(context.Background(), "2025-12", "adv-1")
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if len(docs) != 2 || docs[0].Total != 1 {
+			t.Errorf("December documents = %+v, want 2 for the charge just before Moscow midnight", docs)
+		}
+	})
+
+	invalid := []struct {
+		name   string
+		period string
+		want   error
+	}{
+		{"malformed", "2026-1", ErrInvalidInvoicing},
+		{"not ended", time.Now().In(f.loc).Format("2006-01"), ErrInvalidInvoicing},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.svc.Generate(context.Background(), tt.period, ""); !errors.Is(err, tt.want) {
+				t.Errorf("Generate(%q) error = %v, want %v", tt.period, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateCurrencyAndVAT(t *testing.T) {
+	tests := []struct {
+		name         string
+		vatRate      float64
+		vatIncluded  bool
+		advCurrency  string
+		txCurrency   string
+		wantCurrency string
+		wantDocs     int
+		wantNet      float64
+		wantVAT      float64
+		wantTotal    float64
+	}{
+		{"no VAT", 0, false, "RUB", "RUB", "RUB", 2, 120, 0, 120},
+		{"VAT added", 20, false, "RUB", "RUB", "RUB", 3, 120, 24, 144},
+		{"VAT included", 20, true, "RUB", "RUB", "RUB", 3, 100, 20, 120},
+		{"ledger currency", 20, true, "USD", "EUR", "EUR", 3, 100, 20, 120},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newInvoicingFixture(t, config.InvoicingConfig{VATRate: tt.vatRate, VATIncluded: tt.vatIncluded})
+			f.advertiser(t, "adv-1", tt.advCurrency)
+			f.charge(t, "adv-1", models.BillingTxSpend, -120, tt.txCurrency, time.Date(2026, 1, 15, 12, 0, 0, 0, f.loc))
+
+			docs, err := f.svc.Generate(context.Background(), "2026-01", "adv-1")
+			if err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+			if len(docs) != tt.wantDocs {
+				t.Fatalf("Generate() = %d documents, want %d", len(docs), tt.wantDocs)
+			}
+			for _, doc := range docs {
+				if doc.Currency != tt.wantCurrency {
+					t.Errorf("%s currency = %s, want %s", doc.Type, doc.Currency, tt.wantCurrency)
+				}
+				if doc.Net != tt.wantNet || doc.VATAmount != tt.wantVAT || doc.Total != tt.wantTotal {
+					t.Errorf("%s = %v net + %v VAT = %v, want %v + %v = %v", doc.Type,
+						doc.Net, doc.VATAmount, doc.Total, tt.wantNet, tt.wantVAT, tt.wantTotal)
+				}
+			}
+		})
+	}
+}
+
+func TestTickGeneratesOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newInvoicingFixture(t, config.InvoicingConfig{GenerateDay: 3, NumberPrefix: "VD-"})
+	f.advertiser(t, "adv-1", "RUB")
+	f.advertiser(t, "adv-2", "RUB")
+	f.advertiser(t, "adv-idle", "RUB")
+	f.charge(t, "adv-1", models.BillingTxSpend, -10, "RUB", time.Date(2026, 1, 10, 0, 0, 0, 0, f.loc))
+	f.charge(t, "adv-2", models.BillingTxCPA, -20, "RUB", time.Date(2026, 1, 20, 0, 0, 0, 0, f.loc))
+
+	// Before the generation day
+	f.svc.tick(ctx, time.Date(2026, 2, 2, 23, 0, 0, 0, f.loc))
+	if docs := f.documents(t, "2026-01"); len(docs) != 0 {
+		t.Fatalf("%d documents before the generation day, want 0", len(docs))
+	}
+
+	f.svc.tick(ctx, time.Date(2026, 2, 3, 0, 30, 0, 0, f.loc))
+	first := f.documents(t, "2026-01")
+	if len(first) != 4 {
+		t.Fatalf("%d documents after the first tick, want 4 (no documents without charges)", len(first))
+	}
+	numbers := make(map[string]string)
+	updated := make(map[string]time.Time)
+	for _, doc := range first {
+		numbers[doc.ID] = doc.Number
+		updated[doc.ID] = doc.UpdatedAt
+	}
+
+	// A charge debited late but dated in January: the second tick leaves
+	// the month alone, a restart's regenerates it under the same numbers
+	f.charge(t, "adv-1", models.BillingTxSpend, -5, "RUB", time.Date(2026, 1, 31, 0, 0, 0, 0, f.loc))
+	time.Sleep(time.Millisecond)
+	f.svc.tick(ctx, time.Date(2026, 2, 3, 1, 30, 0, 0, f.loc))
+	for _, doc := range f.documents(t, "2026-01") {
+		if !doc.UpdatedAt.Equal(updated[doc.ID]) {
+			t.Errorf("second tick regenerated %s %s", doc.Type, doc.Number)
+		}
+	}
+
+	f.svc = f.restart()
+	f.svc.tick(ctx, time.Date(2026, 2, 3, 2, 30, 0, 0, f.loc))
+	after := f.documents(t, "2026-01")
+	if len(after) != 4 {
+		t.Fatalf("%d documents after a restart's tick, want 4", len(after))
+	}
+	for _, doc := range after {
+		if numbers[doc.ID] != doc.Number {
+			t.Errorf("%s %s of %s renumbered from %q", doc.Type, doc.Number, doc.AdvertiserID, numbers[doc.ID])
+		}
+		if doc.AdvertiserID == "adv-1" && doc.Total != 15 {
+			t.Errorf("regenerated %s total = %v, want 15", doc.Type, doc.Total)
+		}
+	}
+	seq, err := f.docs.NextNumber(ctx, models.DocumentInvoice, 2026)
+	if err != nil || seq != 3 {
+		t.Errorf("next invoice number = %d, %v; want 3", seq, err)
+	}
+
+	// A closed period is left alone
+	if _, err := f.svc.ClosePeriod(ctx, "2026-01", "admin"); err != nil {
+		t.Fatalf("ClosePeriod() error = %v", err)
+	}
+	f.svc = f.restart()
+	f.svc.tick(ctx, time.Date(2026, 2, 4, 0, 0, 0, 0, f.loc))
+	if _, err := f.svc.Generate(ctx, "2026-01", ""); !errors.Is(err, ErrPeriodClosed) {
+		t.Errorf("Generate() of a closed period error = %v, want %v", err, ErrPeriodClosed)
+	}
+}
@@ -0,0 +1,214 @@
+package invoicing
+
+import (
+	"embed"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// Document templates are text/templates executed with a
+// *models.BillingDocument. Their output is a line-based layout that both
+// the PDF and the XLSX writer render:
+//
+//	@landscape            landscape pages (first line)
+//	# Title               heading
+//	@cols 5 50 10 35      relative column widths of the tables that follow
+//	|* No | Name | ...    table header row
+//	| 1 | Ads | >100,00   table row; a cell starting with ">" is right-aligned
+//	(blank line)          vertical space
+//	anything else         paragraph, wrapped to the page width
+//
+// Cells are separated by "|", so values must not contain it; parties are
+// sanitized when documents are generated.
+
+//go:embed templates/*.tmpl
+var builtinTemplates embed.FS
+
+type rowKind int
+
+const (
+	rowText rowKind = iota
+	rowHeading
+	rowHeader
+	rowTable
+	rowSpacer
+)
+
+type layoutCell struct {
+	text  string
+	right bool
+}
+
+type layout struct {
+	landscape bool
+	rows      []layoutRow
+}
+
+type layoutRow struct {
+	kind   rowKind
+	text   string       // rowText, rowHeading
+	cells  []layoutCell // rowHeader, rowTable
+	widths []float64    // Relative column widths of a table row
+}
+
+// parseLayout splits template output into rows.
+func parseLayout(out string) *layout {
+	l := &layout{}
+	var rows []layoutRow
+	var widths []float64
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case line == "":
+			// Collapse runs of blank lines
+			if len(rows) > 0 && rows[len(rows)-1].kind != rowSpacer {
+				rows = append(rows, layoutRow{kind: rowSpacer})
+			}
+		case line == "@landscape":
+			l.landscape = true
+		case strings.HasPrefix(line, "@cols"):
+			widths = nil
+			for _, f := range strings.Fields(strings.TrimPrefix(line, "@cols")) {
+				if w, err := strconv.ParseFloat(f, 64); err == nil && w > 0 {
+					widths = append(widths, w)
+				}
+			}
+		case strings.HasPrefix(line, "# "):
+			rows = append(rows, layoutRow{kind: rowHeading, text: strings.TrimSpace(line[2:])})
+		case strings.HasPrefix(line, "|"):
+			kind := rowTable
+			body := line[1:]
+			if strings.HasPrefix(body, "*") {
+				kind = rowHeader
+				body = body[1:]
+			}
+			var cells []layoutCell
+			for _, c := range strings.Split(body, "|") {
+				c = strings.TrimSpace(c)
+				cell := layoutCell{text: c}
+				if strings.HasPrefix(c, ">") {
+					cell = layoutCell{text: strings.TrimSpace(c[1:]), right: true}
+				}
+				cells = append(cells, cell)
+			}
+			rows = append(rows, layoutRow{kind: kind, cells: cells, widths: columnWidths(widths, len(cells))})
+		default:
+			rows = append(rows, layoutRow{kind: rowText, text: strings.TrimSpace(line)})
+		}
+	}
+	l.rows = rows
+	return l
+}
+
+// columnWidths returns n relative widths, padding or trimming the declared
+// ones with equal widths.
+func columnWidths(declared []float64, n int) []float64 {
+	widths := make([]float64, n)
+	for i := range widths {
+		if i < len(declared) {
+			widths[i] = declared[i]
+		} else {
+			widths[i] = 1
+		}
+	}
+	return widths
+}
+
+// =============================================
+// Template functions
+// =============================================
+
+var monthsNominative = []string{"", "январь", "февраль", "март", "апрель", "май", "июнь", "июль", "август", "сентябрь", "октябрь", "ноябрь", "декабрь"}
+var monthsGenitive = []string{"", "января", "февраля", "марта", "апреля", "мая", "июня", "июля", "августа", "сентября", "октября", "ноября", "декабря"}
+
+// formatMoney formats 1234.5 as "1 234,50".
+func formatMoney(v float64) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	intPart, frac := s[:len(s)-3], s[len(s)-2:]
+	var sb strings.Builder
+	if v < 0 {
+		sb.WriteString("-")
+	}
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteString(" ")
+		}
+		sb.WriteRune(r)
+	}
+	sb.WriteString("," + frac)
+	return sb.String()
+}
+
+var templateFuncs = template.FuncMap{
+	"money": formatMoney,
+	"qty": func(v float64) string {
+		return strings.Replace(strconv.FormatFloat(v, 'f', -1, 64), ".", ",", 1)
+	},
+	"date": func(t time.Time) string { return t.Format("02.01.2006") },
+	"dateLong": func(t time.Time) string {
+		return fmt.Sprintf("%d %s %d г.", t.Day(), monthsGenitive[t.Month()], t.Year())
+	},
+	"month": func(t time.Time) string {
+		return fmt.Sprintf("%s %d г.", monthsNominative[t.Month()], t.Year())
+	},
+	"words": amountWords,
+	"vat": func(rate float64) string {
+		if rate == 0 {
+			return "без НДС"
+		}
+		return strconv.FormatFloat(rate, 'f', -1, 64) + "%"
+	},
+	"currencyName": func(code string) string {
+		if code == "RUB" {
+			return "руб."
+		}
+		return code
+	},
+	// currencyOfficial is the currency's name and OKV code for счёт-фактура
+	"currencyOfficial": func(code string) string {
+		switch code {
+		case "RUB":
+			return "Российский рубль, 643"
+		case "USD":
+			return "Доллар США, 840"
+		case "EUR":
+			return "Евро, 978"
+		}
+		return code
+	},
+	"inc": func(i int) int { return i + 1 },
+}
+
+// loadTemplates parses the built-in templates and overrides them with the
+// ones found in dir.
+func loadTemplates(dir string) (map[models.DocumentType]*template.Template, error) {
+	templates := make(map[models.DocumentType]*template.Template, len(models.DocumentTypes))
+	for _, docType := range models.DocumentTypes {
+		name := string(docType) + ".tmpl"
+		src, err := builtinTemplates.ReadFile("templates/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if dir != "" {
+			if override, err := os.ReadFile(filepath.Join(dir, name)); err == nil {
+				src = override
+			} else if !os.IsNotExist(err) {
+				return nil, fmt.Errorf("failed to read template %s: %w", name, err)
+			}
+		}
+		t, err := template.New(name).Funcs(templateFuncs).Parse(string(src))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse template %s: %w", name, err)
+		}
+		templates[docType] = t
+	}
+	return templates, nil
+}
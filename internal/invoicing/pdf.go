@@ -0,0 +1,344 @@
+package invoicing
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// ErrPDFUnavailable is returned when PDF output is requested but no font
+// could be loaded. Cyrillic text needs an embedded font; the standard PDF
+// fonts don't cover it.
+var ErrPDFUnavailable = errors.New("pdf rendering unavailable: no font loaded")
+
+const (
+	pdfPageWidth   = 595.28 // A4 in points
+	pdfPageHeight  = 841.89
+	pdfMargin      = 40.0
+	pdfBodySize    = 9.0
+	pdfHeadingSize = 13.0
+	pdfLineHeight  = 1.3 // Multiple of the font size
+	pdfCellPadding = 3.0
+)
+
+// pdfWriter lays out a document on pages using one embedded TrueType font.
+type pdfWriter struct {
+	font     *trueTypeFont
+	fontName string
+	width    float64
+	height   float64
+	pages    []*bytes.Buffer
+	page     *bytes.Buffer
+	y        float64         // Distance of the cursor from the top edge
+	used     map[uint16]rune // Glyphs drawn, for the width array and ToUnicode
+}
+
+// renderPDF renders a layout to a PDF file.
+func renderPDF(font *trueTypeFont, fontName string, l *layout, title string) ([]byte, error) {
+	if font == nil {
+		return nil, ErrPDFUnavailable
+	}
+	w := &pdfWriter{
+		font:     font,
+		fontName: fontName,
+		width:    pdfPageWidth,
+		height:   pdfPageHeight,
+		used:     make(map[uint16]rune),
+	}
+	if l.landscape {
+		w.width, w.height = w.height, w.width
+	}
+	w.newPage()
+
+	for _, row := range l.rows {
+		switch row.kind {
+		case rowHeading:
+			w.heading(row.text)
+		case rowText:
+			w.paragraph(row.text)
+		case rowHeader, rowTable:
+			w.tableRow(row)
+		case rowSpacer:
+			w.y += pdfBodySize * pdfLineHeight * 0.6
+		}
+	}
+	return w.bytes(title)
+}
+
+func (w *pdfWriter) newPage() {
+	w.page = &bytes.Buffer{}
+	w.pages = append(w.pages, w.page)
+	w.y = pdfMargin
+}
+
+// ensure starts a new page unless h points fit below the cursor. Content
+// taller than a whole page is drawn anyway and overflows.
+func (w *pdfWriter) ensure(h float64) {
+	if w.y+h > w.height-pdfMargin && w.y > pdfMargin {
+		w.newPage()
+	}
+}
+
+func (w *pdfWriter) contentWidth() float64 {
+	return w.width - 2*pdfMargin
+}
+
+func (w *pdfWriter) heading(text string) {
+	lh := pdfHeadingSize * pdfLineHeight
+	for _, line := range w.wrap(text, w.contentWidth(), pdfHeadingSize) {
+		w.ensure(lh)
+		x := (w.width - w.font.textWidth(line, pdfHeadingSize)) / 2
+		w.text(x, w.y+pdfHeadingSize, pdfHeadingSize, line)
+		w.y += lh
+	}
+	w.y += pdfBodySize * 0.5
+}
+
+func (w *pdfWriter) paragraph(text string) {
+	lh := pdfBodySize * pdfLineHeight
+	for _, line := range w.wrap(text, w.contentWidth(), pdfBodySize) {
+		w.ensure(lh)
+		w.text(pdfMargin, w.y+pdfBodySize, pdfBodySize, line)
+		w.y += lh
+	}
+}
+
+func (w *pdfWriter) tableRow(row layoutRow) {
+	var total float64
+	for _, cw := range row.widths {
+		total += cw
+	}
+	widths := make([]float64, len(row.widths))
+	for i, cw := range row.widths {
+		widths[i] = cw / total * w.contentWidth()
+	}
+
+	lh := pdfBodySize * pdfLineHeight
+	lines := make([][]string, len(row.cells))
+	maxLines := 1
+	for i, cell := range row.cells {
+		lines[i] = w.wrap(cell.text, widths[i]-2*pdfCellPadding, pdfBodySize)
+		if len(lines[i]) > maxLines {
+			maxLines = len(lines[i])
+		}
+	}
+	h := float64(maxLines)*lh + 2*pdfCellPadding
+	w.ensure(h)
+
+	x := pdfMargin
+	bottom := w.height - w.y - h
+	for i, cell := range row.cells {
+		if row.kind == rowHeader {
+			fmt.Fprintf(w.page, "0.9 g %.2f %.2f %.2f %.2f re f 0 g\n", x, bottom, widths[i], h)
+		}
+		fmt.Fprintf(w.page, "0.5 w %.2f %.2f %.2f %.2f re S\n", x, bottom, widths[i], h)
+		for j, line := range lines[i] {
+			tx := x + pdfCellPadding
+			if cell.right {
+				tx = x + widths[i] - pdfCellPadding - w.font.textWidth(line, pdfBodySize)
+			} else if row.kind == rowHeader {
+				tx = x + (widths[i]-w.font.textWidth(line, pdfBodySize))/2
+			}
+			w.text(tx, w.y+pdfCellPadding+float64(j)*lh+pdfBodySize, pdfBodySize, line)
+		}
+		x += widths[i]
+	}
+	w.y += h
+}
+
+// text draws s with its baseline top points below the top edge.
+func (w *pdfWriter) text(x, top, size float64, s string) {
+	if s == "" {
+		return
+	}
+	var hex strings.Builder
+	for _, r := range s {
+		g := w.font.glyph(r)
+		if _, ok := w.used[g]; !ok {
+			w.used[g] = r
+		}
+		fmt.Fprintf(&hex, "%04X", g)
+	}
+	fmt.Fprintf(w.page, "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, x, w.height-top, hex.String())
+}
+
+// wrap breaks s into lines no wider than width, splitting words that don't
+// fit on a line of their own.
+func (w *pdfWriter) wrap(s string, width, size float64) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if w.font.textWidth(candidate, size) <= width {
+			line = candidate
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+			line = ""
+		}
+		for w.font.textWidth(word, size) > width {
+			runes := []rune(word)
+			n := 1
+			for n < len(runes) && w.font.textWidth(string(runes[:n+1]), size) <= width {
+				n++
+			}
+			lines = append(lines, string(runes[:n]))
+			word = string(runes[n:])
+		}
+		line = word
+	}
+	if line != "" || len(lines) == 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// =============================================
+// Serialization
+// =============================================
+
+// bytes writes the PDF objects: catalog, page tree, the Type0 font with
+// its CIDFontType2 descendant, descriptor, font file and ToUnicode map,
+// then a page and content stream per page.
+func (w *pdfWriter) bytes(title string) ([]byte, error) {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	stream := func(dict string, data []byte) error {
+		compressed, err := deflate(data)
+		if err != nil {
+			return err
+		}
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< %s /Filter /FlateDecode /Length %d >>\nstream\n", len(offsets), dict, len(compressed))
+		buf.Write(compressed)
+		buf.WriteString("\nendstream\nendobj\n")
+		return nil
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Fixed objects 1-7; pages start at 8
+	const firstPage = 8
+	kids := make([]string, len(w.pages))
+	for i := range w.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+i*2)
+	}
+	f := w.font
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(w.pages)))
+	obj(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [4 0 R] /ToUnicode 7 0 R >>", w.fontName))
+	obj(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor 5 0 R /DW 500 /W [%s] /CIDToGIDMap /Identity >>",
+		w.fontName, w.widthArray()))
+	obj(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 6 0 R >>",
+		w.fontName, f.scale(f.bbox[0]), f.scale(f.bbox[1]), f.scale(f.bbox[2]), f.scale(f.bbox[3]),
+		f.scale(f.ascent), f.scale(f.descent), f.scale(f.capHeight)))
+	if err := stream(fmt.Sprintf("/Length1 %d", len(f.data)), f.data); err != nil {
+		return nil, err
+	}
+	if err := stream("", w.toUnicode()); err != nil {
+		return nil, err
+	}
+
+	for i, page := range w.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			w.width, w.height, firstPage+i*2+1))
+		if err := stream("", page.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+	obj(fmt.Sprintf("<< /Title %s /Producer (vector-dsp) >>", pdfTextString(title)))
+	info := len(offsets)
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, info, xref)
+	return buf.Bytes(), nil
+}
+
+// widthArray lists the advance widths of the drawn glyphs.
+func (w *pdfWriter) widthArray() string {
+	var sb strings.Builder
+	for _, g := range w.usedGlyphs() {
+		fmt.Fprintf(&sb, "%d [%.0f] ", g, w.font.advance(g))
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// toUnicode builds the CMap that maps glyphs back to text for copying and
+// search.
+func (w *pdfWriter) toUnicode() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// .notdef has no text
+	glyphs := w.usedGlyphs()
+	if len(glyphs) > 0 && glyphs[0] == 0 {
+		glyphs = glyphs[1:]
+	}
+	for start := 0; start < len(glyphs); start += 100 {
+		end := start + 100
+		if end > len(glyphs) {
+			end = len(glyphs)
+		}
+		fmt.Fprintf(&buf, "%d beginbfchar\n", end-start)
+		for _, g := range glyphs[start:end] {
+			var dst strings.Builder
+			for _, u := range utf16.Encode([]rune{w.used[g]}) {
+				fmt.Fprintf(&dst, "%04X", u)
+			}
+			fmt.Fprintf(&buf, "<%04X> <%s>\n", g, dst.String())
+		}
+		buf.WriteString("endbfchar\n")
+	}
+	buf.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return buf.Bytes()
+}
+
+func (w *pdfWriter) usedGlyphs() []uint16 {
+	glyphs := make([]uint16, 0, len(w.used))
+	for g := range w.used {
+		glyphs = append(glyphs, g)
+	}
+	sort.Slice(glyphs, func(i, j int) bool { return glyphs[i] < glyphs[j] })
+	return glyphs
+}
+
+// pdfTextString encodes s as a UTF-16BE hex string with a byte order mark.
+func pdfTextString(s string) string {
+	var sb strings.Builder
+	sb.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&sb, "%04X", u)
+	}
+	sb.WriteString(">")
+	return sb.String()
+}
+
+func deflate(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress stream: %w", err)
+	}
+	return buf.Bytes(), nil
+}
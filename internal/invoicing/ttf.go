@@ -0,0 +1,197 @@
+package invoicing
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// trueTypeFont holds the metrics of a TrueType font needed to lay out text
+// and embed the font into a PDF. Only the tables the PDF writer uses are
+// parsed; the font file itself is embedded unchanged.
+type trueTypeFont struct {
+	data       []byte
+	unitsPerEm int
+	bbox       [4]int
+	ascent     int
+	descent    int
+	capHeight  int
+	advances   []int // Indexed by glyph ID
+	glyphs     map[rune]uint16
+}
+
+// parseTrueType reads the head, hhea, maxp, hmtx and cmap tables.
+func parseTrueType(data []byte) (*trueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errors.New("font file too short")
+	}
+	if v := binary.BigEndian.Uint32(data); v != 0x00010000 && v != 0x74727565 {
+		return nil, errors.New("not a TrueType font")
+	}
+
+	tables := make(map[string][]byte)
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil, errors.New("truncated table directory")
+		}
+		tag := string(data[rec : rec+4])
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, fmt.Errorf("table %s out of bounds", tag)
+		}
+		tables[tag] = data[off : off+length]
+	}
+	for _, tag := range []string{"head", "hhea", "maxp", "hmtx", "cmap"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("missing %s table", tag)
+		}
+	}
+
+	f := &trueTypeFont{data: data}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, errors.New("invalid head table")
+	}
+	f.unitsPerEm = int(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		return nil, errors.New("invalid unitsPerEm")
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int(int16(binary.BigEndian.Uint16(head[36+i*2:])))
+	}
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, errors.New("invalid hhea table")
+	}
+	f.ascent = int(int16(binary.BigEndian.Uint16(hhea[4:])))
+	f.descent = int(int16(binary.BigEndian.Uint16(hhea[6:])))
+	numMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	maxp := tables["maxp"]
+	if len(maxp) < 6 {
+		return nil, errors.New("invalid maxp table")
+	}
+	numGlyphs := int(binary.BigEndian.Uint16(maxp[4:]))
+
+	// Glyphs past numberOfHMetrics repeat the last advance
+	hmtx := tables["hmtx"]
+	if numMetrics == 0 || len(hmtx) < numMetrics*4 {
+		return nil, errors.New("invalid hmtx table")
+	}
+	f.advances = make([]int, numGlyphs)
+	for i := range f.advances {
+		m := i
+		if m >= numMetrics {
+			m = numMetrics - 1
+		}
+		f.advances[i] = int(binary.BigEndian.Uint16(hmtx[m*4:]))
+	}
+
+	f.capHeight = f.ascent
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int(int16(binary.BigEndian.Uint16(os2[88:])))
+	}
+
+	glyphs, err := parseCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.glyphs = glyphs
+	return f, nil
+}
+
+// parseCmap reads the Unicode BMP subtable (format 4).
+func parseCmap(cmap []byte) (map[rune]uint16, error) {
+	if len(cmap) < 4 {
+		return nil, errors.New("invalid cmap table")
+	}
+	var sub []byte
+	n := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < n; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(cmap) {
+			break
+		}
+		platform := binary.BigEndian.Uint16(cmap[rec:])
+		encoding := binary.BigEndian.Uint16(cmap[rec+2:])
+		off := int(binary.BigEndian.Uint32(cmap[rec+4:]))
+		if off+4 > len(cmap) || binary.BigEndian.Uint16(cmap[off:]) != 4 {
+			continue
+		}
+		if platform == 0 || (platform == 3 && encoding == 1) {
+			sub = cmap[off:]
+			break
+		}
+	}
+	if sub == nil || len(sub) < 14 {
+		return nil, errors.New("no Unicode cmap subtable")
+	}
+
+	segs := int(binary.BigEndian.Uint16(sub[6:])) / 2
+	endOff := 14
+	startOff := endOff + segs*2 + 2
+	deltaOff := startOff + segs*2
+	rangeOff := deltaOff + segs*2
+	if rangeOff+segs*2 > len(sub) {
+		return nil, errors.New("truncated cmap subtable")
+	}
+
+	glyphs := make(map[rune]uint16)
+	for s := 0; s < segs; s++ {
+		end := int(binary.BigEndian.Uint16(sub[endOff+s*2:]))
+		start := int(binary.BigEndian.Uint16(sub[startOff+s*2:]))
+		delta := int(binary.BigEndian.Uint16(sub[deltaOff+s*2:]))
+		ro := int(binary.BigEndian.Uint16(sub[rangeOff+s*2:]))
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			var g int
+			if ro == 0 {
+				g = (c + delta) & 0xFFFF
+			} else {
+				idx := rangeOff + s*2 + ro + (c-start)*2
+				if idx+2 > len(sub) {
+					continue
+				}
+				g = int(binary.BigEndian.Uint16(sub[idx:]))
+				if g != 0 {
+					g = (g + delta) & 0xFFFF
+				}
+			}
+			if g != 0 {
+				glyphs[rune(c)] = uint16(g)
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// glyph returns the glyph ID of r, or 0 (.notdef) if the font lacks it.
+func (f *trueTypeFont) glyph(r rune) uint16 {
+	return f.glyphs[r]
+}
+
+// advance returns the advance width of glyph g in thousandths of an em.
+func (f *trueTypeFont) advance(g uint16) float64 {
+	if int(g) >= len(f.advances) {
+		return 0
+	}
+	return float64(f.advances[g]) * 1000 / float64(f.unitsPerEm)
+}
+
+// scale converts font units to thousandths of an em.
+func (f *trueTypeFont) scale(v int) int {
+	return v * 1000 / f.unitsPerEm
+}
+
+// textWidth returns the width of s in points at the given size.
+func (f *trueTypeFont) textWidth(s string, size float64) float64 {
+	var w float64
+	for _, r := range s {
+		w += f.advance(f.glyph(r))
+	}
+	return w * size / 1000
+}
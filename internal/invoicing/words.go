@@ -0,0 +1,116 @@
+package invoicing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Amounts in words ("сумма прописью") as printed on счёт and акт.
+
+var (
+	unitsMasc = []string{"", "один", "два", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	unitsFem  = []string{"", "одна", "две", "три", "четыре", "пять", "шесть", "семь", "восемь", "девять"}
+	teens     = []string{"десять", "одиннадцать", "двенадцать", "тринадцать", "четырнадцать", "пятнадцать", "шестнадцать", "семнадцать", "восемнадцать", "девятнадцать"}
+	tens      = []string{"", "", "двадцать", "тридцать", "сорок", "пятьдесят", "шестьдесят", "семьдесят", "восемьдесят", "девяносто"}
+	hundreds  = []string{"", "сто", "двести", "триста", "четыреста", "пятьсот", "шестьсот", "семьсот", "восемьсот", "девятьсот"}
+)
+
+// noun holds the three forms a noun takes after a number and its gender.
+type noun struct {
+	one, few, many string
+	feminine       bool
+}
+
+var scales = []noun{
+	{"тысяча", "тысячи", "тысяч", true},
+	{"миллион", "миллиона", "миллионов", false},
+	{"миллиард", "миллиарда", "миллиардов", false},
+}
+
+// currencyNouns are the major and minor units of supported currencies.
+var currencyNouns = map[string][2]noun{
+	"RUB": {{"рубль", "рубля", "рублей", false}, {"копейка", "копейки", "копеек", true}},
+	"USD": {{"доллар США", "доллара США", "долларов США", false}, {"цент", "цента", "центов", false}},
+	"EUR": {{"евро", "евро", "евро", false}, {"цент", "цента", "центов", false}},
+}
+
+// plural picks the noun form for n.
+func plural(n int64, w noun) string {
+	switch {
+	case n%100 >= 11 && n%100 <= 14:
+		return w.many
+	case n%10 == 1:
+		return w.one
+	case n%10 >= 2 && n%10 <= 4:
+		return w.few
+	default:
+		return w.many
+	}
+}
+
+// triad spells 0..999; it's empty for 0.
+func triad(n int64, feminine bool) []string {
+	words := []string{}
+	if h := n / 100; h > 0 {
+		words = append(words, hundreds[h])
+	}
+	switch rest := n % 100; {
+	case rest >= 10 && rest < 20:
+		words = append(words, teens[rest-10])
+	default:
+		if t := rest / 10; t > 0 {
+			words = append(words, tens[t])
+		}
+		if u := rest % 10; u > 0 {
+			if feminine {
+				words = append(words, unitsFem[u])
+			} else {
+				words = append(words, unitsMasc[u])
+			}
+		}
+	}
+	return words
+}
+
+// numberWords spells a non-negative integer with the given gender of its
+// last triad.
+func numberWords(n int64, feminine bool) string {
+	if n == 0 {
+		return "ноль"
+	}
+	words := triad(n%1000, feminine)
+	n /= 1000
+	for i := 0; n > 0 && i < len(scales); i++ {
+		if t := n % 1000; t > 0 {
+			part := append(triad(t, scales[i].feminine), plural(t, scales[i]))
+			words = append(part, words...)
+		}
+		n /= 1000
+	}
+	return strings.Join(words, " ")
+}
+
+// amountWords spells amount, e.g. "Одна тысяча двести рублей 50 копеек".
+// Kopecks are written in digits, as is customary.
+func amountWords(amount float64, code string) string {
+	minorTotal := int64(math.Round(math.Abs(amount) * 100))
+	major, minor := minorTotal/100, minorTotal%100
+
+	var s string
+	if nouns, ok := currencyNouns[code]; ok {
+		s = fmt.Sprintf("%s %s %02d %s",
+			numberWords(major, nouns[0].feminine), plural(major, nouns[0]),
+			minor, plural(minor, nouns[1]))
+	} else {
+		s = fmt.Sprintf("%s %s %02d/100", numberWords(major, false), code, minor)
+	}
+	if amount < 0 {
+		s = "минус " + s
+	}
+
+	r, size := utf8.DecodeRuneInString(s)
+	return string(unicode.ToUpper(r)) + s[size:]
+}
@@ -0,0 +1,232 @@
+package invoicing
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Cell styles, indexes into cellXfs of xlsxStyles
+const (
+	xlsxStyleDefault = iota
+	xlsxStyleHeading
+	xlsxStyleCell
+	xlsxStyleNumber
+	xlsxStyleHeader
+	xlsxStyleMoney
+)
+
+const xlsxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="3"><font><sz val="10"/><name val="Arial"/></font><font><b/><sz val="13"/><name val="Arial"/></font><font><b/><sz val="10"/><name val="Arial"/></font></fonts>
+<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill><fill><patternFill patternType="solid"><fgColor rgb="FFE6E6E6"/></patternFill></fill></fills>
+<borders count="2"><border><left/><right/><top/><bottom/><diagonal/></border><border><left style="thin"/><right style="thin"/><top style="thin"/><bottom style="thin"/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="6">
+<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>
+<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>
+<xf numFmtId="0" fontId="0" fillId="0" borderId="1" xfId="0" applyBorder="1" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>
+<xf numFmtId="0" fontId="0" fillId="0" borderId="1" xfId="0" applyBorder="1" applyAlignment="1"><alignment horizontal="right" vertical="top"/></xf>
+<xf numFmtId="0" fontId="2" fillId="2" borderId="1" xfId="0" applyFont="1" applyFill="1" applyBorder="1" applyAlignment="1"><alignment horizontal="center" vertical="center" wrapText="1"/></xf>
+<xf numFmtId="4" fontId="0" fillId="0" borderId="1" xfId="0" applyNumberFormat="1" applyBorder="1" applyAlignment="1"><alignment horizontal="right" vertical="top"/></xf>
+</cellXfs>
+</styleSheet>`
+
+// xlsxSheetWidth is the total width of the table columns in characters.
+const xlsxSheetWidth = 110.0
+
+// renderXLSX renders a layout to a single-sheet workbook. The columns are
+// the union of the column boundaries of all tables, so tables with
+// different widths line up and cells span the columns they cover.
+func renderXLSX(l *layout, sheetName string) ([]byte, error) {
+	bounds := columnBounds(l.rows)
+
+	var sheet bytes.Buffer
+	var merges []string
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	sheet.WriteString(`<cols>`)
+	for i := 1; i < len(bounds); i++ {
+		fmt.Fprintf(&sheet, `<col min="%d" max="%d" width="%.2f" customWidth="1"/>`, i, i, (bounds[i]-bounds[i-1])*xlsxSheetWidth)
+	}
+	sheet.WriteString(`</cols><sheetData>`)
+
+	r := 0
+	for _, row := range l.rows {
+		r++
+		switch row.kind {
+		case rowSpacer:
+			continue
+		case rowHeading:
+			fmt.Fprintf(&sheet, `<row r="%d">`, r)
+			writeXLSXCell(&sheet, cellRef(0, r), row.text, xlsxStyleHeading, false)
+		case rowText:
+			fmt.Fprintf(&sheet, `<row r="%d">`, r)
+			writeXLSXCell(&sheet, cellRef(0, r), row.text, xlsxStyleDefault, false)
+		case rowHeader, rowTable:
+			fmt.Fprintf(&sheet, `<row r="%d">`, r)
+			var total, pos float64
+			for _, w := range row.widths {
+				total += w
+			}
+			for i, cell := range row.cells {
+				first := boundIndex(bounds, pos/total)
+				pos += row.widths[i]
+				last := boundIndex(bounds, pos/total) - 1
+				if last < first {
+					last = first
+				}
+
+				style := xlsxStyleCell
+				numeric := false
+				switch {
+				case row.kind == rowHeader:
+					style = xlsxStyleHeader
+				case cell.right:
+					style = xlsxStyleNumber
+					if _, ok := parseNumber(cell.text); ok {
+						numeric = true
+						if strings.Contains(cell.text, ",") {
+							style = xlsxStyleMoney
+						}
+					}
+				}
+				writeXLSXCell(&sheet, cellRef(first, r), cell.text, style, numeric)
+				// Spanned cells carry the style so the borders are drawn
+				for c := first + 1; c <= last; c++ {
+					fmt.Fprintf(&sheet, `<c r="%s" s="%d"/>`, cellRef(c, r), style)
+				}
+				if last > first {
+					merges = append(merges, cellRef(first, r)+":"+cellRef(last, r))
+				}
+			}
+		}
+		sheet.WriteString(`</row>`)
+	}
+	sheet.WriteString(`</sheetData>`)
+	if len(merges) > 0 {
+		fmt.Fprintf(&sheet, `<mergeCells count="%d">`, len(merges))
+		for _, m := range merges {
+			fmt.Fprintf(&sheet, `<mergeCell ref="%s"/>`, m)
+		}
+		sheet.WriteString(`</mergeCells>`)
+	}
+	pageSetup := `<pageSetup paperSize="9" orientation="portrait" fitToHeight="0"/>`
+	if l.landscape {
+		pageSetup = `<pageSetup paperSize="9" orientation="landscape" fitToHeight="0"/>`
+	}
+	sheet.WriteString(pageSetup + `</worksheet>`)
+
+	files := []struct {
+		name string
+		body string
+	}{
+		{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/><Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/></Types>`},
+		{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`},
+		{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="` + xmlEscape(sheetName) + `" sheetId="1" r:id="rId1"/></sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/></Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", sheet.String()},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+		if _, err := w.Write([]byte(f.body)); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write workbook: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// columnBounds returns the sorted column boundaries of all table rows as
+// fractions of the table width, from 0 to 1.
+func columnBounds(rows []layoutRow) []float64 {
+	seen := map[float64]bool{0: true, 1: true}
+	for _, row := range rows {
+		if row.kind != rowHeader && row.kind != rowTable {
+			continue
+		}
+		var total, pos float64
+		for _, w := range row.widths {
+			total += w
+		}
+		for _, w := range row.widths {
+			pos += w
+			seen[math.Round(pos/total*1000)/1000] = true
+		}
+	}
+	bounds := make([]float64, 0, len(seen))
+	for b := range seen {
+		bounds = append(bounds, b)
+	}
+	sort.Float64s(bounds)
+	return bounds
+}
+
+// boundIndex returns the index of the boundary at fraction f.
+func boundIndex(bounds []float64, f float64) int {
+	f = math.Round(f*1000) / 1000
+	for i, b := range bounds {
+		if b >= f {
+			return i
+		}
+	}
+	return len(bounds) - 1
+}
+
+func writeXLSXCell(buf *bytes.Buffer, ref, text string, style int, numeric bool) {
+	if numeric {
+		v, _ := parseNumber(text)
+		fmt.Fprintf(buf, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(v, 'f', -1, 64))
+		return
+	}
+	if text == "" {
+		fmt.Fprintf(buf, `<c r="%s" s="%d"/>`, ref, style)
+		return
+	}
+	fmt.Fprintf(buf, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, xmlEscape(text))
+}
+
+// parseNumber parses numbers formatted by the money and qty template
+// functions, such as "1 234,50".
+func parseNumber(s string) (float64, bool) {
+	s = strings.NewReplacer(" ", "", " ", "", ",", ".").Replace(s)
+	if s == "" || strings.Trim(s, "-.0123456789") != "" {
+		return 0, false
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	return v, err == nil
+}
+
+// cellRef returns the A1 reference of the zero-based column and row r.
+func cellRef(col, r int) string {
+	name := ""
+	for col >= 0 {
+		name = string(rune('A'+col%26)) + name
+		col = col/26 - 1
+	}
+	return name + strconv.Itoa(r)
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
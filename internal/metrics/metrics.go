@@ -34,6 +34,32 @@ type Metrics struct {
 	Conversions      *prometheus.CounterVec
 	Revenue          *prometheus.CounterVec
 
+	// Fraud metrics
+	FraudFlags       *prometheus.CounterVec
+
+	// Event storage metrics
+	EventWrites      *prometheus.CounterVec
+	EventBufferDepth prometheus.Gauge
+
+	// Scheduled report metrics
+	ReportRuns       *prometheus.CounterVec
+
+	// Stats rollup metrics
+	StatsRollups     *prometheus.CounterVec
+	StatsWatermark   prometheus.Gauge
+
+	// Live feed metrics
+	LiveSubscribers   prometheus.Gauge
+	LiveFramesDropped prometheus.Counter
+
+	// Billing metrics
+	BillingTransactions  *prometheus.CounterVec
+	BillingFlushFailures prometheus.Counter
+	ExhaustedAdvertisers prometheus.Gauge
+
+	// Invoicing metrics
+	DocumentsGenerated *prometheus.CounterVec
+
 	// System metrics
 	ActiveCampaigns  prometheus.Gauge
 	ActiveLineItems  prometheus.Gauge
@@ -43,6 +69,27 @@ type Metrics struct {
 	// Rate limiting metrics
 	RateLimitHits    *prometheus.CounterVec
 
+	// Auth metrics
+	APIKeyAuthentications *prometheus.CounterVec
+	UserLogins            *prometheus.CounterVec
+
+	// Audit metrics
+	AuditEntries  *prometheus.CounterVec
+	AuditFailures prometheus.Counter
+
+	// Lifecycle metrics
+	CampaignTransitions *prometheus.CounterVec
+	LifecycleWebhooks   *prometheus.CounterVec
+
+	// Automation metrics
+	RuleEvaluations *prometheus.CounterVec
+	RuleFirings     *prometheus.CounterVec
+
+	// Alerting metrics
+	Postbacks          *prometheus.CounterVec
+	AlertEvents        *prometheus.CounterVec
+	AlertNotifications *prometheus.CounterVec
+
 	// Pacing metrics
 	PacingRejections *prometheus.CounterVec
 	FreqCapRejections *prometheus.CounterVec
@@ -183,6 +230,110 @@ func NewMetrics(namespace string) *Metrics {
 			[]string{"campaign_id"},
 		),
 
+		// Fraud metrics
+		FraudFlags: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "fraud_flags_total",
+				Help:      "Fraud rule hits by event type and reason",
+			},
+			[]string{"event_type", "reason"},
+		),
+
+		// Event storage metrics
+		EventWrites: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "event_writes_total",
+				Help:      "Event store rows by table and status",
+			},
+			[]string{"table", "status"}, // written, failed, rejected
+		),
+		EventBufferDepth: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "event_buffer_depth",
+				Help:      "Events waiting in the event store buffer",
+			},
+		),
+
+		// Scheduled report metrics
+		ReportRuns: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "report_runs_total",
+				Help:      "Scheduled report run attempts by format and status",
+			},
+			[]string{"format", "status"}, // succeeded, retrying, failed
+		),
+
+		// Stats rollup metrics
+		StatsRollups: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "stats_rollup_days_total",
+				Help:      "Days rolled into daily stats by status",
+			},
+			[]string{"status"}, // ok, error
+		),
+		StatsWatermark: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "stats_rollup_watermark_seconds",
+				Help:      "Unix time up to which events are rolled into daily stats",
+			},
+		),
+
+		// Live feed metrics
+		LiveSubscribers: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "live_subscribers",
+				Help:      "Connected live stats stream clients",
+			},
+		),
+		LiveFramesDropped: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "live_frames_dropped_total",
+				Help:      "Live stats frames dropped for slow clients",
+			},
+		),
+
+		// Billing metrics
+		BillingTransactions: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "billing_transactions_total",
+				Help:      "Billing ledger transactions by type",
+			},
+			[]string{"type"},
+		),
+		BillingFlushFailures: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "billing_flush_failures_total",
+				Help:      "Accrued charges that failed to be written to the ledger",
+			},
+		),
+		ExhaustedAdvertisers: promauto.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "billing_exhausted_advertisers",
+				Help:      "Advertisers not bidding for lack of available balance",
+			},
+		),
+
+		// Invoicing metrics
+		DocumentsGenerated: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "invoicing_documents_generated_total",
+				Help:      "Closing documents generated or regenerated by type",
+			},
+			[]string{"type"},
+		),
+
 		// System metrics
 		ActiveCampaigns: promauto.NewGauge(
 			prometheus.GaugeOpts{
@@ -226,6 +377,103 @@ func NewMetrics(namespace string) *Metrics {
 			[]string{"endpoint", "ip"},
 		),
 
+		// Auth metrics
+		APIKeyAuthentications: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "api_key_authentications_total",
+				Help:      "API key authentications by result (master, ok, unknown, inactive, forbidden, rate_limited, error)",
+			},
+			[]string{"result"},
+		),
+
+		UserLogins: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "user_logins_total",
+				Help:      "User logins and token refreshes by result (ok, invalid, totp_required, refreshed, refresh_reused)",
+			},
+			[]string{"result"},
+		),
+
+		// Audit metrics
+		AuditEntries: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "audit_entries_total",
+				Help:      "Audit log entries by entity type and action",
+			},
+			[]string{"entity_type", "action"},
+		),
+		AuditFailures: promauto.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "audit_failures_total",
+				Help:      "Management changes that were saved but failed to be written to the audit log",
+			},
+		),
+
+		// Lifecycle metrics
+		CampaignTransitions: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "campaign_transitions_total",
+				Help:      "Campaign status changes by new status and reason",
+			},
+			[]string{"status", "reason"},
+		),
+		LifecycleWebhooks: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "lifecycle_webhooks_total",
+				Help:      "Campaign status webhook deliveries by result (ok, error)",
+			},
+			[]string{"result"},
+		),
+
+		// Automation metrics
+		RuleEvaluations: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "rule_evaluations_total",
+				Help:      "Automation rule evaluations by result (ok, error)",
+			},
+			[]string{"result"},
+		),
+		RuleFirings: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "rule_firings_total",
+				Help:      "Automation rule actions taken by action and result (ok, error)",
+			},
+			[]string{"action", "result"},
+		),
+
+		Postbacks: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "postbacks_total",
+				Help:      "MMP postbacks received by MMP and result (ok, error)",
+			},
+			[]string{"mmp", "result"},
+		),
+		AlertEvents: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "alert_events_total",
+				Help:      "Alerts triggered and resolved by type and event (triggered, resolved)",
+			},
+			[]string{"type", "event"},
+		),
+		AlertNotifications: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "alert_notifications_total",
+				Help:      "Alert notifications sent by channel type and result (ok, error)",
+			},
+			[]string{"channel", "result"},
+		),
+
 		// Pacing metrics
 		PacingRejections: promauto.NewCounterVec(
 			prometheus.CounterOpts{
@@ -327,6 +575,66 @@ func (m *Metrics) RecordConversion(campaignID, eventName string, revenue float64
 	}
 }
 
+// RecordFraudFlag records a fraud rule hit.
+func (m *Metrics) RecordFraudFlag(eventType, reason string) {
+	m.FraudFlags.WithLabelValues(eventType, reason).Inc()
+}
+
+// RecordEventWrite records event store rows written, failed or rejected.
+func (m *Metrics) RecordEventWrite(table, status string, rows int) {
+	m.EventWrites.WithLabelValues(table, status).Add(float64(rows))
+}
+
+// SetEventBufferDepth updates the number of buffered events.
+func (m *Metrics) SetEventBufferDepth(n int) {
+	m.EventBufferDepth.Set(float64(n))
+}
+
+// RecordReportRun records a scheduled report run attempt.
+func (m *Metrics) RecordReportRun(format, status string) {
+	m.ReportRuns.WithLabelValues(format, status).Inc()
+}
+
+// RecordStatsRollup records a day rolled into daily stats.
+func (m *Metrics) RecordStatsRollup(status string) {
+	m.StatsRollups.WithLabelValues(status).Inc()
+}
+
+// SetStatsWatermark updates the daily stats rollup watermark.
+func (m *Metrics) SetStatsWatermark(t time.Time) {
+	m.StatsWatermark.Set(float64(t.Unix()))
+}
+
+// SetLiveSubscribers updates the number of live stream clients.
+func (m *Metrics) SetLiveSubscribers(n int) {
+	m.LiveSubscribers.Set(float64(n))
+}
+
+// RecordLiveFrameDropped records a frame dropped for a slow client.
+func (m *Metrics) RecordLiveFrameDropped() {
+	m.LiveFramesDropped.Inc()
+}
+
+// RecordBillingTransaction records a ledger transaction.
+func (m *Metrics) RecordBillingTransaction(txType string) {
+	m.BillingTransactions.WithLabelValues(txType).Inc()
+}
+
+// RecordBillingFlushFailure records a charge that failed to be written.
+func (m *Metrics) RecordBillingFlushFailure() {
+	m.BillingFlushFailures.Inc()
+}
+
+// SetExhaustedAdvertisers updates the number of advertisers out of balance.
+func (m *Metrics) SetExhaustedAdvertisers(n int) {
+	m.ExhaustedAdvertisers.Set(float64(n))
+}
+
+// RecordDocumentGenerated records a generated closing document.
+func (m *Metrics) RecordDocumentGenerated(docType string) {
+	m.DocumentsGenerated.WithLabelValues(docType).Inc()
+}
+
 // RecordPacingRejection records a pacing rejection.
 func (m *Metrics) RecordPacingRejection(lineItemID, reason string) {
 	m.PacingRejections.WithLabelValues(lineItemID, reason).Inc()
@@ -373,3 +681,58 @@ func (m *Metrics) UpdateActiveCounts(campaigns, lineItems int) {
 func (m *Metrics) RecordRateLimitHit(endpoint, ip string) {
 	m.RateLimitHits.WithLabelValues(endpoint, ip).Inc()
 }
+
+// RecordAPIKeyAuthentication records the result of an API key check.
+func (m *Metrics) RecordAPIKeyAuthentication(result string) {
+	m.APIKeyAuthentications.WithLabelValues(result).Inc()
+}
+
+// RecordUserLogin records the result of a login or token refresh.
+func (m *Metrics) RecordUserLogin(result string) {
+	m.UserLogins.WithLabelValues(result).Inc()
+}
+
+// RecordAuditEntry records a written audit log entry.
+func (m *Metrics) RecordAuditEntry(entityType, action string) {
+	m.AuditEntries.WithLabelValues(entityType, action).Inc()
+}
+
+// RecordAuditFailure records a change that failed to be audited.
+func (m *Metrics) RecordAuditFailure() {
+	m.AuditFailures.Inc()
+}
+
+// RecordCampaignTransition records a campaign status change.
+func (m *Metrics) RecordCampaignTransition(status, reason string) {
+	m.CampaignTransitions.WithLabelValues(status, reason).Inc()
+}
+
+// RecordLifecycleWebhook records a status webhook delivery.
+func (m *Metrics) RecordLifecycleWebhook(result string) {
+	m.LifecycleWebhooks.WithLabelValues(result).Inc()
+}
+
+// RecordRuleEvaluation records an automation rule evaluation.
+func (m *Metrics) RecordRuleEvaluation(result string) {
+	m.RuleEvaluations.WithLabelValues(result).Inc()
+}
+
+// RecordRuleFiring records an action taken by an automation rule.
+func (m *Metrics) RecordRuleFiring(action, result string) {
+	m.RuleFirings.WithLabelValues(action, result).Inc()
+}
+
+// RecordPostback records an MMP postback.
+func (m *Metrics) RecordPostback(mmp, result string) {
+	m.Postbacks.WithLabelValues(mmp, result).Inc()
+}
+
+// RecordAlertEvent records an alert being triggered or resolved.
+func (m *Metrics) RecordAlertEvent(alertType, event string) {
+	m.AlertEvents.WithLabelValues(alertType, event).Inc()
+}
+
+// RecordAlertNotification records an alert notification delivery.
+func (m *Metrics) RecordAlertNotification(channel, result string) {
+	m.AlertNotifications.WithLabelValues(channel, result).Inc()
+}
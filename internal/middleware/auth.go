@@ -5,9 +5,14 @@ import (
 	"crypto/subtle"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 )
 
 // contextKey is a custom type for context keys to avoid collisions.
@@ -22,22 +27,90 @@ const (
 	
 	// AuthQueryParam is the query parameter name for the API key (fallback).
 	AuthQueryParam = "api_key"
+
+	// AdvertiserScopeContextKey is the context key for the advertiser an API
+	// key is restricted to.
+	AdvertiserScopeContextKey contextKey = "advertiser_scope"
+
+	// APIKeyInfoContextKey is the context key for the stored API key that
+	// authenticated the request; absent for the master key.
+	APIKeyInfoContextKey contextKey = "api_key_info"
+
+	// PrincipalContextKey is the context key for the *models.Principal of
+	// the request: the master key, an API key or a user.
+	PrincipalContextKey contextKey = "principal"
 )
 
-// AuthMiddleware validates API key authentication.
+// KeyStore looks up per-advertiser API keys, returning nil for unknown
+// keys. See dsp.APIKeyService.
+type KeyStore interface {
+	Authenticate(ctx context.Context, key string) (*models.APIKey, error)
+}
+
+// TokenVerifier checks user access tokens, returning nil for invalid and
+// expired tokens. See dsp.UserService.
+type TokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (*models.Principal, error)
+}
+
+// publicPaths start or continue a user session and need no credentials.
+var publicPaths = map[string]bool{
+	"/api/auth/login":   true,
+	"/api/auth/refresh": true,
+	"/api/auth/logout":  true,
+}
+
+// selfServicePrefix covers the endpoints users call on their own account;
+// they are open to every role.
+const selfServicePrefix = "/api/auth/"
+
+// AuthMiddleware validates API key and user token authentication. The
+// master key has full access; keys from the KeyStore are checked for
+// expiry, scope and their own rate limit, and advertiser keys are
+// restricted to their advertiser. Users send "Authorization: Bearer" access
+// tokens and are limited by their role.
 type AuthMiddleware struct {
-	cfg    config.AuthConfig
-	logger *zap.Logger
+	cfg     config.AuthConfig
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+	keys    KeyStore
+	tokens  TokenVerifier
+
+	// Per-key rate limiters
+	mu       sync.Mutex
+	limiters map[string]*keyLimiter
+}
+
+// keyLimiter is the rate limiter of one key at the key's current limit.
+type keyLimiter struct {
+	limiter *rate.Limiter
+	rps     int
 }
 
 // NewAuthMiddleware creates a new authentication middleware.
 func NewAuthMiddleware(cfg config.AuthConfig, logger *zap.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
-		cfg:    cfg,
-		logger: logger,
+		cfg:      cfg,
+		logger:   logger,
+		limiters: make(map[string]*keyLimiter),
 	}
 }
 
+// SetMetrics sets the metrics collector.
+func (a *AuthMiddleware) SetMetrics(m *metrics.Metrics) {
+	a.metrics = m
+}
+
+// SetKeyStore enables per-advertiser API keys in addition to the master key.
+func (a *AuthMiddleware) SetKeyStore(keys KeyStore) {
+	a.keys = keys
+}
+
+// SetTokenVerifier enables user access tokens.
+func (a *AuthMiddleware) SetTokenVerifier(tokens TokenVerifier) {
+	a.tokens = tokens
+}
+
 // Handler wraps an http.Handler with authentication.
 func (a *AuthMiddleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -48,11 +121,17 @@ func (a *AuthMiddleware) Handler(next http.Handler) http.Handler {
 		}
 
 		// Skip auth for whitelisted paths
-		if a.shouldSkip(r.URL.Path) {
+		if a.shouldSkip(r.URL.Path) || publicPaths[r.URL.Path] {
 			next.ServeHTTP(w, r)
 			return
 		}
 
+		// User access tokens take precedence over API keys
+		if token, ok := bearerToken(r); ok {
+			a.serveUser(w, r, next, token)
+			return
+		}
+
 		// Extract API key from header or query param
 		apiKey := r.Header.Get(AuthHeaderName)
 		if apiKey == "" {
@@ -65,21 +144,176 @@ func (a *AuthMiddleware) Handler(next http.Handler) http.Handler {
 			return
 		}
 
-		if !a.validateKey(apiKey) {
+		// Store API key in context for downstream handlers
+		ctx := context.WithValue(r.Context(), APIKeyContextKey, apiKey)
+
+		if a.validateKey(apiKey) {
+			a.record("master")
+			ctx = withPrincipal(ctx, &models.Principal{
+				Kind:        models.PrincipalMaster,
+				Permissions: []string{models.ScopeRead, models.ScopeWrite, models.ScopeAdmin},
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		key, err := a.lookupKey(r.Context(), apiKey)
+		if err != nil {
+			a.logger.Error("failed to look up API key", zap.Error(err))
+			a.record("error")
+			a.respond(w, http.StatusServiceUnavailable, "authentication unavailable")
+			return
+		}
+		if key == nil {
 			a.logger.Warn("invalid API key attempt",
 				zap.String("path", r.URL.Path),
 				zap.String("remote_addr", r.RemoteAddr),
 			)
+			a.record("unknown")
 			a.unauthorized(w, "invalid API key")
 			return
 		}
+		if !key.Active(time.Now()) {
+			a.record("inactive")
+			a.unauthorized(w, "API key expired or revoked")
+			return
+		}
 
-		// Store API key in context for downstream handlers
-		ctx := context.WithValue(r.Context(), APIKeyContextKey, apiKey)
+		scope := requiredScope(r)
+		if !key.Allows(scope) {
+			a.record("forbidden")
+			a.respond(w, http.StatusForbidden, "API key lacks "+scope+" permission")
+			return
+		}
+
+		if !a.allow(key) {
+			a.logger.Warn("API key rate limit exceeded",
+				zap.String("api_key_id", key.ID),
+				zap.String("path", r.URL.Path),
+			)
+			a.record("rate_limited")
+			if a.metrics != nil {
+				a.metrics.RecordRateLimitHit("api_key", key.ID)
+			}
+			w.Header().Set("Retry-After", "1")
+			a.respond(w, http.StatusTooManyRequests, "rate limit exceeded")
+			return
+		}
+
+		a.record("ok")
+		ctx = context.WithValue(ctx, APIKeyInfoContextKey, key)
+		ctx = withPrincipal(ctx, models.NewAPIKeyPrincipal(key))
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// serveUser authenticates a user access token and applies the user's role.
+func (a *AuthMiddleware) serveUser(w http.ResponseWriter, r *http.Request, next http.Handler, token string) {
+	var p *models.Principal
+	if a.tokens != nil {
+		var err error
+		p, err = a.tokens.VerifyAccessToken(r.Context(), token)
+		if err != nil {
+			a.logger.Error("failed to verify access token", zap.Error(err))
+			a.respond(w, http.StatusServiceUnavailable, "authentication unavailable")
+			return
+		}
+	}
+	if p == nil {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		a.respond(w, http.StatusUnauthorized, "invalid or expired token")
+		return
+	}
+
+	if !strings.HasPrefix(r.URL.Path, selfServicePrefix) {
+		scope := requiredScope(r)
+		if !p.Allows(scope) {
+			a.respond(w, http.StatusForbidden, "role "+p.Role+" lacks "+scope+" permission")
+			return
+		}
+		if scope == models.ScopeWrite && !p.CanWrite(r.URL.Path) {
+			a.respond(w, http.StatusForbidden, "role "+p.Role+" can't change this resource")
+			return
+		}
+	}
+
+	next.ServeHTTP(w, r.WithContext(withPrincipal(r.Context(), p)))
+}
+
+// withPrincipal stores p and the advertiser it is restricted to in ctx.
+func withPrincipal(ctx context.Context, p *models.Principal) context.Context {
+	ctx = context.WithValue(ctx, PrincipalContextKey, p)
+	if p.AdvertiserID != "" {
+		ctx = context.WithValue(ctx, AdvertiserScopeContextKey, p.AdvertiserID)
+	}
+	return ctx
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header.
+func bearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, token != ""
+}
+
+// lookupKey returns the stored key matching key, or nil without a KeyStore.
+func (a *AuthMiddleware) lookupKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if a.keys == nil {
+		return nil, nil
+	}
+	return a.keys.Authenticate(ctx, key)
+}
+
+// queryPaths accept a POST body that only describes a query, so keys with
+// the read scope may use them.
+var queryPaths = map[string]bool{
+	"/api/reports/query":        true,
+	"/api/reports/cohorts":      true,
+	"/api/reports/campaigns":    true,
+	"/api/payout-rules/preview": true,
+	"/api/diagnostics/bid":      true,
+}
+
+// requiredScope returns the scope a request needs: read for GET and
+// queries, write for everything else.
+func requiredScope(r *http.Request) string {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return models.ScopeRead
+	}
+	if queryPaths[r.URL.Path] {
+		return models.ScopeRead
+	}
+	return models.ScopeWrite
+}
+
+// allow applies the key's own rate limit; keys without one are only
+// subject to the global limits.
+func (a *AuthMiddleware) allow(key *models.APIKey) bool {
+	if key.RateLimit <= 0 {
+		return true
+	}
+
+	a.mu.Lock()
+	l, ok := a.limiters[key.ID]
+	if !ok || l.rps != key.RateLimit {
+		l = &keyLimiter{limiter: rate.NewLimiter(rate.Limit(key.RateLimit), key.RateLimit), rps: key.RateLimit}
+		a.limiters[key.ID] = l
+	}
+	a.mu.Unlock()
+
+	return l.limiter.Allow()
+}
+
+func (a *AuthMiddleware) record(result string) {
+	if a.metrics != nil {
+		a.metrics.RecordAPIKeyAuthentication(result)
+	}
+}
+
 // shouldSkip checks if the path should bypass authentication.
 func (a *AuthMiddleware) shouldSkip(path string) bool {
 	for _, skip := range a.cfg.SkipPaths {
@@ -90,25 +324,25 @@ func (a *AuthMiddleware) shouldSkip(path string) bool {
 	return false
 }
 
-// validateKey checks if the provided key is valid.
+// validateKey checks if the provided key is the master key.
 // Uses constant-time comparison to prevent timing attacks.
 func (a *AuthMiddleware) validateKey(key string) bool {
-	// Check against master key
-	if subtle.ConstantTimeCompare([]byte(key), []byte(a.cfg.MasterKey)) == 1 {
-		return true
+	if a.cfg.MasterKey == "" {
+		return false
 	}
-	
-	// TODO: Add support for per-advertiser API keys from database
-	// This would involve a database lookup here
-	
-	return false
+	return subtle.ConstantTimeCompare([]byte(key), []byte(a.cfg.MasterKey)) == 1
 }
 
 // unauthorized sends a 401 response.
 func (a *AuthMiddleware) unauthorized(w http.ResponseWriter, message string) {
-	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("WWW-Authenticate", "ApiKey")
-	w.WriteHeader(http.StatusUnauthorized)
+	a.respond(w, http.StatusUnauthorized, message)
+}
+
+// respond sends a JSON error response.
+func (a *AuthMiddleware) respond(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
 	w.Write([]byte(`{"error":"` + message + `"}`))
 }
 
@@ -119,3 +353,30 @@ func GetAPIKey(ctx context.Context) string {
 	}
 	return ""
 }
+
+// GetAdvertiserScope returns the advertiser the request's API key or user
+// is restricted to, or "" for unrestricted callers such as the master key.
+func GetAdvertiserScope(ctx context.Context) string {
+	if id, ok := ctx.Value(AdvertiserScopeContextKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// GetAPIKeyInfo returns the stored API key that authenticated the request,
+// or nil for the master key and unauthenticated requests.
+func GetAPIKeyInfo(ctx context.Context) *models.APIKey {
+	if key, ok := ctx.Value(APIKeyInfoContextKey).(*models.APIKey); ok {
+		return key
+	}
+	return nil
+}
+
+// GetPrincipal returns the authenticated caller of the request, or nil when
+// auth is disabled or the path skips it.
+func GetPrincipal(ctx context.Context) *models.Principal {
+	if p, ok := ctx.Value(PrincipalContextKey).(*models.Principal); ok {
+		return p
+	}
+	return nil
+}
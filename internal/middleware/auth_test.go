@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"go.uber.org/zap"
+)
+
+// keyMap is a KeyStore over a map of keys by their plain value.
+type keyMap map[string]*models.APIKey
+
+func (m keyMap) Authenticate(ctx context.Context, key string) (*models.APIKey, error) {
+	if key == "broken" {
+		return nil, errors.New("key store unavailable")
+	}
+	return m[key], nil
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/api/campaigns", models.ScopeRead},
+		{http.MethodHead, "/api/campaigns", models.ScopeRead},
+		{http.MethodOptions, "/api/campaigns", models.ScopeRead},
+		{http.MethodPost, "/api/campaigns", models.ScopeWrite},
+		{http.MethodPut, "/api/campaigns/cmp-1", models.ScopeWrite},
+		{http.MethodPatch, "/api/v1/campaigns/cmp-1", models.ScopeWrite},
+		{http.MethodDelete, "/api/campaigns/cmp-1", models.ScopeWrite},
+		{http.MethodPost, "/api/reports/query", models.ScopeRead},
+		{http.MethodPost, "/api/reports/cohorts", models.ScopeRead},
+		{http.MethodPost, "/api/payout-rules/preview", models.ScopeRead},
+		{http.MethodPost, "/api/diagnostics/bid", models.ScopeRead},
+		// Query paths match exactly
+		{http.MethodPost, "/api/reports/query/", models.ScopeWrite},
+		{http.MethodPost, "/api/scheduled-reports", models.ScopeWrite},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			if got := requiredScope(r); got != tt.want {
+				t.Errorf("requiredScope() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	keys := keyMap{
+		"read-key":  {ID: "k-read", Permissions: []string{models.ScopeRead}, Status: models.APIKeyActive},
+		"write-key": {ID: "k-write", Permissions: []string{models.ScopeWrite}, Status: models.APIKeyActive},
+		"admin-key": {ID: "k-admin", Permissions: []string{models.ScopeAdmin}, Status: models.APIKeyActive},
+		"adv-key": {
+			ID: "k-adv", AdvertiserID: "adv-1", Permissions: []string{models.ScopeWrite}, Status: models.APIKeyActive,
+		},
+		"revoked-key": {ID: "k-revoked", Permissions: []string{models.ScopeWrite}, Status: models.APIKeyRevoked},
+		"expired-key": {ID: "k-expired", Permissions: []string{models.ScopeWrite}, Status: models.APIKeyActive, ExpiresAt: &expired},
+	}
+
+	tests := []struct {
+		name      string
+		disabled  bool
+		method    string
+		target    string
+		key       string
+		wantCode  int
+		wantKind  string
+		wantScope string
+	}{
+		{name: "auth disabled", disabled: true, method: http.MethodPost, target: "/api/campaigns", wantCode: http.StatusOK},
+		{name: "skipped path", method: http.MethodPost, target: "/bid", wantCode: http.StatusOK},
+		{name: "public path", method: http.MethodPost, target: "/api/auth/login", wantCode: http.StatusOK},
+		{name: "missing key", method: http.MethodGet, target: "/api/campaigns", wantCode: http.StatusUnauthorized},
+		{name: "unknown key", method: http.MethodGet, target: "/api/campaigns", key: "nope", wantCode: http.StatusUnauthorized},
+		{name: "key store error", method: http.MethodGet, target: "/api/campaigns", key: "broken", wantCode: http.StatusServiceUnavailable},
+
+		{name: "master key", method: http.MethodDelete, target: "/api/campaigns/cmp-1", key: "master", wantCode: http.StatusOK, wantKind: models.PrincipalMaster},
+		{name: "master key in query", method: http.MethodPost, target: "/api/campaigns?api_key=master", wantCode: http.StatusOK, wantKind: models.PrincipalMaster},
+
+		{name: "read key reads", method: http.MethodGet, target: "/api/campaigns", key: "read-key", wantCode: http.StatusOK, wantKind: models.PrincipalAPIKey},
+		{name: "read key queries", method: http.MethodPost, target: "/api/reports/query", key: "read-key", wantCode: http.StatusOK, wantKind: models.PrincipalAPIKey},
+		{name: "read key writes", method: http.MethodPost, target: "/api/campaigns", key: "read-key", wantCode: http.StatusForbidden},
+		{name: "write key writes", method: http.MethodPut, target: "/api/campaigns/cmp-1", key: "write-key", wantCode: http.StatusOK, wantKind: models.PrincipalAPIKey},
+		{name: "admin key writes", method: http.MethodDelete, target: "/api/campaigns/cmp-1", key: "admin-key", wantCode: http.StatusOK, wantKind: models.PrincipalAPIKey},
+		{name: "advertiser key", method: http.MethodGet, target: "/api/campaigns", key: "adv-key", wantCode: http.StatusOK, wantKind: models.PrincipalAPIKey, wantScope: "adv-1"},
+		{name: "revoked key", method: http.MethodGet, target: "/api/campaigns", key: "revoked-key", wantCode: http.StatusUnauthorized},
+		{name: "expired key", method: http.MethodGet, target: "/api/campaigns", key: "expired-key", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := NewAuthMiddleware(config.AuthConfig{
+				Enabled:   !tt.disabled,
+				MasterKey: "master",
+				SkipPaths: []string{"/bid", "/health"},
+			}, zap.NewNop())
+			auth.SetKeyStore(keys)
+
+			var principal *models.Principal
+			var scope string
+			h := auth.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal = GetPrincipal(r.Context())
+				scope = GetAdvertiserScope(r.Context())
+			}))
+
+			r := httptest.NewRequest(tt.method, tt.target, nil)
+			if tt.key != "" {
+				r.Header.Set(AuthHeaderName, tt.key)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("status = %d, want %d (%s)", w.Code, tt.wantCode, w.Body.String())
+			}
+			if tt.wantKind != "" && (principal == nil || principal.Kind != tt.wantKind) {
+				t.Errorf("principal = %+v, want kind %q", principal, tt.wantKind)
+			}
+			if scope != tt.wantScope {
+				t.Errorf("advertiser scope = %q, want %q", scope, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareKeyRateLimit(t *testing.T) {
+	limited := &models.APIKey{ID: "k-limited", Permissions: []string{models.ScopeRead}, Status: models.APIKeyActive, RateLimit: 2}
+	other := &models.APIKey{ID: "k-other", Permissions: []string{models.ScopeRead}, Status: models.APIKeyActive, RateLimit: 2}
+	unlimited := &models.APIKey{ID: "k-unlimited", Permissions: []string{models.ScopeRead}, Status: models.APIKeyActive}
+
+	auth := NewAuthMiddleware(config.AuthConfig{Enabled: true, MasterKey: "master"}, zap.NewNop())
+	auth.SetKeyStore(keyMap{"limited": limited, "other": other, "unlimited": unlimited})
+	h := auth.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	get := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/api/campaigns", nil)
+		r.Header.Set(AuthHeaderName, key)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// The burst is one second's worth of requests
+	for i := 0; i < 2; i++ {
+		if w := get("limited"); w.Code != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i+1, w.Code)
+		}
+	}
+	w := get("limited")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Errorf("request over the limit = %d, Retry-After %q; want 429, 1", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// Limits are per key; the master key and keys without a limit have none
+	if w := get("other"); w.Code != http.StatusOK {
+		t.Errorf("other key = %d, want 200", w.Code)
+	}
+	for i := 0; i < 20; i++ {
+		if w := get("unlimited"); w.Code != http.StatusOK {
+			t.Fatalf("key without a limit, request %d = %d, want 200", i+1, w.Code)
+		}
+		if w := get("master"); w.Code != http.StatusOK {
+			t.Fatalf("master key, request %d = %d, want 200", i+1, w.Code)
+		}
+	}
+
+	// A changed limit takes effect with a fresh bucket
+	limited.RateLimit = 5
+	for i := 0; i < 5; i++ {
+		if w := get("limited"); w.Code != http.StatusOK {
+			t.Fatalf("request %d after raising the limit = %d, want 200", i+1, w.Code)
+		}
+	}
+	if w := get("limited"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("request over the raised limit = %d, want 429", w.Code)
+	}
+}
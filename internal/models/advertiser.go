@@ -21,6 +21,7 @@ type Advertiser struct {
 	Balance      float64 `json:"balance"`
 	CreditLimit  float64 `json:"credit_limit,omitempty"`
 	Currency     string  `json:"currency,omitempty"` // USD, RUB, EUR
+	Timezone     string  `json:"timezone,omitempty"` // IANA, e.g. Europe/Moscow; for reports
 	
 	// Bank details
 	BIK           string `json:"bik,omitempty"`
@@ -31,6 +32,9 @@ type Advertiser struct {
 	ContractNumber string     `json:"contract_number,omitempty"`
 	ContractDate   *time.Time `json:"contract_date,omitempty"`
 	
+	// Account management
+	AccountManager string `json:"account_manager,omitempty"` // Email; included in campaign status webhooks
+
 	Status    string    `json:"status,omitempty"` // active, paused, suspended
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
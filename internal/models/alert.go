@@ -0,0 +1,212 @@
+package models
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// ALERTS
+// ===========================================
+
+// AlertType is the anomaly an alert definition looks for.
+type AlertType string
+
+const (
+	// Active line items spending below Threshold% of their pacing target
+	// for the time of day (UTC)
+	AlertUnderdelivery AlertType = "underdelivery"
+	// Active campaigns with at least MinVolume clicks and no conversions
+	// over the last WindowHours
+	AlertZeroConversions AlertType = "zero_conversions"
+	// MMPs with at least MinVolume postbacks over the last WindowHours of
+	// which more than Threshold% failed
+	AlertPostbackErrors AlertType = "postback_errors"
+	// Sources with at least MinVolume clicks over the days of the last
+	// WindowHours of which more than Threshold% of clicks or conversions
+	// were flagged as fraud
+	AlertFraudSpike AlertType = "fraud_spike"
+	// Campaigns that spent at least Threshold% of their total budget
+	AlertBudget AlertType = "budget"
+)
+
+// AlertTypes lists the supported alert types.
+var AlertTypes = []AlertType{
+	AlertUnderdelivery, AlertZeroConversions, AlertPostbackErrors, AlertFraudSpike, AlertBudget,
+}
+
+// PlatformAlert reports whether alerts of type t are about the platform
+// (MMPs, sources) rather than an advertiser's campaigns.
+func PlatformAlert(t AlertType) bool {
+	return t == AlertPostbackErrors || t == AlertFraudSpike
+}
+
+// AlertChannelType is where alert notifications are sent.
+type AlertChannelType string
+
+const (
+	AlertChannelWebhook AlertChannelType = "webhook" // JSON POST, signed like the other webhooks
+	AlertChannelSlack   AlertChannelType = "slack"   // Slack-compatible incoming webhook ({"text": ...})
+	AlertChannelEmail   AlertChannelType = "email"   // Plain text email
+)
+
+// AlertChannel is one notification target of an alert definition.
+type AlertChannel struct {
+	Type       AlertChannelType `json:"type"`
+	URL        string           `json:"url,omitempty"`        // webhook, slack
+	Recipients []string         `json:"recipients,omitempty"` // email
+}
+
+// AlertDefinition checks for one kind of anomaly every alert interval.
+// While an anomaly lasts it is one open alert, notified once (and again
+// every RenotifyHours, if set); it is resolved, with a notification, once
+// the check no longer finds it.
+type AlertDefinition struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Type    AlertType `json:"type"`
+	Enabled bool      `json:"enabled"`
+	BuiltIn bool      `json:"built_in"` // Created by the service; can be changed or disabled, not deleted
+
+	// Campaign alerts only: empty selects every campaign
+	AdvertiserID string   `json:"advertiser_id,omitempty"`
+	CampaignIDs  []string `json:"campaign_ids,omitempty"`
+
+	Threshold   float64 `json:"threshold,omitempty"`    // Percentage; see AlertType
+	WindowHours int     `json:"window_hours,omitempty"` // zero_conversions, postback_errors, fraud_spike
+	MinVolume   int64   `json:"min_volume,omitempty"`   // Clicks or postbacks below which nothing is checked
+
+	// Channels notified; empty uses the configured default channels
+	Channels      []AlertChannel `json:"channels,omitempty"`
+	RenotifyHours int            `json:"renotify_hours,omitempty"` // 0: once per alert
+	SnoozedUntil  *time.Time     `json:"snoozed_until,omitempty"`  // No notifications for any of its alerts until then
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the definition and returns ValidationErrors listing
+// every invalid field.
+func (d *AlertDefinition) Validate() error {
+	var errs ValidationErrors
+	if d.ID == "" {
+		errs.add("id", "is required")
+	}
+	if d.Name == "" {
+		errs.add("name", "is required")
+	}
+	known := false
+	for _, t := range AlertTypes {
+		known = known || d.Type == t
+	}
+	if !known {
+		errs.add("type", "must be one of underdelivery, zero_conversions, postback_errors, fraud_spike, budget")
+	}
+	if PlatformAlert(d.Type) && (d.AdvertiserID != "" || len(d.CampaignIDs) > 0) {
+		errs.add("campaign_ids", "must be empty for "+string(d.Type)+" alerts")
+	}
+
+	switch d.Type {
+	case AlertZeroConversions:
+		if d.Threshold != 0 {
+			errs.add("threshold", "must be empty for zero_conversions alerts")
+		}
+	case AlertBudget:
+		if d.Threshold <= 0 {
+			errs.add("threshold", "must be a percentage above 0")
+		}
+	default:
+		if d.Threshold <= 0 || d.Threshold > 100 {
+			errs.add("threshold", "must be a percentage between 0 and 100")
+		}
+	}
+	switch d.Type {
+	case AlertZeroConversions, AlertPostbackErrors, AlertFraudSpike:
+		if d.WindowHours < 1 || d.WindowHours > 24*7 {
+			errs.add("window_hours", "must be between 1 and 168")
+		}
+	default:
+		if d.WindowHours != 0 {
+			errs.add("window_hours", "must be empty for "+string(d.Type)+" alerts")
+		}
+	}
+	if d.MinVolume < 0 {
+		errs.add("min_volume", "must not be negative")
+	}
+	if d.RenotifyHours < 0 {
+		errs.add("renotify_hours", "must not be negative")
+	}
+
+	for i, c := range d.Channels {
+		field := fmt.Sprintf("channels[%d]", i)
+		switch c.Type {
+		case AlertChannelWebhook, AlertChannelSlack:
+			if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+				errs.add(field+".url", "must be an http(s) url")
+			}
+		case AlertChannelEmail:
+			if len(c.Recipients) == 0 {
+				errs.add(field+".recipients", "at least one recipient required")
+			}
+			for _, r := range c.Recipients {
+				if _, err := mail.ParseAddress(r); err != nil {
+					errs.add(field+".recipients", "invalid address "+r)
+				}
+			}
+		default:
+			errs.add(field+".type", "must be one of webhook, slack, email")
+		}
+	}
+	return errs.err()
+}
+
+// AlertStatus is the state of an alert.
+type AlertStatus string
+
+const (
+	AlertOpen     AlertStatus = "open"
+	AlertResolved AlertStatus = "resolved"
+)
+
+// Alert is one anomaly found by a definition, from the first check that
+// found it until the first that didn't. Key identifies what it is about
+// (e.g. "line_item:li_1"), so each definition has at most one open alert
+// per key.
+type Alert struct {
+	ID             string      `json:"id"`
+	DefinitionID   string      `json:"definition_id"`
+	DefinitionName string      `json:"definition_name"`
+	Type           AlertType   `json:"type"`
+	Key            string      `json:"key"`
+	Status         AlertStatus `json:"status"`
+
+	AdvertiserID string `json:"advertiser_id,omitempty"`
+	CampaignID   string `json:"campaign_id,omitempty"`
+	LineItemID   string `json:"line_item_id,omitempty"`
+	SourceID     string `json:"source_id,omitempty"`
+	MMP          string `json:"mmp,omitempty"`
+
+	Message   string  `json:"message"`
+	Value     float64 `json:"value"` // Latest measured value
+	Threshold float64 `json:"threshold"`
+	Checks    int     `json:"checks"` // Checks that found it
+
+	FirstSeenAt  time.Time  `json:"first_seen_at"`
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+	ResolvedAt   *time.Time `json:"resolved_at,omitempty"`
+	NotifiedAt   *time.Time `json:"notified_at,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+}
+
+// Snoozed reports whether the alert's notifications are snoozed at now.
+func (a *Alert) Snoozed(now time.Time) bool {
+	return a.SnoozedUntil != nil && now.Before(*a.SnoozedUntil)
+}
+
+// AlertNotification is the body of webhook channel notifications.
+type AlertNotification struct {
+	Event string `json:"event"` // alert.triggered, alert.resolved or alert.test
+	Alert Alert  `json:"alert"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ===========================================
+// API KEYS
+// ===========================================
+
+// API key scopes. Read allows GET requests, write allows changes and admin
+// allows managing API keys. Write and admin imply read.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+	ScopeAdmin = "admin"
+)
+
+// API key statuses.
+const (
+	APIKeyActive  = "active"
+	APIKeyRevoked = "revoked"
+)
+
+// APIKey is a key for the management API. Only the SHA-256 hash of the key
+// is stored; the key itself is returned once, when it is created. A key
+// with an AdvertiserID sees and changes only that advertiser's objects.
+type APIKey struct {
+	ID           string     `json:"id"`
+	Name         string     `json:"name"`
+	KeyHash      string     `json:"-"`
+	AdvertiserID string     `json:"advertiser_id,omitempty"`
+	Permissions  []string   `json:"permissions"`
+	RateLimit    int        `json:"rate_limit"` // Requests per second; 0 uses the global limit
+	Status       string     `json:"status"`
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Validate checks that required fields are present and scopes are known.
+func (k *APIKey) Validate() error {
+	if k.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(k.Permissions) == 0 {
+		return errors.New("at least one permission is required")
+	}
+	for _, p := range k.Permissions {
+		if p != ScopeRead && p != ScopeWrite && p != ScopeAdmin {
+			return errors.New("unknown permission: " + p)
+		}
+	}
+	if k.RateLimit < 0 {
+		return errors.New("rate_limit must not be negative")
+	}
+	return nil
+}
+
+// Allows reports whether the key grants scope.
+func (k *APIKey) Allows(scope string) bool {
+	for _, p := range k.Permissions {
+		if p == scope || p == ScopeAdmin || (p == ScopeWrite && scope == ScopeRead) {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can be used at t.
+func (k *APIKey) Active(t time.Time) bool {
+	return k.Status == APIKeyActive && (k.ExpiresAt == nil || t.Before(*k.ExpiresAt))
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ===========================================
+// AUDIT LOG
+// ===========================================
+
+// Audited entity types.
+const (
+	AuditCampaign   = "campaign"
+	AuditAdGroup    = "ad_group"
+	AuditCreative   = "creative"
+	AuditS2SSource  = "s2s_source"
+	AuditRTBSource  = "rtb_source"
+	AuditAdvertiser = "advertiser"
+	AuditPayoutRule = "payout_rule"
+	AuditRule       = "automation_rule"
+	AuditAlert      = "alert_definition"
+	AuditTemplate   = "campaign_template"
+)
+
+// Audit actions.
+const (
+	AuditCreate   = "create"
+	AuditUpdate   = "update"
+	AuditDelete   = "delete"
+	AuditRollback = "rollback"
+)
+
+// AuditAnonymous is the actor kind of changes made with auth disabled.
+const AuditAnonymous = "anonymous"
+
+// AuditEntry records one change of a managed entity. Before and After are
+// full JSON snapshots; Before is null for creations and After for
+// deletions.
+type AuditEntry struct {
+	ID         string          `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Action     string          `json:"action"`
+	ActorKind  string          `json:"actor_kind"` // Principal kind or AuditAnonymous
+	ActorID    string          `json:"actor_id,omitempty"`
+	ActorName  string          `json:"actor_name,omitempty"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Diff       []AuditChange   `json:"diff"`
+	RollbackOf string          `json:"rollback_of,omitempty"` // Entry restored by a rollback
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+// AuditChange is one changed field. Nested fields are joined with dots,
+// e.g. "targeting.countries"; elements of arrays of objects with IDs are
+// keyed by ID, e.g. "line_items[li-1].bid_price", and other arrays are
+// compared whole.
+type AuditChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before"` // null if the field was added
+	After  json.RawMessage `json:"after"`  // null if the field was removed
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ===========================================
+// AUTOMATION RULES
+// ===========================================
+
+// RuleScope is the reporting dimension a rule's conditions are checked
+// per. Every scope is checked per line item, so "line_item" rules look at
+// a line item's totals and "country" rules at each country of each line
+// item.
+type RuleScope string
+
+const (
+	RuleScopeLineItem  RuleScope = "line_item"
+	RuleScopeSource    RuleScope = "source"
+	RuleScopeCountry   RuleScope = "country"
+	RuleScopeAppBundle RuleScope = "app_bundle"
+)
+
+// Rule condition metrics. cr, ctr and fraud_rate are percentages.
+const (
+	RuleMetricSpend       = "spend"
+	RuleMetricImpressions = "impressions"
+	RuleMetricClicks      = "clicks"
+	RuleMetricInstalls    = "installs"
+	RuleMetricCPI         = "cpi"        // spend / installs; spend when there are no installs
+	RuleMetricCPA         = "cpa"        // spend / conversions; spend when there are none
+	RuleMetricCR          = "cr"         // conversions / clicks
+	RuleMetricCTR         = "ctr"        // clicks / impressions
+	RuleMetricROAS        = "roas"       // revenue / spend
+	RuleMetricFraudRate   = "fraud_rate" // Flagged clicks of the source; source scope only
+)
+
+// RuleMetrics lists the supported condition metrics.
+var RuleMetrics = []string{
+	RuleMetricSpend, RuleMetricImpressions, RuleMetricClicks, RuleMetricInstalls,
+	RuleMetricCPI, RuleMetricCPA, RuleMetricCR, RuleMetricCTR, RuleMetricROAS, RuleMetricFraudRate,
+}
+
+// Rule condition operators.
+const (
+	RuleOpGreater      = "gt"
+	RuleOpGreaterEqual = "gte"
+	RuleOpLess         = "lt"
+	RuleOpLessEqual    = "lte"
+)
+
+// RuleCondition compares one metric over the rule's lookback window with
+// a value.
+type RuleCondition struct {
+	Metric   string  `json:"metric"`
+	Operator string  `json:"operator"` // gt, gte, lt, lte
+	Value    float64 `json:"value"`
+}
+
+// Holds reports whether v satisfies the condition.
+func (c *RuleCondition) Holds(v float64) bool {
+	switch c.Operator {
+	case RuleOpGreater:
+		return v > c.Value
+	case RuleOpGreaterEqual:
+		return v >= c.Value
+	case RuleOpLess:
+		return v < c.Value
+	case RuleOpLessEqual:
+		return v <= c.Value
+	}
+	return false
+}
+
+// RuleActionType is what a rule does to a matching line item.
+type RuleActionType string
+
+const (
+	RuleActionPause     RuleActionType = "pause"      // Deactivates the line item
+	RuleActionAdjustBid RuleActionType = "adjust_bid" // Scales fixed, min and max CPM by BidAdjustPct
+	RuleActionBlacklist RuleActionType = "blacklist"  // Adds the app bundle to List; app_bundle scope only
+	RuleActionNotify    RuleActionType = "notify"     // Posts to the automation webhook
+)
+
+// RuleAction is one action of a rule.
+type RuleAction struct {
+	Type         RuleActionType `json:"type"`
+	BidAdjustPct float64        `json:"bid_adjust_pct,omitempty"` // adjust_bid: -20 lowers bids by 20%
+	List         string         `json:"list,omitempty"`           // blacklist: bundle_blacklist (default) or domain_blacklist
+}
+
+// AutomationRule pauses, rebids, blacklists or notifies when reporting
+// metrics of a line item (or of a source, country or app bundle within it)
+// over the lookback window meet every condition, e.g. "CPI > 2 in country
+// BR over 6h: pause".
+type AutomationRule struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	AdvertiserID string `json:"advertiser_id,omitempty"` // Only this advertiser's campaigns
+	Enabled      bool   `json:"enabled"`
+
+	// Line items checked; empty lists select every active line item
+	CampaignIDs []string `json:"campaign_ids,omitempty"`
+	LineItemIDs []string `json:"line_item_ids,omitempty"`
+
+	Scope       RuleScope `json:"scope"`
+	ScopeValues []string  `json:"scope_values,omitempty"` // Only these sources, countries or bundles
+
+	Conditions    []RuleCondition `json:"conditions"`               // All must hold
+	LookbackHours int             `json:"lookback_hours"`           // Window the metrics are summed over
+	CooldownHours int             `json:"cooldown_hours,omitempty"` // Before firing again for the same target; defaults to the lookback
+	Actions       []RuleAction    `json:"actions"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Cooldown returns how long the rule waits before firing again for the
+// same line item and scope value.
+func (r *AutomationRule) Cooldown() time.Duration {
+	if r.CooldownHours > 0 {
+		return time.Duration(r.CooldownHours) * time.Hour
+	}
+	return time.Duration(r.LookbackHours) * time.Hour
+}
+
+// Validate checks the rule and returns ValidationErrors listing every
+// invalid field.
+func (r *AutomationRule) Validate() error {
+	var errs ValidationErrors
+	if r.ID == "" {
+		errs.add("id", "is required")
+	}
+	if r.Name == "" {
+		errs.add("name", "is required")
+	}
+	switch r.Scope {
+	case RuleScopeLineItem:
+		if len(r.ScopeValues) > 0 {
+			errs.add("scope_values", "must be empty for line_item scope; use line_item_ids")
+		}
+	case RuleScopeSource, RuleScopeCountry, RuleScopeAppBundle:
+	default:
+		errs.add("scope", "must be one of line_item, source, country, app_bundle")
+	}
+
+	if len(r.Conditions) == 0 {
+		errs.add("conditions", "at least one condition required")
+	}
+	for i, c := range r.Conditions {
+		field := fmt.Sprintf("conditions[%d]", i)
+		if !containsRuleMetric(c.Metric) {
+			errs.add(field+".metric", "unknown metric")
+		}
+		if c.Metric == RuleMetricFraudRate && r.Scope != RuleScopeSource {
+			errs.add(field+".metric", "fraud_rate requires source scope")
+		}
+		switch c.Operator {
+		case RuleOpGreater, RuleOpGreaterEqual, RuleOpLess, RuleOpLessEqual:
+		default:
+			errs.add(field+".operator", "must be one of gt, gte, lt, lte")
+		}
+	}
+	if r.LookbackHours < 1 || r.LookbackHours > 24*30 {
+		errs.add("lookback_hours", "must be between 1 and 720")
+	}
+	if r.CooldownHours < 0 {
+		errs.add("cooldown_hours", "must not be negative")
+	}
+
+	if len(r.Actions) == 0 {
+		errs.add("actions", "at least one action required")
+	}
+	for i, a := range r.Actions {
+		field := fmt.Sprintf("actions[%d]", i)
+		switch a.Type {
+		case RuleActionPause, RuleActionNotify:
+		case RuleActionAdjustBid:
+			if a.BidAdjustPct == 0 || a.BidAdjustPct <= -100 || a.BidAdjustPct > 500 {
+				errs.add(field+".bid_adjust_pct", "must be non-zero, above -100 and at most 500")
+			}
+		case RuleActionBlacklist:
+			if r.Scope != RuleScopeAppBundle {
+				errs.add(field+".type", "blacklist requires app_bundle scope")
+			}
+			if a.List != "" && a.List != "bundle_blacklist" && a.List != "domain_blacklist" {
+				errs.add(field+".list", "must be bundle_blacklist or domain_blacklist")
+			}
+		default:
+			errs.add(field+".type", "must be one of pause, adjust_bid, blacklist, notify")
+		}
+	}
+	return errs.err()
+}
+
+func containsRuleMetric(m string) bool {
+	for _, known := range RuleMetrics {
+		if m == known {
+			return true
+		}
+	}
+	return false
+}
+
+// RuleFiring records one action a rule took (or, in a dry run, would
+// take) on a line item.
+type RuleFiring struct {
+	ID           string             `json:"id"`
+	RuleID       string             `json:"rule_id"`
+	RuleName     string             `json:"rule_name"`
+	AdvertiserID string             `json:"advertiser_id"`
+	CampaignID   string             `json:"campaign_id"`
+	LineItemID   string             `json:"line_item_id"`
+	Scope        RuleScope          `json:"scope"`
+	ScopeValue   string             `json:"scope_value,omitempty"` // Source, country or bundle; empty for line_item scope
+	Action       RuleActionType     `json:"action"`
+	Detail       string             `json:"detail,omitempty"` // e.g. "fixed_cpm 2.00 -> 1.60"
+	Metrics      map[string]float64 `json:"metrics"`          // Condition metrics over the window
+	DryRun       bool               `json:"dry_run"`
+	Error        string             `json:"error,omitempty"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// RuleEvaluation is the result of evaluating a rule once.
+type RuleEvaluation struct {
+	RuleID   string       `json:"rule_id"`
+	DryRun   bool         `json:"dry_run"`
+	Start    time.Time    `json:"start"` // Lookback window
+	End      time.Time    `json:"end"`
+	Checked  int          `json:"checked"`  // Line item and scope value pairs with data
+	Matched  int          `json:"matched"`  // Pairs meeting every condition
+	Cooldown int          `json:"cooldown"` // Matched pairs skipped as the rule fired for them recently
+	Firings  []RuleFiring `json:"firings"`
+}
+
+// RuleWebhook is the body of the webhook posted for a rule's notify
+// actions, once per evaluation.
+type RuleWebhook struct {
+	Event    string       `json:"event"` // automation.rule_fired
+	RuleID   string       `json:"rule_id"`
+	RuleName string       `json:"rule_name"`
+	Firings  []RuleFiring `json:"firings"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// ===========================================
+// BID SAMPLE (request/response debugging log)
+// ===========================================
+
+// Bid decision stages, in evaluation order.
+const (
+	BidStageBalance   = "balance"   // Advertiser out of balance; Reason is the advertiser ID
+	BidStageTargeting = "targeting" // Targeting.Match failed; Reason is the failed criteria
+	BidStagePrice     = "price"     // Bid strategy produced no price
+	BidStageFloor     = "floor"     // Price below the impression floor
+	BidStagePacing    = "pacing"    // Pacing or frequency cap rejected the bid
+	BidStageCreative  = "creative"  // No creative fits the impression
+	BidStageOutbid    = "outbid"    // Eligible, lost to a higher priority or price
+	BidStageBid       = "bid"       // Won the internal auction and was bid
+)
+
+// LineItemDecision records how far a line item got for one impression.
+type LineItemDecision struct {
+	ImpID      string  `json:"imp_id"`
+	CampaignID string  `json:"campaign_id"`
+	LineItemID string  `json:"line_item_id"`
+	Stage      string  `json:"stage"`
+	Reason     string  `json:"reason,omitempty"`
+	Price      float64 `json:"price,omitempty"`
+}
+
+// BidSample is a sampled bid request with our response and the decision
+// trail of every active line item.
+type BidSample struct {
+	ID          string             `json:"id"`
+	Timestamp   time.Time          `json:"timestamp"`
+	SourceID    string             `json:"source_id,omitempty"`
+	Request     *BidRequest        `json:"request"`
+	Response    *BidResponse       `json:"response,omitempty"`
+	NoBidReason string             `json:"no_bid_reason,omitempty"`
+	Trail       []LineItemDecision `json:"trail"`
+}
+
+// CampaignIDs returns the campaigns evaluated for the sample.
+func (s *BidSample) CampaignIDs() []string {
+	return s.distinct(func(d LineItemDecision) string { return d.CampaignID })
+}
+
+// LineItemIDs returns the line items evaluated for the sample.
+func (s *BidSample) LineItemIDs() []string {
+	return s.distinct(func(d LineItemDecision) string { return d.LineItemID })
+}
+
+func (s *BidSample) distinct(field func(LineItemDecision) string) []string {
+	seen := make(map[string]bool)
+	ids := make([]string, 0)
+	for _, d := range s.Trail {
+		id := field(d)
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// ===========================================
+// BILLING LEDGER
+// ===========================================
+
+// BillingTransactionType is the kind of a ledger entry.
+type BillingTransactionType string
+
+const (
+	BillingTxSpend      BillingTransactionType = "spend"      // RTB media cost of wins
+	BillingTxCPA        BillingTransactionType = "cpa"        // Payouts of S2S conversions
+	BillingTxTopUp      BillingTransactionType = "topup"      // Payment received
+	BillingTxAdjustment BillingTransactionType = "adjustment" // Manual credit or debit
+)
+
+// BillingTransaction is one entry of an advertiser's ledger. Every change to
+// Advertiser.Balance is a transaction; entries are never updated or deleted.
+// Spend and CPA entries aggregate the charges of one campaign over a short
+// accrual period.
+type BillingTransaction struct {
+	ID           string                 `json:"id"`
+	AdvertiserID string                 `json:"advertiser_id"`
+	Type         BillingTransactionType `json:"type"`
+	Amount       float64                `json:"amount"` // Signed, in Currency; debits are negative
+	Currency     string                 `json:"currency"`
+	BalanceAfter float64                `json:"balance_after"`
+
+	// Charges
+	CampaignID  string     `json:"campaign_id,omitempty"`
+	Quantity    int64      `json:"quantity,omitempty"` // Wins or conversions charged
+	PeriodStart *time.Time `json:"period_start,omitempty"`
+	PeriodEnd   *time.Time `json:"period_end,omitempty"`
+
+	// Top-ups and adjustments
+	Reference   string `json:"reference,omitempty"` // External payment ID; unique per advertiser
+	Description string `json:"description,omitempty"`
+	CreatedBy   string `json:"created_by,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// IsCharge reports whether the transaction is a spend or CPA charge.
+func (t *BillingTransaction) IsCharge() bool {
+	return t.Type == BillingTxSpend || t.Type == BillingTxCPA
+}
+
+// BillingBalance is an advertiser's balance including charges that have
+// accrued but not yet been written to the ledger.
+type BillingBalance struct {
+	AdvertiserID string  `json:"advertiser_id"`
+	Currency     string  `json:"currency"`
+	Balance      float64 `json:"balance"`
+	CreditLimit  float64 `json:"credit_limit"`
+	Pending      float64 `json:"pending"`   // Accrued charges not yet debited
+	Available    float64 `json:"available"` // Balance + CreditLimit - Pending
+	Exhausted    bool    `json:"exhausted"` // Bidding is stopped
+}
+
+// BillingStatement summarizes an advertiser's ledger over a period.
+type BillingStatement struct {
+	AdvertiserID   string                `json:"advertiser_id"`
+	Currency       string                `json:"currency"`
+	From           time.Time             `json:"from"`
+	To             time.Time             `json:"to"`
+	OpeningBalance float64               `json:"opening_balance"`
+	ClosingBalance float64               `json:"closing_balance"`
+	Spend          float64               `json:"spend"` // Positive totals of charges
+	CPA            float64               `json:"cpa"`
+	TopUps         float64               `json:"topups"`
+	Adjustments    float64               `json:"adjustments"` // Net, signed
+	Transactions   []*BillingTransaction `json:"transactions"`
+}
@@ -0,0 +1,122 @@
+package models
+
+import "time"
+
+// ===========================================
+// BULK OPERATIONS
+// ===========================================
+
+// Bulk row statuses.
+const (
+	BulkCreated   = "created"
+	BulkChanged   = "changed"
+	BulkUnchanged = "unchanged"
+	BulkFailed    = "failed"
+)
+
+// LineItemSelector selects line items for a bulk change. Empty fields
+// match everything; list fields match any of their values.
+type LineItemSelector struct {
+	CampaignIDs      []string         `json:"campaign_ids,omitempty"`
+	AdvertiserID     string           `json:"advertiser_id,omitempty"`
+	CampaignStatuses []CampaignStatus `json:"campaign_statuses,omitempty"` // Default: all but archived
+	LineItemIDs      []string         `json:"line_item_ids,omitempty"`
+	NameContains     string           `json:"name_contains,omitempty"` // Case-insensitive
+	IsActive         *bool            `json:"is_active,omitempty"`
+	Countries        []string         `json:"countries,omitempty"` // Line items targeting any of them
+}
+
+// LineItemChanges are the changes a bulk operation applies to each
+// selected line item. Nil fields are left alone. TargetingAdd and
+// TargetingRemove map targeting list fields ("countries", "os", ...) to
+// values to add or remove.
+type LineItemChanges struct {
+	FixedCPM        *float64            `json:"fixed_cpm,omitempty"`
+	BidMultiplier   *float64            `json:"bid_multiplier,omitempty"` // Scales fixed, min and max CPM
+	DailyBudget     *float64            `json:"daily_budget,omitempty"`
+	TotalBudget     *float64            `json:"total_budget,omitempty"`
+	IsActive        *bool               `json:"is_active,omitempty"`
+	Priority        *int32              `json:"priority,omitempty"`
+	TargetingAdd    map[string][]string `json:"targeting_add,omitempty"`
+	TargetingRemove map[string][]string `json:"targeting_remove,omitempty"`
+}
+
+// BulkLineItemRequest changes the selected line items.
+type BulkLineItemRequest struct {
+	Filter  LineItemSelector `json:"filter"`
+	Changes LineItemChanges  `json:"changes"`
+	DryRun  bool             `json:"dry_run"`
+}
+
+// CampaignSelector selects campaigns for a bulk change.
+type CampaignSelector struct {
+	CampaignIDs  []string         `json:"campaign_ids,omitempty"`
+	AdvertiserID string           `json:"advertiser_id,omitempty"`
+	Statuses     []CampaignStatus `json:"statuses,omitempty"` // Default: all but archived
+	NameContains string           `json:"name_contains,omitempty"`
+}
+
+// CampaignChanges are the changes applied to each selected campaign.
+type CampaignChanges struct {
+	Status      *CampaignStatus `json:"status,omitempty"`
+	DailyBudget *float64        `json:"daily_budget,omitempty"`
+	TotalBudget *float64        `json:"total_budget,omitempty"`
+	EndDate     *time.Time      `json:"end_date,omitempty"`
+}
+
+// BulkCampaignRequest changes the selected campaigns.
+type BulkCampaignRequest struct {
+	Filter  CampaignSelector `json:"filter"`
+	Changes CampaignChanges  `json:"changes"`
+	DryRun  bool             `json:"dry_run"`
+}
+
+// CreativeSelector selects creatives for a bulk change.
+type CreativeSelector struct {
+	CreativeIDs  []string `json:"creative_ids,omitempty"`
+	AdvertiserID string   `json:"advertiser_id,omitempty"`
+	Format       string   `json:"format,omitempty"`
+	NameContains string   `json:"name_contains,omitempty"`
+}
+
+// CreativeChanges are the changes applied to each selected creative.
+type CreativeChanges struct {
+	ClickURL                 *string  `json:"click_url,omitempty"`
+	AuditStatus              *string  `json:"audit_status,omitempty"`
+	ADomain                  []string `json:"adomain,omitempty"` // Replaces the list
+	ImpressionTrackersAdd    []string `json:"impression_trackers_add,omitempty"`
+	ImpressionTrackersRemove []string `json:"impression_trackers_remove,omitempty"`
+	ClickTrackersAdd         []string `json:"click_trackers_add,omitempty"`
+	ClickTrackersRemove      []string `json:"click_trackers_remove,omitempty"`
+}
+
+// BulkCreativeRequest changes the selected creatives.
+type BulkCreativeRequest struct {
+	Filter  CreativeSelector `json:"filter"`
+	Changes CreativeChanges  `json:"changes"`
+	DryRun  bool             `json:"dry_run"`
+}
+
+// BulkResult reports a bulk operation row by row. Operations are all or
+// nothing: Applied is false for dry runs and when any row failed, and then
+// nothing was saved.
+type BulkResult struct {
+	DryRun  bool            `json:"dry_run"`
+	Applied bool            `json:"applied"`
+	Matched int             `json:"matched"`
+	Changed int             `json:"changed"` // Including created
+	Failed  int             `json:"failed"`
+	Rows    []BulkRowResult `json:"rows"`
+}
+
+// BulkRowResult is the outcome of one selected object or import row.
+type BulkRowResult struct {
+	Row        int           `json:"row,omitempty"` // Import file line, header being 1
+	CampaignID string        `json:"campaign_id,omitempty"`
+	LineItemID string        `json:"line_item_id,omitempty"`
+	CreativeID string        `json:"creative_id,omitempty"`
+	Status     string        `json:"status"`
+	Diff       []AuditChange `json:"diff,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Fields     []FieldError  `json:"fields,omitempty"`
+}
@@ -1,7 +1,7 @@
 package models
 
 import (
-	"errors"
+	"fmt"
 	"time"
 )
 
@@ -243,23 +243,26 @@ type LineItem struct {
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
 }
 
+// Validate checks the line item and returns ValidationErrors listing
+// every invalid field.
 func (li *LineItem) Validate() error {
+	var errs ValidationErrors
 	if li.ID == "" {
-		return errors.New("line_item id is required")
+		errs.add("id", "is required")
 	}
 	if li.CampaignID == "" {
-		return errors.New("line_item campaign_id is required")
+		errs.add("campaign_id", "is required")
 	}
 	if li.BidStrategy.Type == BidStrategyFixedCPM && li.BidStrategy.FixedCPM <= 0 {
-		return errors.New("fixed_cpm must be > 0")
+		errs.add("bid_strategy.fixed_cpm", "must be > 0")
 	}
 	if li.Pacing.DailyBudget <= 0 {
-		return errors.New("daily_budget must be > 0")
+		errs.add("pacing.daily_budget", "must be > 0")
 	}
 	if len(li.Creatives) == 0 {
-		return errors.New("at least one creative required")
+		errs.add("creatives", "at least one creative required")
 	}
-	return nil
+	return errs.err()
 }
 
 // ===========================================
@@ -269,11 +272,12 @@ func (li *LineItem) Validate() error {
 type CampaignStatus string
 
 const (
-	CampaignStatusDraft    CampaignStatus = "draft"
-	CampaignStatusActive   CampaignStatus = "active"
-	CampaignStatusPaused   CampaignStatus = "paused"
-	CampaignStatusEnded    CampaignStatus = "ended"
-	CampaignStatusArchived CampaignStatus = "archived"
+	CampaignStatusDraft     CampaignStatus = "draft"
+	CampaignStatusScheduled CampaignStatus = "scheduled" // Ready; the lifecycle scheduler activates it at start_date
+	CampaignStatusActive    CampaignStatus = "active"
+	CampaignStatusPaused    CampaignStatus = "paused"
+	CampaignStatusEnded     CampaignStatus = "ended"
+	CampaignStatusArchived  CampaignStatus = "archived"
 )
 
 type CampaignObjective string
@@ -323,22 +327,33 @@ type Campaign struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// Validate checks the campaign and its line items and returns
+// ValidationErrors listing every invalid field.
 func (c *Campaign) Validate() error {
+	var errs ValidationErrors
 	if c.ID == "" {
-		return errors.New("id is required")
+		errs.add("id", "is required")
 	}
 	if c.Name == "" {
-		return errors.New("name is required")
+		errs.add("name", "is required")
 	}
 	if c.AdvertiserID == "" {
-		return errors.New("advertiser_id is required")
+		errs.add("advertiser_id", "is required")
+	}
+	switch c.Status {
+	case "", CampaignStatusDraft, CampaignStatusScheduled, CampaignStatusActive, CampaignStatusPaused, CampaignStatusEnded, CampaignStatusArchived:
+	default:
+		errs.add("status", "must be one of draft, scheduled, active, paused, ended, archived")
+	}
+	if !c.StartDate.IsZero() && !c.EndDate.IsZero() && !c.EndDate.After(c.StartDate) {
+		errs.add("end_date", "must be after start_date")
 	}
 	for i := range c.LineItems {
 		if err := c.LineItems[i].Validate(); err != nil {
-			return err
+			errs.nest(fmt.Sprintf("line_items[%d]", i), err)
 		}
 	}
-	return nil
+	return errs.err()
 }
 
 // HasMMPTracking returns true if MMP tracking is configured
@@ -54,6 +54,11 @@ type Click struct {
 	// Target URL
 	TargetURL string `json:"target_url"`
 	
+	// Fraud
+	FraudScore   int      `json:"fraud_score"`
+	FraudReasons []string `json:"fraud_reasons,omitempty"`
+	FraudFlagged bool     `json:"fraud_flagged"`
+	
 	// Additional params
 	Params map[string]string `json:"params,omitempty"`
 }
@@ -130,9 +135,16 @@ type Conversion struct {
 	Payout         float64 `json:"payout,omitempty"`
 	PayoutCurrency string  `json:"payout_currency,omitempty"`
 	PayoutUSD      float64 `json:"payout_usd,omitempty"`
+	PayoutRuleID   string  `json:"payout_rule_id,omitempty"` // Rule that priced the payout
+	
+	// NeedsConversion marks revenue or payout that had no exchange rate at
+	// event time: the *_usd field is 0 and must be re-priced from the
+	// original amount and currency.
+	NeedsConversion bool `json:"needs_conversion,omitempty"`
 	
 	// Device info
-	DeviceIFA string `json:"device_ifa,omitempty"`
+	DeviceIFA  string `json:"device_ifa,omitempty"`
+	GeoCountry string `json:"geo_country,omitempty"` // Country of the click
 	
 	// Attribution
 	ClickTime     *time.Time `json:"click_time,omitempty"`
@@ -142,6 +154,11 @@ type Conversion struct {
 	// External IDs
 	ExternalID string `json:"external_id,omitempty"` // From MMP
 	
+	// Fraud (flagged conversions are not posted back to the source)
+	FraudScore   int      `json:"fraud_score"`
+	FraudReasons []string `json:"fraud_reasons,omitempty"`
+	FraudFlagged bool     `json:"fraud_flagged"`
+	
 	// Additional params from postback
 	Params map[string]string `json:"params,omitempty"`
 }
@@ -219,8 +236,14 @@ type Win struct {
 	SourceID string `json:"source_id"`
 
 	// Pricing
-	BidPrice float64 `json:"bid_price"`
-	WinPrice float64 `json:"win_price"`
+	BidPrice    float64 `json:"bid_price"`
+	WinPrice    float64 `json:"win_price"`
+	WinCurrency string  `json:"win_currency,omitempty"`
+	WinPriceUSD float64 `json:"win_price_usd"`
+
+	// NeedsConversion marks a win price that had no exchange rate at event
+	// time: WinPriceUSD is 0 and must be re-priced from WinPrice.
+	NeedsConversion bool `json:"needs_conversion,omitempty"`
 
 	// Device
 	DeviceIFA  string `json:"device_ifa,omitempty"`
@@ -275,5 +298,7 @@ var StandardMacros = []string{
 	"{revenue}",
 	"{currency}",
 	"{payout}",
+	"{payout_original}",
+	"{payout_original_currency}",
 	"{conversion_id}",
 }
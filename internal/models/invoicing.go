@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ===========================================
+// CLOSING DOCUMENTS
+// ===========================================
+
+// DocumentType is the kind of a closing document.
+type DocumentType string
+
+const (
+	DocumentInvoice    DocumentType = "invoice"     // Счёт на оплату
+	DocumentAct        DocumentType = "act"         // Акт оказанных услуг
+	DocumentVATInvoice DocumentType = "vat_invoice" // Счёт-фактура; only issued with VAT
+)
+
+// DocumentTypes lists document types in the order they are generated.
+var DocumentTypes = []DocumentType{DocumentInvoice, DocumentAct, DocumentVATInvoice}
+
+// DocumentFormat is the file format of a rendered document.
+type DocumentFormat string
+
+const (
+	DocumentFormatPDF  DocumentFormat = "pdf"
+	DocumentFormatXLSX DocumentFormat = "xlsx"
+)
+
+// LegalParty holds the requisites printed on documents. Documents keep a
+// copy so later edits of the advertiser don't change issued documents.
+type LegalParty struct {
+	Name           string     `json:"name"`
+	TaxID          string     `json:"tax_id,omitempty"` // ИНН
+	KPP            string     `json:"kpp,omitempty"`    // КПП
+	OGRN           string     `json:"ogrn,omitempty"`   // ОГРН
+	Address        string     `json:"address,omitempty"`
+	BankName       string     `json:"bank_name,omitempty"`
+	BIK            string     `json:"bik,omitempty"`
+	AccountNumber  string     `json:"account_number,omitempty"`  // Расчётный счёт
+	CorrAccount    string     `json:"corr_account,omitempty"`    // Корреспондентский счёт
+	ContractNumber string     `json:"contract_number,omitempty"` // Buyer only
+	ContractDate   *time.Time `json:"contract_date,omitempty"`
+	Director       string     `json:"director,omitempty"`   // Supplier only
+	Accountant     string     `json:"accountant,omitempty"` // Supplier only
+}
+
+// DocumentLine is one service line. Amounts are rounded to kopecks.
+type DocumentLine struct {
+	Name      string  `json:"name"`
+	Unit      string  `json:"unit"`
+	Quantity  float64 `json:"quantity"`
+	Net       float64 `json:"net"` // Without VAT
+	VATAmount float64 `json:"vat_amount"`
+	Total     float64 `json:"total"` // With VAT
+}
+
+// BillingDocument is a closing document for one advertiser and month,
+// built from the spend and CPA charges in the billing ledger.
+type BillingDocument struct {
+	ID           string       `json:"id"`
+	AdvertiserID string       `json:"advertiser_id"`
+	Type         DocumentType `json:"type"`
+	Number       string       `json:"number"`
+	Seq          int64        `json:"seq"` // Position in the type's yearly sequence
+	Date         time.Time    `json:"date"`
+	Period       string       `json:"period"` // YYYY-MM
+	PeriodStart  time.Time    `json:"period_start"`
+	PeriodEnd    time.Time    `json:"period_end"` // Exclusive
+
+	Supplier LegalParty `json:"supplier"`
+	Buyer    LegalParty `json:"buyer"`
+
+	Currency  string         `json:"currency"`
+	VATRate   float64        `json:"vat_rate"` // Percent; 0 is "без НДС"
+	Lines     []DocumentLine `json:"lines"`
+	Net       float64        `json:"net"`
+	VATAmount float64        `json:"vat_amount"`
+	Total     float64        `json:"total"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ClosingPeriod is a closed month. Its documents can no longer be
+// regenerated.
+type ClosingPeriod struct {
+	Period   string    `json:"period"` // YYYY-MM
+	ClosedAt time.Time `json:"closed_at"`
+	ClosedBy string    `json:"closed_by,omitempty"`
+}
+
+// ParsePeriod parses a YYYY-MM period into the first day of the month in loc.
+func ParsePeriod(period string, loc *time.Location) (time.Time, error) {
+	t, err := time.ParseInLocation("2006-01", period, loc)
+	if err != nil {
+		return time.Time{}, errors.New("period must be YYYY-MM")
+	}
+	return t, nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// ===========================================
+// CAMPAIGN LIFECYCLE
+// ===========================================
+
+// Campaign status transition reasons.
+const (
+	TransitionStartDate        = "start_date"        // Scheduled campaign reached its start date
+	TransitionEndDate          = "end_date"          // Campaign reached its end date
+	TransitionBudgetSpent      = "budget_spent"      // Spend reached the campaign's total budget
+	TransitionBalanceExhausted = "balance_exhausted" // Advertiser has no available balance
+	TransitionBalanceRestored  = "balance_restored"  // Balance is available again after balance_exhausted
+	TransitionManual           = "manual"            // Changed through the API
+)
+
+// CampaignTransition records one campaign status change and its reason.
+// Changes by the lifecycle scheduler have ActorKind PrincipalSystem.
+type CampaignTransition struct {
+	ID           string         `json:"id"`
+	CampaignID   string         `json:"campaign_id"`
+	AdvertiserID string         `json:"advertiser_id"`
+	From         CampaignStatus `json:"from"` // Empty for new campaigns
+	To           CampaignStatus `json:"to"`
+	Reason       string         `json:"reason"`
+	Detail       string         `json:"detail,omitempty"` // e.g. "spent $1000.00 of $1000.00"
+	ActorKind    string         `json:"actor_kind"`
+	ActorID      string         `json:"actor_id,omitempty"`
+	ActorName    string         `json:"actor_name,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// CampaignStatusWebhook is the body of campaign status webhooks.
+type CampaignStatusWebhook struct {
+	Event          string             `json:"event"` // campaign.status_changed
+	Transition     CampaignTransition `json:"transition"`
+	CampaignName   string             `json:"campaign_name"`
+	AdvertiserName string             `json:"advertiser_name,omitempty"`
+	AccountManager string             `json:"account_manager,omitempty"`
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// PAYOUT RULES
+// ===========================================
+
+// PayoutType defines how a payout amount is calculated.
+type PayoutType string
+
+const (
+	PayoutTypeFixed   PayoutType = "fixed"   // Fixed amount per conversion
+	PayoutTypePercent PayoutType = "percent" // Percent of conversion revenue
+	PayoutTypeTiered  PayoutType = "tiered"  // Amount/percent picked by revenue tier
+)
+
+// PayoutTier is one step of a tiered payout.
+// The tier with the highest MinRevenue not above the conversion revenue applies.
+type PayoutTier struct {
+	MinRevenue float64 `json:"min_revenue"`
+	Amount     float64 `json:"amount,omitempty"`
+	Percent    float64 `json:"percent,omitempty"`
+}
+
+// PayoutRule prices conversions paid out to traffic sources.
+// Empty key fields match any value; the most specific matching rule wins,
+// then the highest Priority.
+type PayoutRule struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+
+	// Match keys
+	CampaignID string `json:"campaign_id,omitempty"`
+	SourceType string `json:"source_type,omitempty"` // s2s, rtb
+	SourceID   string `json:"source_id,omitempty"`
+	Country    string `json:"country,omitempty"` // ISO-3166 alpha-2
+	OS         string `json:"os,omitempty"`
+	Event      string `json:"event,omitempty"` // internal event name
+
+	// Pricing
+	Type     PayoutType   `json:"type"`
+	Amount   float64      `json:"amount,omitempty"`   // fixed
+	Percent  float64      `json:"percent,omitempty"`  // percent of revenue
+	Tiers    []PayoutTier `json:"tiers,omitempty"`    // tiered
+	Currency string       `json:"currency,omitempty"` // defaults to advertiser currency
+
+	// Caps
+	MinPayout float64 `json:"min_payout,omitempty"`
+	MaxPayout float64 `json:"max_payout,omitempty"`
+
+	// Effective period
+	EffectiveFrom *time.Time `json:"effective_from,omitempty"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty"`
+
+	Priority  int       `json:"priority"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks that the rule is well-formed.
+func (r *PayoutRule) Validate() error {
+	if r == nil {
+		return errors.New("payout rule is nil")
+	}
+	if r.ID == "" {
+		return errors.New("id is required")
+	}
+	switch r.Type {
+	case PayoutTypeFixed:
+		if r.Amount < 0 {
+			return errors.New("amount must be non-negative")
+		}
+	case PayoutTypePercent:
+		if r.Percent < 0 || r.Percent > 100 {
+			return errors.New("percent must be between 0 and 100")
+		}
+	case PayoutTypeTiered:
+		if len(r.Tiers) == 0 {
+			return errors.New("tiered payout requires at least one tier")
+		}
+		for _, t := range r.Tiers {
+			if t.Amount < 0 || t.Percent < 0 || t.Percent > 100 {
+				return errors.New("invalid payout tier")
+			}
+		}
+	default:
+		return errors.New("type must be fixed, percent or tiered")
+	}
+	if r.MaxPayout > 0 && r.MinPayout > r.MaxPayout {
+		return errors.New("min_payout must not exceed max_payout")
+	}
+	if r.EffectiveFrom != nil && r.EffectiveTo != nil && !r.EffectiveTo.After(*r.EffectiveFrom) {
+		return errors.New("effective_to must be after effective_from")
+	}
+	return nil
+}
+
+// EffectiveAt returns true if the rule is active at the given time.
+func (r *PayoutRule) EffectiveAt(t time.Time) bool {
+	if !r.IsActive {
+		return false
+	}
+	if r.EffectiveFrom != nil && t.Before(*r.EffectiveFrom) {
+		return false
+	}
+	if r.EffectiveTo != nil && !t.Before(*r.EffectiveTo) {
+		return false
+	}
+	return true
+}
+
+// Specificity returns the number of match keys set on the rule.
+func (r *PayoutRule) Specificity() int {
+	n := 0
+	for _, k := range []string{r.CampaignID, r.SourceType, r.SourceID, r.Country, r.OS, r.Event} {
+		if k != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Calculate returns the payout for a conversion with the given revenue,
+// expressed in the rule's currency.
+func (r *PayoutRule) Calculate(revenue float64) float64 {
+	var payout float64
+	switch r.Type {
+	case PayoutTypeFixed:
+		payout = r.Amount
+	case PayoutTypePercent:
+		payout = revenue * r.Percent / 100
+	case PayoutTypeTiered:
+		var tier *PayoutTier
+		for i := range r.Tiers {
+			t := &r.Tiers[i]
+			if revenue >= t.MinRevenue && (tier == nil || t.MinRevenue > tier.MinRevenue) {
+				tier = t
+			}
+		}
+		if tier != nil {
+			payout = tier.Amount + revenue*tier.Percent/100
+		}
+	}
+
+	if payout < r.MinPayout {
+		payout = r.MinPayout
+	}
+	if r.MaxPayout > 0 && payout > r.MaxPayout {
+		payout = r.MaxPayout
+	}
+	return payout
+}
+
+// Matches checks the rule's keys against a conversion's attributes.
+func (r *PayoutRule) Matches(campaignID, sourceType, sourceID, country, os, event string) bool {
+	return matchKey(r.CampaignID, campaignID) &&
+		matchKey(r.SourceType, sourceType) &&
+		matchKey(r.SourceID, sourceID) &&
+		matchKey(r.Country, country) &&
+		matchKey(r.OS, os) &&
+		matchKey(r.Event, event)
+}
+
+func matchKey(ruleVal, val string) bool {
+	return ruleVal == "" || strings.EqualFold(ruleVal, val)
+}
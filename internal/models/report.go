@@ -0,0 +1,239 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// SCHEDULED REPORTS
+// ===========================================
+
+// ReportFormat is the file format of an exported report.
+type ReportFormat string
+
+const (
+	ReportFormatCSV    ReportFormat = "csv"
+	ReportFormatXLSX   ReportFormat = "xlsx"
+	ReportFormatNDJSON ReportFormat = "ndjson" // One JSON object per row
+)
+
+// ReportFrequency is how often a scheduled report runs.
+type ReportFrequency string
+
+const (
+	ReportFrequencyDaily  ReportFrequency = "daily"
+	ReportFrequencyWeekly ReportFrequency = "weekly"
+)
+
+// ReportPeriod is the date range a run covers, relative to the day it runs.
+type ReportPeriod string
+
+const (
+	ReportPeriodYesterday     ReportPeriod = "yesterday"
+	ReportPeriodLast7Days     ReportPeriod = "last_7_days"
+	ReportPeriodLast30Days    ReportPeriod = "last_30_days"
+	ReportPeriodPreviousWeek  ReportPeriod = "previous_week" // Monday to Sunday
+	ReportPeriodPreviousMonth ReportPeriod = "previous_month"
+	ReportPeriodMonthToDate   ReportPeriod = "month_to_date" // Up to yesterday
+)
+
+// ReportDeliveryType selects where a report file is sent.
+type ReportDeliveryType string
+
+const (
+	ReportDeliveryWebhook ReportDeliveryType = "webhook" // HTTP POST of the file
+	ReportDeliveryDir     ReportDeliveryType = "dir"     // File in a local directory
+	ReportDeliverySFTP    ReportDeliveryType = "sftp"    // Upload with the sftp client
+	ReportDeliverySMTP    ReportDeliveryType = "smtp"    // Email attachment
+)
+
+// ReportDelivery configures delivery of a scheduled report.
+type ReportDelivery struct {
+	Type ReportDeliveryType `json:"type"`
+
+	// webhook
+	URL     string            `json:"url,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// dir (relative to the configured output directory) and sftp
+	Path string `json:"path,omitempty"`
+
+	// sftp
+	Host string `json:"host,omitempty"` // host or host:port
+	User string `json:"user,omitempty"`
+
+	// smtp
+	Recipients []string `json:"recipients,omitempty"`
+	Subject    string   `json:"subject,omitempty"`
+}
+
+// ScheduledReport is a saved report definition delivered on a schedule.
+type ScheduledReport struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	AdvertiserID string `json:"advertiser_id,omitempty"`
+
+	// Filter is a dsp.ReportFilter as JSON. Its start and end dates are
+	// replaced by Period on every run.
+	Filter json.RawMessage `json:"filter"`
+	Period ReportPeriod    `json:"period"`
+	Format ReportFormat    `json:"format"`
+
+	// Schedule, in Timezone (the advertiser's timezone when empty)
+	Frequency ReportFrequency `json:"frequency"`
+	Weekday   time.Weekday    `json:"weekday,omitempty"` // Weekly reports; 0 is Sunday
+	Hour      int             `json:"hour"`              // Local hour to run (0-23)
+	Timezone  string          `json:"timezone,omitempty"`
+
+	Delivery ReportDelivery `json:"delivery"`
+
+	IsActive  bool       `json:"is_active"`
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// Validate checks that the report definition is complete.
+func (r *ScheduledReport) Validate() error {
+	if r == nil {
+		return errors.New("report is nil")
+	}
+	if r.ID == "" {
+		return errors.New("id is required")
+	}
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+
+	switch r.Format {
+	case ReportFormatCSV, ReportFormatXLSX, ReportFormatNDJSON:
+	default:
+		return fmt.Errorf("unknown format %q", r.Format)
+	}
+
+	switch r.Period {
+	case ReportPeriodYesterday, ReportPeriodLast7Days, ReportPeriodLast30Days,
+		ReportPeriodPreviousWeek, ReportPeriodPreviousMonth, ReportPeriodMonthToDate:
+	default:
+		return fmt.Errorf("unknown period %q", r.Period)
+	}
+
+	switch r.Frequency {
+	case ReportFrequencyDaily, ReportFrequencyWeekly:
+	default:
+		return fmt.Errorf("unknown frequency %q", r.Frequency)
+	}
+	if r.Weekday < time.Sunday || r.Weekday > time.Saturday {
+		return errors.New("weekday must be between 0 (Sunday) and 6")
+	}
+	if r.Hour < 0 || r.Hour > 23 {
+		return errors.New("hour must be between 0 and 23")
+	}
+	if r.Timezone != "" {
+		if _, err := time.LoadLocation(r.Timezone); err != nil {
+			return fmt.Errorf("unknown timezone %q", r.Timezone)
+		}
+	}
+
+	d := r.Delivery
+	switch d.Type {
+	case ReportDeliveryWebhook:
+		if !strings.HasPrefix(d.URL, "http://") && !strings.HasPrefix(d.URL, "https://") {
+			return errors.New("webhook delivery requires an http(s) url")
+		}
+	case ReportDeliveryDir:
+		if strings.Contains(d.Path, "..") {
+			return errors.New("dir delivery path must not contain ..")
+		}
+	case ReportDeliverySFTP:
+		if d.Host == "" || d.User == "" {
+			return errors.New("sftp delivery requires host and user")
+		}
+		if strings.ContainsAny(d.Host+d.User+d.Path, "\r\n\"") {
+			return errors.New("sftp delivery fields must not contain quotes or newlines")
+		}
+	case ReportDeliverySMTP:
+		if len(d.Recipients) == 0 {
+			return errors.New("smtp delivery requires recipients")
+		}
+		for _, rcpt := range d.Recipients {
+			if !strings.Contains(rcpt, "@") || strings.ContainsAny(rcpt, "\r\n") {
+				return fmt.Errorf("invalid recipient %q", rcpt)
+			}
+		}
+		if strings.ContainsAny(d.Subject, "\r\n") {
+			return errors.New("subject must not contain newlines")
+		}
+	default:
+		return fmt.Errorf("unknown delivery type %q", d.Type)
+	}
+	return nil
+}
+
+// NextRun returns the first scheduled time strictly after t, in loc.
+func (r *ScheduledReport) NextRun(t time.Time, loc *time.Location) time.Time {
+	local := t.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), r.Hour, 0, 0, 0, loc)
+	for !next.After(t) || (r.Frequency == ReportFrequencyWeekly && next.Weekday() != r.Weekday) {
+		next = time.Date(next.Year(), next.Month(), next.Day()+1, r.Hour, 0, 0, 0, loc)
+	}
+	return next
+}
+
+// PeriodRange returns the [start, end) range the period covers for a run
+// scheduled at t, in whole days of loc.
+func (r *ScheduledReport) PeriodRange(t time.Time, loc *time.Location) (time.Time, time.Time) {
+	local := t.In(loc)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	switch r.Period {
+	case ReportPeriodLast7Days:
+		return today.AddDate(0, 0, -7), today
+	case ReportPeriodLast30Days:
+		return today.AddDate(0, 0, -30), today
+	case ReportPeriodPreviousWeek:
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return monday.AddDate(0, 0, -7), monday
+	case ReportPeriodPreviousMonth:
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, loc)
+		return first.AddDate(0, -1, 0), first
+	case ReportPeriodMonthToDate:
+		yesterday := today.AddDate(0, 0, -1)
+		return time.Date(yesterday.Year(), yesterday.Month(), 1, 0, 0, 0, 0, loc), today
+	default:
+		return today.AddDate(0, 0, -1), today
+	}
+}
+
+// ReportRunStatus is the state of a report run.
+type ReportRunStatus string
+
+const (
+	ReportRunRunning   ReportRunStatus = "running"
+	ReportRunSucceeded ReportRunStatus = "succeeded"
+	ReportRunRetrying  ReportRunStatus = "retrying" // Failed, next attempt at NextAttemptAt
+	ReportRunFailed    ReportRunStatus = "failed"   // Out of attempts
+)
+
+// ReportRun records one execution of a scheduled report.
+type ReportRun struct {
+	ID            string          `json:"id"`
+	ReportID      string          `json:"report_id"`
+	ScheduledFor  time.Time       `json:"scheduled_for"`
+	PeriodStart   time.Time       `json:"period_start"`
+	PeriodEnd     time.Time       `json:"period_end"`
+	Status        ReportRunStatus `json:"status"`
+	Attempts      int             `json:"attempts"`
+	Error         string          `json:"error,omitempty"`
+	FileName      string          `json:"file_name,omitempty"`
+	Rows          int             `json:"rows"`
+	Bytes         int             `json:"bytes"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at,omitempty"`
+	StartedAt     time.Time       `json:"started_at"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+}
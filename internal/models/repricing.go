@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// ===========================================
+// HELD EVENTS (awaiting an exchange rate)
+// ===========================================
+
+// Kinds of held events.
+const (
+	UnpricedWin        = "win"
+	UnpricedConversion = "conversion"
+)
+
+// UnpricedEvent is a win or conversion held back because an amount had no
+// exchange rate at event time. It is saved, charged and posted back once a
+// rate for its timestamp is known; until then it appears in no report.
+type UnpricedEvent struct {
+	ID         string      `json:"id"` // ID of the win or conversion
+	Kind       string      `json:"kind"`
+	Win        *Win        `json:"win,omitempty"`
+	Conversion *Conversion `json:"conversion,omitempty"`
+	Attempts   int         `json:"attempts"` // Failed re-pricing attempts
+	LastError  string      `json:"last_error,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
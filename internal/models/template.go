@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// ===========================================
+// CLONES, TEMPLATES AND DRAFTS
+// ===========================================
+
+// CloneRequest overrides fields of a cloned campaign, line item or
+// creative, or of a campaign created from a template. Empty fields keep
+// the copied value.
+type CloneRequest struct {
+	ID   string `json:"id,omitempty"`   // ID of the copy; generated when empty
+	Name string `json:"name,omitempty"` // Default: the original name with " (copy)" appended; a template's campaign name
+
+	// Campaigns and line items: replaces the targeted countries of every
+	// copied line item
+	Countries []string `json:"countries,omitempty"`
+
+	// Campaigns: the campaign budgets. Line items: the pacing budgets.
+	DailyBudget *float64 `json:"daily_budget,omitempty"`
+	TotalBudget *float64 `json:"total_budget,omitempty"`
+
+	// Campaigns: the flight dates
+	StartDate *time.Time `json:"start_date,omitempty"`
+	EndDate   *time.Time `json:"end_date,omitempty"`
+
+	// Line items: the campaign the copy is added to; default the original's
+	CampaignID string `json:"campaign_id,omitempty"`
+
+	// Creatives: the click URL of the copy
+	ClickURL string `json:"click_url,omitempty"`
+}
+
+// CampaignTemplate is a reusable campaign of an advertiser. Campaigns
+// created from it are drafts copying Campaign with new IDs.
+type CampaignTemplate struct {
+	ID               string `json:"id"`
+	Name             string `json:"name"`
+	AdvertiserID     string `json:"advertiser_id"`
+	Description      string `json:"description,omitempty"`
+	SourceCampaignID string `json:"source_campaign_id,omitempty"` // Campaign the template was made from, if any
+
+	// Campaign is the blueprint. Its ID, status and timestamps are ignored;
+	// its advertiser is the template's.
+	Campaign Campaign `json:"campaign"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the template and its campaign and returns
+// ValidationErrors listing every invalid field.
+func (t *CampaignTemplate) Validate() error {
+	var errs ValidationErrors
+	if t.ID == "" {
+		errs.add("id", "is required")
+	}
+	if t.Name == "" {
+		errs.add("name", "is required")
+	}
+	if t.AdvertiserID == "" {
+		errs.add("advertiser_id", "is required")
+	}
+	// The blueprint is checked as the campaign it creates; IDs are
+	// generated then, so they're optional here.
+	c := t.Campaign
+	c.ID = t.ID
+	c.AdvertiserID = t.AdvertiserID
+	c.Status = CampaignStatusDraft
+	if c.Name == "" {
+		c.Name = t.Name
+	}
+	c.LineItems = append([]LineItem(nil), t.Campaign.LineItems...)
+	for i := range c.LineItems {
+		c.LineItems[i].CampaignID = c.ID
+		if c.LineItems[i].ID == "" {
+			c.LineItems[i].ID = "template"
+		}
+	}
+	if err := c.Validate(); err != nil {
+		errs.nest("campaign", err)
+	}
+	return errs.err()
+}
+
+// CampaignDraft stages changes of a campaign: the bidder keeps using the
+// live campaign until the draft is published. BaseUpdatedAt is the
+// version of the live campaign the draft started from; publishing fails if
+// the campaign changed since, unless forced.
+type CampaignDraft struct {
+	CampaignID    string    `json:"campaign_id"`
+	AdvertiserID  string    `json:"advertiser_id"`
+	Campaign      Campaign  `json:"campaign"` // The staged version
+	BaseUpdatedAt time.Time `json:"base_updated_at"`
+	ActorID       string    `json:"actor_id,omitempty"` // Last to change the draft
+	ActorName     string    `json:"actor_name,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,176 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ===========================================
+// USERS
+// ===========================================
+
+// User roles.
+const (
+	RoleAdmin            = "admin"             // Everything, including users and API keys
+	RoleTrader           = "trader"            // Campaigns, ad groups, creatives and reports
+	RoleAnalyst          = "analyst"           // Read-only, plus scheduled reports
+	RoleAdvertiserViewer = "advertiser_viewer" // Read-only, restricted to one advertiser
+)
+
+// User statuses.
+const (
+	UserActive   = "active"
+	UserDisabled = "disabled"
+)
+
+// roleScopes are the API key scopes each role has.
+var roleScopes = map[string][]string{
+	RoleAdmin:            {ScopeRead, ScopeWrite, ScopeAdmin},
+	RoleTrader:           {ScopeRead, ScopeWrite},
+	RoleAnalyst:          {ScopeRead, ScopeWrite},
+	RoleAdvertiserViewer: {ScopeRead},
+}
+
+// roleWrites are the /api route prefixes each role may change. Reads are
+// open to every role; admins may change every route.
+var roleWrites = map[string][]string{
+	RoleTrader: {
+		"/api/campaigns", "/api/v1/campaigns", "/api/v1/bulk", "/api/v1/rules", "/api/v1/alerts", "/api/v1/templates",
+		"/api/v1/creatives", "/api/adgroups", "/api/creatives", "/api/scheduled-reports", "/api/stats/rollup",
+	},
+	RoleAnalyst: {"/api/scheduled-reports"},
+}
+
+// UserAccount is a management UI login.
+type UserAccount struct {
+	ID           string     `json:"id"`
+	Email        string     `json:"email"`
+	Name         string     `json:"name,omitempty"`
+	PasswordHash string     `json:"-"` // bcrypt
+	Role         string     `json:"role"`
+	AdvertiserID string     `json:"advertiser_id,omitempty"` // Restricts the user to one advertiser
+	TOTPSecret   string     `json:"-"`                       // Base32; set on setup
+	TOTPEnabled  bool       `json:"totp_enabled"`
+	TOTPLastStep int64      `json:"-"` // Last accepted time step
+	Status       string     `json:"status"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// Validate checks that required fields are present and the role is known.
+func (u *UserAccount) Validate() error {
+	if u == nil {
+		return errors.New("user is nil")
+	}
+	if u.ID == "" {
+		return errors.New("id is required")
+	}
+	if u.Email == "" || !strings.Contains(u.Email, "@") {
+		return errors.New("valid email is required")
+	}
+	if _, ok := roleScopes[u.Role]; !ok {
+		return errors.New("unknown role: " + u.Role)
+	}
+	if u.Role == RoleAdvertiserViewer && u.AdvertiserID == "" {
+		return errors.New("advertiser_id is required for advertiser_viewer")
+	}
+	if u.Role == RoleAdmin && u.AdvertiserID != "" {
+		return errors.New("admins can't be restricted to an advertiser")
+	}
+	if u.Status != UserActive && u.Status != UserDisabled {
+		return errors.New("status must be active or disabled")
+	}
+	return nil
+}
+
+// UserSession is a refresh token of a user. Tokens are rotated on every
+// refresh; only the SHA-256 hash is stored.
+type UserSession struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	TokenHash string     `json:"-"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	IP        string     `json:"ip,omitempty"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Active reports whether the session can be refreshed at t.
+func (s *UserSession) Active(t time.Time) bool {
+	return s.RevokedAt == nil && t.Before(s.ExpiresAt)
+}
+
+// ===========================================
+// PRINCIPAL
+// ===========================================
+
+// Principal kinds.
+const (
+	PrincipalMaster = "master"
+	PrincipalAPIKey = "api_key"
+	PrincipalUser   = "user"
+	PrincipalSystem = "system" // Background jobs such as the lifecycle scheduler; never authenticates
+)
+
+// Principal is the authenticated caller of a request: the master key, an
+// API key or a user.
+type Principal struct {
+	Kind         string   `json:"kind"`
+	ID           string   `json:"id,omitempty"` // API key or user ID
+	Name         string   `json:"name,omitempty"`
+	Role         string   `json:"role,omitempty"` // Users only
+	AdvertiserID string   `json:"advertiser_id,omitempty"`
+	Permissions  []string `json:"permissions"`
+}
+
+// NewUserPrincipal returns the principal of a user.
+func NewUserPrincipal(u *UserAccount) *Principal {
+	return &Principal{
+		Kind:         PrincipalUser,
+		ID:           u.ID,
+		Name:         u.Email,
+		Role:         u.Role,
+		AdvertiserID: u.AdvertiserID,
+		Permissions:  roleScopes[u.Role],
+	}
+}
+
+// NewAPIKeyPrincipal returns the principal of an API key.
+func NewAPIKeyPrincipal(k *APIKey) *Principal {
+	return &Principal{
+		Kind:         PrincipalAPIKey,
+		ID:           k.ID,
+		Name:         k.Name,
+		AdvertiserID: k.AdvertiserID,
+		Permissions:  k.Permissions,
+	}
+}
+
+// Allows reports whether the principal has scope.
+func (p *Principal) Allows(scope string) bool {
+	if p.Kind == PrincipalMaster {
+		return true
+	}
+	k := APIKey{Permissions: p.Permissions}
+	return k.Allows(scope)
+}
+
+// CanWrite reports whether the principal may change resources under path.
+// API keys are limited by their scopes only; users also by their role.
+func (p *Principal) CanWrite(path string) bool {
+	if !p.Allows(ScopeWrite) {
+		return false
+	}
+	if p.Kind != PrincipalUser || p.Role == RoleAdmin {
+		return true
+	}
+	for _, prefix := range roleWrites[p.Role] {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
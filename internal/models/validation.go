@@ -0,0 +1,52 @@
+package models
+
+import "strings"
+
+// ===========================================
+// VALIDATION
+// ===========================================
+
+// FieldError is a validation failure of one field. Field is the JSON path
+// of the field, e.g. "line_items[0].pacing.daily_budget".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors lists every invalid field of an object. Validate
+// methods that collect all failures return it, so APIs can report them
+// per field.
+type ValidationErrors []FieldError
+
+func (v ValidationErrors) Error() string {
+	msgs := make([]string, len(v))
+	for i, e := range v {
+		msgs[i] = e.Field + " " + e.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// add appends a failure of field.
+func (v *ValidationErrors) add(field, message string) {
+	*v = append(*v, FieldError{Field: field, Message: message})
+}
+
+// nest appends the failures of a nested object under prefix.
+func (v *ValidationErrors) nest(prefix string, err error) {
+	nested, ok := err.(ValidationErrors)
+	if !ok {
+		v.add(prefix, err.Error())
+		return
+	}
+	for _, e := range nested {
+		v.add(prefix+"."+e.Field, e.Message)
+	}
+}
+
+// err returns v as an error, or nil if there are no failures.
+func (v ValidationErrors) err() error {
+	if len(v) == 0 {
+		return nil
+	}
+	return v
+}
@@ -0,0 +1,196 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// Deliverer sends rendered reports to webhooks, directories, SFTP servers
+// and mailboxes.
+type Deliverer struct {
+	cfg        config.ReportsConfig
+	httpClient *http.Client
+}
+
+// NewDeliverer creates a new report deliverer.
+func NewDeliverer(cfg config.ReportsConfig) *Deliverer {
+	return &Deliverer{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: cfg.WebhookTimeout},
+	}
+}
+
+// Deliver sends f as configured by d.
+func (d *Deliverer) Deliver(ctx context.Context, delivery models.ReportDelivery, f *File) error {
+	switch delivery.Type {
+	case models.ReportDeliveryWebhook:
+		return d.deliverWebhook(ctx, delivery, f)
+	case models.ReportDeliveryDir:
+		return d.deliverDir(delivery, f)
+	case models.ReportDeliverySFTP:
+		return d.deliverSFTP(ctx, delivery, f)
+	case models.ReportDeliverySMTP:
+		return d.deliverSMTP(delivery, f)
+	default:
+		return fmt.Errorf("unknown delivery type %q", delivery.Type)
+	}
+}
+
+// deliverWebhook POSTs the file as the request body.
+func (d *Deliverer) deliverWebhook(ctx context.Context, delivery models.ReportDelivery, f *File) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(f.Data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", f.ContentType)
+	req.Header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", f.Name))
+	for k, v := range delivery.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post report: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// deliverDir writes the file under the output directory. The file is
+// renamed into place so readers never see a partial report.
+func (d *Deliverer) deliverDir(delivery models.ReportDelivery, f *File) error {
+	dir := filepath.Join(d.cfg.OutputDir, filepath.FromSlash(delivery.Path))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".report-*")
+	if err != nil {
+		return fmt.Errorf("failed to create report file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(f.Data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write report file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, f.Name)); err != nil {
+		return fmt.Errorf("failed to move report file: %w", err)
+	}
+	return nil
+}
+
+// deliverSFTP uploads the file with the OpenSSH sftp client in batch mode,
+// authenticating with the configured key.
+func (d *Deliverer) deliverSFTP(ctx context.Context, delivery models.ReportDelivery, f *File) error {
+	tmp, err := os.CreateTemp("", "report-*")
+	if err != nil {
+		return fmt.Errorf("failed to stage report file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(f.Data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to stage report file: %w", err)
+	}
+	tmp.Close()
+
+	host, port := delivery.Host, ""
+	if h, p, err := net.SplitHostPort(delivery.Host); err == nil {
+		host, port = h, p
+	}
+
+	args := []string{"-b", "-", "-o", "BatchMode=yes", "-o", "StrictHostKeyChecking=accept-new"}
+	if port != "" {
+		args = append(args, "-P", port)
+	}
+	if d.cfg.SFTPKeyFile != "" {
+		args = append(args, "-i", d.cfg.SFTPKeyFile)
+	}
+	args = append(args, delivery.User+"@"+host)
+
+	remote := path.Join(delivery.Path, f.Name)
+	cmd := exec.CommandContext(ctx, "sftp", args...)
+	cmd.Stdin = strings.NewReader(fmt.Sprintf("put \"%s\" \"%s\"\n", tmp.Name(), remote))
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("sftp upload failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// deliverSMTP emails the file as an attachment.
+func (d *Deliverer) deliverSMTP(delivery models.ReportDelivery, f *File) error {
+	subject := delivery.Subject
+	if subject == "" {
+		subject = "Report " + f.Name
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fmt.Fprintf(&body, "From: %s\r\n", d.cfg.SMTPFrom)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(delivery.Recipients, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&body, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&body, "Message-ID: <%s@vector-dsp>\r\n", uuid.New().String())
+	body.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&body, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+
+	text, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	fmt.Fprintf(text, "The scheduled report %s is attached.\r\n", f.Name)
+
+	attachment, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {f.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {fmt.Sprintf("attachment; filename=%q", f.Name)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(f.Data)
+	for len(encoded) > 76 {
+		io.WriteString(attachment, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(attachment, encoded+"\r\n")
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("failed to build email: %w", err)
+	}
+
+	var auth smtp.Auth
+	if d.cfg.SMTPUser != "" {
+		host, _, _ := net.SplitHostPort(d.cfg.SMTPAddr)
+		auth = smtp.PlainAuth("", d.cfg.SMTPUser, d.cfg.SMTPPassword, host)
+	}
+	if err := smtp.SendMail(d.cfg.SMTPAddr, auth, d.cfg.SMTPFrom, delivery.Recipients, body.Bytes()); err != nil {
+		return fmt.Errorf("failed to send report email: %w", err)
+	}
+	return nil
+}
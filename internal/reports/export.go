@@ -0,0 +1,113 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// File is a rendered report ready for delivery.
+type File struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// contentTypes maps each format to its MIME type and file extension.
+var contentTypes = map[models.ReportFormat]struct{ mime, ext string }{
+	models.ReportFormatCSV:    {"text/csv", "csv"},
+	models.ReportFormatXLSX:   {"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx"},
+	models.ReportFormatNDJSON: {"application/x-ndjson", "ndjson"},
+}
+
+// Export renders result in format. The file is named base plus the
+// format's extension.
+func Export(result *storage.ReportResult, format models.ReportFormat, base string) (*File, error) {
+	ct, ok := contentTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown report format %q", format)
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch format {
+	case models.ReportFormatCSV:
+		data, err = exportCSV(result)
+	case models.ReportFormatXLSX:
+		data, err = exportXLSX(result)
+	default:
+		data, err = exportNDJSON(result)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export %s report: %w", format, err)
+	}
+
+	return &File{Name: base + "." + ct.ext, ContentType: ct.mime, Data: data}, nil
+}
+
+// header returns the column names: dimensions then metrics.
+func header(result *storage.ReportResult) []string {
+	cols := make([]string, 0, len(result.Dimensions)+len(result.Metrics))
+	cols = append(cols, result.Dimensions...)
+	return append(cols, result.Metrics...)
+}
+
+// formatMetric prints a metric without exponent notation or trailing zeros.
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func exportCSV(result *storage.ReportResult) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	if err := w.Write(header(result)); err != nil {
+		return nil, err
+	}
+	record := make([]string, 0, len(result.Dimensions)+len(result.Metrics))
+	for _, row := range result.Rows {
+		record = record[:0]
+		for _, d := range result.Dimensions {
+			record = append(record, row.Dimensions[d])
+		}
+		for _, m := range result.Metrics {
+			record = append(record, formatMetric(row.Metrics[m]))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportNDJSON writes one flat JSON object per row.
+func exportNDJSON(result *storage.ReportResult) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+
+	for _, row := range result.Rows {
+		obj := make(map[string]interface{}, len(row.Dimensions)+len(row.Metrics))
+		for _, d := range result.Dimensions {
+			obj[d] = row.Dimensions[d]
+		}
+		for _, m := range result.Metrics {
+			obj[m] = row.Metrics[m]
+		}
+		if err := enc.Encode(obj); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,351 @@
+package reports
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/currency"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// ErrReportNotFound is returned when a scheduled report doesn't exist.
+var ErrReportNotFound = errors.New("scheduled report not found")
+
+// AdvertiserLookup resolves an advertiser's timezone and currency.
+type AdvertiserLookup interface {
+	GetAdvertiser(ctx context.Context, id string) (*models.Advertiser, error)
+}
+
+// Scheduler runs saved reports on their schedules, delivers the files and
+// retries failed runs with exponential backoff.
+type Scheduler struct {
+	repo        storage.ScheduledReportRepo
+	reporting   *dsp.ReportingService
+	advertisers AdvertiserLookup
+	deliverer   *Deliverer
+	cfg         config.ReportsConfig
+	logger      *zap.Logger
+	metrics     *metrics.Metrics
+
+	// mu serializes runs so a manual run and a tick don't overlap
+	mu sync.Mutex
+}
+
+// NewScheduler creates a new report scheduler.
+func NewScheduler(
+	repo storage.ScheduledReportRepo,
+	reporting *dsp.ReportingService,
+	advertisers AdvertiserLookup,
+	deliverer *Deliverer,
+	cfg config.ReportsConfig,
+	logger *zap.Logger,
+	m *metrics.Metrics,
+) *Scheduler {
+	return &Scheduler{
+		repo:        repo,
+		reporting:   reporting,
+		advertisers: advertisers,
+		deliverer:   deliverer,
+		cfg:         cfg,
+		logger:      logger,
+		metrics:     m,
+	}
+}
+
+// Run checks for due reports and retries every poll interval until ctx
+// is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.tick(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// tick runs due reports and due retries.
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due, err := s.repo.ListDue(ctx, now)
+	if err != nil {
+		s.logger.Error("failed to list due reports", zap.Error(err))
+		return
+	}
+	for _, report := range due {
+		if ctx.Err() != nil {
+			return
+		}
+		s.runScheduled(ctx, report, now)
+	}
+
+	retries, err := s.repo.ListRetryableRuns(ctx, now)
+	if err != nil {
+		s.logger.Error("failed to list report retries", zap.Error(err))
+		return
+	}
+	for _, run := range retries {
+		if ctx.Err() != nil {
+			return
+		}
+		report, err := s.repo.GetByID(ctx, run.ReportID)
+		if err != nil {
+			s.logger.Error("failed to load report for retry", zap.String("report_id", run.ReportID), zap.Error(err))
+			continue
+		}
+		if report == nil {
+			continue // Deleted; its runs went with it
+		}
+		s.attempt(ctx, report, run)
+	}
+}
+
+// runScheduled runs a due report once and advances its schedule. Runs
+// missed while the scheduler was down are skipped, not replayed.
+func (s *Scheduler) runScheduled(ctx context.Context, report *models.ScheduledReport, now time.Time) {
+	loc := s.location(ctx, report)
+	run := s.newRun(report, report.NextRunAt, loc)
+	s.attempt(ctx, report, run)
+
+	updated := *report
+	updated.NextRunAt = report.NextRun(now, loc)
+	updated.LastRunAt = &now
+	if err := s.repo.Upsert(ctx, &updated); err != nil {
+		s.logger.Error("failed to advance report schedule", zap.String("report_id", report.ID), zap.Error(err))
+	}
+}
+
+// RunNow runs a report immediately for the period ending today, outside
+// its schedule. Failed runs are retried like scheduled ones.
+func (s *Scheduler) RunNow(ctx context.Context, id string) (*models.ReportRun, error) {
+	report, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled report: %w", err)
+	}
+	if report == nil {
+		return nil, ErrReportNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run := s.newRun(report, time.Now(), s.location(ctx, report))
+	s.attempt(ctx, report, run)
+	return run, nil
+}
+
+// Schedule sets the report's next run after now.
+func (s *Scheduler) Schedule(ctx context.Context, report *models.ScheduledReport, now time.Time) {
+	report.NextRunAt = report.NextRun(now, s.location(ctx, report))
+}
+
+// Export renders the report's current period without delivering it.
+// An empty format uses the report's own.
+func (s *Scheduler) Export(ctx context.Context, report *models.ScheduledReport, format models.ReportFormat) (*File, error) {
+	if format == "" {
+		format = report.Format
+	}
+	loc := s.location(ctx, report)
+	start, end := report.PeriodRange(time.Now(), loc)
+	f, _, err := s.render(ctx, report, format, start, end, loc)
+	return f, err
+}
+
+// ValidateFilter checks that the report's saved filter can run.
+func (s *Scheduler) ValidateFilter(report *models.ScheduledReport) error {
+	filter, err := decodeFilter(report)
+	if err != nil {
+		return err
+	}
+	return s.reporting.ValidateFilter(filter)
+}
+
+func (s *Scheduler) newRun(report *models.ScheduledReport, scheduledFor time.Time, loc *time.Location) *models.ReportRun {
+	start, end := report.PeriodRange(scheduledFor, loc)
+	return &models.ReportRun{
+		ID:           uuid.New().String(),
+		ReportID:     report.ID,
+		ScheduledFor: scheduledFor,
+		PeriodStart:  start,
+		PeriodEnd:    end,
+		Status:       models.ReportRunRunning,
+		StartedAt:    time.Now(),
+	}
+}
+
+// attempt renders and delivers one attempt of run and records the outcome.
+func (s *Scheduler) attempt(ctx context.Context, report *models.ScheduledReport, run *models.ReportRun) {
+	run.Attempts++
+	run.Status = models.ReportRunRunning
+	run.NextAttemptAt = nil
+	if err := s.repo.SaveRun(ctx, run); err != nil {
+		s.logger.Error("failed to save report run", zap.String("run_id", run.ID), zap.Error(err))
+	}
+
+	err := s.execute(ctx, report, run)
+	now := time.Now()
+	switch {
+	case err == nil:
+		run.Status = models.ReportRunSucceeded
+		run.Error = ""
+		run.FinishedAt = &now
+	case run.Attempts < s.cfg.MaxAttempts:
+		run.Status = models.ReportRunRetrying
+		run.Error = err.Error()
+		next := now.Add(s.cfg.RetryBackoff << (run.Attempts - 1))
+		run.NextAttemptAt = &next
+	default:
+		run.Status = models.ReportRunFailed
+		run.Error = err.Error()
+		run.FinishedAt = &now
+	}
+
+	if err := s.repo.SaveRun(ctx, run); err != nil {
+		s.logger.Error("failed to save report run", zap.String("run_id", run.ID), zap.Error(err))
+	}
+	if s.metrics != nil {
+		s.metrics.RecordReportRun(string(report.Format), string(run.Status))
+	}
+
+	fields := []zap.Field{
+		zap.String("report_id", report.ID),
+		zap.String("run_id", run.ID),
+		zap.Int("attempt", run.Attempts),
+		zap.String("status", string(run.Status)),
+	}
+	if err != nil {
+		s.logger.Warn("report run failed", append(fields, zap.Error(err))...)
+	} else {
+		s.logger.Info("report delivered", append(fields, zap.String("file", run.FileName), zap.Int("rows", run.Rows))...)
+	}
+}
+
+// execute renders the run's period and delivers the file.
+func (s *Scheduler) execute(ctx context.Context, report *models.ScheduledReport, run *models.ReportRun) error {
+	f, rows, err := s.render(ctx, report, report.Format, run.PeriodStart, run.PeriodEnd, s.location(ctx, report))
+	if err != nil {
+		return err
+	}
+	run.FileName = f.Name
+	run.Rows = rows
+	run.Bytes = len(f.Data)
+
+	return s.deliverer.Deliver(ctx, report.Delivery, f)
+}
+
+// render runs the saved filter over [start, end) and exports every row.
+func (s *Scheduler) render(ctx context.Context, report *models.ScheduledReport, format models.ReportFormat, start, end time.Time, loc *time.Location) (*File, int, error) {
+	filter, err := decodeFilter(report)
+	if err != nil {
+		return nil, 0, err
+	}
+	filter.StartDate = start
+	filter.EndDate = end
+	if filter.Timezone == "" {
+		filter.Timezone = loc.String()
+	}
+	if filter.AdvertiserID == "" {
+		filter.AdvertiserID = report.AdvertiserID
+	}
+	if filter.Currency == "" {
+		filter.Currency = s.advertiserCurrency(ctx, filter.AdvertiserID)
+	}
+
+	// Page through the whole report
+	filter.Limit = storage.MaxReportLimit
+	filter.Offset = 0
+	var all *storage.ReportResult
+	for {
+		page, err := s.reporting.Report(ctx, filter)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to run report: %w", err)
+		}
+		if all == nil {
+			all = page
+		} else {
+			all.Rows = append(all.Rows, page.Rows...)
+		}
+		if len(page.Rows) < filter.Limit || int64(len(all.Rows)) >= page.Total {
+			break
+		}
+		filter.Offset += filter.Limit
+	}
+
+	f, err := Export(all, format, fileBase(report, start, end, loc))
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, len(all.Rows), nil
+}
+
+// location is the report's timezone, else its advertiser's, else UTC.
+func (s *Scheduler) location(ctx context.Context, report *models.ScheduledReport) *time.Location {
+	tz := report.Timezone
+	if tz == "" && report.AdvertiserID != "" && s.advertisers != nil {
+		if adv, err := s.advertisers.GetAdvertiser(ctx, report.AdvertiserID); err == nil && adv != nil {
+			tz = adv.Timezone
+		}
+	}
+	if tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+func (s *Scheduler) advertiserCurrency(ctx context.Context, advertiserID string) string {
+	if advertiserID != "" && s.advertisers != nil {
+		if adv, err := s.advertisers.GetAdvertiser(ctx, advertiserID); err == nil && adv != nil && adv.Currency != "" {
+			return currency.Normalize(adv.Currency)
+		}
+	}
+	return currency.ReportingCurrency
+}
+
+func decodeFilter(report *models.ScheduledReport) (dsp.ReportFilter, error) {
+	var filter dsp.ReportFilter
+	if len(report.Filter) > 0 {
+		if err := json.Unmarshal(report.Filter, &filter); err != nil {
+			return filter, fmt.Errorf("%w: filter: %v", dsp.ErrInvalidReport, err)
+		}
+	}
+	return filter, nil
+}
+
+// fileBase names a report file after the report and its period, e.g.
+// "daily_spend_2024-05-01" or "weekly_2024-04-22_2024-04-28".
+func fileBase(report *models.ScheduledReport, start, end time.Time, loc *time.Location) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, report.Name)
+
+	first := start.In(loc).Format("2006-01-02")
+	last := end.In(loc).AddDate(0, 0, -1).Format("2006-01-02")
+	if first == last {
+		return name + "_" + first
+	}
+	return name + "_" + first + "_" + last
+}
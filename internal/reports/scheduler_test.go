@@ -0,0 +1,171 @@
+package reports
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/config"
+	"github.com/radiusdt/vector-dsp/internal/dsp"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"go.uber.org/zap"
+)
+
+// newTestScheduler returns a scheduler over an empty event store that
+// delivers to a webhook answering with status.
+func newTestScheduler(t *testing.T, status int) (*Scheduler, *storage.InMemoryScheduledReportRepo, *models.ScheduledReport) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := config.ReportsConfig{MaxAttempts: 3, RetryBackoff: time.Minute, WebhookTimeout: time.Second}
+	repo := storage.NewInMemoryScheduledReportRepo()
+	s := NewScheduler(repo, dsp.NewReportingService(storage.NewInMemoryEventStore(), nil), nil,
+		NewDeliverer(cfg), cfg, zap.NewNop(), nil)
+
+	report := &models.ScheduledReport{
+		ID:        "rep-1",
+		Name:      "daily spend",
+		Filter:    []byte(`{}`),
+		Period:    models.ReportPeriodYesterday,
+		Format:    models.ReportFormatCSV,
+		Frequency: models.ReportFrequencyDaily,
+		Hour:      6,
+		Delivery:  models.ReportDelivery{Type: models.ReportDeliveryWebhook, URL: srv.URL},
+		IsActive:  true,
+	}
+	return s, repo, report
+}
+
+func TestSchedulerAttemptTransitions(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		prevAttempts int
+		wantStatus   models.ReportRunStatus
+		wantBackoff  time.Duration // 0 means no retry is scheduled
+	}{
+		{name: "delivered", status: http.StatusOK, wantStatus: models.ReportRunSucceeded},
+		{name: "first failure retries", status: http.StatusBadGateway, wantStatus: models.ReportRunRetrying, wantBackoff: time.Minute},
+		{name: "backoff doubles", status: http.StatusBadGateway, prevAttempts: 1, wantStatus: models.ReportRunRetrying, wantBackoff: 2 * time.Minute},
+		{name: "out of attempts fails", status: http.StatusBadGateway, prevAttempts: 2, wantStatus: models.ReportRunFailed},
+		{name: "retry succeeds", status: http.StatusOK, prevAttempts: 2, wantStatus: models.ReportRunSucceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s, repo, report := newTestScheduler(t, tt.status)
+
+			run := s.newRun(report, time.Now(), time.UTC)
+			run.Attempts = tt.prevAttempts
+			before := time.Now()
+			s.attempt(ctx, report, run)
+
+			saved, err := repo.GetRun(ctx, run.ID)
+			if err != nil || saved == nil {
+				t.Fatalf("GetRun() = %v, %v", saved, err)
+			}
+			if saved.Status != tt.wantStatus {
+				t.Fatalf("Status = %s, want %s (error %q)", saved.Status, tt.wantStatus, saved.Error)
+			}
+			if saved.Attempts != tt.prevAttempts+1 {
+				t.Errorf("Attempts = %d, want %d", saved.Attempts, tt.prevAttempts+1)
+			}
+			if (saved.Error == "") != (tt.wantStatus == models.ReportRunSucceeded) {
+				t.Errorf("Error = %q with status %s", saved.Error, saved.Status)
+			}
+
+			if tt.wantBackoff == 0 {
+				if saved.NextAttemptAt != nil {
+					t.Errorf("NextAttemptAt = %v, want none", saved.NextAttemptAt)
+				}
+				if saved.FinishedAt == nil {
+					t.Error("FinishedAt not set")
+				}
+				return
+			}
+			if saved.NextAttemptAt == nil {
+				t.Fatal("NextAttemptAt not set")
+			}
+			if d := saved.NextAttemptAt.Sub(before); d < tt.wantBackoff || d > tt.wantBackoff+time.Minute/2 {
+				t.Errorf("next attempt in %v, want %v", d, tt.wantBackoff)
+			}
+			if saved.FinishedAt != nil {
+				t.Errorf("FinishedAt = %v, want none while retrying", saved.FinishedAt)
+			}
+		})
+	}
+}
+
+func TestSchedulerTick(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("due report runs and advances", func(t *testing.T) {
+		s, repo, report := newTestScheduler(t, http.StatusOK)
+		now := time.Date(2024, 5, 3, 7, 30, 0, 0, time.UTC)
+		report.NextRunAt = time.Date(2024, 5, 3, 6, 0, 0, 0, time.UTC)
+		if err := repo.Upsert(ctx, report); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+
+		s.tick(ctx, now)
+
+		saved, _ := repo.GetByID(ctx, report.ID)
+		if want := time.Date(2024, 5, 4, 6, 0, 0, 0, time.UTC); !saved.NextRunAt.Equal(want) {
+			t.Errorf("NextRunAt = %v, want %v", saved.NextRunAt, want)
+		}
+		if saved.LastRunAt == nil || !saved.LastRunAt.Equal(now) {
+			t.Errorf("LastRunAt = %v, want %v", saved.LastRunAt, now)
+		}
+		runs, _ := repo.ListRuns(ctx, report.ID, 0)
+		if len(runs) != 1 || runs[0].Status != models.ReportRunSucceeded {
+			t.Fatalf("runs = %+v, want one succeeded run", runs)
+		}
+		if want := time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC); !runs[0].PeriodStart.Equal(want) {
+			t.Errorf("PeriodStart = %v, want %v", runs[0].PeriodStart, want)
+		}
+	})
+
+	t.Run("report not due is skipped", func(t *testing.T) {
+		s, repo, report := newTestScheduler(t, http.StatusOK)
+		now := time.Date(2024, 5, 3, 5, 0, 0, 0, time.UTC)
+		report.NextRunAt = time.Date(2024, 5, 3, 6, 0, 0, 0, time.UTC)
+		if err := repo.Upsert(ctx, report); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+
+		s.tick(ctx, now)
+
+		if runs, _ := repo.ListRuns(ctx, report.ID, 0); len(runs) != 0 {
+			t.Errorf("runs = %d, want 0", len(runs))
+		}
+	})
+
+	t.Run("due retry is attempted again", func(t *testing.T) {
+		s, repo, report := newTestScheduler(t, http.StatusOK)
+		report.NextRunAt = time.Now().AddDate(1, 0, 0)
+		if err := repo.Upsert(ctx, report); err != nil {
+			t.Fatalf("Upsert() error = %v", err)
+		}
+		run := s.newRun(report, time.Now().Add(-time.Hour), time.UTC)
+		due := time.Now().Add(-time.Minute)
+		run.Status = models.ReportRunRetrying
+		run.Attempts = 1
+		run.NextAttemptAt = &due
+		if err := repo.SaveRun(ctx, run); err != nil {
+			t.Fatalf("SaveRun() error = %v", err)
+		}
+
+		s.tick(ctx, time.Now())
+
+		saved, _ := repo.GetRun(ctx, run.ID)
+		if saved.Status != models.ReportRunSucceeded || saved.Attempts != 2 {
+			t.Errorf("run = %s after %d attempts, want succeeded after 2", saved.Status, saved.Attempts)
+		}
+	})
+}
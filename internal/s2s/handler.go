@@ -13,10 +13,14 @@ import (
 )
 
 // S2SHandler handles S2S partner ad requests
+//
+// Deprecated: the server answers /s2s/{source}/ad with dsp.S2SAdService,
+// which applies RTB targeting, pacing, source caps and payout ranking.
 type S2SHandler struct {
 	sourceStore     SourceStore
 	campaignStore   CampaignStore
 	trackingService TrackingService
+	payoutQuoter    PayoutQuoter
 	logger          *zap.Logger
 	baseURL         string
 }
@@ -87,6 +91,11 @@ type TrackingService interface {
 	BuildOurViewURL(campaignID, creativeID, lineItemID, sourceID, sourceType, impressionID string) string
 }
 
+// PayoutQuoter prices the payout advertised to a source (e.g. dsp.PayoutEngine)
+type PayoutQuoter interface {
+	QuotePayout(ctx context.Context, campaignID, sourceType, sourceID, country, os string) (float64, error)
+}
+
 // NewS2SHandler creates a new S2S handler
 func NewS2SHandler(
 	sourceStore SourceStore,
@@ -104,6 +113,12 @@ func NewS2SHandler(
 	}
 }
 
+// SetPayoutQuoter makes the handler price payouts with the payout rule engine
+// instead of the campaign/source defaults
+func (h *S2SHandler) SetPayoutQuoter(q PayoutQuoter) {
+	h.payoutQuoter = q
+}
+
 // AdRequest represents incoming ad request from S2S partner
 type AdRequest struct {
 	SourceName  string
@@ -220,7 +235,7 @@ func (h *S2SHandler) HandleAdRequest(ctx context.Context, r *http.Request) (*AdR
 	viewURL := h.buildViewURL(campaign, creative, source, req, impressionID)
 
 	// Calculate payout
-	payout := h.calculatePayout(source, campaign)
+	payout := h.calculatePayout(ctx, source, campaign, req)
 
 	h.logger.Info("s2s ad served",
 		zap.String("source", source.Name),
@@ -356,7 +371,15 @@ func (h *S2SHandler) buildViewURL(campaign *Campaign, creative *Creative, source
 }
 
 // calculatePayout calculates payout for this impression
-func (h *S2SHandler) calculatePayout(source *S2SSource, campaign *Campaign) float64 {
+func (h *S2SHandler) calculatePayout(ctx context.Context, source *S2SSource, campaign *Campaign, req *AdRequest) float64 {
+	if h.payoutQuoter != nil {
+		payout, err := h.payoutQuoter.QuotePayout(ctx, campaign.ID, "s2s", source.ID, req.Country, req.OS)
+		if err == nil {
+			return payout
+		}
+		h.logger.Warn("failed to quote payout", zap.String("campaign_id", campaign.ID), zap.Error(err))
+	}
+
 	// Campaign-specific payout takes priority
 	if campaign.PayoutAmount > 0 {
 		if source.PayoutType == "percent" {
@@ -1,72 +1,191 @@
 package storage
 
 import (
-    "sync"
-    "github.com/radiusdt/vector-dsp/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
 )
 
-// AdGroupRepo defines operations for storing and retrieving ad groups.
-// An AdGroup belongs to a campaign.
-type AdGroupRepo interface {
-    GetAdGroup(id string) (*models.AdGroup, error)
-    ListAdGroups() ([]*models.AdGroup, error)
-    UpsertAdGroup(g *models.AdGroup) error
-    ListAdGroupsByCampaign(campaignID string) ([]*models.AdGroup, error)
-}
+// =============================================
+// In-memory
+// =============================================
 
-// InMemoryAdGroupRepo is a simple thread-safe in-memory implementation
-// of AdGroupRepo.
+// InMemoryAdGroupRepo provides in-memory storage for ad groups.
 type InMemoryAdGroupRepo struct {
-    mu      sync.RWMutex
-    groups  map[string]*models.AdGroup
+	mu     sync.RWMutex
+	groups map[string]*models.AdGroup
 }
 
-// NewInMemoryAdGroupRepo creates an empty in-memory ad group repo.
+// NewInMemoryAdGroupRepo creates a new in-memory ad group repository.
 func NewInMemoryAdGroupRepo() *InMemoryAdGroupRepo {
-    return &InMemoryAdGroupRepo{
-        groups: make(map[string]*models.AdGroup),
-    }
-}
-
-// GetAdGroup returns an ad group by ID.
-func (r *InMemoryAdGroupRepo) GetAdGroup(id string) (*models.AdGroup, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    if g, ok := r.groups[id]; ok {
-        return g, nil
-    }
-    return nil, nil
-}
-
-// ListAdGroups returns all ad groups.
-func (r *InMemoryAdGroupRepo) ListAdGroups() ([]*models.AdGroup, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    res := make([]*models.AdGroup, 0, len(r.groups))
-    for _, g := range r.groups {
-        res = append(res, g)
-    }
-    return res, nil
-}
-
-// UpsertAdGroup inserts or updates the given ad group.
-func (r *InMemoryAdGroupRepo) UpsertAdGroup(g *models.AdGroup) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-    cp := *g
-    r.groups[g.ID] = &cp
-    return nil
-}
-
-// ListAdGroupsByCampaign returns all ad groups for a given campaign.
-func (r *InMemoryAdGroupRepo) ListAdGroupsByCampaign(campaignID string) ([]*models.AdGroup, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    var res []*models.AdGroup
-    for _, g := range r.groups {
-        if g.CampaignID == campaignID {
-            res = append(res, g)
-        }
-    }
-    return res, nil
-}
\ No newline at end of file
+	return &InMemoryAdGroupRepo{
+		groups: make(map[string]*models.AdGroup),
+	}
+}
+
+func (r *InMemoryAdGroupRepo) ListAll(ctx context.Context) ([]*models.AdGroup, error) {
+	return r.list(func(g *models.AdGroup) bool { return true })
+}
+
+func (r *InMemoryAdGroupRepo) ListByCampaign(ctx context.Context, campaignID string) ([]*models.AdGroup, error) {
+	return r.list(func(g *models.AdGroup) bool { return g.CampaignID == campaignID })
+}
+
+func (r *InMemoryAdGroupRepo) GetByID(ctx context.Context, id string) (*models.AdGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	g, ok := r.groups[id]
+	if !ok {
+		return nil, nil
+	}
+	var saved models.AdGroup
+	if err := copyJSON(&saved, g); err != nil {
+		return nil, fmt.Errorf("failed to copy ad group: %w", err)
+	}
+	return &saved, nil
+}
+
+func (r *InMemoryAdGroupRepo) Upsert(ctx context.Context, ag *models.AdGroup) error {
+	var saved models.AdGroup
+	if err := copyJSON(&saved, ag); err != nil {
+		return fmt.Errorf("failed to copy ad group: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.groups[ag.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryAdGroupRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.groups, id)
+	return nil
+}
+
+// list returns deep copies of the ad groups matching keep, ordered by ID.
+func (r *InMemoryAdGroupRepo) list(keep func(g *models.AdGroup) bool) ([]*models.AdGroup, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.AdGroup, 0)
+	for _, g := range r.groups {
+		if keep(g) {
+			var saved models.AdGroup
+			if err := copyJSON(&saved, g); err != nil {
+				return nil, fmt.Errorf("failed to copy ad group: %w", err)
+			}
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// =============================================
+// PostgreSQL
+// =============================================
+
+// PostgresAdGroupRepo implements AdGroupRepo on the ad_groups table. The ad
+// group is stored in the data column.
+type PostgresAdGroupRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAdGroupRepo creates a new PostgreSQL ad group repository.
+func NewPostgresAdGroupRepo(pool *pgxpool.Pool) *PostgresAdGroupRepo {
+	return &PostgresAdGroupRepo{pool: pool}
+}
+
+func (r *PostgresAdGroupRepo) ListAll(ctx context.Context) ([]*models.AdGroup, error) {
+	return r.query(ctx, `SELECT data FROM ad_groups ORDER BY id`)
+}
+
+func (r *PostgresAdGroupRepo) ListByCampaign(ctx context.Context, campaignID string) ([]*models.AdGroup, error) {
+	return r.query(ctx, `SELECT data FROM ad_groups WHERE campaign_id = $1 ORDER BY id`, campaignID)
+}
+
+func (r *PostgresAdGroupRepo) GetByID(ctx context.Context, id string) (*models.AdGroup, error) {
+	var data []byte
+	err := r.pool.QueryRow(ctx, `SELECT data FROM ad_groups WHERE id = $1`, id).Scan(&data)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ad group: %w", err)
+	}
+
+	var ag models.AdGroup
+	if err := json.Unmarshal(data, &ag); err != nil {
+		return nil, fmt.Errorf("failed to decode ad group %s: %w", id, err)
+	}
+	return &ag, nil
+}
+
+func (r *PostgresAdGroupRepo) Upsert(ctx context.Context, ag *models.AdGroup) error {
+	data, err := json.Marshal(ag)
+	if err != nil {
+		return fmt.Errorf("failed to encode ad group: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO ad_groups (
+			id, campaign_id, name, budget, start_at, end_at, is_active, created_at, updated_at, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			campaign_id = EXCLUDED.campaign_id,
+			name = EXCLUDED.name,
+			budget = EXCLUDED.budget,
+			start_at = EXCLUDED.start_at,
+			end_at = EXCLUDED.end_at,
+			is_active = EXCLUDED.is_active,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at,
+			data = EXCLUDED.data
+	`, ag.ID, ag.CampaignID, ag.Name, ag.Budget, nullTime(ag.StartAt), nullTime(ag.EndAt), ag.IsActive,
+		ag.CreatedAt, ag.UpdatedAt, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to upsert ad group: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAdGroupRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM ad_groups WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete ad group: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAdGroupRepo) query(ctx context.Context, sql string, args ...interface{}) ([]*models.AdGroup, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ad groups: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.AdGroup, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan ad group: %w", err)
+		}
+		var ag models.AdGroup
+		if err := json.Unmarshal(data, &ag); err != nil {
+			return nil, fmt.Errorf("failed to decode ad group: %w", err)
+		}
+		result = append(result, &ag)
+	}
+	return result, rows.Err()
+}
@@ -1,59 +1,234 @@
 package storage
 
 import (
-    "sync"
-    "github.com/radiusdt/vector-dsp/internal/models"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
 )
 
-// AdvertiserRepo defines operations for storing and retrieving advertisers.
-// Implementations may be in-memory, database-backed, etc.
-type AdvertiserRepo interface {
-    GetAdvertiser(id string) (*models.Advertiser, error)
-    ListAdvertisers() ([]*models.Advertiser, error)
-    UpsertAdvertiser(a *models.Advertiser) error
-}
+// =============================================
+// In-memory
+// =============================================
 
-// InMemoryAdvertiserRepo is a simple thread-safe in-memory implementation
-// of AdvertiserRepo.  It is intended for demonstration and testing; replace
-// with a persistent store in production.
+// InMemoryAdvertiserRepo provides in-memory storage for advertisers. It is
+// also the BalanceStore of InMemoryBillingRepo.
 type InMemoryAdvertiserRepo struct {
-    mu         sync.RWMutex
-    advertisers map[string]*models.Advertiser
+	mu          sync.RWMutex
+	advertisers map[string]*models.Advertiser
 }
 
-// NewInMemoryAdvertiserRepo creates an empty in-memory advertiser repo.
+// NewInMemoryAdvertiserRepo creates a new in-memory advertiser repository.
 func NewInMemoryAdvertiserRepo() *InMemoryAdvertiserRepo {
-    return &InMemoryAdvertiserRepo{
-        advertisers: make(map[string]*models.Advertiser),
-    }
-}
-
-// GetAdvertiser returns the advertiser with the given ID or nil if not found.
-func (r *InMemoryAdvertiserRepo) GetAdvertiser(id string) (*models.Advertiser, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    if a, ok := r.advertisers[id]; ok {
-        return a, nil
-    }
-    return nil, nil
-}
-
-// ListAdvertisers returns a slice of all advertisers.
-func (r *InMemoryAdvertiserRepo) ListAdvertisers() ([]*models.Advertiser, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    res := make([]*models.Advertiser, 0, len(r.advertisers))
-    for _, a := range r.advertisers {
-        res = append(res, a)
-    }
-    return res, nil
-}
-
-// UpsertAdvertiser inserts or updates the given advertiser.
-func (r *InMemoryAdvertiserRepo) UpsertAdvertiser(a *models.Advertiser) error {
-    r.mu.Lock()
-    defer r.mu.Unlock()
-    cp := *a
-    r.advertisers[a.ID] = &cp
-    return nil
-}
\ No newline at end of file
+	return &InMemoryAdvertiserRepo{
+		advertisers: make(map[string]*models.Advertiser),
+	}
+}
+
+func (r *InMemoryAdvertiserRepo) ListAll(ctx context.Context) ([]*models.Advertiser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.Advertiser, 0, len(r.advertisers))
+	for _, a := range r.advertisers {
+		saved := *a
+		result = append(result, &saved)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (r *InMemoryAdvertiserRepo) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.advertisers[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *a
+	return &saved, nil
+}
+
+// Upsert saves a and sets a.Balance to the stored balance.
+func (r *InMemoryAdvertiserRepo) Upsert(ctx context.Context, a *models.Advertiser) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a.Balance = 0
+	if existing, ok := r.advertisers[a.ID]; ok {
+		a.Balance = existing.Balance
+	}
+	saved := *a
+	r.advertisers[a.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryAdvertiserRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.advertisers, id)
+	return nil
+}
+
+func (r *InMemoryAdvertiserRepo) UpdateBalance(ctx context.Context, id string, delta float64) (float64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	a, ok := r.advertisers[id]
+	if !ok {
+		return 0, ErrNotFound
+	}
+	saved := *a
+	saved.Balance += delta
+	r.advertisers[id] = &saved
+	return saved.Balance, nil
+}
+
+// =============================================
+// PostgreSQL
+// =============================================
+
+// PostgresAdvertiserRepo implements AdvertiserRepo on the advertisers
+// table. The balance is only written by UpdateBalance and the billing
+// ledger.
+type PostgresAdvertiserRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAdvertiserRepo creates a new PostgreSQL advertiser repository.
+func NewPostgresAdvertiserRepo(pool *pgxpool.Pool) *PostgresAdvertiserRepo {
+	return &PostgresAdvertiserRepo{pool: pool}
+}
+
+// advertiserColumns are selected by scanAdvertiser.
+const advertiserColumns = `id, name,
+	COALESCE(legal_name, ''), COALESCE(tax_id, ''), COALESCE(kpp, ''), COALESCE(ogrn, ''), COALESCE(address, ''),
+	COALESCE(website, ''), COALESCE(industry, ''),
+	COALESCE(balance, 0)::float8, COALESCE(credit_limit, 0)::float8, COALESCE(currency, ''), COALESCE(timezone, ''),
+	COALESCE(bik, ''), COALESCE(account_number, ''), COALESCE(bank_name, ''),
+	COALESCE(contract_number, ''), contract_date, COALESCE(account_manager, ''),
+	COALESCE(status, ''), created_at, updated_at`
+
+func scanAdvertiser(row pgx.Row) (*models.Advertiser, error) {
+	var a models.Advertiser
+	var createdAt, updatedAt *time.Time
+	err := row.Scan(&a.ID, &a.Name,
+		&a.LegalName, &a.TaxID, &a.KPP, &a.OGRN, &a.Address,
+		&a.Website, &a.Industry,
+		&a.Balance, &a.CreditLimit, &a.Currency, &a.Timezone,
+		&a.BIK, &a.AccountNumber, &a.BankName,
+		&a.ContractNumber, &a.ContractDate, &a.AccountManager,
+		&a.Status, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if createdAt != nil {
+		a.CreatedAt = *createdAt
+	}
+	if updatedAt != nil {
+		a.UpdatedAt = *updatedAt
+	}
+	return &a, nil
+}
+
+func (r *PostgresAdvertiserRepo) ListAll(ctx context.Context) ([]*models.Advertiser, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+advertiserColumns+` FROM advertisers ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list advertisers: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Advertiser, 0)
+	for rows.Next() {
+		a, err := scanAdvertiser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan advertiser: %w", err)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresAdvertiserRepo) GetByID(ctx context.Context, id string) (*models.Advertiser, error) {
+	a, err := scanAdvertiser(r.pool.QueryRow(ctx, `SELECT `+advertiserColumns+` FROM advertisers WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	return a, nil
+}
+
+// Upsert saves a and sets a.Balance to the stored balance.
+func (r *PostgresAdvertiserRepo) Upsert(ctx context.Context, a *models.Advertiser) error {
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO advertisers (
+			id, name, legal_name, tax_id, kpp, ogrn, address, website, industry,
+			credit_limit, currency, timezone, bik, account_number, bank_name,
+			contract_number, contract_date, account_manager, status, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			legal_name = EXCLUDED.legal_name,
+			tax_id = EXCLUDED.tax_id,
+			kpp = EXCLUDED.kpp,
+			ogrn = EXCLUDED.ogrn,
+			address = EXCLUDED.address,
+			website = EXCLUDED.website,
+			industry = EXCLUDED.industry,
+			credit_limit = EXCLUDED.credit_limit,
+			currency = EXCLUDED.currency,
+			timezone = EXCLUDED.timezone,
+			bik = EXCLUDED.bik,
+			account_number = EXCLUDED.account_number,
+			bank_name = EXCLUDED.bank_name,
+			contract_number = EXCLUDED.contract_number,
+			contract_date = EXCLUDED.contract_date,
+			account_manager = EXCLUDED.account_manager,
+			status = EXCLUDED.status,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING COALESCE(balance, 0)::float8
+	`, a.ID, a.Name, nullString(a.LegalName), nullString(a.TaxID), nullString(a.KPP), nullString(a.OGRN),
+		nullString(a.Address), nullString(a.Website), nullString(a.Industry),
+		a.CreditLimit, nullString(a.Currency), nullString(a.Timezone),
+		nullString(a.BIK), nullString(a.AccountNumber), nullString(a.BankName),
+		nullString(a.ContractNumber), a.ContractDate, nullString(a.AccountManager), nullString(a.Status),
+		nullTime(a.CreatedAt), nullTime(a.UpdatedAt)).Scan(&a.Balance)
+	if err != nil {
+		return fmt.Errorf("failed to upsert advertiser: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAdvertiserRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM advertisers WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete advertiser: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAdvertiserRepo) UpdateBalance(ctx context.Context, id string, delta float64) (float64, error) {
+	var balance float64
+	err := r.pool.QueryRow(ctx, `
+		UPDATE advertisers SET balance = COALESCE(balance, 0) + $2
+		WHERE id = $1
+		RETURNING balance::float8
+	`, id, delta).Scan(&balance)
+	if err == pgx.ErrNoRows {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update balance: %w", err)
+	}
+	return balance, nil
+}
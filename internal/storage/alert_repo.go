@@ -0,0 +1,376 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// matches reports whether a passes the filter.
+func (filter *AlertFilter) matches(a *models.Alert) bool {
+	if filter.DefinitionID != "" && a.DefinitionID != filter.DefinitionID {
+		return false
+	}
+	if filter.Type != "" && a.Type != filter.Type {
+		return false
+	}
+	if filter.Status != "" && a.Status != filter.Status {
+		return false
+	}
+	if filter.AdvertiserID != "" && a.AdvertiserID != filter.AdvertiserID {
+		return false
+	}
+	if filter.CampaignID != "" && a.CampaignID != filter.CampaignID {
+		return false
+	}
+	if !filter.From.IsZero() && a.FirstSeenAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !a.FirstSeenAt.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+// InMemoryAlertRepo provides in-memory storage for alert definitions and
+// alerts.
+type InMemoryAlertRepo struct {
+	mu          sync.RWMutex
+	definitions map[string]*models.AlertDefinition
+	alerts      map[string]*models.Alert
+}
+
+// NewInMemoryAlertRepo creates a new in-memory alert repository.
+func NewInMemoryAlertRepo() *InMemoryAlertRepo {
+	return &InMemoryAlertRepo{
+		definitions: make(map[string]*models.AlertDefinition),
+		alerts:      make(map[string]*models.Alert),
+	}
+}
+
+func (r *InMemoryAlertRepo) ListDefinitions(ctx context.Context, advertiserID string) ([]*models.AlertDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.AlertDefinition, 0, len(r.definitions))
+	for _, d := range r.definitions {
+		if advertiserID == "" || d.AdvertiserID == advertiserID {
+			saved := *d
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (r *InMemoryAlertRepo) GetDefinition(ctx context.Context, id string) (*models.AlertDefinition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.definitions[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *d
+	return &saved, nil
+}
+
+func (r *InMemoryAlertRepo) UpsertDefinition(ctx context.Context, d *models.AlertDefinition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.definitions[d.ID]; ok {
+		d.CreatedAt = existing.CreatedAt
+	} else if d.CreatedAt.IsZero() {
+		d.CreatedAt = now
+	}
+	d.UpdatedAt = now
+
+	saved := *d
+	r.definitions[d.ID] = &saved
+	return nil
+}
+
+// DeleteDefinition removes the definition. Its alerts are kept.
+func (r *InMemoryAlertRepo) DeleteDefinition(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.definitions, id)
+	return nil
+}
+
+func (r *InMemoryAlertRepo) ListAlerts(ctx context.Context, filter AlertFilter) ([]*models.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.Alert, 0)
+	for _, a := range r.alerts {
+		if filter.matches(a) {
+			saved := *a
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].FirstSeenAt.Equal(result[j].FirstSeenAt) {
+			return result[i].FirstSeenAt.After(result[j].FirstSeenAt)
+		}
+		return result[i].ID > result[j].ID
+	})
+	if filter.Limit > 0 && len(result) > filter.Limit {
+		result = result[:filter.Limit]
+	}
+	return result, nil
+}
+
+func (r *InMemoryAlertRepo) GetAlert(ctx context.Context, id string) (*models.Alert, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, ok := r.alerts[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *a
+	return &saved, nil
+}
+
+func (r *InMemoryAlertRepo) UpsertAlert(ctx context.Context, a *models.Alert) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *a
+	r.alerts[a.ID] = &saved
+	return nil
+}
+
+// PostgresAlertRepo implements AlertRepo on the alert_definitions and
+// alerts tables. A definition's campaigns and channels are kept as JSONB.
+type PostgresAlertRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAlertRepo creates a new PostgreSQL-backed alert repository.
+func NewPostgresAlertRepo(pool *pgxpool.Pool) *PostgresAlertRepo {
+	return &PostgresAlertRepo{pool: pool}
+}
+
+const alertDefinitionColumns = `id, name, type, enabled, built_in, advertiser_id, campaign_ids, threshold,
+	window_hours, min_volume, channels, renotify_hours, snoozed_until, created_at, updated_at`
+
+func scanAlertDefinition(row pgx.Row) (*models.AlertDefinition, error) {
+	var d models.AlertDefinition
+	var campaignIDs, channels []byte
+	err := row.Scan(&d.ID, &d.Name, &d.Type, &d.Enabled, &d.BuiltIn, &d.AdvertiserID, &campaignIDs,
+		&d.Threshold, &d.WindowHours, &d.MinVolume, &channels, &d.RenotifyHours, &d.SnoozedUntil,
+		&d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(campaignIDs, &d.CampaignIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode alert definition %s: %w", d.ID, err)
+	}
+	if err := json.Unmarshal(channels, &d.Channels); err != nil {
+		return nil, fmt.Errorf("failed to decode alert definition %s: %w", d.ID, err)
+	}
+	return &d, nil
+}
+
+func (r *PostgresAlertRepo) ListDefinitions(ctx context.Context, advertiserID string) ([]*models.AlertDefinition, error) {
+	query := `SELECT ` + alertDefinitionColumns + ` FROM alert_definitions`
+	args := []interface{}{}
+	if advertiserID != "" {
+		query += ` WHERE advertiser_id = $1`
+		args = append(args, advertiserID)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alert definitions: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.AlertDefinition, 0)
+	for rows.Next() {
+		d, err := scanAlertDefinition(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert definition: %w", err)
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresAlertRepo) GetDefinition(ctx context.Context, id string) (*models.AlertDefinition, error) {
+	d, err := scanAlertDefinition(r.pool.QueryRow(ctx,
+		`SELECT `+alertDefinitionColumns+` FROM alert_definitions WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert definition: %w", err)
+	}
+	return d, nil
+}
+
+func (r *PostgresAlertRepo) UpsertDefinition(ctx context.Context, d *models.AlertDefinition) error {
+	campaignIDs, err := json.Marshal(d.CampaignIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert definition: %w", err)
+	}
+	channels, err := json.Marshal(d.Channels)
+	if err != nil {
+		return fmt.Errorf("failed to encode alert definition: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = now
+	}
+	d.UpdatedAt = now
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO alert_definitions (`+alertDefinitionColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			type = EXCLUDED.type,
+			enabled = EXCLUDED.enabled,
+			built_in = EXCLUDED.built_in,
+			advertiser_id = EXCLUDED.advertiser_id,
+			campaign_ids = EXCLUDED.campaign_ids,
+			threshold = EXCLUDED.threshold,
+			window_hours = EXCLUDED.window_hours,
+			min_volume = EXCLUDED.min_volume,
+			channels = EXCLUDED.channels,
+			renotify_hours = EXCLUDED.renotify_hours,
+			snoozed_until = EXCLUDED.snoozed_until,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at
+	`, d.ID, d.Name, d.Type, d.Enabled, d.BuiltIn, d.AdvertiserID, string(campaignIDs), d.Threshold,
+		d.WindowHours, d.MinVolume, string(channels), d.RenotifyHours, d.SnoozedUntil, d.CreatedAt, d.UpdatedAt).Scan(&d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert alert definition: %w", err)
+	}
+	return nil
+}
+
+// DeleteDefinition removes the definition. Its alerts are kept.
+func (r *PostgresAlertRepo) DeleteDefinition(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM alert_definitions WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete alert definition: %w", err)
+	}
+	return nil
+}
+
+const alertColumns = `id, definition_id, definition_name, type, key, status, advertiser_id, campaign_id,
+	line_item_id, source_id, mmp, message, value, threshold, checks, first_seen_at, last_seen_at,
+	resolved_at, notified_at, snoozed_until`
+
+func scanAlert(row pgx.Row) (*models.Alert, error) {
+	var a models.Alert
+	err := row.Scan(&a.ID, &a.DefinitionID, &a.DefinitionName, &a.Type, &a.Key, &a.Status, &a.AdvertiserID,
+		&a.CampaignID, &a.LineItemID, &a.SourceID, &a.MMP, &a.Message, &a.Value, &a.Threshold, &a.Checks,
+		&a.FirstSeenAt, &a.LastSeenAt, &a.ResolvedAt, &a.NotifiedAt, &a.SnoozedUntil)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *PostgresAlertRepo) ListAlerts(ctx context.Context, filter AlertFilter) ([]*models.Alert, error) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.DefinitionID != "" {
+		add("definition_id = $%d", filter.DefinitionID)
+	}
+	if filter.Type != "" {
+		add("type = $%d", filter.Type)
+	}
+	if filter.Status != "" {
+		add("status = $%d", filter.Status)
+	}
+	if filter.AdvertiserID != "" {
+		add("advertiser_id = $%d", filter.AdvertiserID)
+	}
+	if filter.CampaignID != "" {
+		add("campaign_id = $%d", filter.CampaignID)
+	}
+	if !filter.From.IsZero() {
+		add("first_seen_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("first_seen_at < $%d", filter.To)
+	}
+	query := `SELECT ` + alertColumns + ` FROM alerts
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY first_seen_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list alerts: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Alert, 0)
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		result = append(result, a)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresAlertRepo) GetAlert(ctx context.Context, id string) (*models.Alert, error) {
+	a, err := scanAlert(r.pool.QueryRow(ctx, `SELECT `+alertColumns+` FROM alerts WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alert: %w", err)
+	}
+	return a, nil
+}
+
+func (r *PostgresAlertRepo) UpsertAlert(ctx context.Context, a *models.Alert) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO alerts (`+alertColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)
+		ON CONFLICT (id) DO UPDATE SET
+			definition_name = EXCLUDED.definition_name,
+			status = EXCLUDED.status,
+			message = EXCLUDED.message,
+			value = EXCLUDED.value,
+			threshold = EXCLUDED.threshold,
+			checks = EXCLUDED.checks,
+			last_seen_at = EXCLUDED.last_seen_at,
+			resolved_at = EXCLUDED.resolved_at,
+			notified_at = EXCLUDED.notified_at,
+			snoozed_until = EXCLUDED.snoozed_until
+	`, a.ID, a.DefinitionID, a.DefinitionName, a.Type, a.Key, a.Status, a.AdvertiserID, a.CampaignID,
+		a.LineItemID, a.SourceID, a.MMP, a.Message, a.Value, a.Threshold, a.Checks, a.FirstSeenAt, a.LastSeenAt,
+		a.ResolvedAt, a.NotifiedAt, a.SnoozedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to upsert alert: %w", err)
+	}
+	return nil
+}
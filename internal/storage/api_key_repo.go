@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// InMemoryAPIKeyRepo provides in-memory storage for API keys.
+type InMemoryAPIKeyRepo struct {
+	mu   sync.RWMutex
+	keys map[string]*models.APIKey // id -> key
+}
+
+// NewInMemoryAPIKeyRepo creates a new in-memory API key repository.
+func NewInMemoryAPIKeyRepo() *InMemoryAPIKeyRepo {
+	return &InMemoryAPIKeyRepo{keys: make(map[string]*models.APIKey)}
+}
+
+func (r *InMemoryAPIKeyRepo) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, k := range r.keys {
+		if k.KeyHash == keyHash {
+			saved := *k
+			return &saved, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryAPIKeyRepo) Get(ctx context.Context, id string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *k
+	return &saved, nil
+}
+
+func (r *InMemoryAPIKeyRepo) List(ctx context.Context, advertiserID string) ([]*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.APIKey, 0)
+	for _, k := range r.keys {
+		if advertiserID == "" || k.AdvertiserID == advertiserID {
+			saved := *k
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return result, nil
+}
+
+func (r *InMemoryAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, k := range r.keys {
+		if k.KeyHash == key.KeyHash {
+			return fmt.Errorf("api key hash already exists")
+		}
+	}
+	saved := *key
+	r.keys[key.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryAPIKeyRepo) UpdateStatus(ctx context.Context, id, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k, ok := r.keys[id]
+	if !ok {
+		return fmt.Errorf("api key not found: %s", id)
+	}
+	k.Status = status
+	return nil
+}
+
+func (r *InMemoryAPIKeyRepo) TouchLastUsed(ctx context.Context, used map[string]time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, t := range used {
+		if k, ok := r.keys[id]; ok {
+			t := t
+			k.LastUsedAt = &t
+		}
+	}
+	return nil
+}
+
+// PostgresAPIKeyRepo implements APIKeyRepo on the api_keys table.
+type PostgresAPIKeyRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAPIKeyRepo creates a new PostgreSQL-backed API key repository.
+func NewPostgresAPIKeyRepo(pool *pgxpool.Pool) *PostgresAPIKeyRepo {
+	return &PostgresAPIKeyRepo{pool: pool}
+}
+
+const apiKeyColumns = `id, name, key_hash, COALESCE(advertiser_id, ''), COALESCE(permissions, '{}'),
+	COALESCE(rate_limit, 0), COALESCE(status, 'active'), last_used_at, expires_at, created_at`
+
+func scanAPIKey(row pgx.Row) (*models.APIKey, error) {
+	var k models.APIKey
+	err := row.Scan(&k.ID, &k.Name, &k.KeyHash, &k.AdvertiserID, &k.Permissions,
+		&k.RateLimit, &k.Status, &k.LastUsedAt, &k.ExpiresAt, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+func (r *PostgresAPIKeyRepo) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1
+	`, keyHash))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return k, nil
+}
+
+func (r *PostgresAPIKeyRepo) Get(ctx context.Context, id string) (*models.APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys WHERE id = $1
+	`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	return k, nil
+}
+
+func (r *PostgresAPIKeyRepo) List(ctx context.Context, advertiserID string) ([]*models.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+apiKeyColumns+` FROM api_keys
+		WHERE $1 = '' OR advertiser_id = $1
+		ORDER BY created_at
+	`, advertiserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.APIKey, 0)
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		result = append(result, k)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresAPIKeyRepo) Create(ctx context.Context, key *models.APIKey) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO api_keys (id, name, key_hash, advertiser_id, permissions, rate_limit, status, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, key.ID, key.Name, key.KeyHash, nullString(key.AdvertiserID), key.Permissions, key.RateLimit, key.Status,
+		key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepo) UpdateStatus(ctx context.Context, id, status string) error {
+	tag, err := r.pool.Exec(ctx, `UPDATE api_keys SET status = $2 WHERE id = $1`, id, status)
+	if err != nil {
+		return fmt.Errorf("failed to update api key status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("api key not found: %s", id)
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepo) TouchLastUsed(ctx context.Context, used map[string]time.Time) error {
+	batch := &pgx.Batch{}
+	for id, t := range used {
+		batch.Queue(`UPDATE api_keys SET last_used_at = GREATEST(COALESCE(last_used_at, $2), $2) WHERE id = $1`, id, t)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	if err := r.pool.SendBatch(ctx, batch).Close(); err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// matches reports whether e passes the filter.
+func (f *AuditFilter) matches(e *models.AuditEntry) bool {
+	if f.EntityType != "" && e.EntityType != f.EntityType {
+		return false
+	}
+	if f.EntityID != "" && e.EntityID != f.EntityID {
+		return false
+	}
+	if f.ActorID != "" && e.ActorID != f.ActorID {
+		return false
+	}
+	if f.Action != "" && e.Action != f.Action {
+		return false
+	}
+	if !f.From.IsZero() && e.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// InMemoryAuditRepo provides in-memory storage for the audit log.
+type InMemoryAuditRepo struct {
+	mu      sync.RWMutex
+	entries []*models.AuditEntry // In append order
+}
+
+// NewInMemoryAuditRepo creates a new in-memory audit repository.
+func NewInMemoryAuditRepo() *InMemoryAuditRepo {
+	return &InMemoryAuditRepo{}
+}
+
+func (r *InMemoryAuditRepo) Append(ctx context.Context, e *models.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *e
+	r.entries = append(r.entries, &saved)
+	return nil
+}
+
+func (r *InMemoryAuditRepo) Get(ctx context.Context, id string) (*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, e := range r.entries {
+		if e.ID == id {
+			saved := *e
+			return &saved, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryAuditRepo) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.AuditEntry, 0)
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		if !filter.matches(r.entries[i]) {
+			continue
+		}
+		saved := *r.entries[i]
+		result = append(result, &saved)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// PostgresAuditRepo implements AuditRepo on the audit_log table.
+type PostgresAuditRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAuditRepo creates a new PostgreSQL-backed audit repository.
+func NewPostgresAuditRepo(pool *pgxpool.Pool) *PostgresAuditRepo {
+	return &PostgresAuditRepo{pool: pool}
+}
+
+const auditColumns = `id, entity_type, entity_id, action, actor_kind, actor_id, actor_name,
+	before, after, diff, rollback_of, created_at`
+
+func scanAuditEntry(row pgx.Row) (*models.AuditEntry, error) {
+	var e models.AuditEntry
+	var before, after, diff []byte
+	err := row.Scan(&e.ID, &e.EntityType, &e.EntityID, &e.Action, &e.ActorKind, &e.ActorID, &e.ActorName,
+		&before, &after, &diff, &e.RollbackOf, &e.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	e.Before = before
+	e.After = after
+	if err := json.Unmarshal(diff, &e.Diff); err != nil {
+		return nil, fmt.Errorf("failed to decode diff: %w", err)
+	}
+	return &e, nil
+}
+
+func (r *PostgresAuditRepo) Append(ctx context.Context, e *models.AuditEntry) error {
+	diff, err := json.Marshal(e.Diff)
+	if err != nil {
+		return fmt.Errorf("failed to encode diff: %w", err)
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO audit_log (
+			id, entity_type, entity_id, action, actor_kind, actor_id, actor_name,
+			before, after, diff, rollback_of, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`, e.ID, e.EntityType, e.EntityID, e.Action, e.ActorKind, e.ActorID, e.ActorName,
+		nullJSON(e.Before), nullJSON(e.After), string(diff), e.RollbackOf, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAuditRepo) Get(ctx context.Context, id string) (*models.AuditEntry, error) {
+	e, err := scanAuditEntry(r.pool.QueryRow(ctx, `SELECT `+auditColumns+` FROM audit_log WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit entry: %w", err)
+	}
+	return e, nil
+}
+
+func (r *PostgresAuditRepo) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.EntityType != "" {
+		add("entity_type = $%d", filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		add("entity_id = $%d", filter.EntityID)
+	}
+	if filter.ActorID != "" {
+		add("actor_id = $%d", filter.ActorID)
+	}
+	if filter.Action != "" {
+		add("action = $%d", filter.Action)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < $%d", filter.To)
+	}
+	query := `SELECT ` + auditColumns + ` FROM audit_log
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY seq DESC`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.AuditEntry, 0)
+	for rows.Next() {
+		e, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// nullJSON returns a JSON document as text for a JSONB column, or nil for
+// SQL NULL.
+func nullJSON(b json.RawMessage) interface{} {
+	if len(b) == 0 || string(b) == "null" {
+		return nil
+	}
+	return string(b)
+}
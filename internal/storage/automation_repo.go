@@ -0,0 +1,343 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// matches reports whether f passes the filter.
+func (filter *RuleFiringFilter) matches(f *models.RuleFiring) bool {
+	if filter.RuleID != "" && f.RuleID != filter.RuleID {
+		return false
+	}
+	if filter.AdvertiserID != "" && f.AdvertiserID != filter.AdvertiserID {
+		return false
+	}
+	if filter.CampaignID != "" && f.CampaignID != filter.CampaignID {
+		return false
+	}
+	if filter.LineItemID != "" && f.LineItemID != filter.LineItemID {
+		return false
+	}
+	if filter.ScopeValue != "" && f.ScopeValue != filter.ScopeValue {
+		return false
+	}
+	if !filter.From.IsZero() && f.CreatedAt.Before(filter.From) {
+		return false
+	}
+	if !filter.To.IsZero() && !f.CreatedAt.Before(filter.To) {
+		return false
+	}
+	return true
+}
+
+// InMemoryAutomationRuleRepo provides in-memory storage for automation
+// rules and their firings.
+type InMemoryAutomationRuleRepo struct {
+	mu      sync.RWMutex
+	rules   map[string]*models.AutomationRule
+	firings []*models.RuleFiring // In append order
+}
+
+// NewInMemoryAutomationRuleRepo creates a new in-memory automation rule
+// repository.
+func NewInMemoryAutomationRuleRepo() *InMemoryAutomationRuleRepo {
+	return &InMemoryAutomationRuleRepo{
+		rules: make(map[string]*models.AutomationRule),
+	}
+}
+
+func (r *InMemoryAutomationRuleRepo) List(ctx context.Context, advertiserID string) ([]*models.AutomationRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.AutomationRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		if advertiserID == "" || rule.AdvertiserID == advertiserID {
+			saved := *rule
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (r *InMemoryAutomationRuleRepo) GetByID(ctx context.Context, id string) (*models.AutomationRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *rule
+	return &saved, nil
+}
+
+func (r *InMemoryAutomationRuleRepo) Upsert(ctx context.Context, rule *models.AutomationRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.rules[rule.ID]; ok {
+		rule.CreatedAt = existing.CreatedAt
+	} else if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	saved := *rule
+	r.rules[rule.ID] = &saved
+	return nil
+}
+
+// Delete removes the rule. Its firings stay in the log.
+func (r *InMemoryAutomationRuleRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.rules, id)
+	return nil
+}
+
+func (r *InMemoryAutomationRuleRepo) AppendFiring(ctx context.Context, f *models.RuleFiring) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *f
+	r.firings = append(r.firings, &saved)
+	return nil
+}
+
+func (r *InMemoryAutomationRuleRepo) ListFirings(ctx context.Context, filter RuleFiringFilter) ([]*models.RuleFiring, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.RuleFiring, 0)
+	for i := len(r.firings) - 1; i >= 0; i-- {
+		if !filter.matches(r.firings[i]) {
+			continue
+		}
+		saved := *r.firings[i]
+		result = append(result, &saved)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// PostgresAutomationRuleRepo implements AutomationRuleRepo on the
+// automation_rules and rule_firings tables. A rule's selection, conditions
+// and actions are kept as JSONB.
+type PostgresAutomationRuleRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresAutomationRuleRepo creates a new PostgreSQL-backed automation
+// rule repository.
+func NewPostgresAutomationRuleRepo(pool *pgxpool.Pool) *PostgresAutomationRuleRepo {
+	return &PostgresAutomationRuleRepo{pool: pool}
+}
+
+const automationRuleColumns = `id, name, advertiser_id, enabled, campaign_ids, line_item_ids, scope, scope_values,
+	conditions, lookback_hours, cooldown_hours, actions, created_at, updated_at`
+
+func scanAutomationRule(row pgx.Row) (*models.AutomationRule, error) {
+	var rule models.AutomationRule
+	var campaignIDs, lineItemIDs, scopeValues, conditions, actions []byte
+	err := row.Scan(&rule.ID, &rule.Name, &rule.AdvertiserID, &rule.Enabled, &campaignIDs, &lineItemIDs,
+		&rule.Scope, &scopeValues, &conditions, &rule.LookbackHours, &rule.CooldownHours, &actions,
+		&rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range []struct {
+		data []byte
+		dst  interface{}
+	}{
+		{campaignIDs, &rule.CampaignIDs},
+		{lineItemIDs, &rule.LineItemIDs},
+		{scopeValues, &rule.ScopeValues},
+		{conditions, &rule.Conditions},
+		{actions, &rule.Actions},
+	} {
+		if err := json.Unmarshal(f.data, f.dst); err != nil {
+			return nil, fmt.Errorf("failed to decode rule %s: %w", rule.ID, err)
+		}
+	}
+	return &rule, nil
+}
+
+func (r *PostgresAutomationRuleRepo) List(ctx context.Context, advertiserID string) ([]*models.AutomationRule, error) {
+	query := `SELECT ` + automationRuleColumns + ` FROM automation_rules`
+	args := []interface{}{}
+	if advertiserID != "" {
+		query += ` WHERE advertiser_id = $1`
+		args = append(args, advertiserID)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list automation rules: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.AutomationRule, 0)
+	for rows.Next() {
+		rule, err := scanAutomationRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan automation rule: %w", err)
+		}
+		result = append(result, rule)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresAutomationRuleRepo) GetByID(ctx context.Context, id string) (*models.AutomationRule, error) {
+	rule, err := scanAutomationRule(r.pool.QueryRow(ctx,
+		`SELECT `+automationRuleColumns+` FROM automation_rules WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get automation rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (r *PostgresAutomationRuleRepo) Upsert(ctx context.Context, rule *models.AutomationRule) error {
+	docs := make([]string, 0, 5)
+	for _, v := range []interface{}{rule.CampaignIDs, rule.LineItemIDs, rule.ScopeValues, rule.Conditions, rule.Actions} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("failed to encode automation rule: %w", err)
+		}
+		docs = append(docs, string(b))
+	}
+
+	now := time.Now().UTC()
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO automation_rules (`+automationRuleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			advertiser_id = EXCLUDED.advertiser_id,
+			enabled = EXCLUDED.enabled,
+			campaign_ids = EXCLUDED.campaign_ids,
+			line_item_ids = EXCLUDED.line_item_ids,
+			scope = EXCLUDED.scope,
+			scope_values = EXCLUDED.scope_values,
+			conditions = EXCLUDED.conditions,
+			lookback_hours = EXCLUDED.lookback_hours,
+			cooldown_hours = EXCLUDED.cooldown_hours,
+			actions = EXCLUDED.actions,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at
+	`, rule.ID, rule.Name, rule.AdvertiserID, rule.Enabled, docs[0], docs[1], rule.Scope, docs[2],
+		docs[3], rule.LookbackHours, rule.CooldownHours, docs[4], rule.CreatedAt, rule.UpdatedAt).Scan(&rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert automation rule: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the rule. Its firings stay in the log.
+func (r *PostgresAutomationRuleRepo) Delete(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM automation_rules WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete automation rule: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAutomationRuleRepo) AppendFiring(ctx context.Context, f *models.RuleFiring) error {
+	metrics, err := json.Marshal(f.Metrics)
+	if err != nil {
+		return fmt.Errorf("failed to encode rule firing metrics: %w", err)
+	}
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO rule_firings (
+			id, rule_id, rule_name, advertiser_id, campaign_id, line_item_id, scope, scope_value,
+			action, detail, metrics, dry_run, error, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, f.ID, f.RuleID, f.RuleName, f.AdvertiserID, f.CampaignID, f.LineItemID, f.Scope, f.ScopeValue,
+		f.Action, f.Detail, string(metrics), f.DryRun, f.Error, f.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to append rule firing: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresAutomationRuleRepo) ListFirings(ctx context.Context, filter RuleFiringFilter) ([]*models.RuleFiring, error) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.RuleID != "" {
+		add("rule_id = $%d", filter.RuleID)
+	}
+	if filter.AdvertiserID != "" {
+		add("advertiser_id = $%d", filter.AdvertiserID)
+	}
+	if filter.CampaignID != "" {
+		add("campaign_id = $%d", filter.CampaignID)
+	}
+	if filter.LineItemID != "" {
+		add("line_item_id = $%d", filter.LineItemID)
+	}
+	if filter.ScopeValue != "" {
+		add("scope_value = $%d", filter.ScopeValue)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < $%d", filter.To)
+	}
+	query := `SELECT id, rule_id, rule_name, advertiser_id, campaign_id, line_item_id, scope, scope_value,
+			action, detail, metrics, dry_run, error, created_at
+		FROM rule_firings
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY seq DESC`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rule firings: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.RuleFiring, 0)
+	for rows.Next() {
+		var f models.RuleFiring
+		var metrics []byte
+		if err := rows.Scan(&f.ID, &f.RuleID, &f.RuleName, &f.AdvertiserID, &f.CampaignID, &f.LineItemID,
+			&f.Scope, &f.ScopeValue, &f.Action, &f.Detail, &metrics, &f.DryRun, &f.Error, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan rule firing: %w", err)
+		}
+		if err := json.Unmarshal(metrics, &f.Metrics); err != nil {
+			return nil, fmt.Errorf("failed to decode rule firing metrics: %w", err)
+		}
+		result = append(result, &f)
+	}
+	return result, rows.Err()
+}
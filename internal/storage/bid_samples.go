@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// DefaultBidSampleLimit is the page size when a filter sets no limit.
+const DefaultBidSampleLimit = 100
+
+// InMemoryBidSampleStore keeps the most recent bid samples in a ring buffer.
+type InMemoryBidSampleStore struct {
+	mu      sync.RWMutex
+	samples []*models.BidSample
+	next    int // Ring position of the next write
+	full    bool
+}
+
+// NewInMemoryBidSampleStore creates a store holding up to capacity samples.
+func NewInMemoryBidSampleStore(capacity int) *InMemoryBidSampleStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &InMemoryBidSampleStore{
+		samples: make([]*models.BidSample, capacity),
+	}
+}
+
+func (s *InMemoryBidSampleStore) SaveBidSample(ctx context.Context, sample *models.BidSample) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % len(s.samples)
+	if s.next == 0 {
+		s.full = true
+	}
+	return nil
+}
+
+func (s *InMemoryBidSampleStore) GetBidSample(ctx context.Context, id string) (*models.BidSample, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sample := range s.samples {
+		if sample != nil && sample.ID == id {
+			return sample, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *InMemoryBidSampleStore) ListBidSamples(ctx context.Context, filter BidSampleFilter) ([]*models.BidSample, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultBidSampleLimit
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := s.next
+	if s.full {
+		n = len(s.samples)
+	}
+
+	// Walk backwards from the newest sample
+	result := make([]*models.BidSample, 0)
+	for i := 1; i <= n && len(result) < limit; i++ {
+		sample := s.samples[(s.next-i+len(s.samples))%len(s.samples)]
+		if matchesBidSampleFilter(sample, filter) {
+			result = append(result, sample)
+		}
+	}
+	return result, nil
+}
+
+func matchesBidSampleFilter(sample *models.BidSample, filter BidSampleFilter) bool {
+	if filter.SourceID != "" && sample.SourceID != filter.SourceID {
+		return false
+	}
+	if filter.NoBidOnly && sample.Response != nil {
+		return false
+	}
+	if !filter.Since.IsZero() && sample.Timestamp.Before(filter.Since) {
+		return false
+	}
+	if filter.CampaignID == "" && filter.LineItemID == "" {
+		return true
+	}
+	for _, d := range sample.Trail {
+		if (filter.CampaignID == "" || d.CampaignID == filter.CampaignID) &&
+			(filter.LineItemID == "" || d.LineItemID == filter.LineItemID) {
+			return true
+		}
+	}
+	return false
+}
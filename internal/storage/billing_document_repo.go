@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// matches reports whether doc passes the filter.
+func (f *DocumentFilter) matches(doc *models.BillingDocument) bool {
+	return (f.AdvertiserID == "" || doc.AdvertiserID == f.AdvertiserID) &&
+		(f.Period == "" || doc.Period == f.Period) &&
+		(f.Type == "" || doc.Type == f.Type)
+}
+
+// documentOrder is the position of each type within an advertiser's period.
+var documentOrder = map[models.DocumentType]int{
+	models.DocumentInvoice:    0,
+	models.DocumentAct:        1,
+	models.DocumentVATInvoice: 2,
+}
+
+// InMemoryBillingDocumentRepo provides in-memory storage for closing
+// documents.
+type InMemoryBillingDocumentRepo struct {
+	mu        sync.RWMutex
+	documents map[string]*models.BillingDocument
+	sequences map[string]int64 // type/year -> last number
+	closed    map[string]*models.ClosingPeriod
+}
+
+// NewInMemoryBillingDocumentRepo creates a new in-memory billing document repository.
+func NewInMemoryBillingDocumentRepo() *InMemoryBillingDocumentRepo {
+	return &InMemoryBillingDocumentRepo{
+		documents: make(map[string]*models.BillingDocument),
+		sequences: make(map[string]int64),
+		closed:    make(map[string]*models.ClosingPeriod),
+	}
+}
+
+func (r *InMemoryBillingDocumentRepo) ListDocuments(ctx context.Context, filter DocumentFilter) ([]*models.BillingDocument, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.BillingDocument, 0)
+	for _, doc := range r.documents {
+		if filter.matches(doc) {
+			result = append(result, doc)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.AdvertiserID != b.AdvertiserID {
+			return a.AdvertiserID < b.AdvertiserID
+		}
+		return documentOrder[a.Type] < documentOrder[b.Type]
+	})
+	return result, nil
+}
+
+func (r *InMemoryBillingDocumentRepo) GetDocument(ctx context.Context, id string) (*models.BillingDocument, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	doc, ok := r.documents[id]
+	if !ok {
+		return nil, nil
+	}
+	return doc, nil
+}
+
+func (r *InMemoryBillingDocumentRepo) UpsertDocument(ctx context.Context, doc *models.BillingDocument) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *doc
+	r.documents[doc.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryBillingDocumentRepo) NextNumber(ctx context.Context, docType models.DocumentType, year int) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := fmt.Sprintf("%s/%d", docType, year)
+	r.sequences[key]++
+	return r.sequences[key], nil
+}
+
+func (r *InMemoryBillingDocumentRepo) ListClosedPeriods(ctx context.Context) ([]*models.ClosingPeriod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.ClosingPeriod, 0, len(r.closed))
+	for _, p := range r.closed {
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Period < result[j].Period })
+	return result, nil
+}
+
+func (r *InMemoryBillingDocumentRepo) GetClosedPeriod(ctx context.Context, period string) (*models.ClosingPeriod, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	p, ok := r.closed[period]
+	if !ok {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// ClosePeriod records the period as closed; closing it again keeps the
+// first record.
+func (r *InMemoryBillingDocumentRepo) ClosePeriod(ctx context.Context, p *models.ClosingPeriod) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.closed[p.Period]; !ok {
+		saved := *p
+		r.closed[p.Period] = &saved
+	}
+	return nil
+}
+
+// PostgresBillingDocumentRepo implements BillingDocumentRepo on the
+// billing_documents, document_sequences and closing_periods tables.
+type PostgresBillingDocumentRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresBillingDocumentRepo creates a new PostgreSQL-backed billing document repository.
+func NewPostgresBillingDocumentRepo(pool *pgxpool.Pool) *PostgresBillingDocumentRepo {
+	return &PostgresBillingDocumentRepo{pool: pool}
+}
+
+const billingDocumentColumns = `id, advertiser_id, type, number, seq, date, period, period_start, period_end,
+	supplier, buyer, currency, vat_rate::float8, lines, net::float8, vat_amount::float8, total::float8,
+	created_at, updated_at`
+
+func scanBillingDocument(row pgx.Row) (*models.BillingDocument, error) {
+	var doc models.BillingDocument
+	var docType string
+	var supplier, buyer, lines []byte
+	err := row.Scan(&doc.ID, &doc.AdvertiserID, &docType, &doc.Number, &doc.Seq, &doc.Date, &doc.Period, &doc.PeriodStart, &doc.PeriodEnd,
+		&supplier, &buyer, &doc.Currency, &doc.VATRate, &lines, &doc.Net, &doc.VATAmount, &doc.Total,
+		&doc.CreatedAt, &doc.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	doc.Type = models.DocumentType(docType)
+	if err := json.Unmarshal(supplier, &doc.Supplier); err != nil {
+		return nil, fmt.Errorf("failed to decode supplier: %w", err)
+	}
+	if err := json.Unmarshal(buyer, &doc.Buyer); err != nil {
+		return nil, fmt.Errorf("failed to decode buyer: %w", err)
+	}
+	if err := json.Unmarshal(lines, &doc.Lines); err != nil {
+		return nil, fmt.Errorf("failed to decode lines: %w", err)
+	}
+	return &doc, nil
+}
+
+func (r *PostgresBillingDocumentRepo) ListDocuments(ctx context.Context, filter DocumentFilter) ([]*models.BillingDocument, error) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.AdvertiserID != "" {
+		add("advertiser_id = $%d", filter.AdvertiserID)
+	}
+	if filter.Period != "" {
+		add("period = $%d", filter.Period)
+	}
+	if filter.Type != "" {
+		add("type = $%d", string(filter.Type))
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT `+billingDocumentColumns+`
+		FROM billing_documents
+		WHERE `+strings.Join(conds, " AND ")+`
+		ORDER BY period, advertiser_id, CASE type WHEN 'invoice' THEN 0 WHEN 'act' THEN 1 ELSE 2 END`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing documents: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.BillingDocument, 0)
+	for rows.Next() {
+		doc, err := scanBillingDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan billing document: %w", err)
+		}
+		result = append(result, doc)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresBillingDocumentRepo) GetDocument(ctx context.Context, id string) (*models.BillingDocument, error) {
+	doc, err := scanBillingDocument(r.pool.QueryRow(ctx, `
+		SELECT `+billingDocumentColumns+` FROM billing_documents WHERE id = $1
+	`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get billing document: %w", err)
+	}
+	return doc, nil
+}
+
+func (r *PostgresBillingDocumentRepo) UpsertDocument(ctx context.Context, doc *models.BillingDocument) error {
+	supplier, err := json.Marshal(doc.Supplier)
+	if err != nil {
+		return fmt.Errorf("failed to encode supplier: %w", err)
+	}
+	buyer, err := json.Marshal(doc.Buyer)
+	if err != nil {
+		return fmt.Errorf("failed to encode buyer: %w", err)
+	}
+	lines, err := json.Marshal(doc.Lines)
+	if err != nil {
+		return fmt.Errorf("failed to encode lines: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO billing_documents (
+			id, advertiser_id, type, number, seq, date, period, period_start, period_end,
+			supplier, buyer, currency, vat_rate, lines, net, vat_amount, total,
+			created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
+		ON CONFLICT (id) DO UPDATE SET
+			date = EXCLUDED.date,
+			supplier = EXCLUDED.supplier,
+			buyer = EXCLUDED.buyer,
+			currency = EXCLUDED.currency,
+			vat_rate = EXCLUDED.vat_rate,
+			lines = EXCLUDED.lines,
+			net = EXCLUDED.net,
+			vat_amount = EXCLUDED.vat_amount,
+			total = EXCLUDED.total,
+			updated_at = EXCLUDED.updated_at
+	`, doc.ID, doc.AdvertiserID, string(doc.Type), doc.Number, doc.Seq, doc.Date, doc.Period, doc.PeriodStart, doc.PeriodEnd,
+		supplier, buyer, doc.Currency, doc.VATRate, lines, doc.Net, doc.VATAmount, doc.Total,
+		doc.CreatedAt, doc.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert billing document: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBillingDocumentRepo) NextNumber(ctx context.Context, docType models.DocumentType, year int) (int64, error) {
+	var n int64
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO document_sequences (type, year, last_number) VALUES ($1, $2, 1)
+		ON CONFLICT (type, year) DO UPDATE SET last_number = document_sequences.last_number + 1
+		RETURNING last_number
+	`, string(docType), year).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get next document number: %w", err)
+	}
+	return n, nil
+}
+
+func (r *PostgresBillingDocumentRepo) ListClosedPeriods(ctx context.Context) ([]*models.ClosingPeriod, error) {
+	rows, err := r.pool.Query(ctx, `SELECT period, closed_at, closed_by FROM closing_periods ORDER BY period`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list closed periods: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.ClosingPeriod, 0)
+	for rows.Next() {
+		var p models.ClosingPeriod
+		if err := rows.Scan(&p.Period, &p.ClosedAt, &p.ClosedBy); err != nil {
+			return nil, fmt.Errorf("failed to scan closed period: %w", err)
+		}
+		result = append(result, &p)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresBillingDocumentRepo) GetClosedPeriod(ctx context.Context, period string) (*models.ClosingPeriod, error) {
+	var p models.ClosingPeriod
+	err := r.pool.QueryRow(ctx, `
+		SELECT period, closed_at, closed_by FROM closing_periods WHERE period = $1
+	`, period).Scan(&p.Period, &p.ClosedAt, &p.ClosedBy)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get closed period: %w", err)
+	}
+	return &p, nil
+}
+
+func (r *PostgresBillingDocumentRepo) ClosePeriod(ctx context.Context, p *models.ClosingPeriod) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO closing_periods (period, closed_at, closed_by) VALUES ($1, $2, $3)
+		ON CONFLICT (period) DO NOTHING
+	`, p.Period, p.ClosedAt, p.ClosedBy)
+	if err != nil {
+		return fmt.Errorf("failed to close period: %w", err)
+	}
+	return nil
+}
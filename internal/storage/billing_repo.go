@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// ErrDuplicateBillingReference is returned by BillingRepo.Apply when the
+// advertiser already has a transaction with the same reference.
+var ErrDuplicateBillingReference = errors.New("duplicate billing reference")
+
+// matches reports whether tx passes the filter.
+func (f *BillingFilter) matches(tx *models.BillingTransaction) bool {
+	if f.AdvertiserID != "" && tx.AdvertiserID != f.AdvertiserID {
+		return false
+	}
+	if f.Type != "" && tx.Type != f.Type {
+		return false
+	}
+	if !f.From.IsZero() && tx.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !tx.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// BalanceStore is the part of the in-memory advertiser repo the in-memory
+// ledger keeps balances in.
+type BalanceStore interface {
+	GetByID(ctx context.Context, id string) (*models.Advertiser, error)
+	UpdateBalance(ctx context.Context, id string, delta float64) (float64, error)
+}
+
+// InMemoryBillingRepo keeps the ledger in memory and balances in the
+// advertiser repo.
+type InMemoryBillingRepo struct {
+	mu           sync.RWMutex
+	balances     BalanceStore
+	transactions []*models.BillingTransaction
+}
+
+// NewInMemoryBillingRepo creates a new in-memory billing repository.
+func NewInMemoryBillingRepo(balances BalanceStore) *InMemoryBillingRepo {
+	return &InMemoryBillingRepo{balances: balances}
+}
+
+func (r *InMemoryBillingRepo) Apply(ctx context.Context, tx *models.BillingTransaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if tx.Reference != "" {
+		for _, existing := range r.transactions {
+			if existing.AdvertiserID == tx.AdvertiserID && existing.Reference == tx.Reference {
+				return ErrDuplicateBillingReference
+			}
+		}
+	}
+
+	balance, err := r.balances.UpdateBalance(ctx, tx.AdvertiserID, tx.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	tx.BalanceAfter = balance
+
+	saved := *tx
+	r.transactions = append(r.transactions, &saved)
+	return nil
+}
+
+func (r *InMemoryBillingRepo) ListTransactions(ctx context.Context, filter BillingFilter) ([]*models.BillingTransaction, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// Transactions are appended in creation order
+	result := make([]*models.BillingTransaction, 0)
+	for _, tx := range r.transactions {
+		if !filter.matches(tx) {
+			continue
+		}
+		result = append(result, tx)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// BalanceAt works back from the current balance, so balances set before
+// the ledger existed are accounted for.
+func (r *InMemoryBillingRepo) BalanceAt(ctx context.Context, advertiserID string, at time.Time) (float64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	a, err := r.balances.GetByID(ctx, advertiserID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get advertiser: %w", err)
+	}
+	if a == nil {
+		return 0, nil
+	}
+	balance := a.Balance
+	for _, tx := range r.transactions {
+		if tx.AdvertiserID == advertiserID && !tx.CreatedAt.Before(at) {
+			balance -= tx.Amount
+		}
+	}
+	return balance, nil
+}
+
+// PostgresBillingRepo implements BillingRepo on the billing_transactions
+// table and advertisers.balance.
+type PostgresBillingRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresBillingRepo creates a new PostgreSQL-backed billing repository.
+func NewPostgresBillingRepo(pool *pgxpool.Pool) *PostgresBillingRepo {
+	return &PostgresBillingRepo{pool: pool}
+}
+
+// Apply updates the balance first, which locks the advertiser row until
+// commit and so serializes the advertiser's transactions.
+func (r *PostgresBillingRepo) Apply(ctx context.Context, tx *models.BillingTransaction) error {
+	dbtx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer dbtx.Rollback(ctx)
+
+	var balance float64
+	err = dbtx.QueryRow(ctx, `
+		UPDATE advertisers SET balance = balance + $2, updated_at = NOW()
+		WHERE id = $1
+		RETURNING balance::float8
+	`, tx.AdvertiserID, tx.Amount).Scan(&balance)
+	if err == pgx.ErrNoRows {
+		return fmt.Errorf("advertiser %s not found", tx.AdvertiserID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update balance: %w", err)
+	}
+	tx.BalanceAfter = balance
+
+	if tx.Reference != "" {
+		var exists bool
+		err := dbtx.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM billing_transactions WHERE advertiser_id = $1 AND reference = $2)
+		`, tx.AdvertiserID, tx.Reference).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to check billing reference: %w", err)
+		}
+		if exists {
+			return ErrDuplicateBillingReference
+		}
+	}
+
+	_, err = dbtx.Exec(ctx, `
+		INSERT INTO billing_transactions (
+			id, advertiser_id, type, amount, currency, balance_after,
+			campaign_id, quantity, period_start, period_end,
+			reference, description, created_by, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, tx.ID, tx.AdvertiserID, string(tx.Type), tx.Amount, tx.Currency, tx.BalanceAfter,
+		tx.CampaignID, tx.Quantity, tx.PeriodStart, tx.PeriodEnd,
+		tx.Reference, tx.Description, tx.CreatedBy, tx.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert billing transaction: %w", err)
+	}
+
+	if err := dbtx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit billing transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresBillingRepo) ListTransactions(ctx context.Context, filter BillingFilter) ([]*models.BillingTransaction, error) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.AdvertiserID != "" {
+		add("advertiser_id = $%d", filter.AdvertiserID)
+	}
+	if filter.Type != "" {
+		add("type = $%d", string(filter.Type))
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < $%d", filter.To)
+	}
+	query := `
+		SELECT id, advertiser_id, type, amount::float8, currency, balance_after::float8,
+			campaign_id, quantity, period_start, period_end,
+			reference, description, created_by, created_at
+		FROM billing_transactions
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY created_at, seq`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list billing transactions: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.BillingTransaction, 0)
+	for rows.Next() {
+		var tx models.BillingTransaction
+		var txType string
+		err := rows.Scan(&tx.ID, &tx.AdvertiserID, &txType, &tx.Amount, &tx.Currency, &tx.BalanceAfter,
+			&tx.CampaignID, &tx.Quantity, &tx.PeriodStart, &tx.PeriodEnd,
+			&tx.Reference, &tx.Description, &tx.CreatedBy, &tx.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan billing transaction: %w", err)
+		}
+		tx.Type = models.BillingTransactionType(txType)
+		result = append(result, &tx)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresBillingRepo) BalanceAt(ctx context.Context, advertiserID string, at time.Time) (float64, error) {
+	var balance float64
+	err := r.pool.QueryRow(ctx, `
+		SELECT (a.balance - COALESCE((
+			SELECT SUM(t.amount) FROM billing_transactions t
+			WHERE t.advertiser_id = a.id AND t.created_at >= $2
+		), 0))::float8
+		FROM advertisers a WHERE a.id = $1
+	`, advertiserID, at).Scan(&balance)
+
+	if err == pgx.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get balance: %w", err)
+	}
+	return balance, nil
+}
@@ -1,76 +1,255 @@
 package storage
 
 import (
-    "sync"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
 
-    "github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
 )
 
-// CampaignRepo defines the minimal CRUD operations for campaigns.  A
-// campaign groups one or more line items which contain creatives and
-// targeting.  Implementations should provide thread safety.  In
-// production you would replace this with a database-backed store.
-type CampaignRepo interface {
-    // GetCampaign returns a single campaign by ID or nil if not found.
-    GetCampaign(id string) (*models.Campaign, error)
-    // ListCampaigns returns all campaigns.  It does not filter by
-    // advertiser or status; such filtering should be performed by the
-    // caller or via additional methods on the repository.
-    ListCampaigns() ([]*models.Campaign, error)
-    // UpsertCampaign inserts or updates the given campaign.  The
-    // implementation should overwrite any existing campaign with the
-    // same ID.  No validation is performed at this layer.
-    UpsertCampaign(c *models.Campaign) error
-}
-
-// InMemoryCampaignRepo is a simple in-memory implementation of
-// CampaignRepo.  It stores campaigns in a map keyed by campaign ID.  It
-// is intended for demonstration and testing only.  For production
-// deployments you should persist campaigns in a database or other
-// durable store and add indexes for efficient filtering.
+// campaignStatus returns the campaign's status; campaigns saved without one
+// are drafts.
+func campaignStatus(c *models.Campaign) models.CampaignStatus {
+	if c.Status == "" {
+		return models.CampaignStatusDraft
+	}
+	return c.Status
+}
+
+// copyJSON deep-copies src into dst through its JSON encoding, which is
+// how the Postgres repos store the same objects.
+func copyJSON(dst, src interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
+
+// nullString returns nil (SQL NULL) for the empty string.
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// nullTime returns nil (SQL NULL) for the zero time. Times are written in
+// UTC because the older tables use TIMESTAMP without a time zone.
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// =============================================
+// In-memory
+// =============================================
+
+// InMemoryCampaignRepo provides in-memory storage for campaigns. Campaigns
+// are deep-copied on Upsert and on reads, so line items changed by the
+// caller don't leak into the stored campaign.
 type InMemoryCampaignRepo struct {
-    mu        sync.RWMutex
-    campaigns map[string]*models.Campaign
+	mu        sync.RWMutex
+	campaigns map[string]*models.Campaign
 }
 
-// NewInMemoryCampaignRepo creates a new empty in-memory campaign repo.
+// NewInMemoryCampaignRepo creates a new in-memory campaign repository.
 func NewInMemoryCampaignRepo() *InMemoryCampaignRepo {
-    return &InMemoryCampaignRepo{
-        campaigns: make(map[string]*models.Campaign),
-    }
-}
-
-// GetCampaign returns the campaign with the given ID or nil if not found.
-func (r *InMemoryCampaignRepo) GetCampaign(id string) (*models.Campaign, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    if c, ok := r.campaigns[id]; ok {
-        return c, nil
-    }
-    return nil, nil
-}
-
-// ListCampaigns returns a slice containing all campaigns.
-func (r *InMemoryCampaignRepo) ListCampaigns() ([]*models.Campaign, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    res := make([]*models.Campaign, 0, len(r.campaigns))
-    for _, c := range r.campaigns {
-        res = append(res, c)
-    }
-    return res, nil
-}
-
-// UpsertCampaign inserts or updates the given campaign.  It makes a
-// shallow copy of the provided value to avoid external mutation of the
-// stored object.
-func (r *InMemoryCampaignRepo) UpsertCampaign(c *models.Campaign) error {
-    if c == nil {
-        return nil
-    }
-    r.mu.Lock()
-    defer r.mu.Unlock()
-    cp := *c
-    r.campaigns[c.ID] = &cp
-    return nil
-}
\ No newline at end of file
+	return &InMemoryCampaignRepo{
+		campaigns: make(map[string]*models.Campaign),
+	}
+}
+
+func (r *InMemoryCampaignRepo) ListAll(ctx context.Context) ([]*models.Campaign, error) {
+	return r.list(func(c *models.Campaign) bool { return true })
+}
+
+func (r *InMemoryCampaignRepo) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.campaigns[id]
+	if !ok {
+		return nil, nil
+	}
+	var saved models.Campaign
+	if err := copyJSON(&saved, c); err != nil {
+		return nil, fmt.Errorf("failed to copy campaign: %w", err)
+	}
+	return &saved, nil
+}
+
+func (r *InMemoryCampaignRepo) Upsert(ctx context.Context, c *models.Campaign) error {
+	var saved models.Campaign
+	if err := copyJSON(&saved, c); err != nil {
+		return fmt.Errorf("failed to copy campaign: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.campaigns[c.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryCampaignRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.campaigns, id)
+	return nil
+}
+
+func (r *InMemoryCampaignRepo) GetByAdvertiser(ctx context.Context, advertiserID string) ([]*models.Campaign, error) {
+	return r.list(func(c *models.Campaign) bool { return c.AdvertiserID == advertiserID })
+}
+
+func (r *InMemoryCampaignRepo) GetActive(ctx context.Context) ([]*models.Campaign, error) {
+	return r.GetByStatus(ctx, models.CampaignStatusActive)
+}
+
+func (r *InMemoryCampaignRepo) GetByStatus(ctx context.Context, status models.CampaignStatus) ([]*models.Campaign, error) {
+	return r.list(func(c *models.Campaign) bool { return campaignStatus(c) == status })
+}
+
+// list returns deep copies of the campaigns matching keep, ordered by ID.
+func (r *InMemoryCampaignRepo) list(keep func(c *models.Campaign) bool) ([]*models.Campaign, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.Campaign, 0)
+	for _, c := range r.campaigns {
+		if keep(c) {
+			var saved models.Campaign
+			if err := copyJSON(&saved, c); err != nil {
+				return nil, fmt.Errorf("failed to copy campaign: %w", err)
+			}
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// =============================================
+// PostgreSQL
+// =============================================
+
+// PostgresCampaignRepo implements CampaignRepo on the campaigns table. The
+// campaign with its line items is stored in the data column; the other
+// columns are kept in sync for queries and foreign keys.
+type PostgresCampaignRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCampaignRepo creates a new PostgreSQL campaign repository.
+func NewPostgresCampaignRepo(pool *pgxpool.Pool) *PostgresCampaignRepo {
+	return &PostgresCampaignRepo{pool: pool}
+}
+
+func (r *PostgresCampaignRepo) ListAll(ctx context.Context) ([]*models.Campaign, error) {
+	return r.query(ctx, `SELECT data FROM campaigns ORDER BY id`)
+}
+
+func (r *PostgresCampaignRepo) GetByID(ctx context.Context, id string) (*models.Campaign, error) {
+	var data []byte
+	err := r.pool.QueryRow(ctx, `SELECT data FROM campaigns WHERE id = $1`, id).Scan(&data)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign: %w", err)
+	}
+
+	var c models.Campaign
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("failed to decode campaign %s: %w", id, err)
+	}
+	return &c, nil
+}
+
+func (r *PostgresCampaignRepo) Upsert(ctx context.Context, c *models.Campaign) error {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to encode campaign: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO campaigns (
+			id, advertiser_id, name, status, app_bundle, app_name, app_store_url,
+			total_budget, daily_budget, start_date, end_date, created_at, updated_at, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			advertiser_id = EXCLUDED.advertiser_id,
+			name = EXCLUDED.name,
+			status = EXCLUDED.status,
+			app_bundle = EXCLUDED.app_bundle,
+			app_name = EXCLUDED.app_name,
+			app_store_url = EXCLUDED.app_store_url,
+			total_budget = EXCLUDED.total_budget,
+			daily_budget = EXCLUDED.daily_budget,
+			start_date = EXCLUDED.start_date,
+			end_date = EXCLUDED.end_date,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at,
+			data = EXCLUDED.data
+	`, c.ID, nullString(c.AdvertiserID), c.Name, string(campaignStatus(c)),
+		nullString(c.AppBundle), nullString(c.AppName), nullString(c.AppStoreURL),
+		c.TotalBudget, c.DailyBudget, nullTime(c.StartDate), nullTime(c.EndDate),
+		nullTime(c.CreatedAt), nullTime(c.UpdatedAt), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to upsert campaign: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCampaignRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM campaigns WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete campaign: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCampaignRepo) GetByAdvertiser(ctx context.Context, advertiserID string) ([]*models.Campaign, error) {
+	return r.query(ctx, `SELECT data FROM campaigns WHERE COALESCE(advertiser_id, '') = $1 ORDER BY id`, advertiserID)
+}
+
+func (r *PostgresCampaignRepo) GetActive(ctx context.Context) ([]*models.Campaign, error) {
+	return r.GetByStatus(ctx, models.CampaignStatusActive)
+}
+
+func (r *PostgresCampaignRepo) GetByStatus(ctx context.Context, status models.CampaignStatus) ([]*models.Campaign, error) {
+	return r.query(ctx, `SELECT data FROM campaigns WHERE status = $1 ORDER BY id`, string(status))
+}
+
+func (r *PostgresCampaignRepo) query(ctx context.Context, sql string, args ...interface{}) ([]*models.Campaign, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Campaign, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign: %w", err)
+		}
+		var c models.Campaign
+		if err := json.Unmarshal(data, &c); err != nil {
+			return nil, fmt.Errorf("failed to decode campaign: %w", err)
+		}
+		result = append(result, &c)
+	}
+	return result, rows.Err()
+}
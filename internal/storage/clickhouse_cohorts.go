@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// chCohortColumns returns the install date, campaign and source columns of
+// a cohort query; dimensions that aren't grouped by are blank.
+func chCohortColumns(q *CohortQuery, timestamp string) []string {
+	exprs := map[string]string{
+		CohortDimInstallDate: "formatDateTime(" + timestamp + ", '%Y-%m-%d', @tz)",
+		CohortDimCampaign:    "campaign_id",
+		CohortDimSource:      "source_id",
+	}
+	cols := make([]string, 0, len(CohortDimensions))
+	for _, d := range CohortDimensions {
+		expr := "''"
+		if containsString(q.Dimensions, d) {
+			expr = exprs[d]
+		}
+		cols = append(cols, expr+" AS dim_"+d)
+	}
+	return cols
+}
+
+// QueryCohorts builds cohorts in two queries: installs with their
+// post-install events folded per install, then media cost per cohort.
+func (s *ClickHouseEventStore) QueryCohorts(ctx context.Context, q CohortQuery) (*CohortResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	groups := newCohortGroups(&q)
+	args := []interface{}{
+		clickhouse.Named("start", chTime(q.Start)),
+		clickhouse.Named("end", chTime(q.End)),
+		clickhouse.Named("events_end", chTime(q.eventsEnd())),
+		clickhouse.Named("tz", q.Location.String()),
+		clickhouse.Named("install", InstallEvent),
+		clickhouse.Named("retention", q.RetentionEvents),
+		clickhouse.Named("campaigns", q.CampaignIDs),
+		clickhouse.Named("sources", q.SourceIDs),
+	}
+	filters := ""
+	if len(q.CampaignIDs) > 0 {
+		filters += " AND has(@campaigns, campaign_id)"
+	}
+	if len(q.SourceIDs) > 0 {
+		filters += " AND has(@sources, source_id)"
+	}
+	dimAliases := []string{"dim_" + CohortDimInstallDate, "dim_" + CohortDimCampaign, "dim_" + CohortDimSource}
+
+	// Per install: the first install of each click, then sums over its
+	// events by tracked day (d is -1 for installs without later events)
+	perInstall := make([]string, 0, 3*len(q.Days))
+	perCohort := make([]string, 0, 4*len(q.Days))
+	for i, n := range q.Days {
+		perInstall = append(perInstall,
+			fmt.Sprintf("sumIf(rev, d >= 0 AND d <= %d) AS rev_%d", n, i),
+			fmt.Sprintf("toUInt8(countIf(d >= 0 AND d <= %d AND rev > 0) > 0) AS payer_%d", n, i),
+			fmt.Sprintf("countIf(d = %d AND retention) AS ret_%d", n, i),
+		)
+		perCohort = append(perCohort,
+			fmt.Sprintf("sum(rev_%d)", i),
+			fmt.Sprintf("toUInt64(sum(payer_%d))", i),
+			fmt.Sprintf("toUInt64(countIf(ret_%d > 0))", i),
+			fmt.Sprintf("toUInt64(sum(ret_%d))", i),
+		)
+	}
+
+	installs := "SELECT click_id, min(timestamp) AS install_ts," +
+		" argMin(campaign_id, timestamp) AS campaign_id, argMin(source_id, timestamp) AS source_id," +
+		" argMin(payout_usd, timestamp) AS payout" +
+		" FROM conversions" +
+		" WHERE event = @install AND click_id != ''" +
+		" AND date >= toDate(@start) - 1 AND date <= toDate(@end) + 1" +
+		" AND timestamp >= @start AND timestamp < @end" + filters +
+		" GROUP BY click_id"
+	events := "SELECT click_id, timestamp, revenue_usd, has(@retention, event) AS retention" +
+		" FROM conversions" +
+		" WHERE click_id != ''" +
+		" AND date >= toDate(@start) - 1 AND date <= toDate(@events_end) + 1" +
+		" AND timestamp >= @start AND timestamp < @events_end"
+	joined := "SELECT i.click_id AS click_id, i.install_ts AS install_ts, i.campaign_id AS campaign_id," +
+		" i.source_id AS source_id, i.payout AS payout, e.revenue_usd AS rev, e.retention AS retention," +
+		" if(e.timestamp >= i.install_ts, intDiv(dateDiff('second', i.install_ts, e.timestamp), 86400), -1) AS d" +
+		" FROM (" + installs + ") AS i LEFT JOIN (" + events + ") AS e ON e.click_id = i.click_id"
+	perInstallQuery := "SELECT click_id, any(install_ts) AS install_ts, any(campaign_id) AS campaign_id," +
+		" any(source_id) AS source_id, any(payout) AS payout, " + strings.Join(perInstall, ", ") +
+		" FROM (" + joined + ") GROUP BY click_id"
+
+	query := "SELECT " + strings.Join(chCohortColumns(&q, "install_ts"), ", ") +
+		", count() AS installs, sum(payout) AS payout, " + strings.Join(perCohort, ", ") +
+		" FROM (" + perInstallQuery + ")" +
+		" GROUP BY " + strings.Join(dimAliases, ", ")
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohorts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			date, campaign, source string
+			count                  uint64
+			payout                 float64
+		)
+		n := len(q.Days)
+		revenue := make([]float64, n)
+		payers := make([]uint64, n)
+		retained := make([]uint64, n)
+		retentionEvents := make([]uint64, n)
+		dest := []interface{}{&date, &campaign, &source, &count, &payout}
+		for i := 0; i < n; i++ {
+			dest = append(dest, &revenue[i], &payers[i], &retained[i], &retentionEvents[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan cohort row: %w", err)
+		}
+
+		group := groups.get(date, campaign, source)
+		group.installs += int64(count)
+		group.cost += payout
+		for i := 0; i < n; i++ {
+			group.revenue[i] += revenue[i]
+			group.payers[i] += int64(payers[i])
+			group.retained[i] += int64(retained[i])
+			group.retentionEvents[i] += int64(retentionEvents[i])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cohort rows: %w", err)
+	}
+
+	// Media cost (USD win prices) on the install dates
+	costQuery := "SELECT " + strings.Join(chCohortColumns(&q, "timestamp"), ", ") + ", sum(win_price)" +
+		" FROM wins" +
+		" WHERE date >= toDate(@start) - 1 AND date <= toDate(@end) + 1" +
+		" AND timestamp >= @start AND timestamp < @end" + filters +
+		" GROUP BY " + strings.Join(dimAliases, ", ")
+
+	costRows, err := s.conn.Query(ctx, costQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohort cost: %w", err)
+	}
+	defer costRows.Close()
+
+	for costRows.Next() {
+		var date, campaign, source string
+		var cost float64
+		if err := costRows.Scan(&date, &campaign, &source, &cost); err != nil {
+			return nil, fmt.Errorf("failed to scan cohort cost: %w", err)
+		}
+		groups.get(date, campaign, source).cost += cost
+	}
+	if err := costRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cohort cost: %w", err)
+	}
+
+	return groups.result(), nil
+}
@@ -0,0 +1,804 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/radiusdt/vector-dsp/internal/metrics"
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"go.uber.org/zap"
+)
+
+// Event buffer errors.
+var (
+	ErrEventBufferFull  = errors.New("event buffer full")
+	ErrEventStoreClosed = errors.New("event store closed")
+)
+
+// chWriteTimeout bounds a single batch insert.
+const chWriteTimeout = 30 * time.Second
+
+// chWriteAttempts is how many times a failed batch is retried before it is
+// dropped.
+const chWriteAttempts = 3
+
+// Columns written per table; see migrations/clickhouse_001_schema.sql.
+// date and hour are filled by column defaults from timestamp.
+var (
+	chClickColumns = []string{
+		"id", "timestamp",
+		"campaign_id", "line_item_id", "creative_id",
+		"source_type", "source_id",
+		"device_ifa", "ip", "user_agent",
+		"geo_country", "geo_region", "geo_city",
+		"device_type", "device_os", "device_osv", "device_make", "device_model",
+		"bid_request_id", "bid_price", "win_price",
+		"sub1", "sub2", "sub3", "sub4", "sub5",
+		"target_url",
+		"fraud_score", "fraud_reasons", "fraud_flagged",
+	}
+	chImpressionColumns = []string{
+		"id", "timestamp",
+		"campaign_id", "line_item_id", "creative_id",
+		"source_type", "source_id",
+		"bid_request_id", "bid_price", "win_price",
+		"device_ifa", "ip", "geo_country", "device_os",
+		"app_bundle", "publisher_id",
+	}
+	chConversionColumns = []string{
+		"id", "timestamp",
+		"click_id", "click_timestamp",
+		"source_type", "source_id",
+		"campaign_id", "line_item_id", "creative_id", "advertiser_id",
+		"event", "event_original",
+		"revenue", "revenue_currency", "revenue_usd",
+		"payout", "payout_currency", "payout_usd", "payout_rule_id",
+		"device_ifa", "geo_country", "time_to_install", "external_id",
+		"fraud_score", "fraud_reasons", "fraud_flagged",
+		"needs_conversion",
+	}
+	chBidRequestColumns = []string{
+		"id", "timestamp",
+		"source_id",
+		"imp_id", "bid_floor",
+		"device_ifa", "device_os", "device_type",
+		"geo_country", "geo_region",
+		"app_bundle", "app_name", "publisher_id",
+		"ad_format", "width", "height",
+		"bid_campaign_id", "bid_price", "no_bid_reason",
+		"campaign_ids", "line_item_ids",
+		"request", "response", "trail",
+	}
+	chWinColumns = []string{
+		"id", "timestamp",
+		"bid_request_id", "imp_id",
+		"campaign_id", "line_item_id", "creative_id",
+		"source_id",
+		"bid_price", "win_price", "win_price_original", "win_currency", "needs_conversion",
+		"device_ifa", "geo_country",
+	}
+)
+
+var chTableColumns = map[string][]string{
+	"clicks":       chClickColumns,
+	"impressions":  chImpressionColumns,
+	"conversions":  chConversionColumns,
+	"wins":         chWinColumns,
+	"bid_requests": chBidRequestColumns,
+}
+
+// ClickHouseEventStoreConfig holds buffering settings.
+type ClickHouseEventStoreConfig struct {
+	BatchSize      int           // Rows per INSERT
+	FlushInterval  time.Duration // Longest a row waits in the buffer
+	BufferSize     int           // Rows buffered before writes block
+	EnqueueTimeout time.Duration // How long a write waits on a full buffer
+}
+
+// chRow is a buffered row for one table.
+type chRow struct {
+	table  string
+	values []interface{}
+}
+
+// ClickHouseEventStore implements EventStore on ClickHouse.
+//
+// Writes are buffered and inserted in batches by a background writer. When
+// the buffer is full, Save* blocks for up to EnqueueTimeout and then fails
+// with ErrEventBufferFull, so a slow ClickHouse pushes back on callers
+// instead of growing memory. Reads see events once their batch is flushed.
+// Close must be called on shutdown to flush buffered events.
+type ClickHouseEventStore struct {
+	conn    driver.Conn
+	cfg     ClickHouseEventStoreConfig
+	logger  *zap.Logger
+	metrics *metrics.Metrics
+
+	rows     chan chRow
+	flushReq chan chan struct{}
+	closing  chan struct{}
+	done     chan struct{}
+
+	mu        sync.RWMutex // Guards closing rows against in-flight sends
+	closed    bool
+	closeOnce sync.Once
+}
+
+// NewClickHouseEventStore creates a ClickHouse event store and starts its
+// background writer.
+func NewClickHouseEventStore(conn driver.Conn, cfg ClickHouseEventStoreConfig, logger *zap.Logger, m *metrics.Metrics) *ClickHouseEventStore {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.BufferSize < cfg.BatchSize {
+		cfg.BufferSize = cfg.BatchSize
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+
+	s := &ClickHouseEventStore{
+		conn:     conn,
+		cfg:      cfg,
+		logger:   logger,
+		metrics:  m,
+		rows:     make(chan chRow, cfg.BufferSize),
+		flushReq: make(chan chan struct{}),
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// =============================================
+// Clicks
+// =============================================
+
+func (s *ClickHouseEventStore) SaveClick(ctx context.Context, click *models.Click) error {
+	return s.enqueue(ctx, "clicks", []interface{}{
+		click.ID, click.Timestamp,
+		click.CampaignID, click.LineItemID, click.CreativeID,
+		click.SourceType, click.SourceID,
+		click.DeviceIFA, click.IP, click.UserAgent,
+		click.GeoCountry, click.GeoRegion, click.GeoCity,
+		click.DeviceType, click.DeviceOS, click.DeviceOSV, click.DeviceMake, click.DeviceModel,
+		click.BidRequestID, click.BidPrice, click.WinPrice,
+		click.Sub1, click.Sub2, click.Sub3, click.Sub4, click.Sub5,
+		click.TargetURL,
+		uint8(click.FraudScore), stringSlice(click.FraudReasons), boolToUInt8(click.FraudFlagged),
+	})
+}
+
+func (s *ClickHouseEventStore) GetClick(ctx context.Context, id string) (*models.Click, error) {
+	row := s.conn.QueryRow(ctx,
+		"SELECT "+strings.Join(chClickColumns, ", ")+" FROM clicks WHERE id = ? LIMIT 1", id)
+	click, err := scanClick(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get click: %w", err)
+	}
+	return click, nil
+}
+
+func (s *ClickHouseEventStore) GetClicksByDevice(ctx context.Context, deviceIFA string, since time.Time) ([]*models.Click, error) {
+	rows, err := s.conn.Query(ctx,
+		"SELECT "+strings.Join(chClickColumns, ", ")+
+			" FROM clicks WHERE device_ifa = ? AND timestamp > ? ORDER BY timestamp",
+		deviceIFA, chTime(since))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query clicks: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.Click
+	for rows.Next() {
+		click, err := scanClick(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan click: %w", err)
+		}
+		result = append(result, click)
+	}
+	return result, rows.Err()
+}
+
+// =============================================
+// Impressions
+// =============================================
+
+func (s *ClickHouseEventStore) SaveImpression(ctx context.Context, imp *models.Impression) error {
+	return s.enqueue(ctx, "impressions", []interface{}{
+		imp.ID, imp.Timestamp,
+		imp.CampaignID, imp.LineItemID, imp.CreativeID,
+		imp.SourceType, imp.SourceID,
+		imp.BidRequestID, imp.BidPrice, imp.WinPrice,
+		imp.DeviceIFA, imp.IP, imp.GeoCountry, imp.DeviceOS,
+		imp.AppBundle, imp.PublisherID,
+	})
+}
+
+func (s *ClickHouseEventStore) GetImpression(ctx context.Context, id string) (*models.Impression, error) {
+	imp := &models.Impression{}
+	err := s.conn.QueryRow(ctx,
+		"SELECT "+strings.Join(chImpressionColumns, ", ")+" FROM impressions WHERE id = ? LIMIT 1", id,
+	).Scan(
+		&imp.ID, &imp.Timestamp,
+		&imp.CampaignID, &imp.LineItemID, &imp.CreativeID,
+		&imp.SourceType, &imp.SourceID,
+		&imp.BidRequestID, &imp.BidPrice, &imp.WinPrice,
+		&imp.DeviceIFA, &imp.IP, &imp.GeoCountry, &imp.DeviceOS,
+		&imp.AppBundle, &imp.PublisherID,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impression: %w", err)
+	}
+	return imp, nil
+}
+
+// =============================================
+// Conversions
+// =============================================
+
+func (s *ClickHouseEventStore) SaveConversion(ctx context.Context, conv *models.Conversion) error {
+	clickTime := time.Unix(0, 0)
+	if conv.ClickTime != nil {
+		clickTime = *conv.ClickTime
+	}
+	return s.enqueue(ctx, "conversions", []interface{}{
+		conv.ID, conv.Timestamp,
+		conv.ClickID, clickTime,
+		conv.SourceType, conv.SourceID,
+		conv.CampaignID, conv.LineItemID, conv.CreativeID, conv.AdvertiserID,
+		conv.Event, conv.EventOriginal,
+		conv.Revenue, conv.RevenueCurrency, conv.RevenueUSD,
+		conv.Payout, conv.PayoutCurrency, conv.PayoutUSD, conv.PayoutRuleID,
+		conv.DeviceIFA, conv.GeoCountry, int32(conv.TimeToInstall), conv.ExternalID,
+		uint8(conv.FraudScore), stringSlice(conv.FraudReasons), boolToUInt8(conv.FraudFlagged),
+		boolToUInt8(conv.NeedsConversion),
+	})
+}
+
+func (s *ClickHouseEventStore) GetConversion(ctx context.Context, id string) (*models.Conversion, error) {
+	row := s.conn.QueryRow(ctx,
+		"SELECT "+strings.Join(chConversionColumns, ", ")+" FROM conversions WHERE id = ? LIMIT 1", id)
+	conv, err := scanConversion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversion: %w", err)
+	}
+	return conv, nil
+}
+
+func (s *ClickHouseEventStore) GetConversionsByClick(ctx context.Context, clickID string) ([]*models.Conversion, error) {
+	rows, err := s.conn.Query(ctx,
+		"SELECT "+strings.Join(chConversionColumns, ", ")+
+			" FROM conversions WHERE click_id = ? ORDER BY timestamp", clickID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query conversions: %w", err)
+	}
+	defer rows.Close()
+
+	var result []*models.Conversion
+	for rows.Next() {
+		conv, err := scanConversion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversion: %w", err)
+		}
+		result = append(result, conv)
+	}
+	return result, rows.Err()
+}
+
+// =============================================
+// Wins
+// =============================================
+
+func (s *ClickHouseEventStore) SaveWin(ctx context.Context, win *models.Win) error {
+	// wins.win_price is in the reporting currency; win_price_original is in
+	// win_currency
+	return s.enqueue(ctx, "wins", []interface{}{
+		win.ID, win.Timestamp,
+		win.BidRequestID, win.ImpID,
+		win.CampaignID, win.LineItemID, win.CreativeID,
+		win.SourceID,
+		win.BidPrice, win.WinPriceUSD, win.WinPrice, win.WinCurrency, boolToUInt8(win.NeedsConversion),
+		win.DeviceIFA, win.GeoCountry,
+	})
+}
+
+// =============================================
+// Bid Samples
+// =============================================
+
+// bidSampleColumns are read back for samples; the other bid_requests columns
+// are for ad-hoc analysis.
+const bidSampleColumns = "id, timestamp, source_id, no_bid_reason, request, response, trail"
+
+func (s *ClickHouseEventStore) SaveBidSample(ctx context.Context, sample *models.BidSample) error {
+	request, err := json.Marshal(sample.Request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal bid request: %w", err)
+	}
+	response := []byte{}
+	if sample.Response != nil {
+		if response, err = json.Marshal(sample.Response); err != nil {
+			return fmt.Errorf("failed to marshal bid response: %w", err)
+		}
+	}
+	trail, err := json.Marshal(sample.Trail)
+	if err != nil {
+		return fmt.Errorf("failed to marshal decision trail: %w", err)
+	}
+
+	// Flat columns describe the first impression
+	br := sample.Request
+	var impID, format, deviceIFA, deviceOS, deviceType, country, region, bundle, appName, publisherID string
+	var floor float64
+	var w, h uint16
+	if len(br.Imp) > 0 {
+		imp := br.Imp[0]
+		impID, floor = imp.ID, imp.BidFloor
+		switch {
+		case imp.Video != nil:
+			format = "video"
+		case imp.Native != nil:
+			format = "native"
+		case imp.Audio != nil:
+			format = "audio"
+		default:
+			format = "banner"
+			if imp.Banner != nil {
+				w, h = uint16(imp.Banner.W), uint16(imp.Banner.H)
+			}
+		}
+	}
+	if br.Device != nil {
+		deviceIFA, deviceOS = br.Device.Ifa, br.Device.OS
+		deviceType = strconv.Itoa(int(br.Device.DeviceType))
+		if br.Device.Geo != nil {
+			country, region = br.Device.Geo.Country, br.Device.Geo.Region
+		}
+	}
+	if br.App != nil {
+		bundle, appName = br.App.Bundle, br.App.Name
+		if br.App.Publisher != nil {
+			publisherID = br.App.Publisher.ID
+		}
+	}
+	var bidCampaignID string
+	var bidPrice float64
+	if sample.Response != nil && len(sample.Response.SeatBid) > 0 && len(sample.Response.SeatBid[0].Bid) > 0 {
+		bid := sample.Response.SeatBid[0].Bid[0]
+		bidCampaignID, bidPrice = bid.CID, bid.Price
+	}
+
+	return s.enqueue(ctx, "bid_requests", []interface{}{
+		sample.ID, sample.Timestamp,
+		sample.SourceID,
+		impID, floor,
+		deviceIFA, deviceOS, deviceType,
+		country, region,
+		bundle, appName, publisherID,
+		format, w, h,
+		bidCampaignID, bidPrice, sample.NoBidReason,
+		sample.CampaignIDs(), sample.LineItemIDs(),
+		string(request), string(response), string(trail),
+	})
+}
+
+func (s *ClickHouseEventStore) GetBidSample(ctx context.Context, id string) (*models.BidSample, error) {
+	row := s.conn.QueryRow(ctx,
+		"SELECT "+bidSampleColumns+" FROM bid_requests WHERE id = ? LIMIT 1", id)
+	sample, err := scanBidSample(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get bid sample: %w", err)
+	}
+	return sample, nil
+}
+
+func (s *ClickHouseEventStore) ListBidSamples(ctx context.Context, filter BidSampleFilter) ([]*models.BidSample, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultBidSampleLimit
+	}
+
+	query := "SELECT " + bidSampleColumns + " FROM bid_requests WHERE timestamp >= @since"
+	args := []interface{}{clickhouse.Named("since", chTime(filter.Since))}
+	if filter.SourceID != "" {
+		query += " AND source_id = @source"
+		args = append(args, clickhouse.Named("source", filter.SourceID))
+	}
+	if filter.CampaignID != "" {
+		query += " AND has(campaign_ids, @campaign)"
+		args = append(args, clickhouse.Named("campaign", filter.CampaignID))
+	}
+	if filter.LineItemID != "" {
+		query += " AND has(line_item_ids, @line_item)"
+		args = append(args, clickhouse.Named("line_item", filter.LineItemID))
+	}
+	if filter.NoBidOnly {
+		query += " AND bid_campaign_id = ''"
+	}
+	query += " ORDER BY timestamp DESC LIMIT " + strconv.Itoa(limit)
+
+	rows, err := s.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bid samples: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.BidSample, 0)
+	for rows.Next() {
+		sample, err := scanBidSample(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan bid sample: %w", err)
+		}
+		result = append(result, sample)
+	}
+	return result, rows.Err()
+}
+
+// =============================================
+// Aggregations
+// =============================================
+
+// Counts read whole days (hours for impressions) from the materialized
+// views and only the partial first day (hour) from the raw table.
+
+func (s *ClickHouseEventStore) GetClickCount(ctx context.Context, campaignID string, since time.Time) (int64, error) {
+	var count uint64
+	err := s.conn.QueryRow(ctx, `
+		SELECT
+			(SELECT sum(clicks) FROM mv_clicks_daily
+			 WHERE campaign_id = @campaign AND date > toDate(@since))
+			+
+			(SELECT count() FROM clicks
+			 WHERE campaign_id = @campaign AND date = toDate(@since) AND timestamp > @since)
+	`,
+		clickhouse.Named("campaign", campaignID),
+		clickhouse.Named("since", chTime(since)),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clicks: %w", err)
+	}
+	return int64(count), nil
+}
+
+func (s *ClickHouseEventStore) GetImpressionCount(ctx context.Context, campaignID string, since time.Time) (int64, error) {
+	var count uint64
+	err := s.conn.QueryRow(ctx, `
+		SELECT
+			(SELECT sum(impressions) FROM mv_campaign_hourly_stats
+			 WHERE campaign_id = @campaign
+			   AND (date > toDate(@since) OR (date = toDate(@since) AND hour > toHour(@since))))
+			+
+			(SELECT count() FROM impressions
+			 WHERE campaign_id = @campaign AND date = toDate(@since)
+			   AND hour = toHour(@since) AND timestamp > @since)
+	`,
+		clickhouse.Named("campaign", campaignID),
+		clickhouse.Named("since", chTime(since)),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count impressions: %w", err)
+	}
+	return int64(count), nil
+}
+
+func (s *ClickHouseEventStore) GetConversionCount(ctx context.Context, campaignID string, event string, since time.Time) (int64, error) {
+	var count uint64
+	err := s.conn.QueryRow(ctx, `
+		SELECT
+			(SELECT sum(conversions) FROM mv_conversions_daily
+			 WHERE campaign_id = @campaign AND (@event = '' OR event = @event)
+			   AND date > toDate(@since))
+			+
+			(SELECT count() FROM conversions
+			 WHERE campaign_id = @campaign AND (@event = '' OR event = @event)
+			   AND date = toDate(@since) AND timestamp > @since)
+	`,
+		clickhouse.Named("campaign", campaignID),
+		clickhouse.Named("event", event),
+		clickhouse.Named("since", chTime(since)),
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count conversions: %w", err)
+	}
+	return int64(count), nil
+}
+
+// =============================================
+// Buffering
+// =============================================
+
+// Flush writes all buffered events and waits for the inserts to finish.
+func (s *ClickHouseEventStore) Flush(ctx context.Context) error {
+	ack := make(chan struct{})
+	select {
+	case s.flushReq <- ack:
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-ack:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops accepting events, flushes the buffer and waits for the writer
+// to finish or ctx to expire.
+func (s *ClickHouseEventStore) Close(ctx context.Context) error {
+	s.closeOnce.Do(func() {
+		close(s.closing) // Release writers blocked on a full buffer
+		s.mu.Lock()
+		s.closed = true
+		close(s.rows)
+		s.mu.Unlock()
+	})
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush events: %w", ctx.Err())
+	}
+}
+
+// enqueue adds a row to the buffer, blocking for up to EnqueueTimeout while
+// the buffer is full.
+func (s *ClickHouseEventStore) enqueue(ctx context.Context, table string, values []interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.closed {
+		return ErrEventStoreClosed
+	}
+
+	row := chRow{table: table, values: values}
+	select {
+	case s.rows <- row:
+		return nil
+	default:
+	}
+
+	// Buffer full: hold the caller until the writer catches up
+	timer := time.NewTimer(s.cfg.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case s.rows <- row:
+		return nil
+	case <-timer.C:
+		if s.metrics != nil {
+			s.metrics.RecordEventWrite(table, "rejected", 1)
+		}
+		return ErrEventBufferFull
+	case <-s.closing:
+		return ErrEventStoreClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run is the background writer. It inserts a table's rows when BatchSize is
+// reached or every FlushInterval, and flushes everything when rows closes.
+func (s *ClickHouseEventStore) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	pending := make(map[string][][]interface{})
+	for {
+		select {
+		case row, ok := <-s.rows:
+			if !ok {
+				s.flushAll(pending)
+				return
+			}
+			pending[row.table] = append(pending[row.table], row.values)
+			if len(pending[row.table]) >= s.cfg.BatchSize {
+				s.write(row.table, pending[row.table])
+				delete(pending, row.table)
+			}
+		case <-ticker.C:
+			s.flushAll(pending)
+			if s.metrics != nil {
+				s.metrics.SetEventBufferDepth(len(s.rows))
+			}
+		case ack := <-s.flushReq:
+			// Take what was queued before the request
+			for n := len(s.rows); n > 0; n-- {
+				row, ok := <-s.rows
+				if !ok {
+					break
+				}
+				pending[row.table] = append(pending[row.table], row.values)
+			}
+			s.flushAll(pending)
+			close(ack)
+		}
+	}
+}
+
+func (s *ClickHouseEventStore) flushAll(pending map[string][][]interface{}) {
+	for table, rows := range pending {
+		s.write(table, rows)
+		delete(pending, table)
+	}
+}
+
+// write inserts rows into table, retrying before dropping the batch.
+func (s *ClickHouseEventStore) write(table string, rows [][]interface{}) {
+	if len(rows) == 0 {
+		return
+	}
+
+	var err error
+	for attempt := 1; attempt <= chWriteAttempts; attempt++ {
+		if err = s.insert(table, rows); err == nil {
+			if s.metrics != nil {
+				s.metrics.RecordEventWrite(table, "written", len(rows))
+			}
+			return
+		}
+		if attempt < chWriteAttempts {
+			time.Sleep(time.Duration(attempt) * 200 * time.Millisecond)
+		}
+	}
+
+	if s.metrics != nil {
+		s.metrics.RecordEventWrite(table, "failed", len(rows))
+	}
+	s.logger.Error("failed to write events to ClickHouse",
+		zap.String("table", table),
+		zap.Int("rows", len(rows)),
+		zap.Error(err),
+	)
+}
+
+func (s *ClickHouseEventStore) insert(table string, rows [][]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), chWriteTimeout)
+	defer cancel()
+
+	batch, err := s.conn.PrepareBatch(ctx,
+		"INSERT INTO "+table+" ("+strings.Join(chTableColumns[table], ", ")+")")
+	if err != nil {
+		return fmt.Errorf("failed to prepare batch: %w", err)
+	}
+	for _, values := range rows {
+		if err := batch.Append(values...); err != nil {
+			batch.Abort()
+			return fmt.Errorf("failed to append row: %w", err)
+		}
+	}
+	if err := batch.Send(); err != nil {
+		return fmt.Errorf("failed to send batch: %w", err)
+	}
+	return nil
+}
+
+// =============================================
+// Helpers
+// =============================================
+
+// chScanner is implemented by driver.Row and driver.Rows.
+type chScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanClick(row chScanner) (*models.Click, error) {
+	click := &models.Click{}
+	var fraudScore, fraudFlagged uint8
+	err := row.Scan(
+		&click.ID, &click.Timestamp,
+		&click.CampaignID, &click.LineItemID, &click.CreativeID,
+		&click.SourceType, &click.SourceID,
+		&click.DeviceIFA, &click.IP, &click.UserAgent,
+		&click.GeoCountry, &click.GeoRegion, &click.GeoCity,
+		&click.DeviceType, &click.DeviceOS, &click.DeviceOSV, &click.DeviceMake, &click.DeviceModel,
+		&click.BidRequestID, &click.BidPrice, &click.WinPrice,
+		&click.Sub1, &click.Sub2, &click.Sub3, &click.Sub4, &click.Sub5,
+		&click.TargetURL,
+		&fraudScore, &click.FraudReasons, &fraudFlagged,
+	)
+	if err != nil {
+		return nil, err
+	}
+	click.FraudScore = int(fraudScore)
+	click.FraudFlagged = fraudFlagged == 1
+	return click, nil
+}
+
+func scanConversion(row chScanner) (*models.Conversion, error) {
+	conv := &models.Conversion{}
+	var clickTime time.Time
+	var timeToInstall int32
+	var fraudScore, fraudFlagged, needsConversion uint8
+	err := row.Scan(
+		&conv.ID, &conv.Timestamp,
+		&conv.ClickID, &clickTime,
+		&conv.SourceType, &conv.SourceID,
+		&conv.CampaignID, &conv.LineItemID, &conv.CreativeID, &conv.AdvertiserID,
+		&conv.Event, &conv.EventOriginal,
+		&conv.Revenue, &conv.RevenueCurrency, &conv.RevenueUSD,
+		&conv.Payout, &conv.PayoutCurrency, &conv.PayoutUSD, &conv.PayoutRuleID,
+		&conv.DeviceIFA, &conv.GeoCountry, &timeToInstall, &conv.ExternalID,
+		&fraudScore, &conv.FraudReasons, &fraudFlagged,
+		&needsConversion,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if clickTime.Unix() > 0 {
+		conv.ClickTime = &clickTime
+	}
+	conv.TimeToInstall = int64(timeToInstall)
+	conv.FraudScore = int(fraudScore)
+	conv.FraudFlagged = fraudFlagged == 1
+	conv.NeedsConversion = needsConversion == 1
+	return conv, nil
+}
+
+func scanBidSample(row chScanner) (*models.BidSample, error) {
+	sample := &models.BidSample{}
+	var request, response, trail string
+	if err := row.Scan(&sample.ID, &sample.Timestamp, &sample.SourceID, &sample.NoBidReason, &request, &response, &trail); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(request), &sample.Request); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal bid request: %w", err)
+	}
+	if response != "" {
+		if err := json.Unmarshal([]byte(response), &sample.Response); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal bid response: %w", err)
+		}
+	}
+	if err := json.Unmarshal([]byte(trail), &sample.Trail); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal decision trail: %w", err)
+	}
+	return sample, nil
+}
+
+// chTime clamps t to the DateTime64 range (zero means "all time").
+func chTime(t time.Time) time.Time {
+	if t.Unix() < 0 {
+		return time.Unix(0, 0).UTC()
+	}
+	return t.UTC()
+}
+
+func boolToUInt8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// stringSlice returns s, or an empty slice for nil (Array columns reject nil).
+func stringSlice(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// newBufferedStore returns a ClickHouse event store without a connection
+// or writer: saved rows stay in its buffer.
+func newBufferedStore(size int) *ClickHouseEventStore {
+	return &ClickHouseEventStore{
+		cfg:     ClickHouseEventStoreConfig{BufferSize: size, EnqueueTimeout: 10 * time.Millisecond},
+		rows:    make(chan chRow, size),
+		closing: make(chan struct{}),
+	}
+}
+
+// chValues scans the values of a buffered row, as ClickHouse would return
+// them for its columns.
+type chValues struct {
+	columns []string
+	values  []interface{}
+}
+
+func (r chValues) Scan(dest ...interface{}) error {
+	if len(dest) != len(r.values) {
+		return fmt.Errorf("scanning %d columns into %d values", len(r.values), len(dest))
+	}
+	for i, d := range dest {
+		dv := reflect.ValueOf(d).Elem()
+		v := reflect.ValueOf(r.values[i])
+		if !v.Type().AssignableTo(dv.Type()) {
+			return fmt.Errorf("column %s: can't scan %T into %s", r.columns[i], r.values[i], dv.Type())
+		}
+		dv.Set(v)
+	}
+	return nil
+}
+
+func TestClickHouseRowsMatchColumns(t *testing.T) {
+	tests := []struct {
+		table string
+		save  func(ctx context.Context, s *ClickHouseEventStore) error
+	}{
+		{"clicks", func(ctx context.Context, s *ClickHouseEventStore) error {
+			return s.SaveClick(ctx, &models.Click{ID: "c"})
+		}},
+		{"impressions", func(ctx context.Context, s *ClickHouseEventStore) error {
+			return s.SaveImpression(ctx, &models.Impression{ID: "i"})
+		}},
+		{"conversions", func(ctx context.Context, s *ClickHouseEventStore) error {
+			return s.SaveConversion(ctx, &models.Conversion{ID: "v"})
+		}},
+		{"wins", func(ctx context.Context, s *ClickHouseEventStore) error {
+			return s.SaveWin(ctx, &models.Win{ID: "w"})
+		}},
+		{"bid_requests", func(ctx context.Context, s *ClickHouseEventStore) error {
+			return s.SaveBidSample(ctx, &models.BidSample{ID: "b", Request: &models.BidRequest{ID: "br"}})
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.table, func(t *testing.T) {
+			s := newBufferedStore(1)
+			if err := tt.save(context.Background(), s); err != nil {
+				t.Fatalf("save error = %v", err)
+			}
+			row := <-s.rows
+			if row.table != tt.table {
+				t.Errorf("table = %s, want %s", row.table, tt.table)
+			}
+			if got, want := len(row.values), len(chTableColumns[tt.table]); got != want {
+				t.Errorf("%d values for %d columns", got, want)
+			}
+		})
+	}
+}
+
+func TestClickHouseRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	at := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	clickAt := at.Add(-time.Hour)
+
+	t.Run("conversion", func(t *testing.T) {
+		want := &models.Conversion{
+			ID: "conv-1", Timestamp: at, ClickID: "click-1",
+			SourceType: "s2s", SourceID: "src-1",
+			CampaignID: "cmp-1", LineItemID: "li-1", CreativeID: "cr-1", AdvertiserID: "adv-1",
+			Event: "purchase", EventOriginal: "af_purchase",
+			Revenue: 900, RevenueCurrency: "RUB", RevenueUSD: 10,
+			Payout: 90, PayoutCurrency: "RUB", PayoutRuleID: "rule-1", NeedsConversion: true,
+			DeviceIFA: "ifa-1", GeoCountry: "RU",
+			ClickTime: &clickAt, TimeToInstall: 3600, ExternalID: "ext-1",
+			FraudScore: 30, FraudReasons: []string{"geo_mismatch"}, FraudFlagged: true,
+		}
+		s := newBufferedStore(1)
+		if err := s.SaveConversion(ctx, want); err != nil {
+			t.Fatalf("SaveConversion() error = %v", err)
+		}
+		row := <-s.rows
+		got, err := scanConversion(chValues{chConversionColumns, row.values})
+		if err != nil {
+			t.Fatalf("scanConversion() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("click", func(t *testing.T) {
+		want := &models.Click{
+			ID: "click-1", Timestamp: at,
+			CampaignID: "cmp-1", LineItemID: "li-1", CreativeID: "cr-1",
+			SourceType: "rtb", SourceID: "src-1",
+			DeviceIFA: "ifa-1", IP: "10.0.0.1", UserAgent: "ua",
+			GeoCountry: "RU", GeoRegion: "MOW", GeoCity: "Moscow",
+			DeviceType: "phone", DeviceOS: "android", DeviceOSV: "14", DeviceMake: "make", DeviceModel: "model",
+			BidRequestID: "br-1", BidPrice: 1.5, WinPrice: 1.2,
+			Sub1: "a", Sub2: "b", Sub3: "c", Sub4: "d", Sub5: "e",
+			TargetURL: "https://example.com", FraudScore: 60, FraudReasons: []string{"ctit_too_short"}, FraudFlagged: true,
+		}
+		s := newBufferedStore(1)
+		if err := s.SaveClick(ctx, want); err != nil {
+			t.Fatalf("SaveClick() error = %v", err)
+		}
+		row := <-s.rows
+		got, err := scanClick(chValues{chClickColumns, row.values})
+		if err != nil {
+			t.Fatalf("scanClick() error = %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("round trip = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestClickHouseEnqueueBackpressure(t *testing.T) {
+	ctx := context.Background()
+	s := newBufferedStore(1)
+
+	if err := s.SaveClick(ctx, &models.Click{ID: "1"}); err != nil {
+		t.Fatalf("first SaveClick() error = %v", err)
+	}
+	if err := s.SaveClick(ctx, &models.Click{ID: "2"}); !errors.Is(err, ErrEventBufferFull) {
+		t.Errorf("SaveClick() on a full buffer error = %v, want %v", err, ErrEventBufferFull)
+	}
+
+	s.closed = true
+	if err := s.SaveClick(ctx, &models.Click{ID: "3"}); !errors.Is(err, ErrEventStoreClosed) {
+		t.Errorf("SaveClick() after close error = %v, want %v", err, ErrEventStoreClosed)
+	}
+}
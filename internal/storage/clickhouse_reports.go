@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+// chReportClickLookbackDays bounds how far before the report start a
+// conversion's click is looked up for click-level dimensions.
+const chReportClickLookbackDays = 30
+
+// chReportMetricExprs computes each metric from the summed m_* columns of
+// the per-table subqueries.
+var chReportMetricExprs = map[string]string{
+	ReportMetricImpressions: "toFloat64(sum(m_impressions))",
+	ReportMetricClicks:      "toFloat64(sum(m_clicks))",
+	ReportMetricConversions: "toFloat64(sum(m_conversions))",
+	ReportMetricInstalls:    "toFloat64(sum(m_installs))",
+	ReportMetricSpend:       "sum(m_spend)",
+	ReportMetricRevenue:     "sum(m_revenue)",
+	ReportMetricPayout:      "sum(m_payout)",
+	ReportMetricProfit:      "sum(m_revenue) - sum(m_spend)",
+	ReportMetricCTR:         "if(sum(m_impressions) > 0, sum(m_clicks) / sum(m_impressions) * 100, 0)",
+	ReportMetricCVR:         "if(sum(m_clicks) > 0, sum(m_conversions) / sum(m_clicks) * 100, 0)",
+	ReportMetricECPM:        "if(sum(m_impressions) > 0, sum(m_spend) / sum(m_impressions) * 1000, 0)",
+	ReportMetricECPC:        "if(sum(m_clicks) > 0, sum(m_spend) / sum(m_clicks), 0)",
+	ReportMetricECPA:        "if(sum(m_conversions) > 0, sum(m_spend) / sum(m_conversions), 0)",
+	ReportMetricROAS:        "if(sum(m_spend) > 0, sum(m_revenue) / sum(m_spend), 0)",
+}
+
+// chReportTable is one event table feeding a report.
+type chReportTable struct {
+	name    string
+	metrics string // m_impressions, m_clicks, m_conversions, m_installs, m_spend, m_revenue, m_payout
+}
+
+var (
+	chReportImpressions = chReportTable{"impressions",
+		"count() AS m_impressions, toUInt64(0) AS m_clicks, toUInt64(0) AS m_conversions, toUInt64(0) AS m_installs, " +
+			"toFloat64(0) AS m_spend, toFloat64(0) AS m_revenue, toFloat64(0) AS m_payout"}
+	chReportWins = chReportTable{"wins",
+		"toUInt64(0) AS m_impressions, toUInt64(0) AS m_clicks, toUInt64(0) AS m_conversions, toUInt64(0) AS m_installs, " +
+			"sum(e.win_price) AS m_spend, toFloat64(0) AS m_revenue, toFloat64(0) AS m_payout"}
+	chReportClicks = chReportTable{"clicks",
+		"toUInt64(0) AS m_impressions, count() AS m_clicks, toUInt64(0) AS m_conversions, toUInt64(0) AS m_installs, " +
+			"toFloat64(0) AS m_spend, toFloat64(0) AS m_revenue, toFloat64(0) AS m_payout"}
+	chReportConversions = chReportTable{"conversions",
+		"toUInt64(0) AS m_impressions, toUInt64(0) AS m_clicks, count() AS m_conversions, " +
+			"countIf(e.event = '" + InstallEvent + "') AS m_installs, " +
+			"toFloat64(0) AS m_spend, sum(e.revenue_usd) AS m_revenue, sum(e.payout_usd) AS m_payout"}
+)
+
+// chReportColumn returns the expression for a dimension in table (alias e).
+// Conversions take click-level dimensions from the joined click (alias cl);
+// wins are RTB and have no device or publisher columns.
+func chReportColumn(dim, table string) string {
+	if table == "wins" {
+		switch dim {
+		case ReportDimSourceType:
+			return "'" + winSourceType + "'"
+		case ReportDimOS, ReportDimAppBundle, ReportDimPublisher:
+			return "''"
+		}
+	}
+
+	switch dim {
+	case ReportDimDate:
+		return "formatDateTime(e.timestamp, '%Y-%m-%d', @tz)"
+	case ReportDimHour:
+		return "formatDateTime(e.timestamp, '%Y-%m-%d %H:00', @tz)"
+	case ReportDimWeek:
+		return "formatDateTime(toMonday(e.timestamp, @tz), '%Y-%m-%d')"
+	case ReportDimMonth:
+		return "formatDateTime(e.timestamp, '%Y-%m', @tz)"
+	case ReportDimCampaign:
+		return "e.campaign_id"
+	case ReportDimLineItem:
+		return "e.line_item_id"
+	case ReportDimCreative:
+		return "e.creative_id"
+	case ReportDimSource:
+		return "e.source_id"
+	case ReportDimSourceType:
+		return "e.source_type"
+	}
+
+	alias := "e."
+	if table == "conversions" {
+		alias = "cl."
+	}
+	switch dim {
+	case ReportDimCountry:
+		return alias + "geo_country"
+	case ReportDimOS:
+		return alias + "device_os"
+	case ReportDimAppBundle:
+		return alias + "app_bundle"
+	default:
+		return alias + "publisher_id"
+	}
+}
+
+// QueryReport runs the report as one query: each event table is filtered
+// and pre-grouped, the results are unioned and grouped again, and sorting
+// and pagination happen in ClickHouse.
+func (s *ClickHouseEventStore) QueryReport(ctx context.Context, q ReportQuery) (*ReportResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	tables := make([]chReportTable, 0, 4)
+	if q.reportNeeds(ReportMetricImpressions) {
+		tables = append(tables, chReportImpressions)
+	}
+	if q.reportNeeds(ReportMetricSpend) {
+		tables = append(tables, chReportWins)
+	}
+	if q.reportNeeds(ReportMetricClicks) {
+		tables = append(tables, chReportClicks)
+	}
+	if q.reportNeeds(ReportMetricConversions, ReportMetricInstalls, ReportMetricRevenue, ReportMetricPayout) {
+		tables = append(tables, chReportConversions)
+	}
+
+	dimAliases := make([]string, len(q.Dimensions))
+	for i, d := range q.Dimensions {
+		dimAliases[i] = "dim_" + d
+	}
+
+	subqueries := make([]string, 0, len(tables))
+	for _, t := range tables {
+		subqueries = append(subqueries, chReportSubquery(&q, t, dimAliases))
+	}
+
+	selects := append([]string{}, dimAliases...)
+	for _, m := range q.Metrics {
+		selects = append(selects, chReportMetricExprs[m]+" AS "+m)
+	}
+	selects = append(selects, "count() OVER () AS total_rows")
+
+	query := "SELECT " + strings.Join(selects, ", ") +
+		" FROM (" + strings.Join(subqueries, " UNION ALL ") + ")"
+	if len(dimAliases) > 0 {
+		query += " GROUP BY " + strings.Join(dimAliases, ", ")
+	}
+
+	orderBy := make([]string, 0, len(dimAliases)+1)
+	if q.SortBy != "" {
+		sortCol := q.SortBy
+		if containsString(q.Dimensions, q.SortBy) {
+			sortCol = "dim_" + q.SortBy
+		}
+		if q.SortDesc {
+			sortCol += " DESC"
+		}
+		orderBy = append(orderBy, sortCol)
+	}
+	orderBy = append(orderBy, dimAliases...)
+	if len(orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	query += " LIMIT @limit OFFSET @offset"
+
+	rows, err := s.conn.Query(ctx, query,
+		clickhouse.Named("start", chTime(q.Start)),
+		clickhouse.Named("end", chTime(q.End)),
+		clickhouse.Named("tz", q.Location.String()),
+		clickhouse.Named("campaigns", q.CampaignIDs),
+		clickhouse.Named("line_items", q.LineItemIDs),
+		clickhouse.Named("creatives", q.CreativeIDs),
+		clickhouse.Named("sources", q.SourceIDs),
+		clickhouse.Named("countries", q.Countries),
+		clickhouse.Named("os", q.OS),
+		clickhouse.Named("bundles", q.AppBundles),
+		clickhouse.Named("publishers", q.PublisherIDs),
+		clickhouse.Named("limit", q.Limit),
+		clickhouse.Named("offset", q.Offset),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report: %w", err)
+	}
+	defer rows.Close()
+
+	result := &ReportResult{
+		Dimensions: q.Dimensions,
+		Metrics:    q.Metrics,
+		Rows:       make([]ReportRow, 0),
+	}
+	for rows.Next() {
+		dims := make([]string, len(q.Dimensions))
+		values := make([]float64, len(q.Metrics))
+		var total uint64
+		dest := make([]interface{}, 0, len(dims)+len(values)+1)
+		for i := range dims {
+			dest = append(dest, &dims[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &total)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan report row: %w", err)
+		}
+
+		row := ReportRow{
+			Dimensions: make(map[string]string, len(dims)),
+			Metrics:    make(map[string]float64, len(values)),
+		}
+		for i, d := range q.Dimensions {
+			row.Dimensions[d] = dims[i]
+		}
+		for i, m := range q.Metrics {
+			row.Metrics[m] = values[i]
+		}
+		result.Rows = append(result.Rows, row)
+		result.Total = int64(total)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read report rows: %w", err)
+	}
+
+	// Past the last page there are no rows to carry the total
+	if len(result.Rows) == 0 && q.Offset > 0 {
+		q.Offset = 0
+		q.Limit = 1
+		first, err := s.QueryReport(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = first.Total
+	}
+
+	return result, nil
+}
+
+// chReportSubquery filters and pre-groups one event table.
+func chReportSubquery(q *ReportQuery, t chReportTable, dimAliases []string) string {
+	selects := make([]string, 0, len(q.Dimensions)+1)
+	for i, d := range q.Dimensions {
+		selects = append(selects, chReportColumn(d, t.name)+" AS "+dimAliases[i])
+	}
+	selects = append(selects, t.metrics)
+
+	from := t.name + " AS e"
+	if t.name == "conversions" && q.reportNeedsClick() {
+		from += fmt.Sprintf(" LEFT JOIN (SELECT id, geo_country, device_os, app_bundle, publisher_id FROM clicks"+
+			" WHERE date >= toDate(@start) - %d AND date <= toDate(@end) + 1) AS cl ON cl.id = e.click_id",
+			chReportClickLookbackDays)
+	}
+
+	where := []string{
+		"e.date >= toDate(@start) - 1", // date is in server time
+		"e.date <= toDate(@end) + 1",
+		"e.timestamp >= @start",
+		"e.timestamp < @end",
+	}
+	filters := []struct {
+		dim    string
+		param  string
+		values []string
+	}{
+		{ReportDimCampaign, "campaigns", q.CampaignIDs},
+		{ReportDimLineItem, "line_items", q.LineItemIDs},
+		{ReportDimCreative, "creatives", q.CreativeIDs},
+		{ReportDimSource, "sources", q.SourceIDs},
+		{ReportDimCountry, "countries", q.Countries},
+		{ReportDimOS, "os", q.OS},
+		{ReportDimAppBundle, "bundles", q.AppBundles},
+		{ReportDimPublisher, "publishers", q.PublisherIDs},
+	}
+	for _, f := range filters {
+		if len(f.values) > 0 {
+			where = append(where, fmt.Sprintf("has(@%s, %s)", f.param, chReportColumn(f.dim, t.name)))
+		}
+	}
+
+	sub := "SELECT " + strings.Join(selects, ", ") + " FROM " + from + " WHERE " + strings.Join(where, " AND ")
+	if len(dimAliases) > 0 {
+		sub += " GROUP BY " + strings.Join(dimAliases, ", ")
+	}
+	return sub
+}
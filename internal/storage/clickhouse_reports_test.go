@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClickHouseReportSpendFromWins(t *testing.T) {
+	q := ReportQuery{
+		Dimensions: []string{ReportDimSourceType, ReportDimOS},
+		Metrics:    []string{ReportMetricImpressions, ReportMetricSpend},
+	}
+	if err := q.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	aliases := []string{"dim_" + ReportDimSourceType, "dim_" + ReportDimOS}
+
+	wins := chReportSubquery(&q, chReportWins, aliases)
+	for _, want := range []string{
+		"FROM wins AS e",
+		"sum(e.win_price) AS m_spend",
+		"'rtb' AS dim_source_type",
+		"'' AS dim_os",
+	} {
+		if !strings.Contains(wins, want) {
+			t.Errorf("wins subquery has no %q:\n%s", want, wins)
+		}
+	}
+
+	impressions := chReportSubquery(&q, chReportImpressions, aliases)
+	if strings.Contains(impressions, "win_price") {
+		t.Errorf("impressions subquery sums win prices:\n%s", impressions)
+	}
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// =============================================
+// COHORT REPORTS
+// =============================================
+
+// Cohort dimensions. Installs are grouped by the install's date, campaign
+// and source; later events count towards the install's cohort.
+const (
+	CohortDimInstallDate = "install_date"
+	CohortDimCampaign    = "campaign"
+	CohortDimSource      = "source"
+)
+
+// CohortDimensions lists the supported cohort dimensions.
+var CohortDimensions = []string{CohortDimInstallDate, CohortDimCampaign, CohortDimSource}
+
+// MaxCohortDay bounds how far after install a cohort is tracked.
+const MaxCohortDay = 180
+
+var (
+	// DefaultCohortDays are reported when a query asks for none
+	DefaultCohortDays = []int{0, 1, 7, 30}
+
+	// DefaultRetentionEvents are the events that mark a user as retained
+	DefaultRetentionEvents = []string{"session"}
+)
+
+// CohortQuery selects installs in [Start, End) and tracks their events up
+// to the largest of Days after install. Day N is the Nth 24 hours after
+// install, so day 0 is the first 24 hours.
+type CohortQuery struct {
+	Start    time.Time
+	End      time.Time
+	Location *time.Location // Install dates; defaults to UTC
+	Now      time.Time      // For day completeness; defaults to time.Now
+
+	Dimensions      []string // See CohortDimensions; defaults to all
+	Days            []int    // Defaults to DefaultCohortDays
+	RetentionEvents []string // Defaults to DefaultRetentionEvents
+
+	CampaignIDs []string
+	SourceIDs   []string
+}
+
+// Validate checks the query and fills in defaults.
+func (q *CohortQuery) Validate() error {
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.AddDate(0, 0, -30)
+	}
+	if !q.Start.Before(q.End) {
+		return fmt.Errorf("start must be before end")
+	}
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+	if q.Now.IsZero() {
+		q.Now = time.Now()
+	}
+
+	if len(q.Dimensions) == 0 {
+		q.Dimensions = CohortDimensions
+	}
+	seen := make(map[string]bool)
+	for _, d := range q.Dimensions {
+		if !containsString(CohortDimensions, d) {
+			return fmt.Errorf("unknown cohort dimension %q", d)
+		}
+		if seen[d] {
+			return fmt.Errorf("duplicate dimension %q", d)
+		}
+		seen[d] = true
+	}
+
+	if len(q.Days) == 0 {
+		q.Days = DefaultCohortDays
+	}
+	days := append([]int{}, q.Days...)
+	sort.Ints(days)
+	q.Days = days[:0]
+	for _, d := range days {
+		if d < 0 || d > MaxCohortDay {
+			return fmt.Errorf("cohort day %d out of range 0-%d", d, MaxCohortDay)
+		}
+		if len(q.Days) == 0 || q.Days[len(q.Days)-1] != d {
+			q.Days = append(q.Days, d)
+		}
+	}
+
+	if len(q.RetentionEvents) == 0 {
+		q.RetentionEvents = DefaultRetentionEvents
+	}
+	return nil
+}
+
+// eventsEnd is when the last install's last tracked day ends.
+func (q *CohortQuery) eventsEnd() time.Time {
+	return q.End.Add(time.Duration(q.Days[len(q.Days)-1]+1) * 24 * time.Hour)
+}
+
+// CohortDay holds a cohort's metrics N days after install. Revenue and
+// payers are cumulative through day N; retention counts day N only.
+type CohortDay struct {
+	Day             int     `json:"day"`
+	Complete        bool    `json:"complete"` // Every install in the row has been tracked through day N
+	Revenue         float64 `json:"revenue"`
+	ROAS            float64 `json:"roas"` // Revenue / cost
+	ARPU            float64 `json:"arpu"` // Revenue per install
+	Payers          int64   `json:"payers"`
+	PayerRate       float64 `json:"payer_rate"` // % of installs
+	Retained        int64   `json:"retained"`   // Installs with a retention event on day N
+	RetentionRate   float64 `json:"retention_rate"`
+	RetentionEvents int64   `json:"retention_events"`
+}
+
+// CohortRow is one cohort.
+type CohortRow struct {
+	Dimensions map[string]string `json:"dimensions"`
+	Installs   int64             `json:"installs"`
+	Cost       float64           `json:"cost"` // Win spend on the install dates plus install payouts
+	Days       []CohortDay       `json:"days"`
+}
+
+// CohortResult is the outcome of a cohort query, sorted by dimensions.
+type CohortResult struct {
+	Dimensions []string    `json:"dimensions"`
+	Days       []int       `json:"days"`
+	Rows       []CohortRow `json:"rows"`
+}
+
+// cohortGroup accumulates raw counts for one cohort. Per-day slices are
+// indexed like CohortQuery.Days.
+type cohortGroup struct {
+	dims            map[string]string
+	installs        int64
+	cost            float64
+	revenue         []float64
+	payers          []int64
+	retained        []int64
+	retentionEvents []int64
+}
+
+// cohortGroups collects groups keyed by their dimension values.
+type cohortGroups struct {
+	q      *CohortQuery
+	groups map[string]*cohortGroup
+}
+
+func newCohortGroups(q *CohortQuery) *cohortGroups {
+	return &cohortGroups{q: q, groups: make(map[string]*cohortGroup)}
+}
+
+// get returns the group for the given install date, campaign and source.
+func (g *cohortGroups) get(installDate, campaign, source string) *cohortGroup {
+	values := map[string]string{
+		CohortDimInstallDate: installDate,
+		CohortDimCampaign:    campaign,
+		CohortDimSource:      source,
+	}
+	dims := make(map[string]string, len(g.q.Dimensions))
+	keyParts := make([]string, len(g.q.Dimensions))
+	for i, d := range g.q.Dimensions {
+		dims[d] = values[d]
+		keyParts[i] = values[d]
+	}
+	key := strings.Join(keyParts, "\x00")
+
+	group, ok := g.groups[key]
+	if !ok {
+		n := len(g.q.Days)
+		group = &cohortGroup{
+			dims:            dims,
+			revenue:         make([]float64, n),
+			payers:          make([]int64, n),
+			retained:        make([]int64, n),
+			retentionEvents: make([]int64, n),
+		}
+		g.groups[key] = group
+	}
+	return group
+}
+
+// result derives rates and completeness and sorts rows by dimensions.
+func (g *cohortGroups) result() *CohortResult {
+	q := g.q
+	rows := make([]CohortRow, 0, len(g.groups))
+	for _, group := range g.groups {
+		// The last install of the row is at the end of its install date
+		lastInstall := q.End
+		if date, ok := group.dims[CohortDimInstallDate]; ok {
+			if t, err := time.ParseInLocation("2006-01-02", date, q.Location); err == nil {
+				if dayEnd := t.AddDate(0, 0, 1); dayEnd.Before(lastInstall) {
+					lastInstall = dayEnd
+				}
+			}
+		}
+
+		row := CohortRow{
+			Dimensions: group.dims,
+			Installs:   group.installs,
+			Cost:       group.cost,
+			Days:       make([]CohortDay, len(q.Days)),
+		}
+		for i, n := range q.Days {
+			day := CohortDay{
+				Day:             n,
+				Complete:        !lastInstall.Add(time.Duration(n+1) * 24 * time.Hour).After(q.Now),
+				Revenue:         group.revenue[i],
+				Payers:          group.payers[i],
+				Retained:        group.retained[i],
+				RetentionEvents: group.retentionEvents[i],
+			}
+			if group.cost > 0 {
+				day.ROAS = day.Revenue / group.cost
+			}
+			if group.installs > 0 {
+				installs := float64(group.installs)
+				day.ARPU = day.Revenue / installs
+				day.PayerRate = float64(day.Payers) / installs * 100
+				day.RetentionRate = float64(day.Retained) / installs * 100
+			}
+			row.Days[i] = day
+		}
+		rows = append(rows, row)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		for _, d := range q.Dimensions {
+			if a, b := rows[i].Dimensions[d], rows[j].Dimensions[d]; a != b {
+				return a < b
+			}
+		}
+		return false
+	})
+
+	return &CohortResult{Dimensions: q.Dimensions, Days: q.Days, Rows: rows}
+}
+
+// cohortInstall is one tracked install in the in-memory store.
+type cohortInstall struct {
+	group  *cohortGroup
+	at     time.Time
+	payout float64
+	payer  []bool // Paid by each tracked day
+}
+
+// QueryCohorts builds cohorts from the installs in the query range. An
+// install is the first install conversion of a click; its later
+// conversions with the same click ID count towards it.
+func (s *InMemoryEventStore) QueryCohorts(ctx context.Context, q CohortQuery) (*CohortResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	groups := newCohortGroups(&q)
+	eventsEnd := q.eventsEnd()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// First install per click
+	first := make(map[string]*cohortInstall)
+	for _, conv := range s.conversions {
+		if conv.Event != InstallEvent || conv.ClickID == "" {
+			continue
+		}
+		if conv.Timestamp.Before(q.Start) || !conv.Timestamp.Before(q.End) {
+			continue
+		}
+		if !matchesAny(q.CampaignIDs, conv.CampaignID) || !matchesAny(q.SourceIDs, conv.SourceID) {
+			continue
+		}
+		if inst, ok := first[conv.ClickID]; ok && !conv.Timestamp.Before(inst.at) {
+			continue
+		}
+		first[conv.ClickID] = &cohortInstall{
+			group:  groups.get(conv.Timestamp.In(q.Location).Format("2006-01-02"), conv.CampaignID, conv.SourceID),
+			at:     conv.Timestamp,
+			payout: conv.PayoutUSD,
+			payer:  make([]bool, len(q.Days)),
+		}
+	}
+	for _, inst := range first {
+		inst.group.installs++
+		inst.group.cost += inst.payout
+	}
+
+	// Post-install events, including the install's own revenue
+	retainedOn := make(map[string]bool) // click + day index
+	for _, conv := range s.conversions {
+		inst, ok := first[conv.ClickID]
+		if !ok || conv.Timestamp.Before(inst.at) || !conv.Timestamp.Before(eventsEnd) {
+			continue
+		}
+		day := int(conv.Timestamp.Sub(inst.at) / (24 * time.Hour))
+		retention := containsString(q.RetentionEvents, conv.Event)
+		for i, n := range q.Days {
+			if day <= n {
+				inst.group.revenue[i] += conv.RevenueUSD
+				if conv.RevenueUSD > 0 && !inst.payer[i] {
+					inst.payer[i] = true
+					inst.group.payers[i]++
+				}
+			}
+			if day == n && retention {
+				inst.group.retentionEvents[i]++
+				key := fmt.Sprintf("%s\x00%d", conv.ClickID, i)
+				if !retainedOn[key] {
+					retainedOn[key] = true
+					inst.group.retained[i]++
+				}
+			}
+		}
+	}
+
+	// Media cost on the install dates
+	for _, win := range s.wins {
+		if win.Timestamp.Before(q.Start) || !win.Timestamp.Before(q.End) {
+			continue
+		}
+		if !matchesAny(q.CampaignIDs, win.CampaignID) || !matchesAny(q.SourceIDs, win.SourceID) {
+			continue
+		}
+		groups.get(win.Timestamp.In(q.Location).Format("2006-01-02"), win.CampaignID, win.SourceID).cost += win.WinPriceUSD
+	}
+
+	return groups.result(), nil
+}
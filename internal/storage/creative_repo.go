@@ -1,87 +1,227 @@
 package storage
 
 import (
-    "sync"
-    "github.com/radiusdt/vector-dsp/internal/models"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
 )
 
-// CreativeRepo defines CRUD operations for creatives.  In addition to
-// storing creatives globally, a separate repository allows campaigns to
-// reference shared creatives by ID.  In a real system creatives are
-// often stored in a CDN or asset store and metadata kept in a
-// database.
-type CreativeRepo interface {
-    GetCreative(id string) (*models.Creative, error)
-    ListCreatives() ([]*models.Creative, error)
-    UpsertCreative(c *models.Creative) error
-    ListCreativesByAdvertiser(advertiserID string) ([]*models.Creative, error)
-}
-
-// InMemoryCreativeRepo stores creatives in memory keyed by their ID.
-// An optional index by advertiser ID helps listing creatives per
-// advertiser.  For production you should persist creatives in a
-// database and upload assets to cloud storage.
+// =============================================
+// In-memory
+// =============================================
+
+// InMemoryCreativeRepo provides in-memory storage for the creative
+// library.
 type InMemoryCreativeRepo struct {
-    mu         sync.RWMutex
-    creatives  map[string]*models.Creative
+	mu        sync.RWMutex
+	creatives map[string]*models.Creative
 }
 
-// NewInMemoryCreativeRepo constructs an empty creative repository.
+// NewInMemoryCreativeRepo creates a new in-memory creative repository.
 func NewInMemoryCreativeRepo() *InMemoryCreativeRepo {
-    return &InMemoryCreativeRepo{
-        creatives: make(map[string]*models.Creative),
-    }
-}
-
-// GetCreative returns a creative by ID or nil if not found.
-func (r *InMemoryCreativeRepo) GetCreative(id string) (*models.Creative, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    if c, ok := r.creatives[id]; ok {
-        return c, nil
-    }
-    return nil, nil
-}
-
-// ListCreatives returns all creatives.
-func (r *InMemoryCreativeRepo) ListCreatives() ([]*models.Creative, error) {
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    res := make([]*models.Creative, 0, len(r.creatives))
-    for _, c := range r.creatives {
-        res = append(res, c)
-    }
-    return res, nil
-}
-
-// UpsertCreative inserts or updates the given creative.  A shallow
-// copy is stored to prevent external mutation.
-func (r *InMemoryCreativeRepo) UpsertCreative(c *models.Creative) error {
-    if c == nil {
-        return nil
-    }
-    r.mu.Lock()
-    defer r.mu.Unlock()
-    cp := *c
-    r.creatives[c.ID] = &cp
-    return nil
-}
-
-// ListCreativesByAdvertiser returns all creatives belonging to a given
-// advertiser.  When AdvertiserID is empty the entire collection is
-// returned.  For efficiency you should maintain an index per
-// advertiser; this implementation simply filters the in-memory map.
-func (r *InMemoryCreativeRepo) ListCreativesByAdvertiser(advertiserID string) ([]*models.Creative, error) {
-    if advertiserID == "" {
-        return r.ListCreatives()
-    }
-    r.mu.RLock()
-    defer r.mu.RUnlock()
-    var res []*models.Creative
-    for _, c := range r.creatives {
-        if c.AdvertiserID == advertiserID {
-            res = append(res, c)
-        }
-    }
-    return res, nil
-}
\ No newline at end of file
+	return &InMemoryCreativeRepo{
+		creatives: make(map[string]*models.Creative),
+	}
+}
+
+func (r *InMemoryCreativeRepo) ListAll(ctx context.Context) ([]*models.Creative, error) {
+	return r.list(func(c *models.Creative) bool { return true })
+}
+
+func (r *InMemoryCreativeRepo) ListByAdvertiser(ctx context.Context, advertiserID string) ([]*models.Creative, error) {
+	return r.list(func(c *models.Creative) bool { return c.AdvertiserID == advertiserID })
+}
+
+func (r *InMemoryCreativeRepo) GetByID(ctx context.Context, id string) (*models.Creative, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.creatives[id]
+	if !ok {
+		return nil, nil
+	}
+	var saved models.Creative
+	if err := copyJSON(&saved, c); err != nil {
+		return nil, fmt.Errorf("failed to copy creative: %w", err)
+	}
+	return &saved, nil
+}
+
+func (r *InMemoryCreativeRepo) Upsert(ctx context.Context, cr *models.Creative) error {
+	var saved models.Creative
+	if err := copyJSON(&saved, cr); err != nil {
+		return fmt.Errorf("failed to copy creative: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.creatives[cr.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryCreativeRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.creatives, id)
+	return nil
+}
+
+func (r *InMemoryCreativeRepo) UpdateStatus(ctx context.Context, id string, status string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c, ok := r.creatives[id]
+	if !ok {
+		return ErrNotFound
+	}
+	saved := *c
+	saved.AuditStatus = status
+	r.creatives[id] = &saved
+	return nil
+}
+
+// list returns deep copies of the creatives matching keep, ordered by ID.
+func (r *InMemoryCreativeRepo) list(keep func(c *models.Creative) bool) ([]*models.Creative, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.Creative, 0)
+	for _, c := range r.creatives {
+		if keep(c) {
+			var saved models.Creative
+			if err := copyJSON(&saved, c); err != nil {
+				return nil, fmt.Errorf("failed to copy creative: %w", err)
+			}
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// =============================================
+// PostgreSQL
+// =============================================
+
+// PostgresCreativeRepo implements CreativeRepo on the creatives table. The
+// creative is stored in the data column; the other columns are kept in
+// sync for queries and foreign keys.
+type PostgresCreativeRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCreativeRepo creates a new PostgreSQL creative repository.
+func NewPostgresCreativeRepo(pool *pgxpool.Pool) *PostgresCreativeRepo {
+	return &PostgresCreativeRepo{pool: pool}
+}
+
+func (r *PostgresCreativeRepo) ListAll(ctx context.Context) ([]*models.Creative, error) {
+	return r.query(ctx, `SELECT data FROM creatives ORDER BY id`)
+}
+
+func (r *PostgresCreativeRepo) ListByAdvertiser(ctx context.Context, advertiserID string) ([]*models.Creative, error) {
+	return r.query(ctx, `SELECT data FROM creatives WHERE COALESCE(advertiser_id, '') = $1 ORDER BY id`, advertiserID)
+}
+
+func (r *PostgresCreativeRepo) GetByID(ctx context.Context, id string) (*models.Creative, error) {
+	var data []byte
+	err := r.pool.QueryRow(ctx, `SELECT data FROM creatives WHERE id = $1`, id).Scan(&data)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get creative: %w", err)
+	}
+
+	var cr models.Creative
+	if err := json.Unmarshal(data, &cr); err != nil {
+		return nil, fmt.Errorf("failed to decode creative %s: %w", id, err)
+	}
+	return &cr, nil
+}
+
+func (r *PostgresCreativeRepo) Upsert(ctx context.Context, cr *models.Creative) error {
+	data, err := json.Marshal(cr)
+	if err != nil {
+		return fmt.Errorf("failed to encode creative: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO creatives (
+			id, advertiser_id, name, format, status, width, height, adm_template, video_url,
+			created_at, updated_at, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (id) DO UPDATE SET
+			advertiser_id = EXCLUDED.advertiser_id,
+			name = EXCLUDED.name,
+			format = EXCLUDED.format,
+			status = EXCLUDED.status,
+			width = EXCLUDED.width,
+			height = EXCLUDED.height,
+			adm_template = EXCLUDED.adm_template,
+			video_url = EXCLUDED.video_url,
+			created_at = EXCLUDED.created_at,
+			updated_at = EXCLUDED.updated_at,
+			data = EXCLUDED.data
+	`, cr.ID, nullString(cr.AdvertiserID), nullString(cr.Name), cr.Format, nullString(cr.AuditStatus),
+		cr.W, cr.H, nullString(cr.AdmTemplate), nullString(cr.VideoURL),
+		nullTime(cr.CreatedAt), nullTime(cr.UpdatedAt), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to upsert creative: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCreativeRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.pool.Exec(ctx, `DELETE FROM creatives WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete creative: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCreativeRepo) UpdateStatus(ctx context.Context, id string, status string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE creatives SET
+			status = $2,
+			data = jsonb_set(data, '{audit_status}', to_jsonb($2::text))
+		WHERE id = $1
+	`, id, status)
+	if err != nil {
+		return fmt.Errorf("failed to update creative status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *PostgresCreativeRepo) query(ctx context.Context, sql string, args ...interface{}) ([]*models.Creative, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list creatives: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Creative, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan creative: %w", err)
+		}
+		var cr models.Creative
+		if err := json.Unmarshal(data, &cr); err != nil {
+			return nil, fmt.Errorf("failed to decode creative: %w", err)
+		}
+		result = append(result, &cr)
+	}
+	return result, rows.Err()
+}
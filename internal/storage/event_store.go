@@ -2,13 +2,21 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/radiusdt/vector-dsp/internal/models"
 )
 
-// InMemoryEventStore provides in-memory storage for events.
+// InMemoryEventStore provides in-memory storage for events. Saving an event
+// with the ID of a stored one replaces it.
 type InMemoryEventStore struct {
 	mu          sync.RWMutex
 	clicks      map[string]*models.Click
@@ -41,7 +49,14 @@ func (s *InMemoryEventStore) SaveClick(ctx context.Context, click *models.Click)
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.clicks[click.ID] = click
+	if existing, ok := s.clicks[click.ID]; ok {
+		s.clicksByDevice[existing.DeviceIFA] = removeID(s.clicksByDevice[existing.DeviceIFA], click.ID)
+		if len(s.clicksByDevice[existing.DeviceIFA]) == 0 {
+			delete(s.clicksByDevice, existing.DeviceIFA)
+		}
+	}
+	saved := *click
+	s.clicks[click.ID] = &saved
 
 	// Update device index
 	if click.DeviceIFA != "" {
@@ -59,25 +74,28 @@ func (s *InMemoryEventStore) GetClick(ctx context.Context, id string) (*models.C
 	if !ok {
 		return nil, nil
 	}
-	return click, nil
+	saved := *click
+	return &saved, nil
 }
 
 func (s *InMemoryEventStore) GetClicksByDevice(ctx context.Context, deviceIFA string, since time.Time) ([]*models.Click, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	clickIDs, ok := s.clicksByDevice[deviceIFA]
-	if !ok {
-		return nil, nil
-	}
-
 	result := make([]*models.Click, 0)
-	for _, id := range clickIDs {
+	for _, id := range s.clicksByDevice[deviceIFA] {
 		click := s.clicks[id]
 		if click != nil && click.Timestamp.After(since) {
-			result = append(result, click)
+			saved := *click
+			result = append(result, &saved)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Timestamp.Equal(result[j].Timestamp) {
+			return result[i].Timestamp.Before(result[j].Timestamp)
+		}
+		return result[i].ID < result[j].ID
+	})
 	return result, nil
 }
 
@@ -89,7 +107,8 @@ func (s *InMemoryEventStore) SaveImpression(ctx context.Context, imp *models.Imp
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.impressions[imp.ID] = imp
+	saved := *imp
+	s.impressions[imp.ID] = &saved
 	return nil
 }
 
@@ -101,7 +120,8 @@ func (s *InMemoryEventStore) GetImpression(ctx context.Context, id string) (*mod
 	if !ok {
 		return nil, nil
 	}
-	return imp, nil
+	saved := *imp
+	return &saved, nil
 }
 
 // =============================================
@@ -112,7 +132,14 @@ func (s *InMemoryEventStore) SaveConversion(ctx context.Context, conv *models.Co
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.conversions[conv.ID] = conv
+	if existing, ok := s.conversions[conv.ID]; ok {
+		s.conversionsByClick[existing.ClickID] = removeID(s.conversionsByClick[existing.ClickID], conv.ID)
+		if len(s.conversionsByClick[existing.ClickID]) == 0 {
+			delete(s.conversionsByClick, existing.ClickID)
+		}
+	}
+	saved := *conv
+	s.conversions[conv.ID] = &saved
 
 	// Update click index
 	if conv.ClickID != "" {
@@ -130,24 +157,27 @@ func (s *InMemoryEventStore) GetConversion(ctx context.Context, id string) (*mod
 	if !ok {
 		return nil, nil
 	}
-	return conv, nil
+	saved := *conv
+	return &saved, nil
 }
 
 func (s *InMemoryEventStore) GetConversionsByClick(ctx context.Context, clickID string) ([]*models.Conversion, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	convIDs, ok := s.conversionsByClick[clickID]
-	if !ok {
-		return nil, nil
-	}
-
-	result := make([]*models.Conversion, 0, len(convIDs))
-	for _, id := range convIDs {
+	result := make([]*models.Conversion, 0)
+	for _, id := range s.conversionsByClick[clickID] {
 		if conv, ok := s.conversions[id]; ok {
-			result = append(result, conv)
+			saved := *conv
+			result = append(result, &saved)
 		}
 	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].Timestamp.Equal(result[j].Timestamp) {
+			return result[i].Timestamp.Before(result[j].Timestamp)
+		}
+		return result[i].ID < result[j].ID
+	})
 	return result, nil
 }
 
@@ -159,7 +189,8 @@ func (s *InMemoryEventStore) SaveWin(ctx context.Context, win *models.Win) error
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.wins[win.ID] = win
+	saved := *win
+	s.wins[win.ID] = &saved
 	return nil
 }
 
@@ -208,6 +239,20 @@ func (s *InMemoryEventStore) GetConversionCount(ctx context.Context, campaignID
 	return count, nil
 }
 
+// =============================================
+// Buffering
+// =============================================
+
+// Flush is a no-op; writes are applied immediately.
+func (s *InMemoryEventStore) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op; writes are applied immediately.
+func (s *InMemoryEventStore) Close(ctx context.Context) error {
+	return nil
+}
+
 // =============================================
 // Cleanup (for TTL)
 // =============================================
@@ -230,20 +275,10 @@ func (s *InMemoryEventStore) CleanupOldClicks(ctx context.Context, before time.T
 		delete(s.clicks, id)
 
 		// Clean up device index
-		if click.DeviceIFA != "" {
-			if ids, ok := s.clicksByDevice[click.DeviceIFA]; ok {
-				newIDs := make([]string, 0)
-				for _, cid := range ids {
-					if cid != id {
-						newIDs = append(newIDs, cid)
-					}
-				}
-				if len(newIDs) > 0 {
-					s.clicksByDevice[click.DeviceIFA] = newIDs
-				} else {
-					delete(s.clicksByDevice, click.DeviceIFA)
-				}
-			}
+		if ids := removeID(s.clicksByDevice[click.DeviceIFA], id); len(ids) > 0 {
+			s.clicksByDevice[click.DeviceIFA] = ids
+		} else {
+			delete(s.clicksByDevice, click.DeviceIFA)
 		}
 		count++
 	}
@@ -251,18 +286,363 @@ func (s *InMemoryEventStore) CleanupOldClicks(ctx context.Context, before time.T
 	return count, nil
 }
 
+// removeID returns ids without id.
+func removeID(ids []string, id string) []string {
+	result := make([]string, 0, len(ids))
+	for _, existing := range ids {
+		if existing != id {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
+
 // =============================================
-// PostgresEventStore (stub)
+// PostgresEventStore
 // =============================================
 
-// PostgresEventStore provides PostgreSQL storage for events.
+// PostgresEventStore implements EventStore, ReportStore and CohortStore on
+// the clicks, impressions, conversions and wins tables, and BidSampleStore
+// on bid_samples. Events are stored in the data column; the columns the
+// queries filter on are kept in sync. High-volume deployments use
+// ClickHouseEventStore instead.
 type PostgresEventStore struct {
-	// pool *pgxpool.Pool
+	pool *pgxpool.Pool
 }
 
 // NewPostgresEventStore creates a new PostgreSQL event store.
-func NewPostgresEventStore(pool interface{}) *InMemoryEventStore {
-	// TODO: Implement PostgreSQL storage
-	// For now, return in-memory
-	return NewInMemoryEventStore()
+func NewPostgresEventStore(pool *pgxpool.Pool) *PostgresEventStore {
+	return &PostgresEventStore{pool: pool}
+}
+
+func (s *PostgresEventStore) SaveClick(ctx context.Context, click *models.Click) error {
+	data, err := json.Marshal(click)
+	if err != nil {
+		return fmt.Errorf("failed to encode click: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO clicks (
+			id, timestamp, campaign_id, line_item_id, creative_id, source_type, source_id, device_ifa, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (id) DO UPDATE SET
+			timestamp = EXCLUDED.timestamp,
+			campaign_id = EXCLUDED.campaign_id,
+			line_item_id = EXCLUDED.line_item_id,
+			creative_id = EXCLUDED.creative_id,
+			source_type = EXCLUDED.source_type,
+			source_id = EXCLUDED.source_id,
+			device_ifa = EXCLUDED.device_ifa,
+			data = EXCLUDED.data
+	`, click.ID, click.Timestamp.UTC(), nullString(click.CampaignID), nullString(click.LineItemID),
+		nullString(click.CreativeID), nullString(click.SourceType), nullString(click.SourceID),
+		nullString(click.DeviceIFA), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save click: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresEventStore) GetClick(ctx context.Context, id string) (*models.Click, error) {
+	var click models.Click
+	found, err := s.get(ctx, `SELECT data FROM clicks WHERE id = $1`, id, &click)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &click, nil
+}
+
+func (s *PostgresEventStore) GetClicksByDevice(ctx context.Context, deviceIFA string, since time.Time) ([]*models.Click, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT data FROM clicks
+		WHERE device_ifa = $1 AND timestamp > $2
+		ORDER BY timestamp, id
+	`, deviceIFA, since.UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list clicks: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Click, 0)
+	for rows.Next() {
+		var click models.Click
+		if err := scanEvent(rows, &click); err != nil {
+			return nil, err
+		}
+		result = append(result, &click)
+	}
+	return result, rows.Err()
+}
+
+func (s *PostgresEventStore) SaveImpression(ctx context.Context, imp *models.Impression) error {
+	data, err := json.Marshal(imp)
+	if err != nil {
+		return fmt.Errorf("failed to encode impression: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO impressions (
+			id, timestamp, campaign_id, line_item_id, creative_id, source_type, source_id, bid_request_id, device_ifa, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			timestamp = EXCLUDED.timestamp,
+			campaign_id = EXCLUDED.campaign_id,
+			line_item_id = EXCLUDED.line_item_id,
+			creative_id = EXCLUDED.creative_id,
+			source_type = EXCLUDED.source_type,
+			source_id = EXCLUDED.source_id,
+			bid_request_id = EXCLUDED.bid_request_id,
+			device_ifa = EXCLUDED.device_ifa,
+			data = EXCLUDED.data
+	`, imp.ID, imp.Timestamp.UTC(), nullString(imp.CampaignID), nullString(imp.LineItemID),
+		nullString(imp.CreativeID), nullString(imp.SourceType), nullString(imp.SourceID),
+		nullString(imp.BidRequestID), nullString(imp.DeviceIFA), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save impression: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresEventStore) GetImpression(ctx context.Context, id string) (*models.Impression, error) {
+	var imp models.Impression
+	found, err := s.get(ctx, `SELECT data FROM impressions WHERE id = $1`, id, &imp)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+func (s *PostgresEventStore) SaveConversion(ctx context.Context, conv *models.Conversion) error {
+	data, err := json.Marshal(conv)
+	if err != nil {
+		return fmt.Errorf("failed to encode conversion: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO conversions (
+			id, timestamp, click_id, source_type, source_id, campaign_id, line_item_id, creative_id, event, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (id) DO UPDATE SET
+			timestamp = EXCLUDED.timestamp,
+			click_id = EXCLUDED.click_id,
+			source_type = EXCLUDED.source_type,
+			source_id = EXCLUDED.source_id,
+			campaign_id = EXCLUDED.campaign_id,
+			line_item_id = EXCLUDED.line_item_id,
+			creative_id = EXCLUDED.creative_id,
+			event = EXCLUDED.event,
+			data = EXCLUDED.data
+	`, conv.ID, conv.Timestamp.UTC(), nullString(conv.ClickID), nullString(conv.SourceType),
+		nullString(conv.SourceID), nullString(conv.CampaignID), nullString(conv.LineItemID),
+		nullString(conv.CreativeID), conv.Event, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save conversion: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresEventStore) GetConversion(ctx context.Context, id string) (*models.Conversion, error) {
+	var conv models.Conversion
+	found, err := s.get(ctx, `SELECT data FROM conversions WHERE id = $1`, id, &conv)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &conv, nil
+}
+
+func (s *PostgresEventStore) GetConversionsByClick(ctx context.Context, clickID string) ([]*models.Conversion, error) {
+	rows, err := s.pool.Query(ctx, `
+		SELECT data FROM conversions
+		WHERE click_id = $1
+		ORDER BY timestamp, id
+	`, clickID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversions: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.Conversion, 0)
+	for rows.Next() {
+		var conv models.Conversion
+		if err := scanEvent(rows, &conv); err != nil {
+			return nil, err
+		}
+		result = append(result, &conv)
+	}
+	return result, rows.Err()
+}
+
+func (s *PostgresEventStore) SaveWin(ctx context.Context, win *models.Win) error {
+	data, err := json.Marshal(win)
+	if err != nil {
+		return fmt.Errorf("failed to encode win: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO wins (
+			id, timestamp, bid_request_id, campaign_id, line_item_id, creative_id, source_id, data
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			timestamp = EXCLUDED.timestamp,
+			bid_request_id = EXCLUDED.bid_request_id,
+			campaign_id = EXCLUDED.campaign_id,
+			line_item_id = EXCLUDED.line_item_id,
+			creative_id = EXCLUDED.creative_id,
+			source_id = EXCLUDED.source_id,
+			data = EXCLUDED.data
+	`, win.ID, win.Timestamp.UTC(), nullString(win.BidRequestID), nullString(win.CampaignID),
+		nullString(win.LineItemID), nullString(win.CreativeID), nullString(win.SourceID), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save win: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresEventStore) GetClickCount(ctx context.Context, campaignID string, since time.Time) (int64, error) {
+	return s.count(ctx, `
+		SELECT COUNT(*) FROM clicks WHERE campaign_id = $1 AND timestamp > $2
+	`, campaignID, since.UTC())
+}
+
+func (s *PostgresEventStore) GetImpressionCount(ctx context.Context, campaignID string, since time.Time) (int64, error) {
+	return s.count(ctx, `
+		SELECT COUNT(*) FROM impressions WHERE campaign_id = $1 AND timestamp > $2
+	`, campaignID, since.UTC())
+}
+
+func (s *PostgresEventStore) GetConversionCount(ctx context.Context, campaignID string, event string, since time.Time) (int64, error) {
+	return s.count(ctx, `
+		SELECT COUNT(*) FROM conversions
+		WHERE campaign_id = $1 AND timestamp > $2 AND ($3 = '' OR event = $3)
+	`, campaignID, since.UTC(), event)
+}
+
+func (s *PostgresEventStore) SaveBidSample(ctx context.Context, sample *models.BidSample) error {
+	data, err := json.Marshal(sample)
+	if err != nil {
+		return fmt.Errorf("failed to encode bid sample: %w", err)
+	}
+
+	_, err = s.pool.Exec(ctx, `
+		INSERT INTO bid_samples (id, timestamp, source_id, bid, campaign_ids, line_item_ids, data)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (id) DO UPDATE SET
+			timestamp = EXCLUDED.timestamp,
+			source_id = EXCLUDED.source_id,
+			bid = EXCLUDED.bid,
+			campaign_ids = EXCLUDED.campaign_ids,
+			line_item_ids = EXCLUDED.line_item_ids,
+			data = EXCLUDED.data
+	`, sample.ID, sample.Timestamp.UTC(), nullString(sample.SourceID), sample.Response != nil,
+		sample.CampaignIDs(), sample.LineItemIDs(), string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save bid sample: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresEventStore) GetBidSample(ctx context.Context, id string) (*models.BidSample, error) {
+	var sample models.BidSample
+	found, err := s.get(ctx, `SELECT data FROM bid_samples WHERE id = $1`, id, &sample)
+	if err != nil || !found {
+		return nil, err
+	}
+	return &sample, nil
+}
+
+func (s *PostgresEventStore) ListBidSamples(ctx context.Context, filter BidSampleFilter) ([]*models.BidSample, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultBidSampleLimit
+	}
+
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.SourceID != "" {
+		add("source_id = $%d", filter.SourceID)
+	}
+	if filter.CampaignID != "" {
+		add("$%d = ANY(campaign_ids)", filter.CampaignID)
+	}
+	if filter.LineItemID != "" {
+		add("$%d = ANY(line_item_ids)", filter.LineItemID)
+	}
+	if filter.NoBidOnly {
+		conds = append(conds, "NOT bid")
+	}
+	if !filter.Since.IsZero() {
+		add("timestamp >= $%d", filter.Since.UTC())
+	}
+	args = append(args, limit)
+
+	rows, err := s.pool.Query(ctx, `
+		SELECT data FROM bid_samples
+		WHERE `+strings.Join(conds, " AND ")+`
+		ORDER BY timestamp DESC, id DESC
+		LIMIT $`+strconv.Itoa(len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list bid samples: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.BidSample, 0)
+	for rows.Next() {
+		var sample models.BidSample
+		if err := scanEvent(rows, &sample); err != nil {
+			return nil, err
+		}
+		result = append(result, &sample)
+	}
+	return result, rows.Err()
+}
+
+// Flush is a no-op; writes are applied immediately.
+func (s *PostgresEventStore) Flush(ctx context.Context) error {
+	return nil
+}
+
+// Close is a no-op; the pool is owned by the caller.
+func (s *PostgresEventStore) Close(ctx context.Context) error {
+	return nil
+}
+
+// get decodes the data column of the row sql selects into event. It reports
+// false when there is no such row.
+func (s *PostgresEventStore) get(ctx context.Context, sql, id string, event interface{}) (bool, error) {
+	err := scanEvent(s.pool.QueryRow(ctx, sql, id), event)
+	if err == pgx.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *PostgresEventStore) count(ctx context.Context, sql string, args ...interface{}) (int64, error) {
+	var count int64
+	if err := s.pool.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+	return count, nil
+}
+
+// scanEvent decodes the data column of row into event. pgx.ErrNoRows is
+// returned unwrapped.
+func scanEvent(row pgx.Row, event interface{}) error {
+	var data []byte
+	if err := row.Scan(&data); err != nil {
+		if err == pgx.ErrNoRows {
+			return err
+		}
+		return fmt.Errorf("failed to scan event: %w", err)
+	}
+	if err := json.Unmarshal(data, event); err != nil {
+		return fmt.Errorf("failed to decode event: %w", err)
+	}
+	return nil
 }
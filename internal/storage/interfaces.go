@@ -2,16 +2,29 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/radiusdt/vector-dsp/internal/models"
 )
 
+// The campaign, advertiser, ad group and creative repositories and the
+// event store have in-memory and PostgreSQL implementations that pass the
+// same conformance suite, storagetest.Run. Get methods return nil without
+// an error when nothing matches, lists are ordered by ID, Upsert replaces
+// the stored object (timestamps are set by the caller) and deleting a
+// missing object is not an error.
+
+// ErrNotFound is returned by updates of a single field of an object that
+// doesn't exist.
+var ErrNotFound = errors.New("not found")
+
 // =============================================
 // CAMPAIGN REPOSITORY
 // =============================================
 
-// CampaignRepo defines operations for campaign storage.
+// CampaignRepo defines operations for campaign storage. A campaign is
+// stored with its line items and their creatives.
 type CampaignRepo interface {
 	// Basic CRUD
 	ListAll(ctx context.Context) ([]*models.Campaign, error)
@@ -22,20 +35,25 @@ type CampaignRepo interface {
 	// Queries
 	GetByAdvertiser(ctx context.Context, advertiserID string) ([]*models.Campaign, error)
 	GetActive(ctx context.Context) ([]*models.Campaign, error)
-	GetByStatus(ctx context.Context, status string) ([]*models.Campaign, error)
+	GetByStatus(ctx context.Context, status models.CampaignStatus) ([]*models.Campaign, error)
 }
 
 // =============================================
 // ADVERTISER REPOSITORY
 // =============================================
 
-// AdvertiserRepo defines operations for advertiser storage.
+// AdvertiserRepo defines operations for advertiser storage. Upsert keeps
+// the stored balance (zero for new advertisers); only UpdateBalance
+// changes it.
 type AdvertiserRepo interface {
 	ListAll(ctx context.Context) ([]*models.Advertiser, error)
 	GetByID(ctx context.Context, id string) (*models.Advertiser, error)
 	Upsert(ctx context.Context, a *models.Advertiser) error
 	Delete(ctx context.Context, id string) error
-	UpdateBalance(ctx context.Context, id string, delta float64) error
+
+	// UpdateBalance adds delta to the balance and returns the new balance;
+	// ErrNotFound if the advertiser doesn't exist
+	UpdateBalance(ctx context.Context, id string, delta float64) (float64, error)
 }
 
 // =============================================
@@ -64,11 +82,26 @@ type SourceRepo interface {
 	DeleteCampaignSource(ctx context.Context, id string) error
 }
 
+// =============================================
+// PAYOUT RULE REPOSITORY
+// =============================================
+
+// PayoutRuleRepo defines operations for payout rule storage.
+type PayoutRuleRepo interface {
+	ListAll(ctx context.Context) ([]*models.PayoutRule, error)
+	ListByCampaign(ctx context.Context, campaignID string) ([]*models.PayoutRule, error)
+	GetByID(ctx context.Context, id string) (*models.PayoutRule, error)
+	Upsert(ctx context.Context, rule *models.PayoutRule) error
+	Delete(ctx context.Context, id string) error
+}
+
 // =============================================
 // EVENT STORE
 // =============================================
 
-// EventStore defines operations for event storage (clicks, impressions, conversions).
+// EventStore defines operations for event storage (clicks, impressions,
+// conversions). Events of a device or click are listed oldest first; since
+// is exclusive.
 type EventStore interface {
 	// Clicks
 	SaveClick(ctx context.Context, click *models.Click) error
@@ -93,6 +126,295 @@ type EventStore interface {
 	GetConversionCount(ctx context.Context, campaignID string, event string, since time.Time) (int64, error)
 }
 
+// BufferedEventStore is an EventStore that buffers writes. Close flushes the
+// buffer and must be called on shutdown. InMemoryEventStore implements it
+// without buffering, for running services offline.
+type BufferedEventStore interface {
+	EventStore
+	Flush(ctx context.Context) error
+	Close(ctx context.Context) error
+}
+
+// ReportStore runs grouped reports over stored events. See ReportQuery.
+type ReportStore interface {
+	QueryReport(ctx context.Context, q ReportQuery) (*ReportResult, error)
+}
+
+// CohortStore runs install cohort reports over stored events. See CohortQuery.
+type CohortStore interface {
+	QueryCohorts(ctx context.Context, q CohortQuery) (*CohortResult, error)
+}
+
+// =============================================
+// BID SAMPLE STORE
+// =============================================
+
+// BidSampleStore defines operations for sampled bid requests.
+type BidSampleStore interface {
+	SaveBidSample(ctx context.Context, sample *models.BidSample) error
+	GetBidSample(ctx context.Context, id string) (*models.BidSample, error)
+	ListBidSamples(ctx context.Context, filter BidSampleFilter) ([]*models.BidSample, error)
+}
+
+// BidSampleFilter for querying bid samples. Samples are returned newest first.
+type BidSampleFilter struct {
+	SourceID   string
+	CampaignID string // Samples where the campaign was evaluated
+	LineItemID string // Samples where the line item was evaluated
+	NoBidOnly  bool
+	Since      time.Time
+	Limit      int
+}
+
+// =============================================
+// UNPRICED EVENT REPOSITORY
+// =============================================
+
+// UnpricedEventRepo holds wins and conversions until their amounts can be
+// converted to the reporting currency. Events are listed oldest first.
+type UnpricedEventRepo interface {
+	// Hold stores an event, replacing a held event with the same ID.
+	Hold(ctx context.Context, e *models.UnpricedEvent) error
+	List(ctx context.Context, limit int) ([]*models.UnpricedEvent, error)
+	// Release deletes a held event and reports whether it was still held,
+	// so that of several workers only one records it.
+	Release(ctx context.Context, id string) (bool, error)
+}
+
+// =============================================
+// SCHEDULED REPORT REPOSITORY
+// =============================================
+
+// ScheduledReportRepo defines operations for saved report definitions and
+// their run history.
+type ScheduledReportRepo interface {
+	List(ctx context.Context, advertiserID string) ([]*models.ScheduledReport, error) // All when advertiserID is empty
+	ListDue(ctx context.Context, now time.Time) ([]*models.ScheduledReport, error)
+	GetByID(ctx context.Context, id string) (*models.ScheduledReport, error)
+	Upsert(ctx context.Context, report *models.ScheduledReport) error
+	Delete(ctx context.Context, id string) error
+
+	// Runs
+	SaveRun(ctx context.Context, run *models.ReportRun) error
+	GetRun(ctx context.Context, id string) (*models.ReportRun, error)
+	ListRuns(ctx context.Context, reportID string, limit int) ([]*models.ReportRun, error) // Newest first
+	ListRetryableRuns(ctx context.Context, now time.Time) ([]*models.ReportRun, error)
+}
+
+// =============================================
+// BILLING REPOSITORY
+// =============================================
+
+// BillingRepo stores the advertiser billing ledger. Apply is the only way
+// the ledger changes an advertiser's balance.
+type BillingRepo interface {
+	// Apply adds tx.Amount to the advertiser's balance and records tx with
+	// the resulting BalanceAfter in one atomic step. It returns
+	// ErrDuplicateBillingReference when tx.Reference was already used by
+	// the advertiser.
+	Apply(ctx context.Context, tx *models.BillingTransaction) error
+	ListTransactions(ctx context.Context, filter BillingFilter) ([]*models.BillingTransaction, error)
+
+	// BalanceAt returns the advertiser's balance just before at
+	BalanceAt(ctx context.Context, advertiserID string, at time.Time) (float64, error)
+}
+
+// BillingFilter for querying ledger entries. Entries are returned oldest
+// first.
+type BillingFilter struct {
+	AdvertiserID string
+	Type         models.BillingTransactionType // Empty matches all
+	From         time.Time                     // Inclusive; zero is unbounded
+	To           time.Time                     // Exclusive; zero is unbounded
+	Limit        int                           // 0 is unlimited
+}
+
+// =============================================
+// BILLING DOCUMENT REPOSITORY
+// =============================================
+
+// BillingDocumentRepo stores closing documents, their numbering sequences
+// and closed periods.
+type BillingDocumentRepo interface {
+	ListDocuments(ctx context.Context, filter DocumentFilter) ([]*models.BillingDocument, error)
+	GetDocument(ctx context.Context, id string) (*models.BillingDocument, error)
+	UpsertDocument(ctx context.Context, doc *models.BillingDocument) error
+
+	// NextNumber returns the next number of the type's sequence for year,
+	// starting at 1
+	NextNumber(ctx context.Context, docType models.DocumentType, year int) (int64, error)
+
+	// Closing periods
+	ListClosedPeriods(ctx context.Context) ([]*models.ClosingPeriod, error)
+	GetClosedPeriod(ctx context.Context, period string) (*models.ClosingPeriod, error)
+	ClosePeriod(ctx context.Context, p *models.ClosingPeriod) error
+}
+
+// DocumentFilter for querying billing documents. Empty fields match all;
+// documents are returned by period, advertiser and type.
+type DocumentFilter struct {
+	AdvertiserID string
+	Period       string // YYYY-MM
+	Type         models.DocumentType
+}
+
+// =============================================
+// API KEY REPOSITORY
+// =============================================
+
+// APIKeyRepo defines operations for management API keys. Keys are looked up
+// by the hex SHA-256 hash of the key.
+type APIKeyRepo interface {
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	Get(ctx context.Context, id string) (*models.APIKey, error)
+	List(ctx context.Context, advertiserID string) ([]*models.APIKey, error) // "" lists all keys
+	Create(ctx context.Context, key *models.APIKey) error
+	UpdateStatus(ctx context.Context, id, status string) error
+
+	// TouchLastUsed sets last_used_at of each key ID
+	TouchLastUsed(ctx context.Context, used map[string]time.Time) error
+}
+
+// =============================================
+// USER REPOSITORY
+// =============================================
+
+// UserRepo defines operations for user accounts and their refresh token
+// sessions. Emails are matched case-insensitively.
+type UserRepo interface {
+	ListUsers(ctx context.Context) ([]*models.UserAccount, error)
+	GetUser(ctx context.Context, id string) (*models.UserAccount, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.UserAccount, error)
+	CreateUser(ctx context.Context, u *models.UserAccount) error // ErrDuplicateUserEmail if the email is taken
+	UpdateUser(ctx context.Context, u *models.UserAccount) error // ErrDuplicateUserEmail if the email is taken
+
+	// Sessions
+	CreateSession(ctx context.Context, sess *models.UserSession) error
+	GetSessionByHash(ctx context.Context, tokenHash string) (*models.UserSession, error)
+	RevokeSession(ctx context.Context, id string, at time.Time) (bool, error) // False if already revoked
+	RevokeUserSessions(ctx context.Context, userID string, at time.Time) error
+}
+
+// =============================================
+// AUDIT LOG REPOSITORY
+// =============================================
+
+// AuditRepo stores the append-only audit log. Entries are listed newest
+// first.
+type AuditRepo interface {
+	Append(ctx context.Context, e *models.AuditEntry) error
+	Get(ctx context.Context, id string) (*models.AuditEntry, error)
+	List(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
+}
+
+// AuditFilter selects audit log entries.
+type AuditFilter struct {
+	EntityType string
+	EntityID   string
+	ActorID    string
+	Action     string
+	From       time.Time // Inclusive; zero is unbounded
+	To         time.Time // Exclusive; zero is unbounded
+	Limit      int       // 0 is unlimited
+}
+
+// =============================================
+// CAMPAIGN TRANSITION REPOSITORY
+// =============================================
+
+// CampaignTransitionRepo stores the append-only log of campaign status
+// changes. Transitions are listed newest first.
+type CampaignTransitionRepo interface {
+	Append(ctx context.Context, t *models.CampaignTransition) error
+	List(ctx context.Context, filter TransitionFilter) ([]*models.CampaignTransition, error)
+}
+
+// TransitionFilter selects campaign transitions.
+type TransitionFilter struct {
+	CampaignID   string
+	AdvertiserID string
+	Reason       string
+	From         time.Time // Inclusive; zero is unbounded
+	To           time.Time // Exclusive; zero is unbounded
+	Limit        int       // 0 is unlimited
+}
+
+// =============================================
+// AUTOMATION RULE REPOSITORY
+// =============================================
+
+// AutomationRuleRepo stores automation rules and the log of their firings.
+// Firings are listed newest first.
+type AutomationRuleRepo interface {
+	List(ctx context.Context, advertiserID string) ([]*models.AutomationRule, error) // All when advertiserID is empty
+	GetByID(ctx context.Context, id string) (*models.AutomationRule, error)
+	Upsert(ctx context.Context, rule *models.AutomationRule) error
+	Delete(ctx context.Context, id string) error
+	AppendFiring(ctx context.Context, f *models.RuleFiring) error
+	ListFirings(ctx context.Context, filter RuleFiringFilter) ([]*models.RuleFiring, error)
+}
+
+// RuleFiringFilter selects rule firings.
+type RuleFiringFilter struct {
+	RuleID       string
+	AdvertiserID string
+	CampaignID   string
+	LineItemID   string
+	ScopeValue   string
+	From         time.Time // Inclusive; zero is unbounded
+	To           time.Time // Exclusive; zero is unbounded
+	Limit        int       // 0 is unlimited
+}
+
+// =============================================
+// ALERT REPOSITORY
+// =============================================
+
+// AlertRepo stores alert definitions and the alerts they raise. Alerts are
+// listed newest first.
+type AlertRepo interface {
+	ListDefinitions(ctx context.Context, advertiserID string) ([]*models.AlertDefinition, error) // All when advertiserID is empty
+	GetDefinition(ctx context.Context, id string) (*models.AlertDefinition, error)
+	UpsertDefinition(ctx context.Context, d *models.AlertDefinition) error
+	DeleteDefinition(ctx context.Context, id string) error
+	ListAlerts(ctx context.Context, filter AlertFilter) ([]*models.Alert, error)
+	GetAlert(ctx context.Context, id string) (*models.Alert, error)
+	UpsertAlert(ctx context.Context, a *models.Alert) error
+}
+
+// AlertFilter selects alerts.
+type AlertFilter struct {
+	DefinitionID string
+	Type         models.AlertType
+	Status       models.AlertStatus
+	AdvertiserID string
+	CampaignID   string
+	From         time.Time // first_seen_at, inclusive; zero is unbounded
+	To           time.Time // first_seen_at, exclusive; zero is unbounded
+	Limit        int       // 0 is unlimited
+}
+
+// =============================================
+// CAMPAIGN TEMPLATE AND DRAFT REPOSITORIES
+// =============================================
+
+// CampaignTemplateRepo stores campaign templates.
+type CampaignTemplateRepo interface {
+	List(ctx context.Context, advertiserID string) ([]*models.CampaignTemplate, error) // All when advertiserID is empty
+	GetByID(ctx context.Context, id string) (*models.CampaignTemplate, error)
+	Upsert(ctx context.Context, t *models.CampaignTemplate) error
+	Delete(ctx context.Context, id string) error
+}
+
+// CampaignDraftRepo stores the staged drafts of campaigns, at most one per
+// campaign.
+type CampaignDraftRepo interface {
+	List(ctx context.Context, advertiserID string) ([]*models.CampaignDraft, error) // All when advertiserID is empty
+	Get(ctx context.Context, campaignID string) (*models.CampaignDraft, error)
+	Upsert(ctx context.Context, d *models.CampaignDraft) error
+	Delete(ctx context.Context, campaignID string) error
+}
+
 // =============================================
 // AD GROUP REPOSITORY
 // =============================================
@@ -110,13 +432,16 @@ type AdGroupRepo interface {
 // CREATIVE REPOSITORY
 // =============================================
 
-// CreativeRepo defines operations for creative storage.
+// CreativeRepo defines operations for the creative library.
 type CreativeRepo interface {
 	ListAll(ctx context.Context) ([]*models.Creative, error)
 	ListByAdvertiser(ctx context.Context, advertiserID string) ([]*models.Creative, error)
 	GetByID(ctx context.Context, id string) (*models.Creative, error)
 	Upsert(ctx context.Context, cr *models.Creative) error
 	Delete(ctx context.Context, id string) error
+
+	// UpdateStatus sets the audit status; ErrNotFound if the creative
+	// doesn't exist
 	UpdateStatus(ctx context.Context, id string, status string) error
 }
 
@@ -130,6 +455,14 @@ type StatsRepo interface {
 	GetDailyStats(ctx context.Context, filter StatsFilter) ([]*DailyStats, error)
 	UpsertDailyStats(ctx context.Context, stats *DailyStats) error
 
+	// ReplaceDailyStats atomically replaces every row of the given date,
+	// so rolling up a day again is idempotent
+	ReplaceDailyStats(ctx context.Context, date time.Time, stats []*DailyStats) error
+
+	// Rollup watermark: events before it have been rolled up
+	GetRollupWatermark(ctx context.Context) (time.Time, error)
+	SetRollupWatermark(ctx context.Context, watermark time.Time) error
+
 	// Campaign stats
 	GetCampaignStats(ctx context.Context, campaignID string, startDate, endDate time.Time) (*CampaignStatsAgg, error)
 
@@ -140,15 +473,18 @@ type StatsRepo interface {
 // StatsFilter for querying stats.
 type StatsFilter struct {
 	CampaignID  string
+	CampaignIDs []string // Any of these campaigns; empty matches all
 	SourceType  string
 	SourceID    string
 	Country     string
-	StartDate   time.Time
-	EndDate     time.Time
-	GroupBy     []string // date, campaign_id, source_id, country
+	StartDate   time.Time // Inclusive date; zero is unbounded
+	EndDate     time.Time // Inclusive date; zero is unbounded
+	GroupBy     []string  // date, campaign_id, source_type, source_id, country; empty returns stored rows
 }
 
-// DailyStats represents aggregated daily statistics.
+// DailyStats represents aggregated daily statistics. Dates are UTC; the
+// rollup fills date, campaign, source and country and leaves the line
+// item, creative and OS blank. Events counts non-install conversions.
 type DailyStats struct {
 	Date        time.Time
 	CampaignID  string
@@ -0,0 +1,25 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"github.com/radiusdt/vector-dsp/internal/storage/storagetest"
+)
+
+func TestInMemory(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storagetest.Repos {
+		events := storage.NewInMemoryEventStore()
+		return storagetest.Repos{
+			Campaigns:   storage.NewInMemoryCampaignRepo(),
+			Advertisers: storage.NewInMemoryAdvertiserRepo(),
+			AdGroups:    storage.NewInMemoryAdGroupRepo(),
+			Creatives:   storage.NewInMemoryCreativeRepo(),
+			Events:      events,
+			Reports:     events,
+			Cohorts:     events,
+			BidSamples:  storage.NewInMemoryBidSampleStore(100),
+			Unpriced:    storage.NewInMemoryUnpricedEventRepo(),
+		}
+	})
+}
@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// InMemoryPayoutRuleRepo provides in-memory storage for payout rules.
+type InMemoryPayoutRuleRepo struct {
+	mu    sync.RWMutex
+	rules map[string]*models.PayoutRule
+}
+
+// NewInMemoryPayoutRuleRepo creates a new in-memory payout rule repository.
+func NewInMemoryPayoutRuleRepo() *InMemoryPayoutRuleRepo {
+	return &InMemoryPayoutRuleRepo{
+		rules: make(map[string]*models.PayoutRule),
+	}
+}
+
+func (r *InMemoryPayoutRuleRepo) ListAll(ctx context.Context) ([]*models.PayoutRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.PayoutRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		result = append(result, rule)
+	}
+	sortPayoutRules(result)
+	return result, nil
+}
+
+// ListByCampaign returns rules for the campaign plus campaign-agnostic rules.
+func (r *InMemoryPayoutRuleRepo) ListByCampaign(ctx context.Context, campaignID string) ([]*models.PayoutRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var result []*models.PayoutRule
+	for _, rule := range r.rules {
+		if rule.CampaignID == "" || rule.CampaignID == campaignID {
+			result = append(result, rule)
+		}
+	}
+	sortPayoutRules(result)
+	return result, nil
+}
+
+func (r *InMemoryPayoutRuleRepo) GetByID(ctx context.Context, id string) (*models.PayoutRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, nil
+	}
+	return rule, nil
+}
+
+func (r *InMemoryPayoutRuleRepo) Upsert(ctx context.Context, rule *models.PayoutRule) error {
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("invalid payout rule: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.rules[rule.ID]; ok {
+		rule.CreatedAt = existing.CreatedAt
+	} else if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	r.rules[rule.ID] = rule
+	return nil
+}
+
+func (r *InMemoryPayoutRuleRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.rules, id)
+	return nil
+}
+
+// sortPayoutRules orders rules by priority (desc) then ID for stable output.
+func sortPayoutRules(rules []*models.PayoutRule) {
+	sort.Slice(rules, func(i, j int) bool {
+		if rules[i].Priority != rules[j].Priority {
+			return rules[i].Priority > rules[j].Priority
+		}
+		return rules[i].ID < rules[j].ID
+	})
+}
+
+// PostgresPayoutRuleRepo implements PayoutRuleRepo on the payout_rules
+// table. Empty match keys are stored as NULL.
+type PostgresPayoutRuleRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresPayoutRuleRepo creates a new PostgreSQL-backed payout rule
+// repository.
+func NewPostgresPayoutRuleRepo(pool *pgxpool.Pool) *PostgresPayoutRuleRepo {
+	return &PostgresPayoutRuleRepo{pool: pool}
+}
+
+const payoutRuleColumns = `id, name, campaign_id, source_type, source_id, country, os, event,
+	type, amount, percent, tiers, currency, min_payout, max_payout,
+	effective_from, effective_to, priority, is_active, created_at, updated_at`
+
+const payoutRuleSelect = `SELECT id, COALESCE(name, ''), COALESCE(campaign_id, ''), COALESCE(source_type, ''),
+		COALESCE(source_id, ''), COALESCE(country, ''), COALESCE(os, ''), COALESCE(event, ''),
+		type, COALESCE(amount, 0)::float8, COALESCE(percent, 0)::float8, COALESCE(tiers, '[]'),
+		COALESCE(currency, ''), COALESCE(min_payout, 0)::float8, COALESCE(max_payout, 0)::float8,
+		effective_from, effective_to, COALESCE(priority, 0), COALESCE(is_active, false),
+		created_at, updated_at
+	FROM payout_rules`
+
+func scanPayoutRule(row pgx.Row) (*models.PayoutRule, error) {
+	var rule models.PayoutRule
+	var ruleType string
+	var tiers []byte
+	var createdAt, updatedAt *time.Time
+	err := row.Scan(&rule.ID, &rule.Name, &rule.CampaignID, &rule.SourceType, &rule.SourceID,
+		&rule.Country, &rule.OS, &rule.Event, &ruleType, &rule.Amount, &rule.Percent, &tiers,
+		&rule.Currency, &rule.MinPayout, &rule.MaxPayout, &rule.EffectiveFrom, &rule.EffectiveTo,
+		&rule.Priority, &rule.IsActive, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	rule.Type = models.PayoutType(ruleType)
+	if err := json.Unmarshal(tiers, &rule.Tiers); err != nil {
+		return nil, fmt.Errorf("failed to decode payout rule %s tiers: %w", rule.ID, err)
+	}
+	if len(rule.Tiers) == 0 {
+		rule.Tiers = nil
+	}
+	if createdAt != nil {
+		rule.CreatedAt = *createdAt
+	}
+	if updatedAt != nil {
+		rule.UpdatedAt = *updatedAt
+	}
+	return &rule, nil
+}
+
+func (r *PostgresPayoutRuleRepo) ListAll(ctx context.Context) ([]*models.PayoutRule, error) {
+	return r.query(ctx, payoutRuleSelect+` ORDER BY priority DESC, id`)
+}
+
+// ListByCampaign returns rules for the campaign plus campaign-agnostic rules.
+func (r *PostgresPayoutRuleRepo) ListByCampaign(ctx context.Context, campaignID string) ([]*models.PayoutRule, error) {
+	return r.query(ctx, payoutRuleSelect+`
+		WHERE campaign_id IS NULL OR campaign_id = $1
+		ORDER BY priority DESC, id`, campaignID)
+}
+
+func (r *PostgresPayoutRuleRepo) GetByID(ctx context.Context, id string) (*models.PayoutRule, error) {
+	rule, err := scanPayoutRule(r.pool.QueryRow(ctx, payoutRuleSelect+` WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payout rule: %w", err)
+	}
+	return rule, nil
+}
+
+func (r *PostgresPayoutRuleRepo) Upsert(ctx context.Context, rule *models.PayoutRule) error {
+	if err := rule.Validate(); err != nil {
+		return fmt.Errorf("invalid payout rule: %w", err)
+	}
+	tiers := rule.Tiers
+	if tiers == nil {
+		tiers = []models.PayoutTier{}
+	}
+	data, err := json.Marshal(tiers)
+	if err != nil {
+		return fmt.Errorf("failed to encode payout rule tiers: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if rule.CreatedAt.IsZero() {
+		rule.CreatedAt = now
+	}
+	rule.UpdatedAt = now
+
+	var effectiveFrom, effectiveTo *time.Time
+	if rule.EffectiveFrom != nil {
+		effectiveFrom = nullTime(*rule.EffectiveFrom)
+	}
+	if rule.EffectiveTo != nil {
+		effectiveTo = nullTime(*rule.EffectiveTo)
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO payout_rules (`+payoutRuleColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			campaign_id = EXCLUDED.campaign_id,
+			source_type = EXCLUDED.source_type,
+			source_id = EXCLUDED.source_id,
+			country = EXCLUDED.country,
+			os = EXCLUDED.os,
+			event = EXCLUDED.event,
+			type = EXCLUDED.type,
+			amount = EXCLUDED.amount,
+			percent = EXCLUDED.percent,
+			tiers = EXCLUDED.tiers,
+			currency = EXCLUDED.currency,
+			min_payout = EXCLUDED.min_payout,
+			max_payout = EXCLUDED.max_payout,
+			effective_from = EXCLUDED.effective_from,
+			effective_to = EXCLUDED.effective_to,
+			priority = EXCLUDED.priority,
+			is_active = EXCLUDED.is_active,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at
+	`, rule.ID, nullString(rule.Name), nullString(rule.CampaignID), nullString(rule.SourceType),
+		nullString(rule.SourceID), nullString(rule.Country), nullString(rule.OS), nullString(rule.Event),
+		string(rule.Type), rule.Amount, rule.Percent, string(data), nullString(rule.Currency),
+		rule.MinPayout, rule.MaxPayout, effectiveFrom, effectiveTo, rule.Priority, rule.IsActive,
+		rule.CreatedAt, rule.UpdatedAt).Scan(&rule.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert payout rule: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresPayoutRuleRepo) Delete(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM payout_rules WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete payout rule: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresPayoutRuleRepo) query(ctx context.Context, sql string, args ...interface{}) ([]*models.PayoutRule, error) {
+	rows, err := r.pool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payout rules: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.PayoutRule, 0)
+	for rows.Next() {
+		rule, err := scanPayoutRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan payout rule: %w", err)
+		}
+		result = append(result, rule)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// pgCohortColumns returns the install date, campaign and source columns of
+// a cohort query; dimensions that aren't grouped by are blank.
+func pgCohortColumns(q *CohortQuery, timestamp string, args *pgArgs) []string {
+	cols := make([]string, 0, len(CohortDimensions))
+	for _, d := range CohortDimensions {
+		expr := "''"
+		if containsString(q.Dimensions, d) {
+			switch d {
+			case CohortDimInstallDate:
+				expr = "to_char(" + pgLocalTime(timestamp, args.add(q.Location.String())+"::text") + ", 'YYYY-MM-DD')"
+			case CohortDimCampaign:
+				expr = "COALESCE(campaign_id, '')"
+			case CohortDimSource:
+				expr = "COALESCE(source_id, '')"
+			}
+		}
+		cols = append(cols, expr+" AS dim_"+d)
+	}
+	return cols
+}
+
+// pgCohortFilters returns the campaign and source conditions of q.
+func pgCohortFilters(q *CohortQuery, args *pgArgs) string {
+	filters := ""
+	if len(q.CampaignIDs) > 0 {
+		filters += " AND campaign_id = ANY(" + args.add(q.CampaignIDs) + "::text[])"
+	}
+	if len(q.SourceIDs) > 0 {
+		filters += " AND source_id = ANY(" + args.add(q.SourceIDs) + "::text[])"
+	}
+	return filters
+}
+
+// QueryCohorts builds cohorts in two queries, as ClickHouseEventStore
+// does: installs with their post-install events folded per install, then
+// media cost per cohort.
+func (s *PostgresEventStore) QueryCohorts(ctx context.Context, q CohortQuery) (*CohortResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	groups := newCohortGroups(&q)
+	dimAliases := []string{"dim_" + CohortDimInstallDate, "dim_" + CohortDimCampaign, "dim_" + CohortDimSource}
+
+	// Per install: the first install of each click, then sums over its
+	// events by tracked day (d is NULL for installs without later events)
+	perInstall := make([]string, 0, 3*len(q.Days))
+	perCohort := make([]string, 0, 4*len(q.Days))
+	for i, n := range q.Days {
+		perInstall = append(perInstall,
+			fmt.Sprintf("COALESCE(SUM(rev) FILTER (WHERE d >= 0 AND d <= %d), 0) AS rev_%d", n, i),
+			fmt.Sprintf("(COUNT(*) FILTER (WHERE d >= 0 AND d <= %d AND rev > 0) > 0)::int AS payer_%d", n, i),
+			fmt.Sprintf("COUNT(*) FILTER (WHERE d = %d AND retention) AS ret_%d", n, i),
+		)
+		perCohort = append(perCohort,
+			fmt.Sprintf("SUM(rev_%d)", i),
+			fmt.Sprintf("SUM(payer_%d)", i),
+			fmt.Sprintf("COUNT(*) FILTER (WHERE ret_%d > 0)", i),
+			fmt.Sprintf("SUM(ret_%d)::bigint", i),
+		)
+	}
+
+	var args pgArgs
+	start, end := args.add(q.Start.UTC()), args.add(q.End.UTC())
+	installs := "SELECT DISTINCT ON (click_id) click_id, timestamp AS install_ts, campaign_id, source_id," +
+		" COALESCE((data->>'payout_usd')::float8, 0) AS payout" +
+		" FROM conversions" +
+		" WHERE event = " + args.add(InstallEvent) + "::text AND click_id <> ''" +
+		" AND timestamp >= " + start + " AND timestamp < " + end + pgCohortFilters(&q, &args) +
+		" ORDER BY click_id, timestamp"
+	events := "SELECT click_id, timestamp, COALESCE((data->>'revenue_usd')::float8, 0) AS revenue," +
+		" event = ANY(" + args.add(q.RetentionEvents) + "::text[]) AS retention" +
+		" FROM conversions" +
+		" WHERE click_id <> ''" +
+		" AND timestamp >= " + start + " AND timestamp < " + args.add(q.eventsEnd().UTC())
+	joined := "SELECT i.click_id, i.install_ts, i.campaign_id, i.source_id, i.payout," +
+		" e.revenue AS rev, e.retention," +
+		" FLOOR(EXTRACT(EPOCH FROM e.timestamp - i.install_ts) / 86400)::int AS d" +
+		" FROM (" + installs + ") AS i" +
+		" LEFT JOIN (" + events + ") AS e ON e.click_id = i.click_id AND e.timestamp >= i.install_ts"
+	perInstallQuery := "SELECT click_id, install_ts, campaign_id, source_id, payout, " + strings.Join(perInstall, ", ") +
+		" FROM (" + joined + ") AS joined GROUP BY click_id, install_ts, campaign_id, source_id, payout"
+
+	query := "SELECT " + strings.Join(pgCohortColumns(&q, "install_ts", &args), ", ") +
+		", COUNT(*) AS installs, COALESCE(SUM(payout), 0) AS payout, " + strings.Join(perCohort, ", ") +
+		" FROM (" + perInstallQuery + ") AS per_install" +
+		" GROUP BY " + strings.Join(dimAliases, ", ")
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohorts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			date, campaign, source string
+			count                  int64
+			payout                 float64
+		)
+		n := len(q.Days)
+		revenue := make([]float64, n)
+		payers := make([]int64, n)
+		retained := make([]int64, n)
+		retentionEvents := make([]int64, n)
+		dest := []interface{}{&date, &campaign, &source, &count, &payout}
+		for i := 0; i < n; i++ {
+			dest = append(dest, &revenue[i], &payers[i], &retained[i], &retentionEvents[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan cohort row: %w", err)
+		}
+
+		group := groups.get(date, campaign, source)
+		group.installs += count
+		group.cost += payout
+		for i := 0; i < n; i++ {
+			group.revenue[i] += revenue[i]
+			group.payers[i] += payers[i]
+			group.retained[i] += retained[i]
+			group.retentionEvents[i] += retentionEvents[i]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cohort rows: %w", err)
+	}
+
+	// Media cost (USD win prices) on the install dates
+	var costArgs pgArgs
+	costQuery := "SELECT " + strings.Join(pgCohortColumns(&q, "timestamp", &costArgs), ", ") +
+		", COALESCE(SUM((data->>'win_price_usd')::float8), 0)" +
+		" FROM wins" +
+		" WHERE timestamp >= " + costArgs.add(q.Start.UTC()) + " AND timestamp < " + costArgs.add(q.End.UTC()) +
+		pgCohortFilters(&q, &costArgs) +
+		" GROUP BY " + strings.Join(dimAliases, ", ")
+
+	costRows, err := s.pool.Query(ctx, costQuery, costArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cohort cost: %w", err)
+	}
+	defer costRows.Close()
+
+	for costRows.Next() {
+		var date, campaign, source string
+		var cost float64
+		if err := costRows.Scan(&date, &campaign, &source, &cost); err != nil {
+			return nil, fmt.Errorf("failed to scan cohort cost: %w", err)
+		}
+		groups.get(date, campaign, source).cost += cost
+	}
+	if err := costRows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read cohort cost: %w", err)
+	}
+
+	return groups.result(), nil
+}
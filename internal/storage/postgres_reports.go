@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pgArgs collects the positional parameters of a query built in parts.
+type pgArgs []interface{}
+
+// add appends v and returns its placeholder.
+func (a *pgArgs) add(v interface{}) string {
+	*a = append(*a, v)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+// pgLocalTime converts a timestamp column, stored in UTC, to the time in
+// zone tz (a text placeholder).
+func pgLocalTime(column, tz string) string {
+	return "(" + column + " AT TIME ZONE 'UTC' AT TIME ZONE " + tz + ")"
+}
+
+// pgReportMetricExprs computes each metric from the summed m_* columns of
+// the per-table subqueries. Sums are NULL when no event matched.
+var pgReportMetricExprs = map[string]string{
+	ReportMetricImpressions: "COALESCE(SUM(m_impressions), 0)",
+	ReportMetricClicks:      "COALESCE(SUM(m_clicks), 0)",
+	ReportMetricConversions: "COALESCE(SUM(m_conversions), 0)",
+	ReportMetricInstalls:    "COALESCE(SUM(m_installs), 0)",
+	ReportMetricSpend:       "COALESCE(SUM(m_spend), 0)",
+	ReportMetricRevenue:     "COALESCE(SUM(m_revenue), 0)",
+	ReportMetricPayout:      "COALESCE(SUM(m_payout), 0)",
+	ReportMetricProfit:      "COALESCE(SUM(m_revenue), 0) - COALESCE(SUM(m_spend), 0)",
+	ReportMetricCTR:         "CASE WHEN SUM(m_impressions) > 0 THEN SUM(m_clicks) / SUM(m_impressions) * 100 ELSE 0 END",
+	ReportMetricCVR:         "CASE WHEN SUM(m_clicks) > 0 THEN SUM(m_conversions) / SUM(m_clicks) * 100 ELSE 0 END",
+	ReportMetricECPM:        "CASE WHEN SUM(m_impressions) > 0 THEN SUM(m_spend) / SUM(m_impressions) * 1000 ELSE 0 END",
+	ReportMetricECPC:        "CASE WHEN SUM(m_clicks) > 0 THEN SUM(m_spend) / SUM(m_clicks) ELSE 0 END",
+	ReportMetricECPA:        "CASE WHEN SUM(m_conversions) > 0 THEN SUM(m_spend) / SUM(m_conversions) ELSE 0 END",
+	ReportMetricROAS:        "CASE WHEN SUM(m_spend) > 0 THEN SUM(m_revenue) / SUM(m_spend) ELSE 0 END",
+}
+
+// pgReportTable is one event table feeding a report.
+type pgReportTable struct {
+	name    string
+	metrics string // m_impressions, m_clicks, m_conversions, m_installs, m_spend, m_revenue, m_payout
+}
+
+var (
+	pgReportImpressions = pgReportTable{"impressions",
+		"COUNT(*)::float8 AS m_impressions, 0::float8 AS m_clicks, 0::float8 AS m_conversions, 0::float8 AS m_installs, " +
+			"0::float8 AS m_spend, 0::float8 AS m_revenue, 0::float8 AS m_payout"}
+	pgReportWins = pgReportTable{"wins",
+		"0::float8 AS m_impressions, 0::float8 AS m_clicks, 0::float8 AS m_conversions, 0::float8 AS m_installs, " +
+			"COALESCE(SUM((e.data->>'win_price_usd')::float8), 0) AS m_spend, 0::float8 AS m_revenue, 0::float8 AS m_payout"}
+	pgReportClicks = pgReportTable{"clicks",
+		"0::float8 AS m_impressions, COUNT(*)::float8 AS m_clicks, 0::float8 AS m_conversions, 0::float8 AS m_installs, " +
+			"0::float8 AS m_spend, 0::float8 AS m_revenue, 0::float8 AS m_payout"}
+	pgReportConversions = pgReportTable{"conversions",
+		"0::float8 AS m_impressions, 0::float8 AS m_clicks, COUNT(*)::float8 AS m_conversions, " +
+			"(COUNT(*) FILTER (WHERE e.event = '" + InstallEvent + "'))::float8 AS m_installs, 0::float8 AS m_spend, " +
+			"COALESCE(SUM((e.data->>'revenue_usd')::float8), 0) AS m_revenue, " +
+			"COALESCE(SUM((e.data->>'payout_usd')::float8), 0) AS m_payout"}
+)
+
+// pgReportColumn returns the expression for a dimension in table (alias e);
+// tz is the timezone placeholder. Fields only kept in the data column are
+// read from it. Conversions take click-level dimensions from the joined
+// click (alias cl); wins are RTB and have no device or publisher, and only
+// impressions record app bundle and publisher.
+func pgReportColumn(dim, table, tz string) string {
+	if table == "wins" {
+		switch dim {
+		case ReportDimSourceType:
+			return "'" + winSourceType + "'"
+		case ReportDimOS, ReportDimAppBundle, ReportDimPublisher:
+			return "''"
+		}
+	}
+	if table != "impressions" && (dim == ReportDimAppBundle || dim == ReportDimPublisher) {
+		return "''"
+	}
+
+	switch dim {
+	case ReportDimDate:
+		return "to_char(" + pgLocalTime("e.timestamp", tz) + ", 'YYYY-MM-DD')"
+	case ReportDimHour:
+		return "to_char(" + pgLocalTime("e.timestamp", tz) + ", 'YYYY-MM-DD HH24:00')"
+	case ReportDimWeek:
+		return "to_char(date_trunc('week', " + pgLocalTime("e.timestamp", tz) + "), 'YYYY-MM-DD')"
+	case ReportDimMonth:
+		return "to_char(" + pgLocalTime("e.timestamp", tz) + ", 'YYYY-MM')"
+	case ReportDimCampaign:
+		return "COALESCE(e.campaign_id, '')"
+	case ReportDimLineItem:
+		return "COALESCE(e.line_item_id, '')"
+	case ReportDimCreative:
+		return "COALESCE(e.creative_id, '')"
+	case ReportDimSource:
+		return "COALESCE(e.source_id, '')"
+	case ReportDimSourceType:
+		return "COALESCE(e.source_type, '')"
+	}
+
+	alias := "e."
+	if table == "conversions" {
+		alias = "cl."
+	}
+	switch dim {
+	case ReportDimCountry:
+		return "COALESCE(" + alias + "data->>'geo_country', '')"
+	case ReportDimOS:
+		return "COALESCE(" + alias + "data->>'device_os', '')"
+	case ReportDimAppBundle:
+		return "COALESCE(e.data->>'app_bundle', '')"
+	default:
+		return "COALESCE(e.data->>'publisher_id', '')"
+	}
+}
+
+// QueryReport runs the report as one query the way ClickHouseEventStore
+// does: each event table is filtered and pre-grouped, the results are
+// unioned and grouped again, and sorting and pagination happen in
+// PostgreSQL. Dimensions sort bytewise, as in the other stores.
+func (s *PostgresEventStore) QueryReport(ctx context.Context, q ReportQuery) (*ReportResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	tables := make([]pgReportTable, 0, 4)
+	if q.reportNeeds(ReportMetricImpressions) {
+		tables = append(tables, pgReportImpressions)
+	}
+	if q.reportNeeds(ReportMetricSpend) {
+		tables = append(tables, pgReportWins)
+	}
+	if q.reportNeeds(ReportMetricClicks) {
+		tables = append(tables, pgReportClicks)
+	}
+	if q.reportNeeds(ReportMetricConversions, ReportMetricInstalls, ReportMetricRevenue, ReportMetricPayout) {
+		tables = append(tables, pgReportConversions)
+	}
+
+	dimAliases := make([]string, len(q.Dimensions))
+	for i, d := range q.Dimensions {
+		dimAliases[i] = "dim_" + d
+	}
+
+	var args pgArgs
+	subqueries := make([]string, 0, len(tables))
+	for _, t := range tables {
+		subqueries = append(subqueries, pgReportSubquery(&q, t, dimAliases, &args))
+	}
+
+	selects := append([]string{}, dimAliases...)
+	for _, m := range q.Metrics {
+		selects = append(selects, pgReportMetricExprs[m]+" AS "+m)
+	}
+	selects = append(selects, "COUNT(*) OVER () AS total_rows")
+
+	query := "SELECT " + strings.Join(selects, ", ") +
+		" FROM (" + strings.Join(subqueries, " UNION ALL ") + ") AS events"
+	if len(dimAliases) > 0 {
+		query += " GROUP BY " + strings.Join(dimAliases, ", ")
+	}
+
+	orderBy := make([]string, 0, len(dimAliases)+1)
+	if q.SortBy != "" {
+		sortCol := q.SortBy
+		if containsString(q.Dimensions, q.SortBy) {
+			sortCol = "dim_" + q.SortBy + ` COLLATE "C"`
+		}
+		if q.SortDesc {
+			sortCol += " DESC"
+		}
+		orderBy = append(orderBy, sortCol)
+	}
+	for _, a := range dimAliases {
+		orderBy = append(orderBy, a+` COLLATE "C"`)
+	}
+	if len(orderBy) > 0 {
+		query += " ORDER BY " + strings.Join(orderBy, ", ")
+	}
+	query += " LIMIT " + args.add(q.Limit) + " OFFSET " + args.add(q.Offset)
+
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report: %w", err)
+	}
+	defer rows.Close()
+
+	result := &ReportResult{
+		Dimensions: q.Dimensions,
+		Metrics:    q.Metrics,
+		Rows:       make([]ReportRow, 0),
+	}
+	for rows.Next() {
+		dims := make([]string, len(q.Dimensions))
+		values := make([]float64, len(q.Metrics))
+		var total int64
+		dest := make([]interface{}, 0, len(dims)+len(values)+1)
+		for i := range dims {
+			dest = append(dest, &dims[i])
+		}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		dest = append(dest, &total)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan report row: %w", err)
+		}
+
+		row := ReportRow{
+			Dimensions: make(map[string]string, len(dims)),
+			Metrics:    make(map[string]float64, len(values)),
+		}
+		for i, d := range q.Dimensions {
+			row.Dimensions[d] = dims[i]
+		}
+		for i, m := range q.Metrics {
+			row.Metrics[m] = values[i]
+		}
+		result.Rows = append(result.Rows, row)
+		result.Total = total
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read report rows: %w", err)
+	}
+
+	// Past the last page there are no rows to carry the total
+	if len(result.Rows) == 0 && q.Offset > 0 {
+		q.Offset = 0
+		q.Limit = 1
+		first, err := s.QueryReport(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = first.Total
+	}
+
+	return result, nil
+}
+
+// pgReportSubquery filters and pre-groups one event table, adding its
+// parameters to args.
+func pgReportSubquery(q *ReportQuery, t pgReportTable, dimAliases []string, args *pgArgs) string {
+	tz := ""
+	for _, d := range q.Dimensions {
+		if d == ReportDimDate || d == ReportDimHour || d == ReportDimWeek || d == ReportDimMonth {
+			tz = args.add(q.Location.String()) + "::text"
+			break
+		}
+	}
+
+	selects := make([]string, 0, len(q.Dimensions)+1)
+	for i, d := range q.Dimensions {
+		selects = append(selects, pgReportColumn(d, t.name, tz)+" AS "+dimAliases[i])
+	}
+	selects = append(selects, t.metrics)
+
+	from := t.name + " AS e"
+	if t.name == "conversions" && q.reportNeedsClick() {
+		from += " LEFT JOIN clicks AS cl ON cl.id = e.click_id"
+	}
+
+	where := []string{
+		"e.timestamp >= " + args.add(q.Start.UTC()),
+		"e.timestamp < " + args.add(q.End.UTC()),
+	}
+	filters := []struct {
+		dim    string
+		values []string
+	}{
+		{ReportDimCampaign, q.CampaignIDs},
+		{ReportDimLineItem, q.LineItemIDs},
+		{ReportDimCreative, q.CreativeIDs},
+		{ReportDimSource, q.SourceIDs},
+		{ReportDimCountry, q.Countries},
+		{ReportDimOS, q.OS},
+		{ReportDimAppBundle, q.AppBundles},
+		{ReportDimPublisher, q.PublisherIDs},
+	}
+	for _, f := range filters {
+		if len(f.values) > 0 {
+			where = append(where, pgReportColumn(f.dim, t.name, tz)+" = ANY("+args.add(f.values)+"::text[])")
+		}
+	}
+
+	sub := "SELECT " + strings.Join(selects, ", ") + " FROM " + from + " WHERE " + strings.Join(where, " AND ")
+	if len(dimAliases) > 0 {
+		sub += " GROUP BY " + strings.Join(dimAliases, ", ")
+	}
+	return sub
+}
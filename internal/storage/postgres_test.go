@@ -0,0 +1,105 @@
+package storage_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+	"github.com/radiusdt/vector-dsp/internal/storage/storagetest"
+)
+
+// postgresDSNEnv names the database the PostgreSQL test runs against. The
+// test creates a schema of its own there and drops it afterwards.
+const postgresDSNEnv = "VECTOR_DSP_TEST_DB_DSN"
+
+func TestPostgres(t *testing.T) {
+	dsn := os.Getenv(postgresDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", postgresDSNEnv)
+	}
+	ctx := context.Background()
+	pool := newTestPool(ctx, t, dsn)
+
+	storagetest.Run(t, func(t *testing.T) storagetest.Repos {
+		_, err := pool.Exec(ctx, `TRUNCATE advertisers, campaigns, creatives, ad_groups,
+			clicks, impressions, conversions, wins, bid_samples, unpriced_events CASCADE`)
+		if err != nil {
+			t.Fatalf("failed to truncate tables: %v", err)
+		}
+		events := storage.NewPostgresEventStore(pool)
+		return storagetest.Repos{
+			Campaigns:   storage.NewPostgresCampaignRepo(pool),
+			Advertisers: storage.NewPostgresAdvertiserRepo(pool),
+			AdGroups:    storage.NewPostgresAdGroupRepo(pool),
+			Creatives:   storage.NewPostgresCreativeRepo(pool),
+			Events:      events,
+			Reports:     events,
+			Cohorts:     events,
+			BidSamples:  events,
+			Unpriced:    storage.NewPostgresUnpricedEventRepo(pool),
+		}
+	})
+}
+
+// newTestPool creates a fresh schema, applies the migrations to it and
+// returns a pool using it.
+func newTestPool(ctx context.Context, t *testing.T, dsn string) *pgxpool.Pool {
+	t.Helper()
+	schema := fmt.Sprintf("storagetest_%d", time.Now().UnixNano())
+
+	admin, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(admin.Close)
+	if _, err := admin.Exec(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(context.Background(), `DROP SCHEMA `+schema+` CASCADE`); err != nil {
+			t.Errorf("failed to drop schema: %v", err)
+		}
+	})
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse DSN: %v", err)
+	}
+	cfg.ConnConfig.RuntimeParams["search_path"] = schema
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	// Migrations are numbered; the ClickHouse ones are not applied
+	files, err := filepath.Glob("../../migrations/0*.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	sort.Strings(files)
+
+	// A single connection, so the session settings and temporary
+	// functions of a migration last through it
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("failed to acquire connection: %v", err)
+	}
+	defer conn.Release()
+	for _, file := range files {
+		sql, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		if _, err := conn.Exec(ctx, string(sql)); err != nil {
+			t.Fatalf("failed to apply %s: %v", filepath.Base(file), err)
+		}
+	}
+	return pool
+}
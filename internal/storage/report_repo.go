@@ -0,0 +1,389 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// InMemoryScheduledReportRepo provides in-memory storage for scheduled
+// reports and their runs.
+type InMemoryScheduledReportRepo struct {
+	mu      sync.RWMutex
+	reports map[string]*models.ScheduledReport
+	runs    map[string]*models.ReportRun
+}
+
+// NewInMemoryScheduledReportRepo creates a new in-memory scheduled report repository.
+func NewInMemoryScheduledReportRepo() *InMemoryScheduledReportRepo {
+	return &InMemoryScheduledReportRepo{
+		reports: make(map[string]*models.ScheduledReport),
+		runs:    make(map[string]*models.ReportRun),
+	}
+}
+
+func (r *InMemoryScheduledReportRepo) List(ctx context.Context, advertiserID string) ([]*models.ScheduledReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.ScheduledReport, 0, len(r.reports))
+	for _, report := range r.reports {
+		if advertiserID == "" || report.AdvertiserID == advertiserID {
+			result = append(result, report)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// ListDue returns active reports whose next run is at or before now.
+func (r *InMemoryScheduledReportRepo) ListDue(ctx context.Context, now time.Time) ([]*models.ScheduledReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.ScheduledReport, 0)
+	for _, report := range r.reports {
+		if report.IsActive && !report.NextRunAt.IsZero() && !report.NextRunAt.After(now) {
+			result = append(result, report)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].NextRunAt.Before(result[j].NextRunAt) })
+	return result, nil
+}
+
+func (r *InMemoryScheduledReportRepo) GetByID(ctx context.Context, id string) (*models.ScheduledReport, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	report, ok := r.reports[id]
+	if !ok {
+		return nil, nil
+	}
+	return report, nil
+}
+
+func (r *InMemoryScheduledReportRepo) Upsert(ctx context.Context, report *models.ScheduledReport) error {
+	if err := report.Validate(); err != nil {
+		return fmt.Errorf("invalid scheduled report: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.reports[report.ID]; ok {
+		report.CreatedAt = existing.CreatedAt
+	} else if report.CreatedAt.IsZero() {
+		report.CreatedAt = now
+	}
+	report.UpdatedAt = now
+
+	r.reports[report.ID] = report
+	return nil
+}
+
+// Delete removes the report and its run history.
+func (r *InMemoryScheduledReportRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.reports, id)
+	for runID, run := range r.runs {
+		if run.ReportID == id {
+			delete(r.runs, runID)
+		}
+	}
+	return nil
+}
+
+// =============================================
+// Runs
+// =============================================
+
+func (r *InMemoryScheduledReportRepo) SaveRun(ctx context.Context, run *models.ReportRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Store a copy: the scheduler keeps updating run between saves
+	saved := *run
+	r.runs[run.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryScheduledReportRepo) GetRun(ctx context.Context, id string) (*models.ReportRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	run, ok := r.runs[id]
+	if !ok {
+		return nil, nil
+	}
+	return run, nil
+}
+
+func (r *InMemoryScheduledReportRepo) ListRuns(ctx context.Context, reportID string, limit int) ([]*models.ReportRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.ReportRun, 0)
+	for _, run := range r.runs {
+		if run.ReportID == reportID {
+			result = append(result, run)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].StartedAt.After(result[j].StartedAt) })
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+// ListRetryableRuns returns retrying runs whose next attempt is due.
+func (r *InMemoryScheduledReportRepo) ListRetryableRuns(ctx context.Context, now time.Time) ([]*models.ReportRun, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.ReportRun, 0)
+	for _, run := range r.runs {
+		if run.Status == models.ReportRunRetrying && run.NextAttemptAt != nil && !run.NextAttemptAt.After(now) {
+			retry := *run
+			result = append(result, &retry)
+		}
+	}
+	return result, nil
+}
+
+// =============================================
+// PostgreSQL
+// =============================================
+
+// PostgresScheduledReportRepo implements ScheduledReportRepo on the
+// scheduled_reports and report_runs tables. The filter and delivery
+// settings are kept as JSONB.
+type PostgresScheduledReportRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresScheduledReportRepo creates a new PostgreSQL-backed scheduled
+// report repository.
+func NewPostgresScheduledReportRepo(pool *pgxpool.Pool) *PostgresScheduledReportRepo {
+	return &PostgresScheduledReportRepo{pool: pool}
+}
+
+const scheduledReportColumns = `id, name, advertiser_id, filter, period, format, frequency, weekday, hour,
+	timezone, delivery, is_active, next_run_at, last_run_at, created_at, updated_at`
+
+const scheduledReportSelect = `SELECT id, name, COALESCE(advertiser_id, ''), filter, period, format, frequency,
+	weekday, hour, COALESCE(timezone, ''), delivery, COALESCE(is_active, false), next_run_at, last_run_at,
+	created_at, updated_at FROM scheduled_reports`
+
+func scanScheduledReport(row pgx.Row) (*models.ScheduledReport, error) {
+	var report models.ScheduledReport
+	var filter, delivery []byte
+	var weekday int16
+	err := row.Scan(&report.ID, &report.Name, &report.AdvertiserID, &filter, &report.Period, &report.Format,
+		&report.Frequency, &weekday, &report.Hour, &report.Timezone, &delivery, &report.IsActive,
+		&report.NextRunAt, &report.LastRunAt, &report.CreatedAt, &report.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	report.Weekday = time.Weekday(weekday)
+	report.Filter = json.RawMessage(filter)
+	if err := json.Unmarshal(delivery, &report.Delivery); err != nil {
+		return nil, fmt.Errorf("failed to decode report %s delivery: %w", report.ID, err)
+	}
+	return &report, nil
+}
+
+func (r *PostgresScheduledReportRepo) List(ctx context.Context, advertiserID string) ([]*models.ScheduledReport, error) {
+	query := scheduledReportSelect
+	args := []interface{}{}
+	if advertiserID != "" {
+		query += ` WHERE advertiser_id = $1`
+		args = append(args, advertiserID)
+	}
+	return r.queryReports(ctx, query+` ORDER BY id`, args...)
+}
+
+// ListDue returns active reports whose next run is at or before now.
+func (r *PostgresScheduledReportRepo) ListDue(ctx context.Context, now time.Time) ([]*models.ScheduledReport, error) {
+	return r.queryReports(ctx, scheduledReportSelect+` WHERE is_active AND next_run_at <= $1 ORDER BY next_run_at`, now)
+}
+
+func (r *PostgresScheduledReportRepo) queryReports(ctx context.Context, query string, args ...interface{}) ([]*models.ScheduledReport, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduled reports: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.ScheduledReport, 0)
+	for rows.Next() {
+		report, err := scanScheduledReport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan scheduled report: %w", err)
+		}
+		result = append(result, report)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresScheduledReportRepo) GetByID(ctx context.Context, id string) (*models.ScheduledReport, error) {
+	report, err := scanScheduledReport(r.pool.QueryRow(ctx, scheduledReportSelect+` WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get scheduled report: %w", err)
+	}
+	return report, nil
+}
+
+func (r *PostgresScheduledReportRepo) Upsert(ctx context.Context, report *models.ScheduledReport) error {
+	if err := report.Validate(); err != nil {
+		return fmt.Errorf("invalid scheduled report: %w", err)
+	}
+
+	filter := []byte(report.Filter)
+	if len(filter) == 0 {
+		filter = []byte("{}")
+	}
+	delivery, err := json.Marshal(report.Delivery)
+	if err != nil {
+		return fmt.Errorf("failed to encode report delivery: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = now
+	}
+	report.UpdatedAt = now
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO scheduled_reports (`+scheduledReportColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			advertiser_id = EXCLUDED.advertiser_id,
+			filter = EXCLUDED.filter,
+			period = EXCLUDED.period,
+			format = EXCLUDED.format,
+			frequency = EXCLUDED.frequency,
+			weekday = EXCLUDED.weekday,
+			hour = EXCLUDED.hour,
+			timezone = EXCLUDED.timezone,
+			delivery = EXCLUDED.delivery,
+			is_active = EXCLUDED.is_active,
+			next_run_at = EXCLUDED.next_run_at,
+			last_run_at = EXCLUDED.last_run_at,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at
+	`, report.ID, report.Name, nullString(report.AdvertiserID), string(filter), string(report.Period),
+		string(report.Format), string(report.Frequency), int16(report.Weekday), report.Hour,
+		nullString(report.Timezone), string(delivery), report.IsActive, report.NextRunAt, report.LastRunAt,
+		report.CreatedAt, report.UpdatedAt).Scan(&report.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert scheduled report: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the report; its run history is removed by the foreign key.
+func (r *PostgresScheduledReportRepo) Delete(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM scheduled_reports WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete scheduled report: %w", err)
+	}
+	return nil
+}
+
+const reportRunColumns = `id, report_id, scheduled_for, period_start, period_end, status, attempts, error,
+	file_name, rows, bytes, next_attempt_at, started_at, finished_at`
+
+const reportRunSelect = `SELECT id, report_id, scheduled_for, period_start, period_end, status, attempts,
+	COALESCE(error, ''), COALESCE(file_name, ''), COALESCE(rows, 0), COALESCE(bytes, 0), next_attempt_at,
+	started_at, finished_at FROM report_runs`
+
+func scanReportRun(row pgx.Row) (*models.ReportRun, error) {
+	var run models.ReportRun
+	err := row.Scan(&run.ID, &run.ReportID, &run.ScheduledFor, &run.PeriodStart, &run.PeriodEnd, &run.Status,
+		&run.Attempts, &run.Error, &run.FileName, &run.Rows, &run.Bytes, &run.NextAttemptAt,
+		&run.StartedAt, &run.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *PostgresScheduledReportRepo) SaveRun(ctx context.Context, run *models.ReportRun) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO report_runs (`+reportRunColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (id) DO UPDATE SET
+			status = EXCLUDED.status,
+			attempts = EXCLUDED.attempts,
+			error = EXCLUDED.error,
+			file_name = EXCLUDED.file_name,
+			rows = EXCLUDED.rows,
+			bytes = EXCLUDED.bytes,
+			next_attempt_at = EXCLUDED.next_attempt_at,
+			finished_at = EXCLUDED.finished_at
+	`, run.ID, run.ReportID, run.ScheduledFor, run.PeriodStart, run.PeriodEnd, string(run.Status), run.Attempts,
+		nullString(run.Error), nullString(run.FileName), run.Rows, run.Bytes, run.NextAttemptAt,
+		run.StartedAt, run.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save report run: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresScheduledReportRepo) GetRun(ctx context.Context, id string) (*models.ReportRun, error) {
+	run, err := scanReportRun(r.pool.QueryRow(ctx, reportRunSelect+` WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get report run: %w", err)
+	}
+	return run, nil
+}
+
+func (r *PostgresScheduledReportRepo) ListRuns(ctx context.Context, reportID string, limit int) ([]*models.ReportRun, error) {
+	query := reportRunSelect + ` WHERE report_id = $1 ORDER BY started_at DESC`
+	args := []interface{}{reportID}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+	return r.queryRuns(ctx, query, args...)
+}
+
+// ListRetryableRuns returns retrying runs whose next attempt is due.
+func (r *PostgresScheduledReportRepo) ListRetryableRuns(ctx context.Context, now time.Time) ([]*models.ReportRun, error) {
+	return r.queryRuns(ctx, reportRunSelect+` WHERE status = $1 AND next_attempt_at <= $2 ORDER BY next_attempt_at`,
+		string(models.ReportRunRetrying), now)
+}
+
+func (r *PostgresScheduledReportRepo) queryRuns(ctx context.Context, query string, args ...interface{}) ([]*models.ReportRun, error) {
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report runs: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.ReportRun, 0)
+	for rows.Next() {
+		run, err := scanReportRun(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan report run: %w", err)
+		}
+		result = append(result, run)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,473 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// =============================================
+// Report Queries
+// =============================================
+
+// Report dimensions. Time buckets are formatted in the query timezone.
+const (
+	ReportDimDate       = "date"  // 2006-01-02
+	ReportDimHour       = "hour"  // 2006-01-02 15:00
+	ReportDimWeek       = "week"  // Monday, 2006-01-02
+	ReportDimMonth      = "month" // 2006-01
+	ReportDimCampaign   = "campaign"
+	ReportDimLineItem   = "line_item"
+	ReportDimCreative   = "creative"
+	ReportDimSource     = "source"
+	ReportDimSourceType = "source_type" // rtb or s2s
+	ReportDimCountry    = "country"
+	ReportDimOS         = "os"
+	ReportDimAppBundle  = "app_bundle"
+	ReportDimPublisher  = "publisher"
+)
+
+// ReportDimensions lists the supported dimensions.
+var ReportDimensions = []string{
+	ReportDimDate, ReportDimHour, ReportDimWeek, ReportDimMonth,
+	ReportDimCampaign, ReportDimLineItem, ReportDimCreative, ReportDimSource, ReportDimSourceType,
+	ReportDimCountry, ReportDimOS, ReportDimAppBundle, ReportDimPublisher,
+}
+
+// InstallEvent is the conversion event of an app install.
+const InstallEvent = "install"
+
+// winSourceType is the source type of wins, which only RTB sources send.
+const winSourceType = "rtb"
+
+// Report metrics. Impressions, clicks, conversions, installs, spend,
+// revenue and payout are summed from events; the rest are derived after
+// grouping.
+const (
+	ReportMetricImpressions = "impressions"
+	ReportMetricClicks      = "clicks"
+	ReportMetricConversions = "conversions"
+	ReportMetricInstalls    = "installs" // Conversions with InstallEvent
+	ReportMetricSpend       = "spend"    // Sum of win prices (USD)
+	ReportMetricRevenue     = "revenue"  // Conversion revenue (USD)
+	ReportMetricPayout      = "payout"   // Conversion payout to sources (USD)
+	ReportMetricProfit      = "profit"   // revenue - spend
+	ReportMetricCTR         = "ctr"      // %
+	ReportMetricCVR         = "cvr"      // %
+	ReportMetricECPM        = "ecpm"
+	ReportMetricECPC        = "ecpc"
+	ReportMetricECPA        = "ecpa"
+	ReportMetricROAS        = "roas"
+)
+
+// ReportMetrics lists the supported metrics.
+var ReportMetrics = []string{
+	ReportMetricImpressions, ReportMetricClicks, ReportMetricConversions, ReportMetricInstalls,
+	ReportMetricSpend, ReportMetricRevenue, ReportMetricPayout, ReportMetricProfit,
+	ReportMetricCTR, ReportMetricCVR,
+	ReportMetricECPM, ReportMetricECPC, ReportMetricECPA, ReportMetricROAS,
+}
+
+// Report page sizes.
+const (
+	DefaultReportLimit = 1000
+	MaxReportLimit     = 10000
+)
+
+// ReportQuery describes a grouped report over impressions, clicks,
+// conversions and wins. Conversions take country, OS, app bundle and
+// publisher from their click; app bundle and publisher are only recorded on
+// impressions. Spend comes from wins, which are RTB and have no OS, app
+// bundle or publisher: grouped by those it is reported under "", and
+// filtering on them leaves no spend.
+type ReportQuery struct {
+	Start    time.Time      // Inclusive
+	End      time.Time      // Exclusive
+	Location *time.Location // Timezone for time buckets; nil means UTC
+
+	Dimensions []string // Group by, in output order
+	Metrics    []string // Empty means all
+
+	// Filters; empty matches everything
+	CampaignIDs  []string
+	LineItemIDs  []string
+	CreativeIDs  []string
+	SourceIDs    []string
+	Countries    []string
+	OS           []string
+	AppBundles   []string
+	PublisherIDs []string
+
+	SortBy   string // Dimension or selected metric; defaults to the first dimension
+	SortDesc bool
+	Limit    int
+	Offset   int
+}
+
+// ReportRow is one group of a report.
+type ReportRow struct {
+	Dimensions map[string]string  `json:"dimensions"`
+	Metrics    map[string]float64 `json:"metrics"`
+}
+
+// ReportResult is a page of report rows.
+type ReportResult struct {
+	Dimensions []string    `json:"dimensions"` // Column order of the rows
+	Metrics    []string    `json:"metrics"`
+	Rows       []ReportRow `json:"rows"`
+	Total      int64       `json:"total"` // Groups before pagination
+}
+
+// Validate checks names and fills defaults.
+func (q *ReportQuery) Validate() error {
+	if q.End.IsZero() {
+		q.End = time.Now()
+	}
+	if q.Start.IsZero() {
+		q.Start = q.End.AddDate(0, 0, -7)
+	}
+	if !q.Start.Before(q.End) {
+		return fmt.Errorf("start must be before end")
+	}
+	if q.Location == nil {
+		q.Location = time.UTC
+	}
+
+	seen := make(map[string]bool)
+	for _, d := range q.Dimensions {
+		if !containsString(ReportDimensions, d) {
+			return fmt.Errorf("unknown dimension %q", d)
+		}
+		if seen[d] {
+			return fmt.Errorf("duplicate dimension %q", d)
+		}
+		seen[d] = true
+	}
+
+	if len(q.Metrics) == 0 {
+		q.Metrics = ReportMetrics
+	}
+	for _, m := range q.Metrics {
+		if !containsString(ReportMetrics, m) {
+			return fmt.Errorf("unknown metric %q", m)
+		}
+	}
+
+	if q.SortBy == "" && len(q.Dimensions) > 0 {
+		q.SortBy = q.Dimensions[0]
+	}
+	if q.SortBy != "" && !containsString(q.Dimensions, q.SortBy) && !containsString(q.Metrics, q.SortBy) {
+		return fmt.Errorf("sort %q is not a selected dimension or metric", q.SortBy)
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultReportLimit
+	}
+	if q.Limit > MaxReportLimit {
+		return fmt.Errorf("limit must be at most %d", MaxReportLimit)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("offset must not be negative")
+	}
+	return nil
+}
+
+// reportMetricDeps lists the base metrics each metric is computed from.
+var reportMetricDeps = map[string][]string{
+	ReportMetricImpressions: {ReportMetricImpressions},
+	ReportMetricClicks:      {ReportMetricClicks},
+	ReportMetricConversions: {ReportMetricConversions},
+	ReportMetricInstalls:    {ReportMetricInstalls},
+	ReportMetricSpend:       {ReportMetricSpend},
+	ReportMetricRevenue:     {ReportMetricRevenue},
+	ReportMetricPayout:      {ReportMetricPayout},
+	ReportMetricProfit:      {ReportMetricRevenue, ReportMetricSpend},
+	ReportMetricCTR:         {ReportMetricClicks, ReportMetricImpressions},
+	ReportMetricCVR:         {ReportMetricConversions, ReportMetricClicks},
+	ReportMetricECPM:        {ReportMetricSpend, ReportMetricImpressions},
+	ReportMetricECPC:        {ReportMetricSpend, ReportMetricClicks},
+	ReportMetricECPA:        {ReportMetricSpend, ReportMetricConversions},
+	ReportMetricROAS:        {ReportMetricRevenue, ReportMetricSpend},
+}
+
+// reportNeeds reports whether any selected metric reads one of base.
+func (q *ReportQuery) reportNeeds(base ...string) bool {
+	for _, m := range q.Metrics {
+		for _, dep := range reportMetricDeps[m] {
+			if containsString(base, dep) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// reportNeedsClick reports whether conversions must be joined to their click.
+func (q *ReportQuery) reportNeedsClick() bool {
+	for _, d := range q.Dimensions {
+		if d == ReportDimCountry || d == ReportDimOS || d == ReportDimAppBundle || d == ReportDimPublisher {
+			return true
+		}
+	}
+	return len(q.Countries) > 0 || len(q.OS) > 0 || len(q.AppBundles) > 0 || len(q.PublisherIDs) > 0
+}
+
+// deriveReportMetric computes a metric from summed base metrics.
+func deriveReportMetric(name string, m map[string]float64) float64 {
+	ratio := func(num, den float64, scale float64) float64 {
+		if den == 0 {
+			return 0
+		}
+		return num / den * scale
+	}
+	switch name {
+	case ReportMetricProfit:
+		return m[ReportMetricRevenue] - m[ReportMetricSpend]
+	case ReportMetricCTR:
+		return ratio(m[ReportMetricClicks], m[ReportMetricImpressions], 100)
+	case ReportMetricCVR:
+		return ratio(m[ReportMetricConversions], m[ReportMetricClicks], 100)
+	case ReportMetricECPM:
+		return ratio(m[ReportMetricSpend], m[ReportMetricImpressions], 1000)
+	case ReportMetricECPC:
+		return ratio(m[ReportMetricSpend], m[ReportMetricClicks], 1)
+	case ReportMetricECPA:
+		return ratio(m[ReportMetricSpend], m[ReportMetricConversions], 1)
+	case ReportMetricROAS:
+		return ratio(m[ReportMetricRevenue], m[ReportMetricSpend], 1)
+	default:
+		return m[name]
+	}
+}
+
+// reportTimeBucket formats t for a time dimension.
+func reportTimeBucket(dim string, t time.Time, loc *time.Location) string {
+	t = t.In(loc)
+	switch dim {
+	case ReportDimHour:
+		return t.Format("2006-01-02 15:00")
+	case ReportDimWeek:
+		offset := (int(t.Weekday()) + 6) % 7 // Days since Monday
+		return t.AddDate(0, 0, -offset).Format("2006-01-02")
+	case ReportDimMonth:
+		return t.Format("2006-01")
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+// =============================================
+// In-Memory Reports
+// =============================================
+
+// reportEvent holds the dimension values of one event.
+type reportEvent struct {
+	at                                   time.Time
+	campaign, lineItem, creative, source string
+	sourceType                           string
+	country, os, appBundle, publisher    string
+}
+
+func (e *reportEvent) value(dim string, loc *time.Location) string {
+	switch dim {
+	case ReportDimCampaign:
+		return e.campaign
+	case ReportDimLineItem:
+		return e.lineItem
+	case ReportDimCreative:
+		return e.creative
+	case ReportDimSource:
+		return e.source
+	case ReportDimSourceType:
+		return e.sourceType
+	case ReportDimCountry:
+		return e.country
+	case ReportDimOS:
+		return e.os
+	case ReportDimAppBundle:
+		return e.appBundle
+	case ReportDimPublisher:
+		return e.publisher
+	default:
+		return reportTimeBucket(dim, e.at, loc)
+	}
+}
+
+func (e *reportEvent) matches(q *ReportQuery) bool {
+	return !e.at.Before(q.Start) && e.at.Before(q.End) &&
+		matchesAny(q.CampaignIDs, e.campaign) &&
+		matchesAny(q.LineItemIDs, e.lineItem) &&
+		matchesAny(q.CreativeIDs, e.creative) &&
+		matchesAny(q.SourceIDs, e.source) &&
+		matchesAny(q.Countries, e.country) &&
+		matchesAny(q.OS, e.os) &&
+		matchesAny(q.AppBundles, e.appBundle) &&
+		matchesAny(q.PublisherIDs, e.publisher)
+}
+
+// QueryReport groups events in memory. It scans every event and is meant
+// for development and tests; ClickHouseEventStore pushes reports down to SQL.
+func (s *InMemoryEventStore) QueryReport(ctx context.Context, q ReportQuery) (*ReportResult, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*ReportRow)
+	order := make([]string, 0)
+	add := func(e *reportEvent, base map[string]float64) {
+		if !e.matches(&q) {
+			return
+		}
+		dims := make(map[string]string, len(q.Dimensions))
+		keyParts := make([]string, len(q.Dimensions))
+		for i, d := range q.Dimensions {
+			dims[d] = e.value(d, q.Location)
+			keyParts[i] = dims[d]
+		}
+		key := strings.Join(keyParts, "\x00")
+		row, ok := groups[key]
+		if !ok {
+			row = &ReportRow{Dimensions: dims, Metrics: make(map[string]float64)}
+			groups[key] = row
+			order = append(order, key)
+		}
+		for m, v := range base {
+			row.Metrics[m] += v
+		}
+	}
+
+	s.mu.RLock()
+	if q.reportNeeds(ReportMetricImpressions) {
+		for _, imp := range s.impressions {
+			add(&reportEvent{
+				at: imp.Timestamp, campaign: imp.CampaignID, lineItem: imp.LineItemID,
+				creative: imp.CreativeID, source: imp.SourceID, sourceType: imp.SourceType,
+				country: imp.GeoCountry, os: imp.DeviceOS,
+				appBundle: imp.AppBundle, publisher: imp.PublisherID,
+			}, map[string]float64{ReportMetricImpressions: 1})
+		}
+	}
+	if q.reportNeeds(ReportMetricSpend) {
+		for _, win := range s.wins {
+			add(&reportEvent{
+				at: win.Timestamp, campaign: win.CampaignID, lineItem: win.LineItemID,
+				creative: win.CreativeID, source: win.SourceID, sourceType: winSourceType,
+				country: win.GeoCountry,
+			}, map[string]float64{ReportMetricSpend: win.WinPriceUSD})
+		}
+	}
+	if q.reportNeeds(ReportMetricClicks) {
+		for _, click := range s.clicks {
+			add(clickReportEvent(click), map[string]float64{ReportMetricClicks: 1})
+		}
+	}
+	if q.reportNeeds(ReportMetricConversions, ReportMetricInstalls, ReportMetricRevenue, ReportMetricPayout) {
+		for _, conv := range s.conversions {
+			e := &reportEvent{}
+			if click := s.clicks[conv.ClickID]; click != nil {
+				e = clickReportEvent(click)
+			}
+			e.at = conv.Timestamp
+			e.campaign, e.lineItem, e.creative, e.source = conv.CampaignID, conv.LineItemID, conv.CreativeID, conv.SourceID
+			e.sourceType = conv.SourceType
+			installs := 0.0
+			if conv.Event == InstallEvent {
+				installs = 1
+			}
+			add(e, map[string]float64{
+				ReportMetricConversions: 1,
+				ReportMetricInstalls:    installs,
+				ReportMetricRevenue:     conv.RevenueUSD,
+				ReportMetricPayout:      conv.PayoutUSD,
+			})
+		}
+	}
+	s.mu.RUnlock()
+
+	// A report without dimensions always has its single total row
+	if len(q.Dimensions) == 0 && len(groups) == 0 {
+		groups[""] = &ReportRow{Dimensions: map[string]string{}, Metrics: make(map[string]float64)}
+		order = append(order, "")
+	}
+
+	rows := make([]ReportRow, 0, len(groups))
+	for _, key := range order {
+		row := groups[key]
+		metrics := make(map[string]float64, len(q.Metrics))
+		for _, m := range q.Metrics {
+			metrics[m] = deriveReportMetric(m, row.Metrics)
+		}
+		rows = append(rows, ReportRow{Dimensions: row.Dimensions, Metrics: metrics})
+	}
+
+	sortReportRows(rows, &q)
+
+	result := &ReportResult{
+		Dimensions: q.Dimensions,
+		Metrics:    q.Metrics,
+		Rows:       []ReportRow{},
+		Total:      int64(len(rows)),
+	}
+	if q.Offset < len(rows) {
+		end := q.Offset + q.Limit
+		if end > len(rows) {
+			end = len(rows)
+		}
+		result.Rows = rows[q.Offset:end]
+	}
+	return result, nil
+}
+
+func clickReportEvent(click *models.Click) *reportEvent {
+	return &reportEvent{
+		at: click.Timestamp, campaign: click.CampaignID, lineItem: click.LineItemID,
+		creative: click.CreativeID, source: click.SourceID, sourceType: click.SourceType,
+		country: click.GeoCountry, os: click.DeviceOS,
+	}
+}
+
+// sortReportRows orders rows by the sort key, then by dimensions.
+func sortReportRows(rows []ReportRow, q *ReportQuery) {
+	less := func(a, b ReportRow) int {
+		if q.SortBy != "" {
+			var c int
+			if containsString(q.Dimensions, q.SortBy) {
+				c = strings.Compare(a.Dimensions[q.SortBy], b.Dimensions[q.SortBy])
+			} else if a.Metrics[q.SortBy] < b.Metrics[q.SortBy] {
+				c = -1
+			} else if a.Metrics[q.SortBy] > b.Metrics[q.SortBy] {
+				c = 1
+			}
+			if q.SortDesc {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		for _, d := range q.Dimensions {
+			if c := strings.Compare(a.Dimensions[d], b.Dimensions[d]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	sort.SliceStable(rows, func(i, j int) bool { return less(rows[i], rows[j]) < 0 })
+}
+
+func matchesAny(allowed []string, v string) bool {
+	return len(allowed) == 0 || containsString(allowed, v)
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,504 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Daily stats group-by columns (see StatsFilter.GroupBy).
+const (
+	StatsGroupDate       = "date"
+	StatsGroupCampaign   = "campaign_id"
+	StatsGroupSourceType = "source_type"
+	StatsGroupSource     = "source_id"
+	StatsGroupCountry    = "country"
+)
+
+// StatsGroups lists the supported daily stats group-by columns.
+var StatsGroups = []string{StatsGroupDate, StatsGroupCampaign, StatsGroupSourceType, StatsGroupSource, StatsGroupCountry}
+
+// ValidateStatsGroupBy checks group-by column names.
+func ValidateStatsGroupBy(groupBy []string) error {
+	for _, g := range groupBy {
+		if !containsString(StatsGroups, g) {
+			return fmt.Errorf("unknown stats group %q", g)
+		}
+	}
+	return nil
+}
+
+// StatsDate truncates t to its UTC date, the grain of daily stats.
+func StatsDate(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// statsKey identifies a stored daily stats row.
+func statsKey(st *DailyStats) string {
+	return strings.Join([]string{
+		StatsDate(st.Date).Format("2006-01-02"), st.CampaignID, st.LineItemID, st.CreativeID,
+		st.SourceType, st.SourceID, st.GeoCountry, st.DeviceOS,
+	}, "\x00")
+}
+
+func (f *StatsFilter) matches(st *DailyStats) bool {
+	if f.CampaignID != "" && st.CampaignID != f.CampaignID {
+		return false
+	}
+	if len(f.CampaignIDs) > 0 && !containsString(f.CampaignIDs, st.CampaignID) {
+		return false
+	}
+	if f.SourceType != "" && st.SourceType != f.SourceType {
+		return false
+	}
+	if f.SourceID != "" && st.SourceID != f.SourceID {
+		return false
+	}
+	if f.Country != "" && st.GeoCountry != f.Country {
+		return false
+	}
+	if !f.StartDate.IsZero() && st.Date.Before(StatsDate(f.StartDate)) {
+		return false
+	}
+	if !f.EndDate.IsZero() && st.Date.After(StatsDate(f.EndDate)) {
+		return false
+	}
+	return true
+}
+
+// groupDailyStats sums rows by the group-by columns; columns not grouped by
+// are blank. Results are sorted by date, campaign, source and country.
+func groupDailyStats(rows []*DailyStats, groupBy []string) []*DailyStats {
+	grouped := rows
+	if len(groupBy) > 0 {
+		groups := make(map[string]*DailyStats)
+		for _, st := range rows {
+			g := &DailyStats{}
+			if containsString(groupBy, StatsGroupDate) {
+				g.Date = st.Date
+			}
+			if containsString(groupBy, StatsGroupCampaign) {
+				g.CampaignID = st.CampaignID
+			}
+			if containsString(groupBy, StatsGroupSourceType) {
+				g.SourceType = st.SourceType
+			}
+			if containsString(groupBy, StatsGroupSource) {
+				g.SourceID = st.SourceID
+			}
+			if containsString(groupBy, StatsGroupCountry) {
+				g.GeoCountry = st.GeoCountry
+			}
+			key := statsKey(g)
+			if existing, ok := groups[key]; ok {
+				g = existing
+			} else {
+				groups[key] = g
+			}
+			g.add(st)
+		}
+		grouped = make([]*DailyStats, 0, len(groups))
+		for _, g := range groups {
+			grouped = append(grouped, g)
+		}
+	}
+	sortDailyStats(grouped)
+	return grouped
+}
+
+func sortDailyStats(rows []*DailyStats) {
+	sort.Slice(rows, func(i, j int) bool { return statsKey(rows[i]) < statsKey(rows[j]) })
+}
+
+func (st *DailyStats) add(o *DailyStats) {
+	st.Impressions += o.Impressions
+	st.Clicks += o.Clicks
+	st.Installs += o.Installs
+	st.Events += o.Events
+	st.Spend += o.Spend
+	st.Revenue += o.Revenue
+	st.Payout += o.Payout
+}
+
+// campaignStatsAgg totals rows and derives rates.
+func campaignStatsAgg(campaignID string, rows []*DailyStats) *CampaignStatsAgg {
+	var total DailyStats
+	for _, st := range rows {
+		total.add(st)
+	}
+	agg := &CampaignStatsAgg{
+		CampaignID:  campaignID,
+		Impressions: total.Impressions,
+		Clicks:      total.Clicks,
+		Installs:    total.Installs,
+		Spend:       total.Spend,
+		Revenue:     total.Revenue,
+	}
+	if agg.Impressions > 0 {
+		agg.CTR = float64(agg.Clicks) / float64(agg.Impressions) * 100
+	}
+	if agg.Clicks > 0 {
+		agg.CVR = float64(agg.Installs) / float64(agg.Clicks) * 100
+	}
+	if agg.Installs > 0 {
+		agg.CPI = agg.Spend / float64(agg.Installs)
+	}
+	if agg.Spend > 0 {
+		agg.ROAS = agg.Revenue / agg.Spend
+	}
+	return agg
+}
+
+// sourceStatsAgg totals rows and derives the conversion rate.
+func sourceStatsAgg(sourceType, sourceID string, rows []*DailyStats) *SourceStatsAgg {
+	agg := &SourceStatsAgg{SourceType: sourceType, SourceID: sourceID}
+	for _, st := range rows {
+		agg.Clicks += st.Clicks
+		agg.Conversions += st.Installs + st.Events
+		agg.Payout += st.Payout
+	}
+	if agg.Clicks > 0 {
+		agg.CVR = float64(agg.Conversions) / float64(agg.Clicks) * 100
+	}
+	return agg
+}
+
+// =============================================
+// IN-MEMORY STATS REPOSITORY
+// =============================================
+
+// InMemoryStatsRepo provides in-memory storage for daily stats.
+type InMemoryStatsRepo struct {
+	mu        sync.RWMutex
+	stats     map[string]*DailyStats
+	watermark time.Time
+}
+
+// NewInMemoryStatsRepo creates a new in-memory stats repository.
+func NewInMemoryStatsRepo() *InMemoryStatsRepo {
+	return &InMemoryStatsRepo{stats: make(map[string]*DailyStats)}
+}
+
+func (r *InMemoryStatsRepo) GetDailyStats(ctx context.Context, filter StatsFilter) ([]*DailyStats, error) {
+	if err := ValidateStatsGroupBy(filter.GroupBy); err != nil {
+		return nil, err
+	}
+	return groupDailyStats(r.find(filter), filter.GroupBy), nil
+}
+
+// find returns copies of the stored rows matching filter.
+func (r *InMemoryStatsRepo) find(filter StatsFilter) []*DailyStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rows := make([]*DailyStats, 0)
+	for _, st := range r.stats {
+		if filter.matches(st) {
+			cp := *st
+			rows = append(rows, &cp)
+		}
+	}
+	return rows
+}
+
+func (r *InMemoryStatsRepo) UpsertDailyStats(ctx context.Context, stats *DailyStats) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cp := *stats
+	cp.Date = StatsDate(stats.Date)
+	r.stats[statsKey(&cp)] = &cp
+	return nil
+}
+
+func (r *InMemoryStatsRepo) ReplaceDailyStats(ctx context.Context, date time.Time, stats []*DailyStats) error {
+	day := StatsDate(date)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for key, st := range r.stats {
+		if st.Date.Equal(day) {
+			delete(r.stats, key)
+		}
+	}
+	for _, st := range stats {
+		cp := *st
+		cp.Date = day
+		r.stats[statsKey(&cp)] = &cp
+	}
+	return nil
+}
+
+func (r *InMemoryStatsRepo) GetRollupWatermark(ctx context.Context) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.watermark, nil
+}
+
+func (r *InMemoryStatsRepo) SetRollupWatermark(ctx context.Context, watermark time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.watermark = watermark
+	return nil
+}
+
+func (r *InMemoryStatsRepo) GetCampaignStats(ctx context.Context, campaignID string, startDate, endDate time.Time) (*CampaignStatsAgg, error) {
+	rows := r.find(StatsFilter{CampaignID: campaignID, StartDate: startDate, EndDate: endDate})
+	return campaignStatsAgg(campaignID, rows), nil
+}
+
+func (r *InMemoryStatsRepo) GetSourceStats(ctx context.Context, sourceType, sourceID string, startDate, endDate time.Time) (*SourceStatsAgg, error) {
+	rows := r.find(StatsFilter{SourceType: sourceType, SourceID: sourceID, StartDate: startDate, EndDate: endDate})
+	return sourceStatsAgg(sourceType, sourceID, rows), nil
+}
+
+// =============================================
+// POSTGRES STATS REPOSITORY
+// =============================================
+
+// statsRollupName keys the daily stats watermark in stats_rollup_state.
+const statsRollupName = "daily_stats"
+
+// pgStatsColumns maps group-by names to daily_stats columns.
+var pgStatsColumns = map[string]string{
+	StatsGroupDate:       "date",
+	StatsGroupCampaign:   "campaign_id",
+	StatsGroupSourceType: "source_type",
+	StatsGroupSource:     "source_id",
+	StatsGroupCountry:    "geo_country",
+}
+
+// PostgresStatsRepo implements StatsRepo on the daily_stats table.
+type PostgresStatsRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresStatsRepo creates a new PostgreSQL-backed stats repository.
+func NewPostgresStatsRepo(pool *pgxpool.Pool) *PostgresStatsRepo {
+	return &PostgresStatsRepo{pool: pool}
+}
+
+// where builds the WHERE clause and arguments of filter.
+func (f *StatsFilter) where() (string, []interface{}) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.CampaignID != "" {
+		add("campaign_id = $%d", f.CampaignID)
+	}
+	if len(f.CampaignIDs) > 0 {
+		add("campaign_id = ANY($%d)", f.CampaignIDs)
+	}
+	if f.SourceType != "" {
+		add("source_type = $%d", f.SourceType)
+	}
+	if f.SourceID != "" {
+		add("source_id = $%d", f.SourceID)
+	}
+	if f.Country != "" {
+		add("geo_country = $%d", f.Country)
+	}
+	if !f.StartDate.IsZero() {
+		add("date >= $%d", StatsDate(f.StartDate))
+	}
+	if !f.EndDate.IsZero() {
+		add("date <= $%d", StatsDate(f.EndDate))
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func (r *PostgresStatsRepo) GetDailyStats(ctx context.Context, filter StatsFilter) ([]*DailyStats, error) {
+	if err := ValidateStatsGroupBy(filter.GroupBy); err != nil {
+		return nil, err
+	}
+	where, args := filter.where()
+
+	// Stored rows are selected as-is; grouped rows keep only their group
+	// columns and blank the rest
+	groups := filter.GroupBy
+	if len(groups) == 0 {
+		groups = []string{"date", "campaign_id", "line_item_id", "creative_id", "source_type", "source_id", "geo_country", "device_os"}
+	} else {
+		cols := make([]string, len(groups))
+		for i, g := range groups {
+			cols[i] = pgStatsColumns[g]
+		}
+		groups = cols
+	}
+	query := "SELECT " + strings.Join(groups, ", ") + `,
+			SUM(impressions), SUM(clicks), SUM(installs), SUM(events),
+			SUM(spend)::float8, SUM(revenue)::float8, SUM(payout)::float8
+		FROM daily_stats
+		WHERE ` + where + `
+		GROUP BY ` + strings.Join(groups, ", ")
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query daily stats: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*DailyStats, 0)
+	for rows.Next() {
+		st := &DailyStats{}
+		dest := make([]interface{}, 0, len(groups)+7)
+		for _, col := range groups {
+			switch col {
+			case "date":
+				dest = append(dest, &st.Date)
+			case "campaign_id":
+				dest = append(dest, &st.CampaignID)
+			case "line_item_id":
+				dest = append(dest, &st.LineItemID)
+			case "creative_id":
+				dest = append(dest, &st.CreativeID)
+			case "source_type":
+				dest = append(dest, &st.SourceType)
+			case "source_id":
+				dest = append(dest, &st.SourceID)
+			case "geo_country":
+				dest = append(dest, &st.GeoCountry)
+			case "device_os":
+				dest = append(dest, &st.DeviceOS)
+			}
+		}
+		dest = append(dest, &st.Impressions, &st.Clicks, &st.Installs, &st.Events, &st.Spend, &st.Revenue, &st.Payout)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan daily stats: %w", err)
+		}
+		result = append(result, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read daily stats: %w", err)
+	}
+
+	sortDailyStats(result)
+	return result, nil
+}
+
+func (r *PostgresStatsRepo) UpsertDailyStats(ctx context.Context, stats *DailyStats) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO daily_stats (
+			date, campaign_id, line_item_id, creative_id, source_type, source_id, geo_country, device_os,
+			impressions, clicks, installs, events, spend, revenue, payout
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (date, campaign_id, line_item_id, creative_id, source_type, source_id, geo_country, device_os)
+		DO UPDATE SET
+			impressions = EXCLUDED.impressions,
+			clicks = EXCLUDED.clicks,
+			installs = EXCLUDED.installs,
+			events = EXCLUDED.events,
+			spend = EXCLUDED.spend,
+			revenue = EXCLUDED.revenue,
+			payout = EXCLUDED.payout
+	`, StatsDate(stats.Date), stats.CampaignID, stats.LineItemID, stats.CreativeID, stats.SourceType,
+		stats.SourceID, stats.GeoCountry, stats.DeviceOS, stats.Impressions, stats.Clicks, stats.Installs,
+		stats.Events, stats.Spend, stats.Revenue, stats.Payout)
+
+	if err != nil {
+		return fmt.Errorf("failed to upsert daily stats: %w", err)
+	}
+	return nil
+}
+
+// ReplaceDailyStats deletes the date's rows and copies in the new ones in
+// one transaction, so readers see either the old or the new day.
+func (r *PostgresStatsRepo) ReplaceDailyStats(ctx context.Context, date time.Time, stats []*DailyStats) error {
+	day := StatsDate(date)
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM daily_stats WHERE date = $1`, day); err != nil {
+		return fmt.Errorf("failed to delete daily stats: %w", err)
+	}
+
+	rows := make([][]interface{}, len(stats))
+	for i, st := range stats {
+		rows[i] = []interface{}{
+			day, st.CampaignID, st.LineItemID, st.CreativeID, st.SourceType, st.SourceID, st.GeoCountry, st.DeviceOS,
+			st.Impressions, st.Clicks, st.Installs, st.Events, st.Spend, st.Revenue, st.Payout,
+		}
+	}
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{"daily_stats"}, []string{
+		"date", "campaign_id", "line_item_id", "creative_id", "source_type", "source_id", "geo_country", "device_os",
+		"impressions", "clicks", "installs", "events", "spend", "revenue", "payout",
+	}, pgx.CopyFromRows(rows))
+	if err != nil {
+		return fmt.Errorf("failed to insert daily stats: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit daily stats: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresStatsRepo) GetRollupWatermark(ctx context.Context) (time.Time, error) {
+	var watermark time.Time
+	err := r.pool.QueryRow(ctx, `
+		SELECT watermark FROM stats_rollup_state WHERE name = $1
+	`, statsRollupName).Scan(&watermark)
+
+	if err == pgx.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get rollup watermark: %w", err)
+	}
+	return watermark, nil
+}
+
+func (r *PostgresStatsRepo) SetRollupWatermark(ctx context.Context, watermark time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO stats_rollup_state (name, watermark, updated_at)
+		VALUES ($1, $2, NOW())
+		ON CONFLICT (name) DO UPDATE SET watermark = EXCLUDED.watermark, updated_at = NOW()
+	`, statsRollupName, watermark)
+
+	if err != nil {
+		return fmt.Errorf("failed to set rollup watermark: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresStatsRepo) GetCampaignStats(ctx context.Context, campaignID string, startDate, endDate time.Time) (*CampaignStatsAgg, error) {
+	rows, err := r.GetDailyStats(ctx, StatsFilter{
+		CampaignID: campaignID,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		GroupBy:    []string{StatsGroupCampaign},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return campaignStatsAgg(campaignID, rows), nil
+}
+
+func (r *PostgresStatsRepo) GetSourceStats(ctx context.Context, sourceType, sourceID string, startDate, endDate time.Time) (*SourceStatsAgg, error) {
+	rows, err := r.GetDailyStats(ctx, StatsFilter{
+		SourceType: sourceType,
+		SourceID:   sourceID,
+		StartDate:  startDate,
+		EndDate:    endDate,
+		GroupBy:    []string{StatsGroupSource},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sourceStatsAgg(sourceType, sourceID, rows), nil
+}
@@ -0,0 +1,891 @@
+// Package storagetest is the conformance suite of the storage repositories.
+// The in-memory and PostgreSQL implementations must behave the same way,
+// so services can be run offline against the in-memory ones:
+//
+//	func TestInMemory(t *testing.T) {
+//		storagetest.Run(t, func(t *testing.T) storagetest.Repos {
+//			events := storage.NewInMemoryEventStore()
+//			return storagetest.Repos{
+//				Campaigns:   storage.NewInMemoryCampaignRepo(),
+//				Advertisers: storage.NewInMemoryAdvertiserRepo(),
+//				AdGroups:    storage.NewInMemoryAdGroupRepo(),
+//				Creatives:   storage.NewInMemoryCreativeRepo(),
+//				Events:      events,
+//				Reports:     events,
+//				Cohorts:     events,
+//				BidSamples:  storage.NewInMemoryBidSampleStore(100),
+//				Unpriced:    storage.NewInMemoryUnpricedEventRepo(),
+//			}
+//		})
+//	}
+//
+// For PostgreSQL, newRepos truncates the tables of migrations 014 to 016
+// and the ones they depend on and returns the Postgres repositories on the
+// pool.
+package storagetest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/radiusdt/vector-dsp/internal/models"
+	"github.com/radiusdt/vector-dsp/internal/storage"
+)
+
+// Repos are the repositories under test.
+type Repos struct {
+	Campaigns   storage.CampaignRepo
+	Advertisers storage.AdvertiserRepo
+	AdGroups    storage.AdGroupRepo
+	Creatives   storage.CreativeRepo
+	Events      storage.EventStore
+	Reports     storage.ReportStore // Over the events saved to Events
+	Cohorts     storage.CohortStore // Likewise
+	BidSamples  storage.BidSampleStore
+	Unpriced    storage.UnpricedEventRepo
+}
+
+// Run runs the suite. newRepos is called once per subtest and must return
+// empty repositories.
+func Run(t *testing.T, newRepos func(t *testing.T) Repos) {
+	t.Run("Advertisers", func(t *testing.T) { testAdvertisers(t, newRepos(t)) })
+	t.Run("Campaigns", func(t *testing.T) { testCampaigns(t, newRepos(t)) })
+	t.Run("AdGroups", func(t *testing.T) { testAdGroups(t, newRepos(t)) })
+	t.Run("Creatives", func(t *testing.T) { testCreatives(t, newRepos(t)) })
+	t.Run("Events", func(t *testing.T) { testEvents(t, newRepos(t)) })
+	t.Run("Reports", func(t *testing.T) { testReports(t, newRepos(t)) })
+	t.Run("Cohorts", func(t *testing.T) { testCohorts(t, newRepos(t)) })
+	t.Run("BidSamples", func(t *testing.T) { testBidSamples(t, newRepos(t)) })
+	t.Run("UnpricedEvents", func(t *testing.T) { testUnpricedEvents(t, newRepos(t)) })
+}
+
+// base is the time fixtures are created at. PostgreSQL keeps microseconds,
+// so fixture times are whole microseconds.
+var base = time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC)
+
+func testAdvertisers(t *testing.T, r Repos) {
+	ctx := context.Background()
+
+	contract := time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)
+	adv := &models.Advertiser{
+		ID: "adv-2", Name: "Second", LegalName: "Second LLC", TaxID: "7700000000",
+		Website: "https://second.example", Industry: "games",
+		Balance: 500, CreditLimit: 100, Currency: "EUR", Timezone: "Europe/Berlin",
+		ContractNumber: "C-1", ContractDate: &contract, AccountManager: "am@example.com",
+		Status: "active", CreatedAt: base, UpdatedAt: base,
+	}
+	mustDo(t, r.Advertisers.Upsert(ctx, adv))
+	if adv.Balance != 0 {
+		t.Errorf("Upsert of a new advertiser set balance %v, want 0", adv.Balance)
+	}
+	mustDo(t, r.Advertisers.Upsert(ctx, &models.Advertiser{ID: "adv-1", Name: "First", CreatedAt: base, UpdatedAt: base}))
+
+	got, err := r.Advertisers.GetByID(ctx, "adv-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID", adv, got)
+
+	missing, err := r.Advertisers.GetByID(ctx, "adv-missing")
+	mustDo(t, err)
+	if missing != nil {
+		t.Errorf("GetByID of a missing advertiser = %+v, want nil", missing)
+	}
+
+	balance, err := r.Advertisers.UpdateBalance(ctx, "adv-2", 150.5)
+	mustDo(t, err)
+	if balance != 150.5 {
+		t.Errorf("UpdateBalance = %v, want 150.5", balance)
+	}
+	balance, err = r.Advertisers.UpdateBalance(ctx, "adv-2", -50.5)
+	mustDo(t, err)
+	if balance != 100 {
+		t.Errorf("UpdateBalance = %v, want 100", balance)
+	}
+	if _, err := r.Advertisers.UpdateBalance(ctx, "adv-missing", 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("UpdateBalance of a missing advertiser: err = %v, want ErrNotFound", err)
+	}
+
+	// Upsert keeps the stored balance
+	adv.Name = "Second renamed"
+	adv.Balance = 0
+	adv.UpdatedAt = base.Add(time.Hour)
+	mustDo(t, r.Advertisers.Upsert(ctx, adv))
+	if adv.Balance != 100 {
+		t.Errorf("Upsert set balance %v, want the stored 100", adv.Balance)
+	}
+	got, err = r.Advertisers.GetByID(ctx, "adv-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID after update", adv, got)
+
+	list, err := r.Advertisers.ListAll(ctx)
+	mustDo(t, err)
+	assertIDs(t, "ListAll", []string{"adv-1", "adv-2"}, list)
+
+	mustDo(t, r.Advertisers.Delete(ctx, "adv-1"))
+	mustDo(t, r.Advertisers.Delete(ctx, "adv-1"))
+	list, err = r.Advertisers.ListAll(ctx)
+	mustDo(t, err)
+	assertIDs(t, "ListAll after Delete", []string{"adv-2"}, list)
+}
+
+func testCampaigns(t *testing.T, r Repos) {
+	ctx := context.Background()
+	createAdvertisers(t, r, "adv-1", "adv-2")
+
+	camp := &models.Campaign{
+		ID: "camp-2", Name: "Summer", AdvertiserID: "adv-1", Status: models.CampaignStatusActive,
+		AppBundle: "com.example.app", AppName: "Example", TotalBudget: 1000, DailyBudget: 100,
+		StartDate: base, EndDate: base.Add(30 * 24 * time.Hour),
+		LineItems: []models.LineItem{{
+			ID: "li-1", Name: "RU", CampaignID: "camp-2", IsActive: true, Priority: 2,
+			Targeting:   models.Targeting{Countries: []string{"RU"}},
+			BidStrategy: models.BidStrategy{Type: models.BidStrategyFixedCPM, FixedCPM: 2.5},
+			Pacing:      models.PacingConfig{DailyBudget: 50, StartAt: base},
+			Creatives: []models.Creative{{
+				ID: "cr-1", AdmTemplate: "<div/>", W: 320, H: 50,
+				ADomain: []string{"example.com"}, ClickURL: "https://example.com",
+			}},
+			CreatedAt: base, UpdatedAt: base,
+		}},
+		CreatedAt: base, UpdatedAt: base,
+	}
+	mustDo(t, r.Campaigns.Upsert(ctx, camp))
+	mustDo(t, r.Campaigns.Upsert(ctx, &models.Campaign{
+		ID: "camp-1", Name: "No status", AdvertiserID: "adv-2", LineItems: []models.LineItem{},
+		CreatedAt: base, UpdatedAt: base,
+	}))
+	mustDo(t, r.Campaigns.Upsert(ctx, &models.Campaign{
+		ID: "camp-3", Name: "Paused", AdvertiserID: "adv-1", Status: models.CampaignStatusPaused,
+		LineItems: []models.LineItem{}, CreatedAt: base, UpdatedAt: base,
+	}))
+
+	got, err := r.Campaigns.GetByID(ctx, "camp-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID", camp, got)
+
+	// Changes of returned campaigns are not stored either
+	got.LineItems[0].Targeting.Countries[0] = "KZ"
+	got.LineItems[0].Creatives[0].ADomain[0] = "changed.example"
+	listed, err := r.Campaigns.GetByAdvertiser(ctx, "adv-1")
+	mustDo(t, err)
+	for _, c := range listed {
+		if c.ID == "camp-2" {
+			c.LineItems[0].Pacing.DailyBudget = 1
+			c.LineItems[0].Creatives[0].W = 1
+		}
+	}
+	got, err = r.Campaigns.GetByID(ctx, "camp-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID after changing returned campaigns", camp, got)
+
+	// Later changes of the caller's campaign are not stored
+	camp.LineItems[0].Creatives[0].W = 728
+	got, err = r.Campaigns.GetByID(ctx, "camp-2")
+	mustDo(t, err)
+	if w := got.LineItems[0].Creatives[0].W; w != 320 {
+		t.Errorf("stored creative width changed to %d without Upsert", w)
+	}
+
+	missing, err := r.Campaigns.GetByID(ctx, "camp-missing")
+	mustDo(t, err)
+	if missing != nil {
+		t.Errorf("GetByID of a missing campaign = %+v, want nil", missing)
+	}
+
+	list, err := r.Campaigns.ListAll(ctx)
+	mustDo(t, err)
+	assertIDs(t, "ListAll", []string{"camp-1", "camp-2", "camp-3"}, list)
+
+	list, err = r.Campaigns.GetByAdvertiser(ctx, "adv-1")
+	mustDo(t, err)
+	assertIDs(t, "GetByAdvertiser", []string{"camp-2", "camp-3"}, list)
+
+	list, err = r.Campaigns.GetActive(ctx)
+	mustDo(t, err)
+	assertIDs(t, "GetActive", []string{"camp-2"}, list)
+
+	// Campaigns saved without a status are drafts
+	list, err = r.Campaigns.GetByStatus(ctx, models.CampaignStatusDraft)
+	mustDo(t, err)
+	assertIDs(t, "GetByStatus(draft)", []string{"camp-1"}, list)
+	if len(list) == 1 && list[0].Status != "" {
+		t.Errorf("status of a campaign saved without one = %q, want it kept empty", list[0].Status)
+	}
+
+	camp.Status = models.CampaignStatusPaused
+	camp.UpdatedAt = base.Add(time.Hour)
+	mustDo(t, r.Campaigns.Upsert(ctx, camp))
+	list, err = r.Campaigns.GetByStatus(ctx, models.CampaignStatusPaused)
+	mustDo(t, err)
+	assertIDs(t, "GetByStatus(paused)", []string{"camp-2", "camp-3"}, list)
+	if len(list) == 2 {
+		assertJSONEqual(t, "GetByStatus after update", camp, list[0])
+	}
+
+	mustDo(t, r.Campaigns.Delete(ctx, "camp-3"))
+	mustDo(t, r.Campaigns.Delete(ctx, "camp-3"))
+	list, err = r.Campaigns.ListAll(ctx)
+	mustDo(t, err)
+	assertIDs(t, "ListAll after Delete", []string{"camp-1", "camp-2"}, list)
+}
+
+func testAdGroups(t *testing.T, r Repos) {
+	ctx := context.Background()
+
+	group := &models.AdGroup{
+		ID: "ag-2", CampaignID: "camp-1", Name: "Banners", Budget: 250.5,
+		StartAt: base, EndAt: base.Add(7 * 24 * time.Hour),
+		Targeting:   models.Targeting{Countries: []string{"KZ", "UZ"}},
+		CreativeIDs: []string{"cr-1", "cr-2"}, IsActive: true,
+		CreatedAt: base, UpdatedAt: base,
+	}
+	mustDo(t, r.AdGroups.Upsert(ctx, group))
+	mustDo(t, r.AdGroups.Upsert(ctx, &models.AdGroup{ID: "ag-1", CampaignID: "camp-2", Name: "Video", Budget: 10, CreatedAt: base, UpdatedAt: base}))
+	mustDo(t, r.AdGroups.Upsert(ctx, &models.AdGroup{ID: "ag-3", CampaignID: "camp-1", Name: "Native", Budget: 10, CreatedAt: base, UpdatedAt: base}))
+
+	got, err := r.AdGroups.GetByID(ctx, "ag-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID", group, got)
+
+	got.CreativeIDs[0] = "cr-changed"
+	got.Targeting.Countries[0] = "RU"
+	got, err = r.AdGroups.GetByID(ctx, "ag-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID after changing the returned ad group", group, got)
+
+	missing, err := r.AdGroups.GetByID(ctx, "ag-missing")
+	mustDo(t, err)
+	if missing != nil {
+		t.Errorf("GetByID of a missing ad group = %+v, want nil", missing)
+	}
+
+	list, err := r.AdGroups.ListAll(ctx)
+	mustDo(t, err)
+	assertIDs(t, "ListAll", []string{"ag-1", "ag-2", "ag-3"}, list)
+
+	list, err = r.AdGroups.ListByCampaign(ctx, "camp-1")
+	mustDo(t, err)
+	assertIDs(t, "ListByCampaign", []string{"ag-2", "ag-3"}, list)
+
+	group.IsActive = false
+	group.UpdatedAt = base.Add(time.Hour)
+	mustDo(t, r.AdGroups.Upsert(ctx, group))
+	got, err = r.AdGroups.GetByID(ctx, "ag-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID after update", group, got)
+
+	mustDo(t, r.AdGroups.Delete(ctx, "ag-2"))
+	mustDo(t, r.AdGroups.Delete(ctx, "ag-2"))
+	list, err = r.AdGroups.ListByCampaign(ctx, "camp-1")
+	mustDo(t, err)
+	assertIDs(t, "ListByCampaign after Delete", []string{"ag-3"}, list)
+}
+
+func testCreatives(t *testing.T, r Repos) {
+	ctx := context.Background()
+	createAdvertisers(t, r, "adv-1", "adv-2")
+
+	creative := &models.Creative{
+		ID: "cr-2", AdvertiserID: "adv-1", Name: "Native", AdmTemplate: "{}",
+		ADomain: []string{"example.com"}, ClickURL: "https://example.com/click",
+		Format: "native", NativeAssets: &models.NativeAssets{Title: "Play now", Rating: 4.5},
+		AuditStatus: "pending", ImpressionTrackers: []string{"https://t.example.com/imp"},
+		CreatedAt: base, UpdatedAt: base,
+	}
+	mustDo(t, r.Creatives.Upsert(ctx, creative))
+	mustDo(t, r.Creatives.Upsert(ctx, &models.Creative{ID: "cr-1", AdvertiserID: "adv-2", Format: "banner", W: 300, H: 250, ADomain: []string{}, CreatedAt: base, UpdatedAt: base}))
+	mustDo(t, r.Creatives.Upsert(ctx, &models.Creative{ID: "cr-3", AdvertiserID: "adv-1", Format: "video", VideoURL: "https://cdn.example.com/v.mp4", ADomain: []string{}, CreatedAt: base, UpdatedAt: base}))
+
+	got, err := r.Creatives.GetByID(ctx, "cr-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetByID", creative, got)
+
+	missing, err := r.Creatives.GetByID(ctx, "cr-missing")
+	mustDo(t, err)
+	if missing != nil {
+		t.Errorf("GetByID of a missing creative = %+v, want nil", missing)
+	}
+
+	list, err := r.Creatives.ListAll(ctx)
+	mustDo(t, err)
+	assertIDs(t, "ListAll", []string{"cr-1", "cr-2", "cr-3"}, list)
+
+	list, err = r.Creatives.ListByAdvertiser(ctx, "adv-1")
+	mustDo(t, err)
+	assertIDs(t, "ListByAdvertiser", []string{"cr-2", "cr-3"}, list)
+
+	mustDo(t, r.Creatives.UpdateStatus(ctx, "cr-2", "approved"))
+	got, err = r.Creatives.GetByID(ctx, "cr-2")
+	mustDo(t, err)
+	creative.AuditStatus = "approved"
+	assertJSONEqual(t, "GetByID after UpdateStatus", creative, got)
+	if err := r.Creatives.UpdateStatus(ctx, "cr-missing", "approved"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("UpdateStatus of a missing creative: err = %v, want ErrNotFound", err)
+	}
+
+	mustDo(t, r.Creatives.Delete(ctx, "cr-2"))
+	mustDo(t, r.Creatives.Delete(ctx, "cr-2"))
+	list, err = r.Creatives.ListByAdvertiser(ctx, "adv-1")
+	mustDo(t, err)
+	assertIDs(t, "ListByAdvertiser after Delete", []string{"cr-3"}, list)
+}
+
+func testEvents(t *testing.T, r Repos) {
+	ctx := context.Background()
+
+	// Saved out of order; listed oldest first
+	clicks := []*models.Click{
+		{ID: "click-3", Timestamp: base.Add(3 * time.Minute), CampaignID: "camp-1", DeviceIFA: "ifa-1", SourceType: "s2s", SourceID: "src-1", TargetURL: "https://mmp.example.com/3"},
+		{ID: "click-1", Timestamp: base.Add(time.Minute), CampaignID: "camp-1", DeviceIFA: "ifa-1", SourceType: "s2s", SourceID: "src-1", TargetURL: "https://mmp.example.com/1", Params: map[string]string{"sub": "a"}},
+		{ID: "click-2", Timestamp: base.Add(2 * time.Minute), CampaignID: "camp-2", DeviceIFA: "ifa-1", SourceType: "rtb", SourceID: "src-2", FraudReasons: []string{"ip_rate"}, FraudScore: 40},
+		{ID: "click-4", Timestamp: base.Add(4 * time.Minute), CampaignID: "camp-1", DeviceIFA: "ifa-2"},
+	}
+	for _, c := range clicks {
+		mustDo(t, r.Events.SaveClick(ctx, c))
+	}
+
+	got, err := r.Events.GetClick(ctx, "click-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetClick", clicks[2], got)
+	missingClick, err := r.Events.GetClick(ctx, "click-missing")
+	mustDo(t, err)
+	if missingClick != nil {
+		t.Errorf("GetClick of a missing click = %+v, want nil", missingClick)
+	}
+
+	byDevice, err := r.Events.GetClicksByDevice(ctx, "ifa-1", base)
+	mustDo(t, err)
+	assertIDs(t, "GetClicksByDevice", []string{"click-1", "click-2", "click-3"}, byDevice)
+	byDevice, err = r.Events.GetClicksByDevice(ctx, "ifa-1", base.Add(time.Minute))
+	mustDo(t, err)
+	assertIDs(t, "GetClicksByDevice since the first click", []string{"click-2", "click-3"}, byDevice)
+	byDevice, err = r.Events.GetClicksByDevice(ctx, "ifa-unknown", base)
+	mustDo(t, err)
+	assertIDs(t, "GetClicksByDevice of an unknown device", []string{}, byDevice)
+
+	count, err := r.Events.GetClickCount(ctx, "camp-1", base.Add(time.Minute))
+	mustDo(t, err)
+	if count != 2 {
+		t.Errorf("GetClickCount = %d, want 2", count)
+	}
+
+	imp := &models.Impression{
+		ID: "imp-1", Timestamp: base, CampaignID: "camp-1", LineItemID: "li-1", CreativeID: "cr-1",
+		SourceType: "rtb", SourceID: "src-2", BidRequestID: "req-1", BidPrice: 2.5, WinPrice: 1.75,
+		IP: "10.0.0.1", AppBundle: "com.publisher.app",
+	}
+	mustDo(t, r.Events.SaveImpression(ctx, imp))
+	mustDo(t, r.Events.SaveImpression(ctx, &models.Impression{ID: "imp-2", Timestamp: base.Add(time.Minute), CampaignID: "camp-1"}))
+	gotImp, err := r.Events.GetImpression(ctx, "imp-1")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetImpression", imp, gotImp)
+	missingImp, err := r.Events.GetImpression(ctx, "imp-missing")
+	mustDo(t, err)
+	if missingImp != nil {
+		t.Errorf("GetImpression of a missing impression = %+v, want nil", missingImp)
+	}
+	count, err = r.Events.GetImpressionCount(ctx, "camp-1", base.Add(-time.Second))
+	mustDo(t, err)
+	if count != 2 {
+		t.Errorf("GetImpressionCount = %d, want 2", count)
+	}
+
+	conversions := []*models.Conversion{
+		{ID: "conv-2", Timestamp: base.Add(time.Hour), ClickID: "click-1", CampaignID: "camp-1", Event: "purchase", EventOriginal: "af_purchase", Revenue: 9.99, RevenueCurrency: "USD", RevenueUSD: 9.99},
+		{ID: "conv-1", Timestamp: base.Add(30 * time.Minute), ClickID: "click-1", CampaignID: "camp-1", Event: "install", Payout: 1.5, PayoutCurrency: "USD", PayoutUSD: 1.5},
+		{ID: "conv-3", Timestamp: base.Add(2 * time.Hour), ClickID: "click-2", CampaignID: "camp-2", Event: "install"},
+	}
+	for _, c := range conversions {
+		mustDo(t, r.Events.SaveConversion(ctx, c))
+	}
+	gotConv, err := r.Events.GetConversion(ctx, "conv-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetConversion", conversions[0], gotConv)
+	missingConv, err := r.Events.GetConversion(ctx, "conv-missing")
+	mustDo(t, err)
+	if missingConv != nil {
+		t.Errorf("GetConversion of a missing conversion = %+v, want nil", missingConv)
+	}
+
+	byClick, err := r.Events.GetConversionsByClick(ctx, "click-1")
+	mustDo(t, err)
+	assertIDs(t, "GetConversionsByClick", []string{"conv-1", "conv-2"}, byClick)
+	byClick, err = r.Events.GetConversionsByClick(ctx, "click-4")
+	mustDo(t, err)
+	assertIDs(t, "GetConversionsByClick without conversions", []string{}, byClick)
+
+	count, err = r.Events.GetConversionCount(ctx, "camp-1", "", base)
+	mustDo(t, err)
+	if count != 2 {
+		t.Errorf("GetConversionCount of all events = %d, want 2", count)
+	}
+	count, err = r.Events.GetConversionCount(ctx, "camp-1", "install", base)
+	mustDo(t, err)
+	if count != 1 {
+		t.Errorf("GetConversionCount of installs = %d, want 1", count)
+	}
+
+	mustDo(t, r.Events.SaveWin(ctx, &models.Win{
+		ID: "win-1", Timestamp: base, BidRequestID: "req-1", ImpID: "1", CampaignID: "camp-1",
+		SourceID: "src-2", BidPrice: 2.5, WinPrice: 1.75, WinPriceUSD: 1.75,
+	}))
+}
+
+// saveReportEvents saves the events of the report and cohort tests. base
+// is Sunday, 2026-03-01 12:00 UTC.
+func saveReportEvents(t *testing.T, r Repos) {
+	t.Helper()
+	ctx := context.Background()
+	for _, imp := range []*models.Impression{
+		{ID: "imp-1", Timestamp: base, CampaignID: "camp-1", LineItemID: "li-1", CreativeID: "cr-1", SourceType: "rtb", SourceID: "src-2",
+			GeoCountry: "BR", DeviceOS: "android", AppBundle: "com.a", PublisherID: "pub-1"},
+		{ID: "imp-2", Timestamp: base.Add(time.Minute), CampaignID: "camp-1", LineItemID: "li-1", SourceType: "rtb", SourceID: "src-2",
+			GeoCountry: "BR", DeviceOS: "android", AppBundle: "com.a"},
+		{ID: "imp-3", Timestamp: base.Add(13 * time.Hour), CampaignID: "camp-2", LineItemID: "li-2", SourceType: "rtb", SourceID: "src-2",
+			GeoCountry: "US", DeviceOS: "ios", AppBundle: "com.b"},
+		// At the end of the reported range, which is exclusive
+		{ID: "imp-4", Timestamp: base.Add(36 * time.Hour), CampaignID: "camp-1", SourceType: "rtb", SourceID: "src-2"},
+	} {
+		mustDo(t, r.Events.SaveImpression(ctx, imp))
+	}
+	for _, win := range []*models.Win{
+		{ID: "win-1", Timestamp: base, CampaignID: "camp-1", LineItemID: "li-1", SourceID: "src-2", GeoCountry: "BR", WinPrice: 1.5, WinPriceUSD: 1.5},
+		{ID: "win-2", Timestamp: base.Add(13 * time.Hour), CampaignID: "camp-2", LineItemID: "li-2", SourceID: "src-2", GeoCountry: "US", WinPrice: 0.5, WinPriceUSD: 0.5},
+		// Before the reported range
+		{ID: "win-3", Timestamp: base.Add(-13 * time.Hour), CampaignID: "camp-1", LineItemID: "li-1", SourceID: "src-2", WinPrice: 100, WinPriceUSD: 100},
+	} {
+		mustDo(t, r.Events.SaveWin(ctx, win))
+	}
+	for _, click := range []*models.Click{
+		{ID: "click-1", Timestamp: base.Add(2 * time.Minute), CampaignID: "camp-1", LineItemID: "li-1", SourceType: "rtb", SourceID: "src-2", GeoCountry: "BR", DeviceOS: "android"},
+		{ID: "click-2", Timestamp: base.Add(13 * time.Hour), CampaignID: "camp-2", LineItemID: "li-2", SourceType: "rtb", SourceID: "src-2", GeoCountry: "US", DeviceOS: "ios"},
+		{ID: "click-3", Timestamp: base.Add(5 * time.Minute), CampaignID: "camp-1", LineItemID: "li-1", SourceType: "s2s", SourceID: "src-1", GeoCountry: "DE", DeviceOS: "android"},
+	} {
+		mustDo(t, r.Events.SaveClick(ctx, click))
+	}
+	for _, conv := range []*models.Conversion{
+		{ID: "conv-1", Timestamp: base.Add(30 * time.Minute), ClickID: "click-1", CampaignID: "camp-1", LineItemID: "li-1", SourceType: "rtb", SourceID: "src-2",
+			Event: "install", Payout: 0.25, PayoutCurrency: "USD", PayoutUSD: 0.25},
+		{ID: "conv-2", Timestamp: base.Add(2 * time.Hour), ClickID: "click-1", CampaignID: "camp-1", LineItemID: "li-1", SourceType: "rtb", SourceID: "src-2",
+			Event: "purchase", Revenue: 4, RevenueCurrency: "USD", RevenueUSD: 4},
+		{ID: "conv-3", Timestamp: base.Add(14 * time.Hour), ClickID: "click-2", CampaignID: "camp-2", LineItemID: "li-2", SourceType: "rtb", SourceID: "src-2", Event: "install"},
+		// Without a click, so without country or OS
+		{ID: "conv-4", Timestamp: base.Add(6 * time.Minute), CampaignID: "camp-1", LineItemID: "li-1", SourceType: "s2s", SourceID: "src-1", Event: "install"},
+	} {
+		mustDo(t, r.Events.SaveConversion(ctx, conv))
+	}
+}
+
+// reportRow is a row of a test report.
+func reportRow(dims map[string]string, metrics map[string]float64) storage.ReportRow {
+	return storage.ReportRow{Dimensions: dims, Metrics: metrics}
+}
+
+func testReports(t *testing.T, r Repos) {
+	ctx := context.Background()
+	saveReportEvents(t, r)
+	saoPaulo, err := time.LoadLocation("America/Sao_Paulo")
+	mustDo(t, err)
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 2)
+	query := func(dims, metrics []string) storage.ReportQuery {
+		return storage.ReportQuery{Start: start, End: end, Dimensions: dims, Metrics: metrics}
+	}
+	counts := []string{storage.ReportMetricImpressions, storage.ReportMetricClicks}
+	m := func(values ...float64) map[string]float64 {
+		out := make(map[string]float64)
+		for i, v := range values {
+			out[counts[i]] = v
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		query     func() storage.ReportQuery
+		want      []storage.ReportRow
+		wantTotal int64
+	}{
+		{
+			name: "base metrics by country",
+			query: func() storage.ReportQuery {
+				return query([]string{storage.ReportDimCountry}, []string{
+					storage.ReportMetricImpressions, storage.ReportMetricClicks, storage.ReportMetricConversions,
+					storage.ReportMetricInstalls, storage.ReportMetricSpend, storage.ReportMetricRevenue, storage.ReportMetricPayout,
+				})
+			},
+			// Conversions take the country of their click
+			want: []storage.ReportRow{
+				reportRow(map[string]string{"country": ""}, map[string]float64{"impressions": 0, "clicks": 0, "conversions": 1, "installs": 1, "spend": 0, "revenue": 0, "payout": 0}),
+				reportRow(map[string]string{"country": "BR"}, map[string]float64{"impressions": 2, "clicks": 1, "conversions": 2, "installs": 1, "spend": 1.5, "revenue": 4, "payout": 0.25}),
+				reportRow(map[string]string{"country": "DE"}, map[string]float64{"impressions": 0, "clicks": 1, "conversions": 0, "installs": 0, "spend": 0, "revenue": 0, "payout": 0}),
+				reportRow(map[string]string{"country": "US"}, map[string]float64{"impressions": 1, "clicks": 1, "conversions": 1, "installs": 1, "spend": 0.5, "revenue": 0, "payout": 0}),
+			},
+			wantTotal: 4,
+		},
+		{
+			name: "days in UTC",
+			query: func() storage.ReportQuery {
+				return query([]string{storage.ReportDimDate}, counts)
+			},
+			want: []storage.ReportRow{
+				reportRow(map[string]string{"date": "2026-03-01"}, m(2, 2)),
+				reportRow(map[string]string{"date": "2026-03-02"}, m(1, 1)),
+			},
+			wantTotal: 2,
+		},
+		{
+			name: "days in the query timezone",
+			query: func() storage.ReportQuery {
+				q := query([]string{storage.ReportDimDate}, counts)
+				q.Location = saoPaulo
+				return q
+			},
+			want:      []storage.ReportRow{reportRow(map[string]string{"date": "2026-03-01"}, m(3, 3))},
+			wantTotal: 1,
+		},
+		{
+			name: "weeks start on Monday",
+			query: func() storage.ReportQuery {
+				return query([]string{storage.ReportDimWeek}, counts[1:])
+			},
+			want: []storage.ReportRow{
+				reportRow(map[string]string{"week": "2026-02-23"}, map[string]float64{"clicks": 2}),
+				reportRow(map[string]string{"week": "2026-03-02"}, map[string]float64{"clicks": 1}),
+			},
+			wantTotal: 2,
+		},
+		{
+			name: "hours",
+			query: func() storage.ReportQuery {
+				q := query([]string{storage.ReportDimHour}, counts[:1])
+				q.CampaignIDs = []string{"camp-1"}
+				return q
+			},
+			want:      []storage.ReportRow{reportRow(map[string]string{"hour": "2026-03-01 12:00"}, map[string]float64{"impressions": 2})},
+			wantTotal: 1,
+		},
+		{
+			name: "only impressions have app bundle and publisher",
+			query: func() storage.ReportQuery {
+				return query([]string{storage.ReportDimAppBundle, storage.ReportDimPublisher}, counts)
+			},
+			want: []storage.ReportRow{
+				reportRow(map[string]string{"app_bundle": "", "publisher": ""}, m(0, 3)),
+				reportRow(map[string]string{"app_bundle": "com.a", "publisher": ""}, m(1, 0)),
+				reportRow(map[string]string{"app_bundle": "com.a", "publisher": "pub-1"}, m(1, 0)),
+				reportRow(map[string]string{"app_bundle": "com.b", "publisher": ""}, m(1, 0)),
+			},
+			wantTotal: 4,
+		},
+		{
+			name: "os filter leaves no spend",
+			query: func() storage.ReportQuery {
+				q := query([]string{storage.ReportDimSourceType}, []string{
+					storage.ReportMetricImpressions, storage.ReportMetricSpend, storage.ReportMetricConversions,
+				})
+				q.OS = []string{"android"}
+				return q
+			},
+			want: []storage.ReportRow{
+				reportRow(map[string]string{"source_type": "rtb"}, map[string]float64{"impressions": 2, "spend": 0, "conversions": 2}),
+			},
+			wantTotal: 1,
+		},
+		{
+			name: "derived metrics",
+			query: func() storage.ReportQuery {
+				q := query(nil, []string{
+					storage.ReportMetricProfit, storage.ReportMetricCTR, storage.ReportMetricCVR,
+					storage.ReportMetricECPM, storage.ReportMetricECPC, storage.ReportMetricECPA,
+				})
+				q.CampaignIDs = []string{"camp-1"}
+				return q
+			},
+			// camp-1: 2 impressions, 2 clicks, 3 conversions, 1.5 spend, 4 revenue
+			want: []storage.ReportRow{reportRow(map[string]string{}, map[string]float64{
+				"profit": 2.5, "ctr": 100, "cvr": 150, "ecpm": 750, "ecpc": 0.75, "ecpa": 0.5,
+			})},
+			wantTotal: 1,
+		},
+		{
+			name: "total row without events",
+			query: func() storage.ReportQuery {
+				q := query(nil, []string{storage.ReportMetricImpressions, storage.ReportMetricROAS})
+				q.CampaignIDs = []string{"camp-missing"}
+				return q
+			},
+			want:      []storage.ReportRow{reportRow(map[string]string{}, map[string]float64{"impressions": 0, "roas": 0})},
+			wantTotal: 1,
+		},
+		{
+			name: "sorted by a metric and paged",
+			query: func() storage.ReportQuery {
+				q := query([]string{storage.ReportDimCampaign}, []string{storage.ReportMetricSpend})
+				q.SortBy, q.SortDesc = storage.ReportMetricSpend, true
+				q.Offset, q.Limit = 1, 1
+				return q
+			},
+			want:      []storage.ReportRow{reportRow(map[string]string{"campaign": "camp-2"}, map[string]float64{"spend": 0.5})},
+			wantTotal: 2,
+		},
+		{
+			name: "past the last page",
+			query: func() storage.ReportQuery {
+				q := query([]string{storage.ReportDimCampaign}, []string{storage.ReportMetricSpend})
+				q.Offset = 5
+				return q
+			},
+			want:      []storage.ReportRow{},
+			wantTotal: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := r.Reports.QueryReport(ctx, tt.query())
+			mustDo(t, err)
+			assertJSONEqual(t, "QueryReport rows", tt.want, result.Rows)
+			if result.Total != tt.wantTotal {
+				t.Errorf("QueryReport total = %d, want %d", result.Total, tt.wantTotal)
+			}
+		})
+	}
+
+	if _, err := r.Reports.QueryReport(ctx, query([]string{"device"}, nil)); err == nil {
+		t.Error("QueryReport with an unknown dimension succeeded, want an error")
+	}
+}
+
+func testCohorts(t *testing.T, r Repos) {
+	ctx := context.Background()
+	hours := func(n int) time.Time { return base.Add(time.Duration(n) * time.Hour) }
+	conversions := []*models.Conversion{
+		// click-1 installs, installs again (not a new install), purchases on
+		// days 0 and 2 and has two sessions on day 1
+		{ID: "conv-1", Timestamp: base, ClickID: "click-1", CampaignID: "camp-1", SourceID: "src-1", Event: "install", PayoutUSD: 0.5},
+		{ID: "conv-2", Timestamp: hours(1), ClickID: "click-1", CampaignID: "camp-1", SourceID: "src-1", Event: "install", PayoutUSD: 0.5},
+		{ID: "conv-3", Timestamp: hours(2), ClickID: "click-1", CampaignID: "camp-1", SourceID: "src-1", Event: "purchase", RevenueUSD: 2},
+		{ID: "conv-4", Timestamp: hours(25), ClickID: "click-1", CampaignID: "camp-1", SourceID: "src-1", Event: "session"},
+		{ID: "conv-5", Timestamp: hours(26), ClickID: "click-1", CampaignID: "camp-1", SourceID: "src-1", Event: "session"},
+		{ID: "conv-6", Timestamp: hours(50), ClickID: "click-1", CampaignID: "camp-1", SourceID: "src-1", Event: "purchase", RevenueUSD: 1},
+		// click-2 installs later that day and has a session on day 0
+		{ID: "conv-7", Timestamp: hours(6), ClickID: "click-2", CampaignID: "camp-1", SourceID: "src-1", Event: "install", PayoutUSD: 0.5},
+		{ID: "conv-8", Timestamp: hours(7), ClickID: "click-2", CampaignID: "camp-1", SourceID: "src-1", Event: "session"},
+		// click-3 installs from another campaign and source
+		{ID: "conv-9", Timestamp: hours(1), ClickID: "click-3", CampaignID: "camp-2", SourceID: "src-2", Event: "install"},
+		// Installs after the range and without a click aren't counted
+		{ID: "conv-10", Timestamp: hours(13), ClickID: "click-4", CampaignID: "camp-1", SourceID: "src-1", Event: "install", PayoutUSD: 9},
+		{ID: "conv-11", Timestamp: hours(3), CampaignID: "camp-1", SourceID: "src-1", Event: "install", PayoutUSD: 9},
+	}
+	for _, conv := range conversions {
+		mustDo(t, r.Events.SaveConversion(ctx, conv))
+	}
+	mustDo(t, r.Events.SaveWin(ctx, &models.Win{ID: "win-1", Timestamp: hours(1), CampaignID: "camp-1", SourceID: "src-1", WinPrice: 1, WinPriceUSD: 1}))
+	mustDo(t, r.Events.SaveWin(ctx, &models.Win{ID: "win-2", Timestamp: hours(13), CampaignID: "camp-1", SourceID: "src-1", WinPrice: 9, WinPriceUSD: 9}))
+
+	q := storage.CohortQuery{
+		Start: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:   time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		Now:   base.AddDate(1, 0, 0),
+		Days:  []int{7, 0, 1},
+	}
+	// camp-1: 2 installs costing 0.5 each plus 1 of media
+	camp1Days := []storage.CohortDay{
+		{Day: 0, Complete: true, Revenue: 2, ROAS: 1, ARPU: 1, Payers: 1, PayerRate: 50, Retained: 1, RetentionRate: 50, RetentionEvents: 1},
+		{Day: 1, Complete: true, Revenue: 2, ROAS: 1, ARPU: 1, Payers: 1, PayerRate: 50, Retained: 1, RetentionRate: 50, RetentionEvents: 2},
+		{Day: 7, Complete: true, Revenue: 3, ROAS: 1.5, ARPU: 1.5, Payers: 1, PayerRate: 50},
+	}
+	want := &storage.CohortResult{
+		Dimensions: storage.CohortDimensions,
+		Days:       []int{0, 1, 7},
+		Rows: []storage.CohortRow{
+			{Dimensions: map[string]string{"install_date": "2026-03-01", "campaign": "camp-1", "source": "src-1"}, Installs: 2, Cost: 2, Days: camp1Days},
+			{Dimensions: map[string]string{"install_date": "2026-03-01", "campaign": "camp-2", "source": "src-2"}, Installs: 1, Days: []storage.CohortDay{
+				{Day: 0, Complete: true}, {Day: 1, Complete: true}, {Day: 7, Complete: true},
+			}},
+		},
+	}
+	got, err := r.Cohorts.QueryCohorts(ctx, q)
+	mustDo(t, err)
+	assertJSONEqual(t, "QueryCohorts", want, got)
+
+	q.Dimensions = []string{storage.CohortDimCampaign}
+	q.CampaignIDs = []string{"camp-1"}
+	got, err = r.Cohorts.QueryCohorts(ctx, q)
+	mustDo(t, err)
+	assertJSONEqual(t, "QueryCohorts of a campaign", &storage.CohortResult{
+		Dimensions: []string{storage.CohortDimCampaign},
+		Days:       []int{0, 1, 7},
+		Rows:       []storage.CohortRow{{Dimensions: map[string]string{"campaign": "camp-1"}, Installs: 2, Cost: 2, Days: camp1Days}},
+	}, got)
+}
+
+func testBidSamples(t *testing.T, r Repos) {
+	ctx := context.Background()
+	decision := func(campaignID, lineItemID string) models.LineItemDecision {
+		return models.LineItemDecision{ImpID: "1", CampaignID: campaignID, LineItemID: lineItemID, Stage: models.BidStageTargeting, Reason: "geo"}
+	}
+	response := &models.BidResponse{ID: "req", SeatBid: []models.SeatBid{}}
+
+	// Saved oldest first; listed newest first
+	samples := []*models.BidSample{
+		{ID: "sample-1", Timestamp: base, SourceID: "src-1", Request: &models.BidRequest{ID: "req-1"},
+			NoBidReason: "no_match", Trail: []models.LineItemDecision{decision("camp-1", "li-1")}},
+		{ID: "sample-2", Timestamp: base.Add(time.Minute), SourceID: "src-2", Request: &models.BidRequest{ID: "req-2"}, Response: response,
+			Trail: []models.LineItemDecision{decision("camp-1", "li-1"), decision("camp-2", "li-2")}},
+		{ID: "sample-3", Timestamp: base.Add(2 * time.Minute), SourceID: "src-1", Request: &models.BidRequest{ID: "req-3"},
+			NoBidReason: "no_match", Trail: []models.LineItemDecision{decision("camp-2", "li-2")}},
+		{ID: "sample-4", Timestamp: base.Add(3 * time.Minute), SourceID: "src-1", Request: &models.BidRequest{ID: "req-4"}, Response: response,
+			Trail: []models.LineItemDecision{}},
+	}
+	for _, s := range samples {
+		mustDo(t, r.BidSamples.SaveBidSample(ctx, s))
+	}
+
+	got, err := r.BidSamples.GetBidSample(ctx, "sample-2")
+	mustDo(t, err)
+	assertJSONEqual(t, "GetBidSample", samples[1], got)
+	missing, err := r.BidSamples.GetBidSample(ctx, "sample-missing")
+	mustDo(t, err)
+	if missing != nil {
+		t.Errorf("GetBidSample of a missing sample = %+v, want nil", missing)
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.BidSampleFilter
+		want   []string
+	}{
+		{"all", storage.BidSampleFilter{}, []string{"sample-4", "sample-3", "sample-2", "sample-1"}},
+		{"limit", storage.BidSampleFilter{Limit: 2}, []string{"sample-4", "sample-3"}},
+		{"source", storage.BidSampleFilter{SourceID: "src-1"}, []string{"sample-4", "sample-3", "sample-1"}},
+		{"campaign", storage.BidSampleFilter{CampaignID: "camp-2"}, []string{"sample-3", "sample-2"}},
+		{"line item", storage.BidSampleFilter{LineItemID: "li-1"}, []string{"sample-2", "sample-1"}},
+		{"no bids", storage.BidSampleFilter{NoBidOnly: true}, []string{"sample-3", "sample-1"}},
+		{"since", storage.BidSampleFilter{Since: base.Add(time.Minute)}, []string{"sample-4", "sample-3", "sample-2"}},
+		{"nothing", storage.BidSampleFilter{SourceID: "src-missing"}, []string{}},
+	}
+	for _, tt := range tests {
+		list, err := r.BidSamples.ListBidSamples(ctx, tt.filter)
+		mustDo(t, err)
+		assertIDs(t, "ListBidSamples "+tt.name, tt.want, list)
+	}
+}
+
+func testUnpricedEvents(t *testing.T, r Repos) {
+	ctx := context.Background()
+
+	// Held out of order; listed oldest first
+	win := &models.UnpricedEvent{
+		ID: "win-1", Kind: models.UnpricedWin, CreatedAt: base.Add(time.Minute),
+		Win: &models.Win{
+			ID: "win-1", Timestamp: base.Add(time.Minute), CampaignID: "camp-1",
+			WinPrice: 150, WinCurrency: "RUB", NeedsConversion: true,
+		},
+	}
+	conv := &models.UnpricedEvent{
+		ID: "conv-1", Kind: models.UnpricedConversion, CreatedAt: base,
+		Conversion: &models.Conversion{
+			ID: "conv-1", Timestamp: base, ClickID: "click-1", CampaignID: "camp-1", Event: "install",
+			Payout: 90, PayoutCurrency: "RUB", NeedsConversion: true, FraudReasons: []string{"geo_mismatch"},
+		},
+	}
+	mustDo(t, r.Unpriced.Hold(ctx, win))
+	mustDo(t, r.Unpriced.Hold(ctx, conv))
+
+	list, err := r.Unpriced.List(ctx, 0)
+	mustDo(t, err)
+	assertIDs(t, "List", []string{"conv-1", "win-1"}, list)
+	if len(list) == 2 {
+		assertJSONEqual(t, "List", conv, list[0])
+	}
+	list, err = r.Unpriced.List(ctx, 1)
+	mustDo(t, err)
+	assertIDs(t, "List with a limit", []string{"conv-1"}, list)
+
+	// Holding again replaces the event
+	conv.Attempts, conv.LastError = 1, "no exchange rate for RUB"
+	mustDo(t, r.Unpriced.Hold(ctx, conv))
+	list, err = r.Unpriced.List(ctx, 0)
+	mustDo(t, err)
+	assertIDs(t, "List after holding again", []string{"conv-1", "win-1"}, list)
+	if len(list) == 2 {
+		assertJSONEqual(t, "List after holding again", conv, list[0])
+	}
+
+	released, err := r.Unpriced.Release(ctx, "conv-1")
+	mustDo(t, err)
+	if !released {
+		t.Error("Release of a held event = false, want true")
+	}
+	released, err = r.Unpriced.Release(ctx, "conv-1")
+	mustDo(t, err)
+	if released {
+		t.Error("Release of a released event = true, want false")
+	}
+	list, err = r.Unpriced.List(ctx, 0)
+	mustDo(t, err)
+	assertIDs(t, "List after Release", []string{"win-1"}, list)
+}
+
+// createAdvertisers creates the advertisers campaigns and creatives refer
+// to; PostgreSQL enforces the foreign keys.
+func createAdvertisers(t *testing.T, r Repos, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		mustDo(t, r.Advertisers.Upsert(context.Background(), &models.Advertiser{ID: id, Name: id, CreatedAt: base, UpdatedAt: base}))
+	}
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertJSONEqual compares want and got by their JSON encoding, which is
+// what the PostgreSQL repositories store.
+func assertJSONEqual(t *testing.T, what string, want, got interface{}) {
+	t.Helper()
+	wantJSON, err := json.Marshal(want)
+	mustDo(t, err)
+	gotJSON, err := json.Marshal(got)
+	mustDo(t, err)
+	if string(wantJSON) != string(gotJSON) {
+		t.Errorf("%s:\n got %s\nwant %s", what, gotJSON, wantJSON)
+	}
+}
+
+// assertIDs checks the IDs of list, in order. list is a slice of pointers
+// to models with an id field.
+func assertIDs(t *testing.T, what string, want []string, list interface{}) {
+	t.Helper()
+	data, err := json.Marshal(list)
+	mustDo(t, err)
+	var items []struct {
+		ID string `json:"id"`
+	}
+	mustDo(t, json.Unmarshal(data, &items))
+	got := make([]string, 0, len(items))
+	for _, item := range items {
+		got = append(got, item.ID)
+	}
+	if len(items) == 0 && string(data) == "null" {
+		t.Errorf("%s returned nil, want an empty list", what)
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", what, got, want)
+		return
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s = %v, want %v", what, got, want)
+			return
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// =============================================
+// Templates
+// =============================================
+
+// InMemoryCampaignTemplateRepo provides in-memory storage for campaign
+// templates.
+type InMemoryCampaignTemplateRepo struct {
+	mu        sync.RWMutex
+	templates map[string]*models.CampaignTemplate
+}
+
+// NewInMemoryCampaignTemplateRepo creates a new in-memory campaign
+// template repository.
+func NewInMemoryCampaignTemplateRepo() *InMemoryCampaignTemplateRepo {
+	return &InMemoryCampaignTemplateRepo{
+		templates: make(map[string]*models.CampaignTemplate),
+	}
+}
+
+func (r *InMemoryCampaignTemplateRepo) List(ctx context.Context, advertiserID string) ([]*models.CampaignTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.CampaignTemplate, 0, len(r.templates))
+	for _, t := range r.templates {
+		if advertiserID == "" || t.AdvertiserID == advertiserID {
+			saved := *t
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (r *InMemoryCampaignTemplateRepo) GetByID(ctx context.Context, id string) (*models.CampaignTemplate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.templates[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *t
+	return &saved, nil
+}
+
+func (r *InMemoryCampaignTemplateRepo) Upsert(ctx context.Context, t *models.CampaignTemplate) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.templates[t.ID]; ok {
+		t.CreatedAt = existing.CreatedAt
+	} else if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+
+	saved := *t
+	r.templates[t.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryCampaignTemplateRepo) Delete(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.templates, id)
+	return nil
+}
+
+// PostgresCampaignTemplateRepo implements CampaignTemplateRepo on the
+// campaign_templates table. The blueprint campaign is kept as JSONB.
+type PostgresCampaignTemplateRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCampaignTemplateRepo creates a new PostgreSQL-backed campaign
+// template repository.
+func NewPostgresCampaignTemplateRepo(pool *pgxpool.Pool) *PostgresCampaignTemplateRepo {
+	return &PostgresCampaignTemplateRepo{pool: pool}
+}
+
+const campaignTemplateColumns = `id, name, advertiser_id, description, source_campaign_id, campaign, created_at, updated_at`
+
+func scanCampaignTemplate(row pgx.Row) (*models.CampaignTemplate, error) {
+	var t models.CampaignTemplate
+	var campaign []byte
+	if err := row.Scan(&t.ID, &t.Name, &t.AdvertiserID, &t.Description, &t.SourceCampaignID, &campaign,
+		&t.CreatedAt, &t.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(campaign, &t.Campaign); err != nil {
+		return nil, fmt.Errorf("failed to decode template %s: %w", t.ID, err)
+	}
+	return &t, nil
+}
+
+func (r *PostgresCampaignTemplateRepo) List(ctx context.Context, advertiserID string) ([]*models.CampaignTemplate, error) {
+	query := `SELECT ` + campaignTemplateColumns + ` FROM campaign_templates`
+	args := []interface{}{}
+	if advertiserID != "" {
+		query += ` WHERE advertiser_id = $1`
+		args = append(args, advertiserID)
+	}
+	query += ` ORDER BY id`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign templates: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.CampaignTemplate, 0)
+	for rows.Next() {
+		t, err := scanCampaignTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign template: %w", err)
+		}
+		result = append(result, t)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresCampaignTemplateRepo) GetByID(ctx context.Context, id string) (*models.CampaignTemplate, error) {
+	t, err := scanCampaignTemplate(r.pool.QueryRow(ctx,
+		`SELECT `+campaignTemplateColumns+` FROM campaign_templates WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign template: %w", err)
+	}
+	return t, nil
+}
+
+func (r *PostgresCampaignTemplateRepo) Upsert(ctx context.Context, t *models.CampaignTemplate) error {
+	campaign, err := json.Marshal(t.Campaign)
+	if err != nil {
+		return fmt.Errorf("failed to encode campaign template: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	t.UpdatedAt = now
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO campaign_templates (`+campaignTemplateColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (id) DO UPDATE SET
+			name = EXCLUDED.name,
+			advertiser_id = EXCLUDED.advertiser_id,
+			description = EXCLUDED.description,
+			source_campaign_id = EXCLUDED.source_campaign_id,
+			campaign = EXCLUDED.campaign,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at
+	`, t.ID, t.Name, t.AdvertiserID, t.Description, t.SourceCampaignID, string(campaign),
+		t.CreatedAt, t.UpdatedAt).Scan(&t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert campaign template: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCampaignTemplateRepo) Delete(ctx context.Context, id string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM campaign_templates WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete campaign template: %w", err)
+	}
+	return nil
+}
+
+// =============================================
+// Drafts
+// =============================================
+
+// InMemoryCampaignDraftRepo provides in-memory storage for campaign
+// drafts.
+type InMemoryCampaignDraftRepo struct {
+	mu     sync.RWMutex
+	drafts map[string]*models.CampaignDraft // By campaign ID
+}
+
+// NewInMemoryCampaignDraftRepo creates a new in-memory campaign draft
+// repository.
+func NewInMemoryCampaignDraftRepo() *InMemoryCampaignDraftRepo {
+	return &InMemoryCampaignDraftRepo{
+		drafts: make(map[string]*models.CampaignDraft),
+	}
+}
+
+func (r *InMemoryCampaignDraftRepo) List(ctx context.Context, advertiserID string) ([]*models.CampaignDraft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.CampaignDraft, 0, len(r.drafts))
+	for _, d := range r.drafts {
+		if advertiserID == "" || d.AdvertiserID == advertiserID {
+			saved := *d
+			result = append(result, &saved)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].CampaignID < result[j].CampaignID })
+	return result, nil
+}
+
+func (r *InMemoryCampaignDraftRepo) Get(ctx context.Context, campaignID string) (*models.CampaignDraft, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	d, ok := r.drafts[campaignID]
+	if !ok {
+		return nil, nil
+	}
+	saved := *d
+	return &saved, nil
+}
+
+func (r *InMemoryCampaignDraftRepo) Upsert(ctx context.Context, d *models.CampaignDraft) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if existing, ok := r.drafts[d.CampaignID]; ok {
+		d.CreatedAt = existing.CreatedAt
+	} else if d.CreatedAt.IsZero() {
+		d.CreatedAt = now
+	}
+	d.UpdatedAt = now
+
+	saved := *d
+	r.drafts[d.CampaignID] = &saved
+	return nil
+}
+
+func (r *InMemoryCampaignDraftRepo) Delete(ctx context.Context, campaignID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.drafts, campaignID)
+	return nil
+}
+
+// PostgresCampaignDraftRepo implements CampaignDraftRepo on the
+// campaign_drafts table. The staged campaign is kept as JSONB.
+type PostgresCampaignDraftRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresCampaignDraftRepo creates a new PostgreSQL-backed campaign
+// draft repository.
+func NewPostgresCampaignDraftRepo(pool *pgxpool.Pool) *PostgresCampaignDraftRepo {
+	return &PostgresCampaignDraftRepo{pool: pool}
+}
+
+const campaignDraftColumns = `campaign_id, advertiser_id, campaign, base_updated_at, actor_id, actor_name, created_at, updated_at`
+
+func scanCampaignDraft(row pgx.Row) (*models.CampaignDraft, error) {
+	var d models.CampaignDraft
+	var campaign []byte
+	if err := row.Scan(&d.CampaignID, &d.AdvertiserID, &campaign, &d.BaseUpdatedAt, &d.ActorID, &d.ActorName,
+		&d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(campaign, &d.Campaign); err != nil {
+		return nil, fmt.Errorf("failed to decode draft of campaign %s: %w", d.CampaignID, err)
+	}
+	return &d, nil
+}
+
+func (r *PostgresCampaignDraftRepo) List(ctx context.Context, advertiserID string) ([]*models.CampaignDraft, error) {
+	query := `SELECT ` + campaignDraftColumns + ` FROM campaign_drafts`
+	args := []interface{}{}
+	if advertiserID != "" {
+		query += ` WHERE advertiser_id = $1`
+		args = append(args, advertiserID)
+	}
+	query += ` ORDER BY campaign_id`
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign drafts: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.CampaignDraft, 0)
+	for rows.Next() {
+		d, err := scanCampaignDraft(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan campaign draft: %w", err)
+		}
+		result = append(result, d)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresCampaignDraftRepo) Get(ctx context.Context, campaignID string) (*models.CampaignDraft, error) {
+	d, err := scanCampaignDraft(r.pool.QueryRow(ctx,
+		`SELECT `+campaignDraftColumns+` FROM campaign_drafts WHERE campaign_id = $1`, campaignID))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get campaign draft: %w", err)
+	}
+	return d, nil
+}
+
+func (r *PostgresCampaignDraftRepo) Upsert(ctx context.Context, d *models.CampaignDraft) error {
+	campaign, err := json.Marshal(d.Campaign)
+	if err != nil {
+		return fmt.Errorf("failed to encode campaign draft: %w", err)
+	}
+
+	now := time.Now().UTC()
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = now
+	}
+	d.UpdatedAt = now
+
+	err = r.pool.QueryRow(ctx, `
+		INSERT INTO campaign_drafts (`+campaignDraftColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (campaign_id) DO UPDATE SET
+			advertiser_id = EXCLUDED.advertiser_id,
+			campaign = EXCLUDED.campaign,
+			base_updated_at = EXCLUDED.base_updated_at,
+			actor_id = EXCLUDED.actor_id,
+			actor_name = EXCLUDED.actor_name,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at
+	`, d.CampaignID, d.AdvertiserID, string(campaign), d.BaseUpdatedAt, d.ActorID, d.ActorName,
+		d.CreatedAt, d.UpdatedAt).Scan(&d.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert campaign draft: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresCampaignDraftRepo) Delete(ctx context.Context, campaignID string) error {
+	if _, err := r.pool.Exec(ctx, `DELETE FROM campaign_drafts WHERE campaign_id = $1`, campaignID); err != nil {
+		return fmt.Errorf("failed to delete campaign draft: %w", err)
+	}
+	return nil
+}
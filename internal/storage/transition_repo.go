@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// matches reports whether t passes the filter.
+func (f *TransitionFilter) matches(t *models.CampaignTransition) bool {
+	if f.CampaignID != "" && t.CampaignID != f.CampaignID {
+		return false
+	}
+	if f.AdvertiserID != "" && t.AdvertiserID != f.AdvertiserID {
+		return false
+	}
+	if f.Reason != "" && t.Reason != f.Reason {
+		return false
+	}
+	if !f.From.IsZero() && t.CreatedAt.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !t.CreatedAt.Before(f.To) {
+		return false
+	}
+	return true
+}
+
+// InMemoryTransitionRepo provides in-memory storage for campaign
+// transitions.
+type InMemoryTransitionRepo struct {
+	mu          sync.RWMutex
+	transitions []*models.CampaignTransition // In append order
+}
+
+// NewInMemoryTransitionRepo creates a new in-memory transition repository.
+func NewInMemoryTransitionRepo() *InMemoryTransitionRepo {
+	return &InMemoryTransitionRepo{}
+}
+
+func (r *InMemoryTransitionRepo) Append(ctx context.Context, t *models.CampaignTransition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *t
+	r.transitions = append(r.transitions, &saved)
+	return nil
+}
+
+func (r *InMemoryTransitionRepo) List(ctx context.Context, filter TransitionFilter) ([]*models.CampaignTransition, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.CampaignTransition, 0)
+	for i := len(r.transitions) - 1; i >= 0; i-- {
+		if !filter.matches(r.transitions[i]) {
+			continue
+		}
+		saved := *r.transitions[i]
+		result = append(result, &saved)
+		if filter.Limit > 0 && len(result) >= filter.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+// PostgresTransitionRepo implements CampaignTransitionRepo on the
+// campaign_transitions table.
+type PostgresTransitionRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresTransitionRepo creates a new PostgreSQL-backed transition
+// repository.
+func NewPostgresTransitionRepo(pool *pgxpool.Pool) *PostgresTransitionRepo {
+	return &PostgresTransitionRepo{pool: pool}
+}
+
+func (r *PostgresTransitionRepo) Append(ctx context.Context, t *models.CampaignTransition) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO campaign_transitions (
+			id, campaign_id, advertiser_id, from_status, to_status, reason, detail,
+			actor_kind, actor_id, actor_name, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`, t.ID, t.CampaignID, t.AdvertiserID, t.From, t.To, t.Reason, t.Detail,
+		t.ActorKind, t.ActorID, t.ActorName, t.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to append campaign transition: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresTransitionRepo) List(ctx context.Context, filter TransitionFilter) ([]*models.CampaignTransition, error) {
+	conds := []string{"TRUE"}
+	args := []interface{}{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if filter.CampaignID != "" {
+		add("campaign_id = $%d", filter.CampaignID)
+	}
+	if filter.AdvertiserID != "" {
+		add("advertiser_id = $%d", filter.AdvertiserID)
+	}
+	if filter.Reason != "" {
+		add("reason = $%d", filter.Reason)
+	}
+	if !filter.From.IsZero() {
+		add("created_at >= $%d", filter.From)
+	}
+	if !filter.To.IsZero() {
+		add("created_at < $%d", filter.To)
+	}
+	query := `SELECT id, campaign_id, advertiser_id, from_status, to_status, reason, detail,
+			actor_kind, actor_id, actor_name, created_at
+		FROM campaign_transitions
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY seq DESC`
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list campaign transitions: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.CampaignTransition, 0)
+	for rows.Next() {
+		var t models.CampaignTransition
+		if err := rows.Scan(&t.ID, &t.CampaignID, &t.AdvertiserID, &t.From, &t.To, &t.Reason, &t.Detail,
+			&t.ActorKind, &t.ActorID, &t.ActorName, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan campaign transition: %w", err)
+		}
+		result = append(result, &t)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// InMemoryUnpricedEventRepo provides in-memory storage for held events.
+type InMemoryUnpricedEventRepo struct {
+	mu     sync.Mutex
+	events map[string]*models.UnpricedEvent
+}
+
+// NewInMemoryUnpricedEventRepo creates a new in-memory held event
+// repository.
+func NewInMemoryUnpricedEventRepo() *InMemoryUnpricedEventRepo {
+	return &InMemoryUnpricedEventRepo{
+		events: make(map[string]*models.UnpricedEvent),
+	}
+}
+
+func (r *InMemoryUnpricedEventRepo) Hold(ctx context.Context, e *models.UnpricedEvent) error {
+	var saved models.UnpricedEvent
+	if err := copyJSON(&saved, e); err != nil {
+		return fmt.Errorf("failed to copy held event: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[e.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryUnpricedEventRepo) List(ctx context.Context, limit int) ([]*models.UnpricedEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := make([]*models.UnpricedEvent, 0, len(r.events))
+	for _, e := range r.events {
+		var saved models.UnpricedEvent
+		if err := copyJSON(&saved, e); err != nil {
+			return nil, fmt.Errorf("failed to copy held event: %w", err)
+		}
+		result = append(result, &saved)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if !result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].CreatedAt.Before(result[j].CreatedAt)
+		}
+		return result[i].ID < result[j].ID
+	})
+	if limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+func (r *InMemoryUnpricedEventRepo) Release(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.events[id]; !ok {
+		return false, nil
+	}
+	delete(r.events, id)
+	return true, nil
+}
+
+// PostgresUnpricedEventRepo implements UnpricedEventRepo on the
+// unpriced_events table.
+type PostgresUnpricedEventRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresUnpricedEventRepo creates a new PostgreSQL-backed held event
+// repository.
+func NewPostgresUnpricedEventRepo(pool *pgxpool.Pool) *PostgresUnpricedEventRepo {
+	return &PostgresUnpricedEventRepo{pool: pool}
+}
+
+func (r *PostgresUnpricedEventRepo) Hold(ctx context.Context, e *models.UnpricedEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode held event: %w", err)
+	}
+
+	_, err = r.pool.Exec(ctx, `
+		INSERT INTO unpriced_events (id, kind, attempts, last_error, created_at, data)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (id) DO UPDATE SET
+			kind = EXCLUDED.kind,
+			attempts = EXCLUDED.attempts,
+			last_error = EXCLUDED.last_error,
+			created_at = EXCLUDED.created_at,
+			data = EXCLUDED.data
+	`, e.ID, e.Kind, e.Attempts, e.LastError, e.CreatedAt, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to hold event: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresUnpricedEventRepo) List(ctx context.Context, limit int) ([]*models.UnpricedEvent, error) {
+	query := `SELECT data FROM unpriced_events ORDER BY created_at, id`
+	if limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	rows, err := r.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list held events: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.UnpricedEvent, 0)
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to scan held event: %w", err)
+		}
+		var e models.UnpricedEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("failed to decode held event: %w", err)
+		}
+		result = append(result, &e)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresUnpricedEventRepo) Release(ctx context.Context, id string) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM unpriced_events WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to release held event: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/radiusdt/vector-dsp/internal/models"
+)
+
+// ErrDuplicateUserEmail is returned by UserRepo.CreateUser and UpdateUser
+// when another user has the same email.
+var ErrDuplicateUserEmail = errors.New("duplicate user email")
+
+// InMemoryUserRepo provides in-memory storage for users and sessions.
+type InMemoryUserRepo struct {
+	mu       sync.RWMutex
+	users    map[string]*models.UserAccount
+	sessions map[string]*models.UserSession
+}
+
+// NewInMemoryUserRepo creates a new in-memory user repository.
+func NewInMemoryUserRepo() *InMemoryUserRepo {
+	return &InMemoryUserRepo{
+		users:    make(map[string]*models.UserAccount),
+		sessions: make(map[string]*models.UserSession),
+	}
+}
+
+func (r *InMemoryUserRepo) ListUsers(ctx context.Context) ([]*models.UserAccount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]*models.UserAccount, 0, len(r.users))
+	for _, u := range r.users {
+		saved := *u
+		result = append(result, &saved)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Email < result[j].Email })
+	return result, nil
+}
+
+func (r *InMemoryUserRepo) GetUser(ctx context.Context, id string) (*models.UserAccount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok {
+		return nil, nil
+	}
+	saved := *u
+	return &saved, nil
+}
+
+func (r *InMemoryUserRepo) GetUserByEmail(ctx context.Context, email string) (*models.UserAccount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if strings.EqualFold(u.Email, email) {
+			saved := *u
+			return &saved, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryUserRepo) CreateUser(ctx context.Context, u *models.UserAccount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if strings.EqualFold(existing.Email, u.Email) {
+			return ErrDuplicateUserEmail
+		}
+	}
+	saved := *u
+	r.users[u.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryUserRepo) UpdateUser(ctx context.Context, u *models.UserAccount) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[u.ID]; !ok {
+		return fmt.Errorf("user not found: %s", u.ID)
+	}
+	for id, existing := range r.users {
+		if id != u.ID && strings.EqualFold(existing.Email, u.Email) {
+			return ErrDuplicateUserEmail
+		}
+	}
+	saved := *u
+	r.users[u.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryUserRepo) CreateSession(ctx context.Context, sess *models.UserSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	saved := *sess
+	r.sessions[sess.ID] = &saved
+	return nil
+}
+
+func (r *InMemoryUserRepo) GetSessionByHash(ctx context.Context, tokenHash string) (*models.UserSession, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, sess := range r.sessions {
+		if sess.TokenHash == tokenHash {
+			saved := *sess
+			return &saved, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *InMemoryUserRepo) RevokeSession(ctx context.Context, id string, at time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sess, ok := r.sessions[id]
+	if !ok || sess.RevokedAt != nil {
+		return false, nil
+	}
+	sess.RevokedAt = &at
+	return true, nil
+}
+
+func (r *InMemoryUserRepo) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, sess := range r.sessions {
+		if sess.UserID == userID && sess.RevokedAt == nil {
+			sess.RevokedAt = &at
+		}
+	}
+	return nil
+}
+
+// PostgresUserRepo implements UserRepo on the users and user_sessions
+// tables.
+type PostgresUserRepo struct {
+	pool *pgxpool.Pool
+}
+
+// NewPostgresUserRepo creates a new PostgreSQL-backed user repository.
+func NewPostgresUserRepo(pool *pgxpool.Pool) *PostgresUserRepo {
+	return &PostgresUserRepo{pool: pool}
+}
+
+const userColumns = `id, email, COALESCE(name, ''), password_hash, role, COALESCE(advertiser_id, ''),
+	COALESCE(totp_secret, ''), totp_enabled, totp_last_step, status, last_login_at, created_at, updated_at`
+
+func scanUser(row pgx.Row) (*models.UserAccount, error) {
+	var u models.UserAccount
+	err := row.Scan(&u.ID, &u.Email, &u.Name, &u.PasswordHash, &u.Role, &u.AdvertiserID,
+		&u.TOTPSecret, &u.TOTPEnabled, &u.TOTPLastStep, &u.Status, &u.LastLoginAt, &u.CreatedAt, &u.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+func (r *PostgresUserRepo) ListUsers(ctx context.Context) ([]*models.UserAccount, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+userColumns+` FROM users ORDER BY email`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+	defer rows.Close()
+
+	result := make([]*models.UserAccount, 0)
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		result = append(result, u)
+	}
+	return result, rows.Err()
+}
+
+func (r *PostgresUserRepo) GetUser(ctx context.Context, id string) (*models.UserAccount, error) {
+	u, err := scanUser(r.pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return u, nil
+}
+
+func (r *PostgresUserRepo) GetUserByEmail(ctx context.Context, email string) (*models.UserAccount, error) {
+	u, err := scanUser(r.pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE LOWER(email) = LOWER($1)`, email))
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return u, nil
+}
+
+func (r *PostgresUserRepo) CreateUser(ctx context.Context, u *models.UserAccount) error {
+	tag, err := r.pool.Exec(ctx, `
+		INSERT INTO users (
+			id, email, name, password_hash, role, advertiser_id,
+			totp_secret, totp_enabled, totp_last_step, status, last_login_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		ON CONFLICT DO NOTHING
+	`, u.ID, u.Email, nullString(u.Name), u.PasswordHash, u.Role, nullString(u.AdvertiserID),
+		nullString(u.TOTPSecret), u.TOTPEnabled, u.TOTPLastStep, u.Status, u.LastLoginAt, u.CreatedAt, u.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDuplicateUserEmail
+	}
+	return nil
+}
+
+func (r *PostgresUserRepo) UpdateUser(ctx context.Context, u *models.UserAccount) error {
+	var taken bool
+	err := r.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND id <> $2)
+	`, u.Email, u.ID).Scan(&taken)
+	if err != nil {
+		return fmt.Errorf("failed to check user email: %w", err)
+	}
+	if taken {
+		return ErrDuplicateUserEmail
+	}
+
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE users SET
+			email = $2, name = $3, password_hash = $4, role = $5, advertiser_id = $6,
+			totp_secret = $7, totp_enabled = $8, totp_last_step = $9, status = $10,
+			last_login_at = $11, updated_at = $12
+		WHERE id = $1
+	`, u.ID, u.Email, nullString(u.Name), u.PasswordHash, u.Role, nullString(u.AdvertiserID),
+		nullString(u.TOTPSecret), u.TOTPEnabled, u.TOTPLastStep, u.Status, u.LastLoginAt, u.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user not found: %s", u.ID)
+	}
+	return nil
+}
+
+func (r *PostgresUserRepo) CreateSession(ctx context.Context, sess *models.UserSession) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO user_sessions (id, user_id, token_hash, user_agent, ip, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, sess.ID, sess.UserID, sess.TokenHash, nullString(sess.UserAgent), nullString(sess.IP), sess.ExpiresAt, sess.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	return nil
+}
+
+func (r *PostgresUserRepo) GetSessionByHash(ctx context.Context, tokenHash string) (*models.UserSession, error) {
+	var sess models.UserSession
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, user_id, token_hash, COALESCE(user_agent, ''), COALESCE(ip, ''), expires_at, revoked_at, created_at
+		FROM user_sessions WHERE token_hash = $1
+	`, tokenHash).Scan(&sess.ID, &sess.UserID, &sess.TokenHash, &sess.UserAgent, &sess.IP,
+		&sess.ExpiresAt, &sess.RevokedAt, &sess.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &sess, nil
+}
+
+func (r *PostgresUserRepo) RevokeSession(ctx context.Context, id string, at time.Time) (bool, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = $2 WHERE id = $1 AND revoked_at IS NULL
+	`, id, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (r *PostgresUserRepo) RevokeUserSessions(ctx context.Context, userID string, at time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE user_sessions SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
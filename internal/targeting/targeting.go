@@ -1,6 +1,7 @@
 package targeting
 
 import (
+	"fmt"
 	"net"
 	"strings"
 	"sync"
@@ -67,11 +68,24 @@ func NewTargetingEngine(geoProvider GeoProvider, cacheSize int, cacheTTL time.Du
 type MatchResult struct {
 	Matched        bool
 	FailedCriteria string
+	Detail         string // Failed criterion with request and targeting values
 	GeoInfo        *GeoInfo
 }
 
 // Match checks if a bid request matches line item targeting.
 func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *models.LineItem) *MatchResult {
+	return e.match(br, imp, li, e.metrics)
+}
+
+// Explain is Match without recording targeting metrics, for dry runs.
+// Detail on the result describes the failed criterion with its values.
+func (e *TargetingEngine) Explain(br *models.BidRequest, imp *models.Imp, li *models.LineItem) *MatchResult {
+	return e.match(br, imp, li, nil)
+}
+
+// match evaluates targeting criteria in order, stopping at the first miss.
+// Hits and misses are recorded on m when it is non-nil.
+func (e *TargetingEngine) match(br *models.BidRequest, imp *models.Imp, li *models.LineItem, m *metrics.Metrics) *MatchResult {
 	result := &MatchResult{Matched: true}
 	targeting := &li.Targeting
 
@@ -87,18 +101,19 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 
 	// Geo targeting (country)
 	if len(targeting.Countries) > 0 {
-		geoInfo := e.lookupGeo(ip)
+		geoInfo := e.requestGeo(br, ip)
 		result.GeoInfo = geoInfo
 		if geoInfo == nil || !e.matchCountry(geoInfo.CountryCode, targeting.Countries) {
 			result.Matched = false
 			result.FailedCriteria = "geo_country"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "geo_country")
+			result.Detail = fmt.Sprintf("country %s not in %v", geoField(geoInfo, "country"), targeting.Countries)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "geo_country")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "geo_country")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "geo_country")
 		}
 	}
 
@@ -106,19 +121,20 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 	if len(targeting.Regions) > 0 {
 		geoInfo := result.GeoInfo
 		if geoInfo == nil {
-			geoInfo = e.lookupGeo(ip)
+			geoInfo = e.requestGeo(br, ip)
 			result.GeoInfo = geoInfo
 		}
 		if geoInfo == nil || !e.matchRegion(geoInfo.Region, targeting.Regions) {
 			result.Matched = false
 			result.FailedCriteria = "geo_region"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "geo_region")
+			result.Detail = fmt.Sprintf("region %s not in %v", geoField(geoInfo, "region"), targeting.Regions)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "geo_region")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "geo_region")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "geo_region")
 		}
 	}
 
@@ -126,19 +142,20 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 	if len(targeting.Cities) > 0 {
 		geoInfo := result.GeoInfo
 		if geoInfo == nil {
-			geoInfo = e.lookupGeo(ip)
+			geoInfo = e.requestGeo(br, ip)
 			result.GeoInfo = geoInfo
 		}
 		if geoInfo == nil || !e.matchCity(geoInfo.City, targeting.Cities) {
 			result.Matched = false
 			result.FailedCriteria = "geo_city"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "geo_city")
+			result.Detail = fmt.Sprintf("city %s not in %v", geoField(geoInfo, "city"), targeting.Cities)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "geo_city")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "geo_city")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "geo_city")
 		}
 	}
 
@@ -151,13 +168,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchDomain(domain, targeting.SiteDomains) {
 			result.Matched = false
 			result.FailedCriteria = "domain_whitelist"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "domain_whitelist")
+			result.Detail = fmt.Sprintf("domain %q not in %v", domain, targeting.SiteDomains)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "domain_whitelist")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "domain_whitelist")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "domain_whitelist")
 		}
 	}
 
@@ -170,13 +188,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if e.matchDomain(domain, targeting.DomainBlacklist) {
 			result.Matched = false
 			result.FailedCriteria = "domain_blacklist"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "domain_blacklist")
+			result.Detail = fmt.Sprintf("domain %q is blacklisted", domain)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "domain_blacklist")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "domain_blacklist")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "domain_blacklist")
 		}
 	}
 
@@ -189,13 +208,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchBundle(bundle, targeting.AppBundles) {
 			result.Matched = false
 			result.FailedCriteria = "app_bundle"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "app_bundle")
+			result.Detail = fmt.Sprintf("bundle %q not in %v", bundle, targeting.AppBundles)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "app_bundle")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "app_bundle")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "app_bundle")
 		}
 	}
 
@@ -208,13 +228,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if e.matchBundle(bundle, targeting.BundleBlacklist) {
 			result.Matched = false
 			result.FailedCriteria = "bundle_blacklist"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "bundle_blacklist")
+			result.Detail = fmt.Sprintf("bundle %q is blacklisted", bundle)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "bundle_blacklist")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "bundle_blacklist")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "bundle_blacklist")
 		}
 	}
 
@@ -227,13 +248,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchDeviceType(dt, targeting.DeviceTypes) {
 			result.Matched = false
 			result.FailedCriteria = "device_type"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "device_type")
+			result.Detail = fmt.Sprintf("device_type %d not in %v", dt, targeting.DeviceTypes)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "device_type")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "device_type")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "device_type")
 		}
 	}
 
@@ -246,13 +268,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchOS(osVal, targeting.OS) {
 			result.Matched = false
 			result.FailedCriteria = "os"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "os")
+			result.Detail = fmt.Sprintf("os %q not in %v", osVal, targeting.OS)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "os")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "os")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "os")
 		}
 	}
 
@@ -265,13 +288,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchOSVersion(osv, targeting.OSVersionMin, targeting.OSVersionMax) {
 			result.Matched = false
 			result.FailedCriteria = "os_version"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "os_version")
+			result.Detail = describeOSVersion(osv, targeting.OSVersionMin, targeting.OSVersionMax)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "os_version")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "os_version")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "os_version")
 		}
 	}
 
@@ -286,13 +310,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchCategories(cats, targeting.CatWhitelist) {
 			result.Matched = false
 			result.FailedCriteria = "category_whitelist"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "category_whitelist")
+			result.Detail = fmt.Sprintf("categories %v not in %v", cats, targeting.CatWhitelist)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "category_whitelist")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "category_whitelist")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "category_whitelist")
 		}
 	}
 
@@ -307,13 +332,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if e.matchCategories(cats, targeting.CatBlacklist) {
 			result.Matched = false
 			result.FailedCriteria = "category_blacklist"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "category_blacklist")
+			result.Detail = fmt.Sprintf("categories %v intersect blacklist %v", cats, targeting.CatBlacklist)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "category_blacklist")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "category_blacklist")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "category_blacklist")
 		}
 	}
 
@@ -323,22 +349,24 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 			if targeting.MinBannerW > 0 && imp.Banner.W < targeting.MinBannerW {
 				result.Matched = false
 				result.FailedCriteria = "banner_width"
-				if e.metrics != nil {
-					e.metrics.RecordTargetingMiss(li.ID, "banner_size")
+				result.Detail = fmt.Sprintf("banner width %d < min %d", imp.Banner.W, targeting.MinBannerW)
+				if m != nil {
+					m.RecordTargetingMiss(li.ID, "banner_size")
 				}
 				return result
 			}
 			if targeting.MinBannerH > 0 && imp.Banner.H < targeting.MinBannerH {
 				result.Matched = false
 				result.FailedCriteria = "banner_height"
-				if e.metrics != nil {
-					e.metrics.RecordTargetingMiss(li.ID, "banner_size")
+				result.Detail = fmt.Sprintf("banner height %d < min %d", imp.Banner.H, targeting.MinBannerH)
+				if m != nil {
+					m.RecordTargetingMiss(li.ID, "banner_size")
 				}
 				return result
 			}
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "banner_size")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "banner_size")
 		}
 	}
 
@@ -351,13 +379,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchConnectionType(connType, targeting.ConnectionTypes) {
 			result.Matched = false
 			result.FailedCriteria = "connection_type"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "connection_type")
+			result.Detail = fmt.Sprintf("connection_type %d not in %v", connType, targeting.ConnectionTypes)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "connection_type")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "connection_type")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "connection_type")
 		}
 	}
 
@@ -370,13 +399,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchCarrier(carrier, targeting.Carriers) {
 			result.Matched = false
 			result.FailedCriteria = "carrier"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "carrier")
+			result.Detail = fmt.Sprintf("carrier %q not in %v", carrier, targeting.Carriers)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "carrier")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "carrier")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "carrier")
 		}
 	}
 
@@ -389,13 +419,14 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchMake(make, targeting.DeviceMakes) {
 			result.Matched = false
 			result.FailedCriteria = "device_make"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "device_make")
+			result.Detail = fmt.Sprintf("make %q not in %v", make, targeting.DeviceMakes)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "device_make")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "device_make")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "device_make")
 		}
 	}
 
@@ -408,19 +439,61 @@ func (e *TargetingEngine) Match(br *models.BidRequest, imp *models.Imp, li *mode
 		if !e.matchLanguage(lang, targeting.Languages) {
 			result.Matched = false
 			result.FailedCriteria = "language"
-			if e.metrics != nil {
-				e.metrics.RecordTargetingMiss(li.ID, "language")
+			result.Detail = fmt.Sprintf("language %q not in %v", lang, targeting.Languages)
+			if m != nil {
+				m.RecordTargetingMiss(li.ID, "language")
 			}
 			return result
 		}
-		if e.metrics != nil {
-			e.metrics.RecordTargetingMatch(li.ID, "language")
+		if m != nil {
+			m.RecordTargetingMatch(li.ID, "language")
 		}
 	}
 
 	return result
 }
 
+// GetGeoInfo returns geo information for an IP, or nil if unknown.
+func (e *TargetingEngine) GetGeoInfo(ip string) *GeoInfo {
+	return e.lookupGeo(ip)
+}
+
+// requestGeo looks up the device IP, falling back to the geo declared in the
+// request (e.g. the country an S2S partner passes) when the IP is unknown.
+func (e *TargetingEngine) requestGeo(br *models.BidRequest, ip string) *GeoInfo {
+	if info := e.lookupGeo(ip); info != nil {
+		return info
+	}
+	if br.Device == nil || br.Device.Geo == nil || br.Device.Geo.Country == "" {
+		return nil
+	}
+	geo := br.Device.Geo
+	return &GeoInfo{
+		CountryCode: geo.Country,
+		Region:      geo.Region,
+		City:        geo.City,
+	}
+}
+
+// geoField returns a geo field for explanations, "unknown" if missing.
+func geoField(info *GeoInfo, field string) string {
+	var v string
+	if info != nil {
+		switch field {
+		case "country":
+			v = info.CountryCode
+		case "region":
+			v = info.Region
+		case "city":
+			v = info.City
+		}
+	}
+	if v == "" {
+		return "unknown"
+	}
+	return v
+}
+
 // lookupGeo performs a cached geo lookup.
 func (e *TargetingEngine) lookupGeo(ip string) *GeoInfo {
 	if ip == "" || e.geoProvider == nil {
@@ -570,6 +643,14 @@ func (e *TargetingEngine) matchOSVersion(osv, minV, maxV string) bool {
 	return true
 }
 
+// describeOSVersion explains an os_version miss, e.g. "os_version 12.0 < min 13.0".
+func describeOSVersion(osv, minV, maxV string) string {
+	if minV != "" && compareVersions(osv, minV) < 0 {
+		return fmt.Sprintf("os_version %s < min %s", osv, minV)
+	}
+	return fmt.Sprintf("os_version %s > max %s", osv, maxV)
+}
+
 func (e *TargetingEngine) matchCategories(cats, target []string) bool {
 	if len(cats) == 0 {
 		return false
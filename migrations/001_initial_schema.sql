@@ -454,9 +454,9 @@ CREATE TABLE IF NOT EXISTS daily_stats (
     
     spend DECIMAL(15,4) DEFAULT 0,
     revenue DECIMAL(15,4) DEFAULT 0,
-    payout DECIMAL(15,4) DEFAULT 0,
+    payout DECIMAL(15,4) DEFAULT 0
     
-    PRIMARY KEY (date, campaign_id, COALESCE(line_item_id, ''), COALESCE(source_type, ''), COALESCE(source_id, ''), COALESCE(geo_country, ''))
+    -- Key and NOT NULL dimensions are set in 005_daily_stats.sql
 );
 
 CREATE INDEX idx_daily_stats_date ON daily_stats(date);
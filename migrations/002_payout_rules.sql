@@ -0,0 +1,49 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v002: payout rules
+
+-- =============================================
+-- PAYOUT RULES
+-- =============================================
+
+CREATE TABLE IF NOT EXISTS payout_rules (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255),
+    
+    -- Match keys (NULL matches any value)
+    campaign_id VARCHAR(64) REFERENCES campaigns(id) ON DELETE CASCADE,
+    source_type VARCHAR(10), -- s2s, rtb
+    source_id VARCHAR(64),
+    country VARCHAR(2),
+    os VARCHAR(20),
+    event VARCHAR(50),
+    
+    -- Pricing
+    type VARCHAR(20) NOT NULL, -- fixed, percent, tiered
+    amount DECIMAL(15,4),
+    percent DECIMAL(7,4),
+    tiers JSONB DEFAULT '[]', -- [{"min_revenue": 0, "amount": 1.0, "percent": 0}]
+    currency VARCHAR(3),
+    
+    -- Caps
+    min_payout DECIMAL(15,4),
+    max_payout DECIMAL(15,4),
+    
+    -- Effective period
+    effective_from TIMESTAMP,
+    effective_to TIMESTAMP,
+    
+    priority INTEGER DEFAULT 0,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    
+    CONSTRAINT chk_payout_rule_type CHECK (type IN ('fixed', 'percent', 'tiered'))
+);
+
+CREATE INDEX idx_payout_rules_campaign ON payout_rules(campaign_id);
+CREATE INDEX idx_payout_rules_source ON payout_rules(source_type, source_id);
+
+CREATE TRIGGER update_payout_rules_timestamp BEFORE UPDATE ON payout_rules FOR EACH ROW EXECUTE FUNCTION update_updated_at();
+
+-- Rule that priced each conversion
+ALTER TABLE conversions ADD COLUMN IF NOT EXISTS payout_rule_id VARCHAR(64);
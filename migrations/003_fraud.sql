@@ -0,0 +1,19 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v003: fraud scoring
+
+-- =============================================
+-- FRAUD SCORES
+-- =============================================
+
+-- Score (0-100), triggered rules and flag for each click
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS fraud_score SMALLINT DEFAULT 0;
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS fraud_reasons TEXT[];
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS fraud_flagged BOOLEAN DEFAULT false;
+
+-- Flagged conversions are not posted back to the source
+ALTER TABLE conversions ADD COLUMN IF NOT EXISTS fraud_score SMALLINT DEFAULT 0;
+ALTER TABLE conversions ADD COLUMN IF NOT EXISTS fraud_reasons TEXT[];
+ALTER TABLE conversions ADD COLUMN IF NOT EXISTS fraud_flagged BOOLEAN DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_clicks_fraud ON clicks(source_id, timestamp) WHERE fraud_flagged;
+CREATE INDEX IF NOT EXISTS idx_conversions_fraud ON conversions(source_id, timestamp) WHERE fraud_flagged;
@@ -0,0 +1,59 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v004: scheduled reports
+
+-- Schedules default to the advertiser's timezone
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS timezone VARCHAR(64);
+
+-- =============================================
+-- SCHEDULED REPORTS
+-- =============================================
+
+CREATE TABLE IF NOT EXISTS scheduled_reports (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    advertiser_id VARCHAR(64) REFERENCES advertisers(id) ON DELETE CASCADE,
+
+    filter JSONB NOT NULL DEFAULT '{}',     -- ReportFilter; dates come from period
+    period VARCHAR(32) NOT NULL,            -- yesterday, last_7_days, previous_week, ...
+    format VARCHAR(16) NOT NULL,            -- csv, xlsx, ndjson
+
+    frequency VARCHAR(16) NOT NULL,         -- daily, weekly
+    weekday SMALLINT NOT NULL DEFAULT 0,    -- 0 = Sunday
+    hour SMALLINT NOT NULL DEFAULT 0,
+    timezone VARCHAR(64),
+
+    delivery JSONB NOT NULL,                -- {type: webhook|dir|sftp|smtp, ...}
+
+    is_active BOOLEAN DEFAULT true,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_reports_due ON scheduled_reports(next_run_at) WHERE is_active;
+CREATE INDEX IF NOT EXISTS idx_scheduled_reports_advertiser ON scheduled_reports(advertiser_id);
+
+-- =============================================
+-- REPORT RUNS
+-- =============================================
+
+CREATE TABLE IF NOT EXISTS report_runs (
+    id VARCHAR(64) PRIMARY KEY,
+    report_id VARCHAR(64) NOT NULL REFERENCES scheduled_reports(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+    status VARCHAR(16) NOT NULL,            -- running, succeeded, retrying, failed
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    file_name VARCHAR(255),
+    rows INTEGER DEFAULT 0,
+    bytes INTEGER DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ,
+    started_at TIMESTAMPTZ NOT NULL,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_runs_report ON report_runs(report_id, started_at DESC);
+CREATE INDEX IF NOT EXISTS idx_report_runs_retry ON report_runs(next_attempt_at) WHERE status = 'retrying';
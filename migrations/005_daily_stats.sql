@@ -0,0 +1,45 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v005: daily stats rollup
+
+-- =============================================
+-- DAILY STATS
+-- =============================================
+
+-- Dimensions are blank rather than NULL so they can be part of the key.
+-- The rollup writes one row per UTC date, campaign, source and country and
+-- leaves line_item_id, creative_id and device_os blank.
+UPDATE daily_stats SET
+    campaign_id = COALESCE(campaign_id, ''),
+    line_item_id = COALESCE(line_item_id, ''),
+    creative_id = COALESCE(creative_id, ''),
+    source_type = COALESCE(source_type, ''),
+    source_id = COALESCE(source_id, ''),
+    geo_country = COALESCE(geo_country, ''),
+    device_os = COALESCE(device_os, '');
+
+ALTER TABLE daily_stats
+    ALTER COLUMN campaign_id SET DEFAULT '', ALTER COLUMN campaign_id SET NOT NULL,
+    ALTER COLUMN line_item_id SET DEFAULT '', ALTER COLUMN line_item_id SET NOT NULL,
+    ALTER COLUMN creative_id SET DEFAULT '', ALTER COLUMN creative_id SET NOT NULL,
+    ALTER COLUMN source_type SET DEFAULT '', ALTER COLUMN source_type SET NOT NULL,
+    ALTER COLUMN source_id SET DEFAULT '', ALTER COLUMN source_id SET NOT NULL,
+    ALTER COLUMN geo_country SET DEFAULT '', ALTER COLUMN geo_country SET NOT NULL,
+    ALTER COLUMN device_os SET DEFAULT '', ALTER COLUMN device_os SET NOT NULL;
+
+ALTER TABLE daily_stats DROP CONSTRAINT IF EXISTS daily_stats_pkey;
+ALTER TABLE daily_stats ADD CONSTRAINT daily_stats_pkey
+    PRIMARY KEY (date, campaign_id, line_item_id, creative_id, source_type, source_id, geo_country, device_os);
+
+CREATE INDEX IF NOT EXISTS idx_daily_stats_source ON daily_stats(source_type, source_id);
+
+-- =============================================
+-- ROLLUP STATE
+-- =============================================
+
+-- Events before the watermark have been rolled up; each pass also re-rolls
+-- a late window behind it for late postbacks.
+CREATE TABLE IF NOT EXISTS stats_rollup_state (
+    name VARCHAR(64) PRIMARY KEY,
+    watermark TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT NOW()
+);
@@ -0,0 +1,42 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v006: billing ledger
+
+-- advertisers.balance is only changed by the ledger
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS balance DECIMAL(15,4) DEFAULT 0;
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS credit_limit DECIMAL(15,4) DEFAULT 0;
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS currency VARCHAR(3) DEFAULT 'USD';
+
+-- =============================================
+-- BILLING TRANSACTIONS
+-- =============================================
+
+-- Append-only; every balance change has a row whose balance_after is the
+-- advertiser's balance right after it.
+CREATE TABLE IF NOT EXISTS billing_transactions (
+    id VARCHAR(64) PRIMARY KEY,
+    seq BIGSERIAL,                            -- Orders rows created in the same instant
+    advertiser_id VARCHAR(64) NOT NULL REFERENCES advertisers(id) ON DELETE CASCADE,
+    type VARCHAR(16) NOT NULL,                -- spend, cpa, topup, adjustment
+    amount DECIMAL(15,4) NOT NULL,            -- Signed, in currency; debits are negative
+    currency VARCHAR(3) NOT NULL,
+    balance_after DECIMAL(15,4) NOT NULL,
+
+    -- Charges: one campaign over an accrual period
+    campaign_id VARCHAR(64) NOT NULL DEFAULT '',
+    quantity BIGINT NOT NULL DEFAULT 0,       -- Wins or conversions
+    period_start TIMESTAMPTZ,
+    period_end TIMESTAMPTZ,
+
+    -- Top-ups and adjustments
+    reference VARCHAR(128) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    created_by VARCHAR(255) NOT NULL DEFAULT '',
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_billing_type CHECK (type IN ('spend', 'cpa', 'topup', 'adjustment'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_transactions_advertiser ON billing_transactions(advertiser_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_billing_transactions_reference
+    ON billing_transactions(advertiser_id, reference) WHERE reference <> '';
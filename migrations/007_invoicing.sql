@@ -0,0 +1,65 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v007: closing documents
+
+-- Requisites printed on documents
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS legal_name VARCHAR(255);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS tax_id VARCHAR(64);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS kpp VARCHAR(16);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS ogrn VARCHAR(32);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS address TEXT;
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS bik VARCHAR(16);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS account_number VARCHAR(32);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS bank_name VARCHAR(255);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS contract_number VARCHAR(64);
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS contract_date DATE;
+
+-- =============================================
+-- BILLING DOCUMENTS
+-- =============================================
+
+-- One счёт, акт and счёт-фактура per advertiser and month. Parties are
+-- snapshots of the requisites at generation time.
+CREATE TABLE IF NOT EXISTS billing_documents (
+    id VARCHAR(64) PRIMARY KEY,
+    advertiser_id VARCHAR(64) NOT NULL REFERENCES advertisers(id) ON DELETE RESTRICT,
+    type VARCHAR(16) NOT NULL,              -- invoice, act, vat_invoice
+    number VARCHAR(64) NOT NULL,
+    seq BIGINT NOT NULL,                    -- Position in the type's yearly sequence
+    date DATE NOT NULL,
+    period CHAR(7) NOT NULL,                -- YYYY-MM
+    period_start TIMESTAMPTZ NOT NULL,
+    period_end TIMESTAMPTZ NOT NULL,
+
+    supplier JSONB NOT NULL,
+    buyer JSONB NOT NULL,
+
+    currency VARCHAR(3) NOT NULL,
+    vat_rate DECIMAL(5,2) NOT NULL,
+    lines JSONB NOT NULL,
+    net DECIMAL(15,2) NOT NULL,
+    vat_amount DECIMAL(15,2) NOT NULL,
+    total DECIMAL(15,2) NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_billing_document_type CHECK (type IN ('invoice', 'act', 'vat_invoice')),
+    UNIQUE (advertiser_id, period, type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_billing_documents_period ON billing_documents(period);
+
+-- Numbers run per document type and calendar year
+CREATE TABLE IF NOT EXISTS document_sequences (
+    type VARCHAR(16) NOT NULL,
+    year INT NOT NULL,
+    last_number BIGINT NOT NULL,
+    PRIMARY KEY (type, year)
+);
+
+-- Closed months; their documents are final
+CREATE TABLE IF NOT EXISTS closing_periods (
+    period CHAR(7) PRIMARY KEY,
+    closed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    closed_by VARCHAR(255) NOT NULL DEFAULT ''
+);
@@ -0,0 +1,45 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v008: user accounts and sessions
+
+-- =============================================
+-- USERS
+-- =============================================
+
+-- Management UI logins. Passwords are bcrypt hashes; the TOTP secret is
+-- set on setup and only checked once totp_enabled.
+CREATE TABLE IF NOT EXISTS users (
+    id VARCHAR(64) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    name VARCHAR(255),
+    password_hash VARCHAR(255) NOT NULL,
+    role VARCHAR(32) NOT NULL,              -- admin, trader, analyst, advertiser_viewer
+    advertiser_id VARCHAR(64) REFERENCES advertisers(id),
+    totp_secret VARCHAR(64),
+    totp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    totp_last_step BIGINT NOT NULL DEFAULT 0, -- Last accepted time step; codes can't be replayed
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    last_login_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(LOWER(email));
+
+-- =============================================
+-- SESSIONS
+-- =============================================
+
+-- One row per refresh token. Tokens are rotated on every refresh; the
+-- SHA-256 hash of the token is stored.
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(64) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    user_agent TEXT,
+    ip VARCHAR(64),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
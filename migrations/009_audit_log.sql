@@ -0,0 +1,42 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v009: audit log of management changes
+
+-- =============================================
+-- AUDIT LOG
+-- =============================================
+
+-- Append-only; one row per change of a campaign, ad group, creative,
+-- source, advertiser or payout rule through the management API. before and
+-- after are full snapshots of the entity (NULL when created or deleted);
+-- diff lists the changed fields.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id VARCHAR(64) PRIMARY KEY,
+    seq BIGSERIAL,                            -- Orders rows created in the same instant
+    entity_type VARCHAR(32) NOT NULL,         -- campaign, ad_group, creative, s2s_source, rtb_source, advertiser, payout_rule
+    entity_id VARCHAR(64) NOT NULL,
+    action VARCHAR(16) NOT NULL,              -- create, update, delete, rollback
+    actor_kind VARCHAR(16) NOT NULL,          -- master, api_key, user, anonymous (auth disabled)
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB,
+    diff JSONB NOT NULL DEFAULT '[]',
+    rollback_of VARCHAR(64) NOT NULL DEFAULT '', -- Entry restored by a rollback
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_created ON audit_log(created_at);
+
+-- Reject changes to written entries
+CREATE OR REPLACE FUNCTION audit_log_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_log_no_update ON audit_log;
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,41 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v010: campaign lifecycle
+
+-- scheduled: ready, activated by the lifecycle scheduler at start_date
+ALTER TABLE campaigns DROP CONSTRAINT IF EXISTS chk_campaign_status;
+ALTER TABLE campaigns ADD CONSTRAINT chk_campaign_status
+    CHECK (status IN ('draft', 'scheduled', 'active', 'paused', 'ended', 'archived'));
+
+-- The scheduler reads the flight dates and total budget
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS total_budget DECIMAL(12, 2);
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS daily_budget DECIMAL(12, 2);
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS start_date TIMESTAMPTZ;
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS end_date TIMESTAMPTZ;
+
+-- Email of the account manager notified of status changes
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS account_manager VARCHAR(255);
+
+-- =============================================
+-- CAMPAIGN TRANSITIONS
+-- =============================================
+
+-- Append-only; one row per campaign status change, by the scheduler
+-- (actor_kind system) or through the API (reason manual).
+CREATE TABLE IF NOT EXISTS campaign_transitions (
+    id VARCHAR(64) PRIMARY KEY,
+    seq BIGSERIAL,                            -- Orders rows created in the same instant
+    campaign_id VARCHAR(64) NOT NULL,
+    advertiser_id VARCHAR(64) NOT NULL DEFAULT '',
+    from_status VARCHAR(32) NOT NULL DEFAULT '',
+    to_status VARCHAR(32) NOT NULL,
+    reason VARCHAR(32) NOT NULL,              -- start_date, end_date, budget_spent, balance_exhausted, balance_restored, manual
+    detail TEXT NOT NULL DEFAULT '',
+    actor_kind VARCHAR(16) NOT NULL,
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_transitions_campaign ON campaign_transitions(campaign_id, seq);
+CREATE INDEX IF NOT EXISTS idx_campaign_transitions_advertiser ON campaign_transitions(advertiser_id, seq);
+CREATE INDEX IF NOT EXISTS idx_campaign_transitions_created ON campaign_transitions(created_at);
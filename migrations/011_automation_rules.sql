@@ -0,0 +1,56 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v011: automation rules and their firing log
+
+-- =============================================
+-- AUTOMATION RULES
+-- =============================================
+
+-- Conditions over reporting metrics per line item (or per source, country
+-- or app bundle within it) and the actions taken when they all hold.
+CREATE TABLE IF NOT EXISTS automation_rules (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    advertiser_id VARCHAR(64) NOT NULL DEFAULT '',  -- Empty: every advertiser
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    campaign_ids JSONB NOT NULL DEFAULT '[]',
+    line_item_ids JSONB NOT NULL DEFAULT '[]',
+    scope VARCHAR(16) NOT NULL,                     -- line_item, source, country, app_bundle
+    scope_values JSONB NOT NULL DEFAULT '[]',
+    conditions JSONB NOT NULL,                      -- [{"metric", "operator", "value"}]
+    lookback_hours INT NOT NULL,
+    cooldown_hours INT NOT NULL DEFAULT 0,          -- 0: the lookback
+    actions JSONB NOT NULL,                         -- [{"type", "bid_adjust_pct", "list"}]
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_automation_rule_scope CHECK (scope IN ('line_item', 'source', 'country', 'app_bundle'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_automation_rules_advertiser ON automation_rules(advertiser_id);
+
+-- =============================================
+-- RULE FIRINGS
+-- =============================================
+
+-- Append-only log of the actions rules took. Rows outlive their rule.
+CREATE TABLE IF NOT EXISTS rule_firings (
+    id VARCHAR(64) PRIMARY KEY,
+    seq BIGSERIAL,                                  -- Orders rows created in the same instant
+    rule_id VARCHAR(64) NOT NULL,
+    rule_name VARCHAR(255) NOT NULL DEFAULT '',
+    advertiser_id VARCHAR(64) NOT NULL DEFAULT '',
+    campaign_id VARCHAR(64) NOT NULL,
+    line_item_id VARCHAR(64) NOT NULL,
+    scope VARCHAR(16) NOT NULL,
+    scope_value VARCHAR(255) NOT NULL DEFAULT '',   -- Source, country or bundle; empty for line_item scope
+    action VARCHAR(16) NOT NULL,                    -- pause, adjust_bid, blacklist, notify
+    detail TEXT NOT NULL DEFAULT '',
+    metrics JSONB NOT NULL DEFAULT '{}',
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_rule_firings_rule ON rule_firings(rule_id, line_item_id, seq);
+CREATE INDEX IF NOT EXISTS idx_rule_firings_campaign ON rule_firings(campaign_id, seq);
+CREATE INDEX IF NOT EXISTS idx_rule_firings_created ON rule_firings(created_at);
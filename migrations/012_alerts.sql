@@ -0,0 +1,64 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v012: delivery and health alerts
+
+-- =============================================
+-- ALERT DEFINITIONS
+-- =============================================
+
+-- Checks run every alert interval; built-in definitions are created by the
+-- service on startup.
+CREATE TABLE IF NOT EXISTS alert_definitions (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    built_in BOOLEAN NOT NULL DEFAULT FALSE,
+    advertiser_id VARCHAR(64) NOT NULL DEFAULT '',  -- Empty: every advertiser
+    campaign_ids JSONB NOT NULL DEFAULT '[]',
+    threshold DECIMAL(12, 4) NOT NULL,              -- Percentage
+    window_hours INT NOT NULL DEFAULT 0,
+    min_volume BIGINT NOT NULL DEFAULT 0,
+    channels JSONB NOT NULL DEFAULT '[]',           -- [{"type", "url", "recipients"}]; empty: default channels
+    renotify_hours INT NOT NULL DEFAULT 0,
+    snoozed_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT chk_alert_definition_type CHECK (type IN ('underdelivery', 'zero_conversions', 'postback_errors', 'fraud_spike', 'budget'))
+);
+
+-- =============================================
+-- ALERTS
+-- =============================================
+
+-- One row per anomaly, open from the first check that found it until the
+-- first that didn't. Rows outlive their definition.
+CREATE TABLE IF NOT EXISTS alerts (
+    id VARCHAR(64) PRIMARY KEY,
+    definition_id VARCHAR(64) NOT NULL,
+    definition_name VARCHAR(255) NOT NULL DEFAULT '',
+    type VARCHAR(32) NOT NULL,
+    key VARCHAR(255) NOT NULL,                      -- e.g. line_item:li_1, mmp:appsflyer
+    status VARCHAR(16) NOT NULL DEFAULT 'open',
+    advertiser_id VARCHAR(64) NOT NULL DEFAULT '',
+    campaign_id VARCHAR(64) NOT NULL DEFAULT '',
+    line_item_id VARCHAR(64) NOT NULL DEFAULT '',
+    source_id VARCHAR(64) NOT NULL DEFAULT '',
+    mmp VARCHAR(32) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    value DECIMAL(18, 4) NOT NULL DEFAULT 0,
+    threshold DECIMAL(12, 4) NOT NULL DEFAULT 0,
+    checks INT NOT NULL DEFAULT 1,
+    first_seen_at TIMESTAMPTZ NOT NULL,
+    last_seen_at TIMESTAMPTZ NOT NULL,
+    resolved_at TIMESTAMPTZ,
+    notified_at TIMESTAMPTZ,
+    snoozed_until TIMESTAMPTZ,
+
+    CONSTRAINT chk_alert_status CHECK (status IN ('open', 'resolved'))
+);
+
+-- At most one open alert per definition and key
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alerts_open ON alerts(definition_id, key) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS idx_alerts_first_seen ON alerts(first_seen_at);
+CREATE INDEX IF NOT EXISTS idx_alerts_campaign ON alerts(campaign_id, first_seen_at);
@@ -0,0 +1,40 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v013: campaign templates and drafts
+
+-- =============================================
+-- CAMPAIGN TEMPLATES
+-- =============================================
+
+-- Reusable campaigns of an advertiser; campaigns created from a template
+-- are drafts copying its campaign with new IDs.
+CREATE TABLE IF NOT EXISTS campaign_templates (
+    id VARCHAR(64) PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    advertiser_id VARCHAR(64) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    source_campaign_id VARCHAR(64) NOT NULL DEFAULT '',
+    campaign JSONB NOT NULL,                        -- The blueprint, with its line items
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_templates_advertiser ON campaign_templates(advertiser_id);
+
+-- =============================================
+-- CAMPAIGN DRAFTS
+-- =============================================
+
+-- Staged changes of a campaign, applied when published. The bidder only
+-- sees the campaigns table.
+CREATE TABLE IF NOT EXISTS campaign_drafts (
+    campaign_id VARCHAR(64) PRIMARY KEY,
+    advertiser_id VARCHAR(64) NOT NULL,
+    campaign JSONB NOT NULL,                        -- The staged version
+    base_updated_at TIMESTAMPTZ NOT NULL,           -- Live version the draft started from
+    actor_id VARCHAR(64) NOT NULL DEFAULT '',
+    actor_name VARCHAR(255) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_campaign_drafts_advertiser ON campaign_drafts(advertiser_id);
@@ -0,0 +1,292 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v014: unified repository layer
+
+-- Campaigns, creatives, ad groups and events are stored as JSON in a data
+-- column; the other columns are kept in sync by the repositories for
+-- queries and foreign keys. Advertisers stay relational because the
+-- billing ledger updates their balance in SQL. This replaces the drafts
+-- 001_initial.sql and initial.sql; 001_initial_schema.sql is the base
+-- schema. Line items are stored with their campaign: line_items and
+-- line_item_creatives are folded into the campaigns' data and dropped.
+
+SET TIME ZONE 'UTC';
+
+-- RFC 3339 text of a timestamp, as encoding/json writes time.Time; the zero
+-- time for NULL
+CREATE FUNCTION pg_temp.json_time(ts TIMESTAMP) RETURNS TEXT AS $$
+    SELECT COALESCE(to_char(ts, 'YYYY-MM-DD"T"HH24:MI:SS.US"Z"'), '0001-01-01T00:00:00Z')
+$$ LANGUAGE sql IMMUTABLE;
+
+-- =============================================
+-- TIMESTAMPS
+-- =============================================
+
+-- The services set updated_at (ETags are derived from it), so the
+-- triggers overwriting it are dropped
+DROP TRIGGER IF EXISTS update_advertisers_timestamp ON advertisers;
+DROP TRIGGER IF EXISTS update_campaigns_timestamp ON campaigns;
+DROP TRIGGER IF EXISTS update_creatives_timestamp ON creatives;
+
+-- =============================================
+-- ADVERTISERS
+-- =============================================
+
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS website TEXT;
+ALTER TABLE advertisers ADD COLUMN IF NOT EXISTS industry VARCHAR(64);
+
+-- =============================================
+-- CREATIVES
+-- =============================================
+
+ALTER TABLE creatives ADD COLUMN IF NOT EXISTS data JSONB;
+
+-- Banners without markup serve their image URL. mime_types,
+-- iab_categories, attr, video_duration and sponsored_by have no model
+-- field and stay in their columns.
+UPDATE creatives SET data = jsonb_build_object(
+    'id', id,
+    'advertiser_id', COALESCE(advertiser_id, ''),
+    'name', COALESCE(name, ''),
+    'adm_template', COALESCE(NULLIF(adm_template, ''), banner_url, ''),
+    'w', COALESCE(width, 0),
+    'h', COALESCE(height, 0),
+    'adomain', '[]'::jsonb,
+    'click_url', '',
+    'format', format,
+    'video_url', COALESCE(video_url, ''),
+    'audit_status', COALESCE(status, ''),
+    'created_at', pg_temp.json_time(created_at::timestamp),
+    'updated_at', pg_temp.json_time(updated_at::timestamp)
+) || CASE WHEN format = 'native' THEN jsonb_build_object(
+    'native_assets', jsonb_strip_nulls(jsonb_build_object(
+        'title', title,
+        'description', description,
+        'icon_url', icon_url,
+        'image_url', image_url,
+        'cta_text', cta_text,
+        'rating', rating
+    ))
+) ELSE '{}'::jsonb END
+WHERE data IS NULL;
+
+-- The old schema kept the advertiser domain and landing page on the
+-- campaign: a creative takes them from the first campaign serving it
+UPDATE creatives cr SET data = cr.data || jsonb_build_object(
+    'adomain', CASE WHEN COALESCE(cp.app_bundle, '') <> '' THEN jsonb_build_array(cp.app_bundle) ELSE '[]'::jsonb END,
+    'click_url', COALESCE(cp.app_store_url, '')
+)
+FROM (
+    SELECT DISTINCT ON (lic.creative_id) lic.creative_id, c.app_bundle, c.app_store_url
+    FROM line_item_creatives lic
+    JOIN line_items li ON li.id = lic.line_item_id
+    JOIN campaigns c ON c.id = li.campaign_id
+    ORDER BY lic.creative_id, c.id
+) cp
+WHERE cp.creative_id = cr.id AND cr.data->>'click_url' = '';
+
+ALTER TABLE creatives ALTER COLUMN data SET NOT NULL;
+
+-- =============================================
+-- CAMPAIGNS
+-- =============================================
+
+ALTER TABLE campaigns ADD COLUMN IF NOT EXISTS data JSONB;
+
+-- Line items are built from line_items and their active creatives.
+-- Campaign targeting is the base of each line item's targeting and the
+-- campaign timezone applies to its day parting. Frequency caps over an
+-- hour or less are hourly caps, longer ones daily caps. daily_cap and
+-- total_cap have no model field and stay in their columns.
+UPDATE campaigns c SET data = jsonb_build_object(
+    'id', c.id,
+    'name', c.name,
+    'advertiser_id', COALESCE(c.advertiser_id, ''),
+    'status', COALESCE(c.status, 'draft'),
+    'app_bundle', COALESCE(c.app_bundle, ''),
+    'app_name', COALESCE(c.app_name, ''),
+    'app_store_url', COALESCE(c.app_store_url, ''),
+    'total_budget', COALESCE(c.total_budget, 0),
+    'daily_budget', COALESCE(c.daily_budget, 0),
+    'start_date', pg_temp.json_time(c.start_date::timestamp),
+    'end_date', pg_temp.json_time(c.end_date::timestamp),
+    'mmp', jsonb_build_object(
+        'type', COALESCE(c.mmp_type, ''),
+        'click_url', COALESCE(c.mmp_click_url, ''),
+        'view_url', COALESCE(c.mmp_view_url, ''),
+        'macros_mapping', COALESCE(c.mmp_macros_mapping, '{}'::jsonb),
+        'postback_events', COALESCE(to_jsonb(c.mmp_postback_events), '[]'::jsonb)
+    ),
+    'payout_type', COALESCE(c.payout_type, ''),
+    'payout_amount', COALESCE(c.payout_amount, 0),
+    'payout_event', COALESCE(c.payout_event, ''),
+    'line_items', COALESCE((
+        SELECT jsonb_agg(jsonb_build_object(
+            'id', li.id,
+            'name', li.name,
+            'campaign_id', li.campaign_id,
+            'targeting', CASE
+                WHEN jsonb_typeof(t.targeting->'day_parting') = 'object' AND NOT t.targeting->'day_parting' ? 'timezone'
+                THEN jsonb_set(t.targeting, '{day_parting,timezone}', to_jsonb(COALESCE(c.timezone, 'UTC')))
+                ELSE t.targeting
+            END,
+            'bid_strategy', jsonb_strip_nulls(jsonb_build_object(
+                'type', b.type,
+                'fixed_cpm', CASE WHEN b.type = 'fixed_cpm' THEN li.bid_amount END,
+                'target_cpi', CASE WHEN b.type = 'target_cpi' THEN li.bid_amount END,
+                'min_cpm', li.min_bid,
+                'max_cpm', li.max_bid
+            )),
+            'pacing', jsonb_build_object(
+                'daily_budget', COALESCE(li.daily_budget, 0),
+                'total_budget', COALESCE(li.total_budget, 0),
+                'start_at', pg_temp.json_time(c.start_date::timestamp),
+                'end_at', pg_temp.json_time(c.end_date::timestamp),
+                'freq_cap_per_user_per_day', CASE WHEN COALESCE(li.freq_cap_period_hours, 24) > 1 THEN COALESCE(li.freq_cap_impressions, 0) ELSE 0 END,
+                'freq_cap_per_user_per_hour', CASE WHEN li.freq_cap_period_hours <= 1 THEN COALESCE(li.freq_cap_impressions, 0) ELSE 0 END,
+                'pacing_type', CASE WHEN li.pacing_type = 'asap' THEN 'accelerated' ELSE 'even' END
+            ),
+            'creatives', COALESCE((
+                SELECT jsonb_agg(cr.data || jsonb_build_object(
+                    'adomain', CASE WHEN COALESCE(c.app_bundle, '') <> '' THEN jsonb_build_array(c.app_bundle) ELSE cr.data->'adomain' END,
+                    'click_url', COALESCE(NULLIF(c.app_store_url, ''), cr.data->>'click_url')
+                ) ORDER BY lic.weight DESC, cr.id)
+                FROM line_item_creatives lic
+                JOIN creatives cr ON cr.id = lic.creative_id
+                WHERE lic.line_item_id = li.id AND COALESCE(lic.status, 'active') = 'active'
+            ), '[]'::jsonb),
+            'is_active', COALESCE(li.status, 'active') = 'active',
+            'priority', 0,
+            'created_at', pg_temp.json_time(li.created_at::timestamp),
+            'updated_at', pg_temp.json_time(li.updated_at::timestamp)
+        ) ORDER BY li.id)
+        FROM line_items li
+        CROSS JOIN LATERAL (
+            SELECT COALESCE(c.targeting, '{}'::jsonb) || COALESCE(li.targeting, '{}'::jsonb) AS targeting
+        ) t
+        CROSS JOIN LATERAL (
+            SELECT CASE
+                WHEN li.bid_type = 'cpi' THEN 'target_cpi'
+                WHEN li.bid_type = 'cpc' OR li.bid_strategy = 'optimize' THEN 'dynamic_cpm'
+                ELSE 'fixed_cpm'
+            END AS type
+        ) b
+        WHERE li.campaign_id = c.id
+    ), '[]'::jsonb),
+    'created_at', pg_temp.json_time(c.created_at::timestamp),
+    'updated_at', pg_temp.json_time(c.updated_at::timestamp)
+) WHERE c.data IS NULL;
+
+ALTER TABLE campaigns ALTER COLUMN data SET NOT NULL;
+
+DROP TABLE IF EXISTS line_item_creatives;
+DROP TABLE IF EXISTS line_items;
+
+-- =============================================
+-- AD GROUPS
+-- =============================================
+
+CREATE TABLE IF NOT EXISTS ad_groups (
+    id VARCHAR(64) PRIMARY KEY,
+    campaign_id VARCHAR(64) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    budget DECIMAL(12, 2) NOT NULL DEFAULT 0,
+    start_at TIMESTAMPTZ,
+    end_at TIMESTAMPTZ,
+    is_active BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ad_groups_campaign ON ad_groups(campaign_id);
+
+-- Databases created from the 001_initial.sql draft already have the table
+ALTER TABLE ad_groups ADD COLUMN IF NOT EXISTS data JSONB;
+
+UPDATE ad_groups SET data = jsonb_build_object(
+    'id', id,
+    'campaign_id', campaign_id,
+    'name', name,
+    'budget', budget,
+    'start_at', pg_temp.json_time(start_at::timestamp),
+    'end_at', pg_temp.json_time(end_at::timestamp),
+    'targeting', '{}'::jsonb,
+    'creative_ids', '[]'::jsonb,
+    'is_active', is_active,
+    'created_at', pg_temp.json_time(created_at::timestamp),
+    'updated_at', pg_temp.json_time(updated_at::timestamp)
+) WHERE data IS NULL;
+
+ALTER TABLE ad_groups ALTER COLUMN data SET NOT NULL;
+
+-- =============================================
+-- EVENTS
+-- =============================================
+
+ALTER TABLE clicks ADD COLUMN IF NOT EXISTS data JSONB;
+
+UPDATE clicks SET data = jsonb_build_object(
+    'id', id,
+    'timestamp', pg_temp.json_time(timestamp::timestamp),
+    'campaign_id', COALESCE(campaign_id, ''),
+    'line_item_id', COALESCE(line_item_id, ''),
+    'creative_id', COALESCE(creative_id, ''),
+    'source_type', COALESCE(source_type, ''),
+    'source_id', COALESCE(source_id, ''),
+    'source_name', '',
+    'device_ifa', COALESCE(device_ifa, ''),
+    'ip', COALESCE(ip, ''),
+    'user_agent', COALESCE(user_agent, ''),
+    'geo_country', COALESCE(geo_country, '')
+) WHERE data IS NULL;
+
+ALTER TABLE clicks ALTER COLUMN data SET NOT NULL;
+
+ALTER TABLE impressions ADD COLUMN IF NOT EXISTS data JSONB;
+
+UPDATE impressions SET data = jsonb_build_object(
+    'id', id,
+    'timestamp', pg_temp.json_time(timestamp::timestamp),
+    'campaign_id', COALESCE(campaign_id, ''),
+    'line_item_id', COALESCE(line_item_id, ''),
+    'creative_id', COALESCE(creative_id, ''),
+    'source_type', COALESCE(source_type, ''),
+    'source_id', COALESCE(source_id, ''),
+    'source_name', '',
+    'bid_request_id', COALESCE(bid_request_id, ''),
+    'device_ifa', COALESCE(device_ifa, ''),
+    'ip', COALESCE(ip, '')
+) WHERE data IS NULL;
+
+ALTER TABLE impressions ALTER COLUMN data SET NOT NULL;
+
+ALTER TABLE conversions ADD COLUMN IF NOT EXISTS data JSONB;
+
+UPDATE conversions SET data = jsonb_build_object(
+    'id', id,
+    'timestamp', pg_temp.json_time(timestamp::timestamp),
+    'click_id', COALESCE(click_id, ''),
+    'source_type', COALESCE(source_type, ''),
+    'source_id', COALESCE(source_id, ''),
+    'source_name', '',
+    'campaign_id', COALESCE(campaign_id, ''),
+    'line_item_id', COALESCE(line_item_id, ''),
+    'creative_id', COALESCE(creative_id, ''),
+    'event', event,
+    'event_original', COALESCE(event_original, '')
+) WHERE data IS NULL;
+
+ALTER TABLE conversions ALTER COLUMN data SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS wins (
+    id VARCHAR(64) PRIMARY KEY,
+    timestamp TIMESTAMP NOT NULL,
+    bid_request_id VARCHAR(64),
+    campaign_id VARCHAR(64),
+    line_item_id VARCHAR(64),
+    creative_id VARCHAR(64),
+    source_id VARCHAR(64),
+    data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_wins_campaign ON wins(campaign_id, timestamp);
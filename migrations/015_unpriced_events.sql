@@ -0,0 +1,22 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v015: events held for an exchange rate
+
+-- =============================================
+-- UNPRICED EVENTS
+-- =============================================
+
+-- Wins and conversions whose amounts had no exchange rate at event time.
+-- The re-pricing job records them once the rate is known and deletes the
+-- row; data holds the whole event.
+CREATE TABLE IF NOT EXISTS unpriced_events (
+    id VARCHAR(64) PRIMARY KEY,               -- ID of the win or conversion
+    kind VARCHAR(16) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    data JSONB NOT NULL,
+
+    CONSTRAINT chk_unpriced_event_kind CHECK (kind IN ('win', 'conversion'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_unpriced_events_created ON unpriced_events(created_at);
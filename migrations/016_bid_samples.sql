@@ -0,0 +1,23 @@
+-- Vector-DSP Database Schema
+-- PostgreSQL Migration v016: sampled bid requests
+
+-- =============================================
+-- BID SAMPLES
+-- =============================================
+
+-- Bid requests sampled for debugging, with the response and why each line
+-- item did or didn't bid. data holds the whole sample; the campaigns and
+-- line items of its decision trail are kept for filtering. The sampling
+-- rate bounds how fast the table grows.
+CREATE TABLE IF NOT EXISTS bid_samples (
+    id VARCHAR(64) PRIMARY KEY,
+    timestamp TIMESTAMP NOT NULL,
+    source_id VARCHAR(64),
+    bid BOOLEAN NOT NULL,                     -- A response was sent
+    campaign_ids TEXT[] NOT NULL DEFAULT '{}',
+    line_item_ids TEXT[] NOT NULL DEFAULT '{}',
+    data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_bid_samples_timestamp ON bid_samples(timestamp);
+CREATE INDEX IF NOT EXISTS idx_bid_samples_source ON bid_samples(source_id, timestamp);
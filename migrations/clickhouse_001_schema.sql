@@ -55,7 +55,12 @@ CREATE TABLE IF NOT EXISTS clicks (
     publisher_id String,
     
     -- Target URL
-    target_url String
+    target_url String,
+    
+    -- Fraud
+    fraud_score UInt8,
+    fraud_reasons Array(LowCardinality(String)),
+    fraud_flagged UInt8
 )
 ENGINE = MergeTree()
 PARTITION BY toYYYYMM(date)
@@ -64,6 +69,7 @@ TTL date + INTERVAL 90 DAY
 SETTINGS index_granularity = 8192;
 
 -- Secondary indexes
+ALTER TABLE clicks ADD INDEX idx_id id TYPE bloom_filter(0.01) GRANULARITY 1;
 ALTER TABLE clicks ADD INDEX idx_device_ifa device_ifa TYPE bloom_filter(0.01) GRANULARITY 1;
 ALTER TABLE clicks ADD INDEX idx_geo_country geo_country TYPE set(0) GRANULARITY 1;
 
@@ -144,6 +150,7 @@ CREATE TABLE IF NOT EXISTS conversions (
     payout Float64,
     payout_currency LowCardinality(String),
     payout_usd Float64,
+    payout_rule_id String,
     
     -- Device
     device_ifa String,
@@ -153,7 +160,12 @@ CREATE TABLE IF NOT EXISTS conversions (
     time_to_install Int32, -- seconds from click
     
     -- External
-    external_id String
+    external_id String,
+    
+    -- Fraud
+    fraud_score UInt8,
+    fraud_reasons Array(LowCardinality(String)),
+    fraud_flagged UInt8
 )
 ENGINE = MergeTree()
 PARTITION BY toYYYYMM(date)
@@ -161,8 +173,13 @@ ORDER BY (campaign_id, source_id, event, date, id)
 TTL date + INTERVAL 365 DAY
 SETTINGS index_granularity = 8192;
 
+ALTER TABLE conversions ADD INDEX idx_id id TYPE bloom_filter(0.01) GRANULARITY 1;
 ALTER TABLE conversions ADD INDEX idx_click_id click_id TYPE bloom_filter(0.01) GRANULARITY 1;
 
+-- Revenue or payout without an exchange rate at event time: the *_usd
+-- column is 0 until the conversion is re-priced
+ALTER TABLE conversions ADD COLUMN IF NOT EXISTS needs_conversion UInt8;
+
 -- =============================================
 -- BID REQUESTS TABLE (for debugging/analysis)
 -- =============================================
@@ -202,7 +219,14 @@ CREATE TABLE IF NOT EXISTS bid_requests (
     -- Response
     bid_campaign_id String,
     bid_price Float64,
-    no_bid_reason LowCardinality(String)
+    no_bid_reason LowCardinality(String),
+    
+    -- Sampling (JSON request/response and per-line-item decision trail)
+    campaign_ids Array(String),
+    line_item_ids Array(String),
+    request String CODEC(ZSTD(3)),
+    response String CODEC(ZSTD(3)),
+    trail String CODEC(ZSTD(3))
 )
 ENGINE = MergeTree()
 PARTITION BY toYYYYMM(date)
@@ -245,6 +269,13 @@ ORDER BY (campaign_id, date, id)
 TTL date + INTERVAL 90 DAY
 SETTINGS index_granularity = 8192;
 
+-- win_price is in the reporting currency; win_price_original is the price
+-- in win_currency. Wins without an exchange rate at event time have
+-- win_price 0 until they are re-priced.
+ALTER TABLE wins ADD COLUMN IF NOT EXISTS win_price_original Float64;
+ALTER TABLE wins ADD COLUMN IF NOT EXISTS win_currency LowCardinality(String);
+ALTER TABLE wins ADD COLUMN IF NOT EXISTS needs_conversion UInt8;
+
 -- =============================================
 -- AGGREGATED STATS (Materialized Views)
 -- =============================================